@@ -9,13 +9,16 @@ import (
 	"io/ioutil"
 	"math"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 
 	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/bundle/extensions"
 	"github.com/cnabio/cnab-go/claim"
 	"github.com/cnabio/cnab-go/driver"
 	"github.com/cnabio/cnab-go/valuesource"
@@ -32,12 +35,81 @@ const (
 	ActionStatus = "io.cnab.status"
 )
 
+// ResultInvocationImageKey is the key of the bundle.InvocationImage recorded
+// in the custom section of the result of each operation run by an Action,
+// identifying the invocation image that ran the operation.
+const ResultInvocationImageKey = "io.cnab.invocation-image"
+
 // Action executes a bundle operation and helps save the results.
 type Action struct {
 	Driver driver.Driver
 
-	// SaveLogs to the OperationResult.
+	// SaveLogs to the OperationResult. The values of credentials and
+	// sensitive parameters are redacted from the saved logs, as they are from
+	// the output streams of the operation, except for values shorter than
+	// MinRedactedLength.
 	SaveLogs bool
+
+	// SaveEnvironmentSnapshot to the OperationResult, recording the CNAB
+	// environment variables, with sensitive values redacted, and the paths of
+	// the files injected into the invocation image. See EnvironmentSnapshot.
+	SaveEnvironmentSnapshot bool
+
+	// OperationSnapshotPath is the path of a file where the fully resolved
+	// operation is saved before it is run, so that it can be run again with
+	// Replay. See OperationSnapshot.
+	OperationSnapshotPath string
+
+	// OperationSnapshotEncryption encrypts the values of credentials and
+	// sensitive parameters in the operation snapshot. When it is not set, the
+	// values are redacted.
+	OperationSnapshotEncryption claim.EncryptionHandler
+
+	// FallbackInvocationImages indicates that when the driver fails to run an
+	// invocation image because of a problem with the image itself, such as a
+	// failed pull, the next compatible invocation image in the bundle is tried.
+	FallbackInvocationImages bool
+
+	// Interceptors wrap each execution of the driver, in order, for example to
+	// record telemetry or enforce policy. See Use.
+	Interceptors []Interceptor
+
+	// Events receives the progress of each operation run by the action, such
+	// as its logs and outputs, in addition to any handler set on the
+	// operation. See driver.Event.
+	Events driver.EventHandler
+
+	// Runtime advertises the capabilities of the CNAB runtime running the
+	// action. When set, bundles that declare runtime requirements with the
+	// extensions.RuntimeRequirementsExtensionKey extension are only run when
+	// the runtime has the required capabilities.
+	Runtime *extensions.RuntimeCapabilities
+
+	// ImagePolicy restricts the images that the bundle may reference. When
+	// set, bundles with images that are not allowed are not run, and an
+	// *bundle.ImagePolicyError describing the violations is returned.
+	ImagePolicy *bundle.ImagePolicy
+
+	// Timeout limits how long the operation may run. When it is exceeded, the
+	// driver stops the operation and the claim result is marked as failed.
+	// It is set on the operation as its Deadline, unless a config function
+	// sets an earlier deadline. Zero means that the operation is not limited.
+	Timeout time.Duration
+
+	// Claims persists the claims of the operations run by the action, and is
+	// required by SaveInitialClaim.
+	Claims claim.Provider
+
+	// LockLease, when set, makes SaveInitialClaim lock the installation for
+	// the claim, so that concurrent operations on the same installation fail
+	// with an error matching claim.ErrInstallationLocked instead of
+	// overwriting each other's state. See SaveInitialClaim.
+	LockLease time.Duration
+
+	// Spool, when set, journals the results saved by SaveOperationResult to a
+	// local store before they are saved to the claim provider, so that they
+	// are not lost when the provider is unavailable. See claim.Spool.
+	Spool *claim.Spool
 }
 
 // New creates an Action.
@@ -54,54 +126,107 @@ func New(d driver.Driver) Action {
 // SaveOperationResult function. An error is only returned when the operation could not
 // be executed, otherwise any error is returned in the OperationResult.
 func (a Action) Run(c claim.Claim, creds valuesource.Set, opCfgs ...OperationConfigFunc) (driver.OperationResult, claim.Result, error) {
+	return a.RunValues(c, creds.Values(), opCfgs...)
+}
+
+// RunValues executes the action like Run, with credentials that keep their
+// type. Credentials that are not strings, such as objects, are injected as
+// JSON.
+func (a Action) RunValues(c claim.Claim, creds valuesource.Values, opCfgs ...OperationConfigFunc) (driver.OperationResult, claim.Result, error) {
 	if a.Driver == nil {
 		return driver.OperationResult{}, claim.Result{}, errors.New("the action driver is not set")
 	}
 
-	err := c.Validate()
+	err := a.checkClaim(c)
 	if err != nil {
 		return driver.OperationResult{}, claim.Result{}, err
 	}
 
-	invocImage, err := a.selectInvocationImage(c)
+	invocImages, err := a.selectInvocationImages(c)
 	if err != nil {
 		return driver.OperationResult{}, claim.Result{}, err
 	}
 
-	op, err := opFromClaim(stateful, c, invocImage, creds)
-	if err != nil {
-		return driver.OperationResult{}, claim.Result{}, err
-	}
+	var (
+		op       *driver.Operation
+		opResult driver.OperationResult
+		opErr    *multierror.Error
+	)
+	deadline := a.deadline()
+	for i, invocImage := range invocImages {
+		op, err = opFromClaim(stateful, c, invocImage, creds)
+		if err != nil {
+			return driver.OperationResult{}, claim.Result{}, err
+		}
 
-	err = OperationConfigs(opCfgs).ApplyConfig(op)
-	if err != nil {
-		return driver.OperationResult{}, claim.Result{}, err
-	}
+		err = OperationConfigs(opCfgs).ApplyConfig(op)
+		if err != nil {
+			return driver.OperationResult{}, claim.Result{}, err
+		}
+		if op.In != nil && !driver.SupportsStdin(a.Driver) {
+			return driver.OperationResult{}, claim.Result{}, fmt.Errorf("cannot run action %s with a standard input: %w", op.Action, driver.ErrStdinNotSupported)
+		}
+		applyDeadline(op, deadline)
 
-	logFile, err := a.captureLogs(op)
-	if err != nil {
-		return driver.OperationResult{}, claim.Result{}, err
-	}
+		err = a.saveOperationSnapshot(op)
+		if err != nil {
+			return driver.OperationResult{}, claim.Result{}, err
+		}
 
-	var opErr *multierror.Error
-	opResult, err := a.Driver.Run(op)
-	if err != nil {
-		opErr = multierror.Append(opErr, err)
-	}
+		a.reportEvents(op)
 
-	err = a.saveLogs(logFile, opResult)
-	if err != nil {
-		opErr = multierror.Append(opErr, err)
+		logFile, err := a.captureLogs(op)
+		if err != nil {
+			return driver.OperationResult{}, claim.Result{}, err
+		}
+		flushLogs := redactLogs(op)
+
+		var snapshot *EnvironmentSnapshot
+		if a.SaveEnvironmentSnapshot {
+			s := newEnvironmentSnapshot(op)
+			snapshot = &s
+		}
+
+		op.Emit(driver.Event{Type: driver.EventOperationStarted, Image: op.Image.Image})
+		opResult, err = a.runDriver(op)
+		emitResultEvents(op, opResult, err)
+		fallback := err != nil && a.shouldFallback(err, i, len(invocImages))
+		if fallback {
+			fmt.Fprintf(op.Err, "unable to run invocation image %s, trying the next compatible invocation image: %v\n", invocImage.Image, err)
+		}
+		flushLogs()
+		if fallback {
+			a.discardLogs(logFile)
+			continue
+		}
+		if err != nil {
+			opErr = multierror.Append(opErr, err)
+		}
+
+		err = a.saveLogs(logFile, opResult)
+		if err != nil {
+			opErr = multierror.Append(opErr, err)
+		}
+
+		err = a.saveEnvironmentSnapshot(snapshot, &opResult)
+		if err != nil {
+			opErr = multierror.Append(opErr, err)
+		}
+		break
 	}
+	opResult.InvocationImage = op.Image
 
 	err = opResult.SetDefaultOutputValues(*op)
 	if err != nil {
 		opErr = multierror.Append(opErr, err)
 	}
 
-	cr, err := buildClaimResult(c, opResult, opErr)
+	cr, err := BuildClaimResult(c, opResult, opErr)
 	if err != nil {
 		opErr = multierror.Append(opErr, err)
+	} else {
+		setEnvironmentOnClaimResult(&cr, driver.DescribeEnvironment(a.Driver))
+		setInvocationImageOnClaimResult(&cr, opResult.InvocationImage)
 	}
 
 	// These are any errors from running the operation or processing the result,
@@ -114,6 +239,70 @@ func (a Action) Run(c claim.Claim, creds valuesource.Set, opCfgs ...OperationCon
 	return opResult, cr, nil
 }
 
+// setInvocationImageOnClaimResult records the invocation image that ran the
+// operation in the custom section of the result, so that a fallback to
+// another compatible invocation image can be audited after the fact.
+func setInvocationImageOnClaimResult(result *claim.Result, img bundle.InvocationImage) {
+	setCustomValueOnClaimResult(result, ResultInvocationImageKey, img)
+}
+
+// GetInvocationImage returns the invocation image recorded in the result of an
+// operation run by an Action, and whether it was recorded.
+func GetInvocationImage(result claim.Result) (bundle.InvocationImage, bool) {
+	var img bundle.InvocationImage
+	ok := getCustomValueFromClaimResult(result, ResultInvocationImageKey, &img)
+	return img, ok
+}
+
+// shouldFallback determines if the next compatible invocation image should be
+// tried after the driver failed to run the current one.
+func (a Action) shouldFallback(runErr error, attempt int, numImages int) bool {
+	if !a.FallbackInvocationImages || attempt >= numImages-1 {
+		return false
+	}
+	return driver.IsImageError(runErr)
+}
+
+// reportEvents sends the events of the operation to the action's event
+// handler, and reports the operation's logs as events when it has a handler.
+func (a Action) reportEvents(op *driver.Operation) {
+	if a.Events != nil {
+		if opEvents := op.Events; opEvents != nil {
+			op.Events = func(e driver.Event) {
+				opEvents(e)
+				a.Events(e)
+			}
+		} else {
+			op.Events = a.Events
+		}
+	}
+
+	if op.Events == nil {
+		return
+	}
+	op.Out = op.EventWriter(driver.StreamStdout, op.Out)
+	op.Err = op.EventWriter(driver.StreamStderr, op.Err)
+}
+
+// emitResultEvents reports the outputs captured by the driver, in order by
+// name, followed by the completion of the operation.
+func emitResultEvents(op *driver.Operation, opResult driver.OperationResult, err error) {
+	if op.Events == nil {
+		return
+	}
+
+	names := make([]string, 0, len(opResult.Outputs))
+	for name := range opResult.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op.Emit(driver.Event{Type: driver.EventOutputCaptured, Output: name})
+	}
+
+	op.Emit(driver.Event{Type: driver.EventOperationCompleted, Image: op.Image.Image, Error: err})
+}
+
 // captureLogs to a temporary file.
 func (a Action) captureLogs(op *driver.Operation) (*os.File, error) {
 	if !a.SaveLogs {
@@ -130,16 +319,23 @@ func (a Action) captureLogs(op *driver.Operation) (*os.File, error) {
 	return logFile, nil
 }
 
+// discardLogs removes the temporary log file without saving the logs.
+func (a Action) discardLogs(logFile *os.File) {
+	if logFile == nil {
+		return
+	}
+
+	logFile.Close()
+	os.Remove(logFile.Name())
+}
+
 // saveLogs as an output when action.SaveLogs is set.
 func (a Action) saveLogs(logFile *os.File, opResult driver.OperationResult) error {
 	if logFile == nil {
 		return nil
 	}
 
-	defer func() {
-		logFile.Close()
-		os.Remove(logFile.Name())
-	}()
+	defer a.discardLogs(logFile)
 
 	_, logOutputNameInUse := opResult.Outputs[claim.OutputInvocationImageLogs]
 	if logOutputNameInUse {
@@ -248,13 +444,32 @@ func isTypeOk(name, content string, allowedTypes map[string]bool) error {
 	return nil
 }
 
-// buildClaimResult from the result of executing a bundle operation.
-// A result is _always_ returned, even when an error is returned.
-func buildClaimResult(c claim.Claim, opResult driver.OperationResult, opErr *multierror.Error) (result claim.Result, err error) {
-	if accErr := opErr.ErrorOrNil(); accErr != nil {
-		result, err = c.NewResult(claim.StatusFailed)
+// BuildClaimResult from the result of executing a bundle operation, and the
+// error of the operation, if any. A result is _always_ returned, even when an
+// error is returned. The result is canceled when the driver reports that the
+// execution was interrupted, and its message reports a timeout when the
+// operation exceeded its deadline.
+//
+// It is used by Action.Run, and by custom execution loops, such as remote
+// executors, so that they record the same results as an Action. Call
+// driver.OperationResult.SetDefaultOutputValues before building the result,
+// to record the default value of the outputs that were not generated.
+func BuildClaimResult(c claim.Claim, opResult driver.OperationResult, opErr error) (result claim.Result, err error) {
+	if accErr, ok := opErr.(*multierror.Error); ok {
+		opErr = accErr.ErrorOrNil()
+	}
+
+	if opErr != nil {
+		status := claim.StatusFailed
+		if errors.Is(opErr, driver.ErrExecutionInterrupted) {
+			status = claim.StatusCanceled
+		}
+		result, err = c.NewResult(status)
 		if err == nil {
-			result.Message = accErr.Error()
+			result.Message = opErr.Error()
+			if errors.Is(opErr, driver.ErrDeadlineExceeded) {
+				result.Message = timeoutMessage(opErr)
+			}
 		}
 	} else {
 		result, err = c.NewResult(claim.StatusSucceeded)
@@ -264,17 +479,17 @@ func buildClaimResult(c claim.Claim, opResult driver.OperationResult, opErr *mul
 		return claim.Result{}, err
 	}
 
-	err = setOutputsOnClaimResult(c, &result, opResult)
+	err = SetOutputsOnClaimResult(c, &result, opResult)
 
 	return result, err
 }
 
-// setOutputsOnClaimResult updates the result with the name and metadata of each output generated by
-// the operation.
+// SetOutputsOnClaimResult updates the result with the name and metadata of each output generated by
+// the operation, and validates the outputs defined by the bundle against their definition.
 // Metadata:
 // - contentDigest: string
 // - generatedByBundle: boolean
-func setOutputsOnClaimResult(c claim.Claim, result *claim.Result, opResult driver.OperationResult) error {
+func SetOutputsOnClaimResult(c claim.Claim, result *claim.Result, opResult driver.OperationResult) error {
 	var outputErrors []error
 
 	for outputName, outputValue := range opResult.Outputs {
@@ -332,29 +547,68 @@ func buildOutputContentDigest(outputValue string) string {
 	return fmt.Sprintf("sha256:%s", digest)
 }
 
+// checkClaim determines if the claim is valid and can be run by the action.
+func (a Action) checkClaim(c claim.Claim) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = a.checkRuntimeRequirements(c.Bundle)
+	if err != nil {
+		return err
+	}
+
+	if a.ImagePolicy != nil {
+		return a.ImagePolicy.Check(c.Bundle)
+	}
+	return nil
+}
+
+// checkRuntimeRequirements determines if the runtime has the capabilities
+// required by the bundle, when the runtime advertises its capabilities.
+func (a Action) checkRuntimeRequirements(b bundle.Bundle) error {
+	if a.Runtime == nil || !extensions.HasRuntimeRequirements(b) {
+		return nil
+	}
+
+	reqs, err := extensions.ReadRuntimeRequirements(b)
+	if err != nil {
+		return err
+	}
+	return reqs.CheckSupport(*a.Runtime)
+}
+
 func (a Action) selectInvocationImage(c claim.Claim) (bundle.InvocationImage, error) {
+	invocImages, err := a.selectInvocationImages(c)
+	if err != nil {
+		return bundle.InvocationImage{}, err
+	}
+	return invocImages[0], nil
+}
+
+// selectInvocationImages returns the invocation images in the bundle that are
+// compatible with the driver, in the order that they are defined.
+func (a Action) selectInvocationImages(c claim.Claim) ([]bundle.InvocationImage, error) {
 	if len(c.Bundle.InvocationImages) == 0 {
-		return bundle.InvocationImage{}, errors.New("no invocationImages are defined in the bundle")
+		return nil, errors.New("no invocationImages are defined in the bundle")
 	}
 
+	var invocImages []bundle.InvocationImage
 	for _, ii := range c.Bundle.InvocationImages {
 		if a.Driver.Handles(ii.ImageType) {
-			return ii, nil
+			invocImages = append(invocImages, ii)
 		}
 	}
 
-	return bundle.InvocationImage{}, errors.New("driver is not compatible with any of the invocation images in the bundle")
-}
-
-func getImageMap(b bundle.Bundle) ([]byte, error) {
-	imgs := b.Images
-	if imgs == nil {
-		imgs = make(map[string]bundle.Image)
+	if len(invocImages) == 0 {
+		return nil, errors.New("driver is not compatible with any of the invocation images in the bundle")
 	}
-	return json.Marshal(imgs)
+
+	return invocImages, nil
 }
 
-func opFromClaim(stateless bool, c claim.Claim, ii bundle.InvocationImage, creds valuesource.Set) (*driver.Operation, error) {
+func opFromClaim(stateless bool, c claim.Claim, ii bundle.InvocationImage, creds valuesource.Values) (*driver.Operation, error) {
 	env, files, err := expandCredentials(c.Bundle, creds, stateless, c.Action)
 	if err != nil {
 		return nil, err
@@ -377,11 +631,11 @@ func opFromClaim(stateless bool, c claim.Claim, ii bundle.InvocationImage, creds
 	}
 	files["/cnab/bundle.json"] = string(bundleBytes)
 
-	imgMap, err := getImageMap(c.Bundle)
+	imgMap, err := bundle.BuildImageMap(c.Bundle)
 	if err != nil {
 		return nil, fmt.Errorf("unable to generate image map: %s", err)
 	}
-	files["/cnab/app/image-map.json"] = string(imgMap)
+	files[bundle.ImageMapPath] = string(imgMap)
 
 	claimBytes, err := json.Marshal(c)
 	if err != nil {
@@ -433,20 +687,13 @@ func injectParameters(c claim.Claim, env, files map[string]string) error {
 			continue
 		}
 
-		contents, err := json.Marshal(rawval)
-		if err != nil {
-			return err
-		}
-
 		// In order to preserve the exact string value the user provided
 		// we don't marshal string parameters
-		value := string(contents)
-		if value[0] == '"' {
-			value, ok = rawval.(string)
-			if !ok {
-				return fmt.Errorf("failed to parse parameter %q as string", k)
-			}
+		typed, err := valuesource.NewValue(rawval)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", k, err)
 		}
+		value := typed.String()
 
 		if param.Destination == nil {
 			// env is a CNAB_P_
@@ -466,11 +713,12 @@ func injectParameters(c claim.Claim, env, files map[string]string) error {
 // expandCredentials expands the given set into env vars and paths per the spec in the bundle.
 //
 // This matches the credentials required by the bundle to the credentials present
-// in the Set, and then expands them per the definition in the Bundle.
-func expandCredentials(b bundle.Bundle, set valuesource.Set, stateless bool, action string) (env, files map[string]string, err error) {
+// in the Set, and then expands them per the definition in the Bundle. Credentials
+// that are not strings are expanded as JSON.
+func expandCredentials(b bundle.Bundle, set valuesource.Values, stateless bool, action string) (env, files map[string]string, err error) {
 	env, files = map[string]string{}, map[string]string{}
 	for name, val := range b.Credentials {
-		src, ok := set[name]
+		value, ok := set[name]
 		if !ok {
 			if stateless || !val.Required || !val.AppliesTo(action) {
 				continue
@@ -478,6 +726,7 @@ func expandCredentials(b bundle.Bundle, set valuesource.Set, stateless bool, act
 			err = fmt.Errorf("credential %q is missing from the user-supplied credentials", name)
 			return
 		}
+		src := value.String()
 		if val.EnvironmentVariable != "" {
 			env[val.EnvironmentVariable] = src
 		}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/bundle/extensions"
 	"github.com/cnabio/cnab-go/claim"
 	"github.com/cnabio/cnab-go/driver"
 	"github.com/cnabio/cnab-go/driver/debug"
@@ -37,6 +38,21 @@ func (d *mockDriver) Run(op *driver.Operation) (driver.OperationResult, error) {
 	return d.Result, d.Error
 }
 
+// fallbackDriver fails to run any invocation image with an entry in ImageErrors.
+type fallbackDriver struct {
+	ImageErrors map[string]error
+	Attempted   []string
+}
+
+func (d *fallbackDriver) Handles(imageType string) bool {
+	return imageType == driver.ImageTypeDocker
+}
+
+func (d *fallbackDriver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	d.Attempted = append(d.Attempted, op.Image.Image)
+	return driver.OperationResult{}, d.ImageErrors[op.Image.Image]
+}
+
 var mockSet = valuesource.Set{
 	"secret_one": "I'm a secret",
 	"secret_two": "I'm also a secret",
@@ -208,7 +224,7 @@ func TestOpFromClaim(t *testing.T) {
 	}
 	invocImage := c.Bundle.InvocationImages[0]
 
-	op, err := opFromClaim(stateful, c, invocImage, mockSet)
+	op, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -254,7 +270,7 @@ func TestOpFromClaim_NoOutputsOnBundle(t *testing.T) {
 	c.Bundle.Outputs = nil
 	invocImage := c.Bundle.InvocationImages[0]
 
-	op, err := opFromClaim(stateful, c, invocImage, mockSet)
+	op, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -280,7 +296,7 @@ func TestOpFromClaim_NoParameter(t *testing.T) {
 	c.Bundle.Parameters = nil
 	invocImage := c.Bundle.InvocationImages[0]
 
-	op, err := opFromClaim(stateful, c, invocImage, mockSet)
+	op, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -311,7 +327,7 @@ func TestOpFromClaim_UndefinedParams(t *testing.T) {
 	}
 	invocImage := c.Bundle.InvocationImages[0]
 
-	_, err := opFromClaim(stateful, c, invocImage, mockSet)
+	_, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 	require.Error(t, err)
 }
 
@@ -325,13 +341,13 @@ func TestOpFromClaim_MissingRequiredParameter(t *testing.T) {
 	invocImage := c.Bundle.InvocationImages[0]
 
 	t.Run("missing required parameter fails", func(t *testing.T) {
-		_, err := opFromClaim(stateful, c, invocImage, mockSet)
+		_, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 		assert.EqualError(t, err, `missing required parameter "param_one" for action "install"`)
 	})
 
 	t.Run("fill the missing parameter", func(t *testing.T) {
 		c.Parameters["param_one"] = "oneval"
-		_, err := opFromClaim(stateful, c, invocImage, mockSet)
+		_, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 		assert.Nil(t, err)
 	})
 }
@@ -352,20 +368,20 @@ func TestOpFromClaim_MissingRequiredParamSpecificToAction(t *testing.T) {
 	invocImage := c.Bundle.InvocationImages[0]
 
 	t.Run("if param is not required for this action, succeed", func(t *testing.T) {
-		_, err := opFromClaim(stateful, c, invocImage, mockSet)
+		_, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 		assert.Nil(t, err)
 	})
 
 	t.Run("if param is required for this action and is missing, error", func(t *testing.T) {
 		c.Action = "test"
-		_, err := opFromClaim(stateful, c, invocImage, mockSet)
+		_, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 		assert.EqualError(t, err, `missing required parameter "param_test" for action "test"`)
 	})
 
 	t.Run("if param is required for this action and is set, succeed", func(t *testing.T) {
 		c.Action = "test"
 		c.Parameters["param_test"] = "only for test action"
-		_, err := opFromClaim(stateful, c, invocImage, mockSet)
+		_, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 		assert.Nil(t, err)
 	})
 }
@@ -382,7 +398,7 @@ func TestOpFromClaim_NotApplicableToAction(t *testing.T) {
 	}
 
 	t.Run("output is added to the operation when it applies to the action", func(t *testing.T) {
-		op, err := opFromClaim(stateful, c, invocImage, mockSet)
+		op, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 		require.NoError(t, err)
 		gotOutputs := op.Outputs
 		assert.Contains(t, gotOutputs, "/path/to/some-output", "some-output should be listed in op.Outputs")
@@ -390,7 +406,7 @@ func TestOpFromClaim_NotApplicableToAction(t *testing.T) {
 
 	t.Run("output not added to the operation when it doesn't apply to the action", func(t *testing.T) {
 		c.Action = claim.ActionUninstall
-		op, err := opFromClaim(stateful, c, invocImage, mockSet)
+		op, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 		require.NoError(t, err)
 		gotOutputs := op.Outputs
 		assert.NotContains(t, gotOutputs, "/path/to/some-output", "some-output should not be listed in op.Outputs")
@@ -412,7 +428,7 @@ func TestOpFromClaim_Environment(t *testing.T) {
 		"SECRET_TWO":             "I'm also a secret",
 	}
 
-	op, err := opFromClaim(stateful, c, invocImage, mockSet)
+	op, err := opFromClaim(stateful, c, invocImage, mockSet.Values())
 	require.NoError(t, err)
 	assert.Equal(t, expectedEnv, op.Environment, "operation env does not match expected")
 }
@@ -428,7 +444,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "a valid output",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -438,7 +454,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "2",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -454,7 +470,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "null",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -467,7 +483,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "true",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -480,7 +496,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "{}",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -493,7 +509,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "[]",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -506,7 +522,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "3.14",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -519,7 +535,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "372",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -532,7 +548,7 @@ func TestSetOutputsOnClaimResult(t *testing.T) {
 				"some-output": "372",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 }
@@ -548,7 +564,7 @@ func TestSetOutputsOnClaimResult_GeneratedByBundle(t *testing.T) {
 				"some-output": "a valid output",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 
 		generatedByBundle, ok := r.OutputMetadata.GetGeneratedByBundle("some-output")
@@ -562,7 +578,7 @@ func TestSetOutputsOnClaimResult_GeneratedByBundle(t *testing.T) {
 				"rando-output-not-in-bundle": "a valid output",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 
 		generatedByBundle, ok := r.OutputMetadata.GetGeneratedByBundle("rando-output-not-in-bundle")
@@ -587,7 +603,7 @@ func TestSetOutputsOnClaimResult_MultipleTypes(t *testing.T) {
 			},
 		}
 
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -598,7 +614,7 @@ func TestSetOutputsOnClaimResult_MultipleTypes(t *testing.T) {
 			},
 		}
 
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 }
@@ -619,7 +635,7 @@ func TestSetOutputsOnClaimResult_MultipleTypesWithString(t *testing.T) {
 				"some-output": "null",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 
@@ -629,7 +645,7 @@ func TestSetOutputsOnClaimResult_MultipleTypesWithString(t *testing.T) {
 				"some-output": "XYZ is not a JSON value",
 			},
 		}
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		require.NoError(t, outputErrors)
 	})
 }
@@ -650,7 +666,7 @@ func TestSetOutputsOnClaimResult_MismatchType(t *testing.T) {
 			},
 		}
 
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		assert.EqualError(t, outputErrors, `error: ["some-output" is not any of the expected types (boolean) because it is "integer"]`)
 	})
 
@@ -661,7 +677,7 @@ func TestSetOutputsOnClaimResult_MismatchType(t *testing.T) {
 			},
 		}
 
-		outputErrors := setOutputsOnClaimResult(c, &r, opResult)
+		outputErrors := SetOutputsOnClaimResult(c, &r, opResult)
 		assert.EqualError(t, outputErrors, `error: [failed to parse "some-output": invalid character 'N' looking for beginning of value]`)
 	})
 }
@@ -929,6 +945,181 @@ func TestAction_RunAction(t *testing.T) {
 	})
 }
 
+func TestAction_RunAction_FallbackInvocationImages(t *testing.T) {
+	out := func(op *driver.Operation) error {
+		op.Out = ioutil.Discard
+		op.Err = ioutil.Discard
+		return nil
+	}
+
+	newMultiImageClaim := func() claim.Claim {
+		c := newClaim(claim.ActionInstall)
+		c.Bundle.Outputs = nil
+		c.Bundle.InvocationImages = []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "foo/bar-arm:0.1.0", ImageType: "docker"}},
+			{BaseImage: bundle.BaseImage{Image: "foo/bar-vm:0.1.0", ImageType: "qcow"}},
+			{BaseImage: bundle.BaseImage{Image: "foo/bar:0.1.0", ImageType: "docker"}},
+		}
+		return c
+	}
+
+	t.Run("fallback on image error", func(t *testing.T) {
+		d := &fallbackDriver{
+			ImageErrors: map[string]error{
+				"foo/bar-arm:0.1.0": driver.NewImageError("foo/bar-arm:0.1.0", errors.New("no matching manifest for platform")),
+			},
+		}
+		inst := New(d)
+		inst.FallbackInvocationImages = true
+
+		opResult, claimResult, err := inst.Run(newMultiImageClaim(), mockSet, out)
+		require.NoError(t, err)
+		require.NoError(t, opResult.Error)
+		assert.Equal(t, []string{"foo/bar-arm:0.1.0", "foo/bar:0.1.0"}, d.Attempted, "incompatible images should be skipped")
+		assert.Equal(t, "foo/bar:0.1.0", opResult.InvocationImage.Image, "the image that ran should be recorded")
+		assert.Equal(t, claim.StatusSucceeded, claimResult.Status)
+
+		img, ok := GetInvocationImage(claimResult)
+		require.True(t, ok, "the image that ran should be recorded on the claim result")
+		assert.Equal(t, "foo/bar:0.1.0", img.Image)
+
+		data, err := json.Marshal(claimResult)
+		require.NoError(t, err)
+		var loaded claim.Result
+		require.NoError(t, json.Unmarshal(data, &loaded))
+		loadedImg, ok := GetInvocationImage(loaded)
+		require.True(t, ok, "the image that ran should be recorded on a claim result loaded from a store")
+		assert.Equal(t, img, loadedImg)
+	})
+
+	t.Run("fallback disabled", func(t *testing.T) {
+		d := &fallbackDriver{
+			ImageErrors: map[string]error{
+				"foo/bar-arm:0.1.0": driver.NewImageError("foo/bar-arm:0.1.0", errors.New("no matching manifest for platform")),
+			},
+		}
+		inst := New(d)
+
+		opResult, claimResult, err := inst.Run(newMultiImageClaim(), mockSet, out)
+		require.NoError(t, err)
+		require.Error(t, opResult.Error)
+		assert.Equal(t, []string{"foo/bar-arm:0.1.0"}, d.Attempted)
+		assert.Equal(t, "foo/bar-arm:0.1.0", opResult.InvocationImage.Image)
+		assert.Equal(t, claim.StatusFailed, claimResult.Status)
+	})
+
+	t.Run("no fallback on bundle error", func(t *testing.T) {
+		d := &fallbackDriver{
+			ImageErrors: map[string]error{
+				"foo/bar-arm:0.1.0": errors.New("container exit code: 1"),
+			},
+		}
+		inst := New(d)
+		inst.FallbackInvocationImages = true
+
+		opResult, _, err := inst.Run(newMultiImageClaim(), mockSet, out)
+		require.NoError(t, err)
+		require.Contains(t, opResult.Error.Error(), "container exit code: 1")
+		assert.Equal(t, []string{"foo/bar-arm:0.1.0"}, d.Attempted)
+	})
+
+	t.Run("all images fail", func(t *testing.T) {
+		d := &fallbackDriver{
+			ImageErrors: map[string]error{
+				"foo/bar-arm:0.1.0": driver.NewImageError("foo/bar-arm:0.1.0", errors.New("pull failed")),
+				"foo/bar:0.1.0":     driver.NewImageError("foo/bar:0.1.0", errors.New("pull failed again")),
+			},
+		}
+		inst := New(d)
+		inst.FallbackInvocationImages = true
+
+		opResult, claimResult, err := inst.Run(newMultiImageClaim(), mockSet, out)
+		require.NoError(t, err)
+		require.Contains(t, opResult.Error.Error(), "pull failed again")
+		assert.Equal(t, []string{"foo/bar-arm:0.1.0", "foo/bar:0.1.0"}, d.Attempted)
+		assert.Equal(t, claim.StatusFailed, claimResult.Status)
+	})
+}
+
+func TestAction_RunAction_RuntimeRequirements(t *testing.T) {
+	out := func(op *driver.Operation) error {
+		op.Out = ioutil.Discard
+		return nil
+	}
+
+	newRequirementsClaim := func() claim.Claim {
+		c := newClaim(claim.ActionInstall)
+		c.Bundle.Custom = map[string]interface{}{
+			extensions.RuntimeRequirementsExtensionKey: map[string]interface{}{
+				"minimumVersion": "1.2.0",
+				"features":       []string{extensions.FeatureStreamingOutputs},
+			},
+		}
+		return c
+	}
+
+	t.Run("supported", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+		inst.Runtime = &extensions.RuntimeCapabilities{Version: "1.2.0", Features: []string{extensions.FeatureStreamingOutputs}}
+
+		_, claimResult, err := inst.Run(newRequirementsClaim(), mockSet, out)
+		require.NoError(t, err)
+		assert.Equal(t, claim.StatusSucceeded, claimResult.Status)
+		assert.NotNil(t, d.Operation, "expected the driver to run the operation")
+	})
+
+	t.Run("unsupported feature", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+		inst.Runtime = &extensions.RuntimeCapabilities{Version: "1.2.0"}
+
+		_, _, err := inst.Run(newRequirementsClaim(), mockSet, out)
+		require.EqualError(t, err, "the runtime cannot run the bundle: the bundle requires the unsupported runtime features streaming-outputs")
+		var unsupported extensions.UnsupportedRuntimeError
+		assert.True(t, errors.As(err, &unsupported), "expected an UnsupportedRuntimeError, got %T", err)
+		assert.Nil(t, d.Operation, "expected the driver to not run the operation")
+	})
+
+	t.Run("runtime capabilities not advertised", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+
+		_, _, err := inst.Run(newRequirementsClaim(), mockSet, out)
+		require.NoError(t, err)
+		assert.NotNil(t, d.Operation, "expected the requirements to not be checked")
+	})
+}
+
+func TestAction_RunAction_ImagePolicy(t *testing.T) {
+	out := func(op *driver.Operation) error {
+		op.Out = ioutil.Discard
+		return nil
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+		inst.ImagePolicy = &bundle.ImagePolicy{AllowedRepositories: []string{"foo/*"}}
+
+		_, claimResult, err := inst.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.NoError(t, err)
+		assert.Equal(t, claim.StatusSucceeded, claimResult.Status)
+	})
+
+	t.Run("violation", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+		inst.ImagePolicy = &bundle.ImagePolicy{AllowedRegistries: []string{"myregistry.example.com"}}
+
+		_, _, err := inst.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.EqualError(t, err, "the bundle violates the image policy: invocationImages[0] foo/bar:0.1.0: registry docker.io is not allowed; images.image-a foo/bar:0.1.0: registry docker.io is not allowed")
+		var policyErr *bundle.ImagePolicyError
+		assert.True(t, errors.As(err, &policyErr), "expected an ImagePolicyError, got %T", err)
+		assert.Nil(t, d.Operation, "expected the driver to not run the operation")
+	})
+}
+
 func TestBuildClaimResult(t *testing.T) {
 	t.Run("successful operation", func(t *testing.T) {
 		updatedClaim := newClaim(claim.ActionInstall)
@@ -939,9 +1130,9 @@ func TestBuildClaimResult(t *testing.T) {
 		}
 		opErr := &multierror.Error{}
 
-		claimResult, err := buildClaimResult(updatedClaim, opResult, opErr)
+		claimResult, err := BuildClaimResult(updatedClaim, opResult, opErr)
 
-		require.NoError(t, err, "buildClaimResult failed")
+		require.NoError(t, err, "BuildClaimResult failed")
 		assert.NoError(t, opErr.ErrorOrNil(), "an error was logged on the operational result")
 		assert.Equal(t, claim.StatusSucceeded, claimResult.Status, "the operation should have been recorded as a success")
 		assert.Empty(t, claimResult.Message, "an error message was recorded")
@@ -961,15 +1152,60 @@ func TestBuildClaimResult(t *testing.T) {
 			Errors: []error{errors.New("bundle failed")},
 		}
 
-		claimResult, err := buildClaimResult(updatedClaim, opResult, opErr)
+		claimResult, err := BuildClaimResult(updatedClaim, opResult, opErr)
 
-		require.NoError(t, err, "buildClaimResult failed")
+		require.NoError(t, err, "BuildClaimResult failed")
 		assert.Equal(t, claim.StatusFailed, claimResult.Status, "the operation should have been recorded as a failure")
 		assert.Contains(t, claimResult.Message, "bundle failed", "the operation error should have been recorded")
 		digest, ok := claimResult.OutputMetadata.GetContentDigest("some-output")
 		assert.True(t, ok, "the content digest for the output was not recorded")
 		assert.Equal(t, someContentDigest, digest, "the content digest for the output was invalid")
 	})
+
+	t.Run("interrupted operation", func(t *testing.T) {
+		updatedClaim := newClaim(claim.ActionInstall)
+		opErr := &multierror.Error{
+			Errors: []error{fmt.Errorf("job was deleted: %w", driver.ErrExecutionInterrupted)},
+		}
+
+		claimResult, err := BuildClaimResult(updatedClaim, driver.OperationResult{}, opErr)
+
+		require.NoError(t, err, "BuildClaimResult failed")
+		assert.Equal(t, claim.StatusCanceled, claimResult.Status, "the operation should have been recorded as canceled")
+		assert.Contains(t, claimResult.Message, "job was deleted", "the operation error should have been recorded")
+	})
+
+	t.Run("timed out operation", func(t *testing.T) {
+		updatedClaim := newClaim(claim.ActionInstall)
+		opErr := &multierror.Error{
+			Errors: []error{fmt.Errorf("job failed: %w", driver.ErrDeadlineExceeded)},
+		}
+
+		claimResult, err := BuildClaimResult(updatedClaim, driver.OperationResult{}, opErr)
+
+		require.NoError(t, err, "BuildClaimResult failed")
+		assert.Equal(t, claim.StatusFailed, claimResult.Status, "the operation should have been recorded as failed")
+		assert.Contains(t, claimResult.Message, "the operation timed out", "the timeout should have been recorded")
+		assert.Contains(t, claimResult.Message, "job failed", "the operation error should have been recorded")
+	})
+
+	t.Run("custom executor", func(t *testing.T) {
+		updatedClaim := newClaim(claim.ActionInstall)
+
+		claimResult, err := BuildClaimResult(updatedClaim, driver.OperationResult{}, nil)
+		require.NoError(t, err, "BuildClaimResult failed")
+		assert.Equal(t, claim.StatusSucceeded, claimResult.Status, "a nil error should be recorded as a success")
+
+		var noErrors *multierror.Error
+		claimResult, err = BuildClaimResult(updatedClaim, driver.OperationResult{}, noErrors)
+		require.NoError(t, err, "BuildClaimResult failed")
+		assert.Equal(t, claim.StatusSucceeded, claimResult.Status, "a nil multierror should be recorded as a success")
+
+		claimResult, err = BuildClaimResult(updatedClaim, driver.OperationResult{}, errors.New("remote executor failed"))
+		require.NoError(t, err, "BuildClaimResult failed")
+		assert.Equal(t, claim.StatusFailed, claimResult.Status, "the operation should have been recorded as a failure")
+		assert.Equal(t, "remote executor failed", claimResult.Message)
+	})
 }
 
 func TestGetOutputsGeneratedByAction(t *testing.T) {
@@ -1041,7 +1277,7 @@ func TestExpandCredentials(t *testing.T) {
 			"third":  "third",
 		}
 
-		env, path, err := expandCredentials(b, set, false, "install")
+		env, path, err := expandCredentials(b, set.Values(), false, "install")
 		is := assert.New(t)
 		is.NoError(err)
 		for k, v := range b.Credentials {
@@ -1067,9 +1303,9 @@ func TestExpandCredentials(t *testing.T) {
 			},
 		}
 		set := valuesource.Set{}
-		_, _, err := expandCredentials(b, set, false, "install")
+		_, _, err := expandCredentials(b, set.Values(), false, "install")
 		assert.EqualError(t, err, `credential "first" is missing from the user-supplied credentials`)
-		_, _, err = expandCredentials(b, set, true, "install")
+		_, _, err = expandCredentials(b, set.Values(), true, "install")
 		assert.NoError(t, err)
 	})
 
@@ -1085,9 +1321,9 @@ func TestExpandCredentials(t *testing.T) {
 			},
 		}
 		set := valuesource.Set{}
-		_, _, err := expandCredentials(b, set, false, "install")
+		_, _, err := expandCredentials(b, set.Values(), false, "install")
 		assert.NoError(t, err)
-		_, _, err = expandCredentials(b, set, true, "install")
+		_, _, err = expandCredentials(b, set.Values(), true, "install")
 		assert.NoError(t, err)
 	})
 
@@ -1105,9 +1341,38 @@ func TestExpandCredentials(t *testing.T) {
 			},
 		}
 		set := valuesource.Set{}
-		_, _, err := expandCredentials(b, set, false, "install")
+		_, _, err := expandCredentials(b, set.Values(), false, "install")
 		assert.EqualError(t, err, `credential "first" is missing from the user-supplied credentials`)
-		_, _, err = expandCredentials(b, set, false, "upgrade")
+		_, _, err = expandCredentials(b, set.Values(), false, "upgrade")
 		assert.NoError(t, err)
 	})
+
+	t.Run("typed creds", func(t *testing.T) {
+		b := bundle.Bundle{
+			Name: "knapsack",
+			Credentials: map[string]bundle.Credential{
+				"kubeconfig": {
+					Location: bundle.Location{
+						Path: "/root/.kube/config.json",
+					},
+				},
+				"token": {
+					Location: bundle.Location{
+						EnvironmentVariable: "TOKEN",
+					},
+				},
+			},
+		}
+		kubeconfig, err := valuesource.NewValue(map[string]interface{}{"apiVersion": "v1", "clusters": []string{"prod"}})
+		require.NoError(t, err)
+		set := valuesource.Values{
+			"kubeconfig": kubeconfig,
+			"token":      valuesource.StringValue("abc123"),
+		}
+
+		env, files, err := expandCredentials(b, set, false, "install")
+		require.NoError(t, err)
+		assert.Equal(t, `{"apiVersion":"v1","clusters":["prod"]}`, files["/root/.kube/config.json"], "objects should be injected as JSON")
+		assert.Equal(t, "abc123", env["TOKEN"], "strings should be injected as is")
+	})
 }
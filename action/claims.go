@@ -0,0 +1,86 @@
+package action
+
+import (
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// SaveInitialClaim persists a new claim, with a result of the specified
+// status, such as claim.StatusPending, before its operation is run.
+//
+// When LockLease is set, the installation is locked first, with the claim ID
+// as the owner, and the lock is returned. It must be released with
+// claim.Provider.ReleaseInstallationLock once the result of the operation is
+// saved, otherwise the installation remains locked until the lease expires.
+// When the installation is locked by another operation, an error matching
+// claim.ErrInstallationLocked is returned and nothing is saved.
+func (a Action) SaveInitialClaim(c claim.Claim, status string) (claim.InstallationLock, error) {
+	if a.Claims == nil {
+		return claim.InstallationLock{}, errors.New("the action claim provider is not set")
+	}
+
+	var lock claim.InstallationLock
+	if a.LockLease > 0 {
+		var err error
+		lock, err = a.Claims.AcquireInstallationLock(c.Installation, c.ID, a.LockLease)
+		if err != nil {
+			return claim.InstallationLock{}, err
+		}
+	}
+
+	if err := a.saveInitialClaim(c, status); err != nil {
+		var result *multierror.Error
+		result = multierror.Append(result, err)
+		if releaseErr := a.Claims.ReleaseInstallationLock(lock); releaseErr != nil {
+			result = multierror.Append(result, releaseErr)
+		}
+		return claim.InstallationLock{}, result.ErrorOrNil()
+	}
+	return lock, nil
+}
+
+func (a Action) saveInitialClaim(c claim.Claim, status string) error {
+	if err := a.Claims.SaveClaim(c); err != nil {
+		return errors.Wrapf(err, "error saving claim %s", c.ID)
+	}
+
+	r, err := c.NewResult(status)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(a.Claims.SaveResult(r), "error saving the result of claim %s", c.ID)
+}
+
+// SaveOperationResult persists the result of the operation run for the claim
+// and its outputs. When Spool is set, they are journaled to the spool first,
+// and an error matching claim.ErrResultSpooled is returned when they could
+// not be saved to the claim provider yet; they are saved when the spool is
+// flushed.
+func (a Action) SaveOperationResult(opResult driver.OperationResult, c claim.Claim, r claim.Result) error {
+	outputs := make(map[string][]byte, len(opResult.Outputs))
+	for name, value := range opResult.Outputs {
+		outputs[name] = []byte(value)
+	}
+
+	if a.Spool != nil {
+		return a.Spool.Save(c, r, outputs)
+	}
+
+	if a.Claims == nil {
+		return errors.New("the action claim provider is not set")
+	}
+
+	var result *multierror.Error
+	if err := a.Claims.SaveResult(r); err != nil {
+		result = multierror.Append(result, errors.Wrapf(err, "error saving the result of claim %s", c.ID))
+	}
+	for name, value := range outputs {
+		if err := a.Claims.SaveOutput(claim.NewOutput(c, r, name, value)); err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "error saving output %s of claim %s", name, c.ID))
+		}
+	}
+	return result.ErrorOrNil()
+}
@@ -0,0 +1,105 @@
+package action
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestAction_SaveInitialClaim(t *testing.T) {
+	t.Run("no claim provider", func(t *testing.T) {
+		a := New(&mockDriver{})
+		_, err := a.SaveInitialClaim(newClaim(claim.ActionInstall), claim.StatusPending)
+		assert.EqualError(t, err, "the action claim provider is not set")
+	})
+
+	t.Run("without lock", func(t *testing.T) {
+		store := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+		a := New(&mockDriver{})
+		a.Claims = store
+
+		c := newClaim(claim.ActionInstall)
+		lock, err := a.SaveInitialClaim(c, claim.StatusPending)
+		require.NoError(t, err)
+		assert.True(t, lock.IsZero(), "the installation should not be locked")
+
+		r, err := store.ReadLastResult(c.ID)
+		require.NoError(t, err)
+		assert.Equal(t, claim.StatusPending, r.Status)
+	})
+
+	t.Run("with lock", func(t *testing.T) {
+		store := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+		a := New(&mockDriver{})
+		a.Claims = store
+		a.LockLease = time.Minute
+
+		c := newClaim(claim.ActionInstall)
+		lock, err := a.SaveInitialClaim(c, claim.StatusPending)
+		require.NoError(t, err)
+		assert.Equal(t, c.ID, lock.Owner)
+
+		// A concurrent operation on the same installation is rejected
+		upgrade, err := c.NewClaim(claim.ActionUpgrade, c.Bundle, nil)
+		require.NoError(t, err)
+		_, err = a.SaveInitialClaim(upgrade, claim.StatusPending)
+		assert.ErrorIs(t, err, claim.ErrInstallationLocked)
+		_, err = store.ReadClaim(upgrade.ID)
+		assert.ErrorIs(t, err, claim.ErrClaimNotFound, "the claim of the rejected operation should not be saved")
+
+		require.NoError(t, store.ReleaseInstallationLock(lock))
+		_, err = a.SaveInitialClaim(upgrade, claim.StatusPending)
+		require.NoError(t, err, "the operation should run once the lock is released")
+	})
+}
+
+func TestAction_SaveOperationResult(t *testing.T) {
+	opResult := driver.OperationResult{Outputs: map[string]string{"some-output": "some value"}}
+
+	t.Run("no claim provider", func(t *testing.T) {
+		a := New(&mockDriver{})
+		c := newClaim(claim.ActionInstall)
+		r, err := c.NewResult(claim.StatusSucceeded)
+		require.NoError(t, err)
+		assert.EqualError(t, a.SaveOperationResult(opResult, c, r), "the action claim provider is not set")
+	})
+
+	t.Run("without spool", func(t *testing.T) {
+		store := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+		a := New(&mockDriver{})
+		a.Claims = store
+
+		c := newClaim(claim.ActionInstall)
+		r, err := c.NewResult(claim.StatusSucceeded)
+		require.NoError(t, err)
+		require.NoError(t, a.SaveOperationResult(opResult, c, r))
+
+		o, err := store.ReadOutput(c, r, "some-output")
+		require.NoError(t, err)
+		assert.Equal(t, "some value", string(o.Value))
+	})
+
+	t.Run("with spool", func(t *testing.T) {
+		store := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+		a := New(&mockDriver{})
+		a.Spool = claim.NewSpool(crud.NewMockStore(), store, claim.SpoolOptions{})
+
+		c := newClaim(claim.ActionInstall)
+		r, err := c.NewResult(claim.StatusSucceeded)
+		require.NoError(t, err)
+		require.NoError(t, a.SaveOperationResult(opResult, c, r))
+
+		saved, err := store.ReadResult(r.ID)
+		require.NoError(t, err)
+		assert.Equal(t, claim.StatusSucceeded, saved.Status)
+		o, err := store.ReadOutput(c, r, "some-output")
+		require.NoError(t, err)
+		assert.Equal(t, "some value", string(o.Value))
+	})
+}
@@ -0,0 +1,155 @@
+package action
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/cnabio/cnab-go/valuesource"
+)
+
+// DryRunReport describes the operation that Run would execute for a claim,
+// with the values of its credentials and sensitive parameters redacted.
+type DryRunReport struct {
+	// Installation is the name of the installation.
+	Installation string `json:"installation"`
+
+	// Revision of the installation created by the operation.
+	Revision string `json:"revision"`
+
+	// Action that would be performed.
+	Action string `json:"action"`
+
+	// Image is the invocation image that would be run.
+	Image bundle.InvocationImage `json:"image"`
+
+	// Environment variables injected into the invocation image.
+	Environment map[string]string `json:"environment"`
+
+	// Files injected into the invocation image, keyed by their path.
+	Files map[string]string `json:"files"`
+
+	// Parameters of the operation.
+	Parameters map[string]interface{} `json:"parameters"`
+
+	// Outputs maps the name of each output that the operation is expected
+	// to generate to its path in the invocation image.
+	Outputs map[string]string `json:"outputs"`
+
+	// SensitiveEnvironment are the names of the environment variables whose
+	// values are redacted.
+	SensitiveEnvironment []string `json:"sensitiveEnvironment,omitempty"`
+
+	// SensitiveFiles are the paths of the files whose contents are redacted.
+	SensitiveFiles []string `json:"sensitiveFiles,omitempty"`
+
+	// SensitiveParameters are the names of the parameters whose values are
+	// redacted.
+	SensitiveParameters []string `json:"sensitiveParameters,omitempty"`
+}
+
+// DryRun resolves the claim, credentials and parameters into the operation
+// that Run would pass to the driver, and describes it without running it.
+// It implements the io.cnab.dry-run well-known action for any bundle, see
+// ActionDryRun, although the bundle cannot report what it would change.
+func (a Action) DryRun(c claim.Claim, creds valuesource.Set, opCfgs ...OperationConfigFunc) (DryRunReport, error) {
+	if a.Driver == nil {
+		return DryRunReport{}, errors.New("the action driver is not set")
+	}
+
+	err := a.checkClaim(c)
+	if err != nil {
+		return DryRunReport{}, err
+	}
+
+	invocImage, err := a.selectInvocationImage(c)
+	if err != nil {
+		return DryRunReport{}, err
+	}
+
+	op, err := opFromClaim(stateful, c, invocImage, creds.Values())
+	if err != nil {
+		return DryRunReport{}, err
+	}
+
+	err = OperationConfigs(opCfgs).ApplyConfig(op)
+	if err != nil {
+		return DryRunReport{}, err
+	}
+
+	return newDryRunReport(op)
+}
+
+// newDryRunReport describes the operation, redacting its sensitive values.
+func newDryRunReport(op *driver.Operation) (DryRunReport, error) {
+	snapshot, err := NewOperationSnapshot(op, nil)
+	if err != nil {
+		return DryRunReport{}, err
+	}
+
+	redacted := snapshot.Operation
+	report := DryRunReport{
+		Installation:         redacted.Installation,
+		Revision:             redacted.Revision,
+		Action:               redacted.Action,
+		Image:                redacted.Image,
+		Environment:          redacted.Environment,
+		Files:                redacted.Files,
+		Parameters:           redacted.Parameters,
+		Outputs:              make(map[string]string, len(redacted.Outputs)),
+		SensitiveEnvironment: snapshot.SensitiveEnvironment,
+		SensitiveFiles:       snapshot.SensitiveFiles,
+		SensitiveParameters:  snapshot.SensitiveParameters,
+	}
+	for path, name := range redacted.Outputs {
+		report.Outputs[name] = path
+	}
+	return report, nil
+}
+
+// String renders the report for a person to review.
+func (r DryRunReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Action: %s\n", r.Action)
+	fmt.Fprintf(&b, "Installation: %s\n", r.Installation)
+	fmt.Fprintf(&b, "Revision: %s\n", r.Revision)
+	fmt.Fprintf(&b, "Invocation image: %s (%s)\n", r.Image.Image, r.Image.ImageType)
+
+	fmt.Fprintln(&b, "Environment:")
+	for _, name := range sortedKeys(r.Environment) {
+		fmt.Fprintf(&b, "  %s=%s\n", name, r.Environment[name])
+	}
+
+	sensitiveFiles := make(map[string]bool, len(r.SensitiveFiles))
+	for _, path := range r.SensitiveFiles {
+		sensitiveFiles[path] = true
+	}
+	fmt.Fprintln(&b, "Files:")
+	for _, path := range sortedKeys(r.Files) {
+		if sensitiveFiles[path] {
+			fmt.Fprintf(&b, "  %s (redacted)\n", path)
+		} else {
+			fmt.Fprintf(&b, "  %s (%d bytes)\n", path, len(r.Files[path]))
+		}
+	}
+
+	fmt.Fprintln(&b, "Outputs:")
+	for _, name := range sortedKeys(r.Outputs) {
+		fmt.Fprintf(&b, "  %s (%s)\n", name, r.Outputs[name])
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
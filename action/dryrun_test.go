@@ -0,0 +1,73 @@
+package action
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestAction_DryRun(t *testing.T) {
+	writeOnly := true
+	c := newClaim(claim.ActionInstall)
+	c.Bundle.Definitions["Password"] = &definition.Schema{Type: "string", WriteOnly: &writeOnly}
+	c.Bundle.Parameters["password"] = bundle.Parameter{Definition: "Password"}
+	c.Parameters = map[string]interface{}{"password": "hunter2", "param_one": "one"}
+
+	d := &mockDriver{shouldHandle: true}
+	a := New(d)
+
+	report, err := a.DryRun(c, mockSet, func(op *driver.Operation) error {
+		op.Environment["EXTRA"] = "configured"
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Nil(t, d.Operation, "the driver should not run the operation")
+
+	assert.Equal(t, "name", report.Installation)
+	assert.Equal(t, "revision", report.Revision)
+	assert.Equal(t, claim.ActionInstall, report.Action)
+	assert.Equal(t, "foo/bar:0.1.0", report.Image.Image)
+	assert.Equal(t, "configured", report.Environment["EXTRA"], "operation configs should be applied")
+	assert.Equal(t, "one", report.Environment["CNAB_P_PARAM_ONE"])
+	assert.Equal(t, "******", report.Environment["CNAB_P_PASSWORD"])
+	assert.Equal(t, "******", report.Environment["SECRET_ONE"])
+	assert.Equal(t, "******", report.Files["/foo/bar"])
+	assert.Equal(t, "******", report.Parameters["password"])
+	assert.Equal(t, []string{"CNAB_P_PASSWORD", "SECRET_ONE", "SECRET_TWO"}, report.SensitiveEnvironment)
+	assert.Equal(t, []string{"/foo/bar", "/secret/two"}, report.SensitiveFiles)
+	assert.Equal(t, []string{"password"}, report.SensitiveParameters)
+	assert.Equal(t, "/tmp/some/path", report.Outputs["some-output"])
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2", "sensitive parameters should be redacted")
+	assert.NotContains(t, string(data), "I'm a secret", "credentials should be redacted")
+
+	rendered := report.String()
+	assert.Contains(t, rendered, "Action: install\n")
+	assert.Contains(t, rendered, "Invocation image: foo/bar:0.1.0 (docker)\n")
+	assert.Contains(t, rendered, "  SECRET_ONE=******\n")
+	assert.Contains(t, rendered, "  /foo/bar (redacted)\n")
+	assert.Contains(t, rendered, "  some-output (/tmp/some/path)\n")
+	assert.NotContains(t, rendered, "hunter2")
+
+	t.Run("image policy", func(t *testing.T) {
+		a := New(&mockDriver{shouldHandle: true})
+		a.ImagePolicy = &bundle.ImagePolicy{AllowedRegistries: []string{"myregistry.example.com"}}
+		_, err := a.DryRun(c, mockSet)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the bundle violates the image policy")
+	})
+
+	t.Run("incompatible driver", func(t *testing.T) {
+		_, err := New(&mockDriver{shouldHandle: false}).DryRun(c, mockSet)
+		require.EqualError(t, err, "driver is not compatible with any of the invocation images in the bundle")
+	})
+}
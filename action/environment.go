@@ -0,0 +1,43 @@
+package action
+
+import (
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// ResultExecutionEnvironmentKey is the key of the driver.ExecutionEnvironment
+// recorded in the custom section of the result of each operation run by an
+// Action, describing the driver and the server that ran the operation.
+const ResultExecutionEnvironmentKey = "io.cnab.execution-environment"
+
+// setEnvironmentOnClaimResult records the environment that ran the operation
+// in the custom section of the result. Custom data of another type set on the
+// result is left as is.
+func setEnvironmentOnClaimResult(result *claim.Result, env driver.ExecutionEnvironment) {
+	setCustomValueOnClaimResult(result, ResultExecutionEnvironmentKey, env)
+}
+
+// GetExecutionEnvironment returns the environment recorded in the result of an
+// operation run by an Action, and whether it was recorded. The result may
+// have been loaded from a store, where the environment is decoded as a map.
+func GetExecutionEnvironment(result claim.Result) (driver.ExecutionEnvironment, bool) {
+	var env driver.ExecutionEnvironment
+	ok := getCustomValueFromClaimResult(result, ResultExecutionEnvironmentKey, &env)
+	return env, ok
+}
+
+// setCustomValueOnClaimResult records the value under the key in the custom
+// section of the result. Custom data that is not a JSON object is left as is.
+func setCustomValueOnClaimResult(result *claim.Result, key string, value interface{}) {
+	// The value is informational, so it is not recorded rather than failing
+	// the operation when the custom section is not a JSON object
+	_ = result.SetCustomValue(key, value)
+}
+
+// getCustomValueFromClaimResult decodes the value recorded under the key in
+// the custom section of the result into v, and returns whether it was
+// recorded.
+func getCustomValueFromClaimResult(result claim.Result, key string, v interface{}) bool {
+	ok, err := result.GetCustomValue(key, v)
+	return ok && err == nil
+}
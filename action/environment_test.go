@@ -0,0 +1,56 @@
+package action
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestAction_Run_RecordsExecutionEnvironment(t *testing.T) {
+	c := newClaim(claim.ActionInstall)
+	a := New(&mockDriver{shouldHandle: true})
+
+	_, result, err := a.Run(c, mockSet)
+	require.NoError(t, err)
+
+	env, ok := GetExecutionEnvironment(result)
+	require.True(t, ok, "the execution environment should be recorded on the result")
+	assert.Equal(t, "*action.mockDriver", env.Driver)
+	assert.Equal(t, runtime.GOOS, env.HostOS)
+	assert.Equal(t, runtime.GOARCH, env.HostArch)
+
+	t.Run("loaded from a store", func(t *testing.T) {
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		var loaded claim.Result
+		require.NoError(t, json.Unmarshal(data, &loaded))
+
+		loadedEnv, ok := GetExecutionEnvironment(loaded)
+		require.True(t, ok)
+		assert.Equal(t, env, loadedEnv)
+	})
+}
+
+func TestSetEnvironmentOnClaimResult(t *testing.T) {
+	env := driver.ExecutionEnvironment{Driver: "docker"}
+
+	t.Run("existing custom data", func(t *testing.T) {
+		result := claim.Result{Custom: map[string]interface{}{"foo": "bar"}}
+		setEnvironmentOnClaimResult(&result, env)
+		assert.Equal(t, map[string]interface{}{"foo": "bar", ResultExecutionEnvironmentKey: env}, result.Custom)
+	})
+
+	t.Run("custom data of another type", func(t *testing.T) {
+		result := claim.Result{Custom: "foo"}
+		setEnvironmentOnClaimResult(&result, env)
+		assert.Equal(t, "foo", result.Custom)
+		_, ok := GetExecutionEnvironment(result)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,100 @@
+package action
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle/extensions"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/cnabio/cnab-go/valuesource"
+)
+
+// HookedRun records the execution of an action, or of one of its hooks, by
+// RunWithHooks.
+type HookedRun struct {
+	// Hook is extensions.HookPre or extensions.HookPost when the run is a
+	// hook, and is empty for the action itself.
+	Hook string
+
+	// Claim of the run. The claims of hooks link to the claim of the action
+	// with an extensions.HookLink in their custom section.
+	Claim claim.Claim
+
+	// Result of the run.
+	Result claim.Result
+
+	// OperationResult of the run, with the outputs that it generated.
+	OperationResult driver.OperationResult
+}
+
+// RunWithHooks runs the action of the claim, along with the hooks declared
+// for it by the bundle with the extensions.HooksExtensionKey extension: the
+// pre hooks are run before the action, in order, and the post hooks after
+// the action succeeded. Each hook is recorded with its own claim and result,
+// which the caller is responsible for persisting along with those of the
+// action, in the order that they are returned.
+//
+// When a hook or the action fails, the remaining runs are skipped and an
+// error is returned along with the runs that were executed.
+func (a Action) RunWithHooks(c claim.Claim, creds valuesource.Set, opCfgs ...OperationConfigFunc) ([]HookedRun, error) {
+	hooks, err := extensions.ReadHooks(c.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	if err := hooks.Validate(c.Bundle); err != nil {
+		return nil, errors.Wrap(err, "invalid hooks")
+	}
+
+	var runs []HookedRun
+	for _, hook := range hooks.PreHooks(c.Action) {
+		run, err := a.runHook(c, extensions.HookPre, hook, creds, opCfgs)
+		runs = append(runs, run)
+		if err != nil {
+			return runs, errors.Wrapf(err, "the %s action was not run", c.Action)
+		}
+	}
+
+	opResult, result, err := a.Run(c, creds, opCfgs...)
+	if err != nil {
+		return runs, err
+	}
+	runs = append(runs, HookedRun{Claim: c, Result: result, OperationResult: opResult})
+	if opResult.Error != nil {
+		return runs, errors.Wrapf(opResult.Error, "the %s action failed", c.Action)
+	}
+
+	for _, hook := range hooks.PostHooks(c.Action) {
+		run, err := a.runHook(c, extensions.HookPost, hook, creds, opCfgs)
+		runs = append(runs, run)
+		if err != nil {
+			return runs, err
+		}
+	}
+
+	return runs, nil
+}
+
+// runHook runs a hook of the claim's action with a new claim.
+func (a Action) runHook(c claim.Claim, hook string, hookAction string, creds valuesource.Set, opCfgs []OperationConfigFunc) (HookedRun, error) {
+	hookClaim, err := c.NewClaim(hookAction, c.Bundle, c.Parameters)
+	if err != nil {
+		return HookedRun{}, errors.Wrapf(err, "could not create the claim of the %s hook %s", hook, hookAction)
+	}
+	hookClaim.Custom = map[string]interface{}{
+		extensions.HooksExtensionKey: extensions.HookLink{
+			Hook:    hook,
+			Action:  c.Action,
+			ClaimID: c.ID,
+		},
+	}
+
+	opResult, result, err := a.Run(hookClaim, creds, opCfgs...)
+	run := HookedRun{Hook: hook, Claim: hookClaim, Result: result, OperationResult: opResult}
+	if err != nil {
+		return run, errors.Wrapf(err, "could not run the %s hook %s", hook, hookAction)
+	}
+	if opResult.Error != nil {
+		return run, errors.Wrapf(opResult.Error, "the %s hook %s failed", hook, hookAction)
+	}
+	return run, nil
+}
@@ -0,0 +1,110 @@
+package action
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/extensions"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// actionDriver records the actions that it runs and fails those in Failures.
+type actionDriver struct {
+	Ran      []string
+	Failures map[string]error
+}
+
+func (d *actionDriver) Handles(imageType string) bool {
+	return true
+}
+
+func (d *actionDriver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	d.Ran = append(d.Ran, op.Action)
+	return driver.OperationResult{}, d.Failures[op.Action]
+}
+
+func newHookedClaim() claim.Claim {
+	c := newClaim(claim.ActionUpgrade)
+	c.ID = claim.MustNewULID()
+	c.Bundle.Actions = map[string]bundle.Action{
+		"backup":     {},
+		"snapshot":   {},
+		"smoke-test": {},
+	}
+	c.Bundle.Custom = map[string]interface{}{
+		extensions.HooksExtensionKey: extensions.Hooks{
+			Pre:  map[string][]string{claim.ActionUpgrade: {"backup", "snapshot"}},
+			Post: map[string][]string{claim.ActionUpgrade: {"smoke-test"}},
+		},
+	}
+	return c
+}
+
+func TestAction_RunWithHooks(t *testing.T) {
+	t.Run("runs the hooks around the action", func(t *testing.T) {
+		c := newHookedClaim()
+		d := &actionDriver{}
+		a := New(d)
+
+		runs, err := a.RunWithHooks(c, mockSet)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"backup", "snapshot", claim.ActionUpgrade, "smoke-test"}, d.Ran)
+		require.Len(t, runs, 4)
+
+		assert.Equal(t, extensions.HookPre, runs[0].Hook)
+		assert.Equal(t, "backup", runs[0].Claim.Action)
+		assert.NotEqual(t, c.ID, runs[0].Claim.ID)
+		assert.Equal(t, extensions.HookLink{Hook: extensions.HookPre, Action: claim.ActionUpgrade, ClaimID: c.ID},
+			runs[0].Claim.Custom.(map[string]interface{})[extensions.HooksExtensionKey])
+		assert.Equal(t, runs[0].Claim.ID, runs[0].Result.ClaimID)
+		assert.Equal(t, claim.StatusSucceeded, runs[0].Result.Status)
+
+		assert.Empty(t, runs[2].Hook)
+		assert.Equal(t, c.ID, runs[2].Claim.ID)
+		assert.Equal(t, extensions.HookPost, runs[3].Hook)
+		assert.Equal(t, "smoke-test", runs[3].Claim.Action)
+	})
+
+	t.Run("failed pre hook skips the action", func(t *testing.T) {
+		c := newHookedClaim()
+		d := &actionDriver{Failures: map[string]error{"backup": errors.New("disk full")}}
+		a := New(d)
+
+		runs, err := a.RunWithHooks(c, mockSet)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the upgrade action was not run")
+		assert.Contains(t, err.Error(), "disk full")
+		assert.Equal(t, []string{"backup"}, d.Ran)
+		require.Len(t, runs, 1)
+		assert.Equal(t, claim.StatusFailed, runs[0].Result.Status)
+	})
+
+	t.Run("failed action skips the post hooks", func(t *testing.T) {
+		c := newHookedClaim()
+		d := &actionDriver{Failures: map[string]error{claim.ActionUpgrade: errors.New("oops")}}
+		a := New(d)
+
+		runs, err := a.RunWithHooks(c, mockSet)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the upgrade action failed")
+		assert.Equal(t, []string{"backup", "snapshot", claim.ActionUpgrade}, d.Ran)
+		require.Len(t, runs, 3)
+		assert.Equal(t, claim.StatusFailed, runs[2].Result.Status)
+	})
+
+	t.Run("invalid hooks", func(t *testing.T) {
+		c := newHookedClaim()
+		delete(c.Bundle.Actions, "backup")
+		d := &actionDriver{}
+		a := New(d)
+
+		_, err := a.RunWithHooks(c, mockSet)
+		assert.EqualError(t, err, `invalid hooks: hook "backup" of action "upgrade" is not a custom action defined in the bundle`)
+		assert.Empty(t, d.Ran)
+	})
+}
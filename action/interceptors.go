@@ -0,0 +1,75 @@
+package action
+
+import (
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// RunFunc runs an operation, such as the Run function of a driver.Driver.
+type RunFunc func(op *driver.Operation) (driver.OperationResult, error)
+
+// Interceptor wraps the execution of an operation by the action's driver. It
+// should call next to continue the chain and eventually run the operation,
+// and may inspect or modify the operation before it runs and the result after
+// it completes. Returning without calling next prevents the operation from
+// running, for example when a policy check fails.
+//
+// Interceptors are useful for telemetry, audit logging, parameter redaction
+// or policy checks, without wrapping the driver.Driver interface.
+type Interceptor func(op *driver.Operation, next RunFunc) (driver.OperationResult, error)
+
+// BeforeOperationHook is called before the driver runs an operation. Returning
+// an error prevents the operation from running and the error is recorded on
+// the operation result.
+type BeforeOperationHook func(op *driver.Operation) error
+
+// AfterOperationHook is called after the driver runs an operation, with the
+// result and the error returned by the driver. The result may be modified, and
+// an error returned is recorded on the operation result, along with any
+// error from the driver.
+type AfterOperationHook func(op *driver.Operation, result *driver.OperationResult, err error) error
+
+// BeforeOperation creates an Interceptor that calls the hook before the
+// operation runs.
+func BeforeOperation(hook BeforeOperationHook) Interceptor {
+	return func(op *driver.Operation, next RunFunc) (driver.OperationResult, error) {
+		if err := hook(op); err != nil {
+			return driver.OperationResult{}, err
+		}
+		return next(op)
+	}
+}
+
+// AfterOperation creates an Interceptor that calls the hook after the
+// operation runs, even when it failed.
+func AfterOperation(hook AfterOperationHook) Interceptor {
+	return func(op *driver.Operation, next RunFunc) (driver.OperationResult, error) {
+		result, err := next(op)
+		if hookErr := hook(op, &result, err); hookErr != nil {
+			if err != nil {
+				return result, multierror.Append(err, hookErr)
+			}
+			return result, hookErr
+		}
+		return result, err
+	}
+}
+
+// Use registers interceptors that are called, in the order that they are
+// registered, around each execution of the driver.
+func (a *Action) Use(interceptors ...Interceptor) {
+	a.Interceptors = append(a.Interceptors, interceptors...)
+}
+
+// runDriver runs the operation with the driver, wrapped by the interceptors.
+func (a Action) runDriver(op *driver.Operation) (driver.OperationResult, error) {
+	run := RunFunc(a.Driver.Run)
+	for i := len(a.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := a.Interceptors[i], run
+		run = func(op *driver.Operation) (driver.OperationResult, error) {
+			return interceptor(op, next)
+		}
+	}
+	return run(op)
+}
@@ -0,0 +1,153 @@
+package action
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestAction_Interceptors(t *testing.T) {
+	out := func(op *driver.Operation) error {
+		op.Out = ioutil.Discard
+		return nil
+	}
+
+	t.Run("called in order around the driver", func(t *testing.T) {
+		var calls []string
+		record := func(name string) Interceptor {
+			return func(op *driver.Operation, next RunFunc) (driver.OperationResult, error) {
+				calls = append(calls, "before "+name)
+				result, err := next(op)
+				calls = append(calls, "after "+name)
+				return result, err
+			}
+		}
+
+		d := &mockDriver{shouldHandle: true}
+		a := New(d)
+		a.Use(record("first"), record("second"))
+		a.Use(BeforeOperation(func(op *driver.Operation) error {
+			op.Environment["INJECTED"] = "true"
+			return nil
+		}))
+
+		_, _, err := a.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"before first", "before second", "after second", "after first"}, calls)
+		assert.Equal(t, "true", d.Operation.Environment["INJECTED"], "the before hook should be able to modify the operation")
+	})
+
+	t.Run("before hook prevents the operation", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		a := New(d)
+		a.Use(BeforeOperation(func(op *driver.Operation) error {
+			return errors.New("installation is not allowed by policy")
+		}))
+
+		opResult, claimResult, err := a.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.NoError(t, err)
+		assert.Nil(t, d.Operation, "the driver should not have been called")
+		require.Error(t, opResult.Error)
+		assert.Contains(t, opResult.Error.Error(), "installation is not allowed by policy")
+		assert.Equal(t, claim.StatusFailed, claimResult.Status)
+	})
+
+	t.Run("after hook sees the result", func(t *testing.T) {
+		d := &mockDriver{
+			shouldHandle: true,
+			Result:       driver.OperationResult{Outputs: map[string]string{"some-output": someContent}},
+			Error:        errors.New("bundle failed"),
+		}
+		a := New(d)
+
+		var gotErr error
+		a.Use(AfterOperation(func(op *driver.Operation, result *driver.OperationResult, err error) error {
+			gotErr = err
+			result.Outputs["some-output"] = "REDACTED"
+			return errors.New("audit log unavailable")
+		}))
+
+		opResult, _, err := a.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.NoError(t, err)
+		assert.EqualError(t, gotErr, "bundle failed")
+		assert.Equal(t, "REDACTED", opResult.Outputs["some-output"])
+		require.Error(t, opResult.Error)
+		assert.Contains(t, opResult.Error.Error(), "bundle failed")
+		assert.Contains(t, opResult.Error.Error(), "audit log unavailable")
+	})
+}
+
+func TestAction_Events(t *testing.T) {
+	out := func(op *driver.Operation) error {
+		op.Out = ioutil.Discard
+		return nil
+	}
+
+	var events []driver.Event
+	d := &mockDriver{
+		shouldHandle: true,
+		Result: driver.OperationResult{
+			Outputs: map[string]string{
+				"some-output":       someContent,
+				"hello-output":      someContent,
+				"some-other-output": someContent,
+			},
+		},
+	}
+	a := New(d)
+	a.Events = func(e driver.Event) {
+		events = append(events, e)
+	}
+
+	c := newClaim(claim.ActionInstall)
+	_, _, err := a.Run(c, mockSet, out)
+	require.NoError(t, err)
+
+	var types []driver.EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+		assert.Equal(t, c.Installation, e.Installation)
+		assert.Equal(t, claim.ActionInstall, e.Action)
+	}
+	assert.Equal(t, []driver.EventType{
+		driver.EventOperationStarted,
+		driver.EventLogsChunk,
+		driver.EventOutputCaptured,
+		driver.EventOutputCaptured,
+		driver.EventOutputCaptured,
+		driver.EventOperationCompleted,
+	}, types)
+
+	assert.Equal(t, driver.StreamStdout, events[1].Stream)
+	assert.Equal(t, "mocked running the bundle\n", string(events[1].Data))
+	assert.Equal(t, "hello-output", events[2].Output)
+	assert.Equal(t, "some-other-output", events[3].Output)
+	assert.Equal(t, "some-output", events[4].Output)
+	assert.NoError(t, events[5].Error)
+
+	t.Run("operation handler", func(t *testing.T) {
+		var opEvents, actionEvents int
+		a := New(&mockDriver{shouldHandle: true, Error: errors.New("I always fail")})
+		a.Events = func(e driver.Event) { actionEvents++ }
+		opCfg := func(op *driver.Operation) error {
+			op.Events = func(e driver.Event) {
+				opEvents++
+				if e.Type == driver.EventOperationCompleted {
+					assert.EqualError(t, e.Error, "I always fail")
+				}
+			}
+			return nil
+		}
+
+		_, _, err := a.Run(newClaim(claim.ActionInstall), mockSet, out, opCfg)
+		require.NoError(t, err)
+		assert.Equal(t, 3, opEvents)
+		assert.Equal(t, 3, actionEvents, "both the operation and action handlers should receive the events")
+	})
+}
@@ -1,8 +1,10 @@
 package action
 
 import (
+	"io"
 	"os"
 
+	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/driver"
 )
 
@@ -10,6 +12,27 @@ import (
 // operation.
 type OperationConfigFunc func(op *driver.Operation) error
 
+// WithRelocationMapping runs the operation with the images of the bundle
+// relocated to another registry, rewriting the invocation image and the image
+// map, and injecting the mapping into the invocation image at
+// bundle.RelocationMappingPath.
+func WithRelocationMapping(m bundle.RelocationMapping) OperationConfigFunc {
+	return func(op *driver.Operation) error {
+		return op.ApplyRelocationMapping(m)
+	}
+}
+
+// WithStdin streams the input to the standard input of the invocation image,
+// for interactive actions such as prompts or debug shells. The action fails
+// with an error matching driver.ErrStdinNotSupported when the driver cannot
+// stream it.
+func WithStdin(in io.Reader) OperationConfigFunc {
+	return func(op *driver.Operation) error {
+		op.In = in
+		return nil
+	}
+}
+
 // OperationConfigs is a set of configuration functions that can be applied as a
 // unit to an operation.
 type OperationConfigs []OperationConfigFunc
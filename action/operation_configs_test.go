@@ -4,11 +4,14 @@ import (
 	"errors"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/claim"
 	"github.com/cnabio/cnab-go/driver"
 )
 
@@ -62,3 +65,58 @@ func TestOperationConfigs_ApplyConfig(t *testing.T) {
 		require.Nil(t, op.Out, "Changes from the second config function should not have been applied")
 	})
 }
+
+func TestWithRelocationMapping(t *testing.T) {
+	d := &mockDriver{shouldHandle: true}
+	a := New(d)
+	m := bundle.RelocationMapping{"foo/bar:0.1.0": "registry.example.com/foo/bar:0.1.0"}
+
+	opResult, _, err := a.Run(newClaim(claim.ActionInstall), mockSet, func(op *driver.Operation) error {
+		op.Out = io.Discard
+		return nil
+	}, WithRelocationMapping(m))
+	require.NoError(t, err)
+	require.NoError(t, opResult.Error)
+
+	require.NotNil(t, d.Operation)
+	assert.Equal(t, "registry.example.com/foo/bar:0.1.0", d.Operation.Image.Image)
+	assert.Equal(t, `{"foo/bar:0.1.0":"registry.example.com/foo/bar:0.1.0"}`, d.Operation.Files[bundle.RelocationMappingPath])
+	assert.Equal(t, "registry.example.com/foo/bar:0.1.0", opResult.InvocationImage.Image)
+
+	_, _, err = a.Run(newClaim(claim.ActionInstall), mockSet, WithRelocationMapping(bundle.RelocationMapping{"unknown:v1": "registry.example.com/unknown:v1"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid relocation mapping")
+}
+
+type stdinDriver struct {
+	mockDriver
+}
+
+func (d *stdinDriver) SupportsStdin() bool {
+	return true
+}
+
+func TestWithStdin(t *testing.T) {
+	in := strings.NewReader("yes\n")
+
+	t.Run("supported", func(t *testing.T) {
+		d := &stdinDriver{mockDriver{shouldHandle: true}}
+		a := New(d)
+		opResult, _, err := a.Run(newClaim(claim.ActionInstall), mockSet, WithStdin(in), func(op *driver.Operation) error {
+			op.Out = io.Discard
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, opResult.Error)
+		require.NotNil(t, d.Operation)
+		assert.Equal(t, in, d.Operation.In)
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		a := New(d)
+		_, _, err := a.Run(newClaim(claim.ActionInstall), mockSet, WithStdin(in))
+		assert.ErrorIs(t, err, driver.ErrStdinNotSupported)
+		assert.Nil(t, d.Operation, "the operation should not be run")
+	})
+}
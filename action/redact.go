@@ -0,0 +1,149 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// MinRedactedLength is the length of the shortest secret redacted by a
+// RedactingWriter. Shorter secrets, such as "1" or "no", are not redacted
+// since they would replace unrelated output and reveal the secret by where
+// the placeholder appears.
+const MinRedactedLength = 4
+
+// RedactingWriter replaces the sensitive values written to it with a
+// placeholder before writing to the underlying writer. A value split across
+// writes is still redacted, so bytes that may start a sensitive value are
+// held until the rest is written or Flush is called.
+type RedactingWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	secrets [][]byte
+	pending []byte
+}
+
+// NewRedactingWriter creates a writer that redacts the secrets from the data
+// written to w. Secrets shorter than MinRedactedLength are not redacted.
+func NewRedactingWriter(w io.Writer, secrets ...string) *RedactingWriter {
+	r := &RedactingWriter{w: w}
+	seen := map[string]bool{}
+	for _, secret := range secrets {
+		if len(secret) < MinRedactedLength || seen[secret] {
+			continue
+		}
+		seen[secret] = true
+		r.secrets = append(r.secrets, []byte(secret))
+	}
+	// Match the longest secret when one is the prefix of another
+	sort.Slice(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+	return r
+}
+
+// Write redacts p and writes it to the underlying writer, except for the
+// bytes at the end of p that may start a sensitive value.
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.secrets) == 0 {
+		return r.w.Write(p)
+	}
+
+	r.pending = append(r.pending, p...)
+	if err := r.redact(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the bytes held because they may start a sensitive value. It
+// must be called once nothing else is written to the writer.
+func (r *RedactingWriter) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.redact(true)
+}
+
+// redact writes the pending bytes with the sensitive values replaced, until
+// the bytes that may start a sensitive value, unless flushing.
+func (r *RedactingWriter) redact(flush bool) error {
+	var out bytes.Buffer
+	i := 0
+scan:
+	for i < len(r.pending) {
+		rest := r.pending[i:]
+		for _, secret := range r.secrets {
+			if bytes.HasPrefix(rest, secret) {
+				out.WriteString(redactedValue)
+				i += len(secret)
+				continue scan
+			}
+		}
+		if !flush {
+			for _, secret := range r.secrets {
+				if len(rest) < len(secret) && bytes.HasPrefix(secret, rest) {
+					// Wait for the rest of the value
+					break scan
+				}
+			}
+		}
+		out.WriteByte(rest[0])
+		i++
+	}
+	r.pending = append(r.pending[:0], r.pending[i:]...)
+
+	if out.Len() == 0 {
+		return nil
+	}
+	_, err := r.w.Write(out.Bytes())
+	return err
+}
+
+// sensitiveValues returns the values of the credentials and sensitive
+// parameters of the operation, as they may be printed by the bundle.
+func sensitiveValues(op *driver.Operation) []string {
+	var values []string
+	for name := range sensitiveEnvironmentVariables(op) {
+		values = append(values, op.Environment[name])
+	}
+	for path := range sensitiveFiles(op) {
+		values = append(values, op.Files[path])
+	}
+	for name := range sensitiveParameters(op) {
+		value, ok := op.Parameters[name]
+		if !ok {
+			continue
+		}
+		if s, ok := value.(string); ok {
+			values = append(values, s)
+		} else if data, err := json.Marshal(value); err == nil {
+			values = append(values, string(data))
+		}
+	}
+	return values
+}
+
+// redactLogs replaces the output streams of the operation with writers that
+// redact its sensitive values, from the streams and from the saved logs. The
+// returned function flushes the writers once the operation has run.
+func redactLogs(op *driver.Operation) func() {
+	secrets := sensitiveValues(op)
+	if len(secrets) == 0 {
+		return func() {}
+	}
+
+	out := NewRedactingWriter(op.Out, secrets...)
+	errOut := NewRedactingWriter(op.Err, secrets...)
+	op.Out, op.Err = out, errOut
+	return func() {
+		out.Flush()
+		errOut.Flush()
+	}
+}
@@ -0,0 +1,105 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	testcases := []struct {
+		name    string
+		secrets []string
+		writes  []string
+		want    string
+	}{
+		{name: "no secrets", writes: []string{"hello ", "world"}, want: "hello world"},
+		{name: "single write", secrets: []string{"hunter2"}, writes: []string{"password=hunter2\n"}, want: "password=******\n"},
+		{name: "split across writes", secrets: []string{"hunter2"}, writes: []string{"password=hun", "ter", "2\n"}, want: "password=******\n"},
+		{name: "prefix not followed by the secret", secrets: []string{"hunter2"}, writes: []string{"hunt", "ing\n"}, want: "hunting\n"},
+		{name: "longest secret", secrets: []string{"abcd", "abcdefgh"}, writes: []string{"abcdefgh abcd"}, want: "****** ******"},
+		{name: "repeated", secrets: []string{"s3cr3t"}, writes: []string{"s3cr3ts3cr3t"}, want: "************"},
+		{name: "multiline secret", secrets: []string{"-----BEGIN KEY-----\nabc\n-----END KEY-----"}, writes: []string{"key:\n-----BEGIN KEY-----\n", "abc\n-----END KEY-----\n"}, want: "key:\n******\n"},
+		{name: "empty secret", secrets: []string{""}, writes: []string{"hello"}, want: "hello"},
+		{name: "short secrets", secrets: []string{"1", "no", "yes"}, writes: []string{"replicas: 1, enabled: yes, debug: no\n"}, want: "replicas: 1, enabled: yes, debug: no\n"},
+		{name: "flushed prefix", secrets: []string{"hunter2"}, writes: []string{"the end: hunt"}, want: "the end: hunt"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := NewRedactingWriter(&out, tc.secrets...)
+			for _, s := range tc.writes {
+				n, err := w.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			require.NoError(t, w.Flush())
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+
+	t.Run("held until the rest is written", func(t *testing.T) {
+		var out bytes.Buffer
+		w := NewRedactingWriter(&out, "hunter2")
+		_, err := w.Write([]byte("password=hun"))
+		require.NoError(t, err)
+		assert.Equal(t, "password=", out.String(), "a possible start of a secret should be held")
+	})
+}
+
+// printingDriver prints the values of the operation's environment variables
+// and files.
+type printingDriver struct{}
+
+func (d printingDriver) Handles(imageType string) bool {
+	return true
+}
+
+func (d printingDriver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	fmt.Fprintf(op.Out, "token %s\n", op.Environment["CNAB_TOKEN"])
+	fmt.Fprintf(op.Out, "password %s\n", op.Environment["CNAB_P_PASSWORD"])
+	fmt.Fprintf(op.Err, "file %s\n", op.Files["/foo/bar"])
+	fmt.Fprintf(op.Out, "param %s", op.Environment["PARAM_TWO"])
+	return driver.OperationResult{Outputs: map[string]string{}}, nil
+}
+
+func TestAction_RedactLogs(t *testing.T) {
+	writeOnly := true
+	c := newClaim(claim.ActionInstall)
+	c.Bundle.Definitions["Password"] = &definition.Schema{Type: "string", WriteOnly: &writeOnly}
+	c.Bundle.Parameters["password"] = bundle.Parameter{Definition: "Password"}
+	c.Bundle.Credentials["cnab_token"] = bundle.Credential{Location: bundle.Location{EnvironmentVariable: "CNAB_TOKEN"}}
+	c.Parameters = map[string]interface{}{"password": "hunter2", "param_two": "not secret"}
+	creds := map[string]string{"secret_one": "I'm a secret", "secret_two": "I'm also a secret", "cnab_token": "abc123"}
+
+	var stdout, stderr bytes.Buffer
+	streams := func(op *driver.Operation) error {
+		op.Out = &stdout
+		op.Err = &stderr
+		return nil
+	}
+
+	a := New(printingDriver{})
+	a.SaveLogs = true
+	opResult, _, err := a.Run(c, creds, streams)
+	require.NoError(t, err)
+	require.NoError(t, opResult.Error)
+
+	assert.Equal(t, "token ******\npassword ******\nparam not secret", stdout.String(), "credentials and sensitive parameters should be redacted from stdout")
+	assert.Equal(t, "file ******\n", stderr.String(), "credentials should be redacted from stderr")
+
+	logs := opResult.Outputs[claim.OutputInvocationImageLogs]
+	for _, secret := range []string{"abc123", "hunter2", "I'm a secret"} {
+		assert.NotContains(t, logs, secret, "the saved logs should be redacted")
+	}
+	assert.Contains(t, logs, "param not secret")
+}
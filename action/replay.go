@@ -0,0 +1,374 @@
+package action
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// Protection of the sensitive values in an OperationSnapshot.
+const (
+	// SnapshotRedacted indicates that sensitive values were replaced with a
+	// placeholder and must be provided again when the operation is replayed.
+	SnapshotRedacted = "redacted"
+
+	// SnapshotEncrypted indicates that sensitive values were encrypted, and
+	// are decrypted when the operation is replayed.
+	SnapshotEncrypted = "encrypted"
+)
+
+// claimFilePath is the file where the claim is injected into the invocation image.
+const claimFilePath = "/cnab/claim.json"
+
+// OperationSnapshot is a fully resolved driver.Operation, with its
+// credentials and sensitive parameters protected, that is saved to a file
+// when Action.OperationSnapshotPath is set. Use Replay to run the operation
+// again, for example to reproduce a failed install without resolving the
+// claim and credentials again.
+type OperationSnapshot struct {
+	// Created is when the snapshot was taken.
+	Created time.Time `json:"created"`
+
+	// Protection of the sensitive values, either SnapshotRedacted or
+	// SnapshotEncrypted.
+	Protection string `json:"protection"`
+
+	// SensitiveEnvironment are the names of the environment variables of the
+	// operation whose values are protected.
+	SensitiveEnvironment []string `json:"sensitiveEnvironment,omitempty"`
+
+	// SensitiveFiles are the paths of the files of the operation whose
+	// contents are protected.
+	SensitiveFiles []string `json:"sensitiveFiles,omitempty"`
+
+	// SensitiveParameters are the names of the parameters whose values are
+	// protected, both in the parameters of the operation and in the claim
+	// injected into the invocation image.
+	SensitiveParameters []string `json:"sensitiveParameters,omitempty"`
+
+	// Operation that was run by the driver.
+	Operation driver.Operation `json:"operation"`
+}
+
+// NewOperationSnapshot records the operation, protecting the values of its
+// credentials and sensitive parameters. When encrypt is nil, the sensitive
+// values are redacted, otherwise they are encrypted with it.
+func NewOperationSnapshot(op *driver.Operation, encrypt claim.EncryptionHandler) (OperationSnapshot, error) {
+	snapshot := OperationSnapshot{
+		Created:    time.Now(),
+		Protection: SnapshotRedacted,
+		Operation:  copyOperation(op),
+	}
+
+	protect := func(value string) (string, error) {
+		return redactedValue, nil
+	}
+	if encrypt != nil {
+		snapshot.Protection = SnapshotEncrypted
+		protect = func(value string) (string, error) {
+			data, err := encrypt([]byte(value))
+			if err != nil {
+				return "", err
+			}
+			return base64.StdEncoding.EncodeToString(data), nil
+		}
+	}
+
+	err := snapshot.transform(sensitiveEnvironmentVariables(op), sensitiveFiles(op), sensitiveParameters(op), protect, false)
+	return snapshot, errors.Wrap(err, "error protecting the sensitive values of the operation snapshot")
+}
+
+// Restore returns the operation recorded by the snapshot, decrypting its
+// sensitive values with decrypt when they were encrypted. The sensitive
+// values of a redacted snapshot are left redacted.
+func (s OperationSnapshot) Restore(decrypt claim.EncryptionHandler) (*driver.Operation, error) {
+	restored := OperationSnapshot{Operation: copyOperation(&s.Operation)}
+
+	switch s.Protection {
+	case SnapshotRedacted:
+		return &restored.Operation, nil
+	case SnapshotEncrypted:
+		if decrypt == nil {
+			return nil, errors.New("the operation snapshot is encrypted and requires a decryption handler")
+		}
+	default:
+		return nil, errors.Errorf("invalid operation snapshot protection %q", s.Protection)
+	}
+
+	toSet := func(values []string) map[string]bool {
+		set := make(map[string]bool, len(values))
+		for _, v := range values {
+			set[v] = true
+		}
+		return set
+	}
+	unprotect := func(value string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", err
+		}
+		data, err = decrypt(data)
+		return string(data), err
+	}
+
+	err := restored.transform(toSet(s.SensitiveEnvironment), toSet(s.SensitiveFiles), toSet(s.SensitiveParameters), unprotect, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decrypting the sensitive values of the operation snapshot")
+	}
+	return &restored.Operation, nil
+}
+
+// transform applies fn to the sensitive values of the operation, recording
+// which values were transformed. restore is set when fn restores values that
+// were protected.
+func (s *OperationSnapshot) transform(env map[string]bool, files map[string]bool, params map[string]bool, fn func(string) (string, error), restore bool) error {
+	op := &s.Operation
+	s.SensitiveEnvironment, s.SensitiveFiles, s.SensitiveParameters = nil, nil, nil
+
+	for name, value := range op.Environment {
+		if !env[name] {
+			continue
+		}
+		protected, err := fn(value)
+		if err != nil {
+			return errors.Wrapf(err, "environment variable %s", name)
+		}
+		op.Environment[name] = protected
+		s.SensitiveEnvironment = append(s.SensitiveEnvironment, name)
+	}
+
+	for path, contents := range op.Files {
+		if !files[path] {
+			continue
+		}
+		protected, err := fn(contents)
+		if err != nil {
+			return errors.Wrapf(err, "file %s", path)
+		}
+		op.Files[path] = protected
+		s.SensitiveFiles = append(s.SensitiveFiles, path)
+	}
+
+	for name := range params {
+		value, ok := op.Parameters[name]
+		if !ok {
+			continue
+		}
+		protected, err := transformParameter(value, fn, restore)
+		if err != nil {
+			return errors.Wrapf(err, "parameter %s", name)
+		}
+		op.Parameters[name] = protected
+		s.SensitiveParameters = append(s.SensitiveParameters, name)
+	}
+
+	if claimFile, ok := op.Files[claimFilePath]; ok && len(params) > 0 && !files[claimFilePath] {
+		transformed, err := transformClaimParameters(claimFile, params, fn, restore)
+		if err != nil {
+			return errors.Wrapf(err, "file %s", claimFilePath)
+		}
+		op.Files[claimFilePath] = transformed
+	}
+
+	sort.Strings(s.SensitiveEnvironment)
+	sort.Strings(s.SensitiveFiles)
+	sort.Strings(s.SensitiveParameters)
+	return nil
+}
+
+// transformParameter applies fn to the JSON representation of a parameter
+// value when it is protected, and parses the result of fn as JSON when the
+// protected value is restored.
+func transformParameter(value interface{}, fn func(string) (string, error), restore bool) (interface{}, error) {
+	if !restore {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return fn(string(data))
+	}
+
+	protected, ok := value.(string)
+	if !ok {
+		return nil, errors.Errorf("invalid protected value of type %T", value)
+	}
+	data, err := fn(protected)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal([]byte(data), &v)
+	return v, err
+}
+
+// transformClaimParameters applies fn to the sensitive parameters of the
+// claim injected into the invocation image.
+func transformClaimParameters(claimFile string, params map[string]bool, fn func(string) (string, error), restore bool) (string, error) {
+	var c map[string]interface{}
+	if err := json.Unmarshal([]byte(claimFile), &c); err != nil {
+		return "", err
+	}
+
+	claimParams, ok := c["parameters"].(map[string]interface{})
+	if !ok {
+		return claimFile, nil
+	}
+	for name := range params {
+		value, ok := claimParams[name]
+		if !ok {
+			continue
+		}
+		transformed, err := transformParameter(value, fn, restore)
+		if err != nil {
+			return "", errors.Wrapf(err, "parameter %s", name)
+		}
+		claimParams[name] = transformed
+	}
+
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// saveOperationSnapshot to the file at Action.OperationSnapshotPath, when set.
+func (a Action) saveOperationSnapshot(op *driver.Operation) error {
+	if a.OperationSnapshotPath == "" {
+		return nil
+	}
+
+	snapshot, err := NewOperationSnapshot(op, a.OperationSnapshotEncryption)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the operation snapshot")
+	}
+
+	err = ioutil.WriteFile(a.OperationSnapshotPath, data, 0600)
+	return errors.Wrapf(err, "error writing the operation snapshot to %s", a.OperationSnapshotPath)
+}
+
+// ReadOperationSnapshot reads an operation snapshot saved by an Action with
+// OperationSnapshotPath set.
+func ReadOperationSnapshot(path string) (OperationSnapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return OperationSnapshot{}, errors.Wrapf(err, "error reading the operation snapshot %s", path)
+	}
+
+	var snapshot OperationSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return OperationSnapshot{}, errors.Wrapf(err, "error parsing the operation snapshot %s", path)
+	}
+	return snapshot, nil
+}
+
+// Replay runs the operation saved in the snapshot file at path again with
+// the driver, which may be a different driver than the one that ran it
+// originally. decrypt is required when the sensitive values of the snapshot
+// were encrypted. The sensitive values of a redacted snapshot are run as
+// redacted, unless they are provided again with the operation configs.
+//
+// The result is not recorded in a claim, and any error running the
+// operation is returned as the error.
+func Replay(d driver.Driver, path string, decrypt claim.EncryptionHandler, opCfgs ...OperationConfigFunc) (driver.OperationResult, error) {
+	if d == nil {
+		return driver.OperationResult{}, errors.New("the replay driver is not set")
+	}
+
+	snapshot, err := ReadOperationSnapshot(path)
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+
+	op, err := snapshot.Restore(decrypt)
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+
+	if !d.Handles(op.Image.ImageType) {
+		return driver.OperationResult{}, errors.Errorf("driver is not compatible with the invocation image %s of type %s", op.Image.Image, op.Image.ImageType)
+	}
+
+	if err := OperationConfigs(opCfgs).ApplyConfig(op); err != nil {
+		return driver.OperationResult{}, err
+	}
+
+	return d.Run(op)
+}
+
+// sensitiveFiles returns the paths of the files that hold credentials or
+// sensitive parameters.
+func sensitiveFiles(op *driver.Operation) map[string]bool {
+	sensitive := map[string]bool{}
+	if op.Bundle == nil {
+		return sensitive
+	}
+
+	for _, cred := range op.Bundle.Credentials {
+		if cred.Path != "" {
+			sensitive[cred.Path] = true
+		}
+	}
+
+	for name, param := range op.Bundle.Parameters {
+		isSensitive, err := op.Bundle.IsParameterSensitive(name)
+		if err != nil || !isSensitive {
+			continue
+		}
+		if param.Destination != nil && param.Destination.Path != "" {
+			sensitive[param.Destination.Path] = true
+		}
+	}
+	return sensitive
+}
+
+// sensitiveParameters returns the names of the sensitive parameters.
+func sensitiveParameters(op *driver.Operation) map[string]bool {
+	sensitive := map[string]bool{}
+	if op.Bundle == nil {
+		return sensitive
+	}
+
+	for name := range op.Bundle.Parameters {
+		if isSensitive, err := op.Bundle.IsParameterSensitive(name); err == nil && isSensitive {
+			sensitive[name] = true
+		}
+	}
+	return sensitive
+}
+
+// copyOperation copies the operation and the maps that are modified when its
+// sensitive values are protected, without its streams and event handler.
+func copyOperation(op *driver.Operation) driver.Operation {
+	copyMap := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		c := make(map[string]string, len(m))
+		for k, v := range m {
+			c[k] = v
+		}
+		return c
+	}
+
+	c := *op
+	c.Environment = copyMap(op.Environment)
+	c.Files = copyMap(op.Files)
+	if op.Parameters != nil {
+		c.Parameters = make(map[string]interface{}, len(op.Parameters))
+		for k, v := range op.Parameters {
+			c.Parameters[k] = v
+		}
+	}
+	c.Out, c.Err, c.Events = nil, nil, nil
+	return c
+}
@@ -0,0 +1,113 @@
+package action
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// reverse is a reversible encryption handler for tests.
+func reverse(data []byte) ([]byte, error) {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed, nil
+}
+
+func TestAction_OperationSnapshot(t *testing.T) {
+	writeOnly := true
+	c := newClaim(claim.ActionInstall)
+	c.Bundle.Definitions["Password"] = &definition.Schema{Type: "string", WriteOnly: &writeOnly}
+	c.Bundle.Parameters["password"] = bundle.Parameter{Definition: "Password"}
+	c.Parameters = map[string]interface{}{"password": "hunter2", "param_one": "one"}
+
+	out := func(op *driver.Operation) error {
+		op.Out = io.Discard
+		return nil
+	}
+
+	t.Run("redacted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "operation.json")
+		d := &mockDriver{shouldHandle: true}
+		a := New(d)
+		a.OperationSnapshotPath = path
+
+		_, _, err := a.Run(c, mockSet, out)
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "hunter2", "sensitive parameters should be redacted")
+		assert.NotContains(t, string(data), "I'm a secret", "credentials should be redacted")
+
+		snapshot, err := ReadOperationSnapshot(path)
+		require.NoError(t, err)
+		assert.Equal(t, SnapshotRedacted, snapshot.Protection)
+		assert.Equal(t, []string{"CNAB_P_PASSWORD", "SECRET_ONE", "SECRET_TWO"}, snapshot.SensitiveEnvironment)
+		assert.Equal(t, []string{"/foo/bar", "/secret/two"}, snapshot.SensitiveFiles)
+		assert.Equal(t, []string{"password"}, snapshot.SensitiveParameters)
+		assert.Equal(t, "******", snapshot.Operation.Environment["CNAB_P_PASSWORD"])
+		assert.Equal(t, "one", snapshot.Operation.Environment["CNAB_P_PARAM_ONE"])
+		assert.Equal(t, "hunter2", d.Operation.Environment["CNAB_P_PASSWORD"], "the operation run by the driver should not be redacted")
+
+		replayed := &mockDriver{shouldHandle: true}
+		_, err = Replay(replayed, path, nil, out, func(op *driver.Operation) error {
+			op.Environment["CNAB_P_PASSWORD"] = "hunter2"
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", replayed.Operation.Environment["CNAB_P_PASSWORD"], "redacted values can be provided when replaying")
+		assert.Equal(t, "******", replayed.Operation.Environment["SECRET_ONE"])
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "operation.json")
+		d := &mockDriver{shouldHandle: true}
+		a := New(d)
+		a.OperationSnapshotPath = path
+		a.OperationSnapshotEncryption = reverse
+
+		_, _, err := a.Run(c, mockSet, out)
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "hunter2")
+
+		_, err = Replay(&mockDriver{shouldHandle: true}, path, nil, out)
+		require.EqualError(t, err, "the operation snapshot is encrypted and requires a decryption handler")
+
+		replayed := &mockDriver{shouldHandle: true}
+		_, err = Replay(replayed, path, reverse, out)
+		require.NoError(t, err)
+
+		op := replayed.Operation
+		assert.Equal(t, d.Operation.Environment, op.Environment)
+		assert.Equal(t, d.Operation.Files["/foo/bar"], op.Files["/foo/bar"])
+		assert.Equal(t, d.Operation.Parameters, op.Parameters)
+		assert.JSONEq(t, d.Operation.Files["/cnab/claim.json"], op.Files["/cnab/claim.json"])
+		assert.Equal(t, d.Operation.Image, op.Image)
+		assert.Equal(t, d.Operation.Outputs, op.Outputs)
+	})
+
+	t.Run("incompatible driver", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "operation.json")
+		a := New(&mockDriver{shouldHandle: true})
+		a.OperationSnapshotPath = path
+		_, _, err := a.Run(c, mockSet, out)
+		require.NoError(t, err)
+
+		_, err = Replay(&mockDriver{shouldHandle: false}, path, nil, out)
+		require.EqualError(t, err, "driver is not compatible with the invocation image foo/bar:0.1.0 of type docker")
+	})
+}
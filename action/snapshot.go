@@ -0,0 +1,107 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// redactedValue replaces the values of sensitive parameters and credentials
+// in the environment snapshot.
+const redactedValue = "******"
+
+// EnvironmentSnapshot records the CNAB environment variables and the paths of
+// the files that were injected into the invocation image for an operation, so
+// that a post-mortem can see the values that the bundle received. It is saved
+// as the claim.OutputEnvironmentSnapshot output when
+// Action.SaveEnvironmentSnapshot is set.
+type EnvironmentSnapshot struct {
+	// Environment contains the environment variables prefixed with CNAB_,
+	// with the values of sensitive parameters and credentials redacted.
+	Environment map[string]string `json:"environment"`
+
+	// Files are the paths of the files injected into the invocation image,
+	// without their contents.
+	Files []string `json:"files"`
+}
+
+// newEnvironmentSnapshot records the environment and files of the operation.
+func newEnvironmentSnapshot(op *driver.Operation) EnvironmentSnapshot {
+	sensitive := sensitiveEnvironmentVariables(op)
+
+	snapshot := EnvironmentSnapshot{
+		Environment: map[string]string{},
+		Files:       make([]string, 0, len(op.Files)),
+	}
+	for name, value := range op.Environment {
+		if !strings.HasPrefix(name, "CNAB_") {
+			continue
+		}
+		if sensitive[name] {
+			value = redactedValue
+		}
+		snapshot.Environment[name] = value
+	}
+	for path := range op.Files {
+		snapshot.Files = append(snapshot.Files, path)
+	}
+	sort.Strings(snapshot.Files)
+
+	return snapshot
+}
+
+// sensitiveEnvironmentVariables returns the names of the environment variables
+// that hold credentials or sensitive parameters.
+func sensitiveEnvironmentVariables(op *driver.Operation) map[string]bool {
+	sensitive := map[string]bool{}
+	if op.Bundle == nil {
+		return sensitive
+	}
+
+	for _, cred := range op.Bundle.Credentials {
+		if cred.EnvironmentVariable != "" {
+			sensitive[cred.EnvironmentVariable] = true
+		}
+	}
+
+	for name, param := range op.Bundle.Parameters {
+		isSensitive, err := op.Bundle.IsParameterSensitive(name)
+		if err != nil || !isSensitive {
+			continue
+		}
+		if param.Destination == nil {
+			sensitive[fmt.Sprintf("CNAB_P_%s", strings.ToUpper(name))] = true
+		} else if param.Destination.EnvironmentVariable != "" {
+			sensitive[param.Destination.EnvironmentVariable] = true
+		}
+	}
+	return sensitive
+}
+
+// saveEnvironmentSnapshot as an output when action.SaveEnvironmentSnapshot is set.
+func (a Action) saveEnvironmentSnapshot(snapshot *EnvironmentSnapshot, opResult *driver.OperationResult) error {
+	if snapshot == nil {
+		return nil
+	}
+
+	if _, ok := opResult.Outputs[claim.OutputEnvironmentSnapshot]; ok {
+		// The bundle is using our reserved output name, so skip saving the snapshot
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the environment snapshot")
+	}
+	if opResult.Outputs == nil {
+		opResult.Outputs = make(map[string]string)
+	}
+	opResult.Outputs[claim.OutputEnvironmentSnapshot] = string(data)
+	return nil
+}
@@ -0,0 +1,63 @@
+package action
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestAction_SaveEnvironmentSnapshot(t *testing.T) {
+	writeOnly := true
+	c := newClaim(claim.ActionInstall)
+	c.Bundle.Definitions["Password"] = &definition.Schema{Type: "string", WriteOnly: &writeOnly}
+	c.Bundle.Parameters["password"] = bundle.Parameter{Definition: "Password"}
+	c.Bundle.Credentials["cnab_token"] = bundle.Credential{Location: bundle.Location{EnvironmentVariable: "CNAB_TOKEN"}}
+	c.Parameters = map[string]interface{}{"password": "hunter2", "param_one": "one"}
+	creds := map[string]string{"secret_one": "I'm a secret", "secret_two": "I'm also a secret", "cnab_token": "abc123"}
+
+	out := func(op *driver.Operation) error {
+		op.Out = io.Discard
+		return nil
+	}
+
+	t.Run("saved", func(t *testing.T) {
+		a := New(&mockDriver{shouldHandle: true})
+		a.SaveEnvironmentSnapshot = true
+
+		opResult, claimResult, err := a.Run(c, creds, out)
+		require.NoError(t, err)
+		require.NoError(t, opResult.Error)
+		require.Contains(t, opResult.Outputs, claim.OutputEnvironmentSnapshot)
+
+		var snapshot EnvironmentSnapshot
+		require.NoError(t, json.Unmarshal([]byte(opResult.Outputs[claim.OutputEnvironmentSnapshot]), &snapshot))
+		assert.Equal(t, "install", snapshot.Environment["CNAB_ACTION"])
+		assert.Equal(t, "one", snapshot.Environment["CNAB_P_PARAM_ONE"])
+		assert.Equal(t, "******", snapshot.Environment["CNAB_P_PASSWORD"], "sensitive parameters should be redacted")
+		assert.Equal(t, "******", snapshot.Environment["CNAB_TOKEN"], "credentials should be redacted")
+		assert.NotContains(t, snapshot.Environment, "SECRET_ONE", "only CNAB environment variables should be recorded")
+		assert.Contains(t, snapshot.Files, "/cnab/bundle.json")
+		assert.Contains(t, snapshot.Files, "/foo/bar")
+		assert.NotContains(t, opResult.Outputs[claim.OutputEnvironmentSnapshot], "hunter2")
+
+		generatedByBundle, ok := claimResult.OutputMetadata.GetGeneratedByBundle(claim.OutputEnvironmentSnapshot)
+		require.True(t, ok)
+		assert.False(t, generatedByBundle)
+	})
+
+	t.Run("not saved by default", func(t *testing.T) {
+		a := New(&mockDriver{shouldHandle: true})
+
+		opResult, _, err := a.Run(c, creds, out)
+		require.NoError(t, err)
+		assert.NotContains(t, opResult.Outputs, claim.OutputEnvironmentSnapshot)
+	})
+}
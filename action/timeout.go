@@ -0,0 +1,34 @@
+package action
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// deadline returns the time by which an operation started now must complete,
+// and is zero when the action does not have a Timeout.
+func (a Action) deadline() time.Time {
+	if a.Timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(a.Timeout)
+}
+
+// applyDeadline sets the deadline of the action on the operation, keeping any
+// earlier deadline that was already set on it.
+func applyDeadline(op *driver.Operation, deadline time.Time) {
+	if deadline.IsZero() {
+		return
+	}
+	if !op.HasDeadline() || deadline.Before(op.Deadline) {
+		op.Deadline = deadline
+	}
+}
+
+// timeoutMessage is the message of the claim result of an operation that was
+// stopped because it exceeded its deadline.
+func timeoutMessage(err error) string {
+	return fmt.Sprintf("the operation timed out: %s", err)
+}
@@ -0,0 +1,70 @@
+package action
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestAction_Timeout(t *testing.T) {
+	out := func(op *driver.Operation) error {
+		op.Out = ioutil.Discard
+		return nil
+	}
+
+	t.Run("no timeout", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+
+		_, _, err := inst.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.NoError(t, err)
+		assert.False(t, d.Operation.HasDeadline(), "the operation should not have a deadline")
+	})
+
+	t.Run("deadline from timeout", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+		inst.Timeout = time.Hour
+
+		before := time.Now()
+		_, _, err := inst.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.NoError(t, err)
+		assert.WithinRange(t, d.Operation.Deadline, before.Add(time.Hour), time.Now().Add(time.Hour))
+	})
+
+	t.Run("earlier operation deadline", func(t *testing.T) {
+		d := &mockDriver{shouldHandle: true}
+		inst := New(d)
+		inst.Timeout = time.Hour
+
+		deadline := time.Now().Add(time.Minute)
+		setDeadline := func(op *driver.Operation) error {
+			op.Deadline = deadline
+			return nil
+		}
+		_, _, err := inst.Run(newClaim(claim.ActionInstall), mockSet, out, setDeadline)
+		require.NoError(t, err)
+		assert.Equal(t, deadline, d.Operation.Deadline, "the earlier deadline of the operation should be kept")
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		deadline := time.Now().Add(time.Minute)
+		d := &mockDriver{
+			shouldHandle: true,
+			Error:        driver.DeadlineExceededError{Deadline: deadline},
+		}
+		inst := New(d)
+		inst.Timeout = time.Minute
+
+		_, claimResult, err := inst.Run(newClaim(claim.ActionInstall), mockSet, out)
+		require.NoError(t, err)
+		assert.Equal(t, claim.StatusFailed, claimResult.Status)
+		assert.Contains(t, claimResult.Message, "the operation timed out")
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	cjson "github.com/cyberphone/json-canonicalization/go/src/webpki.org/jsoncanonicalizer"
+	"github.com/hashicorp/go-multierror"
 	pkgErrors "github.com/pkg/errors"
 
 	"github.com/cnabio/cnab-go/bundle/definition"
@@ -218,13 +219,15 @@ func ValuesOrDefaults(vals map[string]interface{}, b *Bundle, action string) (ma
 
 		// Set to the corresponding val if it exists in the supplied overrides,
 		// else error out if required or set to the default defined on the parameter
+		// The defaults of the properties of object parameters are applied to
+		// both the supplied and the default values.
 		var uncoerced interface{}
 		if val, ok := vals[name]; ok {
-			uncoerced = val
+			uncoerced = s.ApplyDefaults(val)
 		} else if param.Required {
 			return res, fmt.Errorf("parameter %q is required", name)
 		} else {
-			uncoerced = s.Default
+			uncoerced = s.DefaultValue()
 		}
 
 		// Only collect defaults and specified parameters. Unspecified optional parameters without defaults should not be validated.
@@ -249,6 +252,33 @@ func ValuesOrDefaults(vals map[string]interface{}, b *Bundle, action string) (ma
 	return res, nil
 }
 
+// MergedValuesOrDefaults returns the parameter values for an action on an
+// existing installation, such as upgrade, by merging the parameter values of
+// the installation's previous claim with the values supplied by the user and
+// the default parameter values. The value of each parameter is selected in
+// the following order of precedence:
+//
+//  1. The value supplied by the user in overrides.
+//  2. The value from the previous claim, unless it was unset (nil).
+//  3. The default value of the parameter.
+//
+// Parameters that do not apply to the action, or that are no longer defined
+// by the bundle, are not included even when they were set on the previous
+// claim. The merged values are validated in the same way as ValuesOrDefaults.
+func MergedValuesOrDefaults(previous map[string]interface{}, overrides map[string]interface{}, b *Bundle, action string) (map[string]interface{}, error) {
+	vals := make(map[string]interface{}, len(previous)+len(overrides))
+	for name, val := range previous {
+		if val != nil {
+			vals[name] = val
+		}
+	}
+	for name, val := range overrides {
+		vals[name] = val
+	}
+
+	return ValuesOrDefaults(vals, b, action)
+}
+
 // Validate the bundle contents.
 func (b Bundle) Validate() error {
 	err := b.SchemaVersion.Validate()
@@ -313,6 +343,27 @@ func (b Bundle) Validate() error {
 		}
 	}
 
+	// Outputs that are captured from the same path during an action are ambiguous
+	var conflictErrs *multierror.Error
+	for _, conflict := range b.GetOutputPathConflicts() {
+		conflictErrs = multierror.Append(conflictErrs, conflict)
+	}
+	if err := conflictErrs.ErrorOrNil(); err != nil {
+		return pkgErrors.Wrap(err, "validation failed for outputs")
+	}
+
+	// Validate the parameter sources extension, when present
+	if b.HasParameterSources() {
+		ps, err := b.ReadParameterSources()
+		if err != nil {
+			return err
+		}
+		err = ps.Validate(b)
+		if err != nil {
+			return pkgErrors.Wrapf(err, "validation failed for extension %q", ParameterSourcesExtensionKey)
+		}
+	}
+
 	return nil
 }
 
@@ -355,7 +355,7 @@ func TestValidateMissingSchemaVersion(t *testing.T) {
 	}
 
 	err := b.Validate()
-	is.EqualError(err, "bundle validation failed: invalid schema version \"\": Invalid Semantic Version")
+	is.EqualError(err, "bundle validation failed: invalid schema version \"\": invalid semantic version")
 }
 
 func TestValidateInvalidSchemaVersion(t *testing.T) {
@@ -369,7 +369,7 @@ func TestValidateInvalidSchemaVersion(t *testing.T) {
 	}
 
 	err := b.Validate()
-	is.EqualError(err, "bundle validation failed: invalid schema version \".1\": Invalid Semantic Version")
+	is.EqualError(err, "bundle validation failed: invalid schema version \".1\": invalid semantic version")
 }
 
 func TestValidateBundle_RequiresInvocationImage(t *testing.T) {
@@ -950,6 +950,54 @@ func TestBundle_IsOutputSensitive(t *testing.T) {
 
 }
 
+func TestBundle_IsParameterSensitive(t *testing.T) {
+	var writeOnly = true
+	b := Bundle{
+		Definitions: map[string]*definition.Schema{
+			"port": {
+				Type: "integer",
+			},
+			"password": {
+				Type:      "string",
+				WriteOnly: &writeOnly,
+			},
+		},
+		Parameters: map[string]Parameter{
+			"port": {
+				Definition: "port",
+			},
+			"password": {
+				Definition: "password",
+			},
+			"no-def": {
+				Definition: "no-def",
+			},
+		},
+	}
+
+	t.Run("write-only unset", func(t *testing.T) {
+		sensitive, err := b.IsParameterSensitive("port")
+		require.NoError(t, err, "IsParameterSensitive failed")
+		assert.False(t, sensitive, "expected port to NOT be sensitive because write-only is false")
+	})
+
+	t.Run("write-only true", func(t *testing.T) {
+		sensitive, err := b.IsParameterSensitive("password")
+		require.NoError(t, err, "IsParameterSensitive failed")
+		assert.True(t, sensitive, "expected password to be sensitive because write-only is true")
+	})
+
+	t.Run("missing parameter", func(t *testing.T) {
+		_, err := b.IsParameterSensitive("no-param")
+		require.EqualError(t, err, `parameter "no-param" not defined`)
+	})
+
+	t.Run("missing definition", func(t *testing.T) {
+		_, err := b.IsParameterSensitive("no-def")
+		require.EqualError(t, err, `parameter definition "no-def" not found`)
+	})
+}
+
 func TestBundle_GetAction(t *testing.T) {
 	testcases := []struct {
 		action    string
@@ -986,3 +1034,113 @@ func TestBundle_GetAction(t *testing.T) {
 		})
 	}
 }
+
+func TestMergedValuesOrDefaults(t *testing.T) {
+	b := &Bundle{
+		Definitions: map[string]*definition.Schema{
+			"string":  {Type: "string"},
+			"port":    {Type: "integer", Default: 8080},
+			"replica": {Type: "integer", Default: 3},
+		},
+		Parameters: map[string]Parameter{
+			"host":     {Definition: "string", Required: true},
+			"port":     {Definition: "port"},
+			"replicas": {Definition: "replica"},
+			"version":  {Definition: "string"},
+			"uninstall-only": {
+				Definition: "string",
+				ApplyTo:    []string{"uninstall"},
+			},
+		},
+	}
+
+	previous := map[string]interface{}{
+		"host":           "db.example.com",
+		"port":           5432,
+		"replicas":       nil,
+		"version":        "1.0",
+		"uninstall-only": "cleanup",
+		"removed":        "no longer defined by the bundle",
+	}
+
+	testcases := []struct {
+		name      string
+		previous  map[string]interface{}
+		overrides map[string]interface{}
+		want      map[string]interface{}
+		wantErr   string
+	}{
+		{
+			name:      "overrides take precedence over previous values",
+			previous:  previous,
+			overrides: map[string]interface{}{"version": "2.0"},
+			want:      map[string]interface{}{"host": "db.example.com", "port": 5432, "replicas": 3, "version": "2.0"},
+		},
+		{
+			name:     "previous values take precedence over defaults",
+			previous: previous,
+			want:     map[string]interface{}{"host": "db.example.com", "port": 5432, "replicas": 3, "version": "1.0"},
+		},
+		{
+			name:      "defaults without previous values",
+			overrides: map[string]interface{}{"host": "localhost"},
+			want:      map[string]interface{}{"host": "localhost", "port": 8080, "replicas": 3, "version": nil},
+		},
+		{
+			name:    "required parameter missing",
+			wantErr: `parameter "host" is required`,
+		},
+		{
+			name:      "invalid override",
+			previous:  previous,
+			overrides: map[string]interface{}{"port": "http"},
+			wantErr:   "cannot use value: http as parameter port",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := MergedValuesOrDefaults(tc.previous, tc.overrides, b, "upgrade")
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	assert.Equal(t, "cleanup", previous["uninstall-only"], "the previous values should not be modified")
+}
+
+func TestEmbeddedSchemaVersion(t *testing.T) {
+	version, err := schema.GetEmbeddedVersion(schema.TypeBundle)
+	require.NoError(t, err)
+	assert.Equal(t, GetDefaultSchemaVersion(), version, "the embedded bundle schema should be refreshed with make fetch-schemas")
+}
+
+func TestValuesOrDefaults_NestedObjectDefaults(t *testing.T) {
+	b := &Bundle{
+		Definitions: map[string]*definition.Schema{
+			"config": {
+				Type: "object",
+				Properties: map[string]*definition.Schema{
+					"replicas": {Type: "integer", Default: 1},
+					"image":    {Type: "string"},
+				},
+			},
+		},
+		Parameters: map[string]Parameter{
+			"supplied": {Definition: "config"},
+			"default":  {Definition: "config"},
+		},
+	}
+
+	vod, err := ValuesOrDefaults(map[string]interface{}{
+		"supplied": map[string]interface{}{"image": "nginx"},
+	}, b, "install")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"image": "nginx", "replicas": 1}, vod["supplied"])
+	assert.Equal(t, map[string]interface{}{"replicas": 1}, vod["default"])
+}
@@ -0,0 +1,166 @@
+package bundle
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default limits of the metadata of a bundle checked by a CompliancePolicy.
+const (
+	DefaultMaxDescriptionLength = 1024
+	DefaultMaxKeywords          = 20
+	DefaultMaxKeywordLength     = 32
+)
+
+// CompliancePolicy checks that the license and metadata of a bundle are
+// complete enough for it to be published, for example to a registry:
+//
+//   - the license, when set, is an SPDX license expression, for example
+//     "Apache-2.0" or "MIT OR GPL-2.0-or-later"
+//   - the bundle has at least one maintainer, with a name and a valid
+//     email and url when they are set
+//   - the description and keywords are within length limits, and keywords are
+//     not empty or duplicated
+//
+// The zero value uses the default limits.
+type CompliancePolicy struct {
+	// MaxDescriptionLength is the maximum number of characters of the
+	// description. Defaults to DefaultMaxDescriptionLength.
+	MaxDescriptionLength int `json:"maxDescriptionLength,omitempty" yaml:"maxDescriptionLength,omitempty"`
+
+	// MaxKeywords is the maximum number of keywords. Defaults to
+	// DefaultMaxKeywords.
+	MaxKeywords int `json:"maxKeywords,omitempty" yaml:"maxKeywords,omitempty"`
+
+	// MaxKeywordLength is the maximum number of characters of a keyword.
+	// Defaults to DefaultMaxKeywordLength.
+	MaxKeywordLength int `json:"maxKeywordLength,omitempty" yaml:"maxKeywordLength,omitempty"`
+
+	// RequireLicense fails bundles without a license.
+	RequireLicense bool `json:"requireLicense,omitempty" yaml:"requireLicense,omitempty"`
+
+	// AdditionalLicenses are license identifiers that are allowed in the
+	// license expression in addition to the commonly used SPDX license
+	// identifiers.
+	AdditionalLicenses []string `json:"additionalLicenses,omitempty" yaml:"additionalLicenses,omitempty"`
+}
+
+// ComplianceViolation describes metadata of a bundle that does not comply
+// with a CompliancePolicy.
+type ComplianceViolation struct {
+	// Field of the bundle, for example license or maintainers[0].email.
+	Field string `json:"field"`
+
+	// Reason that the field does not comply.
+	Reason string `json:"reason"`
+}
+
+func (v ComplianceViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Field, v.Reason)
+}
+
+// ComplianceError is returned when the metadata of a bundle does not comply
+// with a CompliancePolicy, and reports every violation.
+type ComplianceError struct {
+	Violations []ComplianceViolation `json:"violations"`
+}
+
+func (e *ComplianceError) Error() string {
+	violations := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		violations[i] = v.String()
+	}
+	return fmt.Sprintf("the bundle does not comply with the publishing requirements: %s", strings.Join(violations, "; "))
+}
+
+// Check the metadata of the bundle against the policy, returning a
+// *ComplianceError describing every violation.
+func (p CompliancePolicy) Check(b Bundle) error {
+	var violations []ComplianceViolation
+	violate := func(field string, reason string, args ...interface{}) {
+		violations = append(violations, ComplianceViolation{Field: field, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if b.License == "" {
+		if p.RequireLicense {
+			violate("license", "a license is required")
+		}
+	} else if err := validateLicenseExpression(b.License, p.AdditionalLicenses); err != nil {
+		violate("license", "%v", err)
+	}
+
+	if len(b.Maintainers) == 0 {
+		violate("maintainers", "at least one maintainer is required")
+	}
+	for i, m := range b.Maintainers {
+		field := fmt.Sprintf("maintainers[%d]", i)
+		if strings.TrimSpace(m.Name) == "" {
+			violate(field+".name", "the name of the maintainer is required")
+		}
+		if m.Email != "" {
+			if addr, err := mail.ParseAddress(m.Email); err != nil || addr.Address != m.Email {
+				violate(field+".email", "%q is not a valid email address", m.Email)
+			}
+		}
+		if m.URL != "" {
+			if u, err := url.Parse(m.URL); err != nil || !u.IsAbs() || u.Host == "" {
+				violate(field+".url", "%q is not an absolute url", m.URL)
+			}
+		}
+	}
+
+	maxDescriptionLength := defaultLimit(p.MaxDescriptionLength, DefaultMaxDescriptionLength)
+	if n := utf8.RuneCountInString(b.Description); n > maxDescriptionLength {
+		violate("description", "the description is %d characters long but may be at most %d", n, maxDescriptionLength)
+	}
+
+	maxKeywords := defaultLimit(p.MaxKeywords, DefaultMaxKeywords)
+	if len(b.Keywords) > maxKeywords {
+		violate("keywords", "the bundle has %d keywords but may have at most %d", len(b.Keywords), maxKeywords)
+	}
+	maxKeywordLength := defaultLimit(p.MaxKeywordLength, DefaultMaxKeywordLength)
+	seen := make(map[string]bool, len(b.Keywords))
+	for i, keyword := range b.Keywords {
+		field := fmt.Sprintf("keywords[%d]", i)
+		switch {
+		case strings.TrimSpace(keyword) == "":
+			violate(field, "keywords cannot be empty")
+		case utf8.RuneCountInString(keyword) > maxKeywordLength:
+			violate(field, "keyword %q is longer than %d characters", keyword, maxKeywordLength)
+		case seen[strings.ToLower(keyword)]:
+			violate(field, "keyword %q is duplicated", keyword)
+		}
+		seen[strings.ToLower(keyword)] = true
+	}
+
+	if len(violations) > 0 {
+		return &ComplianceError{Violations: violations}
+	}
+	return nil
+}
+
+func defaultLimit(limit int, defaultValue int) int {
+	if limit > 0 {
+		return limit
+	}
+	return defaultValue
+}
+
+// ValidateStrict validates the bundle, as Validate does, and then checks that
+// its license and metadata are complete enough for it to be published, with
+// the default CompliancePolicy.
+func (b Bundle) ValidateStrict() error {
+	return b.ValidateWithCompliance(CompliancePolicy{})
+}
+
+// ValidateWithCompliance validates the bundle, as Validate does, and then
+// checks its license and metadata against the policy.
+func (b Bundle) ValidateWithCompliance(policy CompliancePolicy) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	return policy.Check(b)
+}
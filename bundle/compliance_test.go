@@ -0,0 +1,164 @@
+package bundle
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compliantBundle() Bundle {
+	return Bundle{
+		SchemaVersion: "1.2.0",
+		Name:          "mybun",
+		Version:       "0.1.0",
+		Description:   "Installs my application",
+		Keywords:      []string{"mysql", "database"},
+		License:       "Apache-2.0",
+		Maintainers:   []Maintainer{{Name: "My Team", Email: "team@example.com", URL: "https://example.com/team"}},
+		InvocationImages: []InvocationImage{
+			{BaseImage: BaseImage{ImageType: "docker", Image: "myorg/mybun-installer:v0.1.0"}},
+		},
+	}
+}
+
+func TestCompliancePolicy_Check(t *testing.T) {
+	testcases := []struct {
+		name       string
+		policy     CompliancePolicy
+		modify     func(b *Bundle)
+		violations []ComplianceViolation
+	}{
+		{
+			name:   "compliant",
+			modify: func(b *Bundle) {},
+		},
+		{
+			name:   "no license",
+			modify: func(b *Bundle) { b.License = "" },
+		},
+		{
+			name:       "license required",
+			policy:     CompliancePolicy{RequireLicense: true},
+			modify:     func(b *Bundle) { b.License = "" },
+			violations: []ComplianceViolation{{Field: "license", Reason: "a license is required"}},
+		},
+		{
+			name:       "unknown license",
+			modify:     func(b *Bundle) { b.License = "Apache 2" },
+			violations: []ComplianceViolation{{Field: "license", Reason: `"Apache" is not a known SPDX license identifier`}},
+		},
+		{
+			name:   "additional license",
+			policy: CompliancePolicy{AdditionalLicenses: []string{"Acme-1.0"}},
+			modify: func(b *Bundle) { b.License = "acme-1.0 OR MIT" },
+		},
+		{
+			name: "missing maintainers",
+			modify: func(b *Bundle) {
+				b.Maintainers = nil
+			},
+			violations: []ComplianceViolation{{Field: "maintainers", Reason: "at least one maintainer is required"}},
+		},
+		{
+			name: "invalid maintainer",
+			modify: func(b *Bundle) {
+				b.Maintainers = append(b.Maintainers, Maintainer{Email: "team at example.com", URL: "example.com"})
+			},
+			violations: []ComplianceViolation{
+				{Field: "maintainers[1].name", Reason: "the name of the maintainer is required"},
+				{Field: "maintainers[1].email", Reason: `"team at example.com" is not a valid email address`},
+				{Field: "maintainers[1].url", Reason: `"example.com" is not an absolute url`},
+			},
+		},
+		{
+			name:       "description too long",
+			policy:     CompliancePolicy{MaxDescriptionLength: 10},
+			modify:     func(b *Bundle) {},
+			violations: []ComplianceViolation{{Field: "description", Reason: "the description is 23 characters long but may be at most 10"}},
+		},
+		{
+			name: "invalid keywords",
+			policy: CompliancePolicy{
+				MaxKeywords:      3,
+				MaxKeywordLength: 8,
+			},
+			modify: func(b *Bundle) {
+				b.Keywords = []string{"mysql", " ", "relational-database", "MySQL"}
+			},
+			violations: []ComplianceViolation{
+				{Field: "keywords", Reason: "the bundle has 4 keywords but may have at most 3"},
+				{Field: "keywords[1]", Reason: "keywords cannot be empty"},
+				{Field: "keywords[2]", Reason: `keyword "relational-database" is longer than 8 characters`},
+				{Field: "keywords[3]", Reason: `keyword "MySQL" is duplicated`},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := compliantBundle()
+			tc.modify(&b)
+
+			err := tc.policy.Check(b)
+			if len(tc.violations) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var complianceErr *ComplianceError
+			require.True(t, errors.As(err, &complianceErr), "expected a ComplianceError, got %v", err)
+			assert.Equal(t, tc.violations, complianceErr.Violations)
+		})
+	}
+}
+
+func TestValidateLicenseExpression(t *testing.T) {
+	valid := []string{
+		"MIT",
+		"mit",
+		"GPL-2.0+",
+		"Apache-2.0 OR MIT",
+		"(Apache-2.0 OR MIT) AND BSD-3-Clause",
+		"GPL-2.0-only WITH Classpath-exception-2.0",
+		"LicenseRef-Proprietary",
+		"DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2",
+		"((MIT))",
+	}
+	for _, license := range valid {
+		assert.NoError(t, validateLicenseExpression(license, nil), license)
+	}
+
+	invalid := map[string]string{
+		"":                             "license expression is empty",
+		"Apache 2":                     `"Apache" is not a known SPDX license identifier`,
+		"MIT OR":                       "license expression is incomplete",
+		"MIT MIT":                      `unexpected "MIT" in license expression`,
+		"(MIT OR Apache-2.0":           "license expression is missing a closing parenthesis",
+		"MIT)":                         `unexpected ")" in license expression`,
+		"OR MIT":                       `unexpected "OR" in license expression`,
+		"GPL-2.0-only WITH":            "license expression is missing an exception after WITH",
+		"GPL-2.0-only WITH Made-Up-42": `"Made-Up-42" is not a known SPDX license exception`,
+	}
+	for license, wantErr := range invalid {
+		assert.EqualError(t, validateLicenseExpression(license, nil), wantErr, license)
+	}
+}
+
+func TestBundle_ValidateStrict(t *testing.T) {
+	b := compliantBundle()
+	require.NoError(t, b.ValidateStrict())
+	require.NoError(t, b.Validate())
+
+	b.Maintainers = nil
+	b.Keywords = []string{strings.Repeat("k", 40)}
+	assert.NoError(t, b.Validate(), "the compliance checks should be opt-in")
+	err := b.ValidateStrict()
+	assert.EqualError(t, err, "the bundle does not comply with the publishing requirements: "+
+		"maintainers: at least one maintainer is required; keywords[0]: keyword \""+strings.Repeat("k", 40)+"\" is longer than 32 characters")
+
+	b.InvocationImages = nil
+	assert.EqualError(t, b.ValidateStrict(), "at least one invocation image must be defined in the bundle", "the bundle should be validated first")
+}
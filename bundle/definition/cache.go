@@ -0,0 +1,84 @@
+package definition
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/qri-io/jsonschema"
+)
+
+// maxCompiledSchemas is the number of compiled schemas kept by
+// compiledSchemas, after which a schema is evicted to make room.
+const maxCompiledSchemas = 1024
+
+// compiledSchemas caches the validators compiled by Schema.Validate, so that
+// a definition validating many values, for example every parameter of a
+// bundle, is compiled once.
+var compiledSchemas = &schemaCache{entries: map[digest.Digest]*compiledSchema{}}
+
+// compiledSchema is a schema compiled for validation. The validator updates
+// the state of the compiled schema while validating, so validations with the
+// same compiled schema are serialized.
+type compiledSchema struct {
+	mu     sync.Mutex
+	schema *jsonschema.Schema
+
+	// err is set when the schema could not be compiled.
+	err error
+}
+
+// validate the JSON data against the schema.
+func (c *compiledSchema) validate(data []byte) ([]jsonschema.KeyError, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.schema.ValidateBytes(context.Background(), data)
+}
+
+// schemaCache is a concurrency-safe cache of compiled schemas, keyed by the
+// digest of the schema document.
+type schemaCache struct {
+	mu      sync.RWMutex
+	entries map[digest.Digest]*compiledSchema
+}
+
+// get returns the compiled schema document, compiling it when it is not in
+// the cache. A document that is not a valid schema returns the same error
+// each time, without being compiled again.
+func (c *schemaCache) get(doc []byte) (*compiledSchema, error) {
+	key := digest.FromBytes(doc)
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		entry = compileSchema(doc)
+
+		c.mu.Lock()
+		if existing, ok := c.entries[key]; ok {
+			// Another goroutine compiled the schema in the meantime
+			entry = existing
+		} else {
+			if len(c.entries) >= maxCompiledSchemas {
+				for evicted := range c.entries {
+					delete(c.entries, evicted)
+					break
+				}
+			}
+			c.entries[key] = entry
+		}
+		c.mu.Unlock()
+	}
+
+	return entry, entry.err
+}
+
+// compileSchema compiles a schema document for validation.
+func compileSchema(doc []byte) *compiledSchema {
+	rs := NewRootSchema()
+	if err := rs.UnmarshalJSON(doc); err != nil {
+		return &compiledSchema{err: errors.Wrap(err, "schema not valid")}
+	}
+	return &compiledSchema{schema: rs}
+}
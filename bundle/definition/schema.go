@@ -2,6 +2,7 @@ package definition
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -10,12 +11,15 @@ import (
 
 type Definitions map[string]*Schema
 
-// Schema represents a JSON Schema compatible CNAB Definition
+// Schema represents a JSON Schema compatible CNAB Definition. The keywords
+// of drafts 07, 2019-09 and 2020-12 are supported, see Validate.
 type Schema struct {
 	Schema               string                 `json:"$schema,omitempty" yaml:"$schema,omitempty"`
 	Comment              string                 `json:"$comment,omitempty" yaml:"$comment,omitempty"`
 	ID                   string                 `json:"$id,omitempty" yaml:"$id,omitempty"`
 	Ref                  string                 `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Anchor               string                 `json:"$anchor,omitempty" yaml:"$anchor,omitempty"`
+	Defs                 Definitions            `json:"$defs,omitempty" yaml:"$defs,omitempty"`
 	AdditionalItems      interface{}            `json:"additionalItems,omitempty" yaml:"additionalItems,omitempty"`
 	AdditionalProperties interface{}            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
 	AllOf                []*Schema              `json:"allOf,omitempty" yaml:"allOf,omitempty"`
@@ -26,6 +30,9 @@ type Schema struct {
 	Default              interface{}            `json:"default,omitempty" yaml:"default,omitempty"`
 	Definitions          Definitions            `json:"definitions,omitempty" yaml:"definitions,omitempty"`
 	Dependencies         map[string]interface{} `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	DependentRequired    map[string][]string    `json:"dependentRequired,omitempty" yaml:"dependentRequired,omitempty"`
+	DependentSchemas     map[string]*Schema     `json:"dependentSchemas,omitempty" yaml:"dependentSchemas,omitempty"`
+	Deprecated           *bool                  `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
 	Description          string                 `json:"description,omitempty" yaml:"description,omitempty"`
 	Else                 *Schema                `json:"else,omitempty" yaml:"else,omitempty"`
 	Enum                 []interface{}          `json:"enum,omitempty" yaml:"enum,omitempty"`
@@ -36,8 +43,10 @@ type Schema struct {
 	If                   *Schema                `json:"if,omitempty" yaml:"if,omitempty"`
 	//Items can be a Schema or an Array of Schema :(
 	Items         interface{} `json:"items,omitempty" yaml:"items,omitempty"`
+	MaxContains   *int        `json:"maxContains,omitempty" yaml:"maxContains,omitempty"`
 	Maximum       *float64    `json:"maximum,omitempty" yaml:"maximum,omitempty"`
 	MaxLength     *int        `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	MinContains   *int        `json:"minContains,omitempty" yaml:"minContains,omitempty"`
 	MinItems      *int        `json:"minItems,omitempty" yaml:"minItems,omitempty"`
 	MinLength     *int        `json:"minLength,omitempty" yaml:"minLength,omitempty"`
 	MinProperties *int        `json:"minProperties,omitempty" yaml:"minProperties,omitempty"`
@@ -47,6 +56,9 @@ type Schema struct {
 	OneOf         *Schema     `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`
 
 	PatternProperties map[string]*Schema `json:"patternProperties,omitempty" yaml:"patternProperties,omitempty"`
+	// PrefixItems validates the leading items of an array, replacing the array
+	// form of Items in draft 2020-12.
+	PrefixItems []*Schema `json:"prefixItems,omitempty" yaml:"prefixItems,omitempty"`
 
 	Properties    map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
 	PropertyNames *Schema            `json:"propertyNames,omitempty" yaml:"propertyNames,omitempty"`
@@ -56,7 +68,10 @@ type Schema struct {
 	Title         string             `json:"title,omitempty" yaml:"title,omitempty"`
 	Type          interface{}        `json:"type,omitempty" yaml:"type,omitempty"`
 	UniqueItems   *bool              `json:"uniqueItems,omitempty" yaml:"uniqueItems,omitempty"`
-	WriteOnly     *bool              `json:"writeOnly,omitempty" yaml:"writeOnly,omitempty"`
+	// UnevaluatedItems and UnevaluatedProperties can be a Schema or a boolean.
+	UnevaluatedItems      interface{} `json:"unevaluatedItems,omitempty" yaml:"unevaluatedItems,omitempty"`
+	UnevaluatedProperties interface{} `json:"unevaluatedProperties,omitempty" yaml:"unevaluatedProperties,omitempty"`
+	WriteOnly             *bool       `json:"writeOnly,omitempty" yaml:"writeOnly,omitempty"`
 }
 
 // GetType will return the singular type for a given schema and a success boolean. If the
@@ -149,3 +164,68 @@ func (s *Schema) ConvertValue(val string) (interface{}, error) {
 		return nil, errors.New("invalid definition")
 	}
 }
+
+// FormatValue formats a value of a schema as a string, for example as the
+// contents of an output: objects and arrays as JSON, other values with their
+// default format. It is the reverse of ConvertValue.
+func FormatValue(val interface{}) (string, error) {
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	default:
+		return fmt.Sprintf("%v", val), nil
+	}
+}
+
+// DefaultValue returns the default value of the schema. For an object, the
+// defaults of its properties are materialized recursively into the default
+// object, so that a schema without a default of its own still has a default
+// when any of its properties has one. Returns nil when there is no default.
+func (s *Schema) DefaultValue() interface{} {
+	return s.ApplyDefaults(nil)
+}
+
+// ApplyDefaults returns the value with the defaults of the schema applied:
+// a nil value is replaced by the default of the schema, and the properties
+// missing from an object are set to their default, recursively. The value is
+// not modified, objects with defaults applied are copied.
+func (s *Schema) ApplyDefaults(val interface{}) interface{} {
+	if val == nil {
+		val = s.Default
+	}
+	if len(s.Properties) == 0 {
+		return val
+	}
+
+	var obj map[string]interface{}
+	switch v := val.(type) {
+	case nil:
+		obj = map[string]interface{}{}
+	case map[string]interface{}:
+		obj = make(map[string]interface{}, len(v))
+		for name, propVal := range v {
+			obj[name] = propVal
+		}
+	default:
+		// Leave values that are not objects for the validation to report
+		return val
+	}
+
+	for name, prop := range s.Properties {
+		if prop == nil {
+			continue
+		}
+		if propVal := prop.ApplyDefaults(obj[name]); propVal != nil {
+			obj[name] = propVal
+		}
+	}
+
+	if val == nil && len(obj) == 0 {
+		return nil
+	}
+	return obj
+}
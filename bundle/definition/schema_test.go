@@ -361,3 +361,67 @@ func TestConvertValue(t *testing.T) {
 	is.NoError(err)
 	is.Equal(map[string]interface{}{"object": true}, out)
 }
+
+func TestSchema_ApplyDefaults(t *testing.T) {
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name": {Type: "string"},
+			"port": {Type: "integer", Default: 5432},
+			"tls": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"enabled": {Type: "boolean", Default: true},
+					"ca":      {Type: "string"},
+				},
+			},
+		},
+	}
+
+	t.Run("default value", func(t *testing.T) {
+		assert.Equal(t, map[string]interface{}{
+			"port": 5432,
+			"tls":  map[string]interface{}{"enabled": true},
+		}, s.DefaultValue())
+	})
+
+	t.Run("missing properties", func(t *testing.T) {
+		val := map[string]interface{}{
+			"name": "db",
+			"tls":  map[string]interface{}{"ca": "cert"},
+		}
+		assert.Equal(t, map[string]interface{}{
+			"name": "db",
+			"port": 5432,
+			"tls":  map[string]interface{}{"enabled": true, "ca": "cert"},
+		}, s.ApplyDefaults(val))
+		assert.Len(t, val, 2, "the value should not be modified")
+	})
+
+	t.Run("explicit values are kept", func(t *testing.T) {
+		val := map[string]interface{}{
+			"port": 3306,
+			"tls":  map[string]interface{}{"enabled": false},
+		}
+		assert.Equal(t, val, s.ApplyDefaults(val))
+	})
+
+	t.Run("default object", func(t *testing.T) {
+		withDefault := *s
+		withDefault.Default = map[string]interface{}{"name": "default"}
+		assert.Equal(t, map[string]interface{}{
+			"name": "default",
+			"port": 5432,
+			"tls":  map[string]interface{}{"enabled": true},
+		}, withDefault.DefaultValue())
+	})
+
+	t.Run("no defaults", func(t *testing.T) {
+		assert.Nil(t, (&Schema{Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}}).DefaultValue())
+		assert.Nil(t, (&Schema{Type: "string"}).DefaultValue())
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		assert.Equal(t, "oops", s.ApplyDefaults("oops"))
+	})
+}
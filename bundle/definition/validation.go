@@ -1,8 +1,8 @@
 package definition
 
 import (
-	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/qri-io/jsonschema"
@@ -19,7 +19,7 @@ type ValidationError struct {
 // ValidateSchema validates that the Schema is valid JSON Schema.
 // If no errors occur, the validated jsonschema.Schema is returned.
 func (s *Schema) ValidateSchema() (*jsonschema.Schema, error) {
-	b, err := json.Marshal(s)
+	b, err := s.marshalForValidation()
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to load schema")
 	}
@@ -34,8 +34,22 @@ func (s *Schema) ValidateSchema() (*jsonschema.Schema, error) {
 // Validate applies JSON Schema validation to the data passed as a parameter.
 // If validation errors occur, they will be returned in as a slice of ValidationError
 // structs. If any other error occurs, it will be returned as a separate error
+//
+// Schemas are validated using the keywords of draft 2019-09, which include
+// those of draft-07. When the $schema of the definition is draft 2020-12, the
+// prefixItems and items keywords are converted to their draft 2019-09
+// equivalents, items and additionalItems. The $dynamicRef and $dynamicAnchor
+// keywords of draft 2020-12 are not supported.
+//
+// The validator compiled for the schema is cached, keyed by the digest of the
+// schema, so that validating many values against the same schema does not
+// compile it again.
 func (s *Schema) Validate(data interface{}) ([]ValidationError, error) {
-	def, err := s.ValidateSchema()
+	b, err := s.marshalForValidation()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load schema")
+	}
+	def, err := compiledSchemas.get(b)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +58,7 @@ func (s *Schema) Validate(data interface{}) ([]ValidationError, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to process data")
 	}
-	valErrs, err := def.ValidateBytes(context.Background(), payload)
+	valErrs, err := def.validate(payload)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to perform validation")
 	}
@@ -63,6 +77,49 @@ func (s *Schema) Validate(data interface{}) ([]ValidationError, error) {
 	return nil, nil
 }
 
+// draft202012 identifies the meta-schema of JSON Schema draft 2020-12, e.g.
+// https://json-schema.org/draft/2020-12/schema.
+const draft202012 = "/draft/2020-12/schema"
+
+// marshalForValidation returns the schema as JSON that can be loaded by the
+// validator, which implements draft 2019-09.
+func (s *Schema) marshalForValidation() ([]byte, error) {
+	b, err := json.Marshal(s)
+	if err != nil || !strings.Contains(s.Schema, draft202012) {
+		return b, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	convertDraft202012Items(doc)
+	return json.Marshal(doc)
+}
+
+// convertDraft202012Items converts the prefixItems and items keywords of each
+// schema in the document to the items and additionalItems keywords of draft
+// 2019-09.
+func convertDraft202012Items(doc interface{}) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		if prefixItems, ok := v["prefixItems"].([]interface{}); ok {
+			if items, ok := v["items"]; ok {
+				v["additionalItems"] = items
+			}
+			v["items"] = prefixItems
+			delete(v, "prefixItems")
+		}
+		for _, child := range v {
+			convertDraft202012Items(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			convertDraft202012Items(child)
+		}
+	}
+}
+
 // CoerceValue can be used to turn float and other numeric types into integers. When
 // unmarshaled, often integer values are not represented as an integer. This is a
 // convenience method.
@@ -2,6 +2,8 @@ package definition
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -278,3 +280,189 @@ func TestObjectValidationAdditionalPropertiesAreStrings(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "type should be string, got boolean", valErrors[0].Error)
 }
+
+func TestValidation_NewerDrafts(t *testing.T) {
+	testcases := []struct {
+		name    string
+		schema  string
+		valid   []string
+		invalid []string
+	}{
+		{
+			name: "unevaluatedProperties",
+			schema: `{
+				"$schema": "https://json-schema.org/draft/2019-09/schema",
+				"type": "object",
+				"allOf": [{"properties": {"host": {"type": "string"}}}],
+				"unevaluatedProperties": false
+			}`,
+			valid:   []string{`{"host": "db"}`},
+			invalid: []string{`{"host": "db", "port": 5432}`},
+		},
+		{
+			name: "dependentRequired",
+			schema: `{
+				"type": "object",
+				"dependentRequired": {"username": ["password"]}
+			}`,
+			valid:   []string{`{}`, `{"username": "admin", "password": "secret"}`},
+			invalid: []string{`{"username": "admin"}`},
+		},
+		{
+			name: "$defs",
+			schema: `{
+				"type": "object",
+				"properties": {"port": {"$ref": "#/$defs/port"}},
+				"$defs": {"port": {"type": "integer", "minimum": 1}}
+			}`,
+			valid:   []string{`{"port": 80}`},
+			invalid: []string{`{"port": 0}`, `{"port": "80"}`},
+		},
+		{
+			name: "deprecated",
+			schema: `{
+				"type": "string",
+				"deprecated": true
+			}`,
+			valid:   []string{`"mysql"`},
+			invalid: []string{`1`},
+		},
+		{
+			name: "if/then/else",
+			schema: `{
+				"type": "object",
+				"if": {"properties": {"tls": {"const": true}}, "required": ["tls"]},
+				"then": {"required": ["certificate"]},
+				"else": {"properties": {"port": {"const": 80}}}
+			}`,
+			valid:   []string{`{"tls": true, "certificate": "cert"}`, `{"port": 80}`},
+			invalid: []string{`{"tls": true}`, `{"port": 443}`},
+		},
+		{
+			name: "2020-12 prefixItems",
+			schema: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "array",
+				"prefixItems": [{"type": "string"}, {"type": "integer"}],
+				"items": false
+			}`,
+			valid:   []string{`["db", 5432]`, `["db"]`},
+			invalid: []string{`[5432, "db"]`, `["db", 5432, true]`},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			definition := new(Schema)
+			require.NoError(t, json.Unmarshal([]byte(tc.schema), definition), "should have been able to unmarshal the definition")
+
+			for _, value := range tc.valid {
+				var data interface{}
+				require.NoError(t, json.Unmarshal([]byte(value), &data))
+				valErrors, err := definition.Validate(data)
+				require.NoError(t, err)
+				assert.Empty(t, valErrors, "expected %s to be valid", value)
+			}
+			for _, value := range tc.invalid {
+				var data interface{}
+				require.NoError(t, json.Unmarshal([]byte(value), &data))
+				valErrors, err := definition.Validate(data)
+				require.NoError(t, err)
+				assert.NotEmpty(t, valErrors, "expected %s to be invalid", value)
+			}
+		})
+	}
+}
+
+func TestSchema_NewerDraftKeywords(t *testing.T) {
+	s := `{
+		"$anchor": "config",
+		"$defs": {"port": {"type": "integer"}},
+		"type": "object",
+		"dependentRequired": {"username": ["password"]},
+		"dependentSchemas": {"tls": {"required": ["certificate"]}},
+		"deprecated": true,
+		"unevaluatedProperties": false,
+		"properties": {
+			"hosts": {"type": "array", "contains": {"type": "string"}, "minContains": 1, "maxContains": 3, "unevaluatedItems": {"type": "string"}},
+			"pair": {"type": "array", "prefixItems": [{"type": "string"}]}
+		}
+	}`
+	definition := new(Schema)
+	require.NoError(t, json.Unmarshal([]byte(s), definition))
+
+	assert.Equal(t, "config", definition.Anchor)
+	assert.Equal(t, "integer", definition.Defs["port"].Type)
+	assert.Equal(t, map[string][]string{"username": {"password"}}, definition.DependentRequired)
+	assert.Equal(t, []string{"certificate"}, definition.DependentSchemas["tls"].Required)
+	require.NotNil(t, definition.Deprecated)
+	assert.True(t, *definition.Deprecated)
+	assert.Equal(t, false, definition.UnevaluatedProperties)
+
+	hosts := definition.Properties["hosts"]
+	require.NotNil(t, hosts.MinContains)
+	assert.Equal(t, 1, *hosts.MinContains)
+	require.NotNil(t, hosts.MaxContains)
+	assert.Equal(t, 3, *hosts.MaxContains)
+	assert.Equal(t, map[string]interface{}{"type": "string"}, hosts.UnevaluatedItems)
+	require.Len(t, definition.Properties["pair"].PrefixItems, 1)
+
+	b, err := json.Marshal(definition)
+	require.NoError(t, err)
+	assert.JSONEq(t, s, string(b), "the keywords should round trip")
+}
+
+func TestValidate_CompiledSchemaCache(t *testing.T) {
+	s := &Schema{
+		Type:    "integer",
+		Minimum: toFloatPtr(10),
+	}
+	b, err := s.marshalForValidation()
+	require.NoError(t, err)
+
+	first, err := compiledSchemas.get(b)
+	require.NoError(t, err)
+	valErrs, err := s.Validate(5)
+	require.NoError(t, err)
+	assert.Len(t, valErrs, 1)
+	second, err := compiledSchemas.get(b)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "the compiled schema should be reused")
+
+	other := &Schema{
+		Type:    "integer",
+		Minimum: toFloatPtr(1),
+	}
+	valErrs, err = other.Validate(5)
+	require.NoError(t, err)
+	assert.Empty(t, valErrs, "a different schema should not use the compiled schema of another")
+
+	t.Run("concurrent validations", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 50)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				s := &Schema{Type: "integer", Minimum: toFloatPtr(float64(i % 5))}
+				valErrs, err := s.Validate(2)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if wantErrs := i%5 > 2; wantErrs != (len(valErrs) > 0) {
+					errs <- fmt.Errorf("unexpected validation errors for minimum %d: %v", i%5, valErrs)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	})
+}
+
+func toFloatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,238 @@
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const (
+	// DependenciesExtensionKey represents the full key for the Dependencies Extension.
+	DependenciesExtensionKey = "io.cnab.dependencies"
+
+	// DependenciesSchema represents the schema for the Dependencies Extension.
+	DependenciesSchema = "https://cnab.io/v1/dependencies.schema.json"
+)
+
+// Dependencies describes the set of custom extension metadata associated with
+// the Dependencies extension.
+type Dependencies struct {
+	// Sequence is a list to order the dependencies.
+	Sequence []string `json:"sequence,omitempty" yaml:"sequence,omitempty"`
+
+	// Requires is a list of bundles required by this bundle, keyed by the
+	// dependency name.
+	Requires map[string]Dependency `json:"requires,omitempty" yaml:"requires,omitempty"`
+}
+
+// Dependency describes a dependency on another bundle.
+type Dependency struct {
+	// Name of the dependency. This is populated from the key in
+	// Dependencies.Requires when the extension is read.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Bundle is the location of the bundle in a registry, for example a
+	// repository, without a tag or digest.
+	Bundle string `json:"bundle" yaml:"bundle"`
+
+	// Version is a set of allowed versions.
+	Version *DependencyVersion `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// DependencyVersion is a set of allowed versions for a dependency.
+type DependencyVersion struct {
+	// Ranges of semantic versions, with or without the leading v prefix,
+	// allowed by the dependency. If more than one range is specified, a
+	// version is allowed when it satisfies any of the ranges.
+	// See https://github.com/Masterminds/semver#checking-version-constraints
+	// for supported syntax.
+	Ranges []string `json:"ranges,omitempty" yaml:"ranges,omitempty"`
+
+	// AllowPrereleases specifies whether or not pre-release versions of a
+	// bundle, such as 1.0.0-beta.1, satisfy the dependency.
+	AllowPrereleases bool `json:"prereleases,omitempty" yaml:"prereleases,omitempty"`
+}
+
+// HasDependencies returns whether or not the bundle has dependencies defined.
+func HasDependencies(b bundle.Bundle) bool {
+	_, ok := b.Custom[DependenciesExtensionKey]
+	return ok
+}
+
+// ReadDependencies is a convenience method for returning a bonafide
+// Dependencies reference after reading from the applicable section from
+// the provided bundle.
+func ReadDependencies(b bundle.Bundle) (Dependencies, error) {
+	raw, ok := b.Custom[DependenciesExtensionKey]
+	if !ok {
+		return Dependencies{}, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return Dependencies{}, errors.Wrapf(err, "could not marshal the untyped %q extension data", DependenciesExtensionKey)
+	}
+
+	deps := Dependencies{}
+	err = json.Unmarshal(data, &deps)
+	if err != nil {
+		return Dependencies{}, errors.Wrapf(err, "could not unmarshal the %q extension", DependenciesExtensionKey)
+	}
+
+	for name, dep := range deps.Requires {
+		dep.Name = name
+		deps.Requires[name] = dep
+	}
+
+	return deps, nil
+}
+
+// Validate the dependencies, checking that every dependency is well-formed and
+// that the sequence only references defined dependencies.
+func (d Dependencies) Validate() error {
+	for name, dep := range d.Requires {
+		if dep.Bundle == "" {
+			return fmt.Errorf("dependency %q must specify a bundle", name)
+		}
+
+		if dep.Version != nil {
+			if _, err := dep.Version.constraints(); err != nil {
+				return errors.Wrapf(err, "invalid version for dependency %q", name)
+			}
+		}
+	}
+
+	sequenced := make(map[string]bool, len(d.Sequence))
+	for _, name := range d.Sequence {
+		if _, ok := d.Requires[name]; !ok {
+			return fmt.Errorf("sequence references undefined dependency %q", name)
+		}
+		if sequenced[name] {
+			return fmt.Errorf("dependency %q is listed more than once in the sequence", name)
+		}
+		sequenced[name] = true
+	}
+
+	return nil
+}
+
+// ListBySequence returns the dependencies in the order that they should be
+// installed: first the dependencies listed in Sequence, in that order, followed
+// by any remaining dependencies sorted by name.
+func (d Dependencies) ListBySequence() []Dependency {
+	deps := make([]Dependency, 0, len(d.Requires))
+
+	sequenced := make(map[string]bool, len(d.Sequence))
+	for _, name := range d.Sequence {
+		dep, ok := d.Requires[name]
+		if !ok || sequenced[name] {
+			continue
+		}
+		dep.Name = name
+		deps = append(deps, dep)
+		sequenced[name] = true
+	}
+
+	var remaining []string
+	for name := range d.Requires {
+		if !sequenced[name] {
+			remaining = append(remaining, name)
+		}
+	}
+	sort.Strings(remaining)
+	for _, name := range remaining {
+		dep := d.Requires[name]
+		dep.Name = name
+		deps = append(deps, dep)
+	}
+
+	return deps
+}
+
+// IsSatisfiedBy determines if the specified version is allowed by the dependency.
+func (v DependencyVersion) IsSatisfiedBy(version string) (bool, error) {
+	ver, err := semver.NewVersion(version)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid version %q", version)
+	}
+
+	constraints, err := v.constraints()
+	if err != nil {
+		return false, err
+	}
+
+	return v.check(constraints, ver), nil
+}
+
+// ResolveVersion selects the highest version from the available versions that
+// satisfies the dependency. Available versions that are not valid semantic
+// versions, for example a "latest" tag, are ignored.
+func (v DependencyVersion) ResolveVersion(available []string) (string, error) {
+	constraints, err := v.constraints()
+	if err != nil {
+		return "", err
+	}
+
+	var best *semver.Version
+	var bestValue string
+	for _, value := range available {
+		ver, err := semver.NewVersion(value)
+		if err != nil {
+			continue
+		}
+
+		if !v.check(constraints, ver) {
+			continue
+		}
+
+		if best == nil || ver.GreaterThan(best) {
+			best = ver
+			bestValue = value
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("no version satisfies the ranges %v", v.Ranges)
+	}
+	return bestValue, nil
+}
+
+// constraints parses the version ranges.
+func (v DependencyVersion) constraints() ([]*semver.Constraints, error) {
+	constraints := make([]*semver.Constraints, 0, len(v.Ranges))
+	for _, r := range v.Ranges {
+		c, err := semver.NewConstraint(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid version range %q", r)
+		}
+		// Compare pre-releases by their precedence, so that 1.0.0-beta is
+		// lower than 1.0.0, otherwise the constraints only match pre-releases
+		// when the range itself includes a pre-release.
+		c.IncludePrerelease = v.AllowPrereleases
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+// check if the version satisfies any of the constraints.
+func (v DependencyVersion) check(constraints []*semver.Constraints, ver *semver.Version) bool {
+	if ver.Prerelease() != "" && !v.AllowPrereleases {
+		return false
+	}
+
+	if len(constraints) == 0 {
+		return true
+	}
+
+	for _, c := range constraints {
+		if c.Check(ver) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package extensions
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestReadDependencies(t *testing.T) {
+	data := []byte(`{
+		"sequence": ["storage", "mysql"],
+		"requires": {
+			"mysql": {
+				"bundle": "somecloud/mysql",
+				"version": {"ranges": ["5.7.x"]}
+			},
+			"storage": {
+				"bundle": "somecloud/blob-storage",
+				"version": {"prereleases": true, "ranges": ["1.x - 2", "4.x"]}
+			},
+			"cache": {
+				"bundle": "somecloud/redis"
+			}
+		}
+	}`)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+
+	b := bundle.Bundle{
+		Custom: map[string]interface{}{
+			DependenciesExtensionKey: raw,
+		},
+	}
+
+	require.True(t, HasDependencies(b))
+	deps, err := ReadDependencies(b)
+	require.NoError(t, err)
+	require.NoError(t, deps.Validate())
+
+	require.Len(t, deps.Requires, 3)
+	storage := deps.Requires["storage"]
+	assert.Equal(t, "storage", storage.Name)
+	assert.Equal(t, "somecloud/blob-storage", storage.Bundle)
+	assert.Equal(t, &DependencyVersion{Ranges: []string{"1.x - 2", "4.x"}, AllowPrereleases: true}, storage.Version)
+
+	var ordered []string
+	for _, dep := range deps.ListBySequence() {
+		ordered = append(ordered, dep.Name)
+	}
+	assert.Equal(t, []string{"storage", "mysql", "cache"}, ordered, "sequenced dependencies should be first, followed by the rest sorted by name")
+}
+
+func TestReadDependencies_Missing(t *testing.T) {
+	b := bundle.Bundle{}
+	assert.False(t, HasDependencies(b))
+
+	deps, err := ReadDependencies(b)
+	require.NoError(t, err)
+	assert.Empty(t, deps.Requires)
+}
+
+func TestDependencies_Validate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		deps    Dependencies
+		wantErr string
+	}{
+		{
+			name: "missing bundle",
+			deps: Dependencies{
+				Requires: map[string]Dependency{"mysql": {}},
+			},
+			wantErr: `dependency "mysql" must specify a bundle`,
+		},
+		{
+			name: "invalid range",
+			deps: Dependencies{
+				Requires: map[string]Dependency{"mysql": {Bundle: "somecloud/mysql", Version: &DependencyVersion{Ranges: []string{"not-a-range"}}}},
+			},
+			wantErr: `invalid version for dependency "mysql": invalid version range "not-a-range": improper constraint: not-a-range`,
+		},
+		{
+			name: "undefined sequence",
+			deps: Dependencies{
+				Sequence: []string{"mysql"},
+				Requires: map[string]Dependency{"storage": {Bundle: "somecloud/blob-storage"}},
+			},
+			wantErr: `sequence references undefined dependency "mysql"`,
+		},
+		{
+			name: "duplicate sequence",
+			deps: Dependencies{
+				Sequence: []string{"mysql", "mysql"},
+				Requires: map[string]Dependency{"mysql": {Bundle: "somecloud/mysql"}},
+			},
+			wantErr: `dependency "mysql" is listed more than once in the sequence`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, tc.deps.Validate(), tc.wantErr)
+		})
+	}
+}
+
+func TestDependencyVersion_ResolveVersion(t *testing.T) {
+	available := []string{"latest", "v1.0.0", "1.2.0", "2.0.0-beta.1", "2.1.0", "3.0.0"}
+
+	testcases := []struct {
+		name    string
+		version DependencyVersion
+		want    string
+		wantErr string
+	}{
+		{name: "no ranges", version: DependencyVersion{}, want: "3.0.0"},
+		{name: "single range", version: DependencyVersion{Ranges: []string{"1.x"}}, want: "1.2.0"},
+		{name: "multiple ranges", version: DependencyVersion{Ranges: []string{"1.x", "2.x"}}, want: "2.1.0"},
+		{name: "prereleases excluded", version: DependencyVersion{Ranges: []string{"~2.0"}}, wantErr: "no version satisfies the ranges [~2.0]"},
+		{name: "prereleases allowed", version: DependencyVersion{Ranges: []string{"<2.1"}, AllowPrereleases: true}, want: "2.0.0-beta.1"},
+		{name: "prerelease below the range", version: DependencyVersion{Ranges: []string{"~2.0"}, AllowPrereleases: true}, wantErr: "no version satisfies the ranges [~2.0]"},
+		{name: "leading v", version: DependencyVersion{Ranges: []string{"<1.1"}}, want: "v1.0.0"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.version.ResolveVersion(available)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDependencyVersion_IsSatisfiedBy(t *testing.T) {
+	v := DependencyVersion{Ranges: []string{">=1.0, <2.0"}}
+
+	ok, err := v.IsSatisfiedBy("1.5.0")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = v.IsSatisfiedBy("2.0.0")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = v.IsSatisfiedBy("latest")
+	assert.EqualError(t, err, `invalid version "latest": invalid semantic version`)
+}
+
+func TestDependencyVersion_IsSatisfiedBy_Prereleases(t *testing.T) {
+	testcases := []struct {
+		version string
+		ranges  string
+		want    bool
+	}{
+		{version: "1.0.0-beta", ranges: "^1.0.0", want: false},
+		{version: "1.1.0-beta", ranges: "^1.0.0", want: true},
+		{version: "2.0.0-beta", ranges: "<2.0.0", want: true},
+		{version: "2.0.0-beta", ranges: ">=2.0.0", want: false},
+		{version: "1.0.0-beta.2", ranges: ">1.0.0-beta.1", want: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.version+" "+tc.ranges, func(t *testing.T) {
+			v := DependencyVersion{Ranges: []string{tc.ranges}, AllowPrereleases: true}
+			ok, err := v.IsSatisfiedBy(tc.version)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, ok)
+
+			v.AllowPrereleases = false
+			ok, err = v.IsSatisfiedBy(tc.version)
+			require.NoError(t, err)
+			assert.False(t, ok, "pre-releases should only be allowed when AllowPrereleases is set")
+		})
+	}
+}
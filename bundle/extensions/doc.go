@@ -0,0 +1,3 @@
+// Package extensions provides typed access to well-known CNAB extensions that
+// are stored in the custom section of a bundle.
+package extensions
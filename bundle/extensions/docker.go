@@ -0,0 +1,115 @@
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const (
+	// DockerExtensionKey represents the full key for the Docker Extension,
+	// which requests elevated access to the docker host for the invocation
+	// images of the bundle.
+	DockerExtensionKey = "io.cnab.docker"
+
+	// DockerCapabilityPrivileged is the capability to run the invocation
+	// image as a privileged container.
+	DockerCapabilityPrivileged = "privileged"
+
+	// DockerCapabilityHostNetwork is the capability to run the invocation
+	// image on the network of the docker host.
+	DockerCapabilityHostNetwork = "host-network"
+)
+
+// Docker describes the elevated access to the docker host requested by a
+// bundle. A runtime only grants the requested capabilities that its operator
+// allowed, see CheckAllowed.
+type Docker struct {
+	// Privileged requests that the invocation image runs as a privileged
+	// container, for example to run docker in docker.
+	Privileged bool `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+
+	// HostNetwork requests that the invocation image runs on the network of
+	// the docker host, instead of an isolated network.
+	HostNetwork bool `json:"hostNetwork,omitempty" yaml:"hostNetwork,omitempty"`
+}
+
+// DeniedDockerCapabilitiesError is returned when a bundle requests docker
+// capabilities that the runtime operator did not allow.
+type DeniedDockerCapabilitiesError struct {
+	// Denied capabilities, for example DockerCapabilityPrivileged.
+	Denied []string
+}
+
+func (e DeniedDockerCapabilitiesError) Error() string {
+	return fmt.Sprintf("the bundle requests the docker capabilities %s, which are not allowed by the runtime", strings.Join(e.Denied, ", "))
+}
+
+// HasDockerExtension returns whether or not the bundle has the Docker
+// Extension defined.
+func HasDockerExtension(b bundle.Bundle) bool {
+	_, ok := b.Custom[DockerExtensionKey]
+	return ok
+}
+
+// ReadDockerExtension is a convenience method for returning a bonafide Docker
+// reference after reading from the applicable section from the provided
+// bundle.
+func ReadDockerExtension(b bundle.Bundle) (Docker, error) {
+	raw, ok := b.Custom[DockerExtensionKey]
+	if !ok {
+		return Docker{}, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return Docker{}, errors.Wrapf(err, "could not marshal the untyped %q extension data", DockerExtensionKey)
+	}
+
+	d := Docker{}
+	err = json.Unmarshal(data, &d)
+	if err != nil {
+		return Docker{}, errors.Wrapf(err, "could not unmarshal the %q extension", DockerExtensionKey)
+	}
+
+	return d, nil
+}
+
+// Capabilities returns the docker capabilities requested by the bundle, such
+// as DockerCapabilityPrivileged.
+func (d Docker) Capabilities() []string {
+	var capabilities []string
+	if d.Privileged {
+		capabilities = append(capabilities, DockerCapabilityPrivileged)
+	}
+	if d.HostNetwork {
+		capabilities = append(capabilities, DockerCapabilityHostNetwork)
+	}
+	return capabilities
+}
+
+// CheckAllowed determines if every requested capability is allowed,
+// returning a DeniedDockerCapabilitiesError listing the capabilities that are
+// not.
+func (d Docker) CheckAllowed(allowed []string) error {
+	isAllowed := make(map[string]bool, len(allowed))
+	for _, capability := range allowed {
+		isAllowed[capability] = true
+	}
+
+	var denied DeniedDockerCapabilitiesError
+	for _, capability := range d.Capabilities() {
+		if !isAllowed[capability] {
+			denied.Denied = append(denied.Denied, capability)
+		}
+	}
+
+	if len(denied.Denied) == 0 {
+		return nil
+	}
+	return denied
+}
@@ -0,0 +1,50 @@
+package extensions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestReadDockerExtension(t *testing.T) {
+	b := bundle.Bundle{
+		Custom: map[string]interface{}{
+			DockerExtensionKey: map[string]interface{}{
+				"privileged":  true,
+				"hostNetwork": true,
+			},
+		},
+	}
+
+	assert.True(t, HasDockerExtension(b))
+	d, err := ReadDockerExtension(b)
+	require.NoError(t, err)
+	assert.Equal(t, Docker{Privileged: true, HostNetwork: true}, d)
+	assert.Equal(t, []string{DockerCapabilityPrivileged, DockerCapabilityHostNetwork}, d.Capabilities())
+
+	assert.False(t, HasDockerExtension(bundle.Bundle{}))
+	d, err = ReadDockerExtension(bundle.Bundle{})
+	require.NoError(t, err)
+	assert.Equal(t, Docker{}, d)
+	assert.Empty(t, d.Capabilities())
+
+	b.Custom[DockerExtensionKey] = map[string]interface{}{"privileged": "yes"}
+	_, err = ReadDockerExtension(b)
+	assert.Contains(t, err.Error(), `could not unmarshal the "io.cnab.docker" extension`)
+}
+
+func TestDocker_CheckAllowed(t *testing.T) {
+	d := Docker{Privileged: true, HostNetwork: true}
+	assert.NoError(t, d.CheckAllowed([]string{DockerCapabilityHostNetwork, DockerCapabilityPrivileged}))
+	assert.NoError(t, Docker{}.CheckAllowed(nil), "a bundle without requests should always be allowed")
+
+	err := d.CheckAllowed([]string{DockerCapabilityHostNetwork})
+	assert.EqualError(t, err, "the bundle requests the docker capabilities privileged, which are not allowed by the runtime")
+	assert.Equal(t, DeniedDockerCapabilitiesError{Denied: []string{DockerCapabilityPrivileged}}, err)
+
+	err = d.CheckAllowed(nil)
+	assert.EqualError(t, err, "the bundle requests the docker capabilities privileged, host-network, which are not allowed by the runtime")
+}
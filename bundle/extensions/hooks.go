@@ -0,0 +1,101 @@
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const (
+	// HooksExtensionKey represents the full key for the Hooks Extension, which
+	// declares custom actions of the bundle that are run before or after
+	// another action, for example a backup before an upgrade.
+	HooksExtensionKey = "io.cnab.hooks"
+
+	// HookPre identifies a hook run before the action.
+	HookPre = "pre"
+
+	// HookPost identifies a hook run after the action succeeded.
+	HookPost = "post"
+)
+
+// Hooks describes the custom actions run around the actions of a bundle.
+type Hooks struct {
+	// Pre maps an action to the custom actions run before it, in order.
+	Pre map[string][]string `json:"pre,omitempty" yaml:"pre,omitempty"`
+
+	// Post maps an action to the custom actions run after it succeeded, in order.
+	Post map[string][]string `json:"post,omitempty" yaml:"post,omitempty"`
+}
+
+// HookLink is recorded in the custom section of the claim of a hook, under
+// HooksExtensionKey, to link it to the claim of the action that it ran around.
+type HookLink struct {
+	// Hook is either HookPre or HookPost.
+	Hook string `json:"hook"`
+
+	// Action that the hook ran around.
+	Action string `json:"action"`
+
+	// ClaimID of the action that the hook ran around.
+	ClaimID string `json:"claimId"`
+}
+
+// HasHooks returns whether or not the bundle has hooks defined.
+func HasHooks(b bundle.Bundle) bool {
+	_, ok := b.Custom[HooksExtensionKey]
+	return ok
+}
+
+// ReadHooks is a convenience method for returning a bonafide Hooks reference
+// after reading from the applicable section from the provided bundle.
+func ReadHooks(b bundle.Bundle) (Hooks, error) {
+	raw, ok := b.Custom[HooksExtensionKey]
+	if !ok {
+		return Hooks{}, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return Hooks{}, errors.Wrapf(err, "could not marshal the untyped %q extension data", HooksExtensionKey)
+	}
+
+	hooks := Hooks{}
+	err = json.Unmarshal(data, &hooks)
+	if err != nil {
+		return Hooks{}, errors.Wrapf(err, "could not unmarshal the %q extension", HooksExtensionKey)
+	}
+
+	return hooks, nil
+}
+
+// Validate the hooks against the bundle, checking that every hook is a
+// custom action of the bundle and is not itself an action with hooks.
+func (h Hooks) Validate(b bundle.Bundle) error {
+	for _, hooks := range []map[string][]string{h.Pre, h.Post} {
+		for action, actionHooks := range hooks {
+			for _, hook := range actionHooks {
+				if _, ok := b.Actions[hook]; !ok {
+					return fmt.Errorf("hook %q of action %q is not a custom action defined in the bundle", hook, action)
+				}
+				if len(h.Pre[hook]) > 0 || len(h.Post[hook]) > 0 {
+					return fmt.Errorf("hook %q of action %q cannot have hooks", hook, action)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// PreHooks returns the custom actions run before the action, in order.
+func (h Hooks) PreHooks(action string) []string {
+	return h.Pre[action]
+}
+
+// PostHooks returns the custom actions run after the action succeeded, in order.
+func (h Hooks) PostHooks(action string) []string {
+	return h.Post[action]
+}
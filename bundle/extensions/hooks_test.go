@@ -0,0 +1,63 @@
+package extensions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestReadHooks(t *testing.T) {
+	b := bundle.Bundle{
+		Custom: map[string]interface{}{
+			HooksExtensionKey: map[string]interface{}{
+				"pre":  map[string]interface{}{"upgrade": []interface{}{"backup"}},
+				"post": map[string]interface{}{"install": []interface{}{"smoke-test", "notify"}},
+			},
+		},
+	}
+
+	assert.True(t, HasHooks(b))
+	hooks, err := ReadHooks(b)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup"}, hooks.PreHooks("upgrade"))
+	assert.Equal(t, []string{"smoke-test", "notify"}, hooks.PostHooks("install"))
+	assert.Empty(t, hooks.PreHooks("install"))
+
+	assert.False(t, HasHooks(bundle.Bundle{}))
+	hooks, err = ReadHooks(bundle.Bundle{})
+	require.NoError(t, err)
+	assert.Equal(t, Hooks{}, hooks)
+}
+
+func TestHooks_Validate(t *testing.T) {
+	b := bundle.Bundle{
+		Actions: map[string]bundle.Action{
+			"backup":     {},
+			"smoke-test": {},
+		},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		h := Hooks{
+			Pre:  map[string][]string{"upgrade": {"backup"}},
+			Post: map[string][]string{"install": {"smoke-test"}},
+		}
+		assert.NoError(t, h.Validate(b))
+	})
+
+	t.Run("undefined action", func(t *testing.T) {
+		h := Hooks{Pre: map[string][]string{"upgrade": {"restore"}}}
+		assert.EqualError(t, h.Validate(b), `hook "restore" of action "upgrade" is not a custom action defined in the bundle`)
+	})
+
+	t.Run("nested hooks", func(t *testing.T) {
+		h := Hooks{
+			Pre:  map[string][]string{"upgrade": {"backup"}},
+			Post: map[string][]string{"backup": {"smoke-test"}},
+		}
+		assert.EqualError(t, h.Validate(b), `hook "backup" of action "upgrade" cannot have hooks`)
+	})
+}
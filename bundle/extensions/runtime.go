@@ -0,0 +1,163 @@
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const (
+	// RuntimeRequirementsExtensionKey represents the full key for the Runtime
+	// Requirements Extension, which declares the minimum capabilities that a
+	// CNAB runtime must have to run the bundle.
+	RuntimeRequirementsExtensionKey = "io.cnab.runtime-requirements"
+
+	// FeatureStreamingOutputs is a runtime capability to report the outputs
+	// of an operation while it runs, instead of when it completes.
+	FeatureStreamingOutputs = "streaming-outputs"
+
+	// FeatureDependenciesV2 is a runtime capability to install the
+	// dependencies of a bundle declared with version 2 of the Dependencies
+	// Extension.
+	FeatureDependenciesV2 = "dependencies-v2"
+)
+
+// RuntimeRequirements describes the minimum capabilities required of the CNAB
+// runtime that runs the bundle.
+type RuntimeRequirements struct {
+	// MinimumVersion is the lowest version of the runtime that can run the
+	// bundle, as a semantic version, with or without the leading v prefix.
+	MinimumVersion string `json:"minimumVersion,omitempty" yaml:"minimumVersion,omitempty"`
+
+	// Features are the capabilities that the runtime must support, for
+	// example FeatureStreamingOutputs.
+	Features []string `json:"features,omitempty" yaml:"features,omitempty"`
+}
+
+// RuntimeCapabilities are the capabilities advertised by a CNAB runtime.
+type RuntimeCapabilities struct {
+	// Version of the runtime, as a semantic version.
+	Version string
+
+	// Features supported by the runtime.
+	Features []string
+}
+
+// UnsupportedRuntimeError is returned when a runtime does not have the
+// capabilities required by a bundle.
+type UnsupportedRuntimeError struct {
+	// RequiredVersion is the minimum version of the runtime required by the
+	// bundle, set when the runtime version is lower.
+	RequiredVersion string
+
+	// RuntimeVersion is the version of the runtime.
+	RuntimeVersion string
+
+	// MissingFeatures are the features required by the bundle that the
+	// runtime does not support, sorted by name.
+	MissingFeatures []string
+}
+
+func (e UnsupportedRuntimeError) Error() string {
+	var reasons []string
+	if e.RequiredVersion != "" {
+		reasons = append(reasons, fmt.Sprintf("the bundle requires runtime version %s or later but the runtime version is %s", e.RequiredVersion, e.RuntimeVersion))
+	}
+	if len(e.MissingFeatures) > 0 {
+		reasons = append(reasons, fmt.Sprintf("the bundle requires the unsupported runtime features %s", strings.Join(e.MissingFeatures, ", ")))
+	}
+	return "the runtime cannot run the bundle: " + strings.Join(reasons, "; ")
+}
+
+// HasRuntimeRequirements returns whether or not the bundle has runtime
+// requirements defined.
+func HasRuntimeRequirements(b bundle.Bundle) bool {
+	_, ok := b.Custom[RuntimeRequirementsExtensionKey]
+	return ok
+}
+
+// ReadRuntimeRequirements is a convenience method for returning a bonafide
+// RuntimeRequirements reference after reading from the applicable section
+// from the provided bundle.
+func ReadRuntimeRequirements(b bundle.Bundle) (RuntimeRequirements, error) {
+	raw, ok := b.Custom[RuntimeRequirementsExtensionKey]
+	if !ok {
+		return RuntimeRequirements{}, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return RuntimeRequirements{}, errors.Wrapf(err, "could not marshal the untyped %q extension data", RuntimeRequirementsExtensionKey)
+	}
+
+	reqs := RuntimeRequirements{}
+	err = json.Unmarshal(data, &reqs)
+	if err != nil {
+		return RuntimeRequirements{}, errors.Wrapf(err, "could not unmarshal the %q extension", RuntimeRequirementsExtensionKey)
+	}
+
+	return reqs, nil
+}
+
+// Validate the runtime requirements, checking that the minimum version is a
+// semantic version and that the features are not empty.
+func (r RuntimeRequirements) Validate() error {
+	if r.MinimumVersion != "" {
+		if _, err := semver.NewVersion(r.MinimumVersion); err != nil {
+			return errors.Wrapf(err, "invalid minimum runtime version %q", r.MinimumVersion)
+		}
+	}
+
+	for _, feature := range r.Features {
+		if feature == "" {
+			return errors.New("runtime features must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// CheckSupport determines if the runtime has the required capabilities,
+// returning an UnsupportedRuntimeError listing what is missing when it does
+// not.
+func (r RuntimeRequirements) CheckSupport(runtime RuntimeCapabilities) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	var unsupported UnsupportedRuntimeError
+	if r.MinimumVersion != "" {
+		required, _ := semver.NewVersion(r.MinimumVersion)
+		actual, err := semver.NewVersion(runtime.Version)
+		if err != nil {
+			return errors.Wrapf(err, "invalid runtime version %q", runtime.Version)
+		}
+		if actual.LessThan(required) {
+			unsupported.RequiredVersion = r.MinimumVersion
+			unsupported.RuntimeVersion = runtime.Version
+		}
+	}
+
+	supported := make(map[string]bool, len(runtime.Features))
+	for _, feature := range runtime.Features {
+		supported[feature] = true
+	}
+	for _, feature := range r.Features {
+		if !supported[feature] {
+			supported[feature] = true // only report each feature once
+			unsupported.MissingFeatures = append(unsupported.MissingFeatures, feature)
+		}
+	}
+	sort.Strings(unsupported.MissingFeatures)
+
+	if unsupported.RequiredVersion == "" && len(unsupported.MissingFeatures) == 0 {
+		return nil
+	}
+	return unsupported
+}
@@ -0,0 +1,82 @@
+package extensions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestReadRuntimeRequirements(t *testing.T) {
+	b := bundle.Bundle{
+		Custom: map[string]interface{}{
+			RuntimeRequirementsExtensionKey: map[string]interface{}{
+				"minimumVersion": "1.2.0",
+				"features":       []interface{}{FeatureStreamingOutputs},
+			},
+		},
+	}
+
+	assert.True(t, HasRuntimeRequirements(b))
+	reqs, err := ReadRuntimeRequirements(b)
+	require.NoError(t, err)
+	assert.Equal(t, RuntimeRequirements{MinimumVersion: "1.2.0", Features: []string{FeatureStreamingOutputs}}, reqs)
+
+	assert.False(t, HasRuntimeRequirements(bundle.Bundle{}))
+	reqs, err = ReadRuntimeRequirements(bundle.Bundle{})
+	require.NoError(t, err)
+	assert.Equal(t, RuntimeRequirements{}, reqs)
+}
+
+func TestRuntimeRequirements_CheckSupport(t *testing.T) {
+	runtime := RuntimeCapabilities{
+		Version:  "v1.3.0",
+		Features: []string{FeatureStreamingOutputs},
+	}
+
+	testcases := []struct {
+		name    string
+		reqs    RuntimeRequirements
+		wantErr string
+	}{
+		{name: "no requirements"},
+		{name: "supported", reqs: RuntimeRequirements{MinimumVersion: "1.2.0", Features: []string{FeatureStreamingOutputs}}},
+		{name: "same version", reqs: RuntimeRequirements{MinimumVersion: "v1.3.0"}},
+		{name: "old runtime", reqs: RuntimeRequirements{MinimumVersion: "2.0.0"},
+			wantErr: "the runtime cannot run the bundle: the bundle requires runtime version 2.0.0 or later but the runtime version is v1.3.0"},
+		{name: "missing features", reqs: RuntimeRequirements{Features: []string{FeatureDependenciesV2, FeatureStreamingOutputs, "custom", "custom"}},
+			wantErr: "the runtime cannot run the bundle: the bundle requires the unsupported runtime features custom, dependencies-v2"},
+		{name: "old runtime and missing features", reqs: RuntimeRequirements{MinimumVersion: "2.0.0", Features: []string{FeatureDependenciesV2}},
+			wantErr: "the runtime cannot run the bundle: the bundle requires runtime version 2.0.0 or later but the runtime version is v1.3.0; the bundle requires the unsupported runtime features dependencies-v2"},
+		{name: "invalid minimum version", reqs: RuntimeRequirements{MinimumVersion: "latest"},
+			wantErr: `invalid minimum runtime version "latest": invalid semantic version`},
+		{name: "empty feature", reqs: RuntimeRequirements{Features: []string{""}},
+			wantErr: "runtime features must not be empty"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.reqs.CheckSupport(runtime)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+
+	t.Run("unsupported runtime error", func(t *testing.T) {
+		err := RuntimeRequirements{Features: []string{FeatureDependenciesV2}}.CheckSupport(runtime)
+		var unsupported UnsupportedRuntimeError
+		require.True(t, errors.As(err, &unsupported), "expected an UnsupportedRuntimeError, got %T", err)
+		assert.Equal(t, []string{FeatureDependenciesV2}, unsupported.MissingFeatures)
+	})
+
+	t.Run("invalid runtime version", func(t *testing.T) {
+		err := RuntimeRequirements{MinimumVersion: "1.0.0"}.CheckSupport(RuntimeCapabilities{})
+		require.EqualError(t, err, `invalid runtime version "": invalid semantic version`)
+	})
+}
@@ -0,0 +1,119 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/distribution/reference"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ImageMapPath is the path in the invocation image where the image map is mounted.
+	ImageMapPath = "/cnab/app/image-map.json"
+
+	// RelocationMappingPath is the path in the invocation image where the
+	// relocation mapping is mounted.
+	RelocationMappingPath = "/cnab/app/relocation-mapping.json"
+)
+
+// ImageMap is the content of the image-map.json file, which makes the images
+// defined by a bundle available to the invocation image, keyed by the name
+// of the image in the bundle.
+type ImageMap map[string]Image
+
+// NewImageMap creates the ImageMap for the bundle.
+func NewImageMap(b Bundle) ImageMap {
+	m := make(ImageMap, len(b.Images))
+	for name, img := range b.Images {
+		m[name] = img
+	}
+	return m
+}
+
+// Validate checks that every image has a reference.
+func (m ImageMap) Validate() error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result *multierror.Error
+	for _, name := range names {
+		if m[name].Image == "" {
+			result = multierror.Append(result, fmt.Errorf("image %q does not specify an image reference", name))
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+// BuildImageMap validates and returns the contents of the image-map.json file
+// for the bundle.
+func BuildImageMap(b Bundle) ([]byte, error) {
+	m := NewImageMap(b)
+	if err := m.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid image map")
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal the image map")
+	}
+	return data, nil
+}
+
+// RelocationMapping is the content of the relocation-mapping.json file, which
+// maps the original references of the images used by a bundle to the
+// references of the images after they were relocated to another registry.
+type RelocationMapping map[string]string
+
+// Validate checks that each original reference is an image used by the bundle,
+// and that each relocated reference is a valid image reference.
+func (m RelocationMapping) Validate(b Bundle) error {
+	bundleImages := map[string]struct{}{}
+	for _, ii := range b.InvocationImages {
+		bundleImages[ii.Image] = struct{}{}
+	}
+	for _, img := range b.Images {
+		bundleImages[img.Image] = struct{}{}
+	}
+
+	originals := make([]string, 0, len(m))
+	for original := range m {
+		originals = append(originals, original)
+	}
+	sort.Strings(originals)
+
+	var result *multierror.Error
+	for _, original := range originals {
+		if _, ok := bundleImages[original]; !ok {
+			result = multierror.Append(result, fmt.Errorf("relocated image %q is not used by the bundle", original))
+		}
+
+		relocated := m[original]
+		if _, err := reference.ParseAnyReference(relocated); err != nil {
+			result = multierror.Append(result, fmt.Errorf("invalid relocated reference %q for image %q: %w", relocated, original, err))
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+// BuildRelocationMapping validates and returns the contents of the
+// relocation-mapping.json file for the bundle.
+func BuildRelocationMapping(b Bundle, m RelocationMapping) ([]byte, error) {
+	if err := m.Validate(b); err != nil {
+		return nil, errors.Wrap(err, "invalid relocation mapping")
+	}
+
+	if m == nil {
+		m = RelocationMapping{}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal the relocation mapping")
+	}
+	return data, nil
+}
@@ -0,0 +1,82 @@
+package bundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildImageMapBundle() Bundle {
+	return Bundle{
+		InvocationImages: []InvocationImage{
+			{BaseImage: BaseImage{ImageType: "docker", Image: "example.com/app-installer:v1"}},
+		},
+		Images: map[string]Image{
+			"web": {BaseImage: BaseImage{ImageType: "docker", Image: "example.com/web:v1", Digest: "sha256:abc"}, Description: "web server"},
+		},
+	}
+}
+
+func TestBuildImageMap(t *testing.T) {
+	t.Run("images", func(t *testing.T) {
+		data, err := BuildImageMap(buildImageMapBundle())
+		require.NoError(t, err)
+
+		var m ImageMap
+		require.NoError(t, json.Unmarshal(data, &m))
+		require.Contains(t, m, "web")
+		assert.Equal(t, "example.com/web:v1", m["web"].Image)
+		assert.Equal(t, "sha256:abc", m["web"].Digest)
+		assert.Equal(t, "web server", m["web"].Description)
+	})
+
+	t.Run("no images", func(t *testing.T) {
+		data, err := BuildImageMap(Bundle{})
+		require.NoError(t, err)
+		assert.Equal(t, "{}", string(data))
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		b := buildImageMapBundle()
+		b.Images["db"] = Image{}
+		_, err := BuildImageMap(b)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `image "db" does not specify an image reference`)
+	})
+}
+
+func TestBuildRelocationMapping(t *testing.T) {
+	b := buildImageMapBundle()
+
+	t.Run("valid", func(t *testing.T) {
+		m := RelocationMapping{
+			"example.com/app-installer:v1": "registry.internal/app-installer:v1",
+			"example.com/web:v1":           "registry.internal/web@sha256:8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4",
+		}
+		data, err := BuildRelocationMapping(b, m)
+		require.NoError(t, err)
+
+		var got RelocationMapping
+		require.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, m, got)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		data, err := BuildRelocationMapping(b, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "{}", string(data))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		m := RelocationMapping{
+			"example.com/unknown:v1": "registry.internal/unknown:v1",
+			"example.com/web:v1":     "Not A Reference",
+		}
+		_, err := BuildRelocationMapping(b, m)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `relocated image "example.com/unknown:v1" is not used by the bundle`)
+		assert.Contains(t, err.Error(), `invalid relocated reference "Not A Reference" for image "example.com/web:v1"`)
+	})
+}
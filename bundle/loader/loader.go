@@ -7,6 +7,8 @@ import (
 	"net/url"
 	"os"
 
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
 	"github.com/cnabio/cnab-go/bundle"
 )
 
@@ -19,7 +21,21 @@ type BundleLoader interface {
 }
 
 // Loader loads a bundle manifest (bundle.json)
-type Loader struct{}
+type Loader struct {
+	// HTTPClient downloads the bundles and the documents referenced by $ref
+	// from http(s) urls in LoadResolved. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// RegistryOptions configure the access to registries, for example their
+	// authentication, when LoadResolved pulls a bundle from a registry.
+	RegistryOptions []remote.Option
+
+	// AllowedRefOrigins are the origins, for example
+	// https://schemas.example.com, of the documents that the $refs of a
+	// bundle may reference in LoadResolved, in addition to the origin of the
+	// bundle itself.
+	AllowedRefOrigins []string
+}
 
 // New creates a loader for bundle files.
 // TODO: remove if unnecessary
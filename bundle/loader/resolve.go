@@ -0,0 +1,476 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/qri-io/jsonpointer"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const (
+	// OCIScheme prefixes the reference of a bundle in an OCI registry, for
+	// example oci://example.com/bundles/mysql:v1.0.0. References with a tag
+	// or a digest are recognized without the prefix.
+	OCIScheme = "oci://"
+
+	// CNABConfigMediaType is the media type of the config blob of the OCI
+	// manifest holding a bundle pushed to a registry, whose content is the
+	// bundle.json.
+	CNABConfigMediaType = "application/vnd.cnab.config.v1+json"
+
+	// CNABManifestTypeAnnotation is the annotation of the manifests in the
+	// OCI index of a bundle pushed to a registry, identifying the manifest
+	// holding the bundle.json by the CNABManifestTypeConfig value.
+	CNABManifestTypeAnnotation = "io.cnab.manifest.type"
+
+	// CNABManifestTypeConfig is the value of CNABManifestTypeAnnotation for
+	// the manifest holding the bundle.json.
+	CNABManifestTypeConfig = "config"
+
+	// MaxDocumentSize is the maximum size, in bytes, of the bundle and of
+	// each document referenced by its $refs that LoadResolved reads.
+	MaxDocumentSize = 10 << 20
+)
+
+// LoadResolved loads a bundle from a file path, a http(s) or file:// URL, or a
+// reference to a bundle in an OCI registry, such as
+// oci://example.com/bundles/mysql:v1.0.0. The JSON references, $ref, in the
+// definitions of the bundle are replaced with the schemas that they reference,
+// either in the bundle, e.g. #/definitions/port, or in other documents that
+// are loaded relative to the bundle, e.g. common.json#/definitions/port.
+// Keywords next to a $ref override those of the referenced schema.
+//
+// So that a bundle cannot make the loader request arbitrary urls, such as
+// internal services, a $ref can only reference a http(s) url with the same
+// origin as the bundle, or one of the AllowedRefOrigins of the loader, and
+// documents are read up to MaxDocumentSize.
+//
+// The digest of the canonical JSON of the resolved bundle, as written by
+// bundle.Marshal, is returned with the bundle.
+func (l *Loader) LoadResolved(source string) (*bundle.Bundle, digest.Digest, error) {
+	doc, base, err := l.loadDocument(source)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if root, ok := doc.(map[string]interface{}); ok {
+		if definitions, ok := root["definitions"]; ok {
+			r := &refResolver{loader: l, documents: map[string]interface{}{}}
+			if base != nil {
+				r.origin = urlOrigin(base)
+				r.documents[documentKey(base)] = doc
+			}
+			resolved, err := r.resolve(definitions, base, doc, nil)
+			if err != nil {
+				return nil, "", errors.Wrapf(err, "cannot resolve the definitions of bundle %s", source)
+			}
+			root["definitions"] = resolved
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := l.LoadData(data)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "cannot parse bundle %s", source)
+	}
+
+	canonical, err := b.Marshal()
+	if err != nil {
+		return nil, "", err
+	}
+	return b, digest.FromBytes(canonical), nil
+}
+
+// loadDocument loads the JSON document of the bundle, and returns the url
+// that the references in the document are relative to, which is nil for
+// bundles loaded from a registry.
+func (l *Loader) loadDocument(source string) (interface{}, *url.URL, error) {
+	if isLocalReference(source) {
+		u, err := fileURL(source)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc, err := l.fetchDocument(u, nil)
+		return doc, u, err
+	}
+
+	if ref, ok := parseOCIReference(source); ok {
+		data, err := l.pullBundle(ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc, err := decodeDocument(data)
+		return doc, nil, errors.Wrapf(err, "cannot parse bundle %s", source)
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file") {
+		return nil, nil, fmt.Errorf("bundle %q not found", source)
+	}
+	doc, err := l.fetchDocument(u, nil)
+	return doc, u, err
+}
+
+// parseOCIReference parses a reference to a bundle in an OCI registry, which
+// either has the oci:// prefix or has a tag or a digest, so that a missing
+// file is not mistaken for a reference.
+func parseOCIReference(source string) (name.Reference, bool) {
+	if strings.HasPrefix(source, OCIScheme) {
+		ref, err := name.ParseReference(strings.TrimPrefix(source, OCIScheme))
+		return ref, err == nil
+	}
+	if strings.Contains(source, "://") {
+		return nil, false
+	}
+	ref, err := name.ParseReference(source, name.StrictValidation)
+	return ref, err == nil
+}
+
+// pullBundle returns the bundle.json of the bundle in an OCI registry, which
+// is the config blob of either the manifest of the reference, or the manifest
+// of its index annotated as the config manifest.
+func (l *Loader) pullBundle(ref name.Reference) ([]byte, error) {
+	desc, err := remote.Get(ref, l.RegistryOptions...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot pull bundle %s", ref)
+	}
+
+	var img v1.Image
+	switch {
+	case desc.MediaType.IsIndex():
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot pull bundle %s", ref)
+		}
+		manifest, err := idx.IndexManifest()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot pull bundle %s", ref)
+		}
+		for _, m := range manifest.Manifests {
+			if m.Annotations[CNABManifestTypeAnnotation] == CNABManifestTypeConfig {
+				if img, err = idx.Image(m.Digest); err != nil {
+					return nil, errors.Wrapf(err, "cannot pull bundle %s", ref)
+				}
+				break
+			}
+		}
+		if img == nil {
+			return nil, fmt.Errorf("%s is not a bundle: its index has no manifest annotated with %s=%s", ref, CNABManifestTypeAnnotation, CNABManifestTypeConfig)
+		}
+	case desc.MediaType.IsImage():
+		if img, err = desc.Image(); err != nil {
+			return nil, errors.Wrapf(err, "cannot pull bundle %s", ref)
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a bundle: unexpected media type %s", ref, desc.MediaType)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot pull bundle %s", ref)
+	}
+	if manifest.Config.MediaType != CNABConfigMediaType {
+		return nil, fmt.Errorf("%s is not a bundle: its config has the media type %s instead of %s", ref, manifest.Config.MediaType, CNABConfigMediaType)
+	}
+	data, err := img.RawConfigFile()
+	return data, errors.Wrapf(err, "cannot pull bundle %s", ref)
+}
+
+// fetchDocument reads and decodes the JSON document at the file or http(s)
+// url. When checkURL is set, it must accept the urls that the request is
+// redirected to.
+func (l *Loader) fetchDocument(u *url.URL, checkURL func(*url.URL) error) (interface{}, error) {
+	var data []byte
+	switch u.Scheme {
+	case "file":
+		f, err := os.Open(filePath(u))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if data, err = readDocument(f); err != nil {
+			return nil, fmt.Errorf("cannot read %s: %v", u, err)
+		}
+	case "http", "https":
+		client := l.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		if checkURL != nil {
+			checked := *client
+			checkRedirect := client.CheckRedirect
+			checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+				if err := checkURL(req.URL); err != nil {
+					return err
+				}
+				if checkRedirect != nil {
+					return checkRedirect(req, via)
+				}
+				if len(via) >= 10 {
+					return errors.New("stopped after 10 redirects")
+				}
+				return nil
+			}
+			client = &checked
+		}
+		response, err := client.Get(u.String())
+		if err != nil {
+			return nil, fmt.Errorf("cannot download %s: %v", u, err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("cannot download %s: %s", u, response.Status)
+		}
+		if data, err = readDocument(response.Body); err != nil {
+			return nil, fmt.Errorf("cannot download %s: %v", u, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported url scheme %q of %s", u.Scheme, u)
+	}
+
+	doc, err := decodeDocument(data)
+	return doc, errors.Wrapf(err, "cannot parse %s", u)
+}
+
+// readDocument reads a document of at most MaxDocumentSize bytes.
+func readDocument(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDocumentSize {
+		return nil, fmt.Errorf("the document is larger than the maximum of %d bytes", MaxDocumentSize)
+	}
+	return data, nil
+}
+
+// decodeDocument decodes a JSON document, keeping the exact value of its
+// numbers.
+func decodeDocument(data []byte) (interface{}, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return doc, decoder.Decode(&doc)
+}
+
+// refResolver replaces the JSON references in a document with the values that
+// they reference.
+type refResolver struct {
+	loader *Loader
+
+	// origin of the bundle, e.g. https://example.com, which is empty for
+	// bundles loaded from a registry.
+	origin string
+
+	// documents that were loaded, by url without fragment.
+	documents map[string]interface{}
+}
+
+// resolve returns a copy of the value, from the document at base, where the
+// objects with a $ref are replaced with the value that they reference. The
+// stack of references being resolved detects circular references.
+func (r *refResolver) resolve(value interface{}, base *url.URL, doc interface{}, stack []string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			return r.resolveRef(ref, v, base, doc, stack)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			item, err := r.resolve(item, base, doc, stack)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = item
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			item, err := r.resolve(item, base, doc, stack)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = item
+		}
+		return resolved, nil
+	default:
+		return v, nil
+	}
+}
+
+// resolveRef returns the value referenced by the object, with the other
+// keywords of the object overriding those of the referenced value.
+func (r *refResolver) resolveRef(ref string, object map[string]interface{}, base *url.URL, doc interface{}, stack []string) (interface{}, error) {
+	target, targetBase, targetDoc, key, err := r.dereference(ref, base, doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range stack {
+		if k == key {
+			return nil, fmt.Errorf("circular $ref %q", ref)
+		}
+	}
+
+	resolved, err := r.resolve(target, targetBase, targetDoc, append(stack, key))
+	if err != nil {
+		return nil, err
+	}
+	if len(object) == 1 {
+		return resolved, nil
+	}
+
+	schema, ok := resolved.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("$ref %q does not reference an object, so it cannot have other keywords", ref)
+	}
+	for keyword, item := range object {
+		if keyword == "$ref" {
+			continue
+		}
+		item, err := r.resolve(item, base, doc, stack)
+		if err != nil {
+			return nil, err
+		}
+		schema[keyword] = item
+	}
+	return schema, nil
+}
+
+// dereference returns the value referenced by ref, from the document at base,
+// with the url and the document that it is in, and the key identifying it.
+func (r *refResolver) dereference(ref string, base *url.URL, doc interface{}) (interface{}, *url.URL, interface{}, string, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return nil, nil, nil, "", errors.Wrapf(err, "invalid $ref %q", ref)
+	}
+
+	targetBase, targetDoc := base, doc
+	if refURL.Scheme != "" || refURL.Host != "" || refURL.Path != "" {
+		switch {
+		case refURL.IsAbs():
+			targetBase = refURL
+		case base != nil:
+			targetBase = base.ResolveReference(refURL)
+		default:
+			return nil, nil, nil, "", fmt.Errorf("cannot resolve the relative $ref %q of a bundle loaded from a registry", ref)
+		}
+		if targetBase.Scheme == "file" && (base == nil || base.Scheme != "file") {
+			return nil, nil, nil, "", fmt.Errorf("$ref %q cannot reference a local file from a remote document", ref)
+		}
+		if err := r.checkURL(targetBase); err != nil {
+			return nil, nil, nil, "", errors.Wrapf(err, "$ref %q is not allowed", ref)
+		}
+
+		if targetDoc, err = r.document(targetBase); err != nil {
+			return nil, nil, nil, "", errors.Wrapf(err, "cannot resolve $ref %q", ref)
+		}
+	}
+
+	if refURL.Fragment != "" && !strings.HasPrefix(refURL.Fragment, "/") {
+		return nil, nil, nil, "", fmt.Errorf("invalid $ref %q: its fragment must be a JSON pointer", ref)
+	}
+	pointer, err := jsonpointer.Parse(refURL.Fragment)
+	if err != nil {
+		return nil, nil, nil, "", errors.Wrapf(err, "invalid $ref %q", ref)
+	}
+	target, err := pointer.Eval(targetDoc)
+	if err != nil || target == nil {
+		return nil, nil, nil, "", fmt.Errorf("$ref %q not found", ref)
+	}
+
+	key := "#" + refURL.Fragment
+	if targetBase != nil {
+		key = documentKey(targetBase) + key
+	}
+	return target, targetBase, targetDoc, key, nil
+}
+
+// document returns the document at the url, loading it the first time.
+func (r *refResolver) document(u *url.URL) (interface{}, error) {
+	key := documentKey(u)
+	if doc, ok := r.documents[key]; ok {
+		return doc, nil
+	}
+	doc, err := r.loader.fetchDocument(u, r.checkURL)
+	if err != nil {
+		return nil, err
+	}
+	r.documents[key] = doc
+	return doc, nil
+}
+
+// checkURL returns an error when the http(s) url does not have the origin of
+// the bundle or one of the allowed origins of the loader.
+func (r *refResolver) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+
+	origin := urlOrigin(u)
+	if origin == r.origin {
+		return nil
+	}
+	for _, allowed := range r.loader.AllowedRefOrigins {
+		if strings.ToLower(strings.TrimSuffix(allowed, "/")) == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is neither the origin of the bundle nor an allowed origin", origin)
+}
+
+// urlOrigin returns the origin of the url, its scheme and host, for example
+// https://example.com:8443.
+func urlOrigin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// documentKey returns the url of the document, without fragment.
+func documentKey(u *url.URL) string {
+	withoutFragment := *u
+	withoutFragment.Fragment = ""
+	withoutFragment.RawFragment = ""
+	return withoutFragment.String()
+}
+
+// fileURL returns the file:// url of the local file.
+func fileURL(path string) (*url.URL, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		// Windows paths start with a drive letter, e.g. /C:/bundle.json
+		abs = "/" + abs
+	}
+	return &url.URL{Scheme: "file", Path: abs}, nil
+}
+
+// filePath returns the local path of the file:// url.
+func filePath(u *url.URL) string {
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		// Windows paths start with a drive letter, e.g. /C:/bundle.json
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
@@ -0,0 +1,229 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const resolvedBundle = `{
+	"schemaVersion": "v1.0.0",
+	"name": "mybun",
+	"version": "v1.0.0",
+	"invocationImages": [{"image": "cnabio/mybunii:def456", "imageType": "docker"}],
+	"definitions": {
+		"port": {"$ref": "%s", "default": 8080},
+		"host": {"$ref": "#/definitions/hostname"},
+		"hostname": {"type": "string", "maxLength": 253}
+	}
+}`
+
+const commonDefinitions = `{
+	"definitions": {
+		"port": {"$ref": "#/definitions/integer", "minimum": 1, "maximum": 65535},
+		"integer": {"type": "integer"}
+	}
+}`
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func assertResolved(t *testing.T, bun *bundle.Bundle, dgst digest.Digest) {
+	data, err := bun.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, digest.FromBytes(data), dgst)
+}
+
+func TestLoader_LoadResolved_File(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "common.json"), commonDefinitions)
+	bundleFile := filepath.Join(dir, "bundle.json")
+	writeFile(t, bundleFile, fmt.Sprintf(resolvedBundle, "common.json#/definitions/port"))
+
+	bun, dgst, err := NewLoader().LoadResolved(bundleFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, "mybun", bun.Name)
+	port := bun.Definitions["port"]
+	require.NotNil(t, port)
+	assert.Equal(t, "integer", port.Type)
+	assert.Equal(t, float64(1), *port.Minimum)
+	assert.Equal(t, float64(65535), *port.Maximum)
+	assert.Equal(t, float64(8080), port.Default)
+
+	host := bun.Definitions["host"]
+	require.NotNil(t, host)
+	assert.Equal(t, "string", host.Type)
+	assert.Equal(t, 253, *host.MaxLength)
+
+	assertResolved(t, bun, dgst)
+
+	_, fileURLDigest, err := NewLoader().LoadResolved("file://" + filepath.ToSlash(bundleFile))
+	require.NoError(t, err)
+	assert.Equal(t, dgst, fileURLDigest, "the file url should load the same bundle")
+}
+
+func TestLoader_LoadResolved_Remote(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bundles/common.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, commonDefinitions)
+	})
+	mux.HandleFunc("/bundles/bundle.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, resolvedBundle, "common.json#/definitions/port")
+	})
+	mux.HandleFunc("/bundles/local.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, resolvedBundle, "file:///etc/common.json#/definitions/port")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	l := NewLoader()
+	l.HTTPClient = ts.Client()
+
+	bun, dgst, err := l.LoadResolved(ts.URL + "/bundles/bundle.json")
+	require.NoError(t, err)
+	assert.Equal(t, "integer", bun.Definitions["port"].Type)
+	assertResolved(t, bun, dgst)
+
+	_, _, err = l.LoadResolved(ts.URL + "/bundles/missing.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found")
+
+	_, _, err = l.LoadResolved(ts.URL + "/bundles/local.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot reference a local file from a remote document")
+}
+
+func TestLoader_LoadResolved_RefOrigins(t *testing.T) {
+	schemas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, commonDefinitions)
+	}))
+	defer schemas.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bundle.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, resolvedBundle, schemas.URL+"/common.json#/definitions/port")
+	})
+	mux.HandleFunc("/redirected.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, resolvedBundle, "redirect.json#/definitions/port")
+	})
+	mux.HandleFunc("/redirect.json", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, schemas.URL+"/common.json", http.StatusFound)
+	})
+	mux.HandleFunc("/large.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"`+strings.Repeat("a", MaxDocumentSize)+`"}`)
+	})
+	bundles := httptest.NewServer(mux)
+	defer bundles.Close()
+
+	l := NewLoader()
+	_, _, err := l.LoadResolved(bundles.URL + "/bundle.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is neither the origin of the bundle nor an allowed origin", "a $ref should not reference another origin by default")
+
+	_, _, err = l.LoadResolved(bundles.URL + "/redirected.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is neither the origin of the bundle nor an allowed origin", "a $ref should not be redirected to another origin")
+
+	bundleFile := filepath.Join(t.TempDir(), "bundle.json")
+	writeFile(t, bundleFile, fmt.Sprintf(resolvedBundle, schemas.URL+"/common.json#/definitions/port"))
+	_, _, err = l.LoadResolved(bundleFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is neither the origin of the bundle nor an allowed origin", "a local bundle should not reference a url by default")
+
+	l.AllowedRefOrigins = []string{schemas.URL + "/"}
+	bun, _, err := l.LoadResolved(bundles.URL + "/bundle.json")
+	require.NoError(t, err)
+	assert.Equal(t, "integer", bun.Definitions["port"].Type)
+	bun, _, err = l.LoadResolved(bundles.URL + "/redirected.json")
+	require.NoError(t, err)
+	assert.Equal(t, "integer", bun.Definitions["port"].Type)
+
+	_, _, err = l.LoadResolved(bundles.URL + "/large.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("the document is larger than the maximum of %d bytes", MaxDocumentSize))
+}
+
+func TestLoader_LoadResolved_InvalidRefs(t *testing.T) {
+	testcases := []struct {
+		name    string
+		ref     string
+		wantErr string
+	}{
+		{name: "missing definition", ref: "#/definitions/missing", wantErr: `$ref "#/definitions/missing" not found`},
+		{name: "circular", ref: "#/definitions/port", wantErr: `circular $ref "#/definitions/port"`},
+		{name: "missing document", ref: "missing.json#/definitions/port", wantErr: `cannot resolve $ref "missing.json#/definitions/port"`},
+		{name: "anchor", ref: "#port", wantErr: `invalid $ref "#port": its fragment must be a JSON pointer`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			bundleFile := filepath.Join(t.TempDir(), "bundle.json")
+			writeFile(t, bundleFile, fmt.Sprintf(resolvedBundle, tc.ref))
+
+			_, _, err := NewLoader().LoadResolved(bundleFile)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+// cnabManifest is the manifest of a bundle pushed to a registry.
+type cnabManifest struct {
+	manifest []byte
+}
+
+func (m cnabManifest) RawManifest() ([]byte, error) {
+	return m.manifest, nil
+}
+
+func (m cnabManifest) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
+
+func TestLoader_LoadResolved_Registry(t *testing.T) {
+	ts := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	data := []byte(fmt.Sprintf(resolvedBundle, "#/definitions/hostname"))
+	repo, err := name.NewRepository(host + "/bundles/mybun")
+	require.NoError(t, err)
+	config := static.NewLayer(data, CNABConfigMediaType)
+	require.NoError(t, remote.WriteLayer(repo, config))
+
+	configDigest, err := config.Digest()
+	require.NoError(t, err)
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":%q,"digest":%q,"size":%d},"layers":[]}`,
+		types.OCIManifestSchema1, CNABConfigMediaType, configDigest, len(data))
+	ref, err := name.ParseReference(host + "/bundles/mybun:v1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, remote.Put(ref, cnabManifest{manifest: []byte(manifest)}))
+
+	bun, dgst, err := NewLoader().LoadResolved(OCIScheme + ref.String())
+	require.NoError(t, err)
+	assert.Equal(t, "mybun", bun.Name)
+	assert.Equal(t, "string", bun.Definitions["port"].Type)
+	assertResolved(t, bun, dgst)
+
+	_, dgstWithoutScheme, err := NewLoader().LoadResolved(ref.String())
+	require.NoError(t, err)
+	assert.Equal(t, dgst, dgstWithoutScheme)
+}
@@ -2,6 +2,8 @@ package bundle
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
@@ -64,3 +66,105 @@ func (o *Output) Validate(name string, bun Bundle) error {
 
 	return valResult.ErrorOrNil()
 }
+
+// OutputPathConflict describes outputs that are captured from the same path
+// by at least one action. Only one of the outputs can be captured, so which
+// one is set is nondeterministic.
+type OutputPathConflict struct {
+	// Path in the invocation image shared by the outputs.
+	Path string
+
+	// Outputs that share the path, sorted by name.
+	Outputs []string
+
+	// Actions that the conflicting outputs apply to, sorted by name.
+	Actions []string
+}
+
+func (c OutputPathConflict) Error() string {
+	return fmt.Sprintf("outputs %s share the path %s and apply to the same action(s): %s",
+		strings.Join(c.Outputs, ", "), c.Path, strings.Join(c.Actions, ", "))
+}
+
+// GetOutputPathConflicts returns the outputs that share the same path and
+// apply to the same action, sorted by path. Outputs with the same path that
+// never apply to the same action, for example one that applies to install and
+// another to uninstall, do not conflict.
+func (b Bundle) GetOutputPathConflicts() []OutputPathConflict {
+	outputsByPath := make(map[string][]string, len(b.Outputs))
+	for name, output := range b.Outputs {
+		outputsByPath[output.Path] = append(outputsByPath[output.Path], name)
+	}
+
+	actions := b.listOutputActions()
+
+	var conflicts []OutputPathConflict
+	for path, names := range outputsByPath {
+		if len(names) < 2 {
+			continue
+		}
+
+		conflictingOutputs := map[string]struct{}{}
+		var conflictingActions []string
+		for _, action := range actions {
+			var applicable []string
+			for _, name := range names {
+				if b.Outputs[name].AppliesTo(action) {
+					applicable = append(applicable, name)
+				}
+			}
+
+			if len(applicable) > 1 {
+				conflictingActions = append(conflictingActions, action)
+				for _, name := range applicable {
+					conflictingOutputs[name] = struct{}{}
+				}
+			}
+		}
+
+		if len(conflictingActions) == 0 {
+			continue
+		}
+
+		conflict := OutputPathConflict{
+			Path:    path,
+			Outputs: make([]string, 0, len(conflictingOutputs)),
+			Actions: conflictingActions,
+		}
+		for name := range conflictingOutputs {
+			conflict.Outputs = append(conflict.Outputs, name)
+		}
+		sort.Strings(conflict.Outputs)
+		conflicts = append(conflicts, conflict)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Path < conflicts[j].Path
+	})
+	return conflicts
+}
+
+// listOutputActions returns every action that an output may apply to, sorted
+// by name: the core actions, custom actions and any referenced by an output.
+func (b Bundle) listOutputActions() []string {
+	actionSet := map[string]struct{}{
+		"install":   {},
+		"upgrade":   {},
+		"uninstall": {},
+	}
+	for action := range b.Actions {
+		actionSet[action] = struct{}{}
+	}
+	for _, output := range b.Outputs {
+		for _, action := range output.ApplyTo {
+			actionSet[action] = struct{}{}
+		}
+	}
+
+	actions := make([]string, 0, len(actionSet))
+	for action := range actionSet {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
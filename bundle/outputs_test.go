@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/cnabio/cnab-go/bundle/definition"
 )
@@ -38,3 +39,77 @@ func TestOutputValidate(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestBundle_GetOutputPathConflicts(t *testing.T) {
+	testcases := []struct {
+		name    string
+		outputs map[string]Output
+		want    []OutputPathConflict
+	}{
+		{
+			name: "distinct paths",
+			outputs: map[string]Output{
+				"a": {Definition: "string", Path: "/cnab/app/outputs/a"},
+				"b": {Definition: "string", Path: "/cnab/app/outputs/b"},
+			},
+		},
+		{
+			name: "same path for different actions",
+			outputs: map[string]Output{
+				"a": {Definition: "string", Path: "/cnab/app/outputs/a", ApplyTo: []string{"install"}},
+				"b": {Definition: "number", Path: "/cnab/app/outputs/a", ApplyTo: []string{"uninstall"}},
+			},
+		},
+		{
+			name: "same path for all actions",
+			outputs: map[string]Output{
+				"a": {Definition: "string", Path: "/cnab/app/outputs/a"},
+				"b": {Definition: "number", Path: "/cnab/app/outputs/a"},
+			},
+			want: []OutputPathConflict{
+				{Path: "/cnab/app/outputs/a", Outputs: []string{"a", "b"}, Actions: []string{"install", "status", "uninstall", "upgrade"}},
+			},
+		},
+		{
+			name: "overlapping applyTo",
+			outputs: map[string]Output{
+				"a": {Definition: "string", Path: "/cnab/app/outputs/a", ApplyTo: []string{"install", "upgrade"}},
+				"b": {Definition: "string", Path: "/cnab/app/outputs/a", ApplyTo: []string{"upgrade"}},
+				"c": {Definition: "string", Path: "/cnab/app/outputs/a", ApplyTo: []string{"uninstall"}},
+			},
+			want: []OutputPathConflict{
+				{Path: "/cnab/app/outputs/a", Outputs: []string{"a", "b"}, Actions: []string{"upgrade"}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := Bundle{
+				Actions: map[string]Action{"status": {}},
+				Outputs: tc.outputs,
+			}
+			assert.Equal(t, tc.want, b.GetOutputPathConflicts())
+		})
+	}
+}
+
+func TestValidateOutputPathConflicts(t *testing.T) {
+	b := Bundle{
+		Version:          "0.1.0",
+		SchemaVersion:    "99.98",
+		InvocationImages: []InvocationImage{{BaseImage{}}},
+		Definitions: definition.Definitions{
+			"string": &definition.Schema{Type: "string"},
+		},
+		Outputs: map[string]Output{
+			"a": {Definition: "string", Path: "/cnab/app/outputs/a", ApplyTo: []string{"install"}},
+			"b": {Definition: "string", Path: "/cnab/app/outputs/a", ApplyTo: []string{"install", "upgrade"}},
+		},
+	}
+
+	err := b.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "validation failed for outputs")
+	assert.Contains(t, err.Error(), "outputs a, b share the path /cnab/app/outputs/a and apply to the same action(s): install")
+}
@@ -0,0 +1,189 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ParameterSourcesExtensionKey represents the full key for the Parameter Sources Extension.
+	ParameterSourcesExtensionKey = "io.cnab.parameter-sources"
+
+	// ParameterSourcesSchema represents the schema for the Parameter Sources Extension.
+	ParameterSourcesSchema = "https://cnab.io/v1/parameter-sources.schema.json"
+
+	// ParameterSourceTypeOutput defines a type of parameter source that accepts
+	// an output from a previous run of the bundle.
+	ParameterSourceTypeOutput = "output"
+)
+
+// ParameterSources describes the set of custom extension metadata associated
+// with the Parameter Sources extension, keyed by the name of the parameter.
+type ParameterSources map[string]ParameterSource
+
+// SetParameterFromOutput creates an entry in the parameter sources section
+// setting the parameter's value using the specified output.
+func (ps ParameterSources) SetParameterFromOutput(parameter string, output string) {
+	ps[parameter] = ParameterSource{
+		Priority: []string{ParameterSourceTypeOutput},
+		Sources: ParameterSourceMap{
+			ParameterSourceTypeOutput: OutputParameterSource{OutputName: output},
+		},
+	}
+}
+
+// Validate checks that each parameter source references a parameter and
+// output defined in the bundle, and that the sources are consistent with their
+// priority.
+func (ps ParameterSources) Validate(b Bundle) error {
+	for paramName, src := range ps {
+		if _, ok := b.Parameters[paramName]; !ok {
+			return fmt.Errorf("parameter source defined for undefined parameter %q", paramName)
+		}
+
+		err := src.Validate(b)
+		if err != nil {
+			return errors.Wrapf(err, "invalid parameter source for parameter %q", paramName)
+		}
+	}
+
+	return nil
+}
+
+// ParameterSource defines a single parameter source, which specifies where
+// the value of a parameter may be sourced from when one is not provided.
+type ParameterSource struct {
+	// Priority is an array of source types in the priority order that they
+	// should be used to populate the parameter.
+	Priority []string `json:"priority" yaml:"priority"`
+
+	// Sources is a map of key/value pairs of a source type and definition for
+	// the parameter value.
+	Sources ParameterSourceMap `json:"sources" yaml:"sources"`
+}
+
+// ListSourcesByPriority returns the parameter sources by the requested priority,
+// if none is specified, they are unsorted.
+func (s ParameterSource) ListSourcesByPriority() []ParameterSourceDefinition {
+	sources := make([]ParameterSourceDefinition, 0, len(s.Sources))
+	if len(s.Priority) == 0 {
+		for _, source := range s.Sources {
+			sources = append(sources, source)
+		}
+	} else {
+		for _, sourceType := range s.Priority {
+			if source, ok := s.Sources[sourceType]; ok {
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources
+}
+
+// Validate the parameter source.
+func (s ParameterSource) Validate(b Bundle) error {
+	if len(s.Sources) == 0 {
+		return errors.New("at least one source must be defined")
+	}
+
+	for _, sourceType := range s.Priority {
+		if _, ok := s.Sources[sourceType]; !ok {
+			return fmt.Errorf("source type %q is listed in the priority but is not defined in the sources", sourceType)
+		}
+	}
+
+	for sourceType, source := range s.Sources {
+		switch src := source.(type) {
+		case OutputParameterSource:
+			if src.OutputName == "" {
+				return fmt.Errorf("source %q must specify an output name", sourceType)
+			}
+			if _, ok := b.Outputs[src.OutputName]; !ok {
+				return fmt.Errorf("source %q references undefined output %q", sourceType, src.OutputName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParameterSourceMap maps the type of each parameter source to its definition.
+type ParameterSourceMap map[string]ParameterSourceDefinition
+
+// UnmarshalJSON converts each source definition into the typed struct for
+// its source type. Unrecognized source types are unmarshaled as generic maps.
+func (m *ParameterSourceMap) UnmarshalJSON(data []byte) error {
+	if *m == nil {
+		*m = ParameterSourceMap{}
+	}
+
+	var rawMap map[string]json.RawMessage
+	err := json.Unmarshal(data, &rawMap)
+	if err != nil {
+		return err
+	}
+
+	for sourceType, rawDef := range rawMap {
+		switch sourceType {
+		case ParameterSourceTypeOutput:
+			var output OutputParameterSource
+			err := json.Unmarshal(rawDef, &output)
+			if err != nil {
+				return errors.Wrapf(err, "invalid parameter source definition for key %s", sourceType)
+			}
+			(*m)[sourceType] = output
+		default:
+			var def map[string]interface{}
+			err := json.Unmarshal(rawDef, &def)
+			if err != nil {
+				return errors.Wrapf(err, "invalid parameter source definition for key %s", sourceType)
+			}
+			(*m)[sourceType] = def
+		}
+	}
+
+	return nil
+}
+
+// ParameterSourceDefinition defines a source of a parameter value, for
+// example an OutputParameterSource.
+type ParameterSourceDefinition interface{}
+
+// OutputParameterSource represents a parameter that is set using the value from
+// an output of a previous run of the bundle.
+type OutputParameterSource struct {
+	// OutputName is the name of the output that is the source of the parameter.
+	OutputName string `json:"name" yaml:"name"`
+}
+
+// HasParameterSources returns whether or not the bundle has parameter sources
+// defined.
+func (b Bundle) HasParameterSources() bool {
+	_, ok := b.Custom[ParameterSourcesExtensionKey]
+	return ok
+}
+
+// ReadParameterSources is a convenience method for returning a bonafide
+// ParameterSources reference after reading from the applicable section from
+// the provided bundle.
+func (b Bundle) ReadParameterSources() (ParameterSources, error) {
+	raw, ok := b.Custom[ParameterSourcesExtensionKey]
+	if !ok {
+		return ParameterSources{}, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not marshal the untyped %q extension data", ParameterSourcesExtensionKey)
+	}
+
+	ps := ParameterSources{}
+	err = json.Unmarshal(data, &ps)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal the %q extension", ParameterSourcesExtensionKey)
+	}
+
+	return ps, nil
+}
@@ -0,0 +1,145 @@
+package bundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle/definition"
+)
+
+func buildParameterSourcesBundle() Bundle {
+	return Bundle{
+		Definitions: definition.Definitions{
+			"string": {Type: "string"},
+		},
+		Parameters: map[string]Parameter{
+			"tfstate": {Definition: "string", Destination: &Location{Path: "/cnab/app/tfstate"}},
+		},
+		Outputs: map[string]Output{
+			"tfstate": {Definition: "string", Path: "/cnab/app/outputs/tfstate"},
+		},
+	}
+}
+
+func TestReadParameterSources(t *testing.T) {
+	data := []byte(`{
+		"tfstate": {
+			"priority": ["output"],
+			"sources": {
+				"output": {"name": "tfstate"},
+				"custom": {"foo": "bar"}
+			}
+		}
+	}`)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+
+	b := buildParameterSourcesBundle()
+	b.Custom = map[string]interface{}{
+		ParameterSourcesExtensionKey: raw,
+	}
+
+	require.True(t, b.HasParameterSources())
+	ps, err := b.ReadParameterSources()
+	require.NoError(t, err)
+
+	require.Contains(t, ps, "tfstate")
+	src := ps["tfstate"]
+	assert.Equal(t, []string{"output"}, src.Priority)
+	assert.Equal(t, OutputParameterSource{OutputName: "tfstate"}, src.Sources[ParameterSourceTypeOutput])
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, src.Sources["custom"])
+	assert.Equal(t, []ParameterSourceDefinition{OutputParameterSource{OutputName: "tfstate"}}, src.ListSourcesByPriority())
+
+	assert.NoError(t, ps.Validate(b))
+}
+
+func TestReadParameterSources_Missing(t *testing.T) {
+	b := Bundle{}
+	assert.False(t, b.HasParameterSources())
+
+	ps, err := b.ReadParameterSources()
+	require.NoError(t, err)
+	assert.Empty(t, ps)
+}
+
+func TestParameterSources_SetParameterFromOutput(t *testing.T) {
+	ps := ParameterSources{}
+	ps.SetParameterFromOutput("tfstate", "tfstate")
+
+	b := buildParameterSourcesBundle()
+	b.Custom = map[string]interface{}{
+		ParameterSourcesExtensionKey: ps,
+	}
+
+	got, err := b.ReadParameterSources()
+	require.NoError(t, err)
+	assert.Equal(t, ps, got)
+}
+
+func TestParameterSources_Validate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		sources ParameterSources
+		wantErr string
+	}{
+		{
+			name: "valid",
+			sources: ParameterSources{
+				"tfstate": {
+					Priority: []string{ParameterSourceTypeOutput},
+					Sources:  ParameterSourceMap{ParameterSourceTypeOutput: OutputParameterSource{OutputName: "tfstate"}},
+				},
+			},
+		},
+		{
+			name: "undefined parameter",
+			sources: ParameterSources{
+				"missing": {
+					Sources: ParameterSourceMap{ParameterSourceTypeOutput: OutputParameterSource{OutputName: "tfstate"}},
+				},
+			},
+			wantErr: `parameter source defined for undefined parameter "missing"`,
+		},
+		{
+			name: "undefined output",
+			sources: ParameterSources{
+				"tfstate": {
+					Sources: ParameterSourceMap{ParameterSourceTypeOutput: OutputParameterSource{OutputName: "missing"}},
+				},
+			},
+			wantErr: `invalid parameter source for parameter "tfstate": source "output" references undefined output "missing"`,
+		},
+		{
+			name: "no sources",
+			sources: ParameterSources{
+				"tfstate": {},
+			},
+			wantErr: `invalid parameter source for parameter "tfstate": at least one source must be defined`,
+		},
+		{
+			name: "priority not in sources",
+			sources: ParameterSources{
+				"tfstate": {
+					Priority: []string{"custom"},
+					Sources:  ParameterSourceMap{ParameterSourceTypeOutput: OutputParameterSource{OutputName: "tfstate"}},
+				},
+			},
+			wantErr: `invalid parameter source for parameter "tfstate": source type "custom" is listed in the priority but is not defined in the sources`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := buildParameterSourcesBundle()
+			err := tc.sources.Validate(b)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
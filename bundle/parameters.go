@@ -16,6 +16,21 @@ type Parameter struct {
 	Required    bool      `json:"required,omitempty" yaml:"required,omitempty"`
 }
 
+// IsParameterSensitive is a convenience function that determines if a parameter's
+// value is sensitive.
+func (b Bundle) IsParameterSensitive(parameterName string) (bool, error) {
+	if param, ok := b.Parameters[parameterName]; ok {
+		if def, ok := b.Definitions[param.Definition]; ok {
+			sensitive := def.WriteOnly != nil && *def.WriteOnly
+			return sensitive, nil
+		}
+
+		return false, fmt.Errorf("parameter definition %q not found", param.Definition)
+	}
+
+	return false, fmt.Errorf("parameter %q not defined", parameterName)
+}
+
 // GetApplyTo returns the list of actions that the Parameter applies to.
 func (p *Parameter) GetApplyTo() []string {
 	return p.ApplyTo
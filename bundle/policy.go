@@ -0,0 +1,140 @@
+package bundle
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// ImagePolicy restricts the registries and repositories of the invocation
+// images and images referenced by a bundle, for example to only allow images
+// from an organization's registry mirror.
+//
+// Each pattern is matched with path.Match, so that * matches any sequence of
+// characters except /. A repository pattern ending with /** also matches any
+// repository nested below the prefix. A policy without any patterns allows
+// every image.
+type ImagePolicy struct {
+	// AllowedRegistries are patterns of the registries that images may be
+	// pulled from, for example "myregistry.azurecr.io" or "*.gcr.io". Images
+	// without a registry are on docker.io.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty" yaml:"allowedRegistries,omitempty"`
+
+	// AllowedRepositories are patterns of the repositories, without their
+	// registry, that images may be pulled from, for example "myorg/*" or
+	// "myorg/**". Official images on docker.io are in the library namespace.
+	AllowedRepositories []string `json:"allowedRepositories,omitempty" yaml:"allowedRepositories,omitempty"`
+}
+
+// ImagePolicyViolation describes an image of the bundle that is not allowed
+// by an ImagePolicy.
+type ImagePolicyViolation struct {
+	// Location of the image in the bundle, for example invocationImages[0]
+	// or images.web.
+	Location string `json:"location"`
+
+	// Image is the reference of the image.
+	Image string `json:"image"`
+
+	// Reason that the image is not allowed.
+	Reason string `json:"reason"`
+}
+
+func (v ImagePolicyViolation) String() string {
+	return fmt.Sprintf("%s %s: %s", v.Location, v.Image, v.Reason)
+}
+
+// ImagePolicyError is returned when a bundle references images that are not
+// allowed by an ImagePolicy, and reports every violation.
+type ImagePolicyError struct {
+	Violations []ImagePolicyViolation `json:"violations"`
+}
+
+func (e *ImagePolicyError) Error() string {
+	violations := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		violations[i] = v.String()
+	}
+	return fmt.Sprintf("the bundle violates the image policy: %s", strings.Join(violations, "; "))
+}
+
+// Check the images of the bundle against the policy, returning an
+// *ImagePolicyError describing every image that is not allowed.
+func (p ImagePolicy) Check(b Bundle) error {
+	var violations []ImagePolicyViolation
+	check := func(location string, image string) {
+		if image == "" {
+			return
+		}
+		if reason := p.checkImage(image); reason != "" {
+			violations = append(violations, ImagePolicyViolation{Location: location, Image: image, Reason: reason})
+		}
+	}
+
+	for i, img := range b.InvocationImages {
+		check(fmt.Sprintf("invocationImages[%d]", i), img.Image)
+	}
+
+	names := make([]string, 0, len(b.Images))
+	for name := range b.Images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check("images."+name, b.Images[name].Image)
+	}
+
+	if len(violations) > 0 {
+		return &ImagePolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// checkImage returns the reason that the image is not allowed, or an empty
+// string when it is allowed.
+func (p ImagePolicy) checkImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Sprintf("invalid image reference: %v", err)
+	}
+
+	registry := reference.Domain(named)
+	if len(p.AllowedRegistries) > 0 && !matchesAny(p.AllowedRegistries, registry) {
+		return fmt.Sprintf("registry %s is not allowed", registry)
+	}
+
+	repository := reference.Path(named)
+	if len(p.AllowedRepositories) > 0 && !matchesAny(p.AllowedRepositories, repository) {
+		return fmt.Sprintf("repository %s is not allowed", repository)
+	}
+
+	return ""
+}
+
+// matchesAny determines if the value matches any of the patterns.
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if strings.HasPrefix(value, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateWithPolicy validates the bundle, as Validate does, and then checks
+// its images against the policy.
+func (b Bundle) ValidateWithPolicy(policy ImagePolicy) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	return policy.Check(b)
+}
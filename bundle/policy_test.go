@@ -0,0 +1,97 @@
+package bundle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImagePolicy_Check(t *testing.T) {
+	b := Bundle{
+		SchemaVersion: "1.2.0",
+		Name:          "mybun",
+		Version:       "0.1.0",
+		InvocationImages: []InvocationImage{
+			{BaseImage: BaseImage{ImageType: "docker", Image: "myregistry.example.com/myorg/mybun-installer:v0.1.0"}},
+		},
+		Images: map[string]Image{
+			"web":   {BaseImage: BaseImage{Image: "myregistry.example.com/myorg/apps/web:v1"}},
+			"db":    {BaseImage: BaseImage{Image: "mysql:5.7"}},
+			"cache": {BaseImage: BaseImage{Image: "ghcr.io/myorg/redis:6"}},
+		},
+	}
+
+	testcases := []struct {
+		name       string
+		policy     ImagePolicy
+		violations []ImagePolicyViolation
+	}{
+		{
+			name:   "empty policy",
+			policy: ImagePolicy{},
+		},
+		{
+			name:   "registry patterns",
+			policy: ImagePolicy{AllowedRegistries: []string{"*.example.com", "docker.io"}},
+			violations: []ImagePolicyViolation{
+				{Location: "images.cache", Image: "ghcr.io/myorg/redis:6", Reason: "registry ghcr.io is not allowed"},
+			},
+		},
+		{
+			name:   "single level repository pattern",
+			policy: ImagePolicy{AllowedRepositories: []string{"myorg/*", "library/*"}},
+			violations: []ImagePolicyViolation{
+				{Location: "images.web", Image: "myregistry.example.com/myorg/apps/web:v1", Reason: "repository myorg/apps/web is not allowed"},
+			},
+		},
+		{
+			name: "nested repository pattern",
+			policy: ImagePolicy{
+				AllowedRegistries:   []string{"myregistry.example.com"},
+				AllowedRepositories: []string{"myorg/**"},
+			},
+			violations: []ImagePolicyViolation{
+				{Location: "images.cache", Image: "ghcr.io/myorg/redis:6", Reason: "registry ghcr.io is not allowed"},
+				{Location: "images.db", Image: "mysql:5.7", Reason: "registry docker.io is not allowed"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Check(b)
+			if len(tc.violations) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var policyErr *ImagePolicyError
+			require.True(t, errors.As(err, &policyErr), "expected an ImagePolicyError, got %T", err)
+			assert.Equal(t, tc.violations, policyErr.Violations)
+		})
+	}
+
+	t.Run("invocation image", func(t *testing.T) {
+		err := ImagePolicy{AllowedRegistries: []string{"docker.io"}}.Check(b)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the bundle violates the image policy: invocationImages[0] myregistry.example.com/myorg/mybun-installer:v0.1.0: registry myregistry.example.com is not allowed; ")
+	})
+
+	t.Run("invalid reference", func(t *testing.T) {
+		invalid := Bundle{InvocationImages: []InvocationImage{{BaseImage: BaseImage{Image: "Invalid:Image"}}}}
+		err := ImagePolicy{AllowedRegistries: []string{"docker.io"}}.Check(invalid)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invocationImages[0] Invalid:Image: invalid image reference")
+	})
+
+	t.Run("validate with policy", func(t *testing.T) {
+		require.NoError(t, b.ValidateWithPolicy(ImagePolicy{AllowedRepositories: []string{"myorg/**", "library/*"}}))
+
+		err := b.ValidateWithPolicy(ImagePolicy{AllowedRegistries: []string{"ghcr.io"}})
+		var policyErr *ImagePolicyError
+		require.True(t, errors.As(err, &policyErr), "expected an ImagePolicyError, got %T", err)
+		assert.Len(t, policyErr.Violations, 3)
+	})
+}
@@ -0,0 +1,127 @@
+package bundle
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cnabio/cnab-go/bundle/definition"
+)
+
+// Kinds of values described by a Prompt.
+const (
+	PromptKindParameter  = "parameter"
+	PromptKindCredential = "credential"
+)
+
+// Prompt describes a value that a user supplies to run an action, such as a
+// parameter or a credential, in a form suitable for building interactive
+// prompts and web forms.
+type Prompt struct {
+	// Kind of value, either PromptKindParameter or PromptKindCredential.
+	Kind string `json:"kind"`
+
+	// Name of the parameter or credential.
+	Name string `json:"name"`
+
+	// Title of the value from its definition, when set.
+	Title string `json:"title,omitempty"`
+
+	// Description of the parameter or credential, falling back to the
+	// description of the parameter's definition.
+	Description string `json:"description,omitempty"`
+
+	// Types are the JSON schema types that the value may have, for example
+	// string or integer. Credentials are strings.
+	Types []string `json:"types,omitempty"`
+
+	// Required is true when the user must supply the value, because it is
+	// required and does not have a default.
+	Required bool `json:"required"`
+
+	// Default value of the parameter, when it has one.
+	Default interface{} `json:"default,omitempty"`
+
+	// Enum are the allowed values of the parameter, when they are restricted.
+	Enum []interface{} `json:"enum,omitempty"`
+
+	// Sensitive is true when the value should be masked while it is entered,
+	// such as a credential or a writeOnly parameter.
+	Sensitive bool `json:"sensitive"`
+
+	// Definition of the parameter, used to validate the value and to convert
+	// it from a string with ConvertValue. It is nil for credentials.
+	Definition *definition.Schema `json:"-"`
+}
+
+// GetPrompts returns the parameters and credentials that apply to the action,
+// in the order that they should be requested: the values that the user must
+// supply first, followed by the optional values. Within each group,
+// parameters come before credentials, sorted by name.
+func (b Bundle) GetPrompts(action string) ([]Prompt, error) {
+	prompts := make([]Prompt, 0, len(b.Parameters)+len(b.Credentials))
+
+	for name, param := range b.Parameters {
+		if !param.AppliesTo(action) {
+			continue
+		}
+
+		def, ok := b.Definitions[param.Definition]
+		if !ok {
+			return nil, fmt.Errorf("parameter definition %q not found", param.Definition)
+		}
+
+		prompt := Prompt{
+			Kind:        PromptKindParameter,
+			Name:        name,
+			Title:       def.Title,
+			Description: param.Description,
+			Types:       schemaTypes(def),
+			Required:    param.Required && def.Default == nil,
+			Default:     def.Default,
+			Enum:        def.Enum,
+			Sensitive:   def.WriteOnly != nil && *def.WriteOnly,
+			Definition:  def,
+		}
+		if prompt.Description == "" {
+			prompt.Description = def.Description
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	for name, cred := range b.Credentials {
+		if !cred.AppliesTo(action) {
+			continue
+		}
+
+		prompts = append(prompts, Prompt{
+			Kind:        PromptKindCredential,
+			Name:        name,
+			Description: cred.Description,
+			Types:       []string{"string"},
+			Required:    cred.Required,
+			Sensitive:   true,
+		})
+	}
+
+	sort.SliceStable(prompts, func(i, j int) bool {
+		if prompts[i].Required != prompts[j].Required {
+			return prompts[i].Required
+		}
+		if prompts[i].Kind != prompts[j].Kind {
+			return prompts[i].Kind == PromptKindParameter
+		}
+		return prompts[i].Name < prompts[j].Name
+	})
+	return prompts, nil
+}
+
+// schemaTypes returns the types allowed by the schema.
+func schemaTypes(s *definition.Schema) []string {
+	if t, ok, _ := s.GetType(); ok {
+		return []string{t}
+	}
+	if types, ok, _ := s.GetTypes(); ok {
+		return types
+	}
+	return nil
+}
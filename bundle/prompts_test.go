@@ -0,0 +1,81 @@
+package bundle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle/definition"
+)
+
+func TestBundle_GetPrompts(t *testing.T) {
+	writeOnly := true
+	b := Bundle{
+		Definitions: map[string]*definition.Schema{
+			"port":     {Type: "integer", Default: 8080, Title: "Port", Description: "port to listen on"},
+			"password": {Type: "string", WriteOnly: &writeOnly},
+			"size":     {Type: "string", Enum: []interface{}{"small", "large"}, Description: "size of the cluster"},
+			"flag":     {Type: []interface{}{"boolean", "string"}},
+		},
+		Parameters: map[string]Parameter{
+			"port":     {Definition: "port", Required: true},
+			"password": {Definition: "password", Required: true, Description: "admin password"},
+			"size":     {Definition: "size"},
+			"flag":     {Definition: "flag", ApplyTo: []string{"upgrade"}},
+		},
+		Credentials: map[string]Credential{
+			"kubeconfig": {Location: Location{Path: "/root/.kube/config"}, Required: true, Description: "cluster access"},
+			"token":      {Location: Location{EnvironmentVariable: "TOKEN"}, ApplyTo: []string{"install"}},
+			"cert":       {Location: Location{Path: "/cert"}, ApplyTo: []string{"upgrade"}},
+		},
+	}
+
+	prompts, err := b.GetPrompts("install")
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(prompts))
+	for _, p := range prompts {
+		names = append(names, p.Kind+":"+p.Name)
+	}
+	assert.Equal(t, []string{
+		"parameter:password",
+		"credential:kubeconfig",
+		"parameter:port",
+		"parameter:size",
+		"credential:token",
+	}, names, "values that must be supplied should be first")
+
+	password := prompts[0]
+	assert.True(t, password.Required)
+	assert.True(t, password.Sensitive)
+	assert.Equal(t, "admin password", password.Description)
+	assert.Equal(t, []string{"string"}, password.Types)
+
+	kubeconfig := prompts[1]
+	assert.True(t, kubeconfig.Required)
+	assert.True(t, kubeconfig.Sensitive, "credentials should be sensitive")
+	assert.Nil(t, kubeconfig.Definition)
+
+	port := prompts[2]
+	assert.False(t, port.Required, "required parameters with a default do not need to be supplied")
+	assert.Equal(t, 8080, port.Default)
+	assert.Equal(t, "Port", port.Title)
+	assert.Equal(t, "port to listen on", port.Description, "the description should fall back to the definition")
+	assert.False(t, port.Sensitive)
+
+	size := prompts[3]
+	assert.Equal(t, []interface{}{"small", "large"}, size.Enum)
+
+	prompts, err = b.GetPrompts("upgrade")
+	require.NoError(t, err)
+	require.Len(t, prompts, 6)
+	assert.Equal(t, "flag", prompts[2].Name)
+	assert.Equal(t, []string{"boolean", "string"}, prompts[2].Types)
+
+	t.Run("missing definition", func(t *testing.T) {
+		b := Bundle{Parameters: map[string]Parameter{"port": {Definition: "missing"}}}
+		_, err := b.GetPrompts("install")
+		require.EqualError(t, err, `parameter definition "missing" not found`)
+	})
+}
@@ -0,0 +1,179 @@
+package bundle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// spdxLicenses are the commonly used identifiers of the SPDX license list,
+// including deprecated identifiers that are still widely used, such as
+// GPL-2.0. Identifiers are matched case-insensitively.
+var spdxLicenses = []string{
+	"0BSD", "AFL-3.0", "AGPL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later",
+	"Apache-1.0", "Apache-1.1", "Apache-2.0", "APSL-2.0", "Artistic-1.0",
+	"Artistic-2.0", "Beerware", "BlueOak-1.0.0", "BSD-1-Clause", "BSD-2-Clause",
+	"BSD-2-Clause-Patent", "BSD-3-Clause", "BSD-3-Clause-Clear", "BSD-4-Clause",
+	"BSL-1.0", "BUSL-1.1", "bzip2-1.0.6", "CAL-1.0", "CC-BY-3.0", "CC-BY-4.0",
+	"CC-BY-NC-4.0", "CC-BY-NC-ND-4.0", "CC-BY-NC-SA-4.0", "CC-BY-ND-4.0",
+	"CC-BY-SA-3.0", "CC-BY-SA-4.0", "CC0-1.0", "CDDL-1.0", "CDDL-1.1",
+	"CECILL-2.1", "CPAL-1.0", "CPL-1.0", "curl", "ECL-2.0", "EFL-2.0",
+	"Elastic-2.0", "EPL-1.0", "EPL-2.0", "EUPL-1.1", "EUPL-1.2",
+	"GFDL-1.3-only", "GFDL-1.3-or-later", "GPL-1.0-only", "GPL-1.0-or-later",
+	"GPL-2.0", "GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0", "GPL-3.0-only",
+	"GPL-3.0-or-later", "ISC", "JSON", "LGPL-2.0-only", "LGPL-2.0-or-later",
+	"LGPL-2.1", "LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0",
+	"LGPL-3.0-only", "LGPL-3.0-or-later", "libpng-2.0", "LPL-1.02",
+	"LPPL-1.3c", "MIT", "MIT-0", "MPL-1.0", "MPL-1.1", "MPL-2.0",
+	"MPL-2.0-no-copyleft-exception", "MS-PL", "MS-RL", "MulanPSL-2.0", "NCSA",
+	"NTP", "ODbL-1.0", "OFL-1.1", "OLDAP-2.8", "OpenSSL", "OSL-3.0",
+	"PHP-3.01", "PostgreSQL", "Python-2.0", "QPL-1.0", "RPL-1.5", "Ruby",
+	"Sleepycat", "SSPL-1.0", "Unicode-DFS-2016", "Unlicense", "UPL-1.0", "Vim",
+	"W3C", "WTFPL", "X11", "Xnet", "Zlib", "zlib-acknowledgement", "ZPL-2.1",
+}
+
+// spdxExceptions are the commonly used identifiers of the SPDX license
+// exception list, used after WITH in a license expression.
+var spdxExceptions = []string{
+	"Autoconf-exception-3.0", "Bison-exception-2.2", "Classpath-exception-2.0",
+	"eCos-exception-2.0", "Font-exception-2.0", "freertos-exception-2.0",
+	"GCC-exception-3.1", "GPL-3.0-linking-exception", "GPL-CC-1.0",
+	"i2p-gpl-java-exception", "LGPL-3.0-linking-exception", "Linux-syscall-note",
+	"LLVM-exception", "OpenJDK-assembly-exception-1.0",
+	"openvpn-openssl-exception", "Qt-LGPL-exception-1.1", "Swift-exception",
+	"u-boot-exception-2.0", "Universal-FOSS-exception-1.0",
+	"WxWindows-exception-3.1",
+}
+
+// spdxLicenseRef matches the references to licenses that are not on the SPDX
+// license list, such as LicenseRef-Proprietary.
+var spdxLicenseRef = regexp.MustCompile(`^(DocumentRef-[A-Za-z0-9.-]+:)?LicenseRef-[A-Za-z0-9.-]+$`)
+
+// validateLicenseExpression validates that the license is an SPDX license
+// expression, for example "MIT" or "(Apache-2.0 OR MIT) AND
+// GPL-2.0-only WITH Classpath-exception-2.0", whose identifiers are known or
+// in the additional licenses.
+func validateLicenseExpression(license string, additionalLicenses []string) error {
+	known := make(map[string]bool, len(spdxLicenses)+len(additionalLicenses))
+	for _, l := range append(append([]string{}, spdxLicenses...), additionalLicenses...) {
+		known[strings.ToLower(l)] = true
+	}
+	exceptions := make(map[string]bool, len(spdxExceptions))
+	for _, e := range spdxExceptions {
+		exceptions[strings.ToLower(e)] = true
+	}
+
+	p := &spdxParser{
+		tokens:     tokenizeLicenseExpression(license),
+		licenses:   known,
+		exceptions: exceptions,
+	}
+	if len(p.tokens) == 0 {
+		return fmt.Errorf("license expression is empty")
+	}
+	if err := p.parseCompound(); err != nil {
+		return err
+	}
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("unexpected %q in license expression", p.tokens[p.pos])
+	}
+	return nil
+}
+
+func tokenizeLicenseExpression(license string) []string {
+	license = strings.ReplaceAll(license, "(", " ( ")
+	license = strings.ReplaceAll(license, ")", " ) ")
+	return strings.Fields(license)
+}
+
+// spdxParser parses the grammar of SPDX license expressions:
+//
+//	compound = term *( ("AND" / "OR") term )
+//	term     = "(" compound ")" / license [ "WITH" exception ]
+type spdxParser struct {
+	tokens     []string
+	pos        int
+	licenses   map[string]bool
+	exceptions map[string]bool
+}
+
+func (p *spdxParser) next() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, true
+}
+
+func (p *spdxParser) peekOperator(operators ...string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	for _, op := range operators {
+		if strings.EqualFold(p.tokens[p.pos], op) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *spdxParser) parseCompound() error {
+	if err := p.parseTerm(); err != nil {
+		return err
+	}
+	for p.peekOperator("AND", "OR") {
+		p.pos++
+		if err := p.parseTerm(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *spdxParser) parseTerm() error {
+	token, ok := p.next()
+	if !ok {
+		return fmt.Errorf("license expression is incomplete")
+	}
+
+	if token == "(" {
+		if err := p.parseCompound(); err != nil {
+			return err
+		}
+		if closing, ok := p.next(); !ok || closing != ")" {
+			return fmt.Errorf("license expression is missing a closing parenthesis")
+		}
+		return nil
+	}
+
+	if err := p.checkLicense(token); err != nil {
+		return err
+	}
+
+	if p.peekOperator("WITH") {
+		p.pos++
+		exception, ok := p.next()
+		if !ok {
+			return fmt.Errorf("license expression is missing an exception after WITH")
+		}
+		if !p.exceptions[strings.ToLower(exception)] {
+			return fmt.Errorf("%q is not a known SPDX license exception", exception)
+		}
+	}
+	return nil
+}
+
+func (p *spdxParser) checkLicense(token string) error {
+	if token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR") || strings.EqualFold(token, "WITH") {
+		return fmt.Errorf("unexpected %q in license expression", token)
+	}
+	if spdxLicenseRef.MatchString(token) {
+		return nil
+	}
+	// The + suffix means the version of the license or any later version
+	if !p.licenses[strings.ToLower(strings.TrimSuffix(token, "+"))] {
+		return fmt.Errorf("%q is not a known SPDX license identifier", token)
+	}
+	return nil
+}
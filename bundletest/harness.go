@@ -0,0 +1,253 @@
+// Package bundletest executes a table of scenarios against a bundle using a
+// driver and verifies the results, so that a bundle can be tested from a Go
+// test or CI pipeline without scripting a CNAB tool.
+package bundletest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/action"
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/cnabio/cnab-go/driver/lookup"
+	"github.com/cnabio/cnab-go/valuesource"
+)
+
+// DefaultInstallation is the installation name used when a Harness does not specify one.
+const DefaultInstallation = "bundletest"
+
+// Scenario is a single bundle operation and its expected results.
+type Scenario struct {
+	// Name of the scenario, used to identify it in test output.
+	Name string
+
+	// Action to execute, for example install.
+	Action string
+
+	// Parameters to pass to the bundle. Defaults from the bundle are used for
+	// parameters that are not specified.
+	Parameters map[string]interface{}
+
+	// Credentials to pass to the bundle.
+	Credentials valuesource.Set
+
+	// ExpectedStatus of the operation's result. Defaults to succeeded.
+	ExpectedStatus string
+
+	// ExpectedOutputs are the values of outputs that must be generated by the
+	// operation. Outputs that are not listed are not checked.
+	ExpectedOutputs map[string]string
+}
+
+// GetExpectedStatus returns the status that the operation must result in.
+func (s Scenario) GetExpectedStatus() string {
+	if s.ExpectedStatus == "" {
+		return claim.StatusSucceeded
+	}
+	return s.ExpectedStatus
+}
+
+// ScenarioResult is the outcome of executing a Scenario.
+type ScenarioResult struct {
+	// Scenario that was executed.
+	Scenario Scenario
+
+	// Claim used to execute the operation.
+	Claim claim.Claim
+
+	// Result of the operation.
+	Result claim.Result
+
+	// OperationResult returned by the driver, including the outputs.
+	OperationResult driver.OperationResult
+
+	// Failures are the expectations that were not met by the operation.
+	Failures []string
+}
+
+// Passed indicates if the operation met all of the scenario's expectations.
+func (r ScenarioResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Harness executes scenarios against a bundle.
+type Harness struct {
+	// Bundle under test.
+	Bundle bundle.Bundle
+
+	// Driver used to execute the bundle.
+	Driver driver.Driver
+
+	// Installation name used for the scenarios. Defaults to DefaultInstallation.
+	Installation string
+
+	// Out is where the output of the bundle is written. Defaults to discarding it.
+	Out io.Writer
+
+	// OperationConfigs are applied to the operation of every scenario.
+	OperationConfigs action.OperationConfigs
+}
+
+// New creates a Harness that executes the bundle with the specified driver.
+func New(b bundle.Bundle, d driver.Driver) *Harness {
+	return &Harness{
+		Bundle:       b,
+		Driver:       d,
+		Installation: DefaultInstallation,
+	}
+}
+
+// LookupDriver resolves a driver by name, for example debug or docker, and
+// applies the settings to it when the driver is configurable.
+func LookupDriver(name string, settings map[string]string) (driver.Driver, error) {
+	d, err := lookup.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if configurable, ok := d.(driver.Configurable); ok {
+		if settings == nil {
+			settings = map[string]string{}
+		}
+		if err := configurable.SetConfig(settings); err != nil {
+			return nil, errors.Wrapf(err, "could not configure the %s driver", name)
+		}
+	}
+
+	return d, nil
+}
+
+// Run executes the scenarios in order against the same installation, so that
+// for example an upgrade scenario runs against the preceding install. An error
+// is only returned when a scenario could not be executed; unmet expectations
+// are reported in the ScenarioResult.Failures.
+func (h *Harness) Run(scenarios []Scenario) ([]ScenarioResult, error) {
+	results := make([]ScenarioResult, 0, len(scenarios))
+
+	var lastClaim *claim.Claim
+	for _, s := range scenarios {
+		result, err := h.runScenario(s, lastClaim)
+		if err != nil {
+			return results, errors.Wrapf(err, "could not execute scenario %q", s.Name)
+		}
+		results = append(results, result)
+		lastClaim = &result.Claim
+	}
+
+	return results, nil
+}
+
+// RunTests executes the scenarios in order as subtests, failing each subtest
+// whose expectations are not met.
+func (h *Harness) RunTests(t *testing.T, scenarios []Scenario) {
+	var lastClaim *claim.Claim
+	for _, s := range scenarios {
+		result, err := h.runScenario(s, lastClaim)
+		t.Run(s.Name, func(t *testing.T) {
+			if err != nil {
+				t.Fatalf("could not execute scenario: %v", err)
+			}
+			for _, failure := range result.Failures {
+				t.Error(failure)
+			}
+		})
+		if err != nil {
+			return
+		}
+		lastClaim = &result.Claim
+	}
+}
+
+func (h *Harness) runScenario(s Scenario, lastClaim *claim.Claim) (ScenarioResult, error) {
+	params, err := bundle.ValuesOrDefaults(s.Parameters, &h.Bundle, s.Action)
+	if err != nil {
+		return ScenarioResult{}, errors.Wrap(err, "invalid parameters")
+	}
+
+	var c claim.Claim
+	if lastClaim == nil {
+		installation := h.Installation
+		if installation == "" {
+			installation = DefaultInstallation
+		}
+		c, err = claim.New(installation, s.Action, h.Bundle, params)
+	} else {
+		c, err = lastClaim.NewClaim(s.Action, h.Bundle, params)
+	}
+	if err != nil {
+		return ScenarioResult{}, err
+	}
+
+	creds := s.Credentials
+	if creds == nil {
+		creds = valuesource.Set{}
+	}
+
+	out := h.Out
+	if out == nil {
+		out = ioutil.Discard
+	}
+	opCfgs := append([]action.OperationConfigFunc{
+		func(op *driver.Operation) error {
+			op.Out = out
+			op.Err = out
+			return nil
+		},
+	}, h.OperationConfigs...)
+
+	a := action.New(h.Driver)
+	opResult, r, err := a.Run(c, creds, opCfgs...)
+	if err != nil {
+		return ScenarioResult{}, err
+	}
+
+	result := ScenarioResult{
+		Scenario:        s,
+		Claim:           c,
+		Result:          r,
+		OperationResult: opResult,
+	}
+	result.Failures = checkExpectations(s, r, opResult)
+	return result, nil
+}
+
+// checkExpectations compares the results of an operation against the
+// scenario, returning a description of each unmet expectation.
+func checkExpectations(s Scenario, r claim.Result, opResult driver.OperationResult) []string {
+	var failures []string
+
+	if r.Status != s.GetExpectedStatus() {
+		failure := fmt.Sprintf("expected status %s but got %s", s.GetExpectedStatus(), r.Status)
+		if opResult.Error != nil {
+			failure += fmt.Sprintf(": %v", opResult.Error)
+		}
+		failures = append(failures, failure)
+	}
+
+	outputNames := make([]string, 0, len(s.ExpectedOutputs))
+	for name := range s.ExpectedOutputs {
+		outputNames = append(outputNames, name)
+	}
+	sort.Strings(outputNames)
+
+	for _, name := range outputNames {
+		want := s.ExpectedOutputs[name]
+		got, ok := opResult.Outputs[name]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("expected output %s was not generated", name))
+			continue
+		}
+		if got != want {
+			failures = append(failures, fmt.Sprintf("expected output %s to be %q but got %q", name, want, got))
+		}
+	}
+
+	return failures
+}
@@ -0,0 +1,113 @@
+package bundletest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// echoDriver sets the output "greeting" to the value of the GREETING environment variable.
+type echoDriver struct {
+	ops []*driver.Operation
+}
+
+func (d *echoDriver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	d.ops = append(d.ops, op)
+	if op.Action == "fail" {
+		return driver.OperationResult{}, errors.New("oops")
+	}
+	return driver.OperationResult{
+		Outputs: map[string]string{"greeting": op.Environment["GREETING"]},
+	}, nil
+}
+
+func (d *echoDriver) Handles(string) bool {
+	return true
+}
+
+func testBundle() bundle.Bundle {
+	return bundle.Bundle{
+		SchemaVersion: bundle.GetDefaultSchemaVersion(),
+		Name:          "mybun",
+		Version:       "0.1.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: driver.ImageTypeDocker, Image: "example.com/mybun:0.1.0"}},
+		},
+		Actions: map[string]bundle.Action{
+			"fail": {},
+		},
+		Definitions: definition.Definitions{
+			"string": {Type: "string", Default: "hello"},
+		},
+		Parameters: map[string]bundle.Parameter{
+			"greeting": {Definition: "string", Destination: &bundle.Location{EnvironmentVariable: "GREETING"}},
+		},
+		Outputs: map[string]bundle.Output{
+			"greeting": {Definition: "string", Path: "/cnab/app/outputs/greeting"},
+		},
+	}
+}
+
+func TestHarness_Run(t *testing.T) {
+	d := &echoDriver{}
+	h := New(testBundle(), d)
+
+	results, err := h.Run([]Scenario{
+		{Name: "install with defaults", Action: claim.ActionInstall, ExpectedOutputs: map[string]string{"greeting": "hello"}},
+		{Name: "upgrade", Action: claim.ActionUpgrade, Parameters: map[string]interface{}{"greeting": "hi"}, ExpectedOutputs: map[string]string{"greeting": "hi"}},
+		{Name: "wrong output", Action: claim.ActionUpgrade, ExpectedOutputs: map[string]string{"greeting": "hi", "missing": "value"}},
+		{Name: "expected failure", Action: "fail", ExpectedStatus: claim.StatusFailed},
+		{Name: "unexpected failure", Action: "fail"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.True(t, results[0].Passed(), "%v", results[0].Failures)
+	assert.True(t, results[1].Passed(), "%v", results[1].Failures)
+	assert.Equal(t, []string{
+		`expected output greeting to be "hi" but got "hello"`,
+		"expected output missing was not generated",
+	}, results[2].Failures)
+	assert.True(t, results[3].Passed(), "%v", results[3].Failures)
+	require.Len(t, results[4].Failures, 1)
+	assert.Contains(t, results[4].Failures[0], "expected status succeeded but got failed")
+	assert.Contains(t, results[4].Failures[0], "oops", "the operation error should be included")
+
+	// The scenarios should run against the same installation
+	assert.Equal(t, DefaultInstallation, results[0].Claim.Installation)
+	assert.Equal(t, results[0].Claim.Installation, results[1].Claim.Installation)
+	assert.NotEqual(t, results[0].Claim.Revision, results[1].Claim.Revision, "the upgrade should create a new revision")
+}
+
+func TestHarness_Run_InvalidParameters(t *testing.T) {
+	h := New(testBundle(), &echoDriver{})
+
+	_, err := h.Run([]Scenario{
+		{Name: "bad param", Action: claim.ActionInstall, Parameters: map[string]interface{}{"greeting": 1}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `could not execute scenario "bad param": invalid parameters`)
+}
+
+func TestHarness_RunTests(t *testing.T) {
+	d, err := LookupDriver("debug", nil)
+	require.NoError(t, err)
+
+	h := New(testBundle(), d)
+	h.RunTests(t, []Scenario{
+		{Name: "install", Action: claim.ActionInstall, ExpectedOutputs: map[string]string{"greeting": "hello"}},
+		{Name: "uninstall", Action: claim.ActionUninstall},
+	})
+}
+
+func TestLookupDriver_Unknown(t *testing.T) {
+	_, err := LookupDriver("missing-driver", nil)
+	require.Error(t, err)
+}
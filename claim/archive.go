@@ -0,0 +1,385 @@
+package claim
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/schema"
+)
+
+// ArchiveFormat is the file format of a claims archive.
+type ArchiveFormat string
+
+const (
+	// ArchiveFormatTar writes the claims archive as an uncompressed tar file.
+	ArchiveFormatTar ArchiveFormat = "tar"
+
+	// ArchiveFormatZip writes the claims archive as a zip file.
+	ArchiveFormatZip ArchiveFormat = "zip"
+)
+
+// Validate checks that the archive format is supported.
+func (f ArchiveFormat) Validate() error {
+	switch f {
+	case ArchiveFormatTar, ArchiveFormatZip:
+		return nil
+	}
+	return fmt.Errorf("unsupported claims archive format %q, the supported formats are %s and %s", f, ArchiveFormatTar, ArchiveFormatZip)
+}
+
+// WriteArchive writes the claims, results and outputs of the installations,
+// or of every installation when none are specified, to w as a claims archive
+// in the specified format. The documents are laid out in the directories of
+// the CNAB claims specification, as described in the package documentation:
+//
+//	claims/INSTALLATION/CLAIM_ID.json
+//	results/CLAIM_ID/RESULT_ID.json
+//	outputs/RESULT_ID/RESULT_ID-OUTPUT_NAME
+//
+// so that the history of the installations can be read by other CNAB
+// compliant tools. The values of sensitive parameters and outputs are written
+// decrypted, so the archive must be protected accordingly.
+func (s Store) WriteArchive(w io.Writer, format ArchiveFormat, installations ...string) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	if len(installations) == 0 {
+		installations, err = s.ListInstallations()
+		if err != nil {
+			return err
+		}
+	}
+
+	aw := newArchiveWriter(w, format)
+	for _, installation := range installations {
+		if err := s.writeInstallationArchive(aw, installation); err != nil {
+			return errors.Wrapf(err, "error archiving installation %s", installation)
+		}
+	}
+	return errors.Wrap(aw.Close(), "error writing the claims archive")
+}
+
+func (s Store) writeInstallationArchive(aw archiveWriter, installation string) error {
+	claims, err := s.ReadAllClaims(installation)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range claims {
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "error marshaling claim %s", c.ID)
+		}
+		if err := aw.WriteFile(path.Join(ItemTypeClaims, installation, c.ID+".json"), data, c.Created); err != nil {
+			return err
+		}
+
+		results, err := s.ReadAllResults(c.ID)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			data, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return errors.Wrapf(err, "error marshaling result %s", r.ID)
+			}
+			if err := aw.WriteFile(path.Join(ItemTypeResults, c.ID, r.ID+".json"), data, r.Created); err != nil {
+				return err
+			}
+
+			outputNames, err := s.ListOutputs(r.ID)
+			if err != nil && !errors.Is(err, ErrResultNotFound) {
+				return err
+			}
+			for _, name := range outputNames {
+				o, err := s.ReadOutput(c, r, name)
+				if err != nil {
+					return err
+				}
+				if err := aw.WriteFile(path.Join(ItemTypeOutputs, r.ID, s.outputKey(r.ID, name)), o.Value, r.Created); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ImportArchive reads a claims archive in the specified format, such as
+// written by WriteArchive, and saves its claims, results and outputs. Every
+// document is validated before any of them is saved: claims against the
+// claim schema, and results with Result.Validate. Documents whose location in
+// the archive does not match their content, or that reference a claim or a
+// result that is not in the archive, are rejected. Documents that already
+// exist in the store are overwritten.
+func (s Store) ImportArchive(r io.Reader, format ArchiveFormat) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	files, err := readArchive(r, format)
+	if err != nil {
+		return errors.Wrap(err, "error reading the claims archive")
+	}
+
+	archive, err := parseClaimsArchive(files)
+	if err != nil {
+		return errors.Wrap(err, "invalid claims archive")
+	}
+
+	return s.saveClaimsArchive(archive)
+}
+
+// saveClaimsArchive saves the validated documents of a claims archive.
+func (s Store) saveClaimsArchive(archive claimsArchive) error {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range archive.claims {
+		if err := s.SaveClaim(c); err != nil {
+			return errors.Wrapf(err, "error importing claim %s", c.ID)
+		}
+	}
+	for _, r := range archive.results {
+		if err := s.SaveResult(r); err != nil {
+			return errors.Wrapf(err, "error importing result %s", r.ID)
+		}
+	}
+	for _, o := range archive.outputs {
+		if err := s.SaveOutput(o); err != nil {
+			return errors.Wrapf(err, "error importing output %s of result %s", o.Name, o.result.ID)
+		}
+	}
+	return nil
+}
+
+// claimsArchive holds the validated documents of a claims archive, in the
+// order that they are saved.
+type claimsArchive struct {
+	claims  []Claim
+	results []Result
+	outputs []Output
+}
+
+// parseClaimsArchive validates the documents of a claims archive, keyed by
+// their path in the archive.
+func parseClaimsArchive(files map[string][]byte) (claimsArchive, error) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var archive claimsArchive
+	claims := map[string]Claim{}
+	results := map[string]Result{}
+	type outputFile struct {
+		path     string
+		resultID string
+		name     string
+	}
+	var outputs []outputFile
+
+	for _, p := range paths {
+		data := files[p]
+		dir, file := path.Split(p)
+		parts := strings.SplitN(strings.TrimSuffix(dir, "/"), "/", 2)
+		if len(parts) != 2 || parts[1] == "" || file == "" {
+			return claimsArchive{}, fmt.Errorf("unexpected file %s", p)
+		}
+
+		switch parts[0] {
+		case ItemTypeClaims:
+			c, err := parseArchivedClaim(p, data)
+			if err != nil {
+				return claimsArchive{}, err
+			}
+			if c.Installation != parts[1] || c.ID+".json" != file {
+				return claimsArchive{}, fmt.Errorf("%s contains claim %s of installation %s", p, c.ID, c.Installation)
+			}
+			claims[c.ID] = c
+			archive.claims = append(archive.claims, c)
+		case ItemTypeResults:
+			var r Result
+			if err := json.Unmarshal(data, &r); err != nil {
+				return claimsArchive{}, errors.Wrapf(err, "error unmarshaling %s", p)
+			}
+			if err := r.Validate(); err != nil {
+				return claimsArchive{}, errors.Wrapf(err, "invalid result %s", p)
+			}
+			if r.ClaimID != parts[1] || r.ID+".json" != file {
+				return claimsArchive{}, fmt.Errorf("%s contains result %s of claim %s", p, r.ID, r.ClaimID)
+			}
+			results[r.ID] = r
+			archive.results = append(archive.results, r)
+		case ItemTypeOutputs:
+			prefix := parts[1] + "-"
+			if !strings.HasPrefix(file, prefix) || len(file) == len(prefix) {
+				return claimsArchive{}, fmt.Errorf("the output %s should be named %s-OUTPUT_NAME", p, parts[1])
+			}
+			outputs = append(outputs, outputFile{path: p, resultID: parts[1], name: strings.TrimPrefix(file, prefix)})
+		default:
+			return claimsArchive{}, fmt.Errorf("unexpected file %s", p)
+		}
+	}
+
+	for _, r := range archive.results {
+		if _, ok := claims[r.ClaimID]; !ok {
+			return claimsArchive{}, fmt.Errorf("the claim %s of result %s is not in the archive", r.ClaimID, r.ID)
+		}
+	}
+	for _, o := range outputs {
+		r, ok := results[o.resultID]
+		if !ok {
+			return claimsArchive{}, fmt.Errorf("the result %s of output %s is not in the archive", o.resultID, o.path)
+		}
+		archive.outputs = append(archive.outputs, NewOutput(claims[r.ClaimID], r, o.name, files[o.path]))
+	}
+
+	return archive, nil
+}
+
+// parseArchivedClaim validates a claim against the claim schema.
+func parseArchivedClaim(p string, data []byte) (Claim, error) {
+	valErrs, err := schema.ValidateClaim(data)
+	if err != nil {
+		return Claim{}, errors.Wrapf(err, "error validating %s", p)
+	}
+	if len(valErrs) > 0 {
+		msgs := make([]string, len(valErrs))
+		for i, valErr := range valErrs {
+			msgs[i] = valErr.Error()
+		}
+		return Claim{}, fmt.Errorf("%s does not match the claim schema: %s", p, strings.Join(msgs, "; "))
+	}
+
+	var c Claim
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Claim{}, errors.Wrapf(err, "error unmarshaling %s", p)
+	}
+	if err := c.Validate(); err != nil {
+		return Claim{}, errors.Wrapf(err, "invalid claim %s", p)
+	}
+	return c, nil
+}
+
+// archiveWriter writes the files of a claims archive.
+type archiveWriter interface {
+	WriteFile(name string, data []byte, modified time.Time) error
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) archiveWriter {
+	if format == ArchiveFormatZip {
+		return zipArchiveWriter{zip.NewWriter(w)}
+	}
+	return tarArchiveWriter{tar.NewWriter(w)}
+}
+
+type tarArchiveWriter struct {
+	*tar.Writer
+}
+
+func (w tarArchiveWriter) WriteFile(name string, data []byte, modified time.Time) error {
+	err := w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     int64(len(data)),
+		Mode:     0600,
+		ModTime:  modified,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error writing %s", name)
+	}
+	_, err = w.Write(data)
+	return errors.Wrapf(err, "error writing %s", name)
+}
+
+type zipArchiveWriter struct {
+	*zip.Writer
+}
+
+func (w zipArchiveWriter) WriteFile(name string, data []byte, modified time.Time) error {
+	f, err := w.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error writing %s", name)
+	}
+	_, err = f.Write(data)
+	return errors.Wrapf(err, "error writing %s", name)
+}
+
+// readArchive returns the regular files of an archive, keyed by their path.
+func readArchive(r io.Reader, format ArchiveFormat) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	if format == ArchiveFormatZip {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reading %s", f.Name)
+			}
+			data, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reading %s", f.Name)
+			}
+			files[path.Clean(f.Name)] = data
+		}
+		return files, nil
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", hdr.Name)
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+}
@@ -0,0 +1,198 @@
+package claim
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// archiveBundle is a bundle that is valid against the bundle schema, so
+// that its claims can be imported.
+var archiveBundle = bundle.Bundle{
+	SchemaVersion: "1.2.0",
+	Name:          "mysql",
+	Version:       "0.1.0",
+	InvocationImages: []bundle.InvocationImage{
+		{BaseImage: bundle.BaseImage{Image: "example.com/mysql:0.1.0", ImageType: "docker"}},
+	},
+	Definitions: claimStoreBundle.Definitions,
+	Outputs: map[string]bundle.Output{
+		"host":     {Definition: "string", Path: "/cnab/app/outputs/host"},
+		"password": {Definition: "password", Path: "/cnab/app/outputs/password"},
+	},
+}
+
+func generateArchiveData(t *testing.T, store Store, installation string) (Claim, Result) {
+	c, err := New(installation, ActionInstall, archiveBundle, map[string]interface{}{"port": 3306})
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+
+	r, err := c.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "host", []byte("mysql.example.com"))))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "password", []byte("topsecret"))))
+	return c, r
+}
+
+func TestStore_WriteArchive(t *testing.T) {
+	encrypt := func(data []byte) ([]byte, error) {
+		return append([]byte("encrypted:"), data...), nil
+	}
+	decrypt := func(data []byte) ([]byte, error) {
+		return data[len("encrypted:"):], nil
+	}
+
+	for _, format := range []ArchiveFormat{ArchiveFormatTar, ArchiveFormatZip} {
+		t.Run(string(format), func(t *testing.T) {
+			store := NewClaimStore(crud.NewMockStore(), encrypt, decrypt)
+			c, r := generateArchiveData(t, store, "mysql")
+			generateArchiveData(t, store, "wordpress")
+
+			var buf bytes.Buffer
+			require.NoError(t, store.WriteArchive(&buf, format, "mysql"))
+
+			files, err := readArchive(bytes.NewReader(buf.Bytes()), format)
+			require.NoError(t, err)
+			assert.Len(t, files, 4, "only the documents of the installation should be archived")
+			assert.Contains(t, files, "claims/mysql/"+c.ID+".json")
+			assert.Contains(t, files, "results/"+c.ID+"/"+r.ID+".json")
+			assert.Equal(t, "topsecret", string(files["outputs/"+r.ID+"/"+r.ID+"-password"]), "outputs should be archived decrypted")
+
+			imported := NewClaimStore(crud.NewMockStore(), nil, nil)
+			require.NoError(t, imported.ImportArchive(bytes.NewReader(buf.Bytes()), format))
+
+			gotClaim, err := imported.ReadClaim(c.ID)
+			require.NoError(t, err)
+			assert.Equal(t, c.Installation, gotClaim.Installation)
+			assert.Equal(t, float64(3306), gotClaim.Parameters["port"])
+
+			gotResult, err := imported.ReadResult(r.ID)
+			require.NoError(t, err)
+			assert.Equal(t, StatusSucceeded, gotResult.Status)
+
+			o, err := imported.ReadOutput(gotClaim, gotResult, "password")
+			require.NoError(t, err)
+			assert.Equal(t, "topsecret", string(o.Value))
+
+			_, err = imported.ListClaims("wordpress")
+			assert.ErrorIs(t, err, ErrInstallationNotFound)
+		})
+	}
+
+	t.Run("all installations", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		generateArchiveData(t, store, "mysql")
+		generateArchiveData(t, store, "wordpress")
+
+		var buf bytes.Buffer
+		require.NoError(t, store.WriteArchive(&buf, ArchiveFormatTar))
+
+		imported := NewClaimStore(crud.NewMockStore(), nil, nil)
+		require.NoError(t, imported.ImportArchive(&buf, ArchiveFormatTar))
+		installations, err := imported.ListInstallations()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"mysql", "wordpress"}, installations)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		err := store.WriteArchive(io.Discard, "rar")
+		assert.EqualError(t, err, `unsupported claims archive format "rar", the supported formats are tar and zip`)
+	})
+}
+
+func TestStore_ImportArchive_Invalid(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	c, r := generateArchiveData(t, store, "mysql")
+	var buf bytes.Buffer
+	require.NoError(t, store.WriteArchive(&buf, ArchiveFormatTar))
+	files, err := readArchive(&buf, ArchiveFormatTar)
+	require.NoError(t, err)
+
+	claimPath := "claims/mysql/" + c.ID + ".json"
+	resultPath := "results/" + c.ID + "/" + r.ID + ".json"
+	testcases := []struct {
+		name    string
+		modify  func(files map[string][]byte)
+		wantErr string
+	}{
+		{
+			name: "claim not matching the schema",
+			modify: func(files map[string][]byte) {
+				files[claimPath] = bytes.Replace(files[claimPath], []byte(`"action": "install"`), []byte(`"action": 1`), 1)
+			},
+			wantErr: "does not match the claim schema",
+		},
+		{
+			name: "claim in the wrong installation",
+			modify: func(files map[string][]byte) {
+				files["claims/wordpress/"+c.ID+".json"] = files[claimPath]
+				delete(files, claimPath)
+			},
+			wantErr: "contains claim " + c.ID + " of installation mysql",
+		},
+		{
+			name: "invalid result",
+			modify: func(files map[string][]byte) {
+				files[resultPath] = bytes.Replace(files[resultPath], []byte(`"status": "succeeded"`), []byte(`"status": "done"`), 1)
+			},
+			wantErr: "invalid status: done",
+		},
+		{
+			name: "missing claim",
+			modify: func(files map[string][]byte) {
+				delete(files, claimPath)
+			},
+			wantErr: "the claim " + c.ID + " of result " + r.ID + " is not in the archive",
+		},
+		{
+			name: "missing result",
+			modify: func(files map[string][]byte) {
+				delete(files, resultPath)
+			},
+			wantErr: "the result " + r.ID + " of output",
+		},
+		{
+			name: "unexpected file",
+			modify: func(files map[string][]byte) {
+				files["README.md"] = []byte("hello")
+			},
+			wantErr: "unexpected file README.md",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			modified := map[string][]byte{}
+			for p, data := range files {
+				modified[p] = data
+			}
+			tc.modify(modified)
+
+			var archive bytes.Buffer
+			tw := tar.NewWriter(&archive)
+			for p, data := range modified {
+				require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: p, Size: int64(len(data)), Mode: 0600}))
+				_, err := tw.Write(data)
+				require.NoError(t, err)
+			}
+			require.NoError(t, tw.Close())
+
+			imported := NewClaimStore(crud.NewMockStore(), nil, nil)
+			err := imported.ImportArchive(&archive, ArchiveFormatTar)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+
+			installations, _ := imported.ListInstallations()
+			assert.Empty(t, installations, "nothing should be imported from an invalid archive")
+		})
+	}
+}
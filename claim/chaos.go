@@ -0,0 +1,473 @@
+package claim
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrChaos is matched with errors.Is by the failures injected by a
+// ChaosProvider.
+var ErrChaos = errors.New("failure injected by the chaos provider")
+
+// ChaosOptions configures the degraded storage conditions simulated by a
+// ChaosProvider.
+type ChaosOptions struct {
+	// Latency is added to every call.
+	Latency time.Duration
+
+	// Jitter is the maximum random latency added to every call on top of
+	// Latency, so that concurrent calls complete out of order.
+	Jitter time.Duration
+
+	// ReadFailureRate is the probability, between 0 and 1, that a read
+	// fails with ErrChaos.
+	ReadFailureRate float64
+
+	// WriteFailureRate is the probability, between 0 and 1, that a write,
+	// such as a save, a delete or a lock, fails with ErrChaos without being
+	// applied.
+	WriteFailureRate float64
+
+	// PartialWriteRate is the probability, between 0 and 1, that a write is
+	// applied but fails with ErrChaos anyway, like a request whose response
+	// was lost, so that callers that retry it apply it twice.
+	PartialWriteRate float64
+
+	// ReorderRate is the probability, between 0 and 1, that the items
+	// returned by a read of multiple items, such as ListClaims or
+	// ReadAllResults, are shuffled instead of being in their documented
+	// order.
+	ReorderRate float64
+
+	// Seed of the random decisions, so that a failing test can be replayed.
+	// The current time is used when it is zero.
+	Seed int64
+}
+
+// Validate the options.
+func (o ChaosOptions) Validate() error {
+	if o.Latency < 0 || o.Jitter < 0 {
+		return errors.New("the latency and jitter of the chaos provider cannot be negative")
+	}
+	rates := map[string]float64{
+		"ReadFailureRate":  o.ReadFailureRate,
+		"WriteFailureRate": o.WriteFailureRate,
+		"PartialWriteRate": o.PartialWriteRate,
+		"ReorderRate":      o.ReorderRate,
+	}
+	for name, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return errors.Errorf("%s must be between 0 and 1 but was %v", name, rate)
+		}
+	}
+	return nil
+}
+
+// ChaosProvider decorates a Provider to inject latency, failures and
+// reordering into its reads and writes, to test how orchestration code built
+// on top of it behaves when the storage is degraded. It is intended for
+// tests only. It is safe for concurrent use when the decorated Provider is.
+type ChaosProvider struct {
+	provider Provider
+	sleep    func(time.Duration)
+
+	mu      sync.Mutex
+	options ChaosOptions
+	rand    *rand.Rand
+}
+
+var _ Provider = &ChaosProvider{}
+
+// NewChaosProvider wraps the provider, injecting the conditions described by
+// the options.
+func NewChaosProvider(p Provider, options ChaosOptions) (*ChaosProvider, error) {
+	c := &ChaosProvider{provider: p, sleep: time.Sleep}
+	if err := c.SetOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetOptions replaces the conditions injected by the provider, for example
+// to degrade the storage in the middle of a test and restore it afterwards.
+// The random decisions are seeded again.
+func (c *ChaosProvider) SetOptions(options ChaosOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.options = options
+	c.rand = rand.New(rand.NewSource(seed))
+	return nil
+}
+
+// chance returns true with the probability.
+func (c *ChaosProvider) chance(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rand.Float64() < probability
+}
+
+// delay sleeps for the configured latency and a random jitter.
+func (c *ChaosProvider) delay() {
+	c.mu.Lock()
+	d := c.options.Latency
+	if c.options.Jitter > 0 {
+		d += time.Duration(c.rand.Int63n(int64(c.options.Jitter) + 1))
+	}
+	c.mu.Unlock()
+
+	if d > 0 {
+		c.sleep(d)
+	}
+}
+
+func (c *ChaosProvider) currentOptions() ChaosOptions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.options
+}
+
+// read delays a read and returns the failure to inject, if any.
+func (c *ChaosProvider) read(method string) error {
+	c.delay()
+	if c.chance(c.currentOptions().ReadFailureRate) {
+		return errors.Wrapf(ErrChaos, "%s failed", method)
+	}
+	return nil
+}
+
+// write delays a write, then applies it unless a failure is injected.
+func (c *ChaosProvider) write(method string, apply func() error) error {
+	c.delay()
+	options := c.currentOptions()
+	if c.chance(options.WriteFailureRate) {
+		return errors.Wrapf(ErrChaos, "%s failed", method)
+	}
+	if err := apply(); err != nil {
+		return err
+	}
+	if c.chance(options.PartialWriteRate) {
+		return errors.Wrapf(ErrChaos, "%s was applied but failed", method)
+	}
+	return nil
+}
+
+// reorder shuffles n items with the swap function, when reordering is
+// injected.
+func (c *ChaosProvider) reorder(n int, swap func(i, j int)) {
+	if n < 2 || !c.chance(c.currentOptions().ReorderRate) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rand.Shuffle(n, swap)
+}
+
+func (c *ChaosProvider) reorderStrings(items []string) {
+	c.reorder(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+}
+
+func (c *ChaosProvider) ListInstallations() ([]string, error) {
+	if err := c.read("ListInstallations"); err != nil {
+		return nil, err
+	}
+	names, err := c.provider.ListInstallations()
+	c.reorderStrings(names)
+	return names, err
+}
+
+func (c *ChaosProvider) ListInstallationsBySelector(selector string) ([]string, error) {
+	if err := c.read("ListInstallationsBySelector"); err != nil {
+		return nil, err
+	}
+	names, err := c.provider.ListInstallationsBySelector(selector)
+	c.reorderStrings(names)
+	return names, err
+}
+
+func (c *ChaosProvider) ListClaims(installation string) ([]string, error) {
+	if err := c.read("ListClaims"); err != nil {
+		return nil, err
+	}
+	ids, err := c.provider.ListClaims(installation)
+	c.reorderStrings(ids)
+	return ids, err
+}
+
+func (c *ChaosProvider) ListResults(claimID string) ([]string, error) {
+	if err := c.read("ListResults"); err != nil {
+		return nil, err
+	}
+	ids, err := c.provider.ListResults(claimID)
+	c.reorderStrings(ids)
+	return ids, err
+}
+
+func (c *ChaosProvider) ListOutputs(resultID string) ([]string, error) {
+	if err := c.read("ListOutputs"); err != nil {
+		return nil, err
+	}
+	names, err := c.provider.ListOutputs(resultID)
+	c.reorderStrings(names)
+	return names, err
+}
+
+func (c *ChaosProvider) ReadInstallation(installation string) (Installation, error) {
+	if err := c.read("ReadInstallation"); err != nil {
+		return Installation{}, err
+	}
+	return c.provider.ReadInstallation(installation)
+}
+
+func (c *ChaosProvider) ReadInstallationStatus(installation string) (Installation, error) {
+	if err := c.read("ReadInstallationStatus"); err != nil {
+		return Installation{}, err
+	}
+	return c.provider.ReadInstallationStatus(installation)
+}
+
+func (c *ChaosProvider) ReadAllInstallationStatus() ([]Installation, error) {
+	if err := c.read("ReadAllInstallationStatus"); err != nil {
+		return nil, err
+	}
+	installations, err := c.provider.ReadAllInstallationStatus()
+	c.reorder(len(installations), func(i, j int) {
+		installations[i], installations[j] = installations[j], installations[i]
+	})
+	return installations, err
+}
+
+func (c *ChaosProvider) ReadClaim(claimID string) (Claim, error) {
+	if err := c.read("ReadClaim"); err != nil {
+		return Claim{}, err
+	}
+	return c.provider.ReadClaim(claimID)
+}
+
+func (c *ChaosProvider) ReadClaimWithRevision(claimID string) (Claim, string, error) {
+	if err := c.read("ReadClaimWithRevision"); err != nil {
+		return Claim{}, "", err
+	}
+	return c.provider.ReadClaimWithRevision(claimID)
+}
+
+func (c *ChaosProvider) ReadAllClaims(installation string) ([]Claim, error) {
+	if err := c.read("ReadAllClaims"); err != nil {
+		return nil, err
+	}
+	claims, err := c.provider.ReadAllClaims(installation)
+	c.reorder(len(claims), func(i, j int) { claims[i], claims[j] = claims[j], claims[i] })
+	return claims, err
+}
+
+func (c *ChaosProvider) QueryClaims(query ClaimQuery) (ClaimPage, error) {
+	if err := c.read("QueryClaims"); err != nil {
+		return ClaimPage{}, err
+	}
+	return c.provider.QueryClaims(query)
+}
+
+func (c *ChaosProvider) ListClaimsByIndex(index string, value string) ([]string, error) {
+	if err := c.read("ListClaimsByIndex"); err != nil {
+		return nil, err
+	}
+	ids, err := c.provider.ListClaimsByIndex(index, value)
+	c.reorderStrings(ids)
+	return ids, err
+}
+
+func (c *ChaosProvider) ReadLastClaim(installation string) (Claim, error) {
+	if err := c.read("ReadLastClaim"); err != nil {
+		return Claim{}, err
+	}
+	return c.provider.ReadLastClaim(installation)
+}
+
+func (c *ChaosProvider) ReadResult(resultID string) (Result, error) {
+	if err := c.read("ReadResult"); err != nil {
+		return Result{}, err
+	}
+	return c.provider.ReadResult(resultID)
+}
+
+func (c *ChaosProvider) ReadResultWithRevision(resultID string) (Result, string, error) {
+	if err := c.read("ReadResultWithRevision"); err != nil {
+		return Result{}, "", err
+	}
+	return c.provider.ReadResultWithRevision(resultID)
+}
+
+func (c *ChaosProvider) ReadAllResults(claimID string) ([]Result, error) {
+	if err := c.read("ReadAllResults"); err != nil {
+		return nil, err
+	}
+	results, err := c.provider.ReadAllResults(claimID)
+	c.reorder(len(results), func(i, j int) { results[i], results[j] = results[j], results[i] })
+	return results, err
+}
+
+func (c *ChaosProvider) ReadLastResult(claimID string) (Result, error) {
+	if err := c.read("ReadLastResult"); err != nil {
+		return Result{}, err
+	}
+	return c.provider.ReadLastResult(claimID)
+}
+
+func (c *ChaosProvider) ReadLastOutputs(installation string) (Outputs, error) {
+	if err := c.read("ReadLastOutputs"); err != nil {
+		return Outputs{}, err
+	}
+	return c.provider.ReadLastOutputs(installation)
+}
+
+func (c *ChaosProvider) ReadLastOutput(installation string, name string) (Output, error) {
+	if err := c.read("ReadLastOutput"); err != nil {
+		return Output{}, err
+	}
+	return c.provider.ReadLastOutput(installation, name)
+}
+
+func (c *ChaosProvider) ReadOutputHistory(installation string, outputName string) ([]OutputHistoryEntry, error) {
+	if err := c.read("ReadOutputHistory"); err != nil {
+		return nil, err
+	}
+	history, err := c.provider.ReadOutputHistory(installation, outputName)
+	c.reorder(len(history), func(i, j int) { history[i], history[j] = history[j], history[i] })
+	return history, err
+}
+
+func (c *ChaosProvider) ReadOutput(claim Claim, result Result, outputName string) (Output, error) {
+	if err := c.read("ReadOutput"); err != nil {
+		return Output{}, err
+	}
+	return c.provider.ReadOutput(claim, result, outputName)
+}
+
+func (c *ChaosProvider) ReadOutputOrDefault(claim Claim, result Result, outputName string) (Output, error) {
+	if err := c.read("ReadOutputOrDefault"); err != nil {
+		return Output{}, err
+	}
+	return c.provider.ReadOutputOrDefault(claim, result, outputName)
+}
+
+func (c *ChaosProvider) ReadInstallationLock(installation string) (InstallationLock, error) {
+	if err := c.read("ReadInstallationLock"); err != nil {
+		return InstallationLock{}, err
+	}
+	return c.provider.ReadInstallationLock(installation)
+}
+
+func (c *ChaosProvider) SaveClaim(claim Claim) error {
+	return c.write("SaveClaim", func() error {
+		return c.provider.SaveClaim(claim)
+	})
+}
+
+func (c *ChaosProvider) SaveClaimIfRevision(claim Claim, revision string) (string, error) {
+	var newRevision string
+	err := c.write("SaveClaimIfRevision", func() (err error) {
+		newRevision, err = c.provider.SaveClaimIfRevision(claim, revision)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return newRevision, nil
+}
+
+func (c *ChaosProvider) SaveResult(result Result) error {
+	return c.write("SaveResult", func() error {
+		return c.provider.SaveResult(result)
+	})
+}
+
+func (c *ChaosProvider) SaveResultIfRevision(result Result, revision string) (string, error) {
+	var newRevision string
+	err := c.write("SaveResultIfRevision", func() (err error) {
+		newRevision, err = c.provider.SaveResultIfRevision(result, revision)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return newRevision, nil
+}
+
+func (c *ChaosProvider) SaveOutput(o Output) error {
+	return c.write("SaveOutput", func() error {
+		return c.provider.SaveOutput(o)
+	})
+}
+
+func (c *ChaosProvider) AcquireInstallationLock(installation string, owner string, lease time.Duration) (InstallationLock, error) {
+	var lock InstallationLock
+	err := c.write("AcquireInstallationLock", func() (err error) {
+		lock, err = c.provider.AcquireInstallationLock(installation, owner, lease)
+		return err
+	})
+	if err != nil {
+		return InstallationLock{}, err
+	}
+	return lock, nil
+}
+
+func (c *ChaosProvider) ReleaseInstallationLock(lock InstallationLock) error {
+	return c.write("ReleaseInstallationLock", func() error {
+		return c.provider.ReleaseInstallationLock(lock)
+	})
+}
+
+func (c *ChaosProvider) DeleteInstallation(installation string) error {
+	return c.write("DeleteInstallation", func() error {
+		return c.provider.DeleteInstallation(installation)
+	})
+}
+
+func (c *ChaosProvider) DeleteInstallationWithReport(installation string) (DeletionReport, error) {
+	var report DeletionReport
+	err := c.write("DeleteInstallationWithReport", func() (err error) {
+		report, err = c.provider.DeleteInstallationWithReport(installation)
+		return err
+	})
+	return report, err
+}
+
+func (c *ChaosProvider) DeleteClaim(claimID string) error {
+	return c.write("DeleteClaim", func() error {
+		return c.provider.DeleteClaim(claimID)
+	})
+}
+
+func (c *ChaosProvider) DeleteResult(resultID string) error {
+	return c.write("DeleteResult", func() error {
+		return c.provider.DeleteResult(resultID)
+	})
+}
+
+func (c *ChaosProvider) DeleteOutput(resultID string, outputName string) error {
+	return c.write("DeleteOutput", func() error {
+		return c.provider.DeleteOutput(resultID, outputName)
+	})
+}
+
+func (c *ChaosProvider) Prune(installation string, policy RetentionPolicy) ([]string, error) {
+	var pruned []string
+	err := c.write("Prune", func() (err error) {
+		pruned, err = c.provider.Prune(installation, policy)
+		return err
+	})
+	return pruned, err
+}
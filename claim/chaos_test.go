@@ -0,0 +1,102 @@
+package claim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestChaosProvider(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	p, err := NewChaosProvider(store, ChaosOptions{Latency: time.Second, Jitter: time.Second, Seed: 42})
+	require.NoError(t, err)
+	var slept []time.Duration
+	p.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	c, err := New("mysql", ActionInstall, exampleBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, p.SaveClaim(c))
+	got, err := p.ReadClaim(c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, c.ID, got.ID)
+
+	require.Len(t, slept, 2, "every call should be delayed")
+	for _, d := range slept {
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.LessOrEqual(t, d, 2*time.Second)
+	}
+
+	t.Run("read failures", func(t *testing.T) {
+		require.NoError(t, p.SetOptions(ChaosOptions{ReadFailureRate: 1}))
+		_, err := p.ReadClaim(c.ID)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrChaos))
+		assert.Contains(t, err.Error(), "ReadClaim failed")
+
+		require.NoError(t, p.SaveClaim(c), "writes should not fail")
+	})
+
+	t.Run("write failures", func(t *testing.T) {
+		require.NoError(t, p.SetOptions(ChaosOptions{WriteFailureRate: 1}))
+		c2, err := New("wordpress", ActionInstall, exampleBundle, nil)
+		require.NoError(t, err)
+		err = p.SaveClaim(c2)
+		assert.True(t, errors.Is(err, ErrChaos))
+
+		_, err = store.ReadClaim(c2.ID)
+		assert.True(t, errors.Is(err, ErrClaimNotFound), "the failed write should not be applied")
+	})
+
+	t.Run("partial writes", func(t *testing.T) {
+		require.NoError(t, p.SetOptions(ChaosOptions{PartialWriteRate: 1}))
+		c2, err := New("wordpress", ActionInstall, exampleBundle, nil)
+		require.NoError(t, err)
+		err = p.SaveClaim(c2)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrChaos))
+		assert.Contains(t, err.Error(), "SaveClaim was applied but failed")
+
+		_, err = store.ReadClaim(c2.ID)
+		assert.NoError(t, err, "the write should be applied")
+	})
+
+	t.Run("reordering", func(t *testing.T) {
+		require.NoError(t, p.SetOptions(ChaosOptions{}))
+		for i := 0; i < 8; i++ {
+			c, err := New("mysql", ActionUpgrade, exampleBundle, nil)
+			require.NoError(t, err)
+			require.NoError(t, p.SaveClaim(c))
+		}
+		ordered, err := p.ListClaims("mysql")
+		require.NoError(t, err)
+
+		require.NoError(t, p.SetOptions(ChaosOptions{ReorderRate: 1, Seed: 1}))
+		shuffled, err := p.ListClaims("mysql")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, ordered, shuffled)
+		assert.NotEqual(t, ordered, shuffled, "the claims should be reordered")
+
+		require.NoError(t, p.SetOptions(ChaosOptions{ReorderRate: 1, Seed: 1}))
+		replayed, err := p.ListClaims("mysql")
+		require.NoError(t, err)
+		assert.Equal(t, shuffled, replayed, "the same seed should reorder the claims the same way")
+	})
+}
+
+func TestChaosOptions_Validate(t *testing.T) {
+	assert.NoError(t, ChaosOptions{ReadFailureRate: 0.5, ReorderRate: 1}.Validate())
+
+	err := ChaosOptions{WriteFailureRate: 1.5}.Validate()
+	assert.EqualError(t, err, "WriteFailureRate must be between 0 and 1 but was 1.5")
+
+	err = ChaosOptions{Latency: -time.Second}.Validate()
+	assert.EqualError(t, err, "the latency and jitter of the chaos provider cannot be negative")
+
+	_, err = NewChaosProvider(NewClaimStore(crud.NewMockStore(), nil, nil), ChaosOptions{ReorderRate: -1})
+	assert.Error(t, err)
+}
@@ -55,8 +55,21 @@ const (
 	// outputs are created by the executing driver or CNAB tool.
 	OutputGeneratedByBundle = "generatedByBundle"
 
+	// OutputCompression is the output metadata key for the algorithm that the
+	// output was compressed with when it was persisted, for example
+	// CompressionGzip. The content digest is of the uncompressed value.
+	OutputCompression = "compression"
+
+	// CompressionGzip indicates that an output was compressed with gzip.
+	CompressionGzip = "gzip"
+
 	// OutputInvocationImageLogs is a well-known output name used to store the logs from the invocation image.
 	OutputInvocationImageLogs = "io.cnab.outputs.invocationImageLogs"
+
+	// OutputEnvironmentSnapshot is a well-known output name used to store a
+	// redacted snapshot of the CNAB environment variables and the paths of the
+	// files injected into the invocation image.
+	OutputEnvironmentSnapshot = "io.cnab.outputs.environmentSnapshot"
 )
 
 var (
@@ -99,6 +112,17 @@ type Claim struct {
 	// Custom extension data applicable to a given runtime.
 	Custom interface{} `json:"custom,omitempty"`
 
+	// Labels organize installations, for example environment=prod or
+	// team=payments. The labels of an installation are those of its last
+	// claim, and are copied to the claims created with NewClaim. See
+	// ValidateLabels for the allowed keys and values.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are arbitrary metadata about the installation, such as a
+	// description or the URL of its dashboard, that are not used to select
+	// installations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
 	// Results of executing the Claim's operation.
 	// These are not stored in the Claim document but can be loaded onto the
 	// the Claim to build an in-memory hierarchy.
@@ -157,6 +181,8 @@ func (c Claim) NewClaim(action string, bun bundle.Bundle, parameters map[string]
 	updatedClaim.Action = action
 	updatedClaim.Parameters = parameters
 	updatedClaim.Created = time.Now()
+	updatedClaim.Labels = copyStringMap(c.Labels)
+	updatedClaim.Annotations = copyStringMap(c.Annotations)
 
 	id, err := NewULID()
 	if err != nil {
@@ -226,6 +252,10 @@ func (c Claim) Validate() error {
 		return errors.New("the action must be set")
 	}
 
+	if err := ValidateLabels(c.Labels); err != nil {
+		return err
+	}
+
 	// Check the action is built-in or defined as a custom action
 	if _, isBuiltInAction := builtinActions[c.Action]; !isBuiltInAction {
 		_, isCustomAction := c.Bundle.Actions[c.Action]
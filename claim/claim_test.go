@@ -202,7 +202,7 @@ func TestValidateExampleClaim(t *testing.T) {
 	claim.SchemaVersion = "not-semver"
 	err = claim.Validate()
 	assert.EqualError(t, err,
-		`claim validation failed: invalid schema version "not-semver": Invalid Semantic Version`)
+		`claim validation failed: invalid schema version "not-semver": invalid semantic version`)
 }
 
 func TestMarshal_AllFields(t *testing.T) {
@@ -423,3 +423,9 @@ func TestClaim_HasLogs(t *testing.T) {
 		assert.False(t, hasLogs, "Expected hasLogs to be false")
 	})
 }
+
+func TestEmbeddedSchemaVersion(t *testing.T) {
+	version, err := schema.GetEmbeddedVersion(schema.TypeClaim)
+	require.NoError(t, err)
+	assert.Equal(t, GetDefaultSchemaVersion(), version, "the embedded claim schema should be refreshed with make fetch-schemas")
+}
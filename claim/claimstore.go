@@ -0,0 +1,937 @@
+package claim
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// Item types used to persist claim data in a crud.Store.
+const (
+	ItemTypeClaims  = "claims"
+	ItemTypeResults = "results"
+	ItemTypeOutputs = "outputs"
+
+	// ItemTypeLocks holds the InstallationLock of each locked installation,
+	// keyed by the name of the installation.
+	ItemTypeLocks = "locks"
+)
+
+// NewClaimStoreFileExtensions returns the file extensions used by the
+// claim item types, suitable for use with crud.NewFileSystemStore.
+func NewClaimStoreFileExtensions() map[string]string {
+	return map[string]string{
+		ItemTypeClaims:  ".json",
+		ItemTypeResults: ".json",
+		// Outputs are the raw output value and do not have a file extension.
+		ItemTypeOutputs: "",
+		ItemTypeLocks:   ".json",
+	}
+}
+
+// EncryptionHandler is a function that transforms data by encrypting or decrypting it.
+type EncryptionHandler func([]byte) ([]byte, error)
+
+// noOpEncryptionHandler is used when no handler is specified.
+var noOpEncryptionHandler = func(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+var _ Provider = Store{}
+
+// encryptedParameterPrefix identifies the values of sensitive parameters
+// that were encrypted before the claim was persisted.
+const encryptedParameterPrefix = "cnab-encrypted:"
+
+// Store is a persistent store for claims, results and outputs.
+type Store struct {
+	backingStore *crud.ManagedStore
+	keyring      Keyring
+
+	// encryptParameters is set when an encryption handler was provided.
+	encryptParameters bool
+
+	// verifyOutputDigests is set when outputs are verified against the
+	// content digest recorded on their result when they are read.
+	verifyOutputDigests bool
+
+	// customIndexes are the indexes of claims added with AddClaimIndex.
+	customIndexes map[string]ClaimIndexFunc
+
+	// outputCompressionThreshold is the size above which outputs are
+	// compressed, see SetOutputCompression.
+	outputCompressionThreshold int
+}
+
+// NewClaimStore creates a persistent store for claims using the specified
+// backing key-blob store. The encrypt and decrypt handlers are applied to the
+// values of sensitive parameters and outputs, and may be nil when encryption
+// is not used.
+func NewClaimStore(store crud.Store, encrypt EncryptionHandler, decrypt EncryptionHandler) Store {
+	encryptParameters := encrypt != nil
+	if encrypt == nil {
+		encrypt = noOpEncryptionHandler
+	}
+
+	if decrypt == nil {
+		decrypt = noOpEncryptionHandler
+	}
+
+	return Store{
+		backingStore: crud.NewManagedStore(store),
+		keyring: Keyring{
+			Keys: map[string]EncryptionKey{
+				"": {Encrypt: encrypt, Decrypt: decrypt},
+			},
+		},
+		encryptParameters: encryptParameters,
+	}
+}
+
+// NewClaimStoreWithKeyring creates a persistent store for claims using the
+// specified backing key-blob store, that encrypts the values of sensitive
+// parameters and outputs with the keys in the keyring.
+//
+// Records encrypted by a store created with NewClaimStore are decrypted with
+// the key whose ID is empty, when present, or the current key, so that an
+// existing store can be migrated to a keyring.
+func NewClaimStoreWithKeyring(store crud.Store, keyring Keyring) (Store, error) {
+	if err := keyring.Validate(); err != nil {
+		return Store{}, errors.Wrap(err, "invalid keyring")
+	}
+
+	return Store{
+		backingStore:      crud.NewManagedStore(store),
+		keyring:           keyring,
+		encryptParameters: true,
+	}, nil
+}
+
+// SetVerifyOutputDigests enables a strict mode where ReadOutput recomputes the
+// content digest of each output that it reads and compares it with the digest
+// recorded on the output's result, returning an OutputTamperedError when they
+// do not match. Outputs whose result does not record a digest are not
+// verified.
+func (s *Store) SetVerifyOutputDigests(verify bool) {
+	s.verifyOutputDigests = verify
+}
+
+// GetBackingStore returns the data store behind this claim store.
+func (s Store) GetBackingStore() *crud.ManagedStore {
+	return s.backingStore
+}
+
+func (s Store) ListInstallations() ([]string, error) {
+	names, err := s.backingStore.List(ItemTypeClaims, "")
+	sort.Strings(names)
+	return names, s.handleNotExistsError(err, ErrInstallationNotFound)
+}
+
+func (s Store) ListClaims(installation string) ([]string, error) {
+	claimIDs, err := s.backingStore.List(ItemTypeClaims, installation)
+	if err != nil {
+		return nil, s.handleNotExistsError(err, ErrInstallationNotFound)
+	}
+	if len(claimIDs) == 0 {
+		return nil, ErrInstallationNotFound
+	}
+
+	sort.Strings(claimIDs)
+	return claimIDs, nil
+}
+
+func (s Store) ListResults(claimID string) ([]string, error) {
+	resultIDs, err := s.backingStore.List(ItemTypeResults, claimID)
+	sort.Strings(resultIDs)
+	return resultIDs, s.handleNotExistsError(err, ErrClaimNotFound)
+}
+
+func (s Store) ListOutputs(resultID string) ([]string, error) {
+	outputNames, err := s.backingStore.List(ItemTypeOutputs, resultID)
+	if err != nil {
+		return nil, s.handleNotExistsError(err, ErrResultNotFound)
+	}
+
+	// Outputs are keyed by RESULTID-OUTPUTNAME to keep them unique
+	prefix := resultID + "-"
+	for i, name := range outputNames {
+		outputNames[i] = strings.TrimPrefix(name, prefix)
+	}
+
+	sort.Strings(outputNames)
+	return outputNames, nil
+}
+
+func (s Store) ReadInstallation(installation string) (Installation, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return Installation{}, err
+	}
+
+	claims, err := s.ReadAllClaims(installation)
+	if err != nil {
+		return Installation{}, err
+	}
+
+	for i, c := range claims {
+		results, err := s.ReadAllResults(c.ID)
+		if err != nil {
+			return Installation{}, err
+		}
+		resultsRef := Results(results)
+		claims[i].results = &resultsRef
+	}
+
+	return NewInstallation(installation, claims).WithProvider(s), nil
+}
+
+func (s Store) ReadInstallationStatus(installation string) (Installation, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return Installation{}, err
+	}
+
+	claims, err := s.ReadAllClaims(installation)
+	if err != nil {
+		return Installation{}, err
+	}
+
+	// Only load the last result of the most recent claim
+	lastClaim := &claims[len(claims)-1]
+	results := Results{}
+	lastResult, err := s.ReadLastResult(lastClaim.ID)
+	if err == nil {
+		results = append(results, lastResult)
+	} else if !errors.Is(err, ErrResultNotFound) {
+		return Installation{}, err
+	}
+	lastClaim.results = &results
+
+	return NewInstallation(installation, claims).WithProvider(s), nil
+}
+
+func (s Store) ReadAllInstallationStatus() ([]Installation, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := s.ListInstallations()
+	if err != nil {
+		return nil, err
+	}
+
+	installations := make([]Installation, 0, len(names))
+	for _, name := range names {
+		installation, err := s.ReadInstallationStatus(name)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, installation)
+	}
+
+	return installations, nil
+}
+
+func (s Store) ReadClaim(claimID string) (Claim, error) {
+	c, _, err := s.ReadClaimWithRevision(claimID)
+	return c, err
+}
+
+// ReadClaimWithRevision returns the specified claim and the revision of its
+// record, which is used to save changes to the claim with SaveClaimIfRevision.
+func (s Store) ReadClaimWithRevision(claimID string) (Claim, string, error) {
+	bytes, err := s.backingStore.Read(ItemTypeClaims, claimID)
+	if err != nil {
+		return Claim{}, "", s.handleNotExistsError(err, ErrClaimNotFound)
+	}
+
+	claim := Claim{}
+	err = json.Unmarshal(bytes, &claim)
+	if err != nil {
+		return Claim{}, "", errors.Wrapf(err, "error unmarshaling claim %s", claimID)
+	}
+
+	claim, err = s.decryptSensitiveParameters(claim)
+	return claim, crud.Revision(bytes), err
+}
+
+func (s Store) ReadAllClaims(installation string) ([]Claim, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	claimIDs, err := s.ListClaims(installation)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(Claims, 0, len(claimIDs))
+	for _, claimID := range claimIDs {
+		c, err := s.ReadClaim(claimID)
+		if err != nil {
+			return nil, err
+		}
+		claims = append(claims, c)
+	}
+
+	sort.Sort(claims)
+	return claims, nil
+}
+
+func (s Store) ReadLastClaim(installation string) (Claim, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return Claim{}, err
+	}
+
+	claimIDs, err := s.ListClaims(installation)
+	if err != nil {
+		return Claim{}, err
+	}
+
+	return s.ReadClaim(claimIDs[len(claimIDs)-1])
+}
+
+// QueryClaims returns a page of the claims matching the query. When the
+// backing store implements crud.Indexer, its indexes select the claims
+// matching the action and status filters. The claims are then read and
+// filtered in order, stopping once the page is full.
+func (s Store) QueryClaims(query ClaimQuery) (ClaimPage, error) {
+	if err := query.Validate(); err != nil {
+		return ClaimPage{}, err
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return ClaimPage{}, err
+	}
+
+	claimIDs, err := s.listQueriedClaims(query)
+	if err != nil {
+		return ClaimPage{}, err
+	}
+
+	var page ClaimPage
+	skipped := 0
+	for _, claimID := range claimIDs {
+		if !query.afterCursor(claimID) {
+			continue
+		}
+
+		c, err := s.readQueriedClaim(claimID, query)
+		if err != nil {
+			return ClaimPage{}, err
+		}
+		if !query.matches(c) {
+			continue
+		}
+		if len(query.Statuses) > 0 && !containsString(query.Statuses, c.GetStatus()) {
+			continue
+		}
+
+		if skipped < query.Offset {
+			skipped++
+			continue
+		}
+		if query.Limit > 0 && len(page.Claims) == query.Limit {
+			page.NextCursor = page.Claims[len(page.Claims)-1].ID
+			break
+		}
+		page.Claims = append(page.Claims, c)
+	}
+
+	return page, nil
+}
+
+// listQueriedClaims returns the IDs of the claims of the installations
+// selected by the query, in the requested order.
+func (s Store) listQueriedClaims(query ClaimQuery) ([]string, error) {
+	claimIDs, indexed, err := s.listIndexedClaims(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if !indexed {
+		claimIDs, err = s.listInstallationClaims(query.Installation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if query.Direction == SortDescending {
+		sort.Sort(sort.Reverse(sort.StringSlice(claimIDs)))
+	} else {
+		sort.Strings(claimIDs)
+	}
+	return claimIDs, nil
+}
+
+// listInstallationClaims returns the IDs of the claims of an installation,
+// or of every installation when it is empty.
+func (s Store) listInstallationClaims(installation string) ([]string, error) {
+	if installation != "" {
+		return s.ListClaims(installation)
+	}
+
+	names, err := s.ListInstallations()
+	if err != nil {
+		return nil, err
+	}
+
+	var claimIDs []string
+	for _, name := range names {
+		ids, err := s.ListClaims(name)
+		if err != nil {
+			return nil, err
+		}
+		claimIDs = append(claimIDs, ids...)
+	}
+	return claimIDs, nil
+}
+
+// readQueriedClaim reads a claim, loading its last result when the query
+// filters by status.
+func (s Store) readQueriedClaim(claimID string, query ClaimQuery) (Claim, error) {
+	c, err := s.ReadClaim(claimID)
+	if err != nil {
+		return Claim{}, err
+	}
+	if len(query.Statuses) == 0 {
+		return c, nil
+	}
+
+	results := Results{}
+	lastResult, err := s.ReadLastResult(claimID)
+	if err == nil {
+		results = append(results, lastResult)
+	} else if !errors.Is(err, ErrResultNotFound) {
+		return Claim{}, err
+	}
+	c.results = &results
+	return c, nil
+}
+
+func (s Store) ReadResult(resultID string) (Result, error) {
+	r, _, err := s.ReadResultWithRevision(resultID)
+	return r, err
+}
+
+// ReadResultWithRevision returns the specified result and the revision of its
+// record, which is used to save changes to the result with
+// SaveResultIfRevision.
+func (s Store) ReadResultWithRevision(resultID string) (Result, string, error) {
+	bytes, err := s.backingStore.Read(ItemTypeResults, resultID)
+	if err != nil {
+		return Result{}, "", s.handleNotExistsError(err, ErrResultNotFound)
+	}
+
+	result := Result{}
+	err = json.Unmarshal(bytes, &result)
+	if err != nil {
+		return Result{}, "", errors.Wrapf(err, "error unmarshaling result %s", resultID)
+	}
+	return result, crud.Revision(bytes), nil
+}
+
+func (s Store) ReadAllResults(claimID string) ([]Result, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	resultIDs, err := s.ListResults(claimID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(Results, 0, len(resultIDs))
+	for _, resultID := range resultIDs {
+		r, err := s.ReadResult(resultID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	sort.Sort(results)
+	return results, nil
+}
+
+func (s Store) ReadLastResult(claimID string) (Result, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return Result{}, err
+	}
+
+	resultIDs, err := s.ListResults(claimID)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(resultIDs) == 0 {
+		return Result{}, ErrResultNotFound
+	}
+
+	return s.ReadResult(resultIDs[len(resultIDs)-1])
+}
+
+func (s Store) ReadLastOutputs(installation string) (Outputs, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return Outputs{}, err
+	}
+
+	claims, err := s.ReadAllClaims(installation)
+	if err != nil {
+		return Outputs{}, err
+	}
+
+	// Walk the claims and results from newest to oldest, keeping the first
+	// value found for each output.
+	lastOutputs := map[string]Output{}
+	for i := len(claims) - 1; i >= 0; i-- {
+		c := claims[i]
+		results, err := s.ReadAllResults(c.ID)
+		if err != nil {
+			return Outputs{}, err
+		}
+
+		for j := len(results) - 1; j >= 0; j-- {
+			r := results[j]
+			outputNames, err := s.ListOutputs(r.ID)
+			if err != nil {
+				return Outputs{}, err
+			}
+
+			for _, name := range outputNames {
+				if _, ok := lastOutputs[name]; ok {
+					continue
+				}
+
+				o, err := s.ReadOutput(c, r, name)
+				if err != nil {
+					return Outputs{}, err
+				}
+				lastOutputs[name] = o
+			}
+		}
+	}
+
+	outputs := make([]Output, 0, len(lastOutputs))
+	for _, o := range lastOutputs {
+		outputs = append(outputs, o)
+	}
+	return NewOutputs(outputs), nil
+}
+
+func (s Store) ReadLastOutput(installation string, name string) (Output, error) {
+	outputs, err := s.ReadLastOutputs(installation)
+	if err != nil {
+		return Output{}, err
+	}
+
+	o, ok := outputs.GetByName(name)
+	if !ok {
+		return Output{}, ErrOutputNotFound
+	}
+	return o, nil
+}
+
+func (s Store) ReadOutput(c Claim, r Result, outputName string) (Output, error) {
+	bytes, err := s.backingStore.Read(ItemTypeOutputs, s.outputKey(r.ID, outputName))
+	if err != nil {
+		return Output{}, s.handleNotExistsError(err, ErrOutputNotFound)
+	}
+
+	if s.isOutputSensitive(c, outputName) {
+		bytes, err = s.keyring.decryptRecord(bytes)
+		if err != nil {
+			return Output{}, errors.Wrapf(err, "error decrypting output %s", outputName)
+		}
+	}
+
+	bytes, err = s.decompressOutput(r, outputName, bytes)
+	if err != nil {
+		return Output{}, errors.Wrapf(err, "error decompressing output %s", outputName)
+	}
+
+	if s.verifyOutputDigests {
+		if err := verifyOutputDigest(r, outputName, bytes); err != nil {
+			return Output{}, err
+		}
+	}
+
+	return NewOutput(c, r, outputName, bytes), nil
+}
+
+// ReadOutputOrDefault returns the value of an output generated by a result.
+// When the output was not persisted, the default value from the output's
+// definition in the bundle is returned instead, matching the value that
+// driver.OperationResult.SetDefaultOutputValues sets when an operation runs.
+func (s Store) ReadOutputOrDefault(c Claim, r Result, outputName string) (Output, error) {
+	o, err := s.ReadOutput(c, r, outputName)
+	if !errors.Is(err, ErrOutputNotFound) {
+		return o, err
+	}
+
+	def, ok := c.Bundle.Outputs[outputName]
+	if !ok || !def.AppliesTo(c.Action) {
+		return Output{}, ErrOutputNotFound
+	}
+
+	schema, ok := c.Bundle.Definitions[def.Definition]
+	if !ok {
+		return Output{}, ErrOutputNotFound
+	}
+	defaultValue := schema.DefaultValue()
+	if defaultValue == nil {
+		return Output{}, ErrOutputNotFound
+	}
+
+	contents, err := definition.FormatValue(defaultValue)
+	if err != nil {
+		return Output{}, errors.Wrapf(err, "error formatting the default value of output %s", outputName)
+	}
+	return NewOutput(c, r, outputName, []byte(contents)), nil
+}
+
+func (s Store) SaveClaim(c Claim) error {
+	c, bytes, err := s.marshalClaim(c)
+	if err != nil {
+		return err
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	if err := s.backingStore.Save(ItemTypeClaims, c.Installation, c.ID, bytes); err != nil {
+		return err
+	}
+
+	if indexer, ok := s.indexer(); ok {
+		return s.indexClaim(indexer, c)
+	}
+	return nil
+}
+
+// SaveClaimIfRevision persists the specified claim when its stored record
+// has the revision returned by ReadClaimWithRevision, so that concurrent
+// updates of the claim do not overwrite each other. An empty revision
+// requires that the claim was not saved before. When the claim was modified
+// in the meantime, an error wrapping ErrConflict is returned. The revision
+// of the saved claim is returned.
+func (s Store) SaveClaimIfRevision(c Claim, revision string) (string, error) {
+	c, bytes, err := s.marshalClaim(c)
+	if err != nil {
+		return "", err
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.backingStore.SaveIfRevision(ItemTypeClaims, c.Installation, c.ID, bytes, revision); err != nil {
+		return "", err
+	}
+
+	if indexer, ok := s.indexer(); ok {
+		if err := s.indexClaim(indexer, c); err != nil {
+			return "", err
+		}
+	}
+	return crud.Revision(bytes), nil
+}
+
+// marshalClaim returns the claim, with its sensitive parameters encrypted,
+// and its record.
+func (s Store) marshalClaim(c Claim) (Claim, []byte, error) {
+	c, err := s.encryptSensitiveParameters(c)
+	if err != nil {
+		return Claim{}, nil, err
+	}
+
+	bytes, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return Claim{}, nil, errors.Wrapf(err, "error marshaling claim %s", c.ID)
+	}
+	return c, bytes, nil
+}
+
+func (s Store) SaveResult(r Result) error {
+	bytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling result %s", r.ID)
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	if err := s.backingStore.Save(ItemTypeResults, r.ClaimID, r.ID, bytes); err != nil {
+		return err
+	}
+
+	if indexer, ok := s.indexer(); ok {
+		return s.indexClaimStatus(indexer, r.ClaimID)
+	}
+	return nil
+}
+
+// SaveResultIfRevision persists the specified result when its stored record
+// has the revision returned by ReadResultWithRevision, so that concurrent
+// processes updating the result, such as a status updater and the executor
+// of the operation, do not overwrite each other. An empty revision requires
+// that the result was not saved before. When the result was modified in the
+// meantime, an error wrapping ErrConflict is returned. The revision of the
+// saved result is returned.
+func (s Store) SaveResultIfRevision(r Result, revision string) (string, error) {
+	bytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", errors.Wrapf(err, "error marshaling result %s", r.ID)
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.backingStore.SaveIfRevision(ItemTypeResults, r.ClaimID, r.ID, bytes, revision); err != nil {
+		return "", err
+	}
+
+	if indexer, ok := s.indexer(); ok {
+		if err := s.indexClaimStatus(indexer, r.ClaimID); err != nil {
+			return "", err
+		}
+	}
+	return crud.Revision(bytes), nil
+}
+
+func (s Store) SaveOutput(o Output) error {
+	bytes, compressed := o.Value, false
+	if s.outputCompressionThreshold > 0 && len(o.Value) > s.outputCompressionThreshold {
+		// Compressed outputs are identified by the flag on their result
+		_, err := s.ReadResult(o.result.ID)
+		if err == nil {
+			bytes, compressed, err = s.compressOutput(o.Value)
+			if err != nil {
+				return errors.Wrapf(err, "error compressing output %s", o.Name)
+			}
+		} else if !errors.Is(err, ErrResultNotFound) {
+			return err
+		}
+	}
+
+	var err error
+
+	if s.isOutputSensitive(o.claim, o.Name) {
+		bytes, err = s.keyring.encryptRecord(o.claim.Installation, bytes)
+		if err != nil {
+			return errors.Wrapf(err, "error encrypting output %s", o.Name)
+		}
+	}
+
+	if err := s.backingStore.Save(ItemTypeOutputs, o.result.ID, s.outputKey(o.result.ID, o.Name), bytes); err != nil {
+		return err
+	}
+
+	return s.flagCompressedOutput(o, compressed)
+}
+
+func (s Store) DeleteInstallation(installation string) error {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	claimIDs, err := s.ListClaims(installation)
+	if err != nil {
+		return err
+	}
+
+	for _, claimID := range claimIDs {
+		if err := s.DeleteClaim(claimID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s Store) DeleteClaim(claimID string) error {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	resultIDs, err := s.ListResults(claimID)
+	if err != nil {
+		return err
+	}
+
+	for _, resultID := range resultIDs {
+		if err := s.DeleteResult(resultID); err != nil {
+			return err
+		}
+	}
+
+	err = s.backingStore.Delete(ItemTypeClaims, claimID)
+	if err != nil {
+		return s.handleNotExistsError(err, ErrClaimNotFound)
+	}
+
+	if indexer, ok := s.indexer(); ok {
+		err = indexer.RemoveIndexes(ItemTypeClaims, claimID)
+		return errors.Wrapf(err, "error removing the indexes of claim %s", claimID)
+	}
+	return nil
+}
+
+func (s Store) DeleteResult(resultID string) error {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	outputNames, err := s.ListOutputs(resultID)
+	if err != nil {
+		return err
+	}
+
+	for _, outputName := range outputNames {
+		if err := s.DeleteOutput(resultID, outputName); err != nil {
+			return err
+		}
+	}
+
+	err = s.backingStore.Delete(ItemTypeResults, resultID)
+	return s.handleNotExistsError(err, ErrResultNotFound)
+}
+
+func (s Store) DeleteOutput(resultID string, outputName string) error {
+	err := s.backingStore.Delete(ItemTypeOutputs, s.outputKey(resultID, outputName))
+	return s.handleNotExistsError(err, ErrOutputNotFound)
+}
+
+// outputKey generates the unique key used to store an output.
+func (s Store) outputKey(resultID string, outputName string) string {
+	return resultID + "-" + outputName
+}
+
+// isOutputSensitive determines if an output should be encrypted at rest.
+// Outputs that are not defined by the bundle are not sensitive.
+func (s Store) isOutputSensitive(c Claim, outputName string) bool {
+	sensitive, err := c.Bundle.IsOutputSensitive(outputName)
+	return err == nil && sensitive
+}
+
+// encryptSensitiveParameters returns a copy of the claim with the values of
+// sensitive parameters encrypted, when the store has an encryption handler.
+func (s Store) encryptSensitiveParameters(c Claim) (Claim, error) {
+	if !s.encryptParameters || len(c.Parameters) == 0 {
+		return c, nil
+	}
+
+	params := make(map[string]interface{}, len(c.Parameters))
+	for name, value := range c.Parameters {
+		if sensitive, _ := c.Bundle.IsParameterSensitive(name); !sensitive {
+			params[name] = value
+			continue
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return Claim{}, errors.Wrapf(err, "error marshaling parameter %s", name)
+		}
+		keyID, data, err := s.keyring.encrypt(c.Installation, data)
+		if err != nil {
+			return Claim{}, errors.Wrapf(err, "error encrypting parameter %s", name)
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		if keyID != "" {
+			encoded = keyID + ":" + encoded
+		}
+		params[name] = encryptedParameterPrefix + encoded
+	}
+
+	c.Parameters = params
+	return c, nil
+}
+
+// decryptSensitiveParameters decrypts the values of sensitive parameters that
+// were encrypted when the claim was saved.
+func (s Store) decryptSensitiveParameters(c Claim) (Claim, error) {
+	for name, value := range c.Parameters {
+		encoded, ok := value.(string)
+		if !ok || !strings.HasPrefix(encoded, encryptedParameterPrefix) {
+			continue
+		}
+		if sensitive, _ := c.Bundle.IsParameterSensitive(name); !sensitive {
+			continue
+		}
+
+		// The key ID is omitted for values encrypted without a keyring
+		keyID := ""
+		encoded = strings.TrimPrefix(encoded, encryptedParameterPrefix)
+		if i := strings.IndexByte(encoded, ':'); i >= 0 {
+			keyID, encoded = encoded[:i], encoded[i+1:]
+		}
+
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return Claim{}, errors.Wrapf(err, "error decoding parameter %s", name)
+		}
+		data, err = s.keyring.decrypt(keyID, data)
+		if err != nil {
+			return Claim{}, errors.Wrapf(err, "error decrypting parameter %s", name)
+		}
+
+		var decrypted interface{}
+		if err := json.Unmarshal(data, &decrypted); err != nil {
+			return Claim{}, errors.Wrapf(err, "error unmarshaling parameter %s", name)
+		}
+		c.Parameters[name] = decrypted
+	}
+
+	return c, nil
+}
+
+// handleNotExistsError replaces a not found error from the backing store
+// with a NotFoundError for the claim item type.
+func (s Store) handleNotExistsError(err error, notExistsError error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, crud.ErrRecordDoesNotExist) {
+		return NotFoundError{Err: notExistsError, Cause: err}
+	}
+	return err
+}
@@ -0,0 +1,328 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+var claimStoreBundle = bundle.Bundle{
+	Definitions: map[string]*definition.Schema{
+		"string":   {Type: "string"},
+		"password": {Type: "string", WriteOnly: makeBoolPtr(true)},
+	},
+	Outputs: map[string]bundle.Output{
+		"host":     {Definition: "string"},
+		"password": {Definition: "password"},
+	},
+}
+
+func makeBoolPtr(value bool) *bool {
+	return &value
+}
+
+// generateClaimData creates an installation with a single claim, result and output.
+func generateClaimData(t *testing.T, store Store, installation string, action string, status string) (Claim, Result) {
+	var c Claim
+	var err error
+	if lastClaim, lastErr := store.ReadLastClaim(installation); lastErr == nil {
+		c, err = lastClaim.NewClaim(action, claimStoreBundle, nil)
+	} else {
+		c, err = New(installation, action, claimStoreBundle, nil)
+	}
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+
+	r, err := c.NewResult(status)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "host", []byte(c.Action))))
+
+	return c, r
+}
+
+func TestStore_ReadInstallation(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	install, _ := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	upgrade, upgradeResult := generateClaimData(t, store, "mysql", ActionUpgrade, StatusFailed)
+	generateClaimData(t, store, "wordpress", ActionInstall, StatusSucceeded)
+
+	installations, err := store.ListInstallations()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql", "wordpress"}, installations)
+
+	i, err := store.ReadInstallation("mysql")
+	require.NoError(t, err)
+	require.Len(t, i.Claims, 2)
+	assert.Equal(t, install.ID, i.Claims[0].ID)
+	assert.Equal(t, upgrade.ID, i.Claims[1].ID)
+	assert.Equal(t, StatusFailed, i.GetLastStatus())
+
+	lastResult, err := store.ReadLastResult(upgrade.ID)
+	require.NoError(t, err)
+	assert.Equal(t, upgradeResult.ID, lastResult.ID)
+
+	outputs, err := store.ListOutputs(upgradeResult.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host"}, outputs)
+
+	o, err := store.ReadLastOutput("mysql", "host")
+	require.NoError(t, err)
+	assert.Equal(t, ActionUpgrade, string(o.Value))
+
+	statuses, err := store.ReadAllInstallationStatus()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, StatusFailed, statuses[0].GetLastStatus())
+	assert.Equal(t, StatusSucceeded, statuses[1].GetLastStatus())
+}
+
+func TestStore_NotFound(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+	_, err := store.ReadInstallation("missing")
+	assert.ErrorIs(t, err, ErrInstallationNotFound)
+
+	_, err = store.ReadClaim("missing")
+	assert.ErrorIs(t, err, ErrClaimNotFound)
+
+	_, err = store.ReadResult("missing")
+	assert.ErrorIs(t, err, ErrResultNotFound)
+
+	_, err = store.ReadOutput(Claim{}, Result{ID: "missing"}, "host")
+	assert.ErrorIs(t, err, ErrOutputNotFound)
+	assert.ErrorIs(t, err, crud.ErrRecordDoesNotExist, "the error from the backing store should be preserved")
+
+	var notFoundErr NotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+	assert.Equal(t, ErrOutputNotFound, notFoundErr.Err)
+	assert.EqualError(t, err, "output does not exist")
+}
+
+func TestStore_EncryptSensitiveOutputs(t *testing.T) {
+	encrypt := func(data []byte) ([]byte, error) {
+		return append([]byte("encrypted:"), data...), nil
+	}
+	decrypt := func(data []byte) ([]byte, error) {
+		return data[len("encrypted:"):], nil
+	}
+	backingStore := crud.NewMockStore()
+	store := NewClaimStore(backingStore, encrypt, decrypt)
+
+	c, r := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "password", []byte("topsecret"))))
+
+	raw, err := backingStore.Read(ItemTypeOutputs, r.ID+"-password")
+	require.NoError(t, err)
+	assert.Equal(t, "encrypted:topsecret", string(raw), "sensitive outputs should be encrypted")
+
+	raw, err = backingStore.Read(ItemTypeOutputs, r.ID+"-host")
+	require.NoError(t, err)
+	assert.Equal(t, ActionInstall, string(raw), "outputs that are not sensitive should not be encrypted")
+
+	o, err := store.ReadOutput(c, r, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "topsecret", string(o.Value))
+}
+
+func TestStore_ReadOutputOrDefault(t *testing.T) {
+	b := bundle.Bundle{
+		Definitions: map[string]*definition.Schema{
+			"port":   {Type: "integer", Default: 8080},
+			"string": {Type: "string"},
+			"endpoint": {
+				Type:    "object",
+				Default: map[string]interface{}{"host": "localhost"},
+				Properties: map[string]*definition.Schema{
+					"host": {Type: "string"},
+					"tls":  {Type: "object", Properties: map[string]*definition.Schema{"enabled": {Type: "boolean", Default: true}}},
+				},
+			},
+		},
+		Outputs: map[string]bundle.Output{
+			"host":        {Definition: "string"},
+			"port":        {Definition: "port"},
+			"upgradePort": {Definition: "port", ApplyTo: []string{ActionUpgrade}},
+			"endpoint":    {Definition: "endpoint"},
+		},
+	}
+
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	c, err := New("mysql", ActionInstall, b, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+	r, err := c.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "host", []byte("localhost"))))
+
+	t.Run("stored output", func(t *testing.T) {
+		o, err := store.ReadOutputOrDefault(c, r, "host")
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", string(o.Value))
+	})
+
+	t.Run("default value", func(t *testing.T) {
+		o, err := store.ReadOutputOrDefault(c, r, "port")
+		require.NoError(t, err)
+		assert.Equal(t, "port", o.Name)
+		assert.Equal(t, "8080", string(o.Value))
+	})
+
+	t.Run("object default value", func(t *testing.T) {
+		o, err := store.ReadOutputOrDefault(c, r, "endpoint")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"host":"localhost","tls":{"enabled":true}}`, string(o.Value))
+
+		opResult := driver.OperationResult{Outputs: map[string]string{"host": "localhost"}}
+		require.NoError(t, opResult.SetDefaultOutputValues(driver.Operation{Action: ActionInstall, Bundle: &b}))
+		assert.Equal(t, opResult.Outputs["endpoint"], string(o.Value), "the default value should be the same as the one set by the driver")
+	})
+
+	t.Run("output does not apply to the action", func(t *testing.T) {
+		_, err := store.ReadOutputOrDefault(c, r, "upgradePort")
+		assert.ErrorIs(t, err, ErrOutputNotFound)
+	})
+
+	t.Run("no default value", func(t *testing.T) {
+		require.NoError(t, store.DeleteOutput(r.ID, "host"))
+		_, err := store.ReadOutputOrDefault(c, r, "host")
+		assert.ErrorIs(t, err, ErrOutputNotFound)
+	})
+
+	t.Run("undefined output", func(t *testing.T) {
+		_, err := store.ReadOutputOrDefault(c, r, "missing")
+		assert.ErrorIs(t, err, ErrOutputNotFound)
+	})
+}
+
+func TestStore_EncryptSensitiveParameters(t *testing.T) {
+	encrypt := func(data []byte) ([]byte, error) {
+		return append([]byte("encrypted:"), data...), nil
+	}
+	decrypt := func(data []byte) ([]byte, error) {
+		return data[len("encrypted:"):], nil
+	}
+
+	b := claimStoreBundle
+	b.Parameters = map[string]bundle.Parameter{
+		"password": {Definition: "password"},
+		"host":     {Definition: "string"},
+	}
+	params := map[string]interface{}{"password": "topsecret", "host": "localhost"}
+
+	t.Run("encryption handler", func(t *testing.T) {
+		backingStore := crud.NewMockStore()
+		store := NewClaimStore(backingStore, encrypt, decrypt)
+
+		c, err := New("mysql", ActionInstall, b, params)
+		require.NoError(t, err)
+		require.NoError(t, store.SaveClaim(c))
+		assert.Equal(t, "topsecret", c.Parameters["password"], "the claim passed to SaveClaim should not be modified")
+
+		raw, err := backingStore.Read(ItemTypeClaims, c.ID)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "topsecret", "sensitive parameters should be encrypted")
+		assert.Contains(t, string(raw), "localhost", "parameters that are not sensitive should not be encrypted")
+
+		got, err := store.ReadClaim(c.ID)
+		require.NoError(t, err)
+		assert.Equal(t, params, got.Parameters)
+	})
+
+	t.Run("no encryption handler", func(t *testing.T) {
+		backingStore := crud.NewMockStore()
+		store := NewClaimStore(backingStore, nil, nil)
+
+		c, err := New("mysql", ActionInstall, b, params)
+		require.NoError(t, err)
+		require.NoError(t, store.SaveClaim(c))
+
+		raw, err := backingStore.Read(ItemTypeClaims, c.ID)
+		require.NoError(t, err)
+		assert.Contains(t, string(raw), "topsecret")
+
+		got, err := store.ReadClaim(c.ID)
+		require.NoError(t, err)
+		assert.Equal(t, params, got.Parameters)
+	})
+}
+
+func TestStore_DeleteInstallation(t *testing.T) {
+	backingStore := crud.NewMockStore()
+	store := NewClaimStore(backingStore, nil, nil)
+	c, r := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+
+	require.NoError(t, store.DeleteInstallation("mysql"))
+
+	_, err := store.ReadClaim(c.ID)
+	assert.ErrorIs(t, err, ErrClaimNotFound)
+	_, err = store.ReadResult(r.ID)
+	assert.ErrorIs(t, err, ErrResultNotFound)
+	_, err = backingStore.Read(ItemTypeOutputs, r.ID+"-host")
+	assert.Equal(t, crud.ErrRecordDoesNotExist, err)
+}
+
+func TestStore_SaveResultIfRevision(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	c, err := New("mysql", ActionInstall, claimStoreBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+
+	r, err := c.NewResult(StatusRunning)
+	require.NoError(t, err)
+	revision, err := store.SaveResultIfRevision(r, "")
+	require.NoError(t, err, "the result should be created when no revision is expected")
+	_, err = store.SaveResultIfRevision(r, "")
+	assert.ErrorIs(t, err, ErrConflict, "the result should not be created twice")
+
+	executorResult, executorRevision, err := store.ReadResultWithRevision(r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, revision, executorRevision)
+	updaterResult, updaterRevision, err := store.ReadResultWithRevision(r.ID)
+	require.NoError(t, err)
+
+	// The executor saves its changes first
+	executorResult.Message = "installed"
+	_, err = store.SaveResultIfRevision(executorResult, executorRevision)
+	require.NoError(t, err)
+
+	// The status updater read the result before it was modified
+	updaterResult.Message = "still running"
+	_, err = store.SaveResultIfRevision(updaterResult, updaterRevision)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorIs(t, err, crud.ErrConflict)
+
+	got, err := store.ReadResult(r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "installed", got.Message, "the change of the executor should not be overwritten")
+}
+
+func TestStore_SaveClaimIfRevision(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	c, err := New("mysql", ActionInstall, claimStoreBundle, map[string]interface{}{"password": "secret"})
+	require.NoError(t, err)
+
+	revision, err := store.SaveClaimIfRevision(c, "")
+	require.NoError(t, err)
+
+	got, gotRevision, err := store.ReadClaimWithRevision(c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, revision, gotRevision)
+	assert.Equal(t, "secret", got.Parameters["password"])
+
+	got.Custom = "updated"
+	newRevision, err := store.SaveClaimIfRevision(got, revision)
+	require.NoError(t, err)
+	assert.NotEqual(t, revision, newRevision)
+
+	_, err = store.SaveClaimIfRevision(c, revision)
+	assert.ErrorIs(t, err, ErrConflict, "a claim saved with a stale revision should be rejected")
+}
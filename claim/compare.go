@@ -0,0 +1,222 @@
+package claim
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+// RedactedValue replaces the value of sensitive parameters in a DriftReport.
+const RedactedValue = "******"
+
+// InstallationRef identifies an installation stored by a Provider, for
+// example the same application installed in staging and production.
+type InstallationRef struct {
+	// Provider that stores the installation's claims.
+	Provider Provider
+
+	// Name of the installation.
+	Name string
+}
+
+// DriftReport describes the differences between two installations.
+// The source is typically the environment being promoted from, and the
+// target the environment being promoted to.
+type DriftReport struct {
+	// Source is the name of the source installation.
+	Source string `json:"source"`
+
+	// Target is the name of the target installation.
+	Target string `json:"target"`
+
+	// Bundle is set when the installations use a different bundle name or version.
+	Bundle *BundleDrift `json:"bundle,omitempty"`
+
+	// Parameters that differ between the installations, sorted by name.
+	Parameters []ValueDrift `json:"parameters,omitempty"`
+
+	// Outputs whose content digest differs between the installations, sorted by name.
+	Outputs []ValueDrift `json:"outputs,omitempty"`
+}
+
+// HasDrift returns true when the installations are not the same.
+func (r DriftReport) HasDrift() bool {
+	return r.Bundle != nil || len(r.Parameters) > 0 || len(r.Outputs) > 0
+}
+
+// BundleDrift describes the bundle used by each installation.
+type BundleDrift struct {
+	SourceName    string `json:"sourceName"`
+	SourceVersion string `json:"sourceVersion"`
+	TargetName    string `json:"targetName"`
+	TargetVersion string `json:"targetVersion"`
+}
+
+// ValueDrift describes a parameter or output that differs between two
+// installations. A nil value indicates that it is not set on that installation.
+type ValueDrift struct {
+	// Name of the parameter or output.
+	Name string `json:"name"`
+
+	// Source is the value from the source installation. Sensitive parameters
+	// are replaced with RedactedValue, and outputs are represented by their
+	// content digest.
+	Source interface{} `json:"source,omitempty"`
+
+	// Target is the value from the target installation. Sensitive parameters
+	// are replaced with RedactedValue, and outputs are represented by their
+	// content digest.
+	Target interface{} `json:"target,omitempty"`
+}
+
+// CompareInstallations compares the most recent claim and outputs of two
+// installations, which may be stored by different providers, and reports the
+// differences in the bundle, parameters and outputs.
+func CompareInstallations(source InstallationRef, target InstallationRef) (DriftReport, error) {
+	sourceClaim, err := source.Provider.ReadLastClaim(source.Name)
+	if err != nil {
+		return DriftReport{}, errors.Wrapf(err, "could not read the last claim for installation %s", source.Name)
+	}
+
+	targetClaim, err := target.Provider.ReadLastClaim(target.Name)
+	if err != nil {
+		return DriftReport{}, errors.Wrapf(err, "could not read the last claim for installation %s", target.Name)
+	}
+
+	sourceOutputs, err := source.Provider.ReadLastOutputs(source.Name)
+	if err != nil {
+		return DriftReport{}, errors.Wrapf(err, "could not read the outputs for installation %s", source.Name)
+	}
+
+	targetOutputs, err := target.Provider.ReadLastOutputs(target.Name)
+	if err != nil {
+		return DriftReport{}, errors.Wrapf(err, "could not read the outputs for installation %s", target.Name)
+	}
+
+	report := DriftReport{
+		Source: source.Name,
+		Target: target.Name,
+	}
+
+	if sourceClaim.Bundle.Name != targetClaim.Bundle.Name || sourceClaim.Bundle.Version != targetClaim.Bundle.Version {
+		report.Bundle = &BundleDrift{
+			SourceName:    sourceClaim.Bundle.Name,
+			SourceVersion: sourceClaim.Bundle.Version,
+			TargetName:    targetClaim.Bundle.Name,
+			TargetVersion: targetClaim.Bundle.Version,
+		}
+	}
+
+	report.Parameters = compareParameters(sourceClaim, targetClaim)
+	report.Outputs = compareOutputs(sourceOutputs, targetOutputs)
+
+	return report, nil
+}
+
+// compareParameters returns the parameters that differ between the claims,
+// redacting the values of sensitive parameters.
+func compareParameters(source Claim, target Claim) []ValueDrift {
+	var drift []ValueDrift
+	for _, name := range unionKeys(source.Parameters, target.Parameters) {
+		sourceValue, sourceOk := source.Parameters[name]
+		targetValue, targetOk := target.Parameters[name]
+		if sourceOk == targetOk && reflect.DeepEqual(sourceValue, targetValue) {
+			continue
+		}
+
+		d := ValueDrift{Name: name}
+		if sourceOk {
+			d.Source = redactParameter(source.Bundle, name, sourceValue)
+		}
+		if targetOk {
+			d.Target = redactParameter(target.Bundle, name, targetValue)
+		}
+		drift = append(drift, d)
+	}
+	return drift
+}
+
+// compareOutputs returns the outputs whose content digest differs between
+// the installations.
+func compareOutputs(source Outputs, target Outputs) []ValueDrift {
+	sourceDigests := outputDigests(source)
+	targetDigests := outputDigests(target)
+
+	var names []string
+	for name := range sourceDigests {
+		names = append(names, name)
+	}
+	for name := range targetDigests {
+		if _, ok := sourceDigests[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var drift []ValueDrift
+	for _, name := range names {
+		sourceDigest, sourceOk := sourceDigests[name]
+		targetDigest, targetOk := targetDigests[name]
+		if sourceOk == targetOk && sourceDigest == targetDigest {
+			continue
+		}
+
+		d := ValueDrift{Name: name}
+		if sourceOk {
+			d.Source = sourceDigest
+		}
+		if targetOk {
+			d.Target = targetDigest
+		}
+		drift = append(drift, d)
+	}
+	return drift
+}
+
+// outputDigests returns the content digest of each output, using the digest
+// recorded on the result when available.
+func outputDigests(outputs Outputs) map[string]string {
+	digests := make(map[string]string, outputs.Len())
+	for i := 0; i < outputs.Len(); i++ {
+		o, _ := outputs.GetByIndex(i)
+		digests[o.Name] = outputDigest(o)
+	}
+	return digests
+}
+
+// outputDigest returns the content digest of the output, using the digest
+// recorded on the result when available.
+func outputDigest(o Output) string {
+	if digest, ok := o.result.OutputMetadata.GetContentDigest(o.Name); ok && digest != "" {
+		return digest
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(o.Value))
+}
+
+// redactParameter replaces the value of a sensitive parameter with RedactedValue.
+func redactParameter(b bundle.Bundle, name string, value interface{}) interface{} {
+	if sensitive, _ := b.IsParameterSensitive(name); sensitive {
+		return RedactedValue
+	}
+	return value
+}
+
+// unionKeys returns the sorted set of parameter names from both maps.
+func unionKeys(a map[string]interface{}, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func saveCompareInstallation(t *testing.T, store Store, installation string, version string, params map[string]interface{}, outputs map[string]string) {
+	b := bundle.Bundle{
+		Name:    "mybuns",
+		Version: version,
+		Definitions: map[string]*definition.Schema{
+			"string":   {Type: "string"},
+			"password": {Type: "string", WriteOnly: makeBoolPtr(true)},
+		},
+		Parameters: map[string]bundle.Parameter{
+			"region":   {Definition: "string"},
+			"password": {Definition: "password"},
+		},
+		Outputs: map[string]bundle.Output{
+			"host": {Definition: "string"},
+		},
+	}
+
+	c, err := New(installation, ActionInstall, b, params)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+
+	r, err := c.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+
+	for name, value := range outputs {
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, name, []byte(value))))
+	}
+}
+
+func TestCompareInstallations(t *testing.T) {
+	staging := NewClaimStore(crud.NewMockStore(), nil, nil)
+	prod := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+	saveCompareInstallation(t, staging, "app", "1.1.0",
+		map[string]interface{}{"region": "us-east", "password": "staging-secret"},
+		map[string]string{"host": "staging.example.com", "port": "8080"})
+	saveCompareInstallation(t, prod, "app", "1.0.0",
+		map[string]interface{}{"region": "us-east", "password": "prod-secret", "replicas": 3},
+		map[string]string{"host": "prod.example.com", "port": "8080"})
+
+	report, err := CompareInstallations(InstallationRef{Provider: staging, Name: "app"}, InstallationRef{Provider: prod, Name: "app"})
+	require.NoError(t, err)
+
+	assert.True(t, report.HasDrift())
+	assert.Equal(t, &BundleDrift{SourceName: "mybuns", SourceVersion: "1.1.0", TargetName: "mybuns", TargetVersion: "1.0.0"}, report.Bundle)
+
+	wantParams := []ValueDrift{
+		{Name: "password", Source: RedactedValue, Target: RedactedValue},
+		{Name: "replicas", Target: float64(3)},
+	}
+	assert.Equal(t, wantParams, report.Parameters)
+
+	require.Len(t, report.Outputs, 1)
+	assert.Equal(t, "host", report.Outputs[0].Name)
+	assert.Contains(t, report.Outputs[0].Source, "sha256:")
+	assert.NotEqual(t, report.Outputs[0].Source, report.Outputs[0].Target)
+}
+
+func TestCompareInstallations_NoDrift(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	params := map[string]interface{}{"region": "us-east", "password": "secret"}
+	outputs := map[string]string{"host": "example.com"}
+	saveCompareInstallation(t, store, "staging", "1.0.0", params, outputs)
+	saveCompareInstallation(t, store, "prod", "1.0.0", params, outputs)
+
+	report, err := CompareInstallations(InstallationRef{Provider: store, Name: "staging"}, InstallationRef{Provider: store, Name: "prod"})
+	require.NoError(t, err)
+	assert.False(t, report.HasDrift(), "expected no drift, got %#v", report)
+}
+
+func TestCompareInstallations_NotFound(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	_, err := CompareInstallations(InstallationRef{Provider: store, Name: "missing"}, InstallationRef{Provider: store, Name: "missing"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "could not read the last claim for installation missing")
+}
@@ -0,0 +1,118 @@
+package claim
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// compressedRecordPrefix identifies an output that was compressed with gzip
+// before it was persisted, and is followed by the compressed value.
+const compressedRecordPrefix = "cnab-gzip:"
+
+// SetOutputCompression enables the transparent compression of the outputs
+// larger than the threshold, in bytes, when they are saved. Compressed
+// outputs are flagged with OutputCompression in the metadata of their
+// result, and are decompressed when they are read. Only the outputs of
+// results that were saved are compressed, since the flag is saved on the
+// result. Outputs that do not shrink when compressed, such as binary data
+// that is already compressed, are saved as is. A threshold of zero disables
+// the compression.
+func (s *Store) SetOutputCompression(threshold int) {
+	s.outputCompressionThreshold = threshold
+}
+
+// compressOutput compresses the value of an output when it is larger than the
+// compression threshold of the store, and returns whether it was compressed.
+func (s Store) compressOutput(value []byte) ([]byte, bool, error) {
+	if s.outputCompressionThreshold <= 0 || len(value) <= s.outputCompressionThreshold {
+		return value, false, nil
+	}
+
+	var record bytes.Buffer
+	record.WriteString(compressedRecordPrefix)
+	w := gzip.NewWriter(&record)
+	if _, err := w.Write(value); err != nil {
+		return nil, false, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, false, err
+	}
+
+	if record.Len() >= len(value) {
+		return value, false, nil
+	}
+	return record.Bytes(), true, nil
+}
+
+// decompressOutput decompresses the value of an output saved by
+// compressOutput, when the output is flagged as compressed in the metadata of
+// its result. The flag is read from the saved result when the result passed
+// by the caller is not flagged but the value looks compressed, since the
+// flag is saved after the result, and the record prefix is only used as a
+// cross-check: a value that is not flagged is returned as is, even when it
+// starts with the prefix.
+func (s Store) decompressOutput(r Result, outputName string, record []byte) ([]byte, error) {
+	if !bytes.HasPrefix(record, []byte(compressedRecordPrefix)) {
+		return record, nil
+	}
+
+	compression, _ := r.OutputMetadata.GetCompression(outputName)
+	if compression != CompressionGzip {
+		saved, err := s.ReadResult(r.ID)
+		if err != nil && !errors.Is(err, ErrResultNotFound) {
+			return nil, err
+		}
+		compression, _ = saved.OutputMetadata.GetCompression(outputName)
+	}
+	if compression != CompressionGzip {
+		return record, nil
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(record[len(compressedRecordPrefix):]))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	return ioutil.ReadAll(gr)
+}
+
+// flagCompressedOutput records whether the output was compressed in the
+// metadata of its persisted result, removing the flag of a compressed value
+// that the output replaced. The result is saved with its revision, and read
+// again when it was modified concurrently, so that other changes to the
+// result are not overwritten.
+func (s Store) flagCompressedOutput(o Output, compressed bool) error {
+	var err error
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		var (
+			r        Result
+			revision string
+		)
+		r, revision, err = s.ReadResultWithRevision(o.result.ID)
+		if err != nil {
+			if errors.Is(err, ErrResultNotFound) && !compressed {
+				return nil
+			}
+			return err
+		}
+
+		compression, _ := r.OutputMetadata.GetCompression(o.Name)
+		if (compression == CompressionGzip) == compressed {
+			return nil
+		}
+		if compressed {
+			r.OutputMetadata.SetCompression(o.Name, CompressionGzip)
+		} else {
+			delete(r.OutputMetadata[o.Name], OutputCompression)
+		}
+		_, err = s.SaveResultIfRevision(r, revision)
+		if !errors.Is(err, ErrConflict) {
+			break
+		}
+	}
+	return errors.Wrapf(err, "error flagging output %s as compressed", o.Name)
+}
@@ -0,0 +1,128 @@
+package claim
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_OutputCompression(t *testing.T) {
+	manifest := strings.Repeat("apiVersion: v1\nkind: ConfigMap\n", 100)
+
+	t.Run("large outputs are compressed", func(t *testing.T) {
+		backingStore := crud.NewMockStore()
+		store := NewClaimStore(backingStore, nil, nil)
+		store.SetOutputCompression(1024)
+
+		c, r := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "manifest", []byte(manifest))))
+
+		raw, err := backingStore.Read(ItemTypeOutputs, r.ID+"-manifest")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(raw), compressedRecordPrefix), "the output should be compressed")
+		assert.Less(t, len(raw), len(manifest))
+
+		raw, err = backingStore.Read(ItemTypeOutputs, r.ID+"-host")
+		require.NoError(t, err)
+		assert.Equal(t, ActionInstall, string(raw), "outputs below the threshold should not be compressed")
+
+		o, err := store.ReadOutput(c, r, "manifest")
+		require.NoError(t, err)
+		assert.Equal(t, manifest, string(o.Value))
+
+		savedResult, err := store.ReadResult(r.ID)
+		require.NoError(t, err)
+		compression, ok := savedResult.OutputMetadata.GetCompression("manifest")
+		assert.True(t, ok, "the compressed output should be flagged on its result")
+		assert.Equal(t, CompressionGzip, compression)
+		_, ok = savedResult.OutputMetadata.GetCompression("host")
+		assert.False(t, ok)
+	})
+
+	t.Run("compressed before encryption", func(t *testing.T) {
+		encrypt := func(data []byte) ([]byte, error) {
+			return append([]byte("encrypted:"), data...), nil
+		}
+		decrypt := func(data []byte) ([]byte, error) {
+			return data[len("encrypted:"):], nil
+		}
+		backingStore := crud.NewMockStore()
+		store := NewClaimStore(backingStore, encrypt, decrypt)
+		store.SetOutputCompression(1024)
+
+		c, r := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "password", []byte(manifest))))
+
+		raw, err := backingStore.Read(ItemTypeOutputs, r.ID+"-password")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(raw), "encrypted:"+compressedRecordPrefix))
+
+		o, err := store.ReadOutput(c, r, "password")
+		require.NoError(t, err)
+		assert.Equal(t, manifest, string(o.Value))
+	})
+
+	t.Run("incompressible outputs", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		store.SetOutputCompression(1)
+
+		value, compressed, err := store.compressOutput([]byte("ab"))
+		require.NoError(t, err)
+		assert.False(t, compressed)
+		assert.Equal(t, "ab", string(value))
+	})
+
+	t.Run("compressed outputs are read when disabled", func(t *testing.T) {
+		backingStore := crud.NewMockStore()
+		store := NewClaimStore(backingStore, nil, nil)
+		store.SetOutputCompression(1024)
+		c, r := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "manifest", []byte(manifest))))
+
+		store.SetOutputCompression(0)
+		o, err := store.ReadOutput(c, r, "manifest")
+		require.NoError(t, err)
+		assert.Equal(t, manifest, string(o.Value))
+	})
+	t.Run("outputs are decompressed when flagged", func(t *testing.T) {
+		backingStore := crud.NewMockStore()
+		store := NewClaimStore(backingStore, nil, nil)
+		store.SetOutputCompression(1024)
+		c, r := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+
+		lookalike := compressedRecordPrefix + "not compressed"
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "manifest", []byte(lookalike))))
+		o, err := store.ReadOutput(c, r, "manifest")
+		require.NoError(t, err)
+		assert.Equal(t, lookalike, string(o.Value), "an output that is not flagged as compressed should be returned as is")
+
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "manifest", []byte(manifest))))
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "manifest", []byte(lookalike))))
+		savedResult, err := store.ReadResult(r.ID)
+		require.NoError(t, err)
+		_, ok := savedResult.OutputMetadata.GetCompression("manifest")
+		assert.False(t, ok, "the flag should be removed when the output is replaced by a value that is not compressed")
+		o, err = store.ReadOutput(c, savedResult, "manifest")
+		require.NoError(t, err)
+		assert.Equal(t, lookalike, string(o.Value))
+	})
+
+	t.Run("outputs of unsaved results are not compressed", func(t *testing.T) {
+		backingStore := crud.NewMockStore()
+		store := NewClaimStore(backingStore, nil, nil)
+		store.SetOutputCompression(1024)
+		c, err := New("mysql", ActionInstall, claimStoreBundle, nil)
+		require.NoError(t, err)
+		r, err := c.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "manifest", []byte(manifest))))
+		raw, err := backingStore.Read(ItemTypeOutputs, r.ID+"-manifest")
+		require.NoError(t, err)
+		assert.Equal(t, manifest, string(raw), "the output cannot be flagged as compressed without its result")
+	})
+}
@@ -0,0 +1,121 @@
+package claim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle/definition"
+)
+
+// SetCustomValue records the value under the key in the Custom section of
+// the result, so that runtimes can persist metadata about how the operation
+// was executed, such as the name of a job or the ID of a container. Keys
+// should be namespaced, for example io.cnab.execution-environment. The value
+// must be serializable to JSON. An error is returned when the Custom section
+// holds data that is not a JSON object.
+func (r *Result) SetCustomValue(key string, value interface{}) error {
+	if key == "" {
+		return errors.New("the key of the custom value is required")
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return errors.Wrapf(err, "the custom value %s of result %s cannot be serialized to JSON", key, r.ID)
+	}
+
+	custom, err := r.customValues()
+	if err != nil {
+		return err
+	}
+	if custom == nil {
+		custom = map[string]interface{}{}
+	}
+	custom[key] = value
+	r.Custom = custom
+	return nil
+}
+
+// GetCustomValue decodes the value recorded under the key in the Custom
+// section of the result into v, which must be a pointer, and returns whether
+// it was recorded. The value is either of the type of v, when it was set on
+// the result in this process, or decoded from JSON, when the result was
+// loaded from a store, so it is converted through JSON.
+func (r Result) GetCustomValue(key string, v interface{}) (bool, error) {
+	custom, err := r.customValues()
+	if err != nil {
+		return false, err
+	}
+	value, ok := custom[key]
+	if !ok || value == nil {
+		return false, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, errors.Wrapf(err, "error marshaling the custom value %s of result %s", key, r.ID)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, errors.Wrapf(err, "error unmarshaling the custom value %s of result %s", key, r.ID)
+	}
+	return true, nil
+}
+
+// DeleteCustomValue removes the value recorded under the key in the Custom
+// section of the result.
+func (r *Result) DeleteCustomValue(key string) error {
+	custom, err := r.customValues()
+	if err != nil || custom == nil {
+		return err
+	}
+	delete(custom, key)
+	r.Custom = custom
+	return nil
+}
+
+// ValidateCustomValue validates the value recorded under the key in the
+// Custom section of the result against the schema, for example the
+// definition of the metadata that a driver records. A value that is not
+// recorded is not validated.
+func (r Result) ValidateCustomValue(key string, schema *definition.Schema) error {
+	var value interface{}
+	ok, err := r.GetCustomValue(key, &value)
+	if err != nil || !ok {
+		return err
+	}
+
+	valErrs, err := schema.Validate(value)
+	if err != nil {
+		return errors.Wrapf(err, "error validating the custom value %s of result %s", key, r.ID)
+	}
+	if len(valErrs) > 0 {
+		msgs := make([]string, len(valErrs))
+		for i, valErr := range valErrs {
+			msgs[i] = fmt.Sprintf("%s: %s", valErr.Path, valErr.Error)
+		}
+		return fmt.Errorf("the custom value %s of result %s does not match its schema: %s", key, r.ID, strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// customValues returns the Custom section of the result as a map, or nil
+// when it is not set. Custom data of another type, such as a struct, is
+// converted through JSON when it is a JSON object.
+func (r Result) customValues() (map[string]interface{}, error) {
+	switch custom := r.Custom.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return custom, nil
+	}
+
+	data, err := json.Marshal(r.Custom)
+	if err != nil {
+		return nil, errors.Wrapf(err, "the custom data of result %s cannot be serialized to JSON", r.ID)
+	}
+	var custom map[string]interface{}
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, errors.Errorf("the custom data of result %s is not a JSON object", r.ID)
+	}
+	return custom, nil
+}
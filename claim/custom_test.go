@@ -0,0 +1,110 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+type jobMetadata struct {
+	JobName   string `json:"jobName"`
+	Namespace string `json:"namespace"`
+	Attempts  int    `json:"attempts"`
+}
+
+func TestResult_CustomValue(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	c, err := New("mysql", ActionInstall, exampleBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+	r, err := c.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+
+	job := jobMetadata{JobName: "mysql-install", Namespace: "cnab", Attempts: 2}
+	require.NoError(t, r.SetCustomValue("io.cnab.kubernetes.job", job))
+	require.NoError(t, r.SetCustomValue("io.cnab.cloudrun.url", "https://mysql.example.com"))
+
+	var got jobMetadata
+	ok, err := r.GetCustomValue("io.cnab.kubernetes.job", &got)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, job, got)
+
+	require.NoError(t, store.SaveResult(r))
+	loaded, err := store.ReadResult(r.ID)
+	require.NoError(t, err)
+
+	got = jobMetadata{}
+	ok, err = loaded.GetCustomValue("io.cnab.kubernetes.job", &got)
+	require.NoError(t, err)
+	require.True(t, ok, "the custom value should round trip through the store")
+	assert.Equal(t, job, got)
+
+	var url string
+	ok, err = loaded.GetCustomValue("io.cnab.cloudrun.url", &url)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "https://mysql.example.com", url)
+
+	ok, err = loaded.GetCustomValue("io.cnab.docker.container", &url)
+	require.NoError(t, err)
+	assert.False(t, ok, "a missing value should not be found")
+
+	require.NoError(t, loaded.DeleteCustomValue("io.cnab.cloudrun.url"))
+	ok, err = loaded.GetCustomValue("io.cnab.cloudrun.url", &url)
+	require.NoError(t, err)
+	assert.False(t, ok, "the value should be deleted")
+
+	t.Run("custom data of another type", func(t *testing.T) {
+		r := Result{ID: "result1", Custom: struct {
+			Owner string `json:"owner"`
+		}{Owner: "team-a"}}
+		require.NoError(t, r.SetCustomValue("io.cnab.example", 1), "a struct should be converted to a map")
+		assert.Equal(t, map[string]interface{}{"owner": "team-a", "io.cnab.example": 1}, r.Custom)
+
+		r = Result{ID: "result1", Custom: "foo"}
+		err := r.SetCustomValue("io.cnab.example", 1)
+		assert.EqualError(t, err, "the custom data of result result1 is not a JSON object")
+		assert.Equal(t, "foo", r.Custom, "custom data that is not a JSON object should be left as is")
+	})
+
+	t.Run("value not serializable", func(t *testing.T) {
+		r := Result{ID: "result1"}
+		err := r.SetCustomValue("io.cnab.example", make(chan int))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the custom value io.cnab.example of result result1 cannot be serialized to JSON")
+
+		r.Custom = map[string]interface{}{"io.cnab.example": make(chan int)}
+		r.ClaimID = "claim1"
+		r.Status = StatusSucceeded
+		err = r.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the custom data must be serializable to JSON")
+	})
+}
+
+func TestResult_ValidateCustomValue(t *testing.T) {
+	schema := &definition.Schema{
+		Type:     "object",
+		Required: []string{"jobName"},
+		Properties: map[string]*definition.Schema{
+			"jobName":  {Type: "string"},
+			"attempts": {Type: "integer"},
+		},
+	}
+
+	r := Result{ID: "result1"}
+	require.NoError(t, r.ValidateCustomValue("io.cnab.kubernetes.job", schema), "a missing value should not be validated")
+
+	require.NoError(t, r.SetCustomValue("io.cnab.kubernetes.job", jobMetadata{JobName: "mysql-install", Attempts: 1}))
+	require.NoError(t, r.ValidateCustomValue("io.cnab.kubernetes.job", schema))
+
+	require.NoError(t, r.SetCustomValue("io.cnab.kubernetes.job", map[string]interface{}{"attempts": "many"}))
+	err := r.ValidateCustomValue("io.cnab.kubernetes.job", schema)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the custom value io.cnab.kubernetes.job of result result1 does not match its schema")
+}
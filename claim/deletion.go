@@ -0,0 +1,150 @@
+package claim
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// DeletionFailure is a record that could not be deleted.
+type DeletionFailure struct {
+	// ItemType of the record, for example ItemTypeResults.
+	ItemType string
+
+	// Name of the record, such as the claim or result ID.
+	Name string
+
+	// Err is the reason that the record could not be deleted.
+	Err error
+}
+
+func (f DeletionFailure) Error() string {
+	return fmt.Sprintf("could not delete %s %s: %v", f.ItemType, f.Name, f.Err)
+}
+
+func (f DeletionFailure) Unwrap() error {
+	return f.Err
+}
+
+// DeletionReport describes the progress of deleting the records of an
+// installation, so that a partial deletion can be inspected and retried.
+type DeletionReport struct {
+	// Installation whose records were deleted.
+	Installation string
+
+	// Deleted is the number of records deleted, by item type.
+	Deleted map[string]int
+
+	// Failures are the records that could not be deleted. A record is not
+	// deleted when any of its results or outputs could not be deleted, so that
+	// they can still be found when the deletion is retried.
+	Failures []DeletionFailure
+}
+
+// Err returns an error combining the failures, or nil when every record was
+// deleted.
+func (r DeletionReport) Err() error {
+	var err *multierror.Error
+	for _, f := range r.Failures {
+		err = multierror.Append(err, f)
+	}
+	return err.ErrorOrNil()
+}
+
+func (r *DeletionReport) deleted(itemType string) {
+	r.Deleted[itemType]++
+}
+
+func (r *DeletionReport) failed(itemType string, name string, err error) {
+	r.Failures = append(r.Failures, DeletionFailure{ItemType: itemType, Name: name, Err: err})
+}
+
+// DeleteInstallationWithReport removes all data associated with an
+// installation, like DeleteInstallation, but continues after a record cannot
+// be deleted. The report lists what was deleted and the records that could
+// not be deleted, and the error returned combines the failures.
+func (s Store) DeleteInstallationWithReport(installation string) (DeletionReport, error) {
+	report := DeletionReport{
+		Installation: installation,
+		Deleted:      map[string]int{},
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return report, err
+	}
+
+	claimIDs, err := s.ListClaims(installation)
+	if err != nil {
+		return report, err
+	}
+
+	for _, claimID := range claimIDs {
+		s.deleteClaimWithReport(claimID, &report)
+	}
+
+	return report, report.Err()
+}
+
+// deleteClaimWithReport deletes the claim and its results, returning false when
+// the claim or any of its results could not be deleted.
+func (s Store) deleteClaimWithReport(claimID string, report *DeletionReport) bool {
+	resultIDs, err := s.ListResults(claimID)
+	if err != nil {
+		report.failed(ItemTypeClaims, claimID, errors.Wrap(err, "error listing results"))
+		return false
+	}
+
+	ok := true
+	for _, resultID := range resultIDs {
+		if !s.deleteResultWithReport(resultID, report) {
+			ok = false
+		}
+	}
+	if !ok {
+		report.failed(ItemTypeClaims, claimID, errors.New("not all of its results were deleted"))
+		return false
+	}
+
+	err = s.backingStore.Delete(ItemTypeClaims, claimID)
+	if err != nil {
+		report.failed(ItemTypeClaims, claimID, s.handleNotExistsError(err, ErrClaimNotFound))
+		return false
+	}
+	report.deleted(ItemTypeClaims)
+	return true
+}
+
+// deleteResultWithReport deletes the result and its outputs, returning false
+// when the result or any of its outputs could not be deleted.
+func (s Store) deleteResultWithReport(resultID string, report *DeletionReport) bool {
+	outputNames, err := s.ListOutputs(resultID)
+	if err != nil {
+		report.failed(ItemTypeResults, resultID, errors.Wrap(err, "error listing outputs"))
+		return false
+	}
+
+	ok := true
+	for _, outputName := range outputNames {
+		if err := s.DeleteOutput(resultID, outputName); err != nil {
+			report.failed(ItemTypeOutputs, s.outputKey(resultID, outputName), err)
+			ok = false
+			continue
+		}
+		report.deleted(ItemTypeOutputs)
+	}
+	if !ok {
+		report.failed(ItemTypeResults, resultID, errors.New("not all of its outputs were deleted"))
+		return false
+	}
+
+	err = s.backingStore.Delete(ItemTypeResults, resultID)
+	if err != nil {
+		report.failed(ItemTypeResults, resultID, s.handleNotExistsError(err, ErrResultNotFound))
+		return false
+	}
+	report.deleted(ItemTypeResults)
+	return true
+}
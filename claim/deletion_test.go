@@ -0,0 +1,74 @@
+package claim
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// failingDeleteStore fails to delete the records named in failures.
+type failingDeleteStore struct {
+	*crud.MockStore
+	failures map[string]bool
+}
+
+func (s *failingDeleteStore) Delete(itemType string, name string) error {
+	if s.failures[name] {
+		return errors.New("permission denied")
+	}
+	return s.MockStore.Delete(itemType, name)
+}
+
+func TestStore_DeleteInstallationWithReport(t *testing.T) {
+	t.Run("all records deleted", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+		generateClaimData(t, store, "mysql", ActionUpgrade, StatusSucceeded)
+
+		report, err := store.DeleteInstallationWithReport("mysql")
+		require.NoError(t, err)
+		assert.Equal(t, "mysql", report.Installation)
+		assert.Equal(t, map[string]int{ItemTypeClaims: 2, ItemTypeResults: 2, ItemTypeOutputs: 2}, report.Deleted)
+		assert.Empty(t, report.Failures)
+
+		_, err = store.ListClaims("mysql")
+		assert.ErrorIs(t, err, ErrInstallationNotFound)
+	})
+
+	t.Run("partial deletion", func(t *testing.T) {
+		backingStore := &failingDeleteStore{MockStore: crud.NewMockStore(), failures: map[string]bool{}}
+		store := NewClaimStore(backingStore, nil, nil)
+		install, installResult := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+		upgrade, _ := generateClaimData(t, store, "mysql", ActionUpgrade, StatusSucceeded)
+		backingStore.failures[installResult.ID+"-host"] = true
+
+		report, err := store.DeleteInstallationWithReport("mysql")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not delete outputs "+installResult.ID+"-host: permission denied")
+		assert.Equal(t, map[string]int{ItemTypeClaims: 1, ItemTypeResults: 1, ItemTypeOutputs: 1}, report.Deleted)
+		require.Len(t, report.Failures, 3)
+		assert.Equal(t, ItemTypeOutputs, report.Failures[0].ItemType)
+		assert.Equal(t, DeletionFailure{ItemType: ItemTypeResults, Name: installResult.ID, Err: report.Failures[1].Err}, report.Failures[1])
+		assert.Equal(t, install.ID, report.Failures[2].Name)
+
+		_, err = store.ReadClaim(install.ID)
+		require.NoError(t, err, "the claim should be kept so that the deletion can be retried")
+		_, err = store.ReadClaim(upgrade.ID)
+		assert.ErrorIs(t, err, ErrClaimNotFound)
+
+		delete(backingStore.failures, installResult.ID+"-host")
+		report, err = store.DeleteInstallationWithReport("mysql")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{ItemTypeClaims: 1, ItemTypeResults: 1, ItemTypeOutputs: 1}, report.Deleted)
+	})
+
+	t.Run("missing installation", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		_, err := store.DeleteInstallationWithReport("missing")
+		assert.ErrorIs(t, err, ErrInstallationNotFound)
+	})
+}
@@ -54,5 +54,26 @@ outputs/
 
 	01EAZDGPM8EQKXA544AHCBMYXH/
 	  01EAZDGPM8EQKXA544AHCBMYXH-CONNECTIONSTRING
+
+The same layout is used by Store.WriteArchive to export the history of
+installations as a tar or zip claims archive, which Store.ImportArchive reads
+back after validating each document, so that claims can be exchanged with
+other CNAB compliant tools. Store.ExportInstallation and
+Store.ImportInstallation use it to back up a single installation, optionally
+encrypted, and restore it into another backing store.
+
+# Errors
+
+The errors returned by the Store are part of its API. Items that do not exist
+are reported with the ErrInstallationNotFound, ErrClaimNotFound,
+ErrResultNotFound and ErrOutputNotFound sentinel errors, usually wrapped in a
+NotFoundError that also matches the crud.ErrRecordDoesNotExist error from the
+backing store. Outputs that fail verification are reported with an
+OutputTamperedError, which matches ErrOutputTampered. Claims and results that
+are saved with a stale revision, by SaveClaimIfRevision and
+SaveResultIfRevision, are reported with ErrConflict, and installations locked
+by another owner with an InstallationLockedError, which matches
+ErrInstallationLocked. Match them with
+errors.Is and errors.As, rather than comparing them or their messages.
 */
 package claim
@@ -0,0 +1,72 @@
+package claim
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// The errors returned by the Store wrap the following sentinel errors, and
+// should be matched with errors.Is rather than by comparing them or their
+// messages, for example:
+//
+//	if errors.Is(err, claim.ErrClaimNotFound) { ... }
+//
+// Errors for items that are not found in the backing store are returned as a
+// NotFoundError, which also matches crud.ErrRecordDoesNotExist.
+var (
+	// ErrInstallationNotFound represents an installation not found in storage
+	ErrInstallationNotFound = errors.New("installation does not exist")
+
+	// ErrClaimNotFound represents a claim not found in storage
+	ErrClaimNotFound = errors.New("claim does not exist")
+
+	// ErrResultNotFound represents a result not found in storage
+	ErrResultNotFound = errors.New("result does not exist")
+
+	// ErrOutputNotFound represents an output not found in storage
+	ErrOutputNotFound = errors.New("output does not exist")
+
+	// ErrOutputTampered is matched by an OutputTamperedError with errors.Is.
+	ErrOutputTampered = errors.New("output does not match its recorded content digest")
+
+	// ErrInstallationLocked is matched by an InstallationLockedError with
+	// errors.Is.
+	ErrInstallationLocked = errors.New("installation is locked")
+
+	// ErrResultSpooled is matched by a SpooledError with errors.Is.
+	ErrResultSpooled = errors.New("result was spooled but could not be saved")
+
+	// ErrConflict is returned when a claim or result is saved with a revision
+	// that does not match its stored record, because it was modified by
+	// another process since it was read. It is the same error as
+	// crud.ErrConflict.
+	ErrConflict = crud.ErrConflict
+)
+
+// maxConflictRetries is how many times a record that was modified
+// concurrently, failing with ErrConflict, is read and updated again before
+// giving up.
+const maxConflictRetries = 3
+
+// NotFoundError is returned when a claim item does not exist in the backing
+// store. It matches both the sentinel error for the item type, such as
+// ErrClaimNotFound, and the error returned by the backing store, such as
+// crud.ErrRecordDoesNotExist, with errors.Is.
+type NotFoundError struct {
+	// Err is the sentinel error for the item type.
+	Err error
+
+	// Cause is the error returned by the backing store.
+	Cause error
+}
+
+func (e NotFoundError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the sentinel error for the item type and the error returned
+// by the backing store.
+func (e NotFoundError) Unwrap() []error {
+	return []error{e.Err, e.Cause}
+}
@@ -0,0 +1,35 @@
+package claim
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_HandleNotExistsError(t *testing.T) {
+	s := Store{}
+
+	t.Run("no error", func(t *testing.T) {
+		assert.NoError(t, s.handleNotExistsError(nil, ErrClaimNotFound))
+	})
+
+	t.Run("wrapped not found error", func(t *testing.T) {
+		storeErr := fmt.Errorf("error reading claims/abc: %w", crud.ErrRecordDoesNotExist)
+
+		err := s.handleNotExistsError(storeErr, ErrClaimNotFound)
+		assert.ErrorIs(t, err, ErrClaimNotFound)
+		assert.ErrorIs(t, err, crud.ErrRecordDoesNotExist)
+		assert.NotErrorIs(t, err, ErrResultNotFound)
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		storeErr := errors.New("connection refused")
+
+		err := s.handleNotExistsError(storeErr, ErrClaimNotFound)
+		assert.Equal(t, storeErr, err)
+	})
+}
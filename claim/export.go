@@ -0,0 +1,241 @@
+package claim
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExportFormat is the file format of an installation export.
+type ExportFormat string
+
+const (
+	// ExportFormatTar writes the export as an uncompressed tar file, laid out
+	// like a claims archive.
+	ExportFormatTar ExportFormat = "tar"
+
+	// ExportFormatJSONLines writes the export as newline-delimited JSON, one
+	// ExportRecord per document.
+	ExportFormatJSONLines ExportFormat = "jsonl"
+
+	// exportManifestPath is the path of the ExportManifest in an export.
+	exportManifestPath = "manifest.json"
+)
+
+// ExportManifest describes an installation export. It is the first document
+// of the export, and it is never encrypted.
+type ExportManifest struct {
+	// Installation that was exported.
+	Installation string `json:"installation"`
+
+	// Exported is when the installation was exported.
+	Exported time.Time `json:"exported"`
+
+	// Encrypted is set when the documents of the export are encrypted.
+	Encrypted bool `json:"encrypted,omitempty"`
+}
+
+// ExportRecord is a single line of an export in the ExportFormatJSONLines
+// format.
+type ExportRecord struct {
+	// Path of the document, in the layout of a claims archive.
+	Path string `json:"path"`
+
+	// Data of the document, encoded with base64.
+	Data []byte `json:"data"`
+
+	// Modified is when the document was last modified.
+	Modified time.Time `json:"modified"`
+}
+
+// ExportOption configures ExportInstallation and ImportInstallation.
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+	format  ExportFormat
+	encrypt EncryptionHandler
+	decrypt EncryptionHandler
+}
+
+// WithExportFormat writes the export in the specified format. Defaults to
+// ExportFormatTar. ImportInstallation detects the format of the export, and
+// ignores this option.
+func WithExportFormat(format ExportFormat) ExportOption {
+	return func(cfg *exportConfig) {
+		cfg.format = format
+	}
+}
+
+// WithExportEncryption encrypts every document of the export with encrypt,
+// and decrypts them with decrypt when the export is imported. The handlers
+// are independent from the encryption of the stores, so that an installation
+// can be moved between stores that use different keys.
+func WithExportEncryption(encrypt EncryptionHandler, decrypt EncryptionHandler) ExportOption {
+	return func(cfg *exportConfig) {
+		cfg.encrypt = encrypt
+		cfg.decrypt = decrypt
+	}
+}
+
+func newExportConfig(opts []ExportOption) exportConfig {
+	cfg := exportConfig{format: ExportFormatTar}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// ExportInstallation writes the claims, results and outputs of an
+// installation to w, so that it can be restored with ImportInstallation, for
+// example into another backing store for disaster recovery. The values of
+// sensitive parameters and outputs are written decrypted, unless the export
+// is encrypted with WithExportEncryption.
+func (s Store) ExportInstallation(installation string, w io.Writer, opts ...ExportOption) error {
+	cfg := newExportConfig(opts)
+	var aw archiveWriter
+	switch cfg.format {
+	case ExportFormatTar:
+		aw = newArchiveWriter(w, ArchiveFormatTar)
+	case ExportFormatJSONLines:
+		aw = jsonLinesArchiveWriter{json.NewEncoder(w)}
+	default:
+		return fmt.Errorf("unsupported export format %q, the supported formats are %s and %s", cfg.format, ExportFormatTar, ExportFormatJSONLines)
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	// Fail before writing anything when the installation does not exist
+	if _, err := s.ListClaims(installation); err != nil {
+		return err
+	}
+
+	manifest := ExportManifest{
+		Installation: installation,
+		Exported:     time.Now().UTC(),
+		Encrypted:    cfg.encrypt != nil,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the export manifest")
+	}
+	if err := aw.WriteFile(exportManifestPath, data, manifest.Exported); err != nil {
+		return err
+	}
+
+	if cfg.encrypt != nil {
+		aw = encryptingArchiveWriter{archiveWriter: aw, encrypt: cfg.encrypt}
+	}
+	if err := s.writeInstallationArchive(aw, installation); err != nil {
+		return errors.Wrapf(err, "error exporting installation %s", installation)
+	}
+	return errors.Wrap(aw.Close(), "error writing the export")
+}
+
+// ImportInstallation reads an export written by ExportInstallation, in either
+// format, and saves the claims, results and outputs of its installation.
+// Every document is validated, as with ImportArchive, before any of them is
+// saved. An encrypted export requires the decrypt handler of
+// WithExportEncryption.
+func (s Store) ImportInstallation(r io.Reader, opts ...ExportOption) error {
+	cfg := newExportConfig(opts)
+
+	files, err := readExport(r)
+	if err != nil {
+		return errors.Wrap(err, "error reading the export")
+	}
+
+	data, ok := files[exportManifestPath]
+	if !ok {
+		return errors.Errorf("invalid export: %s not found", exportManifestPath)
+	}
+	delete(files, exportManifestPath)
+	var manifest ExportManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return errors.Wrapf(err, "invalid export: error unmarshaling %s", exportManifestPath)
+	}
+
+	if manifest.Encrypted {
+		if cfg.decrypt == nil {
+			return errors.Errorf("the export of installation %s is encrypted, a decryption handler is required", manifest.Installation)
+		}
+		for p, data := range files {
+			if files[p], err = cfg.decrypt(data); err != nil {
+				return errors.Wrapf(err, "error decrypting %s", p)
+			}
+		}
+	}
+
+	archive, err := parseClaimsArchive(files)
+	if err != nil {
+		return errors.Wrap(err, "invalid export")
+	}
+	for _, c := range archive.claims {
+		if c.Installation != manifest.Installation {
+			return errors.Errorf("invalid export: claim %s belongs to installation %s instead of the exported installation %s", c.ID, c.Installation, manifest.Installation)
+		}
+	}
+
+	return s.saveClaimsArchive(archive)
+}
+
+// readExport returns the documents of an export, keyed by their path,
+// detecting whether it is a tar file or newline-delimited JSON.
+func readExport(r io.Reader) (map[string][]byte, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] != '{' {
+		return readArchive(br, ArchiveFormatTar)
+	}
+
+	files := map[string][]byte{}
+	decoder := json.NewDecoder(br)
+	for {
+		var record ExportRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		}
+		files[path.Clean(record.Path)] = record.Data
+	}
+}
+
+// jsonLinesArchiveWriter writes each file as an ExportRecord.
+type jsonLinesArchiveWriter struct {
+	encoder *json.Encoder
+}
+
+func (w jsonLinesArchiveWriter) WriteFile(name string, data []byte, modified time.Time) error {
+	err := w.encoder.Encode(ExportRecord{Path: name, Data: data, Modified: modified})
+	return errors.Wrapf(err, "error writing %s", name)
+}
+
+func (w jsonLinesArchiveWriter) Close() error {
+	return nil
+}
+
+// encryptingArchiveWriter encrypts each file before writing it.
+type encryptingArchiveWriter struct {
+	archiveWriter
+	encrypt EncryptionHandler
+}
+
+func (w encryptingArchiveWriter) WriteFile(name string, data []byte, modified time.Time) error {
+	encrypted, err := w.encrypt(data)
+	if err != nil {
+		return errors.Wrapf(err, "error encrypting %s", name)
+	}
+	return w.archiveWriter.WriteFile(name, encrypted, modified)
+}
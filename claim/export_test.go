@@ -0,0 +1,132 @@
+package claim
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_ExportInstallation(t *testing.T) {
+	sourceEncrypt := func(data []byte) ([]byte, error) {
+		return append([]byte("source:"), data...), nil
+	}
+	sourceDecrypt := func(data []byte) ([]byte, error) {
+		return bytes.TrimPrefix(data, []byte("source:")), nil
+	}
+	exportEncrypt := func(data []byte) ([]byte, error) {
+		return []byte("export:" + base64.StdEncoding.EncodeToString(data)), nil
+	}
+	exportDecrypt := func(data []byte) ([]byte, error) {
+		return base64.StdEncoding.DecodeString(string(bytes.TrimPrefix(data, []byte("export:"))))
+	}
+
+	testcases := []struct {
+		name string
+		opts []ExportOption
+	}{
+		{name: "tar"},
+		{name: "json lines", opts: []ExportOption{WithExportFormat(ExportFormatJSONLines)}},
+		{name: "encrypted tar", opts: []ExportOption{WithExportEncryption(exportEncrypt, exportDecrypt)}},
+		{name: "encrypted json lines", opts: []ExportOption{WithExportFormat(ExportFormatJSONLines), WithExportEncryption(exportEncrypt, exportDecrypt)}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			source := NewClaimStore(crud.NewMockStore(), sourceEncrypt, sourceDecrypt)
+			c, r := generateArchiveData(t, source, "mysql")
+			generateArchiveData(t, source, "wordpress")
+
+			var buf bytes.Buffer
+			require.NoError(t, source.ExportInstallation("mysql", &buf, tc.opts...))
+			assert.NotContains(t, buf.String(), "source:", "the export should not be encrypted by the source store")
+
+			target := NewClaimStore(crud.NewMockStore(), nil, nil)
+			require.NoError(t, target.ImportInstallation(&buf, tc.opts...))
+
+			installations, err := target.ListInstallations()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"mysql"}, installations)
+
+			gotClaim, err := target.ReadClaim(c.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "mysql", gotClaim.Installation)
+			gotResult, err := target.ReadResult(r.ID)
+			require.NoError(t, err)
+			o, err := target.ReadOutput(gotClaim, gotResult, "password")
+			require.NoError(t, err)
+			assert.Equal(t, "topsecret", string(o.Value))
+		})
+	}
+
+	t.Run("encrypted export", func(t *testing.T) {
+		source := NewClaimStore(crud.NewMockStore(), nil, nil)
+		generateArchiveData(t, source, "mysql")
+
+		var buf bytes.Buffer
+		require.NoError(t, source.ExportInstallation("mysql", &buf, WithExportFormat(ExportFormatJSONLines), WithExportEncryption(exportEncrypt, exportDecrypt)))
+
+		decoder := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+		var manifest ExportRecord
+		require.NoError(t, decoder.Decode(&manifest))
+		assert.Equal(t, "manifest.json", manifest.Path)
+		for decoder.More() {
+			var record ExportRecord
+			require.NoError(t, decoder.Decode(&record))
+			assert.True(t, bytes.HasPrefix(record.Data, []byte("export:")), "%s should be encrypted", record.Path)
+		}
+
+		target := NewClaimStore(crud.NewMockStore(), nil, nil)
+		err := target.ImportInstallation(bytes.NewReader(buf.Bytes()))
+		assert.EqualError(t, err, "the export of installation mysql is encrypted, a decryption handler is required")
+	})
+
+	t.Run("installation not found", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		var buf bytes.Buffer
+		err := store.ExportInstallation("missing", &buf)
+		assert.ErrorIs(t, err, ErrInstallationNotFound)
+		assert.Zero(t, buf.Len(), "nothing should be written")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		var buf bytes.Buffer
+		err := store.ExportInstallation("mysql", &buf, WithExportFormat("zip"))
+		assert.EqualError(t, err, `unsupported export format "zip", the supported formats are tar and jsonl`)
+	})
+}
+
+func TestStore_ImportInstallation_Invalid(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	generateArchiveData(t, store, "mysql")
+	var buf bytes.Buffer
+	require.NoError(t, store.ExportInstallation("mysql", &buf, WithExportFormat(ExportFormatJSONLines)))
+
+	t.Run("missing manifest", func(t *testing.T) {
+		lines := bytes.SplitN(buf.Bytes(), []byte("\n"), 2)
+		err := NewClaimStore(crud.NewMockStore(), nil, nil).ImportInstallation(bytes.NewReader(lines[1]))
+		assert.EqualError(t, err, "invalid export: manifest.json not found")
+	})
+
+	t.Run("claim of another installation", func(t *testing.T) {
+		var manifest ExportRecord
+		lines := bytes.SplitN(buf.Bytes(), []byte("\n"), 2)
+		require.NoError(t, json.Unmarshal(lines[0], &manifest))
+		manifest.Data = bytes.Replace(manifest.Data, []byte(`"mysql"`), []byte(`"wordpress"`), 1)
+		line, err := json.Marshal(manifest)
+		require.NoError(t, err)
+
+		target := NewClaimStore(crud.NewMockStore(), nil, nil)
+		err = target.ImportInstallation(bytes.NewReader(append(append(line, '\n'), lines[1]...)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "belongs to installation mysql instead of the exported installation wordpress")
+		installations, _ := target.ListInstallations()
+		assert.Empty(t, installations)
+	})
+}
@@ -0,0 +1,92 @@
+package claim
+
+import (
+	"time"
+)
+
+// OutputHistoryEntry is the value of an output generated by a result, as part
+// of the history of the output across the operations of an installation.
+type OutputHistoryEntry struct {
+	// ClaimID of the operation that generated the output.
+	ClaimID string
+
+	// ResultID of the result that generated the output.
+	ResultID string
+
+	// Action of the operation, for example upgrade.
+	Action string
+
+	// BundleVersion is the version of the bundle used by the operation.
+	BundleVersion string
+
+	// Created is when the result that generated the output was recorded.
+	Created time.Time
+
+	// Sensitive indicates that the output is sensitive, in which case the
+	// Value is not included and only the Digest can be compared.
+	Sensitive bool
+
+	// Value of the output. Empty when the output is sensitive.
+	Value []byte
+
+	// Digest of the value of the output, so that changes to the value can be
+	// detected even when it is sensitive.
+	Digest string
+}
+
+// ReadOutputHistory returns the value of the named output generated by each
+// result of the installation, oldest first, for example to see how a
+// connection string or certificate changed across upgrades. The values of
+// sensitive outputs are not included, only their digests. An empty history is
+// returned when the output was never generated by the installation.
+func (s Store) ReadOutputHistory(installation string, outputName string) ([]OutputHistoryEntry, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.ReadAllClaims(installation)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []OutputHistoryEntry{}
+	for _, c := range claims {
+		results, err := s.ReadAllResults(c.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range results {
+			outputNames, err := s.ListOutputs(r.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !containsString(outputNames, outputName) {
+				continue
+			}
+
+			o, err := s.ReadOutput(c, r, outputName)
+			if err != nil {
+				return nil, err
+			}
+
+			entry := OutputHistoryEntry{
+				ClaimID:       c.ID,
+				ResultID:      r.ID,
+				Action:        c.Action,
+				BundleVersion: c.Bundle.Version,
+				Created:       r.Created,
+				Sensitive:     s.isOutputSensitive(c, outputName),
+				Digest:        outputDigest(o),
+			}
+			if !entry.Sensitive {
+				entry.Value = o.Value
+			}
+			history = append(history, entry)
+		}
+	}
+
+	return history, nil
+}
@@ -0,0 +1,69 @@
+package claim
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_ReadOutputHistory(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	install, installResult := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	require.NoError(t, store.SaveOutput(NewOutput(install, installResult, "password", []byte("first"))))
+	upgrade, upgradeResult := generateClaimData(t, store, "mysql", ActionUpgrade, StatusSucceeded)
+	require.NoError(t, store.SaveOutput(NewOutput(upgrade, upgradeResult, "password", []byte("second"))))
+	generateClaimData(t, store, "wordpress", ActionInstall, StatusSucceeded)
+
+	// An operation that did not generate the output is not in its history
+	uninstall, err := upgrade.NewClaim(ActionUninstall, claimStoreBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(uninstall))
+	uninstallResult, err := uninstall.NewResult(StatusFailed)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(uninstallResult))
+
+	t.Run("output", func(t *testing.T) {
+		history, err := store.ReadOutputHistory("mysql", "host")
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+
+		assert.Equal(t, install.ID, history[0].ClaimID)
+		assert.Equal(t, installResult.ID, history[0].ResultID)
+		assert.Equal(t, ActionInstall, history[0].Action)
+		assert.True(t, installResult.Created.Equal(history[0].Created), "expected the time the result was created")
+		assert.False(t, history[0].Sensitive)
+		assert.Equal(t, ActionInstall, string(history[0].Value))
+		assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(ActionInstall))), history[0].Digest)
+
+		assert.Equal(t, upgradeResult.ID, history[1].ResultID)
+		assert.Equal(t, ActionUpgrade, string(history[1].Value))
+	})
+
+	t.Run("sensitive output", func(t *testing.T) {
+		history, err := store.ReadOutputHistory("mysql", "password")
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+
+		for i, value := range []string{"first", "second"} {
+			assert.True(t, history[i].Sensitive)
+			assert.Empty(t, history[i].Value, "the value of a sensitive output should not be included")
+			assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(value))), history[i].Digest)
+		}
+	})
+
+	t.Run("missing output", func(t *testing.T) {
+		history, err := store.ReadOutputHistory("mysql", "port")
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("missing installation", func(t *testing.T) {
+		_, err := store.ReadOutputHistory("missing", "host")
+		require.EqualError(t, err, ErrInstallationNotFound.Error())
+	})
+}
@@ -0,0 +1,245 @@
+package claim
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// Secondary indexes of claims maintained in a backing store that implements
+// crud.Indexer.
+const (
+	// IndexInstallation is the name of the installation of the claim.
+	IndexInstallation = "installation"
+
+	// IndexAction is the action of the claim.
+	IndexAction = "action"
+
+	// IndexBundleName is the name of the bundle of the claim.
+	IndexBundleName = "bundleName"
+
+	// IndexBundleVersion is the version of the bundle of the claim.
+	IndexBundleVersion = "bundleVersion"
+
+	// IndexStatus is the status of the last result of the claim, or
+	// StatusUnknown when the claim has no results.
+	IndexStatus = "status"
+)
+
+// ClaimIndexFunc returns the value of a custom index for a claim.
+type ClaimIndexFunc func(c Claim) string
+
+// AddClaimIndex registers a custom index of claims, such as a label, that is
+// populated using the specified function when claims are saved and the
+// backing store implements crud.Indexer. Claims saved before the index was
+// added are indexed by Reindex.
+func (s *Store) AddClaimIndex(index string, value ClaimIndexFunc) {
+	if s.customIndexes == nil {
+		s.customIndexes = map[string]ClaimIndexFunc{}
+	}
+	s.customIndexes[index] = value
+}
+
+// ListClaimsByIndex returns the sorted IDs of the claims whose index has the
+// value. When the backing store implements crud.Indexer, its indexes are
+// used. Otherwise every claim is read to find the matching claims.
+func (s Store) ListClaimsByIndex(index string, value string) ([]string, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	if indexer, ok := s.indexer(); ok {
+		claimIDs, err := indexer.ListByIndex(ItemTypeClaims, index, value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing claims by index %s", index)
+		}
+		sort.Strings(claimIDs)
+		return claimIDs, nil
+	}
+
+	installations, err := s.ListInstallations()
+	if err != nil {
+		return nil, err
+	}
+
+	var claimIDs []string
+	for _, installation := range installations {
+		claims, err := s.ReadAllClaims(installation)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range claims {
+			indexes, err := s.claimIndexes(c, index == IndexStatus)
+			if err != nil {
+				return nil, err
+			}
+			if v, ok := indexes[index]; ok && v == value {
+				claimIDs = append(claimIDs, c.ID)
+			}
+		}
+	}
+
+	sort.Strings(claimIDs)
+	return claimIDs, nil
+}
+
+// Reindex populates the indexes of every claim, for example after custom
+// indexes were added or when the backing store starts to implement
+// crud.Indexer. It does nothing when the backing store does not implement
+// crud.Indexer.
+func (s Store) Reindex() error {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	indexer, ok := s.indexer()
+	if !ok {
+		return nil
+	}
+
+	installations, err := s.ListInstallations()
+	if err != nil {
+		return err
+	}
+
+	for _, installation := range installations {
+		claims, err := s.ReadAllClaims(installation)
+		if err != nil {
+			return err
+		}
+		for _, c := range claims {
+			if err := s.indexClaim(indexer, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// indexer returns the Indexer of the backing store when it maintains
+// secondary indexes, including through decorators such as crud.TracingStore.
+func (s Store) indexer() (crud.Indexer, bool) {
+	return crud.GetIndexer(s.backingStore.GetStore())
+}
+
+// indexClaim sets every index of the claim, including its status.
+func (s Store) indexClaim(indexer crud.Indexer, c Claim) error {
+	indexes, err := s.claimIndexes(c, true)
+	if err != nil {
+		return err
+	}
+
+	err = indexer.SetIndexes(ItemTypeClaims, c.ID, indexes)
+	return errors.Wrapf(err, "error indexing claim %s", c.ID)
+}
+
+// indexClaimStatus updates the status index of a claim after one of its
+// results was saved.
+func (s Store) indexClaimStatus(indexer crud.Indexer, claimID string) error {
+	status, err := s.readClaimStatus(claimID)
+	if err != nil {
+		return err
+	}
+
+	err = indexer.SetIndexes(ItemTypeClaims, claimID, map[string]string{IndexStatus: status})
+	return errors.Wrapf(err, "error indexing claim %s", claimID)
+}
+
+// claimIndexes returns the values of the indexes of a claim. The status is
+// only included when requested because it requires reading the last result
+// of the claim.
+func (s Store) claimIndexes(c Claim, includeStatus bool) (map[string]string, error) {
+	indexes := map[string]string{
+		IndexInstallation:  c.Installation,
+		IndexAction:        c.Action,
+		IndexBundleName:    c.Bundle.Name,
+		IndexBundleVersion: c.Bundle.Version,
+	}
+	for index, value := range s.customIndexes {
+		indexes[index] = value(c)
+	}
+
+	if includeStatus {
+		status, err := s.readClaimStatus(c.ID)
+		if err != nil {
+			return nil, err
+		}
+		indexes[IndexStatus] = status
+	}
+	return indexes, nil
+}
+
+// readClaimStatus returns the status of the last result of a claim.
+func (s Store) readClaimStatus(claimID string) (string, error) {
+	lastResult, err := s.ReadLastResult(claimID)
+	if err != nil {
+		if errors.Is(err, ErrResultNotFound) {
+			return StatusUnknown, nil
+		}
+		return "", err
+	}
+	return lastResult.Status, nil
+}
+
+// listIndexedClaims uses the indexes of the backing store to select the
+// claims matching the action and status filters of a query, so that the
+// other claims are not read. It returns false when the backing store does
+// not implement crud.Indexer or the query does not filter by action or
+// status.
+func (s Store) listIndexedClaims(query ClaimQuery) ([]string, bool, error) {
+	indexer, ok := s.indexer()
+	if !ok || (len(query.Actions) == 0 && len(query.Statuses) == 0) {
+		return nil, false, nil
+	}
+
+	var selected map[string]bool
+	filter := func(index string, values []string) error {
+		if len(values) == 0 {
+			return nil
+		}
+
+		matches := map[string]bool{}
+		for _, value := range values {
+			claimIDs, err := indexer.ListByIndex(ItemTypeClaims, index, value)
+			if err != nil {
+				return errors.Wrapf(err, "error listing claims by index %s", index)
+			}
+			for _, claimID := range claimIDs {
+				if selected == nil || selected[claimID] {
+					matches[claimID] = true
+				}
+			}
+		}
+		selected = matches
+		return nil
+	}
+
+	if query.Installation != "" {
+		claimIDs, err := s.ListClaims(query.Installation)
+		if err != nil {
+			return nil, false, err
+		}
+		selected = make(map[string]bool, len(claimIDs))
+		for _, claimID := range claimIDs {
+			selected[claimID] = true
+		}
+	}
+	if err := filter(IndexAction, query.Actions); err != nil {
+		return nil, false, err
+	}
+	if err := filter(IndexStatus, query.Statuses); err != nil {
+		return nil, false, err
+	}
+
+	claimIDs := make([]string, 0, len(selected))
+	for claimID := range selected {
+		claimIDs = append(claimIDs, claimID)
+	}
+	return claimIDs, true, nil
+}
@@ -0,0 +1,158 @@
+package claim
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// indexingStore maintains secondary indexes in memory.
+type indexingStore struct {
+	*crud.MockStore
+
+	// indexes maps itemType -> name -> index -> value.
+	indexes map[string]map[string]map[string]string
+
+	// lookups is the number of times ListByIndex was called.
+	lookups int
+}
+
+func newIndexingStore() *indexingStore {
+	return &indexingStore{
+		MockStore: crud.NewMockStore(),
+		indexes:   map[string]map[string]map[string]string{},
+	}
+}
+
+func (s *indexingStore) SetIndexes(itemType string, name string, indexes map[string]string) error {
+	items, ok := s.indexes[itemType]
+	if !ok {
+		items = map[string]map[string]string{}
+		s.indexes[itemType] = items
+	}
+	values, ok := items[name]
+	if !ok {
+		values = map[string]string{}
+		items[name] = values
+	}
+	for index, value := range indexes {
+		values[index] = value
+	}
+	return nil
+}
+
+func (s *indexingStore) RemoveIndexes(itemType string, name string) error {
+	delete(s.indexes[itemType], name)
+	return nil
+}
+
+func (s *indexingStore) ListByIndex(itemType string, index string, value string) ([]string, error) {
+	s.lookups++
+	var names []string
+	for name, values := range s.indexes[itemType] {
+		if values[index] == value {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func TestStore_ListClaimsByIndex(t *testing.T) {
+	backingStore := newIndexingStore()
+	store := NewClaimStore(backingStore, nil, nil)
+	store.AddClaimIndex("env", func(c Claim) string {
+		return c.Installation + "-env"
+	})
+
+	install, _ := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	upgrade, _ := generateClaimData(t, store, "mysql", ActionUpgrade, StatusFailed)
+	wordpress, _ := generateClaimData(t, store, "wordpress", ActionInstall, StatusSucceeded)
+
+	testcases := []struct {
+		index string
+		value string
+		want  []string
+	}{
+		{IndexInstallation, "mysql", []string{install.ID, upgrade.ID}},
+		{IndexAction, ActionInstall, []string{install.ID, wordpress.ID}},
+		{IndexBundleName, install.Bundle.Name, []string{install.ID, upgrade.ID, wordpress.ID}},
+		{IndexStatus, StatusFailed, []string{upgrade.ID}},
+		{"env", "wordpress-env", []string{wordpress.ID}},
+		{IndexAction, ActionUninstall, nil},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.index+"="+tc.value, func(t *testing.T) {
+			claimIDs, err := store.ListClaimsByIndex(tc.index, tc.value)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, claimIDs)
+
+			// The same claims are found without indexes by reading every claim
+			unindexed := NewClaimStore(backingStore.MockStore, nil, nil)
+			unindexed.AddClaimIndex("env", func(c Claim) string {
+				return c.Installation + "-env"
+			})
+			claimIDs, err = unindexed.ListClaimsByIndex(tc.index, tc.value)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, claimIDs)
+		})
+	}
+
+	t.Run("status follows the last result", func(t *testing.T) {
+		r, err := upgrade.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+		require.NoError(t, store.SaveResult(r))
+
+		claimIDs, err := store.ListClaimsByIndex(IndexStatus, StatusFailed)
+		require.NoError(t, err)
+		assert.Empty(t, claimIDs)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, store.DeleteClaim(wordpress.ID))
+
+		claimIDs, err := store.ListClaimsByIndex(IndexInstallation, "wordpress")
+		require.NoError(t, err)
+		assert.Empty(t, claimIDs)
+	})
+}
+
+func TestStore_QueryClaims_Indexer(t *testing.T) {
+	backingStore := newIndexingStore()
+	store := NewClaimStore(backingStore, nil, nil)
+	install, _ := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	upgrade, _ := generateClaimData(t, store, "mysql", ActionUpgrade, StatusFailed)
+	generateClaimData(t, store, "wordpress", ActionUpgrade, StatusSucceeded)
+
+	page, err := store.QueryClaims(ClaimQuery{Installation: "mysql", Actions: []string{ActionUpgrade}, Statuses: []string{StatusFailed}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{upgrade.ID}, claimIDs(page.Claims))
+	assert.Equal(t, 2, backingStore.lookups, "the action and status indexes should be used")
+
+	page, err = store.QueryClaims(ClaimQuery{Actions: []string{ActionInstall}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{install.ID}, claimIDs(page.Claims))
+
+	_, err = store.QueryClaims(ClaimQuery{Installation: "missing", Actions: []string{ActionInstall}})
+	assert.ErrorIs(t, err, ErrInstallationNotFound)
+}
+
+func TestStore_Reindex(t *testing.T) {
+	backingStore := newIndexingStore()
+	unindexed := NewClaimStore(backingStore.MockStore, nil, nil)
+	install, _ := generateClaimData(t, unindexed, "mysql", ActionInstall, StatusSucceeded)
+
+	store := NewClaimStore(backingStore, nil, nil)
+	claimIDs, err := store.ListClaimsByIndex(IndexStatus, StatusSucceeded)
+	require.NoError(t, err)
+	assert.Empty(t, claimIDs, "claims saved without an indexer are not indexed")
+
+	require.NoError(t, store.Reindex())
+	claimIDs, err = store.ListClaimsByIndex(IndexStatus, StatusSucceeded)
+	require.NoError(t, err)
+	assert.Equal(t, []string{install.ID}, claimIDs)
+}
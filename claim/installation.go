@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 )
 
@@ -11,6 +12,22 @@ import (
 type Installation struct {
 	Name string
 	Claims
+
+	// provider loads the data of the installation that was not already
+	// loaded, when the installation is bound to a Provider.
+	provider Provider
+
+	// cache holds the data loaded from the provider.
+	cache *installationCache
+}
+
+// installationCache holds the data of an installation loaded on demand, and
+// is shared by the copies of the installation.
+type installationCache struct {
+	mu          sync.Mutex
+	lastClaim   *Claim
+	lastResult  *Result
+	lastOutputs *Outputs
 }
 
 // NewInstallation creates an Installation and ensures the contained data is sorted.
@@ -30,6 +47,22 @@ func NewInstallation(name string, claims []Claim) Installation {
 	return i
 }
 
+// NewLazyInstallation creates an Installation bound to the provider, without
+// loading its claims. The last claim, result and outputs of the installation
+// are loaded from the provider when they are first requested, and cached.
+func NewLazyInstallation(name string, provider Provider) Installation {
+	return Installation{Name: name}.WithProvider(provider)
+}
+
+// WithProvider binds the installation to the provider, so that the data that
+// was not loaded with the installation, such as the results of its last
+// claim or its outputs, is loaded from the provider when it is requested.
+func (i Installation) WithProvider(provider Provider) Installation {
+	i.provider = provider
+	i.cache = &installationCache{}
+	return i
+}
+
 // GetInstallationTimestamp searches the claims associated with the installation
 // for the first claim for Install and returns its timestamp.
 func (i Installation) GetInstallationTimestamp() (time.Time, error) {
@@ -49,6 +82,10 @@ func (i Installation) GetInstallationTimestamp() (time.Time, error) {
 // GetLastClaim returns the most recent (last) claim associated with the
 // installation.
 func (i Installation) GetLastClaim() (Claim, error) {
+	if len(i.Claims) == 0 && i.provider != nil {
+		return i.loadLastClaim()
+	}
+
 	if len(i.Claims) == 0 {
 		return Claim{}, fmt.Errorf("the installation %s has no claims", i.Name)
 	}
@@ -65,6 +102,10 @@ func (i Installation) GetLastResult() (Result, error) {
 		return Result{}, err
 	}
 
+	if lastClaim.results == nil && i.provider != nil {
+		return i.loadLastResult(lastClaim)
+	}
+
 	if lastClaim.results == nil {
 		return Result{}, errors.New("the last claim does not have any results loaded")
 	}
@@ -89,6 +130,57 @@ func (i Installation) GetLastStatus() string {
 	return lastResult.Status
 }
 
+// GetLastOutputs returns the most recent (last) value of each output
+// associated with the installation, which are loaded from the provider that
+// the installation is bound to.
+func (i Installation) GetLastOutputs() (Outputs, error) {
+	if i.provider == nil {
+		return Outputs{}, fmt.Errorf("the outputs of the installation %s are not loaded, bind it to a provider with WithProvider", i.Name)
+	}
+
+	i.cache.mu.Lock()
+	defer i.cache.mu.Unlock()
+
+	if i.cache.lastOutputs == nil {
+		outputs, err := i.provider.ReadLastOutputs(i.Name)
+		if err != nil {
+			return Outputs{}, err
+		}
+		i.cache.lastOutputs = &outputs
+	}
+	return *i.cache.lastOutputs, nil
+}
+
+// loadLastClaim reads the last claim of the installation from the provider.
+func (i Installation) loadLastClaim() (Claim, error) {
+	i.cache.mu.Lock()
+	defer i.cache.mu.Unlock()
+
+	if i.cache.lastClaim == nil {
+		c, err := i.provider.ReadLastClaim(i.Name)
+		if err != nil {
+			return Claim{}, err
+		}
+		i.cache.lastClaim = &c
+	}
+	return *i.cache.lastClaim, nil
+}
+
+// loadLastResult reads the last result of the claim from the provider.
+func (i Installation) loadLastResult(c Claim) (Result, error) {
+	i.cache.mu.Lock()
+	defer i.cache.mu.Unlock()
+
+	if i.cache.lastResult == nil || i.cache.lastResult.ClaimID != c.ID {
+		r, err := i.provider.ReadLastResult(c.ID)
+		if err != nil {
+			return Result{}, err
+		}
+		i.cache.lastResult = &r
+	}
+	return *i.cache.lastResult, nil
+}
+
 type InstallationByName []Installation
 
 func (ibn InstallationByName) Len() int {
@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
 )
 
 func TestInstallation_GetInstallationTimestamp(t *testing.T) {
@@ -175,3 +177,85 @@ func TestInstallationByModified_Sort(t *testing.T) {
 	assert.Equal(t, "b", installations[1].Name)
 	assert.Equal(t, "c", installations[2].Name)
 }
+
+// countingProvider counts the reads of the data loaded on demand by an
+// Installation.
+type countingProvider struct {
+	Store
+	lastClaimReads   int
+	lastResultReads  int
+	lastOutputsReads int
+}
+
+func (p *countingProvider) ReadLastClaim(installation string) (Claim, error) {
+	p.lastClaimReads++
+	return p.Store.ReadLastClaim(installation)
+}
+
+func (p *countingProvider) ReadLastResult(claimID string) (Result, error) {
+	p.lastResultReads++
+	return p.Store.ReadLastResult(claimID)
+}
+
+func (p *countingProvider) ReadLastOutputs(installation string) (Outputs, error) {
+	p.lastOutputsReads++
+	return p.Store.ReadLastOutputs(installation)
+}
+
+func TestNewLazyInstallation(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	upgrade, upgradeResult := generateClaimData(t, store, "mysql", ActionUpgrade, StatusFailed)
+
+	p := &countingProvider{Store: store}
+	i := NewLazyInstallation("mysql", p)
+	assert.Empty(t, i.Claims, "the claims should not be loaded")
+
+	for n := 0; n < 2; n++ {
+		c, err := i.GetLastClaim()
+		require.NoError(t, err)
+		assert.Equal(t, upgrade.ID, c.ID)
+
+		r, err := i.GetLastResult()
+		require.NoError(t, err)
+		assert.Equal(t, upgradeResult.ID, r.ID)
+		assert.Equal(t, StatusFailed, i.GetLastStatus())
+
+		outputs, err := i.GetLastOutputs()
+		require.NoError(t, err)
+		o, ok := outputs.GetByName("host")
+		require.True(t, ok, "expected the host output")
+		assert.Equal(t, ActionUpgrade, string(o.Value))
+	}
+
+	assert.Equal(t, 1, p.lastClaimReads, "the last claim should be cached")
+	assert.Equal(t, 1, p.lastResultReads, "the last result should be cached")
+	assert.Equal(t, 1, p.lastOutputsReads, "the outputs should be cached")
+
+	t.Run("not found", func(t *testing.T) {
+		i := NewLazyInstallation("wordpress", store)
+		_, err := i.GetLastClaim()
+		assert.ErrorIs(t, err, ErrInstallationNotFound)
+		assert.Equal(t, StatusUnknown, i.GetLastStatus())
+	})
+
+	t.Run("not bound", func(t *testing.T) {
+		i := NewInstallation("mysql", Claims{upgrade})
+		_, err := i.GetLastOutputs()
+		require.EqualError(t, err, "the outputs of the installation mysql are not loaded, bind it to a provider with WithProvider")
+		_, err = i.GetLastResult()
+		require.EqualError(t, err, "the last claim does not have any results loaded")
+	})
+}
+
+func TestStore_ReadInstallationStatus_LoadsOnDemand(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+
+	i, err := store.ReadInstallationStatus("mysql")
+	require.NoError(t, err)
+
+	outputs, err := i.GetLastOutputs()
+	require.NoError(t, err)
+	assert.Equal(t, 1, outputs.Len())
+}
@@ -0,0 +1,128 @@
+package claim
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// encryptedRecordPrefix identifies the key used to encrypt a sensitive
+// output, and is followed by the key ID and a colon. Records encrypted
+// without a key ID, e.g. by a store created with NewClaimStore, have no
+// prefix.
+const encryptedRecordPrefix = "cnab-key:"
+
+// EncryptionKey is a pair of handlers that encrypt and decrypt data using the
+// same key.
+type EncryptionKey struct {
+	Encrypt EncryptionHandler
+	Decrypt EncryptionHandler
+}
+
+// Keyring holds the keys used to encrypt sensitive claim data, by key ID.
+// Each record is encrypted with one key and stored with its key ID so that it
+// can be decrypted after other keys are added, for example when keys are
+// rotated or when each tenant of a store has their own key.
+type Keyring struct {
+	// CurrentKeyID is the ID of the key used to encrypt new records.
+	CurrentKeyID string
+
+	// KeyForInstallation optionally selects the ID of the key used to encrypt
+	// new records for an installation, e.g. to use a separate key for each
+	// tenant. When nil, or when it returns an empty key ID, CurrentKeyID is
+	// used.
+	KeyForInstallation func(installation string) string
+
+	// Keys by key ID. Records encrypted before the key ID was stored with
+	// them are decrypted with the key whose ID is empty, when present, or the
+	// current key.
+	Keys map[string]EncryptionKey
+}
+
+// Validate that the keyring has the current key and that the key IDs can be
+// stored with the encrypted records.
+func (k Keyring) Validate() error {
+	for keyID := range k.Keys {
+		if strings.ContainsAny(keyID, ":\n") {
+			return errors.Errorf("invalid key ID %q, it must not contain a colon or newline", keyID)
+		}
+	}
+	if _, ok := k.Keys[k.CurrentKeyID]; !ok {
+		return errors.Errorf("the current key %q is not in the keyring", k.CurrentKeyID)
+	}
+	return nil
+}
+
+// keyIDFor returns the ID of the key used to encrypt new records for the
+// installation.
+func (k Keyring) keyIDFor(installation string) string {
+	if k.KeyForInstallation != nil {
+		if keyID := k.KeyForInstallation(installation); keyID != "" {
+			return keyID
+		}
+	}
+	return k.CurrentKeyID
+}
+
+// encrypt the data with the key for the installation, returning the ID of
+// the key used.
+func (k Keyring) encrypt(installation string, data []byte) (string, []byte, error) {
+	keyID := k.keyIDFor(installation)
+	key, ok := k.Keys[keyID]
+	if !ok {
+		return "", nil, errors.Errorf("no encryption key with ID %q in the keyring", keyID)
+	}
+	if key.Encrypt == nil {
+		return keyID, data, nil
+	}
+
+	data, err := key.Encrypt(data)
+	return keyID, data, err
+}
+
+// decrypt the data with the specified key.
+func (k Keyring) decrypt(keyID string, data []byte) ([]byte, error) {
+	key, ok := k.Keys[keyID]
+	if !ok && keyID == "" {
+		key, ok = k.Keys[k.CurrentKeyID]
+	}
+	if !ok {
+		return nil, errors.Errorf("no encryption key with ID %q in the keyring", keyID)
+	}
+	if key.Decrypt == nil {
+		return data, nil
+	}
+
+	return key.Decrypt(data)
+}
+
+// encryptRecord encrypts the data with the key for the installation and
+// prefixes it with the key ID.
+func (k Keyring) encryptRecord(installation string, data []byte) ([]byte, error) {
+	keyID, data, err := k.encrypt(installation, data)
+	if err != nil || keyID == "" {
+		return data, err
+	}
+
+	record := make([]byte, 0, len(encryptedRecordPrefix)+len(keyID)+1+len(data))
+	record = append(record, encryptedRecordPrefix...)
+	record = append(record, keyID...)
+	record = append(record, ':')
+	return append(record, data...), nil
+}
+
+// decryptRecord decrypts a record created by encryptRecord with the key that
+// it was encrypted with.
+func (k Keyring) decryptRecord(record []byte) ([]byte, error) {
+	keyID := ""
+	if bytes.HasPrefix(record, []byte(encryptedRecordPrefix)) {
+		rest := record[len(encryptedRecordPrefix):]
+		if i := bytes.IndexByte(rest, ':'); i >= 0 {
+			keyID = string(rest[:i])
+			record = rest[i+1:]
+		}
+	}
+
+	return k.decrypt(keyID, record)
+}
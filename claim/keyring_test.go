@@ -0,0 +1,154 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func newTestKey(name string) EncryptionKey {
+	prefix := []byte(name + ":")
+	return EncryptionKey{
+		Encrypt: func(data []byte) ([]byte, error) {
+			return append(append([]byte{}, prefix...), data...), nil
+		},
+		Decrypt: func(data []byte) ([]byte, error) {
+			if len(data) < len(prefix) || string(data[:len(prefix)]) != string(prefix) {
+				return nil, assert.AnError
+			}
+			return data[len(prefix):], nil
+		},
+	}
+}
+
+func TestKeyring_Validate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		keyring Keyring
+		wantErr string
+	}{
+		{name: "valid", keyring: Keyring{CurrentKeyID: "v2", Keys: map[string]EncryptionKey{"v1": newTestKey("v1"), "v2": newTestKey("v2")}}},
+		{name: "missing current key", keyring: Keyring{CurrentKeyID: "v3", Keys: map[string]EncryptionKey{"v1": newTestKey("v1")}}, wantErr: `the current key "v3" is not in the keyring`},
+		{name: "invalid key ID", keyring: Keyring{CurrentKeyID: "v1", Keys: map[string]EncryptionKey{"v1": newTestKey("v1"), "a:b": newTestKey("a")}}, wantErr: `invalid key ID "a:b"`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.keyring.Validate()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore_Keyring_Rotation(t *testing.T) {
+	b := claimStoreBundle
+	b.Parameters = map[string]bundle.Parameter{
+		"password": {Definition: "password"},
+	}
+	params := map[string]interface{}{"password": "topsecret"}
+
+	backingStore := crud.NewMockStore()
+
+	// Save records with the store's original key, before it used a keyring
+	legacy := newTestKey("legacy")
+	legacyStore := NewClaimStore(backingStore, legacy.Encrypt, legacy.Decrypt)
+	oldClaim, err := New("mysql", ActionInstall, b, params)
+	require.NoError(t, err)
+	require.NoError(t, legacyStore.SaveClaim(oldClaim))
+	oldResult, err := oldClaim.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, legacyStore.SaveResult(oldResult))
+	require.NoError(t, legacyStore.SaveOutput(NewOutput(oldClaim, oldResult, "password", []byte("oldsecret"))))
+
+	// Rotate to a new key, keeping the original key to read existing records
+	store, err := NewClaimStoreWithKeyring(backingStore, Keyring{
+		CurrentKeyID: "v2",
+		Keys: map[string]EncryptionKey{
+			"":   legacy,
+			"v2": newTestKey("v2"),
+		},
+	})
+	require.NoError(t, err)
+
+	newClaim, err := New("mysql", ActionUpgrade, b, params)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(newClaim))
+	newResult, err := newClaim.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(newResult))
+	require.NoError(t, store.SaveOutput(NewOutput(newClaim, newResult, "password", []byte("newsecret"))))
+
+	raw, err := backingStore.Read(ItemTypeOutputs, newResult.ID+"-password")
+	require.NoError(t, err)
+	assert.Equal(t, "cnab-key:v2:v2:newsecret", string(raw), "the output should be stored with the ID of the key used to encrypt it")
+
+	raw, err = backingStore.Read(ItemTypeClaims, newClaim.ID)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), encryptedParameterPrefix+"v2:", "the parameter should be stored with the ID of the key used to encrypt it")
+
+	for _, tc := range []struct {
+		c    Claim
+		r    Result
+		want string
+	}{
+		{c: oldClaim, r: oldResult, want: "oldsecret"},
+		{c: newClaim, r: newResult, want: "newsecret"},
+	} {
+		got, err := store.ReadClaim(tc.c.ID)
+		require.NoError(t, err)
+		assert.Equal(t, params, got.Parameters)
+
+		o, err := store.ReadOutput(tc.c, tc.r, "password")
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, string(o.Value))
+	}
+
+	// Once the original key is removed, its records can no longer be read
+	store, err = NewClaimStoreWithKeyring(backingStore, Keyring{
+		CurrentKeyID: "v2",
+		Keys:         map[string]EncryptionKey{"v2": newTestKey("v2")},
+	})
+	require.NoError(t, err)
+	_, err = store.ReadOutput(oldClaim, oldResult, "password")
+	require.Error(t, err)
+}
+
+func TestStore_Keyring_PerInstallation(t *testing.T) {
+	backingStore := crud.NewMockStore()
+	store, err := NewClaimStoreWithKeyring(backingStore, Keyring{
+		CurrentKeyID: "default",
+		KeyForInstallation: func(installation string) string {
+			if installation == "tenant-a" {
+				return "tenant-a"
+			}
+			return ""
+		},
+		Keys: map[string]EncryptionKey{
+			"default":  newTestKey("default"),
+			"tenant-a": newTestKey("tenant-a"),
+		},
+	})
+	require.NoError(t, err)
+
+	for installation, wantKey := range map[string]string{"tenant-a": "tenant-a", "tenant-b": "default"} {
+		c, r := generateClaimData(t, store, installation, ActionInstall, StatusSucceeded)
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "password", []byte("topsecret"))))
+
+		raw, err := backingStore.Read(ItemTypeOutputs, r.ID+"-password")
+		require.NoError(t, err)
+		assert.Equal(t, "cnab-key:"+wantKey+":"+wantKey+":topsecret", string(raw))
+
+		o, err := store.ReadOutput(c, r, "password")
+		require.NoError(t, err)
+		assert.Equal(t, "topsecret", string(o.Value))
+	}
+}
@@ -0,0 +1,253 @@
+package claim
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxLabelLength is the maximum length of the name of a label key, without
+// its prefix, and of a label value.
+const maxLabelLength = 63
+
+// labelName matches the name of a label key and a label value, like the labels
+// of kubernetes resources.
+var labelName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// labelPrefix matches the optional DNS prefix of a label key, for example
+// example.com in example.com/team.
+var labelPrefix = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+
+// ValidateLabels validates the keys and values of labels. Like the labels of
+// kubernetes resources, keys are a name with an optional DNS prefix, for
+// example team or example.com/team, and values are empty or a name. A name
+// has at most 63 alphanumeric characters, dashes, underscores and dots, and
+// starts and ends with an alphanumeric character.
+func ValidateLabels(labels map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if value := labels[key]; value != "" && !isLabelName(value) {
+			return errors.Errorf("invalid value %q of label %s: it must be a name of at most %d alphanumeric characters, '-', '_' or '.'", value, key, maxLabelLength)
+		}
+	}
+	return nil
+}
+
+func validateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > 253 || !labelPrefix.MatchString(prefix) {
+			return errors.Errorf("invalid label key %q: its prefix must be a DNS subdomain", key)
+		}
+	}
+	if !isLabelName(name) {
+		return errors.Errorf("invalid label key %q: it must be a name of at most %d alphanumeric characters, '-', '_' or '.', with an optional DNS prefix", key, maxLabelLength)
+	}
+	return nil
+}
+
+func isLabelName(name string) bool {
+	return len(name) <= maxLabelLength && labelName.MatchString(name)
+}
+
+// Operators of the requirements of a LabelSelector.
+const (
+	SelectorEquals       = "="
+	SelectorNotEquals    = "!="
+	SelectorExists       = "exists"
+	SelectorDoesNotExist = "!"
+)
+
+// LabelRequirement is a requirement of a LabelSelector on a label.
+type LabelRequirement struct {
+	// Key of the label.
+	Key string
+
+	// Operator of the requirement, for example SelectorEquals.
+	Operator string
+
+	// Value of the label, for the SelectorEquals and SelectorNotEquals
+	// operators.
+	Value string
+}
+
+// Matches determines if the labels satisfy the requirement.
+func (r LabelRequirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectorEquals:
+		return ok && value == r.Value
+	case SelectorNotEquals:
+		return !ok || value != r.Value
+	case SelectorExists:
+		return ok
+	case SelectorDoesNotExist:
+		return !ok
+	default:
+		return false
+	}
+}
+
+func (r LabelRequirement) String() string {
+	switch r.Operator {
+	case SelectorExists:
+		return r.Key
+	case SelectorDoesNotExist:
+		return "!" + r.Key
+	default:
+		return r.Key + r.Operator + r.Value
+	}
+}
+
+// LabelSelector selects installations by their labels. Every requirement must
+// be satisfied. An empty selector selects everything.
+type LabelSelector []LabelRequirement
+
+// ParseLabelSelector parses a selector of comma separated requirements, like
+// the label selectors of kubernetes, for example
+// "environment=prod,team!=payments,critical,!deprecated":
+//
+//   - key=value or key==value: the label is set to the value
+//   - key!=value: the label is not set to the value, or not set at all
+//   - key: the label is set
+//   - !key: the label is not set
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var s LabelSelector
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var r LabelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = LabelRequirement{Key: kv[0], Operator: SelectorNotEquals, Value: kv[1]}
+		case strings.Contains(part, "=="):
+			kv := strings.SplitN(part, "==", 2)
+			r = LabelRequirement{Key: kv[0], Operator: SelectorEquals, Value: kv[1]}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = LabelRequirement{Key: kv[0], Operator: SelectorEquals, Value: kv[1]}
+		case strings.HasPrefix(part, "!"):
+			r = LabelRequirement{Key: part[1:], Operator: SelectorDoesNotExist}
+		default:
+			r = LabelRequirement{Key: part, Operator: SelectorExists}
+		}
+		r.Key = strings.TrimSpace(r.Key)
+		r.Value = strings.TrimSpace(r.Value)
+
+		if err := validateLabelKey(r.Key); err != nil {
+			return nil, errors.Wrapf(err, "invalid label selector %q", selector)
+		}
+		if r.Value != "" && !isLabelName(r.Value) {
+			return nil, errors.Errorf("invalid label selector %q: invalid value %q of label %s", selector, r.Value, r.Key)
+		}
+		s = append(s, r)
+	}
+	return s, nil
+}
+
+// Matches determines if the labels satisfy every requirement of the
+// selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s LabelSelector) String() string {
+	requirements := make([]string, len(s))
+	for i, r := range s {
+		requirements[i] = r.String()
+	}
+	return strings.Join(requirements, ",")
+}
+
+// GetLabels returns the labels of the installation, which are those of its
+// last claim.
+func (i Installation) GetLabels() (map[string]string, error) {
+	c, err := i.GetLastClaim()
+	if err != nil {
+		return nil, err
+	}
+	return c.Labels, nil
+}
+
+// GetAnnotations returns the annotations of the installation, which are those
+// of its last claim.
+func (i Installation) GetAnnotations() (map[string]string, error) {
+	c, err := i.GetLastClaim()
+	if err != nil {
+		return nil, err
+	}
+	return c.Annotations, nil
+}
+
+// ListInstallationsBySelector returns the sorted names of the installations
+// whose labels, those of their last claim, match the label selector, as
+// parsed by ParseLabelSelector.
+func (s Store) ListInstallationsBySelector(selector string) ([]string, error) {
+	ls, err := ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	installations, err := s.ListInstallations()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, installation := range installations {
+		c, err := s.ReadLastClaim(installation)
+		if errors.Is(err, ErrInstallationNotFound) {
+			// The installation was deleted in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading the labels of installation %s: %w", installation, err)
+		}
+		if ls.Matches(c.Labels) {
+			names = append(names, installation)
+		}
+	}
+	return names, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
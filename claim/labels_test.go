@@ -0,0 +1,133 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestValidateLabels(t *testing.T) {
+	valid := map[string]string{
+		"environment":            "prod",
+		"example.com/team":       "payments",
+		"tier":                   "",
+		"app.kubernetes.io/name": "my_app-1.0",
+	}
+	require.NoError(t, ValidateLabels(valid))
+
+	invalid := map[string]map[string]string{
+		`invalid label key "-env": it must be a name of at most 63 alphanumeric characters, '-', '_' or '.', with an optional DNS prefix`: {"-env": "prod"},
+		`invalid label key "Example.com/team": its prefix must be a DNS subdomain`:                                                        {"Example.com/team": "payments"},
+		`invalid value "prod env" of label env: it must be a name of at most 63 alphanumeric characters, '-', '_' or '.'`:                 {"env": "prod env"},
+	}
+	for wantErr, labels := range invalid {
+		assert.EqualError(t, ValidateLabels(labels), wantErr)
+	}
+}
+
+func TestClaim_Validate_Labels(t *testing.T) {
+	c, err := New("mysql", ActionInstall, exampleBundle, nil)
+	require.NoError(t, err)
+
+	c.Labels = map[string]string{"env": "prod env"}
+	assert.EqualError(t, c.Validate(), `invalid value "prod env" of label env: it must be a name of at most 63 alphanumeric characters, '-', '_' or '.'`)
+}
+
+func TestClaim_NewClaim_Labels(t *testing.T) {
+	c, err := New("mysql", ActionInstall, exampleBundle, nil)
+	require.NoError(t, err)
+	c.Labels = map[string]string{"environment": "prod"}
+	c.Annotations = map[string]string{"owner": "Payments team <payments@example.com>"}
+
+	upgrade, err := c.NewClaim(ActionUpgrade, exampleBundle, nil)
+	require.NoError(t, err)
+	assert.Equal(t, c.Labels, upgrade.Labels, "the labels should be kept")
+	assert.Equal(t, c.Annotations, upgrade.Annotations, "the annotations should be kept")
+
+	upgrade.Labels["environment"] = "staging"
+	assert.Equal(t, "prod", c.Labels["environment"], "the labels should be copied")
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	s, err := ParseLabelSelector("environment=prod, team!=payments,tier==web,critical,!deprecated")
+	require.NoError(t, err)
+	assert.Equal(t, LabelSelector{
+		{Key: "environment", Operator: SelectorEquals, Value: "prod"},
+		{Key: "team", Operator: SelectorNotEquals, Value: "payments"},
+		{Key: "tier", Operator: SelectorEquals, Value: "web"},
+		{Key: "critical", Operator: SelectorExists},
+		{Key: "deprecated", Operator: SelectorDoesNotExist},
+	}, s)
+	assert.Equal(t, "environment=prod,team!=payments,tier=web,critical,!deprecated", s.String())
+
+	assert.True(t, s.Matches(map[string]string{"environment": "prod", "tier": "web", "critical": ""}))
+	assert.True(t, s.Matches(map[string]string{"environment": "prod", "team": "search", "tier": "web", "critical": "true"}))
+	assert.False(t, s.Matches(map[string]string{"environment": "prod", "team": "payments", "tier": "web", "critical": ""}))
+	assert.False(t, s.Matches(map[string]string{"environment": "prod", "tier": "web"}))
+	assert.False(t, s.Matches(map[string]string{"environment": "prod", "tier": "web", "critical": "", "deprecated": ""}))
+	assert.False(t, s.Matches(nil))
+
+	empty, err := ParseLabelSelector("")
+	require.NoError(t, err)
+	assert.True(t, empty.Matches(nil), "an empty selector should select everything")
+
+	_, err = ParseLabelSelector("environment=prod,=web")
+	assert.EqualError(t, err, `invalid label selector "environment=prod,=web": invalid label key "": it must be a name of at most 63 alphanumeric characters, '-', '_' or '.', with an optional DNS prefix`)
+
+	_, err = ParseLabelSelector("environment=prod env")
+	assert.EqualError(t, err, `invalid label selector "environment=prod env": invalid value "prod env" of label environment`)
+}
+
+func TestStore_ListInstallationsBySelector(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	saveLabeledClaim := func(installation string, labels map[string]string) {
+		c, err := New(installation, ActionInstall, claimStoreBundle, nil)
+		require.NoError(t, err)
+		c.Labels = labels
+		require.NoError(t, store.SaveClaim(c))
+	}
+	saveLabeledClaim("mysql", map[string]string{"environment": "prod", "team": "payments"})
+	saveLabeledClaim("redis", map[string]string{"environment": "staging", "team": "payments"})
+	saveLabeledClaim("wordpress", map[string]string{"environment": "prod", "team": "marketing"})
+	saveLabeledClaim("nginx", nil)
+
+	names, err := store.ListInstallationsBySelector("team=payments")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql", "redis"}, names)
+
+	names, err = store.ListInstallationsBySelector("environment=prod,team!=payments")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wordpress"}, names)
+
+	names, err = store.ListInstallationsBySelector("!environment")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nginx"}, names)
+
+	names, err = store.ListInstallationsBySelector("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql", "nginx", "redis", "wordpress"}, names)
+
+	// The labels of the last claim are used
+	last, err := store.ReadLastClaim("redis")
+	require.NoError(t, err)
+	upgrade, err := last.NewClaim(ActionUpgrade, claimStoreBundle, nil)
+	require.NoError(t, err)
+	upgrade.Labels["environment"] = "prod"
+	require.NoError(t, store.SaveClaim(upgrade))
+
+	names, err = store.ListInstallationsBySelector("environment=prod,team=payments")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql", "redis"}, names)
+
+	i, err := store.ReadInstallation("redis")
+	require.NoError(t, err)
+	labels, err := i.GetLabels()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"environment": "prod", "team": "payments"}, labels)
+
+	_, err = store.ListInstallationsBySelector("environment=prod env")
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package claim
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// InstallationLock is an advisory lock on an installation, held by an owner
+// such as the ID of the claim being executed, until it is released or its
+// lease expires.
+//
+// Locks are saved with crud.SaveIfRevision, and released with
+// crud.DeleteIfRevision, so they exclude concurrent owners when the backing
+// store implements crud.ConditionalStore, as the filesystem, Kubernetes and
+// S3 stores do. Remote stores should implement it to support locking.
+type InstallationLock struct {
+	// Installation that is locked.
+	Installation string `json:"installation"`
+
+	// Owner holding the lock.
+	Owner string `json:"owner"`
+
+	// Acquired is when the owner acquired the lock.
+	Acquired time.Time `json:"acquired"`
+
+	// Expires is when the lease of the lock expires, after which another
+	// owner may acquire it.
+	Expires time.Time `json:"expires"`
+}
+
+// IsZero returns true when the lock was not acquired.
+func (l InstallationLock) IsZero() bool {
+	return l.Installation == "" && l.Owner == ""
+}
+
+// Expired returns true when the lease of the lock has expired.
+func (l InstallationLock) Expired() bool {
+	return !time.Now().Before(l.Expires)
+}
+
+// InstallationLockedError is returned when an installation is locked by
+// another owner. It matches ErrInstallationLocked with errors.Is.
+type InstallationLockedError struct {
+	// Lock held by the other owner.
+	Lock InstallationLock
+}
+
+func (e InstallationLockedError) Error() string {
+	return fmt.Sprintf("installation %s is locked by %s until %s", e.Lock.Installation, e.Lock.Owner, e.Lock.Expires.Format(time.RFC3339))
+}
+
+// Is matches ErrInstallationLocked.
+func (e InstallationLockedError) Is(target error) bool {
+	return target == ErrInstallationLocked
+}
+
+// AcquireInstallationLock locks the installation for the owner until the
+// lease expires. Acquiring a lock that is already held by the owner renews
+// its lease. When the installation is locked by another owner whose lease
+// has not expired, an InstallationLockedError is returned.
+func (s Store) AcquireInstallationLock(installation string, owner string, lease time.Duration) (InstallationLock, error) {
+	if installation == "" || owner == "" {
+		return InstallationLock{}, errors.New("the installation and the owner of the lock are required")
+	}
+	if lease <= 0 {
+		return InstallationLock{}, errors.Errorf("invalid lease %s for the lock on installation %s, it must be positive", lease, installation)
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return InstallationLock{}, err
+	}
+
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		var current InstallationLock
+		var revision string
+		current, revision, err = s.readInstallationLock(installation)
+		if err != nil {
+			return InstallationLock{}, err
+		}
+		if !current.IsZero() && current.Owner != owner && !current.Expired() {
+			return InstallationLock{}, InstallationLockedError{Lock: current}
+		}
+
+		now := time.Now().UTC()
+		lock := InstallationLock{
+			Installation: installation,
+			Owner:        owner,
+			Acquired:     now,
+			Expires:      now.Add(lease),
+		}
+		if current.Owner == owner && !current.Expired() {
+			lock.Acquired = current.Acquired
+		}
+
+		var data []byte
+		data, err = json.Marshal(lock)
+		if err != nil {
+			return InstallationLock{}, errors.Wrapf(err, "error marshaling the lock on installation %s", installation)
+		}
+
+		err = s.backingStore.SaveIfRevision(ItemTypeLocks, "", installation, data, revision)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, crud.ErrConflict) {
+			return InstallationLock{}, err
+		}
+		// The lock was modified concurrently, check who holds it now
+	}
+	return InstallationLock{}, errors.Wrapf(err, "error acquiring the lock on installation %s", installation)
+}
+
+// ReleaseInstallationLock releases a lock acquired with
+// AcquireInstallationLock. Releasing a lock that is no longer held by its
+// owner, because its lease expired, does nothing.
+func (s Store) ReleaseInstallationLock(lock InstallationLock) error {
+	if lock.IsZero() {
+		return nil
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	current, revision, err := s.readInstallationLock(lock.Installation)
+	if err != nil {
+		return err
+	}
+	if current.Owner != lock.Owner {
+		return nil
+	}
+
+	// The lock is deleted only when it was not acquired by another owner
+	// since it was read, after its lease expired
+	err = s.backingStore.DeleteIfRevision(ItemTypeLocks, lock.Installation, revision)
+	if errors.Is(err, crud.ErrRecordDoesNotExist) || errors.Is(err, crud.ErrConflict) {
+		return nil
+	}
+	return errors.Wrapf(err, "error releasing the lock on installation %s", lock.Installation)
+}
+
+// ReadInstallationLock returns the lock on the installation, which is zero
+// when the installation is not locked. The lock may have expired.
+func (s Store) ReadInstallationLock(installation string) (InstallationLock, error) {
+	lock, _, err := s.readInstallationLock(installation)
+	return lock, err
+}
+
+// readInstallationLock returns the lock on the installation and the revision
+// of its record, which are empty when the installation is not locked.
+func (s Store) readInstallationLock(installation string) (InstallationLock, string, error) {
+	data, err := s.backingStore.Read(ItemTypeLocks, installation)
+	if err != nil {
+		if errors.Is(err, crud.ErrRecordDoesNotExist) {
+			return InstallationLock{}, "", nil
+		}
+		return InstallationLock{}, "", err
+	}
+
+	var lock InstallationLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return InstallationLock{}, "", errors.Wrapf(err, "error unmarshaling the lock on installation %s", installation)
+	}
+	return lock, crud.Revision(data), nil
+}
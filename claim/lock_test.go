@@ -0,0 +1,104 @@
+package claim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_InstallationLock(t *testing.T) {
+	store := NewClaimStore(crud.NewFileSystemStore(t.TempDir(), NewClaimStoreFileExtensions()), nil, nil)
+
+	lock, err := store.ReadInstallationLock("mysql")
+	require.NoError(t, err)
+	assert.True(t, lock.IsZero(), "the installation should not be locked")
+
+	lock, err = store.AcquireInstallationLock("mysql", "claim1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "mysql", lock.Installation)
+	assert.Equal(t, "claim1", lock.Owner)
+	assert.False(t, lock.Expired())
+
+	_, err = store.AcquireInstallationLock("mysql", "claim2", time.Minute)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInstallationLocked)
+	var lockedErr InstallationLockedError
+	require.ErrorAs(t, err, &lockedErr)
+	assert.Equal(t, "claim1", lockedErr.Lock.Owner)
+	assert.Contains(t, err.Error(), "installation mysql is locked by claim1")
+
+	_, err = store.AcquireInstallationLock("wordpress", "claim2", time.Minute)
+	require.NoError(t, err, "other installations should not be locked")
+
+	renewed, err := store.AcquireInstallationLock("mysql", "claim1", time.Hour)
+	require.NoError(t, err, "the owner should be able to renew its lease")
+	assert.Equal(t, lock.Acquired, renewed.Acquired)
+	assert.True(t, renewed.Expires.After(lock.Expires))
+
+	require.NoError(t, store.ReleaseInstallationLock(renewed))
+	lock, err = store.ReadInstallationLock("mysql")
+	require.NoError(t, err)
+	assert.True(t, lock.IsZero(), "the lock should be released")
+
+	_, err = store.AcquireInstallationLock("mysql", "claim2", time.Minute)
+	require.NoError(t, err, "the lock should be available once released")
+}
+
+func TestStore_InstallationLock_Expired(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+	expired, err := store.AcquireInstallationLock("mysql", "claim1", time.Nanosecond)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	assert.True(t, expired.Expired())
+
+	lock, err := store.AcquireInstallationLock("mysql", "claim2", time.Minute)
+	require.NoError(t, err, "an expired lock should be taken over")
+	assert.Equal(t, "claim2", lock.Owner)
+
+	require.NoError(t, store.ReleaseInstallationLock(expired), "releasing a lock that was taken over should do nothing")
+	current, err := store.ReadInstallationLock("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, "claim2", current.Owner, "the lock of the new owner should not be released")
+}
+
+// takeoverStore renews a lock for another owner before it is released, as if
+// the lease of the lock expired and it was acquired by another owner after
+// it was read.
+type takeoverStore struct {
+	*crud.MockStore
+}
+
+func (s takeoverStore) DeleteIfRevision(itemType string, name string, revision string) error {
+	data := []byte(`{"installation":"mysql","owner":"claim2"}`)
+	if err := s.MockStore.Save(itemType, "", name, data); err != nil {
+		return err
+	}
+	return s.MockStore.DeleteIfRevision(itemType, name, revision)
+}
+
+func TestStore_ReleaseInstallationLock_TakenOver(t *testing.T) {
+	store := NewClaimStore(takeoverStore{crud.NewMockStore()}, nil, nil)
+
+	lock, err := store.AcquireInstallationLock("mysql", "claim1", time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, store.ReleaseInstallationLock(lock), "releasing a lock that was taken over should do nothing")
+	current, err := store.ReadInstallationLock("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, "claim2", current.Owner, "the lock of the new owner should not be released")
+}
+
+func TestStore_AcquireInstallationLock_Validation(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+	_, err := store.AcquireInstallationLock("mysql", "", time.Minute)
+	assert.EqualError(t, err, "the installation and the owner of the lock are required")
+
+	_, err = store.AcquireInstallationLock("mysql", "claim1", 0)
+	assert.EqualError(t, err, "invalid lease 0s for the lock on installation mysql, it must be positive")
+}
@@ -0,0 +1,385 @@
+package claim
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// MaintenanceLockName is the name of the lock held by a MaintenanceRunner
+// while it runs its tasks, so that only one process maintains the claims
+// of a backing store at a time. It is reserved, and must not be used as the
+// name of an installation.
+const MaintenanceLockName = "cnab-maintenance"
+
+// DefaultMaintenanceLease is the lease of the maintenance lock when the
+// MaintenanceRunner does not specify one. The lease is renewed before each
+// task, so it only needs to cover the longest task.
+const DefaultMaintenanceLease = 10 * time.Minute
+
+// MaintenanceTask is a housekeeping task run periodically by a
+// MaintenanceRunner, such as PruneTask or ReindexTask.
+type MaintenanceTask struct {
+	// Name of the task, used in the MaintenanceReport.
+	Name string
+
+	// Run performs the task against the claims of the provider. It should
+	// stop early when the context is cancelled.
+	Run func(ctx context.Context, p Provider) error
+}
+
+// MaintenanceTaskReport is the outcome of a MaintenanceTask.
+type MaintenanceTaskReport struct {
+	// Name of the task.
+	Name string
+
+	// Duration of the task.
+	Duration time.Duration
+
+	// Err is the error returned by the task, nil when it succeeded.
+	Err error
+}
+
+// MaintenanceReport describes a run of the tasks of a MaintenanceRunner.
+type MaintenanceReport struct {
+	// Started is when the run started.
+	Started time.Time
+
+	// Skipped is true when the tasks were not run because another process
+	// held the maintenance lock, described by Lock.
+	Skipped bool
+
+	// Lock is the maintenance lock held by the other process when the run
+	// was skipped.
+	Lock InstallationLock
+
+	// Tasks are the reports of the tasks that were run, in order.
+	Tasks []MaintenanceTaskReport
+}
+
+// Err returns an error combining the errors of the tasks, or nil when every
+// task succeeded.
+func (r MaintenanceReport) Err() error {
+	var err *multierror.Error
+	for _, t := range r.Tasks {
+		if t.Err != nil {
+			err = multierror.Append(err, errors.Wrapf(t.Err, "maintenance task %s failed", t.Name))
+		}
+	}
+	return err.ErrorOrNil()
+}
+
+// MaintenanceRunner periodically runs housekeeping tasks against a Provider,
+// and is suitable for embedding in long running services. The tasks are run
+// while holding the MaintenanceLockName lock, so that when several processes
+// share a backing store, only one of them performs the maintenance at a time.
+type MaintenanceRunner struct {
+	// Provider whose claims are maintained.
+	Provider Provider
+
+	// Tasks that are run, in order. A failed task does not prevent the next
+	// tasks from running.
+	Tasks []MaintenanceTask
+
+	// Interval between the start of two runs of the tasks.
+	Interval time.Duration
+
+	// Owner of the maintenance lock, which identifies this process. Defaults
+	// to the hostname and the process ID.
+	Owner string
+
+	// Lease of the maintenance lock, defaults to DefaultMaintenanceLease.
+	Lease time.Duration
+
+	// OnReport is called by Run with the report of each run, and the error
+	// returned by RunOnce, optional.
+	OnReport func(MaintenanceReport, error)
+}
+
+// Validate the MaintenanceRunner.
+func (m MaintenanceRunner) Validate() error {
+	if m.Provider == nil {
+		return errors.New("invalid maintenance runner: the provider is required")
+	}
+	if len(m.Tasks) == 0 {
+		return errors.New("invalid maintenance runner: at least one task is required")
+	}
+	for i, t := range m.Tasks {
+		if t.Name == "" || t.Run == nil {
+			return errors.Errorf("invalid maintenance runner: task %d requires a name and a Run function", i)
+		}
+	}
+	if m.Interval < 0 || m.Lease < 0 {
+		return errors.New("invalid maintenance runner: the interval and the lease must not be negative")
+	}
+	return nil
+}
+
+// Run runs the tasks immediately, then after every interval, until the
+// context is cancelled. The report of each run is passed to OnReport, and
+// a run that fails does not stop the next runs.
+func (m MaintenanceRunner) Run(ctx context.Context) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if m.Interval == 0 {
+		return errors.New("invalid maintenance runner: the interval is required")
+	}
+
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := m.RunOnce(ctx)
+		if m.OnReport != nil && ctx.Err() == nil {
+			m.OnReport(report, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce runs the tasks once, when the maintenance lock can be acquired.
+// When another process holds the lock, the run is skipped and reported with
+// MaintenanceReport.Skipped. Task failures are reported in the
+// MaintenanceReport, the error is only returned when the lock could not be
+// acquired or released.
+func (m MaintenanceRunner) RunOnce(ctx context.Context) (MaintenanceReport, error) {
+	report := MaintenanceReport{Started: time.Now().UTC()}
+	if err := m.Validate(); err != nil {
+		return report, err
+	}
+
+	owner := m.owner()
+	lease := m.Lease
+	if lease == 0 {
+		lease = DefaultMaintenanceLease
+	}
+
+	lock, err := m.Provider.AcquireInstallationLock(MaintenanceLockName, owner, lease)
+	if err != nil {
+		var locked InstallationLockedError
+		if errors.As(err, &locked) {
+			report.Skipped = true
+			report.Lock = locked.Lock
+			return report, nil
+		}
+		return report, errors.Wrap(err, "error acquiring the maintenance lock")
+	}
+
+	for i, t := range m.Tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		if i > 0 {
+			// Renew the lease so that it does not expire during long runs
+			lock, err = m.Provider.AcquireInstallationLock(MaintenanceLockName, owner, lease)
+			if err != nil {
+				return report, errors.Wrap(err, "error renewing the maintenance lock")
+			}
+		}
+
+		start := time.Now()
+		err := t.Run(ctx, m.Provider)
+		report.Tasks = append(report.Tasks, MaintenanceTaskReport{Name: t.Name, Duration: time.Since(start), Err: err})
+	}
+
+	if err := m.Provider.ReleaseInstallationLock(lock); err != nil {
+		return report, errors.Wrap(err, "error releasing the maintenance lock")
+	}
+	return report, nil
+}
+
+func (m MaintenanceRunner) owner() string {
+	if m.Owner != "" {
+		return m.Owner
+	}
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// forEachInstallation calls fn with each installation of the provider,
+// stopping when the context is cancelled. Installations that fail are
+// reported together after every installation was processed.
+func forEachInstallation(ctx context.Context, p Provider, fn func(installation string) error) error {
+	installations, err := p.ListInstallations()
+	if err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	for _, installation := range installations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(installation); err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "installation %s", installation))
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+// PruneTask prunes the claims of every installation with the retention
+// policy.
+func PruneTask(policy RetentionPolicy) MaintenanceTask {
+	return MaintenanceTask{
+		Name: "prune",
+		Run: func(ctx context.Context, p Provider) error {
+			if err := policy.Validate(); err != nil {
+				return err
+			}
+			return forEachInstallation(ctx, p, func(installation string) error {
+				_, err := p.Prune(installation, policy)
+				return err
+			})
+		},
+	}
+}
+
+// ExpiredLocksTask garbage collects the locks of installations whose lease
+// has expired, for example because their owner crashed before releasing
+// them.
+func ExpiredLocksTask() MaintenanceTask {
+	return MaintenanceTask{
+		Name: "expired-locks",
+		Run: func(ctx context.Context, p Provider) error {
+			return forEachInstallation(ctx, p, func(installation string) error {
+				lock, err := p.ReadInstallationLock(installation)
+				if err != nil {
+					return err
+				}
+				if lock.IsZero() || !lock.Expired() {
+					return nil
+				}
+				return p.ReleaseInstallationLock(lock)
+			})
+		},
+	}
+}
+
+// CompactOutputsTask saves again the outputs that are not compressed, so
+// that outputs saved before Store.SetOutputCompression was enabled, or with
+// a higher threshold, are compressed. It does nothing useful when the
+// provider does not compress outputs.
+func CompactOutputsTask() MaintenanceTask {
+	return MaintenanceTask{
+		Name: "compact-outputs",
+		Run: func(ctx context.Context, p Provider) error {
+			return forEachInstallation(ctx, p, func(installation string) error {
+				return forEachOutput(p, installation, func(c Claim, r Result, name string) error {
+					if compression, _ := r.OutputMetadata.GetCompression(name); compression == CompressionGzip {
+						return nil
+					}
+					o, err := p.ReadOutput(c, r, name)
+					if err != nil {
+						return err
+					}
+					return p.SaveOutput(o)
+				})
+			})
+		},
+	}
+}
+
+// OutputTTLTask deletes the outputs of results created longer than the ttl
+// ago. An output is only deleted when a more recent result of the
+// installation generated an output with the same name, so that the last
+// value of each output, returned by ReadLastOutputs, is kept.
+func OutputTTLTask(ttl time.Duration) MaintenanceTask {
+	return MaintenanceTask{
+		Name: "output-ttl",
+		Run: func(ctx context.Context, p Provider) error {
+			if ttl <= 0 {
+				return errors.Errorf("invalid output ttl %s, it must be positive", ttl)
+			}
+			expired := time.Now().Add(-ttl)
+
+			return forEachInstallation(ctx, p, func(installation string) error {
+				type output struct {
+					resultID string
+					created  time.Time
+					name     string
+				}
+				var outputs []output
+				err := forEachOutput(p, installation, func(c Claim, r Result, name string) error {
+					outputs = append(outputs, output{resultID: r.ID, created: r.Created, name: name})
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				// Walk the outputs from the most recent result, so that the
+				// last value of each output is seen first and kept
+				seen := map[string]bool{}
+				for idx := len(outputs) - 1; idx >= 0; idx-- {
+					o := outputs[idx]
+					if !seen[o.name] {
+						seen[o.name] = true
+						continue
+					}
+					if !o.created.Before(expired) {
+						continue
+					}
+					if err := p.DeleteOutput(o.resultID, o.name); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// ReindexTask rebuilds the claim indexes, including the status index, when
+// the provider is a Store whose backing store maintains secondary indexes.
+func ReindexTask() MaintenanceTask {
+	return MaintenanceTask{
+		Name: "reindex",
+		Run: func(ctx context.Context, p Provider) error {
+			reindexer, ok := p.(interface{ Reindex() error })
+			if !ok {
+				return nil
+			}
+			return reindexer.Reindex()
+		},
+	}
+}
+
+// forEachOutput calls fn with each output of the installation, from the
+// oldest result to the most recent, as sorted by ReadInstallation.
+func forEachOutput(p Provider, installation string, fn func(c Claim, r Result, name string) error) error {
+	i, err := p.ReadInstallation(installation)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range i.Claims {
+		if c.results == nil {
+			continue
+		}
+		for _, r := range *c.results {
+			names, err := p.ListOutputs(r.ID)
+			if err != nil {
+				if errors.Is(err, ErrResultNotFound) {
+					continue
+				}
+				return err
+			}
+			for _, name := range names {
+				if err := fn(c, r, name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package claim
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestMaintenanceRunner_RunOnce(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+	var ran []string
+	task := func(name string, err error) MaintenanceTask {
+		return MaintenanceTask{Name: name, Run: func(ctx context.Context, p Provider) error {
+			lock, lockErr := p.ReadInstallationLock(MaintenanceLockName)
+			require.NoError(t, lockErr)
+			assert.Equal(t, "worker1", lock.Owner, "the tasks should run while holding the maintenance lock")
+			ran = append(ran, name)
+			return err
+		}}
+	}
+	runner := MaintenanceRunner{
+		Provider: store,
+		Owner:    "worker1",
+		Tasks:    []MaintenanceTask{task("first", errors.New("boom")), task("second", nil)},
+	}
+
+	report, err := runner.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Skipped)
+	assert.Equal(t, []string{"first", "second"}, ran, "a failed task should not prevent the next tasks from running")
+	require.Len(t, report.Tasks, 2)
+	assert.EqualError(t, report.Tasks[0].Err, "boom")
+	assert.NoError(t, report.Tasks[1].Err)
+	require.Error(t, report.Err())
+	assert.Contains(t, report.Err().Error(), "maintenance task first failed: boom")
+
+	lock, err := store.ReadInstallationLock(MaintenanceLockName)
+	require.NoError(t, err)
+	assert.True(t, lock.IsZero(), "the maintenance lock should be released")
+
+	installations, err := store.ListInstallations()
+	require.NoError(t, err)
+	assert.Empty(t, installations, "the maintenance lock should not be listed as an installation")
+
+	t.Run("locked by another process", func(t *testing.T) {
+		ran = nil
+		_, err := store.AcquireInstallationLock(MaintenanceLockName, "worker2", time.Minute)
+		require.NoError(t, err)
+
+		report, err := runner.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.True(t, report.Skipped)
+		assert.Equal(t, "worker2", report.Lock.Owner)
+		assert.Empty(t, ran, "the tasks should not run while another process holds the lock")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := MaintenanceRunner{Provider: store}.RunOnce(context.Background())
+		assert.EqualError(t, err, "invalid maintenance runner: at least one task is required")
+	})
+}
+
+func TestMaintenanceRunner_Run(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reports []MaintenanceReport
+	runner := MaintenanceRunner{
+		Provider: store,
+		Interval: time.Millisecond,
+		Tasks:    []MaintenanceTask{ReindexTask()},
+		OnReport: func(report MaintenanceReport, err error) {
+			require.NoError(t, err)
+			reports = append(reports, report)
+			if len(reports) == 3 {
+				cancel()
+			}
+		},
+	}
+
+	require.NoError(t, runner.Run(ctx))
+	assert.Len(t, reports, 3, "the tasks should run after every interval until the context is cancelled")
+
+	err := MaintenanceRunner{Provider: store, Tasks: runner.Tasks}.Run(context.Background())
+	assert.EqualError(t, err, "invalid maintenance runner: the interval is required")
+}
+
+func TestPruneTask(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	for _, installation := range []string{"mysql", "wordpress"} {
+		generateArchiveData(t, store, installation)
+		generateArchiveData(t, store, installation)
+	}
+
+	err := PruneTask(RetentionPolicy{KeepLast: 1}).Run(context.Background(), store)
+	require.NoError(t, err)
+
+	for _, installation := range []string{"mysql", "wordpress"} {
+		claims, err := store.ListClaims(installation)
+		require.NoError(t, err)
+		assert.Len(t, claims, 1, "every installation should be pruned")
+	}
+
+	err = PruneTask(RetentionPolicy{}).Run(context.Background(), store)
+	assert.EqualError(t, err, "invalid retention policy: at least one retention rule must be set")
+}
+
+func TestExpiredLocksTask(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	generateArchiveData(t, store, "mysql")
+	generateArchiveData(t, store, "wordpress")
+
+	_, err := store.AcquireInstallationLock("mysql", "claim1", time.Nanosecond)
+	require.NoError(t, err)
+	_, err = store.AcquireInstallationLock("wordpress", "claim2", time.Hour)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	require.NoError(t, ExpiredLocksTask().Run(context.Background(), store))
+
+	lock, err := store.ReadInstallationLock("mysql")
+	require.NoError(t, err)
+	assert.True(t, lock.IsZero(), "the expired lock should be deleted")
+
+	lock, err = store.ReadInstallationLock("wordpress")
+	require.NoError(t, err)
+	assert.Equal(t, "claim2", lock.Owner, "the lock that has not expired should be kept")
+}
+
+func TestCompactOutputsTask(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	c, err := New("mysql", ActionInstall, archiveBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+	r, err := c.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+	value := []byte(strings.Repeat("mysql.example.com ", 100))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "host", value)))
+
+	store.SetOutputCompression(64)
+	require.NoError(t, CompactOutputsTask().Run(context.Background(), store))
+
+	r, err = store.ReadResult(r.ID)
+	require.NoError(t, err)
+	compression, _ := r.OutputMetadata.GetCompression("host")
+	assert.Equal(t, CompressionGzip, compression, "the output should be compressed")
+
+	o, err := store.ReadOutput(c, r, "host")
+	require.NoError(t, err)
+	assert.Equal(t, value, o.Value)
+}
+
+func TestOutputTTLTask(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+	install, err := New("mysql", ActionInstall, archiveBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(install))
+	old, err := install.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	old.Created = time.Now().Add(-48 * time.Hour)
+	require.NoError(t, store.SaveResult(old))
+	require.NoError(t, store.SaveOutput(NewOutput(install, old, "host", []byte("old.example.com"))))
+	require.NoError(t, store.SaveOutput(NewOutput(install, old, "password", []byte("topsecret"))))
+
+	upgrade, err := install.NewClaim(ActionUpgrade, archiveBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(upgrade))
+	recent, err := upgrade.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(recent))
+	require.NoError(t, store.SaveOutput(NewOutput(upgrade, recent, "host", []byte("new.example.com"))))
+
+	require.NoError(t, OutputTTLTask(24*time.Hour).Run(context.Background(), store))
+
+	names, err := store.ListOutputs(old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"password"}, names, "only the expired output with a more recent value should be deleted")
+
+	outputs, err := store.ReadLastOutputs("mysql")
+	require.NoError(t, err)
+	host, _ := outputs.GetByName("host")
+	assert.Equal(t, "new.example.com", string(host.Value))
+	password, _ := outputs.GetByName("password")
+	assert.Equal(t, "topsecret", string(password.Value), "the last value of each output should be kept")
+
+	err = OutputTTLTask(0).Run(context.Background(), store)
+	assert.EqualError(t, err, "invalid output ttl 0s, it must be positive")
+}
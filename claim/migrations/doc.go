@@ -0,0 +1,14 @@
+/*
+Package migrations upgrades claim data persisted by older versions of cnab-go
+to the claim schema version implemented by the claim package.
+
+A Migrator walks the claims in a claim.Store and applies the chain of
+Migrations from each claim's schemaVersion to claim.GetDefaultSchemaVersion.
+Before a claim is upgraded in place, its original document is saved to the
+backups item type in the same store, grouped by the original schema version:
+
+	migration-backups/
+	  SCHEMA_VERSION/
+	    CLAIM_ID
+*/
+package migrations
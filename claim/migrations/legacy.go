@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cnabio/cnab-go/claim"
+)
+
+// legacyStatuses maps the statuses used before claims and results were split
+// into separate documents to their current values.
+var legacyStatuses = map[string]string{
+	"success": claim.StatusSucceeded,
+	"failure": claim.StatusFailed,
+}
+
+// DefaultMigrations returns the migrations used by a Migrator by default.
+func DefaultMigrations() []Migration {
+	return []Migration{
+		{
+			From:        "",
+			To:          claim.GetDefaultSchemaVersion(),
+			Description: "split the result and outputs out of claims persisted before schema versions were recorded",
+			Migrate:     migrateLegacyClaim,
+		},
+	}
+}
+
+// migrateLegacyClaim upgrades a claim that was persisted before claims,
+// results and outputs were stored as separate documents. These claims were
+// keyed by the installation name, called "name" in the oldest versions, and
+// recorded the action, status and outputs of the last operation only.
+func migrateLegacyClaim(c Document) (MigratedData, error) {
+	if _, ok := c["installation"]; !ok {
+		c["installation"] = c["name"]
+	}
+	delete(c, "name")
+
+	for _, field := range []string{"id", "revision"} {
+		if id, _ := c[field].(string); id == "" {
+			newID, err := claim.NewULID()
+			if err != nil {
+				return MigratedData{}, err
+			}
+			c[field] = newID
+		}
+	}
+
+	modified := c["modified"]
+	if modified == nil {
+		modified = c["created"]
+	}
+	delete(c, "modified")
+
+	var data MigratedData
+	legacyResult, _ := c["result"].(map[string]interface{})
+	delete(c, "result")
+	if legacyResult != nil {
+		if _, ok := c["action"]; !ok {
+			c["action"] = legacyResult["action"]
+		}
+
+		status, _ := legacyResult["status"].(string)
+		if current, ok := legacyStatuses[status]; ok {
+			status = current
+		}
+
+		resultID, err := claim.NewULID()
+		if err != nil {
+			return MigratedData{}, err
+		}
+		r := Document{
+			"id":      resultID,
+			"claimId": c["id"],
+			"created": modified,
+			"status":  status,
+		}
+		if message, ok := legacyResult["message"]; ok {
+			r["message"] = message
+		}
+		data.Results = append(data.Results, r)
+	}
+	if c["action"] == nil {
+		c["action"] = claim.ActionUnknown
+	}
+	if c["created"] == nil {
+		c["created"] = time.Time{}
+	}
+
+	legacyOutputs, _ := c["outputs"].(map[string]interface{})
+	delete(c, "outputs")
+	if len(legacyOutputs) > 0 {
+		if len(data.Results) == 0 {
+			return MigratedData{}, fmt.Errorf("the claim has outputs but no result")
+		}
+
+		data.Outputs = make(map[string][]byte, len(legacyOutputs))
+		for name, value := range legacyOutputs {
+			if s, ok := value.(string); ok {
+				data.Outputs[name] = []byte(s)
+				continue
+			}
+
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return MigratedData{}, fmt.Errorf("could not convert output %s: %w", name, err)
+			}
+			data.Outputs[name] = encoded
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,292 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/schema"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// ItemTypeBackups is the item type used to store the original documents of
+// migrated claims.
+const ItemTypeBackups = "migration-backups"
+
+// Document is an untyped claim data document, such as a claim or result.
+type Document map[string]interface{}
+
+// SchemaVersion returns the schemaVersion of the document, or an empty
+// version when it was persisted before schema versions were recorded.
+func (d Document) SchemaVersion() schema.Version {
+	v, _ := d["schemaVersion"].(string)
+	return schema.Version(v)
+}
+
+// MigratedData is the claim data split out of a claim by a Migration,
+// which is saved alongside the migrated claim.
+type MigratedData struct {
+	// Results of the claim.
+	Results []Document
+
+	// Outputs generated by the last result, keyed by the output name.
+	Outputs map[string][]byte
+}
+
+// Migration upgrades a claim document from one schema version to another.
+type Migration struct {
+	// From is the schema version of the claims upgraded by the migration.
+	From schema.Version
+
+	// To is the schema version of the claims after the migration.
+	To schema.Version
+
+	// Description of the changes made by the migration.
+	Description string
+
+	// Migrate upgrades the claim document in place, returning any data that
+	// was split out of the claim into separate documents.
+	Migrate func(c Document) (MigratedData, error)
+}
+
+// Summary reports the outcome of a migration.
+type Summary struct {
+	// Migrated is the IDs of the claims that were upgraded.
+	Migrated []string
+
+	// Current is the number of claims that were already at the current schema version.
+	Current int
+
+	// Failed maps the IDs of claims that could not be upgraded to the reason.
+	Failed map[string]error
+}
+
+// Migrator upgrades claims persisted with older schema versions.
+type Migrator struct {
+	// Migrations that may be applied. Defaults to DefaultMigrations.
+	Migrations []Migration
+
+	// DryRun reports the claims that would be migrated without modifying the store.
+	DryRun bool
+
+	store        claim.Store
+	backingStore *crud.ManagedStore
+}
+
+// NewMigrator creates a Migrator for the claims in the specified store.
+func NewMigrator(store claim.Store) *Migrator {
+	return &Migrator{
+		Migrations:   DefaultMigrations(),
+		store:        store,
+		backingStore: store.GetBackingStore(),
+	}
+}
+
+// Detect returns the schema version of each claim that is not at the current
+// schema version, keyed by the claim ID.
+func (m *Migrator) Detect() (map[string]schema.Version, error) {
+	handleClose, err := m.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	outdated := map[string]schema.Version{}
+	err = m.walkClaims(func(installation string, name string, doc Document) error {
+		if v := doc.SchemaVersion(); v != claim.GetDefaultSchemaVersion() {
+			outdated[name] = v
+		}
+		return nil
+	})
+	return outdated, err
+}
+
+// Migrate upgrades every claim in the store to the current schema version.
+// A claim that cannot be migrated is reported in the Summary and does not
+// stop the remaining claims from being migrated.
+func (m *Migrator) Migrate() (Summary, error) {
+	summary := Summary{Failed: map[string]error{}}
+
+	handleClose, err := m.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return summary, err
+	}
+
+	err = m.walkClaims(func(installation string, name string, doc Document) error {
+		if doc.SchemaVersion() == claim.GetDefaultSchemaVersion() {
+			summary.Current++
+			return nil
+		}
+
+		if err := m.migrateClaim(installation, name, doc); err != nil {
+			summary.Failed[name] = err
+			return nil
+		}
+
+		summary.Migrated = append(summary.Migrated, name)
+		return nil
+	})
+
+	return summary, err
+}
+
+// Err returns an error listing the claims that failed to migrate, or nil.
+func (s Summary) Err() error {
+	var err *multierror.Error
+	for id, failure := range s.Failed {
+		err = multierror.Append(err, errors.Wrapf(failure, "could not migrate claim %s", id))
+	}
+	return err.ErrorOrNil()
+}
+
+// walkClaims calls fn with the document of each claim in the store.
+func (m *Migrator) walkClaims(fn func(installation string, name string, doc Document) error) error {
+	installations, err := m.backingStore.List(claim.ItemTypeClaims, "")
+	if err != nil {
+		return errors.Wrap(err, "could not list installations")
+	}
+
+	for _, installation := range installations {
+		names, err := m.backingStore.List(claim.ItemTypeClaims, installation)
+		if err != nil {
+			return errors.Wrapf(err, "could not list claims for installation %s", installation)
+		}
+
+		for _, name := range names {
+			data, err := m.backingStore.Read(claim.ItemTypeClaims, name)
+			if err != nil {
+				return errors.Wrapf(err, "could not read claim %s", name)
+			}
+
+			var doc Document
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return errors.Wrapf(err, "could not parse claim %s", name)
+			}
+
+			if err := fn(installation, name, doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrateClaim backs up the claim document, applies the migrations and
+// saves the upgraded claim with any data split out of it.
+func (m *Migrator) migrateClaim(installation string, name string, doc Document) error {
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	originalVersion := doc.SchemaVersion()
+
+	var migrated MigratedData
+	for doc.SchemaVersion() != claim.GetDefaultSchemaVersion() {
+		migration, ok := m.findMigration(doc.SchemaVersion())
+		if !ok {
+			return fmt.Errorf("no migration is available for schema version %q", doc.SchemaVersion())
+		}
+
+		data, err := migration.Migrate(doc)
+		if err != nil {
+			return errors.Wrapf(err, "migration from schema version %q to %q failed", migration.From, migration.To)
+		}
+		doc["schemaVersion"] = string(migration.To)
+		migrated.Results = append(migrated.Results, data.Results...)
+		if len(data.Outputs) > 0 {
+			migrated.Outputs = data.Outputs
+		}
+	}
+
+	c, results, err := decodeMigratedClaim(doc, migrated.Results)
+	if err != nil {
+		return err
+	}
+
+	if m.DryRun {
+		return nil
+	}
+
+	if err := m.backingStore.Save(ItemTypeBackups, string(originalVersion), name, original); err != nil {
+		return errors.Wrapf(err, "could not back up claim %s", name)
+	}
+
+	if err := m.store.SaveClaim(c); err != nil {
+		return err
+	}
+	if c.ID != name {
+		if err := m.backingStore.Delete(claim.ItemTypeClaims, name); err != nil {
+			return errors.Wrapf(err, "could not remove claim %s after it was saved as %s", name, c.ID)
+		}
+	}
+
+	for _, r := range results {
+		if err := m.store.SaveResult(r); err != nil {
+			return err
+		}
+	}
+
+	if len(migrated.Outputs) > 0 {
+		if len(results) == 0 {
+			return errors.New("the migrated claim has outputs but no result that generated them")
+		}
+		lastResult := results[len(results)-1]
+		for outputName, value := range migrated.Outputs {
+			if err := m.store.SaveOutput(claim.NewOutput(c, lastResult, outputName, value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findMigration returns the migration that upgrades the specified schema version.
+func (m *Migrator) findMigration(from schema.Version) (Migration, bool) {
+	for _, migration := range m.Migrations {
+		if migration.From == from {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// decodeMigratedClaim converts the migrated documents into a validated
+// claim and its results.
+func decodeMigratedClaim(doc Document, resultDocs []Document) (claim.Claim, []claim.Result, error) {
+	var c claim.Claim
+	if err := convertDocument(doc, &c); err != nil {
+		return claim.Claim{}, nil, errors.Wrap(err, "the migrated claim is invalid")
+	}
+	if err := c.Validate(); err != nil {
+		return claim.Claim{}, nil, errors.Wrap(err, "the migrated claim is invalid")
+	}
+
+	results := make([]claim.Result, 0, len(resultDocs))
+	for _, resultDoc := range resultDocs {
+		var r claim.Result
+		if err := convertDocument(resultDoc, &r); err != nil {
+			return claim.Claim{}, nil, errors.Wrap(err, "the migrated result is invalid")
+		}
+		if err := r.Validate(); err != nil {
+			return claim.Claim{}, nil, errors.Wrapf(err, "the migrated result %s is invalid", r.ID)
+		}
+		results = append(results, r)
+	}
+
+	return c, results, nil
+}
+
+// convertDocument converts an untyped document into its typed representation.
+func convertDocument(doc Document, v interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/schema"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+const legacyClaim = `{
+  "installation": "mysql",
+  "revision": "01DDY0MT091KBZ8W0J1ERKY1BN",
+  "created": "2019-06-12T19:31:07.000Z",
+  "modified": "2019-06-12T19:35:10.000Z",
+  "bundle": {"name": "mysql", "version": "0.1.0"},
+  "result": {"message": "installed", "action": "install", "status": "success"},
+  "parameters": {"port": 3306},
+  "outputs": {"connstr": "mysql://localhost:3306"}
+}`
+
+func saveDocument(t *testing.T, store claim.Store, installation string, name string, doc string) {
+	require.NoError(t, store.GetBackingStore().Save(claim.ItemTypeClaims, installation, name, []byte(doc)))
+}
+
+func TestMigrator_Migrate(t *testing.T) {
+	store := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+	saveDocument(t, store, "mysql", "mysql", legacyClaim)
+
+	current, err := claim.New("wordpress", claim.ActionInstall, bundle.Bundle{Name: "wordpress"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(current))
+
+	m := NewMigrator(store)
+	outdated, err := m.Detect()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]schema.Version{"mysql": ""}, outdated)
+
+	summary, err := m.Migrate()
+	require.NoError(t, err)
+	require.NoError(t, summary.Err())
+	assert.Equal(t, []string{"mysql"}, summary.Migrated)
+	assert.Equal(t, 1, summary.Current)
+
+	i, err := store.ReadInstallation("mysql")
+	require.NoError(t, err)
+	require.Len(t, i.Claims, 1)
+	c := i.Claims[0]
+	assert.Equal(t, claim.GetDefaultSchemaVersion(), c.SchemaVersion)
+	assert.Equal(t, "01DDY0MT091KBZ8W0J1ERKY1BN", c.Revision)
+	assert.Equal(t, claim.ActionInstall, c.Action)
+	assert.Equal(t, "mysql", c.Bundle.Name)
+	assert.Equal(t, map[string]interface{}{"port": float64(3306)}, c.Parameters)
+
+	r, err := c.GetLastResult()
+	require.NoError(t, err)
+	assert.Equal(t, claim.StatusSucceeded, r.Status)
+	assert.Equal(t, "installed", r.Message)
+	assert.Equal(t, "2019-06-12T19:35:10Z", r.Created.UTC().Format("2006-01-02T15:04:05Z07:00"))
+
+	o, err := store.ReadLastOutput("mysql", "connstr")
+	require.NoError(t, err)
+	assert.Equal(t, "mysql://localhost:3306", string(o.Value))
+
+	backup, err := store.GetBackingStore().Read(ItemTypeBackups, "mysql")
+	require.NoError(t, err)
+	assert.JSONEq(t, legacyClaim, string(backup))
+
+	_, err = store.GetBackingStore().Read(claim.ItemTypeClaims, "mysql")
+	assert.Error(t, err, "the claim keyed by the installation name should be removed")
+
+	outdated, err = m.Detect()
+	require.NoError(t, err)
+	assert.Empty(t, outdated, "all claims should be at the current schema version")
+}
+
+func TestMigrator_DryRun(t *testing.T) {
+	store := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+	saveDocument(t, store, "mysql", "mysql", legacyClaim)
+
+	m := NewMigrator(store)
+	m.DryRun = true
+	summary, err := m.Migrate()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, summary.Migrated)
+
+	data, err := store.GetBackingStore().Read(claim.ItemTypeClaims, "mysql")
+	require.NoError(t, err)
+	assert.JSONEq(t, legacyClaim, string(data), "the claim should not be modified during a dry run")
+}
+
+func TestMigrator_Failures(t *testing.T) {
+	store := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+	saveDocument(t, store, "mysql", "mysql", legacyClaim)
+	saveDocument(t, store, "future", "future", `{"schemaVersion": "2.0.0", "id": "future"}`)
+
+	m := NewMigrator(store)
+	m.Migrations = append(m.Migrations, Migration{
+		From: "0.9.0",
+		To:   claim.GetDefaultSchemaVersion(),
+		Migrate: func(c Document) (MigratedData, error) {
+			return MigratedData{}, errors.New("boom")
+		},
+	})
+	saveDocument(t, store, "broken", "broken", `{"schemaVersion": "0.9.0", "id": "broken"}`)
+
+	summary, err := m.Migrate()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, summary.Migrated)
+	require.Len(t, summary.Failed, 2)
+	assert.EqualError(t, summary.Failed["future"], `no migration is available for schema version "2.0.0"`)
+	assert.EqualError(t, summary.Failed["broken"], `migration from schema version "0.9.0" to "1.0.0-DRAFT+b5ed2f3" failed: boom`)
+	assert.Error(t, summary.Err())
+
+	_, err = store.GetBackingStore().Read(ItemTypeBackups, "broken")
+	assert.Error(t, err, "claims that failed to migrate should not be backed up")
+}
+
+func TestMigrateLegacyClaim_Name(t *testing.T) {
+	var c Document
+	require.NoError(t, json.Unmarshal([]byte(`{"name": "mysql", "created": "2019-06-12T19:31:07Z"}`), &c))
+
+	data, err := migrateLegacyClaim(c)
+	require.NoError(t, err)
+	assert.Empty(t, data.Results)
+	assert.Equal(t, "mysql", c["installation"])
+	assert.NotContains(t, c, "name")
+	assert.NotEmpty(t, c["id"])
+	assert.Equal(t, claim.ActionUnknown, c["action"])
+}
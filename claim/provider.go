@@ -0,0 +1,150 @@
+package claim
+
+import (
+	"time"
+)
+
+// Provider is an interface for interacting with claim data.
+type Provider interface {
+	// ListInstallations returns the names of all installations.
+	ListInstallations() ([]string, error)
+
+	// ListInstallationsBySelector returns the names of the installations
+	// whose labels match the label selector, for example
+	// "environment=prod,team=payments".
+	ListInstallationsBySelector(selector string) ([]string, error)
+
+	// ListClaims returns the IDs of the claims associated with an installation.
+	ListClaims(installation string) ([]string, error)
+
+	// ListResults returns the IDs of the results associated with a claim.
+	ListResults(claimID string) ([]string, error)
+
+	// ListOutputs returns the names of the outputs associated with a result.
+	ListOutputs(resultID string) ([]string, error)
+
+	// ReadInstallation returns the specified installation with its claims
+	// and results loaded. Its outputs are loaded on demand.
+	ReadInstallation(installation string) (Installation, error)
+
+	// ReadInstallationStatus returns the specified installation with its
+	// claims and the last result of the most recent claim loaded. Its
+	// outputs are loaded on demand.
+	ReadInstallationStatus(installation string) (Installation, error)
+
+	// ReadAllInstallationStatus returns every installation with its claims
+	// and the last result of the most recent claim loaded.
+	ReadAllInstallationStatus() ([]Installation, error)
+
+	// ReadClaim returns the specified claim.
+	ReadClaim(claimID string) (Claim, error)
+
+	// ReadClaimWithRevision returns the specified claim and the revision of
+	// its record, for use with SaveClaimIfRevision.
+	ReadClaimWithRevision(claimID string) (Claim, string, error)
+
+	// ReadAllClaims returns all claims associated with an installation, sorted
+	// in ascending order by their creation.
+	ReadAllClaims(installation string) ([]Claim, error)
+
+	// QueryClaims returns a page of the claims matching the query, for
+	// example the failed upgrades of an installation in the last week.
+	QueryClaims(query ClaimQuery) (ClaimPage, error)
+
+	// ListClaimsByIndex returns the IDs of the claims whose index, such as
+	// IndexBundleName or IndexStatus, has the value.
+	ListClaimsByIndex(index string, value string) ([]string, error)
+
+	// ReadLastClaim returns the most recent claim for an installation.
+	ReadLastClaim(installation string) (Claim, error)
+
+	// ReadResult returns the specified result.
+	ReadResult(resultID string) (Result, error)
+
+	// ReadResultWithRevision returns the specified result and the revision of
+	// its record, for use with SaveResultIfRevision.
+	ReadResultWithRevision(resultID string) (Result, string, error)
+
+	// ReadAllResults returns all results associated with a claim, sorted in
+	// ascending order by their creation.
+	ReadAllResults(claimID string) ([]Result, error)
+
+	// ReadLastResult returns the most recent result for a claim.
+	ReadLastResult(claimID string) (Result, error)
+
+	// ReadLastOutputs returns the most recent (last) value of each output
+	// associated with an installation.
+	ReadLastOutputs(installation string) (Outputs, error)
+
+	// ReadLastOutput returns the most recent value of an output associated
+	// with an installation.
+	ReadLastOutput(installation string, name string) (Output, error)
+
+	// ReadOutputHistory returns the value of an output generated by each
+	// result of an installation, oldest first, with only the digests of
+	// sensitive outputs.
+	ReadOutputHistory(installation string, outputName string) ([]OutputHistoryEntry, error)
+
+	// ReadOutput returns the value of an output generated by a result.
+	ReadOutput(c Claim, r Result, outputName string) (Output, error)
+
+	// ReadOutputOrDefault returns the value of an output generated by a
+	// result, falling back to the default value of the output's definition
+	// when the output was not persisted.
+	ReadOutputOrDefault(c Claim, r Result, outputName string) (Output, error)
+
+	// SaveClaim persists the specified claim.
+	// Associated results and outputs are not persisted.
+	SaveClaim(c Claim) error
+
+	// SaveClaimIfRevision persists the specified claim when its stored record
+	// has the revision, returning the new revision, or an error wrapping
+	// ErrConflict when the claim was modified since it was read.
+	SaveClaimIfRevision(c Claim, revision string) (string, error)
+
+	// SaveResult persists the specified result.
+	SaveResult(r Result) error
+
+	// SaveResultIfRevision persists the specified result when its stored
+	// record has the revision, returning the new revision, or an error
+	// wrapping ErrConflict when the result was modified since it was read.
+	SaveResultIfRevision(r Result, revision string) (string, error)
+
+	// SaveOutput persists the output.
+	SaveOutput(o Output) error
+
+	// AcquireInstallationLock locks the installation for the owner until the
+	// lease expires, or renews the lease when the owner already holds the lock.
+	// An error matching ErrInstallationLocked is returned when another owner
+	// holds the lock.
+	AcquireInstallationLock(installation string, owner string, lease time.Duration) (InstallationLock, error)
+
+	// ReleaseInstallationLock releases a lock acquired with
+	// AcquireInstallationLock.
+	ReleaseInstallationLock(lock InstallationLock) error
+
+	// ReadInstallationLock returns the lock on the installation, which is
+	// zero when the installation is not locked.
+	ReadInstallationLock(installation string) (InstallationLock, error)
+
+	// DeleteInstallation removes all data associated with an installation.
+	DeleteInstallation(installation string) error
+
+	// DeleteInstallationWithReport removes all data associated with an
+	// installation, continuing after a record cannot be deleted, and reports
+	// what was deleted and what could not be deleted.
+	DeleteInstallationWithReport(installation string) (DeletionReport, error)
+
+	// DeleteClaim removes a claim and its associated results and outputs.
+	DeleteClaim(claimID string) error
+
+	// DeleteResult removes a result and its associated outputs.
+	DeleteResult(resultID string) error
+
+	// DeleteOutput removes an output generated by a result.
+	DeleteOutput(resultID string, outputName string) error
+
+	// Prune removes claims, and their results and outputs, for an
+	// installation that are not retained by the specified policy.
+	Prune(installation string, policy RetentionPolicy) ([]string, error)
+}
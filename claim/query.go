@@ -0,0 +1,118 @@
+package claim
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SortDirection is the order in which claims are returned by a query.
+type SortDirection int
+
+const (
+	// SortAscending returns the oldest claims first.
+	SortAscending SortDirection = iota
+
+	// SortDescending returns the most recent claims first.
+	SortDescending
+)
+
+// ClaimQuery selects a page of claims. The zero value selects every claim,
+// oldest first.
+type ClaimQuery struct {
+	// Installation limits the query to the claims of an installation. When
+	// empty, the claims of every installation are queried.
+	Installation string
+
+	// Actions limits the query to claims for any of the actions.
+	Actions []string
+
+	// Statuses limits the query to claims whose last result has any of the
+	// statuses. Claims without results have the status StatusUnknown.
+	Statuses []string
+
+	// Since limits the query to claims created at or after the time.
+	Since time.Time
+
+	// Before limits the query to claims created before the time.
+	Before time.Time
+
+	// Direction of the sort order of the claims, which are ordered by ID and
+	// therefore by when they were created.
+	Direction SortDirection
+
+	// Cursor continues a previous query after the claim with this ID, using
+	// the NextCursor of the previous page.
+	Cursor string
+
+	// Offset is the number of matching claims to skip, after the Cursor.
+	Offset int
+
+	// Limit is the maximum number of claims to return. When zero, every
+	// matching claim is returned.
+	Limit int
+}
+
+// Validate the query.
+func (q ClaimQuery) Validate() error {
+	if q.Limit < 0 {
+		return errors.Errorf("invalid limit %d, it must not be negative", q.Limit)
+	}
+	if q.Offset < 0 {
+		return errors.Errorf("invalid offset %d, it must not be negative", q.Offset)
+	}
+	if q.Direction != SortAscending && q.Direction != SortDescending {
+		return errors.Errorf("invalid sort direction %d", q.Direction)
+	}
+	return nil
+}
+
+// ClaimPage is a page of claims returned by a query.
+type ClaimPage struct {
+	// Claims matching the query, in the requested order. When the query has
+	// a status filter, the last result of each claim is loaded.
+	Claims []Claim
+
+	// NextCursor is set when there are more matching claims, and is used as
+	// the Cursor of the query for the next page.
+	NextCursor string
+}
+
+// afterCursor determines if the claim comes after the cursor in the sort order.
+func (q ClaimQuery) afterCursor(claimID string) bool {
+	if q.Cursor == "" {
+		return true
+	}
+	if q.Direction == SortDescending {
+		return claimID < q.Cursor
+	}
+	return claimID > q.Cursor
+}
+
+// matches determines if the claim matches the filters of the query. The
+// status filter is handled by the store because it requires the claim's
+// results.
+func (q ClaimQuery) matches(c Claim) bool {
+	if q.Installation != "" && c.Installation != q.Installation {
+		return false
+	}
+	if len(q.Actions) > 0 && !containsString(q.Actions, c.Action) {
+		return false
+	}
+	if !q.Since.IsZero() && c.Created.Before(q.Since) {
+		return false
+	}
+	if !q.Before.IsZero() && !c.Created.Before(q.Before) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
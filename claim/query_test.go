@@ -0,0 +1,113 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func claimIDs(claims []Claim) []string {
+	ids := make([]string, len(claims))
+	for i, c := range claims {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestStore_QueryClaims(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	install, _ := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	upgrade, _ := generateClaimData(t, store, "mysql", ActionUpgrade, StatusFailed)
+	wordpress, _ := generateClaimData(t, store, "wordpress", ActionInstall, StatusFailed)
+	uninstall, _ := generateClaimData(t, store, "mysql", ActionUninstall, StatusSucceeded)
+
+	testcases := []struct {
+		name       string
+		query      ClaimQuery
+		want       []string
+		nextCursor string
+	}{
+		{
+			name:  "all claims",
+			query: ClaimQuery{},
+			want:  []string{install.ID, upgrade.ID, wordpress.ID, uninstall.ID},
+		},
+		{
+			name:  "installation",
+			query: ClaimQuery{Installation: "mysql"},
+			want:  []string{install.ID, upgrade.ID, uninstall.ID},
+		},
+		{
+			name:  "descending",
+			query: ClaimQuery{Installation: "mysql", Direction: SortDescending},
+			want:  []string{uninstall.ID, upgrade.ID, install.ID},
+		},
+		{
+			name:  "actions",
+			query: ClaimQuery{Actions: []string{ActionInstall, ActionUninstall}},
+			want:  []string{install.ID, wordpress.ID, uninstall.ID},
+		},
+		{
+			name:  "statuses",
+			query: ClaimQuery{Statuses: []string{StatusFailed}},
+			want:  []string{upgrade.ID, wordpress.ID},
+		},
+		{
+			name:  "time range",
+			query: ClaimQuery{Since: upgrade.Created, Before: uninstall.Created},
+			want:  []string{upgrade.ID, wordpress.ID},
+		},
+		{
+			name:       "limit",
+			query:      ClaimQuery{Limit: 2},
+			want:       []string{install.ID, upgrade.ID},
+			nextCursor: upgrade.ID,
+		},
+		{
+			name:  "cursor",
+			query: ClaimQuery{Limit: 2, Cursor: upgrade.ID},
+			want:  []string{wordpress.ID, uninstall.ID},
+		},
+		{
+			name:       "descending cursor",
+			query:      ClaimQuery{Limit: 1, Cursor: wordpress.ID, Direction: SortDescending},
+			want:       []string{upgrade.ID},
+			nextCursor: upgrade.ID,
+		},
+		{
+			name:       "offset",
+			query:      ClaimQuery{Offset: 1, Limit: 1, Statuses: []string{StatusSucceeded, StatusFailed}},
+			want:       []string{upgrade.ID},
+			nextCursor: upgrade.ID,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := store.QueryClaims(tc.query)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, claimIDs(page.Claims))
+			assert.Equal(t, tc.nextCursor, page.NextCursor)
+		})
+	}
+
+	t.Run("loads the last result when filtering by status", func(t *testing.T) {
+		page, err := store.QueryClaims(ClaimQuery{Installation: "wordpress", Statuses: []string{StatusFailed}})
+		require.NoError(t, err)
+		require.Len(t, page.Claims, 1)
+		assert.Equal(t, StatusFailed, page.Claims[0].GetStatus())
+	})
+
+	t.Run("missing installation", func(t *testing.T) {
+		_, err := store.QueryClaims(ClaimQuery{Installation: "missing"})
+		assert.ErrorIs(t, err, ErrInstallationNotFound)
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		_, err := store.QueryClaims(ClaimQuery{Limit: -1})
+		assert.EqualError(t, err, "invalid limit -1, it must not be negative")
+	})
+}
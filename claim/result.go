@@ -1,8 +1,8 @@
 package claim
 
 import (
+	"encoding/json"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -66,6 +66,12 @@ func (r Result) Validate() error {
 		return errors.New("the claimID must be set")
 	}
 
+	if r.Custom != nil {
+		if _, err := json.Marshal(r.Custom); err != nil {
+			return errors.Wrap(err, "the custom data must be serializable to JSON")
+		}
+	}
+
 	switch r.Status {
 	case StatusCanceled, StatusFailed, StatusPending, StatusRunning, StatusSucceeded, StatusUnknown:
 		return nil
@@ -135,17 +141,12 @@ func (o *OutputMetadata) SetMetadata(outputName string, metadataKey string, valu
 
 // GetGeneratedByBundle flag for the specified output.
 func (o *OutputMetadata) GetGeneratedByBundle(outputName string) (bool, bool) {
-	if generatedByBundleS, ok := o.GetMetadata(outputName, OutputGeneratedByBundle); ok {
-		generatedByBundle, err := strconv.ParseBool(generatedByBundleS)
-		return generatedByBundle, err == nil
-	}
-
-	return false, false
+	return o.GetBoolMetadata(outputName, OutputGeneratedByBundle)
 }
 
 // SetGeneratedByBundle for the specified output.
 func (o *OutputMetadata) SetGeneratedByBundle(outputName string, generatedByBundle bool) error {
-	return o.SetMetadata(outputName, OutputGeneratedByBundle, strconv.FormatBool(generatedByBundle))
+	return o.SetBoolMetadata(outputName, OutputGeneratedByBundle, generatedByBundle)
 }
 
 // GetContentDigest for the specified output.
@@ -157,3 +158,13 @@ func (o *OutputMetadata) GetContentDigest(outputName string) (string, bool) {
 func (o *OutputMetadata) SetContentDigest(outputName string, contentDigest string) error {
 	return o.SetMetadata(outputName, OutputContentDigest, contentDigest)
 }
+
+// GetCompression for the specified output.
+func (o *OutputMetadata) GetCompression(outputName string) (string, bool) {
+	return o.GetMetadata(outputName, OutputCompression)
+}
+
+// SetCompression for the specified output.
+func (o *OutputMetadata) SetCompression(outputName string, compression string) error {
+	return o.SetMetadata(outputName, OutputCompression, compression)
+}
@@ -0,0 +1,104 @@
+package claim
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy determines which claims of an installation are kept when
+// the installation is pruned. A claim is retained when any of the rules
+// apply to it. The most recent claim of an installation is always retained.
+type RetentionPolicy struct {
+	// KeepLast retains the specified number of most recent claims.
+	KeepLast int
+
+	// KeepNewerThan retains claims created after the specified time.
+	KeepNewerThan time.Time
+
+	// KeepLastSuccessfulInstall retains the most recent install claim whose
+	// last result succeeded.
+	KeepLastSuccessfulInstall bool
+}
+
+// Validate the RetentionPolicy.
+func (p RetentionPolicy) Validate() error {
+	if p.KeepLast < 0 {
+		return fmt.Errorf("invalid retention policy: KeepLast must not be negative, got %d", p.KeepLast)
+	}
+
+	if p.KeepLast == 0 && p.KeepNewerThan.IsZero() && !p.KeepLastSuccessfulInstall {
+		return errors.New("invalid retention policy: at least one retention rule must be set")
+	}
+
+	return nil
+}
+
+// Prune deletes the claims of an installation that are not retained by the
+// policy, cascading to their results and outputs. The IDs of the deleted
+// claims are returned.
+func (s Store) Prune(installation string, policy RetentionPolicy) ([]string, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := s.ReadInstallation(installation)
+	if err != nil {
+		return nil, err
+	}
+
+	retained := policy.retainedClaims(i)
+	pruned := make([]string, 0, len(i.Claims))
+	for _, c := range i.Claims {
+		if _, ok := retained[c.ID]; ok {
+			continue
+		}
+
+		if err := s.DeleteClaim(c.ID); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, c.ID)
+	}
+
+	return pruned, nil
+}
+
+// retainedClaims returns the set of IDs for the installation's claims that are
+// retained by the policy. The installation's claims must be sorted and have
+// their results loaded.
+func (p RetentionPolicy) retainedClaims(i Installation) map[string]struct{} {
+	retained := make(map[string]struct{}, len(i.Claims))
+	if len(i.Claims) == 0 {
+		return retained
+	}
+
+	// Always keep the most recent claim so that the installation's current state is preserved
+	retained[i.Claims[len(i.Claims)-1].ID] = struct{}{}
+
+	lastSuccessfulInstall := ""
+	for idx, c := range i.Claims {
+		if p.KeepLast > 0 && idx >= len(i.Claims)-p.KeepLast {
+			retained[c.ID] = struct{}{}
+		}
+
+		if !p.KeepNewerThan.IsZero() && c.Created.After(p.KeepNewerThan) {
+			retained[c.ID] = struct{}{}
+		}
+
+		if c.Action == ActionInstall && c.GetStatus() == StatusSucceeded {
+			lastSuccessfulInstall = c.ID
+		}
+	}
+
+	if p.KeepLastSuccessfulInstall && lastSuccessfulInstall != "" {
+		retained[lastSuccessfulInstall] = struct{}{}
+	}
+
+	return retained
+}
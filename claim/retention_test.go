@@ -0,0 +1,128 @@
+package claim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestRetentionPolicy_Validate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		policy  RetentionPolicy
+		wantErr string
+	}{
+		{name: "keep last", policy: RetentionPolicy{KeepLast: 2}},
+		{name: "keep newer than", policy: RetentionPolicy{KeepNewerThan: time.Now()}},
+		{name: "keep last successful install", policy: RetentionPolicy{KeepLastSuccessfulInstall: true}},
+		{name: "empty", policy: RetentionPolicy{}, wantErr: "at least one retention rule must be set"},
+		{name: "negative", policy: RetentionPolicy{KeepLast: -1}, wantErr: "KeepLast must not be negative"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	seed := func(t *testing.T) (Store, []Claim, []Result) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+		var claims []Claim
+		var results []Result
+		for _, step := range []struct{ action, status string }{
+			{ActionInstall, StatusFailed},
+			{ActionInstall, StatusSucceeded},
+			{ActionUpgrade, StatusSucceeded},
+			{ActionUpgrade, StatusFailed},
+			{ActionUpgrade, StatusSucceeded},
+		} {
+			c, r := generateClaimData(t, store, "mysql", step.action, step.status)
+			claims = append(claims, c)
+			results = append(results, r)
+		}
+		return store, claims, results
+	}
+
+	ids := func(claims ...Claim) []string {
+		result := make([]string, len(claims))
+		for i, c := range claims {
+			result[i] = c.ID
+		}
+		return result
+	}
+
+	t.Run("keep last", func(t *testing.T) {
+		store, claims, results := seed(t)
+
+		pruned, err := store.Prune("mysql", RetentionPolicy{KeepLast: 2})
+		require.NoError(t, err)
+		assert.Equal(t, ids(claims[0], claims[1], claims[2]), pruned)
+
+		remaining, err := store.ListClaims("mysql")
+		require.NoError(t, err)
+		assert.Equal(t, ids(claims[3], claims[4]), remaining)
+
+		_, err = store.ReadResult(results[0].ID)
+		assert.ErrorIs(t, err, ErrResultNotFound, "results of pruned claims should be deleted")
+		_, err = store.ReadOutput(claims[0], results[0], "host")
+		assert.ErrorIs(t, err, ErrOutputNotFound, "outputs of pruned claims should be deleted")
+	})
+
+	t.Run("keep newer than", func(t *testing.T) {
+		store, claims, _ := seed(t)
+
+		pruned, err := store.Prune("mysql", RetentionPolicy{KeepNewerThan: claims[2].Created})
+		require.NoError(t, err)
+		assert.Equal(t, ids(claims[0], claims[1], claims[2]), pruned)
+	})
+
+	t.Run("keep last successful install", func(t *testing.T) {
+		store, claims, _ := seed(t)
+
+		pruned, err := store.Prune("mysql", RetentionPolicy{KeepLast: 1, KeepLastSuccessfulInstall: true})
+		require.NoError(t, err)
+		assert.Equal(t, ids(claims[0], claims[2], claims[3]), pruned)
+
+		remaining, err := store.ListClaims("mysql")
+		require.NoError(t, err)
+		assert.Equal(t, ids(claims[1], claims[4]), remaining)
+	})
+
+	t.Run("always keep the last claim", func(t *testing.T) {
+		store, claims, _ := seed(t)
+
+		pruned, err := store.Prune("mysql", RetentionPolicy{KeepNewerThan: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, ids(claims[:4]...), pruned)
+
+		remaining, err := store.ListClaims("mysql")
+		require.NoError(t, err)
+		assert.Equal(t, ids(claims[4]), remaining)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		store, _, _ := seed(t)
+
+		_, err := store.Prune("mysql", RetentionPolicy{})
+		require.Error(t, err)
+	})
+
+	t.Run("missing installation", func(t *testing.T) {
+		store := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+		_, err := store.Prune("missing", RetentionPolicy{KeepLast: 1})
+		assert.ErrorIs(t, err, ErrInstallationNotFound)
+	})
+}
@@ -0,0 +1,139 @@
+package claim
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+// ScrubOptions control which values are replaced with their digests when
+// claims data is scrubbed.
+type ScrubOptions struct {
+	// KeepNonSensitive keeps the values of parameters and outputs that are
+	// not sensitive, instead of replacing every value. The invocation image
+	// logs are always scrubbed because the bundle may print sensitive values.
+	KeepNonSensitive bool
+}
+
+// Diagnostics is an export of the claims data of an installation that is safe
+// to share, for example with support. Parameter values, outputs and logs are
+// replaced with their digests, so that values can be compared between claims
+// without being revealed.
+type Diagnostics struct {
+	// Installation name.
+	Installation string `json:"installation"`
+
+	// Claims of the installation, oldest first, with their parameters scrubbed.
+	Claims []Claim `json:"claims"`
+
+	// Results of the installation's claims.
+	Results []Result `json:"results"`
+
+	// Outputs generated by the results, with their values scrubbed.
+	Outputs []ScrubbedOutput `json:"outputs,omitempty"`
+}
+
+// ScrubbedOutput is an output whose value was scrubbed.
+type ScrubbedOutput struct {
+	// ResultID of the result that generated the output.
+	ResultID string `json:"resultId"`
+
+	// Name of the output.
+	Name string `json:"name"`
+
+	// Value of the output, or its digest when scrubbed.
+	Value string `json:"value"`
+}
+
+// ExportDiagnostics exports the claims, results and outputs of an
+// installation with their values scrubbed.
+func (s Store) ExportDiagnostics(installation string, opts ScrubOptions) (Diagnostics, error) {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return Diagnostics{}, err
+	}
+
+	claims, err := s.ReadAllClaims(installation)
+	if err != nil {
+		return Diagnostics{}, err
+	}
+
+	d := Diagnostics{
+		Installation: installation,
+		Claims:       make([]Claim, 0, len(claims)),
+	}
+	for _, c := range claims {
+		d.Claims = append(d.Claims, ScrubClaim(c, opts))
+
+		results, err := s.ReadAllResults(c.ID)
+		if err != nil {
+			return Diagnostics{}, err
+		}
+		d.Results = append(d.Results, results...)
+
+		for _, r := range results {
+			outputNames, err := s.ListOutputs(r.ID)
+			if err != nil {
+				return Diagnostics{}, err
+			}
+
+			for _, name := range outputNames {
+				o, err := s.ReadOutput(c, r, name)
+				if err != nil {
+					return Diagnostics{}, err
+				}
+				o = ScrubOutput(o, opts)
+				d.Outputs = append(d.Outputs, ScrubbedOutput{ResultID: r.ID, Name: o.Name, Value: string(o.Value)})
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// ScrubClaim returns a copy of the claim with its parameter values replaced
+// with their digests.
+func ScrubClaim(c Claim, opts ScrubOptions) Claim {
+	if len(c.Parameters) == 0 {
+		return c
+	}
+
+	params := make(map[string]interface{}, len(c.Parameters))
+	for name, value := range c.Parameters {
+		if opts.KeepNonSensitive {
+			if sensitive, err := c.Bundle.IsParameterSensitive(name); err == nil && !sensitive {
+				params[name] = value
+				continue
+			}
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			data = []byte(fmt.Sprintf("%v", value))
+		}
+		params[name] = scrubbedDigest(data)
+	}
+
+	c.Parameters = params
+	return c
+}
+
+// ScrubOutput returns a copy of the output with its value replaced with its
+// digest.
+func ScrubOutput(o Output, opts ScrubOptions) Output {
+	if opts.KeepNonSensitive && o.Name != OutputInvocationImageLogs {
+		if sensitive, err := o.claim.Bundle.IsOutputSensitive(o.Name); err == nil && !sensitive {
+			return o
+		}
+	}
+
+	o.Value = []byte(scrubbedDigest(o.Value))
+	return o
+}
+
+// scrubbedDigest returns the digest that replaces a scrubbed value, in the
+// same format as the content digest of an output.
+func scrubbedDigest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
@@ -0,0 +1,73 @@
+package claim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_ExportDiagnostics(t *testing.T) {
+	b := claimStoreBundle
+	b.Parameters = map[string]bundle.Parameter{
+		"password": {Definition: "password"},
+		"host":     {Definition: "string"},
+	}
+
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	c, err := New("mysql", ActionInstall, b, map[string]interface{}{"password": "topsecret", "host": "localhost"})
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+	r, err := c.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "host", []byte("localhost"))))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "password", []byte("topsecret"))))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, OutputInvocationImageLogs, []byte("connecting to localhost with topsecret"))))
+
+	testcases := []struct {
+		name      string
+		opts      ScrubOptions
+		wantHost  string
+		wantParam interface{}
+	}{
+		{name: "scrub everything", wantHost: scrubbedDigest([]byte("localhost")), wantParam: scrubbedDigest([]byte(`"localhost"`))},
+		{name: "keep non-sensitive", opts: ScrubOptions{KeepNonSensitive: true}, wantHost: "localhost", wantParam: "localhost"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := store.ExportDiagnostics("mysql", tc.opts)
+			require.NoError(t, err)
+
+			require.Len(t, d.Claims, 1)
+			require.Len(t, d.Results, 1)
+			assert.Equal(t, r.ID, d.Results[0].ID)
+			assert.Equal(t, tc.wantParam, d.Claims[0].Parameters["host"])
+
+			outputs := map[string]string{}
+			for _, o := range d.Outputs {
+				assert.Equal(t, r.ID, o.ResultID)
+				outputs[o.Name] = o.Value
+			}
+			assert.Equal(t, tc.wantHost, outputs["host"])
+			assert.Equal(t, scrubbedDigest([]byte("topsecret")), outputs["password"])
+			assert.Equal(t, scrubbedDigest([]byte("connecting to localhost with topsecret")), outputs[OutputInvocationImageLogs], "logs should always be scrubbed")
+
+			data, err := json.Marshal(d)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "topsecret", "sensitive values should not be exported")
+		})
+	}
+}
+
+func TestScrubClaim_DoesNotModifyClaim(t *testing.T) {
+	c := Claim{Parameters: map[string]interface{}{"host": "localhost"}}
+	scrubbed := ScrubClaim(c, ScrubOptions{})
+	assert.Equal(t, "localhost", c.Parameters["host"])
+	assert.Equal(t, scrubbedDigest([]byte(`"localhost"`)), scrubbed.Parameters["host"])
+}
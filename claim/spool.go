@@ -0,0 +1,329 @@
+package claim
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// ItemTypeSpool is the item type of the entries of a Spool in its store.
+const ItemTypeSpool = "spool"
+
+// Default retries of a Spool.
+const (
+	DefaultSpoolMaxAttempts = 3
+	DefaultSpoolBackoff     = time.Second
+)
+
+// SpoolOptions customizes how a Spool retries saving results to its provider.
+type SpoolOptions struct {
+	// MaxAttempts is the number of times a spooled result is saved to the
+	// provider before giving up, until the spool is flushed again. Defaults
+	// to DefaultSpoolMaxAttempts.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, which is doubled for each
+	// following retry. Defaults to DefaultSpoolBackoff.
+	Backoff time.Duration
+
+	// Keyring encrypts the values of sensitive parameters and outputs
+	// journaled to the spool, like a store created with
+	// NewClaimStoreWithKeyring. Defaults to the encryption of the provider
+	// when it is a Store.
+	Keyring *Keyring
+}
+
+// SpooledError is returned when a result and its outputs were journaled to a
+// Spool but could not be saved to the provider. The result is not lost: it is
+// saved when the spool is flushed. It matches ErrResultSpooled with
+// errors.Is, and the error of the provider with errors.As.
+type SpooledError struct {
+	// ResultID of the spooled result.
+	ResultID string
+
+	// Err is the error of the last attempt to save the result.
+	Err error
+}
+
+func (e SpooledError) Error() string {
+	return fmt.Sprintf("result %s was spooled but could not be saved: %v", e.ResultID, e.Err)
+}
+
+// Is matches ErrResultSpooled.
+func (e SpooledError) Is(target error) bool {
+	return target == ErrResultSpooled
+}
+
+// Unwrap returns the error of the provider.
+func (e SpooledError) Unwrap() error {
+	return e.Err
+}
+
+// Spool journals the results of operations and their outputs to a local
+// store, such as a crud.FileSystemStore, before saving them to a Provider,
+// so that the outcome of an operation is not lost when the provider is
+// unavailable. Results are retried with an exponential backoff, and those
+// that still cannot be saved are kept in the spool until Flush is called,
+// for example when the tool starts again.
+type Spool struct {
+	store    crud.Store
+	provider Provider
+	opts     SpoolOptions
+
+	// encryption encrypts the sensitive values journaled to the spool, or is
+	// nil when the spool has no keyring.
+	encryption *Store
+
+	// sleep waits between retries, and is replaced in tests.
+	sleep func(time.Duration)
+}
+
+// spoolEntry is a result and its outputs journaled to the spool.
+type spoolEntry struct {
+	Claim   Claim             `json:"claim"`
+	Result  Result            `json:"result"`
+	Outputs map[string][]byte `json:"outputs,omitempty"`
+}
+
+// NewSpool creates a Spool journaling results to the store before saving them
+// to the provider.
+//
+// The values of sensitive parameters and outputs are encrypted before they
+// are journaled with the keyring of the options, or else with the encryption
+// of the provider when it is a Store. Without either, the spool cannot
+// protect sensitive values, so results with sensitive values are not
+// journaled and Save returns an error.
+func NewSpool(store crud.Store, provider Provider, opts SpoolOptions) *Spool {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultSpoolMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultSpoolBackoff
+	}
+
+	var encryption *Store
+	if opts.Keyring != nil {
+		encryption = &Store{keyring: *opts.Keyring, encryptParameters: true}
+	} else if s, ok := provider.(Store); ok {
+		encryption = &s
+	}
+
+	return &Spool{
+		store:      store,
+		provider:   provider,
+		opts:       opts,
+		encryption: encryption,
+		sleep:      time.Sleep,
+	}
+}
+
+// Save journals the result of the claim and its outputs to the spool, and
+// then saves them to the provider, retrying when it fails. Once saved, they
+// are removed from the spool. When the result was journaled but could not be
+// saved, a SpooledError is returned and the result remains in the spool until
+// it is flushed.
+func (s *Spool) Save(c Claim, r Result, outputs map[string][]byte) error {
+	if r.ClaimID != c.ID {
+		return errors.Errorf("result %s does not belong to claim %s", r.ID, c.ID)
+	}
+
+	entry := spoolEntry{Claim: c, Result: r, Outputs: outputs}
+	encrypted, err := s.encryptEntry(entry)
+	if err != nil {
+		return errors.Wrapf(err, "error spooling result %s", r.ID)
+	}
+	data, err := json.Marshal(encrypted)
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling result %s", r.ID)
+	}
+	if err := s.store.Save(ItemTypeSpool, c.Installation, r.ID, data); err != nil {
+		return errors.Wrapf(err, "error spooling result %s", r.ID)
+	}
+
+	return s.flush(entry)
+}
+
+// Pending returns the IDs of the results in the spool, which are not saved
+// to the provider yet, from the oldest to the most recent.
+func (s *Spool) Pending() ([]string, error) {
+	installations, err := s.store.List(ItemTypeSpool, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the spooled results")
+	}
+
+	var ids []string
+	for _, installation := range installations {
+		resultIDs, err := s.store.List(ItemTypeSpool, installation)
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing the spooled results")
+		}
+		ids = append(ids, resultIDs...)
+	}
+
+	// Result IDs are ULIDs, which sort in the order that they were created
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Flush saves the results in the spool to the provider, from the oldest to
+// the most recent, with the same retries as Save, and returns how many were
+// saved. The results that could not be saved remain in the spool, and are
+// reported as a SpooledError each.
+func (s *Spool) Flush() (int, error) {
+	ids, err := s.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	var flushed int
+	var result *multierror.Error
+	for _, id := range ids {
+		data, err := s.store.Read(ItemTypeSpool, id)
+		if err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "error reading spooled result %s", id))
+			continue
+		}
+
+		var entry spoolEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "error unmarshaling spooled result %s", id))
+			continue
+		}
+		if entry, err = s.decryptEntry(entry); err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "error decrypting spooled result %s", id))
+			continue
+		}
+
+		if err := s.flush(entry); err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+		flushed++
+	}
+	return flushed, result.ErrorOrNil()
+}
+
+// encryptEntry returns a copy of the entry with the values of its sensitive
+// parameters and outputs encrypted, or an error when it has sensitive values
+// and the spool has no keyring.
+func (s *Spool) encryptEntry(entry spoolEntry) (spoolEntry, error) {
+	if s.encryption == nil {
+		if hasSensitiveValues(entry) {
+			return spoolEntry{}, errors.New("the result has sensitive values and the spool has no keyring to encrypt them")
+		}
+		return entry, nil
+	}
+
+	c, err := s.encryption.encryptSensitiveParameters(entry.Claim)
+	if err != nil {
+		return spoolEntry{}, err
+	}
+	entry.Claim = c
+
+	if len(entry.Outputs) > 0 {
+		outputs := make(map[string][]byte, len(entry.Outputs))
+		for name, value := range entry.Outputs {
+			if s.encryption.isOutputSensitive(c, name) {
+				if value, err = s.encryption.keyring.encryptRecord(c.Installation, value); err != nil {
+					return spoolEntry{}, errors.Wrapf(err, "error encrypting output %s", name)
+				}
+			}
+			outputs[name] = value
+		}
+		entry.Outputs = outputs
+	}
+	return entry, nil
+}
+
+// decryptEntry decrypts the values of the sensitive parameters and outputs of
+// an entry read from the spool.
+func (s *Spool) decryptEntry(entry spoolEntry) (spoolEntry, error) {
+	if s.encryption == nil {
+		return entry, nil
+	}
+
+	c, err := s.encryption.decryptSensitiveParameters(entry.Claim)
+	if err != nil {
+		return spoolEntry{}, err
+	}
+	entry.Claim = c
+
+	for name, value := range entry.Outputs {
+		if s.encryption.isOutputSensitive(c, name) {
+			if entry.Outputs[name], err = s.encryption.keyring.decryptRecord(value); err != nil {
+				return spoolEntry{}, errors.Wrapf(err, "error decrypting output %s", name)
+			}
+		}
+	}
+	return entry, nil
+}
+
+// hasSensitiveValues determines if the entry has the value of a sensitive
+// parameter or output.
+func hasSensitiveValues(entry spoolEntry) bool {
+	for name := range entry.Claim.Parameters {
+		if sensitive, _ := entry.Claim.Bundle.IsParameterSensitive(name); sensitive {
+			return true
+		}
+	}
+	for name := range entry.Outputs {
+		if sensitive, _ := entry.Claim.Bundle.IsOutputSensitive(name); sensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// flush saves the spooled entry to the provider, with retries, and then
+// removes it from the spool.
+func (s *Spool) flush(entry spoolEntry) error {
+	backoff := s.opts.Backoff
+	var err error
+	for attempt := 1; attempt <= s.opts.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			s.sleep(backoff)
+			backoff *= 2
+		}
+
+		if err = s.save(entry); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return SpooledError{ResultID: entry.Result.ID, Err: err}
+	}
+
+	if err := s.store.Delete(ItemTypeSpool, entry.Result.ID); err != nil {
+		return errors.Wrapf(err, "result %s was saved but could not be removed from the spool", entry.Result.ID)
+	}
+	return nil
+}
+
+// save saves the result and then its outputs, sorted by name. Saving them
+// again overwrites them, so an entry can be retried after a partial failure.
+func (s *Spool) save(entry spoolEntry) error {
+	r := entry.Result
+	r.claim = &entry.Claim
+	if err := s.provider.SaveResult(r); err != nil {
+		return errors.Wrapf(err, "error saving result %s", r.ID)
+	}
+
+	names := make([]string, 0, len(entry.Outputs))
+	for name := range entry.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o := NewOutput(entry.Claim, r, name, entry.Outputs[name])
+		if err := s.provider.SaveOutput(o); err != nil {
+			return errors.Wrapf(err, "error saving output %s of result %s", name, r.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package claim
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestSpool(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	provider, err := NewChaosProvider(store, ChaosOptions{})
+	require.NoError(t, err)
+	provider.sleep = func(time.Duration) {}
+
+	spoolDir := t.TempDir()
+	spool := NewSpool(crud.NewFileSystemStore(spoolDir, nil), provider, SpoolOptions{MaxAttempts: 3, Backoff: time.Second})
+	var slept []time.Duration
+	spool.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	c, err := New("mysql", ActionInstall, claimStoreBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+
+	t.Run("saved", func(t *testing.T) {
+		r, err := c.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+		require.NoError(t, spool.Save(c, r, map[string][]byte{"host": []byte("localhost")}))
+
+		o, err := store.ReadOutput(c, r, "host")
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", string(o.Value))
+
+		pending, err := spool.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, pending, "saved results should be removed from the spool")
+		assert.Empty(t, slept, "the result should not be retried")
+	})
+
+	t.Run("spooled", func(t *testing.T) {
+		require.NoError(t, provider.SetOptions(ChaosOptions{WriteFailureRate: 1}))
+		first, err := c.NewResult(StatusFailed)
+		require.NoError(t, err)
+		err = spool.Save(c, first, map[string][]byte{"host": []byte("first")})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrResultSpooled))
+		assert.True(t, errors.Is(err, ErrChaos), "the error of the provider should be wrapped")
+		var spooledErr SpooledError
+		require.True(t, errors.As(err, &spooledErr))
+		assert.Equal(t, first.ID, spooledErr.ResultID)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, slept, "the result should be retried with a backoff")
+
+		second, err := c.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+		require.Error(t, spool.Save(c, second, map[string][]byte{"host": []byte("second")}))
+
+		// The spool survives the process, and is flushed once the provider is back
+		reopened := NewSpool(crud.NewFileSystemStore(spoolDir, nil), provider, SpoolOptions{})
+		pending, err := reopened.Pending()
+		require.NoError(t, err)
+		assert.Equal(t, []string{first.ID, second.ID}, pending)
+
+		reopened.sleep = func(time.Duration) {}
+		n, err := reopened.Flush()
+		assert.True(t, errors.Is(err, ErrResultSpooled))
+		assert.Equal(t, 0, n)
+
+		require.NoError(t, provider.SetOptions(ChaosOptions{}))
+		n, err = reopened.Flush()
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		pending, err = reopened.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		last, err := store.ReadLastResult(c.ID)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, last.ID)
+		o, err := store.ReadOutput(c, first, "host")
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(o.Value))
+	})
+
+	t.Run("result of another claim", func(t *testing.T) {
+		other, err := New("wordpress", ActionInstall, claimStoreBundle, nil)
+		require.NoError(t, err)
+		r, err := other.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+		err = spool.Save(c, r, nil)
+		assert.EqualError(t, err, "result "+r.ID+" does not belong to claim "+c.ID)
+	})
+}
+
+func TestSpool_SensitiveValues(t *testing.T) {
+	b := claimStoreBundle
+	b.Parameters = map[string]bundle.Parameter{"password": {Definition: "password"}}
+	c, err := New("mysql", ActionInstall, b, map[string]interface{}{"password": "topsecret"})
+	require.NoError(t, err)
+
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	require.NoError(t, store.SaveClaim(c))
+	provider, err := NewChaosProvider(store, ChaosOptions{WriteFailureRate: 1})
+	require.NoError(t, err)
+	provider.sleep = func(time.Duration) {}
+	outputs := map[string][]byte{"host": []byte("localhost"), "password": []byte("topsecret-output")}
+
+	t.Run("keyring", func(t *testing.T) {
+		spoolStore := crud.NewMockStore()
+		spool := NewSpool(spoolStore, provider, SpoolOptions{Keyring: &Keyring{
+			CurrentKeyID: "v1",
+			Keys: map[string]EncryptionKey{"v1": {
+				Encrypt: func(data []byte) ([]byte, error) {
+					return []byte(base64.StdEncoding.EncodeToString(data)), nil
+				},
+				Decrypt: func(data []byte) ([]byte, error) {
+					return base64.StdEncoding.DecodeString(string(data))
+				},
+			}},
+		}})
+		spool.sleep = func(time.Duration) {}
+
+		r, err := c.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+		require.NoError(t, provider.SetOptions(ChaosOptions{WriteFailureRate: 1}))
+		err = spool.Save(c, r, outputs)
+		require.True(t, errors.Is(err, ErrResultSpooled), "expected the result to be spooled, got %v", err)
+
+		raw, err := spoolStore.Read(ItemTypeSpool, r.ID)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "topsecret", "sensitive values should be encrypted in the spool")
+		var entry spoolEntry
+		require.NoError(t, json.Unmarshal(raw, &entry))
+		assert.NotContains(t, string(entry.Outputs["password"]), "topsecret", "sensitive outputs should be encrypted in the spool")
+		assert.Equal(t, "localhost", string(entry.Outputs["host"]), "outputs that are not sensitive should not be encrypted")
+		assert.Equal(t, "topsecret", c.Parameters["password"], "the claim passed to Save should not be modified")
+		assert.Equal(t, "topsecret-output", string(outputs["password"]), "the outputs passed to Save should not be modified")
+
+		require.NoError(t, provider.SetOptions(ChaosOptions{}))
+		n, err := spool.Flush()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		o, err := store.ReadOutput(c, r, "password")
+		require.NoError(t, err)
+		assert.Equal(t, "topsecret-output", string(o.Value), "sensitive outputs should be decrypted when the spool is flushed")
+		saved, err := store.ReadResult(r.ID)
+		require.NoError(t, err)
+		assert.Equal(t, r.ID, saved.ID)
+	})
+
+	t.Run("no keyring", func(t *testing.T) {
+		spoolStore := crud.NewMockStore()
+		spool := NewSpool(spoolStore, provider, SpoolOptions{})
+
+		r, err := c.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+		err = spool.Save(c, r, outputs)
+		require.EqualError(t, err, "error spooling result "+r.ID+": the result has sensitive values and the spool has no keyring to encrypt them")
+		assert.False(t, errors.Is(err, ErrResultSpooled))
+
+		count, err := spoolStore.Count(ItemTypeSpool, "")
+		require.NoError(t, err)
+		assert.Zero(t, count, "sensitive values should not be journaled in plain text")
+	})
+}
@@ -0,0 +1,62 @@
+package claim
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ResultRecord is a single line written by Store.StreamResults.
+type ResultRecord struct {
+	// Claim that was executed.
+	Claim Claim `json:"claim"`
+
+	// Result of the claim. Not set when the claim has no results.
+	Result *Result `json:"result,omitempty"`
+}
+
+// StreamResults writes the claims and results of an installation to w as
+// newline-delimited JSON, one ResultRecord per result, ordered by claim and
+// then by result. Output values are not included. Claims are read one at a
+// time, so the installation is never loaded into memory all at once.
+func (s Store) StreamResults(installation string, w io.Writer) error {
+	handleClose, err := s.backingStore.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	claimIDs, err := s.ListClaims(installation)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, claimID := range claimIDs {
+		c, err := s.ReadClaim(claimID)
+		if err != nil {
+			return err
+		}
+
+		results, err := s.ReadAllResults(claimID)
+		if err != nil {
+			return err
+		}
+
+		if len(results) == 0 {
+			if err := encoder.Encode(ResultRecord{Claim: c}); err != nil {
+				return errors.Wrapf(err, "error writing claim %s", claimID)
+			}
+			continue
+		}
+
+		for i := range results {
+			if err := encoder.Encode(ResultRecord{Claim: c, Result: &results[i]}); err != nil {
+				return errors.Wrapf(err, "error writing result %s", results[i].ID)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package claim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_StreamResults(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+	install, installResult := generateClaimData(t, store, "mysql", ActionInstall, StatusSucceeded)
+	upgrade, upgradeRunning := generateClaimData(t, store, "mysql", ActionUpgrade, StatusRunning)
+	upgradeFailed, err := upgrade.NewResult(StatusFailed)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(upgradeFailed))
+	uninstall, err := upgrade.NewClaim(ActionUninstall, upgrade.Bundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(uninstall))
+	generateClaimData(t, store, "wordpress", ActionInstall, StatusSucceeded)
+
+	var buf bytes.Buffer
+	err = store.StreamResults("mysql", &buf)
+	require.NoError(t, err)
+
+	var records []ResultRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record ResultRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), "each line should be a JSON document")
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, records, 4)
+	assert.Equal(t, install.ID, records[0].Claim.ID)
+	assert.Equal(t, installResult.ID, records[0].Result.ID)
+	assert.Equal(t, upgrade.ID, records[1].Claim.ID)
+	assert.Equal(t, upgradeRunning.ID, records[1].Result.ID)
+	assert.Equal(t, upgrade.ID, records[2].Claim.ID)
+	assert.Equal(t, upgradeFailed.ID, records[2].Result.ID)
+	assert.Equal(t, uninstall.ID, records[3].Claim.ID)
+	assert.Nil(t, records[3].Result, "claims without results should be included")
+}
+
+func TestStore_StreamResults_NotFound(t *testing.T) {
+	store := NewClaimStore(crud.NewMockStore(), nil, nil)
+
+	var buf bytes.Buffer
+	err := store.StreamResults("missing", &buf)
+	assert.ErrorIs(t, err, ErrInstallationNotFound)
+	assert.Empty(t, buf.String())
+}
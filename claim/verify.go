@@ -0,0 +1,59 @@
+package claim
+
+import (
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// OutputTamperedError is returned when reading an output whose content does
+// not match the content digest recorded on its result, for example because it
+// was modified or corrupted in storage.
+type OutputTamperedError struct {
+	// ResultID of the result that generated the output.
+	ResultID string
+
+	// Output is the name of the output.
+	Output string
+
+	// Expected is the content digest recorded on the result.
+	Expected string
+
+	// Actual is the content digest of the stored output.
+	Actual string
+}
+
+func (e OutputTamperedError) Error() string {
+	return fmt.Sprintf("output %s of result %s does not match its recorded content digest: expected %s but got %s", e.Output, e.ResultID, e.Expected, e.Actual)
+}
+
+// Is matches ErrOutputTampered.
+func (e OutputTamperedError) Is(target error) bool {
+	return target == ErrOutputTampered
+}
+
+// verifyOutputDigest compares the digest of the output's value with the
+// content digest recorded on the result, when it was recorded.
+func verifyOutputDigest(r Result, outputName string, value []byte) error {
+	recorded, ok := r.OutputMetadata.GetContentDigest(outputName)
+	if !ok || recorded == "" {
+		return nil
+	}
+
+	expected, err := digest.Parse(recorded)
+	if err != nil {
+		return errors.Wrapf(err, "invalid content digest recorded for output %s of result %s", outputName, r.ID)
+	}
+
+	actual := expected.Algorithm().FromBytes(value)
+	if actual != expected {
+		return OutputTamperedError{
+			ResultID: r.ID,
+			Output:   outputName,
+			Expected: expected.String(),
+			Actual:   actual.String(),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package claim
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestStore_VerifyOutputDigests(t *testing.T) {
+	backingStore := crud.NewMockStore()
+	store := NewClaimStore(backingStore, nil, nil)
+	store.SetVerifyOutputDigests(true)
+
+	c, err := New("mysql", ActionInstall, claimStoreBundle, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+
+	r, err := c.NewResult(StatusSucceeded)
+	require.NoError(t, err)
+	r.OutputMetadata.SetContentDigest("host", fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("localhost"))))
+	r.OutputMetadata.SetContentDigest("password", "md5:invalid")
+	require.NoError(t, store.SaveResult(r))
+	require.NoError(t, store.SaveOutput(NewOutput(c, r, "host", []byte("localhost"))))
+
+	t.Run("matching digest", func(t *testing.T) {
+		o, err := store.ReadOutput(c, r, "host")
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", string(o.Value))
+	})
+
+	t.Run("tampered output", func(t *testing.T) {
+		require.NoError(t, backingStore.Save(ItemTypeOutputs, r.ID, r.ID+"-host", []byte("attacker.example.com")))
+		defer backingStore.Save(ItemTypeOutputs, r.ID, r.ID+"-host", []byte("localhost"))
+
+		_, err := store.ReadOutput(c, r, "host")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrOutputTampered), "expected ErrOutputTampered, got %v", err)
+
+		var tampered OutputTamperedError
+		require.True(t, errors.As(err, &tampered), "expected an OutputTamperedError, got %T", err)
+		assert.Equal(t, r.ID, tampered.ResultID)
+		assert.Equal(t, "host", tampered.Output)
+		assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("attacker.example.com"))), tampered.Actual)
+
+		// Outputs are not verified unless strict mode is enabled
+		lenient := NewClaimStore(backingStore, nil, nil)
+		o, err := lenient.ReadOutput(c, r, "host")
+		require.NoError(t, err)
+		assert.Equal(t, "attacker.example.com", string(o.Value))
+	})
+
+	t.Run("invalid recorded digest", func(t *testing.T) {
+		require.NoError(t, store.SaveOutput(NewOutput(c, r, "password", []byte("topsecret"))))
+		_, err := store.ReadOutput(c, r, "password")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid content digest recorded for output password of result "+r.ID)
+	})
+
+	t.Run("no recorded digest", func(t *testing.T) {
+		r2, err := c.NewResult(StatusSucceeded)
+		require.NoError(t, err)
+		require.NoError(t, store.SaveResult(r2))
+		require.NoError(t, store.SaveOutput(NewOutput(c, r2, "host", []byte("localhost"))))
+
+		_, err = store.ReadOutput(c, r2, "host")
+		require.NoError(t, err)
+	})
+}
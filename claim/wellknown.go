@@ -0,0 +1,141 @@
+package claim
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// WellKnownName is a well-known output name or output metadata key, that
+// CNAB tools and extensions agree on.
+type WellKnownName struct {
+	// Name of the output or metadata key.
+	Name string
+
+	// Description of the output or metadata key.
+	Description string
+}
+
+// wellKnownNames is a registry of well-known names.
+type wellKnownNames struct {
+	mu    sync.RWMutex
+	names map[string]string
+}
+
+func (w *wellKnownNames) register(kind string, name string, description string) error {
+	if name == "" {
+		return errors.Errorf("the name of the well-known %s is required", kind)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.names[name]; ok {
+		return errors.Errorf("the well-known %s %s is already registered", kind, name)
+	}
+	w.names[name] = description
+	return nil
+}
+
+func (w *wellKnownNames) isRegistered(name string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	_, ok := w.names[name]
+	return ok
+}
+
+func (w *wellKnownNames) list() []WellKnownName {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	names := make([]WellKnownName, 0, len(w.names))
+	for name, description := range w.names {
+		names = append(names, WellKnownName{Name: name, Description: description})
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Name < names[j].Name })
+	return names
+}
+
+var (
+	wellKnownOutputs = &wellKnownNames{names: map[string]string{
+		OutputInvocationImageLogs: "Logs of the invocation image",
+		OutputEnvironmentSnapshot: "Redacted snapshot of the CNAB environment variables and of the paths of the files injected into the invocation image",
+	}}
+
+	wellKnownOutputMetadataKeys = &wellKnownNames{names: map[string]string{
+		OutputContentDigest:     "Digest of the content of the output, for example sha256:<hex>",
+		OutputGeneratedByBundle: "Whether the output is defined by the bundle and was set by the invocation image (true|false)",
+		OutputCompression:       "Algorithm that the output was compressed with when it was persisted, for example " + CompressionGzip,
+	}}
+)
+
+// RegisterWellKnownOutput registers the name of an output that is generated
+// by a CNAB tool or an extension rather than defined by the bundle, such as
+// OutputInvocationImageLogs, so that other tools recognize it with
+// IsWellKnownOutput. The name must be namespaced with a reverse DNS prefix,
+// for example com.example.outputs.report, and not be registered already.
+func RegisterWellKnownOutput(name string, description string) error {
+	if name != "" && !strings.Contains(name, ".") {
+		return errors.Errorf("the well-known output %s must be namespaced, for example com.example.outputs.%s", name, name)
+	}
+	return wellKnownOutputs.register("output", name, description)
+}
+
+// IsWellKnownOutput returns whether the output name is well-known.
+func IsWellKnownOutput(name string) bool {
+	return wellKnownOutputs.isRegistered(name)
+}
+
+// WellKnownOutputs returns the well-known output names, sorted by name.
+func WellKnownOutputs() []WellKnownName {
+	return wellKnownOutputs.list()
+}
+
+// RegisterOutputMetadataKey registers a key of the output metadata of a
+// result, such as OutputContentDigest, so that other tools recognize it with
+// IsWellKnownOutputMetadataKey. The key must not be registered already.
+func RegisterOutputMetadataKey(key string, description string) error {
+	return wellKnownOutputMetadataKeys.register("output metadata key", key, description)
+}
+
+// IsWellKnownOutputMetadataKey returns whether the output metadata key is
+// well-known.
+func IsWellKnownOutputMetadataKey(key string) bool {
+	return wellKnownOutputMetadataKeys.isRegistered(key)
+}
+
+// WellKnownOutputMetadataKeys returns the well-known output metadata keys,
+// sorted by name.
+func WellKnownOutputMetadataKeys() []WellKnownName {
+	return wellKnownOutputMetadataKeys.list()
+}
+
+// GetWellKnownOutputs returns the names of the well-known outputs of the
+// result, sorted by name.
+func (r Result) GetWellKnownOutputs() []string {
+	var names []string
+	for name := range r.OutputMetadata {
+		if IsWellKnownOutput(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetBoolMetadata returns the boolean value of the metadata key for the
+// specified output, and whether it was set to a valid boolean.
+func (o OutputMetadata) GetBoolMetadata(outputName string, metadataKey string) (bool, bool) {
+	if value, ok := o.GetMetadata(outputName, metadataKey); ok {
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// SetBoolMetadata sets the metadata key for the specified output to the
+// boolean value.
+func (o *OutputMetadata) SetBoolMetadata(outputName string, metadataKey string, value bool) error {
+	return o.SetMetadata(outputName, metadataKey, strconv.FormatBool(value))
+}
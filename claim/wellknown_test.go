@@ -0,0 +1,80 @@
+package claim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWellKnownOutputs(t *testing.T) {
+	assert.True(t, IsWellKnownOutput(OutputInvocationImageLogs))
+	assert.True(t, IsWellKnownOutput(OutputEnvironmentSnapshot))
+	assert.False(t, IsWellKnownOutput("com.example.outputs.report"))
+
+	require.NoError(t, RegisterWellKnownOutput("com.example.outputs.report", "Report of the operation"))
+	defer func() {
+		wellKnownOutputs.mu.Lock()
+		delete(wellKnownOutputs.names, "com.example.outputs.report")
+		wellKnownOutputs.mu.Unlock()
+	}()
+	assert.True(t, IsWellKnownOutput("com.example.outputs.report"))
+	assert.Contains(t, WellKnownOutputs(), WellKnownName{Name: "com.example.outputs.report", Description: "Report of the operation"})
+
+	err := RegisterWellKnownOutput(OutputInvocationImageLogs, "Logs")
+	assert.EqualError(t, err, "the well-known output io.cnab.outputs.invocationImageLogs is already registered")
+
+	err = RegisterWellKnownOutput("report", "Report")
+	assert.EqualError(t, err, "the well-known output report must be namespaced, for example com.example.outputs.report")
+
+	err = RegisterWellKnownOutput("", "Report")
+	assert.EqualError(t, err, "the name of the well-known output is required")
+
+	names := WellKnownOutputs()
+	for i := 1; i < len(names); i++ {
+		assert.Less(t, names[i-1].Name, names[i].Name, "the outputs should be sorted by name")
+	}
+}
+
+func TestWellKnownOutputMetadataKeys(t *testing.T) {
+	for _, key := range []string{OutputContentDigest, OutputGeneratedByBundle, OutputCompression} {
+		assert.True(t, IsWellKnownOutputMetadataKey(key), key)
+	}
+
+	require.NoError(t, RegisterOutputMetadataKey("com.example.encrypted", "Whether the output is encrypted"))
+	defer func() {
+		wellKnownOutputMetadataKeys.mu.Lock()
+		delete(wellKnownOutputMetadataKeys.names, "com.example.encrypted")
+		wellKnownOutputMetadataKeys.mu.Unlock()
+	}()
+	assert.True(t, IsWellKnownOutputMetadataKey("com.example.encrypted"))
+	assert.Len(t, WellKnownOutputMetadataKeys(), 4)
+
+	err := RegisterOutputMetadataKey(OutputContentDigest, "Digest")
+	assert.EqualError(t, err, "the well-known output metadata key contentDigest is already registered")
+}
+
+func TestResult_GetWellKnownOutputs(t *testing.T) {
+	r := Result{}
+	require.NoError(t, r.OutputMetadata.SetGeneratedByBundle("password", true))
+	require.NoError(t, r.OutputMetadata.SetGeneratedByBundle(OutputInvocationImageLogs, false))
+	require.NoError(t, r.OutputMetadata.SetGeneratedByBundle(OutputEnvironmentSnapshot, false))
+
+	assert.Equal(t, []string{OutputEnvironmentSnapshot, OutputInvocationImageLogs}, r.GetWellKnownOutputs())
+}
+
+func TestOutputMetadata_BoolMetadata(t *testing.T) {
+	var o OutputMetadata
+	require.NoError(t, o.SetBoolMetadata("report", "com.example.encrypted", true))
+
+	value, ok := o.GetBoolMetadata("report", "com.example.encrypted")
+	assert.True(t, ok)
+	assert.True(t, value)
+
+	_, ok = o.GetBoolMetadata("report", "com.example.missing")
+	assert.False(t, ok, "a missing key should not be found")
+
+	require.NoError(t, o.SetMetadata("report", "com.example.encrypted", "maybe"))
+	_, ok = o.GetBoolMetadata("report", "com.example.encrypted")
+	assert.False(t, ok, "a value that is not a boolean should not be returned")
+}
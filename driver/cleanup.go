@@ -0,0 +1,65 @@
+package driver
+
+import (
+	"context"
+	"time"
+)
+
+// OrphanedResource is a resource created by a driver that was not removed
+// after the operation completed, for example because the process running the
+// driver crashed.
+type OrphanedResource struct {
+	// Kind of resource, for example container or job.
+	Kind string
+
+	// Name or ID of the resource.
+	Name string
+
+	// Installation whose operation created the resource, if known.
+	Installation string
+
+	// Action that created the resource, if known.
+	Action string
+
+	// Revision of the installation, if known.
+	Revision string
+
+	// Created is when the resource was created.
+	Created time.Time
+
+	// Deleted is true when the resource was removed by the cleanup.
+	Deleted bool
+}
+
+// CleanupOptions controls how a driver finds and removes orphaned resources.
+type CleanupOptions struct {
+	// Delete the orphaned resources. When false, they are only listed.
+	Delete bool
+
+	// OlderThan only considers resources created more than the specified
+	// duration ago, so that resources from operations that are still running
+	// are not removed.
+	OlderThan time.Duration
+
+	// Installation only considers resources created for the specified installation.
+	Installation string
+}
+
+// Applies returns true when the resource should be considered by the cleanup.
+func (o CleanupOptions) Applies(r OrphanedResource) bool {
+	if o.Installation != "" && r.Installation != o.Installation {
+		return false
+	}
+	if o.OlderThan > 0 && time.Since(r.Created) < o.OlderThan {
+		return false
+	}
+	return true
+}
+
+// Cleaner is implemented by drivers that can find, and optionally remove, the
+// resources left behind by operations that did not clean up after themselves.
+type Cleaner interface {
+	// Cleanup returns the orphaned resources, removing them when
+	// CleanupOptions.Delete is set.
+	Cleanup(ctx context.Context, opts CleanupOptions) ([]OrphanedResource, error)
+}
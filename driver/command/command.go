@@ -2,18 +2,24 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/cnabio/cnab-go/driver"
 )
 
+// outputWaitDelay is how long to wait for the driver's output to be closed
+// after the driver exits or is killed.
+const outputWaitDelay = 10 * time.Second
+
 // Driver relies upon a system command to provide a driver implementation
 type Driver struct {
 	Name string
@@ -22,6 +28,28 @@ type Driver struct {
 	// If unset, the executable is expected to be named "cnab-NAME" and be on the PATH.
 	Path string
 
+	// ActionPaths are the absolute paths to the executables that handle
+	// specific actions, by action name, and take precedence over Path. When an
+	// action does not have a path set, an executable named "cnab-NAME-ACTION"
+	// on the PATH is used when it exists, otherwise the driver's executable
+	// handles the action.
+	ActionPaths map[string]string
+
+	// Timeout is the time allowed for the driver executable to execute the
+	// bundle, after which it is killed. Set to zero to not use a timeout.
+	Timeout time.Duration
+
+	// ProtocolVersion is the protocol used to communicate with the driver
+	// executable, either ProtocolV1 or ProtocolV2. When unset, the executable
+	// is offered both versions and uses ProtocolV2 by writing a result to
+	// CNAB_RESULT_PATH, so that existing executables keep using ProtocolV1.
+	ProtocolVersion int
+
+	// Interactive indicates that the driver executable reads the input of
+	// interactive operations, Operation.In, from the file descriptor set in
+	// CNAB_STDIN_FD. It is not supported on Windows.
+	Interactive bool
+
 	outputDirName string
 }
 
@@ -45,6 +73,11 @@ func (d *Driver) Handles(dt string) bool {
 	return false
 }
 
+// DescribeEnvironment describes the driver by the name of its executable.
+func (d *Driver) DescribeEnvironment() driver.ExecutionEnvironment {
+	return driver.ExecutionEnvironment{Driver: d.cmd()}
+}
+
 // cmd is the command to run to execute the driver.
 //
 // When the driver does not have the path to the executable set,
@@ -57,12 +90,31 @@ func (d *Driver) cmd() string {
 	return "cnab-" + strings.ToLower(d.Name)
 }
 
+// actionCmd is the command to run to execute the specified action.
+func (d *Driver) actionCmd(action string) string {
+	if p, ok := d.ActionPaths[action]; ok && p != "" {
+		return p
+	}
+
+	if d.Path == "" {
+		if p, err := exec.LookPath(d.cmd() + "-" + strings.ToLower(action)); err == nil {
+			return p
+		}
+	}
+
+	return d.cmd()
+}
+
 func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
 	// We need to do two things here: We need to make it easier for the
 	// command to access data, and we need to make it easy for the command
 	// to pass that data on to the image it invokes. So we do some data
 	// duplication.
 
+	if err := d.validateProtocolVersion(); err != nil {
+		return driver.OperationResult{}, err
+	}
+
 	// Construct an environment for the subprocess by cloning our
 	// environment and adding in all the extra env vars.
 	pairs := os.Environ()
@@ -84,6 +136,36 @@ func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
 		added = append(added, "CNAB_OUTPUT_DIR")
 	}
 
+	// Create a directory for the result of the operation, which the command
+	// writes to when it uses ProtocolV2
+	var resultPath string
+	if d.ProtocolVersion != ProtocolV1 {
+		resultDir, err := ioutil.TempDir("", "bundleresult")
+		if err != nil {
+			return driver.OperationResult{}, err
+		}
+		defer os.RemoveAll(resultDir)
+		resultPath = filepath.Join(resultDir, "result.json")
+		pairs = append(pairs, fmt.Sprintf("%s=%s", SettingResultPath, resultPath))
+		added = append(added, SettingResultPath)
+	}
+	pairs = append(pairs, fmt.Sprintf("%s=%s", SettingProtocolVersions, d.supportedProtocolVersions()))
+	added = append(added, SettingProtocolVersions)
+
+	// Stream the input of interactive operations through a pipe inherited by
+	// the command, because its standard input is used for the operation
+	var stdin *stdinPipe
+	if op.In != nil && d.SupportsStdin() {
+		var err error
+		stdin, err = newStdinPipe()
+		if err != nil {
+			return driver.OperationResult{}, err
+		}
+		defer stdin.Close()
+		pairs = append(pairs, fmt.Sprintf("%s=%d", SettingStdinFD, stdinFD))
+		added = append(added, SettingStdinFD)
+	}
+
 	// CNAB_VARS is a list of variables we added to the env. This is to make
 	// it easier for shell script drivers to clone the env vars.
 	pairs = append(pairs, fmt.Sprintf("CNAB_VARS=%s", strings.Join(added, ",")))
@@ -92,51 +174,74 @@ func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
 		return driver.OperationResult{}, err
 	}
 
+	ctx := context.Background()
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	ctx, cancel := op.WithDeadline(ctx)
+	defer cancel()
+
 	args := []string{}
-	cmd := exec.Command(d.cmd(), args...)
+	cmd := exec.CommandContext(ctx, d.actionCmd(op.Action), args...)
 	cmd.Dir, err = os.Getwd()
 	if err != nil {
 		return driver.OperationResult{}, err
 	}
 	cmd.Env = pairs
 	cmd.Stdin = bytes.NewBuffer(data)
-	// Make stdout and stderr from driver available immediately
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return driver.OperationResult{}, fmt.Errorf("Setting up output handling for driver (%s) failed: %v", d.Name, err)
+	// Make stdout and stderr from driver available immediately. Wait returns
+	// once all of the output has been copied, or after outputWaitDelay when
+	// the driver leaves a background process holding its output open. When
+	// the driver uses ProtocolV2, stdout may be used for the result instead.
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = op.Out
+	if d.ProtocolVersion == ProtocolV2 {
+		cmd.Stdout = stdout
 	}
-
-	go func() {
-
-		// Errors not handled here as they only prevent output from the driver being shown, errors in the command execution are handled when command is executed
-
-		io.Copy(op.Out, stdout)
-	}()
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return driver.OperationResult{}, fmt.Errorf("Setting up error output handling for driver (%s) failed: %v", d.Name, err)
+	cmd.Stderr = op.Err
+	cmd.WaitDelay = outputWaitDelay
+	if stdin != nil {
+		cmd.ExtraFiles = []*os.File{stdin.r}
 	}
 
-	go func() {
-
-		// Errors not handled here as they only prevent output from the driver being shown, errors in the command execution are handled when command is executed
-
-		io.Copy(op.Err, stderr)
-	}()
-
 	if err = cmd.Start(); err != nil {
 		return driver.OperationResult{}, fmt.Errorf("Start of driver (%s) failed: %v", d.Name, err)
 	}
+	if stdin != nil {
+		stdin.stream(op.In)
+	}
 
-	if err = cmd.Wait(); err != nil {
-		return driver.OperationResult{}, fmt.Errorf("Command driver (%s) failed executing bundle: %v", d.Name, err)
+	waitErr := cmd.Wait()
+	if waitErr != nil && ctx.Err() == context.DeadlineExceeded {
+		if op.HasDeadline() && !time.Now().Before(op.Deadline) {
+			return driver.OperationResult{}, op.DeadlineError()
+		}
+		return driver.OperationResult{}, &TimeoutError{Driver: d.Name, Timeout: d.Timeout}
 	}
 
-	result, err := d.getOperationResult(op)
+	// A command that fails without returning a result uses ProtocolV1
+	var result Result
+	hasResult := false
+	if resultPath != "" {
+		result, hasResult, err = d.readResult(resultPath, stdout.Bytes())
+		if err != nil && waitErr == nil {
+			return driver.OperationResult{}, err
+		}
+	}
+	if waitErr != nil && !hasResult {
+		return driver.OperationResult{}, d.exitError(waitErr)
+	}
+
+	opResult, err := d.getOperationResult(op)
 	if err != nil {
 		return driver.OperationResult{}, fmt.Errorf("Command driver (%s) failed getting operation result: %v", d.Name, err)
 	}
-	return result, nil
+	if hasResult {
+		return opResult, d.applyResult(op, result, &opResult, waitErr)
+	}
+	return opResult, nil
 }
 
 func (d *Driver) getOperationResult(op *driver.Operation) (driver.OperationResult, error) {
@@ -20,3 +20,9 @@ func (d *Driver) CheckDriverExists() bool {
 	err := cmd.Run()
 	return err == nil
 }
+
+// SupportsStdin determines if the driver streams the input of operations to
+// the driver executable, when it is Interactive.
+func (d *Driver) SupportsStdin() bool {
+	return d.Interactive
+}
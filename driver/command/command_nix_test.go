@@ -4,10 +4,18 @@
 package command
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/bundle/definition"
@@ -145,3 +153,233 @@ func TestCommandDriverOutputs(t *testing.T) {
 		CreateAndRunTestCommandDriver(t, name, false, content, testfunc)
 	})
 }
+
+func TestCommandDriverRun(t *testing.T) {
+	buildOp := func(action string, out *bytes.Buffer) *driver.Operation {
+		return &driver.Operation{
+			Action:       action,
+			Installation: "test",
+			Environment:  map[string]string{},
+			Bundle:       &bundle.Bundle{},
+			Out:          out,
+			Err:          out,
+		}
+	}
+
+	t.Run("exit code", func(t *testing.T) {
+		content := `#!/bin/sh
+		exit 3
+	`
+		testfunc := func(cmddriver *Driver) {
+			_, err := cmddriver.Run(buildOp("install", &bytes.Buffer{}))
+			require.Error(t, err)
+
+			var exitErr *ExitError
+			require.True(t, errors.As(err, &exitErr), "expected an ExitError, got %T", err)
+			assert.Equal(t, 3, exitErr.ExitCode)
+			assert.Contains(t, err.Error(), "failed executing bundle")
+		}
+		CreateAndRunTestCommandDriver(t, "test-exit-code.sh", true, content, testfunc)
+	})
+
+	t.Run("interactive", func(t *testing.T) {
+		content := `#!/bin/sh
+		cat > /dev/null
+		echo "fd: $CNAB_STDIN_FD"
+		cat <&3
+	`
+		testfunc := func(cmddriver *Driver) {
+			assert.False(t, cmddriver.SupportsStdin())
+			cmddriver.Interactive = true
+			assert.True(t, driver.SupportsStdin(cmddriver))
+
+			out := &bytes.Buffer{}
+			op := buildOp("io.cnab.debug", out)
+			op.In = strings.NewReader("yes\n")
+			_, err := cmddriver.Run(op)
+			require.NoError(t, err)
+			assert.Equal(t, "fd: 3\nyes\n", out.String())
+		}
+		CreateAndRunTestCommandDriver(t, "test-interactive.sh", true, content, testfunc)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		content := `#!/bin/sh
+		exec sleep 10
+	`
+		testfunc := func(cmddriver *Driver) {
+			cmddriver.Timeout = 100 * time.Millisecond
+			_, err := cmddriver.Run(buildOp("install", &bytes.Buffer{}))
+			require.Error(t, err)
+
+			var timeoutErr *TimeoutError
+			require.True(t, errors.As(err, &timeoutErr), "expected a TimeoutError, got %T", err)
+			assert.Equal(t, cmddriver.Timeout, timeoutErr.Timeout)
+			assert.ErrorIs(t, err, driver.ErrDeadlineExceeded)
+		}
+		CreateAndRunTestCommandDriver(t, "test-timeout.sh", true, content, testfunc)
+	})
+
+	t.Run("operation deadline", func(t *testing.T) {
+		content := `#!/bin/sh
+		exec sleep 10
+	`
+		testfunc := func(cmddriver *Driver) {
+			op := buildOp("install", &bytes.Buffer{})
+			op.Deadline = time.Now().Add(100 * time.Millisecond)
+			_, err := cmddriver.Run(op)
+			require.Error(t, err)
+
+			var deadlineErr driver.DeadlineExceededError
+			require.True(t, errors.As(err, &deadlineErr), "expected a DeadlineExceededError, got %T", err)
+			assert.Equal(t, op.Deadline, deadlineErr.Deadline)
+			assert.ErrorIs(t, err, driver.ErrDeadlineExceeded)
+		}
+		CreateAndRunTestCommandDriver(t, "test-deadline.sh", true, content, testfunc)
+	})
+
+	t.Run("per action executable", func(t *testing.T) {
+		content := `#!/bin/sh
+		echo "default"
+	`
+		testfunc := func(cmddriver *Driver) {
+			dir, err := ioutil.TempDir("", "cnab")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			// The action specific executable is found on the PATH next to the driver
+			driverPath, err := exec.LookPath(cmddriver.cmd())
+			require.NoError(t, err)
+			installCmd := driverPath + "-install"
+			require.NoError(t, ioutil.WriteFile(installCmd, []byte("#!/bin/sh\necho \"install\"\n"), 0755))
+
+			upgradeCmd := filepath.Join(dir, "upgrade.sh")
+			require.NoError(t, ioutil.WriteFile(upgradeCmd, []byte("#!/bin/sh\necho \"upgrade\"\n"), 0755))
+			cmddriver.ActionPaths = map[string]string{"upgrade": upgradeCmd}
+
+			for action, want := range map[string]string{"install": "install\n", "upgrade": "upgrade\n", "uninstall": "default\n"} {
+				var out bytes.Buffer
+				_, err := cmddriver.Run(buildOp(action, &out))
+				require.NoError(t, err)
+				assert.Equal(t, want, out.String(), "unexpected executable run for the %s action", action)
+			}
+		}
+		CreateAndRunTestCommandDriver(t, "test-actions", false, content, testfunc)
+	})
+}
+
+func TestCommandDriverProtocol(t *testing.T) {
+	buildOp := func(out *bytes.Buffer) *driver.Operation {
+		return &driver.Operation{
+			Action:       "install",
+			Installation: "test",
+			Environment:  map[string]string{},
+			Outputs:      map[string]string{"/cnab/app/outputs/output1": "output1", "/cnab/app/outputs/output2": "output2"},
+			Bundle:       &bundle.Bundle{},
+			Out:          out,
+			Err:          out,
+		}
+	}
+
+	t.Run("result file", func(t *testing.T) {
+		content := `#!/bin/sh
+		echo "versions $CNAB_DRIVER_PROTOCOL_VERSIONS"
+		mkdir -p "${CNAB_OUTPUT_DIR}/cnab/app/outputs"
+		echo "FROM_FILE" > "${CNAB_OUTPUT_DIR}/cnab/app/outputs/output1"
+		echo '{"outputs": {"output2": "FROM_RESULT"}}' > "$CNAB_RESULT_PATH"
+	`
+		testfunc := func(cmddriver *Driver) {
+			var out bytes.Buffer
+			opResult, err := cmddriver.Run(buildOp(&out))
+			require.NoError(t, err)
+			assert.Equal(t, "versions 1,2\n", out.String())
+			assert.Equal(t, map[string]string{
+				"output1": "FROM_FILE\n",
+				"output2": "FROM_RESULT",
+			}, opResult.Outputs)
+		}
+		CreateAndRunTestCommandDriver(t, "test-result-file.sh", true, content, testfunc)
+	})
+
+	t.Run("result with error", func(t *testing.T) {
+		content := `#!/bin/sh
+		echo '{"outputs": {"output1": "partial"}, "error": "helm install failed", "exitCode": 4}' > "$CNAB_RESULT_PATH"
+		exit 1
+	`
+		testfunc := func(cmddriver *Driver) {
+			opResult, err := cmddriver.Run(buildOp(&bytes.Buffer{}))
+			require.Error(t, err)
+
+			var exitErr *ExitError
+			require.True(t, errors.As(err, &exitErr), "expected an ExitError, got %T", err)
+			assert.Equal(t, 4, exitErr.ExitCode)
+			assert.Contains(t, err.Error(), "helm install failed")
+			assert.Equal(t, map[string]string{"output1": "partial"}, opResult.Outputs)
+		}
+		CreateAndRunTestCommandDriver(t, "test-result-error.sh", true, content, testfunc)
+	})
+
+	t.Run("result on stdout", func(t *testing.T) {
+		content := `#!/bin/sh
+		echo "installing" >&2
+		echo '{"outputs": {"output1": "FROM_STDOUT"}}'
+	`
+		testfunc := func(cmddriver *Driver) {
+			cmddriver.ProtocolVersion = ProtocolV2
+			var out bytes.Buffer
+			opResult, err := cmddriver.Run(buildOp(&out))
+			require.NoError(t, err)
+			assert.Equal(t, "installing\n", out.String())
+			assert.Equal(t, map[string]string{"output1": "FROM_STDOUT"}, opResult.Outputs)
+		}
+		CreateAndRunTestCommandDriver(t, "test-result-stdout.sh", true, content, testfunc)
+	})
+
+	t.Run("missing result", func(t *testing.T) {
+		content := `#!/bin/sh
+		echo "not a result"
+	`
+		testfunc := func(cmddriver *Driver) {
+			cmddriver.ProtocolVersion = ProtocolV2
+			_, err := cmddriver.Run(buildOp(&bytes.Buffer{}))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "returned an invalid result")
+		}
+		CreateAndRunTestCommandDriver(t, "test-result-missing.sh", true, content, testfunc)
+	})
+
+	t.Run("unexpected output", func(t *testing.T) {
+		content := `#!/bin/sh
+		echo '{"outputs": {"output3": "oops"}}' > "$CNAB_RESULT_PATH"
+	`
+		testfunc := func(cmddriver *Driver) {
+			_, err := cmddriver.Run(buildOp(&bytes.Buffer{}))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unexpected output: output3")
+		}
+		CreateAndRunTestCommandDriver(t, "test-result-unexpected.sh", true, content, testfunc)
+	})
+
+	t.Run("protocol v1", func(t *testing.T) {
+		content := `#!/bin/sh
+		echo "versions $CNAB_DRIVER_PROTOCOL_VERSIONS result ${CNAB_RESULT_PATH:-unset}"
+	`
+		testfunc := func(cmddriver *Driver) {
+			cmddriver.ProtocolVersion = ProtocolV1
+			var out bytes.Buffer
+			_, err := cmddriver.Run(buildOp(&out))
+			require.NoError(t, err)
+			assert.Equal(t, "versions 1 result unset\n", out.String())
+		}
+		CreateAndRunTestCommandDriver(t, "test-protocol-v1.sh", true, content, testfunc)
+	})
+
+	t.Run("unsupported protocol", func(t *testing.T) {
+		testfunc := func(cmddriver *Driver) {
+			cmddriver.ProtocolVersion = 3
+			_, err := cmddriver.Run(buildOp(&bytes.Buffer{}))
+			require.EqualError(t, err, "Command driver (test-protocol-v3.sh) does not support protocol version 3")
+		}
+		CreateAndRunTestCommandDriver(t, "test-protocol-v3.sh", true, "#!/bin/sh\n", testfunc)
+	})
+}
@@ -19,3 +19,10 @@ func (d *Driver) CheckDriverExists() bool {
 	err := cmd.Run()
 	return err == nil
 }
+
+// SupportsStdin returns false, because the input of operations is streamed to
+// the driver executable through an inherited file descriptor, which is not
+// supported on Windows.
+func (d *Driver) SupportsStdin() bool {
+	return false
+}
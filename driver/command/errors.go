@@ -0,0 +1,48 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// ExitError is returned when the driver executable exits with a non-zero
+// exit code while executing the bundle.
+type ExitError struct {
+	// Driver is the name of the driver.
+	Driver string
+
+	// ExitCode of the driver executable.
+	ExitCode int
+
+	// Err is the error returned when waiting for the executable to exit.
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("Command driver (%s) failed executing bundle: %v", e.Driver, e.Err)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when the driver executable was stopped because it
+// did not finish executing the bundle within the driver's Timeout.
+type TimeoutError struct {
+	// Driver is the name of the driver.
+	Driver string
+
+	// Timeout allowed for the executable to run.
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("Command driver (%s) was stopped after exceeding the timeout of %s", e.Driver, e.Timeout)
+}
+
+// Unwrap classifies the timeout as a driver.ErrDeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return driver.ErrDeadlineExceeded
+}
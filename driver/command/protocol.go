@@ -0,0 +1,136 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// ProtocolV1 is the original protocol, where the operation is passed to
+	// the driver executable on stdin, outputs are written to files in
+	// CNAB_OUTPUT_DIR, and the exit code of the executable is the result of
+	// the operation.
+	ProtocolV1 = 1
+
+	// ProtocolV2 extends ProtocolV1 with a structured result: once it has
+	// executed the bundle, the driver executable writes a JSON Result to the
+	// file at CNAB_RESULT_PATH or, when the driver is configured to use
+	// ProtocolV2, to stdout. Outputs may be returned in the Result in
+	// addition to the files in CNAB_OUTPUT_DIR.
+	ProtocolV2 = 2
+
+	// SettingProtocolVersions is the environment variable set for the driver
+	// executable with the protocol versions supported by the driver,
+	// separated by commas, for example "1,2".
+	SettingProtocolVersions = "CNAB_DRIVER_PROTOCOL_VERSIONS"
+
+	// SettingResultPath is the environment variable set for the driver
+	// executable with the path of the file to write the Result to, when the
+	// executable supports ProtocolV2.
+	SettingResultPath = "CNAB_RESULT_PATH"
+)
+
+// Result is the result of an operation returned by a driver executable using
+// ProtocolV2.
+type Result struct {
+	// Outputs generated by the operation, by output name.
+	Outputs map[string]string `json:"outputs,omitempty"`
+
+	// Error describes why the operation failed.
+	Error string `json:"error,omitempty"`
+
+	// ExitCode of the invocation image. A non-zero exit code fails the
+	// operation, even when the driver executable exits successfully.
+	ExitCode int `json:"exitCode,omitempty"`
+}
+
+// supportedProtocolVersions lists the protocol versions that the driver
+// offers to the executable when ProtocolVersion is not set.
+func (d *Driver) supportedProtocolVersions() string {
+	switch d.ProtocolVersion {
+	case 0:
+		return fmt.Sprintf("%d,%d", ProtocolV1, ProtocolV2)
+	default:
+		return strconv.Itoa(d.ProtocolVersion)
+	}
+}
+
+// validateProtocolVersion checks that the driver supports its ProtocolVersion.
+func (d *Driver) validateProtocolVersion() error {
+	switch d.ProtocolVersion {
+	case 0, ProtocolV1, ProtocolV2:
+		return nil
+	default:
+		return fmt.Errorf("Command driver (%s) does not support protocol version %d", d.Name, d.ProtocolVersion)
+	}
+}
+
+// readResult reads the Result returned by the executable, from the result
+// file or, when the driver uses ProtocolV2, from stdout. ok is false when the
+// executable did not return a result, and is using ProtocolV1.
+func (d *Driver) readResult(resultPath string, stdout []byte) (result Result, ok bool, err error) {
+	data, err := ioutil.ReadFile(resultPath)
+	if err != nil && !os.IsNotExist(err) {
+		return result, false, fmt.Errorf("Command driver (%s) failed reading the result file: %v", d.Name, err)
+	}
+	if len(data) == 0 {
+		if d.ProtocolVersion != ProtocolV2 {
+			return result, false, nil
+		}
+		data = stdout
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, false, fmt.Errorf("Command driver (%s) returned an invalid result: %v", d.Name, err)
+	}
+	return result, true, nil
+}
+
+// applyResult merges the outputs of the Result into opResult, and returns
+// the error for the operation. waitErr is the error returned when waiting for
+// the executable to exit.
+func (d *Driver) applyResult(op *driver.Operation, result Result, opResult *driver.OperationResult, waitErr error) error {
+	known := make(map[string]bool, len(op.Outputs))
+	for _, name := range op.Outputs {
+		known[name] = true
+	}
+	for name, value := range result.Outputs {
+		if !known[name] {
+			return fmt.Errorf("Command driver (%s) returned an unexpected output: %s", d.Name, name)
+		}
+		opResult.Outputs[name] = value
+	}
+
+	if waitErr == nil && result.Error == "" && result.ExitCode == 0 {
+		return nil
+	}
+
+	exitErr := d.exitError(waitErr)
+	if result.ExitCode != 0 {
+		exitErr.ExitCode = result.ExitCode
+	}
+	if result.Error != "" {
+		exitErr.Err = errors.New(result.Error)
+	} else if exitErr.Err == nil {
+		exitErr.Err = fmt.Errorf("bundle exited with code %d", result.ExitCode)
+	}
+	return exitErr
+}
+
+// exitError converts the error returned when waiting for the command to exit
+// into an ExitError.
+func (d *Driver) exitError(err error) *ExitError {
+	exitErr := &ExitError{Driver: d.Name, ExitCode: -1, Err: err}
+	var procErr *exec.ExitError
+	if errors.As(err, &procErr) {
+		exitErr.ExitCode = procErr.ExitCode()
+	}
+	return exitErr
+}
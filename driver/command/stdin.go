@@ -0,0 +1,48 @@
+package command
+
+import (
+	"io"
+	"os"
+)
+
+// SettingStdinFD is the environment variable set for the driver executable,
+// when the driver is Interactive and the operation has an input stream, with
+// the file descriptor that the input of the operation is streamed to. The
+// standard input of the executable is still used for the operation.
+const SettingStdinFD = "CNAB_STDIN_FD"
+
+// stdinFD is the file descriptor of the input stream in the driver
+// executable, after its standard input, output and error.
+const stdinFD = 3
+
+// stdinPipe streams the input of an operation to the driver executable
+// through a pipe that the executable inherits.
+type stdinPipe struct {
+	r *os.File
+	w *os.File
+}
+
+func newStdinPipe() (*stdinPipe, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	return &stdinPipe{r: r, w: w}, nil
+}
+
+// stream copies the input to the pipe, once the executable has started and
+// inherited the read end of the pipe, and closes the pipe at the end of the
+// input so that the executable reads the end of its input.
+func (p *stdinPipe) stream(in io.Reader) {
+	p.r.Close()
+	go func() {
+		io.Copy(p.w, in)
+		p.w.Close()
+	}()
+}
+
+// Close the pipe, when the executable did not consume all of its input.
+func (p *stdinPipe) Close() {
+	p.r.Close()
+	p.w.Close()
+}
@@ -0,0 +1,352 @@
+// Package compose provides a driver that runs the invocation image with
+// docker compose, next to supporting services such as a local database.
+package compose
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	unix_path "path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// SettingComposeFiles is the environment variable for the driver that
+	// specifies the compose files defining the supporting services of the
+	// invocation image, separated like the PATH, for example
+	// "compose.yaml:compose.test.yaml".
+	SettingComposeFiles = "COMPOSE_FILE"
+
+	// SettingService is the environment variable for the driver that
+	// specifies the name of the compose service that runs the invocation
+	// image.
+	SettingService = "COMPOSE_SERVICE"
+
+	// SettingCommand is the environment variable for the driver that
+	// specifies the command running docker compose, separated by whitespace,
+	// for example "docker-compose".
+	SettingCommand = "COMPOSE_COMMAND"
+
+	// DefaultService is the name of the compose service that runs the
+	// invocation image when it is not configured.
+	DefaultService = "cnab"
+
+	// MetadataProject is the key of the name of the compose project that ran
+	// the operation in the Metadata of the OperationResult.
+	MetadataProject = "compose.project"
+
+	// outputsDir is the directory of the invocation image where outputs are
+	// written.
+	outputsDir = "/cnab/app/outputs"
+
+	// teardownTimeout is how long tearing down the project can take after
+	// the operation completed or its deadline was reached.
+	teardownTimeout = 2 * time.Minute
+)
+
+// DefaultCommand is the command running docker compose when it is not
+// configured.
+var DefaultCommand = []string{"docker", "compose"}
+
+// invalidProjectChars are the characters that are not allowed in the name of a
+// compose project.
+var invalidProjectChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// Driver runs the invocation image with docker compose run, in an ephemeral
+// compose project that also starts the services defined by the compose
+// files, such as a database that the bundle is tested against. The project,
+// with its containers, networks and volumes, is torn down after the
+// operation.
+//
+// The service running the invocation image is added to the project by the
+// driver. The compose files may define it too, for example to attach it to a
+// network, but its image, entrypoint, environment and the files of the
+// operation are set by the driver. The files of the operation and the
+// outputs are bind mounted from a temporary directory, so the docker daemon
+// must run on the same host as the driver.
+type Driver struct {
+	// ComposeFiles define the supporting services of the invocation image.
+	// They are optional.
+	ComposeFiles []string
+
+	// Service is the name of the compose service that runs the invocation
+	// image. Defaults to DefaultService.
+	Service string
+
+	// Command runs docker compose. Defaults to DefaultCommand.
+	Command []string
+
+	// runCommand runs a docker compose command, it is replaced in tests.
+	runCommand func(cmd *exec.Cmd) error
+}
+
+// Run executes the operation in a new compose project.
+func (d *Driver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	return d.exec(op)
+}
+
+// Handles indicates that the compose driver supports "docker" and "oci"
+func (d *Driver) Handles(dt string) bool {
+	return dt == driver.ImageTypeDocker || dt == driver.ImageTypeOCI
+}
+
+// DescribeEnvironment describes the driver by its name.
+func (d *Driver) DescribeEnvironment() driver.ExecutionEnvironment {
+	return driver.ExecutionEnvironment{Driver: "compose"}
+}
+
+// Config returns the compose driver configuration options
+func (d *Driver) Config() map[string]string {
+	return map[string]string{
+		SettingComposeFiles: "Compose files defining the supporting services of the invocation image, separated like the PATH",
+		SettingService:      "Name of the compose service that runs the invocation image. Defaults to " + DefaultService,
+		SettingCommand:      "Command running docker compose, separated by whitespace. Defaults to " + strings.Join(DefaultCommand, " "),
+	}
+}
+
+// SetConfig sets compose driver configuration
+func (d *Driver) SetConfig(settings map[string]string) error {
+	if files, ok := settings[SettingComposeFiles]; ok {
+		d.ComposeFiles = nil
+		for _, f := range filepath.SplitList(files) {
+			if f == "" {
+				continue
+			}
+			if _, err := os.Stat(f); err != nil {
+				return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingComposeFiles, files, err)
+			}
+			d.ComposeFiles = append(d.ComposeFiles, f)
+		}
+	}
+
+	if service, ok := settings[SettingService]; ok {
+		if service != "" && invalidProjectChars.MatchString(service) {
+			return fmt.Errorf("environment variable %s has unexpected value %q: the name of a service may only contain lowercase letters, digits, dashes and underscores", SettingService, service)
+		}
+		d.Service = service
+	}
+
+	if command, ok := settings[SettingCommand]; ok {
+		d.Command = strings.Fields(command)
+	}
+	return nil
+}
+
+func (d *Driver) service() string {
+	if d.Service != "" {
+		return d.Service
+	}
+	return DefaultService
+}
+
+func (d *Driver) command() []string {
+	if len(d.Command) > 0 {
+		return d.Command
+	}
+	return DefaultCommand
+}
+
+func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
+	workDir, err := os.MkdirTemp("", "cnab-compose-")
+	if err != nil {
+		return driver.OperationResult{}, fmt.Errorf("error creating the working directory of the compose project: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	project, err := projectName(op.Installation)
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+
+	overrideFile, hostOutputsDir, err := d.writeProject(workDir, op)
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+	files := append(append([]string{}, d.ComposeFiles...), overrideFile)
+
+	ctx, cancel := op.WithDeadline(context.Background())
+	defer cancel()
+
+	// Always tear the project down, even when the deadline was reached
+	defer func() {
+		downCtx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+		defer cancel()
+		var out bytes.Buffer
+		if err := d.compose(downCtx, project, files, &out, &out, "down", "--volumes", "--remove-orphans"); err != nil {
+			fmt.Fprintf(op.Err, "error tearing down compose project %s: %v\n%s", project, err, out.String())
+		}
+	}()
+
+	opResult := driver.OperationResult{
+		Outputs:  map[string]string{},
+		Metadata: map[string]string{MetadataProject: project},
+	}
+
+	if len(d.ComposeFiles) > 0 {
+		var out bytes.Buffer
+		err := d.compose(ctx, project, files, &out, &out, "up", "--detach", "--wait", "--scale", d.service()+"=0")
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return opResult, op.DeadlineError()
+			}
+			return opResult, fmt.Errorf("error starting the services of compose project %s: %w\n%s", project, err, out.String())
+		}
+	}
+
+	runErr := d.compose(ctx, project, files, op.Out, op.Err, "run", "--rm", "--no-TTY", d.service())
+
+	outputs, err := readOutputs(hostOutputsDir, op.Outputs)
+	opResult.Outputs = outputs
+
+	if runErr != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return opResult, op.DeadlineError()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
+			runErr = fmt.Errorf("container exit code: %d", exitErr.ExitCode())
+		}
+		if err != nil {
+			return opResult, fmt.Errorf("%w, fetching outputs failed: %s", runErr, err)
+		}
+		return opResult, runErr
+	}
+	return opResult, err
+}
+
+// compose runs a docker compose command for the project.
+func (d *Driver) compose(ctx context.Context, project string, files []string, stdout io.Writer, stderr io.Writer, args ...string) error {
+	command := d.command()
+	cmdArgs := append([]string{}, command[1:]...)
+	cmdArgs = append(cmdArgs, "--project-name", project)
+	for _, f := range files {
+		cmdArgs = append(cmdArgs, "--file", f)
+	}
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.CommandContext(ctx, command[0], cmdArgs...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if d.runCommand != nil {
+		return d.runCommand(cmd)
+	}
+	return cmd.Run()
+}
+
+// writeProject writes the files of the operation to the working directory,
+// and a compose file defining the service that runs the invocation image with
+// the files mounted. It returns the path of the compose file and of the
+// directory where outputs are written.
+func (d *Driver) writeProject(workDir string, op *driver.Operation) (string, string, error) {
+	var volumes []string
+	for path, content := range op.Files {
+		if !unix_path.IsAbs(path) {
+			return "", "", fmt.Errorf("destination path %s should be an absolute unix path", path)
+		}
+		hostPath := filepath.Join(workDir, "files", filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(hostPath), 0700); err != nil {
+			return "", "", fmt.Errorf("error creating the directory of file %s: %w", path, err)
+		}
+		// The working directory is only accessible by the current user, the
+		// file is readable by everyone so that the container user can read it
+		if err := os.WriteFile(hostPath, []byte(content), 0644); err != nil {
+			return "", "", fmt.Errorf("error writing file %s: %w", path, err)
+		}
+		volumes = append(volumes, escape(hostPath)+":"+escape(path)+":ro")
+	}
+
+	hostOutputsDir := filepath.Join(workDir, "outputs")
+	if err := os.Mkdir(hostOutputsDir, 0700); err != nil {
+		return "", "", fmt.Errorf("error creating the outputs directory: %w", err)
+	}
+	// Let the container user write outputs
+	if err := os.Chmod(hostOutputsDir, 0777); err != nil {
+		return "", "", fmt.Errorf("error creating the outputs directory: %w", err)
+	}
+	volumes = append(volumes, escape(hostOutputsDir)+":"+outputsDir)
+
+	environment := make(map[string]string, len(op.Environment))
+	for k, v := range op.Environment {
+		environment[k] = escape(v)
+	}
+	labels := map[string]string{}
+	for k, v := range driver.NewOperationLabels("compose", op).Map() {
+		labels[k] = escape(v)
+	}
+
+	service := map[string]interface{}{
+		"image":       escape(op.Image.Image),
+		"entrypoint":  []string{"/cnab/app/run"},
+		"environment": environment,
+		"volumes":     volumes,
+		"labels":      labels,
+	}
+	project := map[string]interface{}{
+		"services": map[string]interface{}{d.service(): service},
+	}
+	data, err := json.MarshalIndent(project, "", "  ")
+	if err != nil {
+		return "", "", fmt.Errorf("error generating the compose file of the invocation image: %w", err)
+	}
+
+	overrideFile := filepath.Join(workDir, "compose.cnab.json")
+	if err := os.WriteFile(overrideFile, data, 0600); err != nil {
+		return "", "", fmt.Errorf("error writing the compose file of the invocation image: %w", err)
+	}
+	return overrideFile, hostOutputsDir, nil
+}
+
+// readOutputs reads the outputs of the operation written by the invocation
+// image. Outputs that were not written are skipped.
+func readOutputs(hostOutputsDir string, outputs map[string]string) (map[string]string, error) {
+	values := map[string]string{}
+	for path, name := range outputs {
+		rel := strings.TrimPrefix(path, outputsDir+"/")
+		if rel == path {
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(hostOutputsDir, filepath.FromSlash(rel)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return values, fmt.Errorf("error reading output %s: %w", name, err)
+		}
+		values[name] = string(contents)
+	}
+	return values, nil
+}
+
+// projectName returns a unique name for the compose project of an operation
+// on the installation.
+func projectName(installation string) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("error generating the name of the compose project: %w", err)
+	}
+
+	name := invalidProjectChars.ReplaceAllString(strings.ToLower(installation), "-")
+	name = strings.Trim(name, "-_")
+	if name == "" {
+		return "cnab-" + hex.EncodeToString(suffix), nil
+	}
+	return "cnab-" + name + "-" + hex.EncodeToString(suffix), nil
+}
+
+// escape escapes the $ characters of a value, so that docker compose does not
+// interpolate them as variables.
+func escape(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
@@ -0,0 +1,211 @@
+package compose
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+var _ driver.Driver = &Driver{}
+var _ driver.Configurable = &Driver{}
+
+// composeService is the part of the service definition generated by the
+// driver that is checked by the tests.
+type composeService struct {
+	Image       string            `json:"image"`
+	Environment map[string]string `json:"environment"`
+	Volumes     []string          `json:"volumes"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// fakeCompose records the docker compose commands run by the driver, and
+// simulates the invocation image with run.
+type fakeCompose struct {
+	commands [][]string
+	service  composeService
+	run      func(cmd *exec.Cmd, outputsDir string) error
+}
+
+func (f *fakeCompose) runCommand(cmd *exec.Cmd) error {
+	f.commands = append(f.commands, cmd.Args)
+	if !contains(cmd.Args, "run") {
+		return nil
+	}
+
+	// The last compose file defines the service of the invocation image
+	var overrideFile string
+	for i, arg := range cmd.Args {
+		if arg == "--file" {
+			overrideFile = cmd.Args[i+1]
+		}
+	}
+	data, err := os.ReadFile(overrideFile)
+	if err != nil {
+		return err
+	}
+	var project struct {
+		Services map[string]composeService `json:"services"`
+	}
+	if err := json.Unmarshal(data, &project); err != nil {
+		return err
+	}
+	f.service = project.Services[cmd.Args[len(cmd.Args)-1]]
+
+	var outputsDir string
+	for _, v := range f.service.Volumes {
+		if strings.HasSuffix(v, ":/cnab/app/outputs") {
+			outputsDir = strings.TrimSuffix(v, ":/cnab/app/outputs")
+		}
+	}
+	if f.run != nil {
+		return f.run(cmd, outputsDir)
+	}
+	return nil
+}
+
+func contains(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
+
+func testOperation() *driver.Operation {
+	return &driver.Operation{
+		Installation: "My App",
+		Action:       "install",
+		Revision:     "01ABC",
+		Image: bundle.InvocationImage{
+			BaseImage: bundle.BaseImage{Image: "example.com/myapp:v1", ImageType: "docker"},
+		},
+		Environment: map[string]string{"CNAB_ACTION": "install", "PASSWORD": "pa$$word"},
+		Files:       map[string]string{"/cnab/app/params/port": "3306"},
+		Outputs:     map[string]string{"/cnab/app/outputs/url": "url", "/cnab/app/outputs/missing": "missing"},
+		Out:         &bytes.Buffer{},
+		Err:         &bytes.Buffer{},
+	}
+}
+
+func TestDriver_Run(t *testing.T) {
+	composeFile := filepath.Join(t.TempDir(), "compose.yaml")
+	require.NoError(t, os.WriteFile(composeFile, []byte("services:\n  db:\n    image: mysql\n"), 0600))
+
+	fake := &fakeCompose{
+		run: func(cmd *exec.Cmd, outputsDir string) error {
+			fmt.Fprint(cmd.Stdout, "installing")
+			return os.WriteFile(filepath.Join(outputsDir, "url"), []byte("http://db:3306"), 0644)
+		},
+	}
+	d := &Driver{runCommand: fake.runCommand}
+	require.NoError(t, d.SetConfig(map[string]string{SettingComposeFiles: composeFile}))
+
+	op := testOperation()
+	opResult, err := d.Run(op)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"url": "http://db:3306"}, opResult.Outputs)
+	assert.Equal(t, "installing", op.Out.(*bytes.Buffer).String())
+
+	project := opResult.Metadata[MetadataProject]
+	assert.Regexp(t, `^cnab-my-app-[0-9a-f]{8}$`, project)
+
+	require.Len(t, fake.commands, 3)
+	assert.Equal(t, []string{"docker", "compose", "--project-name", project, "--file", composeFile}, fake.commands[0][:6])
+	assert.Equal(t, []string{"up", "--detach", "--wait", "--scale", "cnab=0"}, fake.commands[0][8:], "the supporting services should be started first")
+	assert.Equal(t, []string{"run", "--rm", "--no-TTY", "cnab"}, fake.commands[1][8:])
+	assert.Equal(t, []string{"down", "--volumes", "--remove-orphans"}, fake.commands[2][8:], "the project should be torn down")
+
+	assert.Equal(t, "example.com/myapp:v1", fake.service.Image)
+	assert.Equal(t, "pa$$$$word", fake.service.Environment["PASSWORD"], "values should not be interpolated by compose")
+	assert.Equal(t, "My App", fake.service.Labels[driver.LabelInstallation])
+
+	var portFile string
+	for _, v := range fake.service.Volumes {
+		if strings.HasSuffix(v, ":/cnab/app/params/port:ro") {
+			portFile = strings.TrimSuffix(v, ":/cnab/app/params/port:ro")
+		}
+	}
+	require.NotEmpty(t, portFile, "the files of the operation should be mounted")
+	_, err = os.Stat(portFile)
+	assert.True(t, os.IsNotExist(err), "the working directory should be removed")
+}
+
+func TestDriver_Run_WithoutComposeFiles(t *testing.T) {
+	fake := &fakeCompose{}
+	d := &Driver{runCommand: fake.runCommand, Service: "bundle", Command: []string{"docker-compose"}}
+
+	_, err := d.Run(testOperation())
+	require.NoError(t, err)
+	require.Len(t, fake.commands, 2, "no supporting services should be started")
+	assert.Equal(t, "docker-compose", fake.commands[0][0])
+	assert.Equal(t, []string{"run", "--rm", "--no-TTY", "bundle"}, fake.commands[0][5:])
+	assert.Equal(t, "down", fake.commands[1][5])
+}
+
+func TestDriver_Run_Failed(t *testing.T) {
+	fake := &fakeCompose{
+		run: func(cmd *exec.Cmd, outputsDir string) error {
+			require.NoError(t, os.WriteFile(filepath.Join(outputsDir, "url"), []byte("partial"), 0644))
+			return exec.Command("sh", "-c", "exit 3").Run()
+		},
+	}
+	d := &Driver{runCommand: fake.runCommand}
+
+	opResult, err := d.Run(testOperation())
+	require.EqualError(t, err, "container exit code: 3")
+	assert.Equal(t, map[string]string{"url": "partial"}, opResult.Outputs, "the outputs should be collected")
+	assert.Contains(t, fake.commands[len(fake.commands)-1], "down", "the project should be torn down")
+}
+
+func TestDriver_SetConfig(t *testing.T) {
+	dir := t.TempDir()
+	composeFile := filepath.Join(dir, "compose.yaml")
+	testFile := filepath.Join(dir, "compose.test.yaml")
+	for _, f := range []string{composeFile, testFile} {
+		require.NoError(t, os.WriteFile(f, []byte("services: {}\n"), 0600))
+	}
+
+	d := &Driver{}
+	require.NoError(t, d.SetConfig(map[string]string{
+		SettingComposeFiles: composeFile + string(filepath.ListSeparator) + testFile,
+		SettingService:      "invocation",
+		SettingCommand:      "podman compose",
+	}))
+	assert.Equal(t, []string{composeFile, testFile}, d.ComposeFiles)
+	assert.Equal(t, "invocation", d.Service)
+	assert.Equal(t, []string{"podman", "compose"}, d.Command)
+
+	err := d.SetConfig(map[string]string{SettingComposeFiles: filepath.Join(dir, "missing.yaml")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment variable COMPOSE_FILE has unexpected value")
+
+	err = d.SetConfig(map[string]string{SettingService: "My Service"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment variable COMPOSE_SERVICE has unexpected value")
+}
+
+func TestProjectName(t *testing.T) {
+	name, err := projectName("Prod/MySQL_1")
+	require.NoError(t, err)
+	assert.Regexp(t, `^cnab-prod-mysql_1-[0-9a-f]{8}$`, name)
+
+	other, err := projectName("Prod/MySQL_1")
+	require.NoError(t, err)
+	assert.NotEqual(t, name, other, "each operation should run in a new project")
+
+	name, err = projectName("---")
+	require.NoError(t, err)
+	assert.Regexp(t, `^cnab-[0-9a-f]{8}$`, name)
+}
@@ -0,0 +1,26 @@
+package driver
+
+import (
+	"context"
+)
+
+// HasDeadline returns true when the operation must complete by a deadline.
+func (o *Operation) HasDeadline() bool {
+	return !o.Deadline.IsZero()
+}
+
+// WithDeadline returns a copy of ctx that is canceled when the deadline of
+// the operation is reached. When the operation has no deadline, the context
+// is only canceled by calling the returned cancel function.
+func (o *Operation) WithDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if !o.HasDeadline() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, o.Deadline)
+}
+
+// DeadlineError returns the error reported when the operation was stopped
+// because its deadline was reached.
+func (o *Operation) DeadlineError() error {
+	return DeadlineExceededError{Deadline: o.Deadline}
+}
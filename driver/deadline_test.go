@@ -0,0 +1,43 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperation_WithDeadline(t *testing.T) {
+	t.Run("no deadline", func(t *testing.T) {
+		op := Operation{}
+		assert.False(t, op.HasDeadline())
+
+		ctx, cancel := op.WithDeadline(context.Background())
+		_, ok := ctx.Deadline()
+		assert.False(t, ok, "the context should not have a deadline")
+
+		cancel()
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		op := Operation{Deadline: time.Now().Add(time.Hour)}
+		assert.True(t, op.HasDeadline())
+
+		ctx, cancel := op.WithDeadline(context.Background())
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok, "the context should have a deadline")
+		assert.Equal(t, op.Deadline, deadline)
+	})
+}
+
+func TestOperation_DeadlineError(t *testing.T) {
+	op := Operation{Deadline: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	err := op.DeadlineError()
+	assert.EqualError(t, err, "the operation was stopped because it did not complete before its deadline of 2020-01-02T03:04:05Z")
+	assert.True(t, errors.Is(err, ErrDeadlineExceeded))
+}
@@ -3,10 +3,14 @@ package debug
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/cnabio/cnab-go/driver"
 )
 
+// redactedValue replaces the values of sensitive parameters in the printed operation.
+const redactedValue = "******"
+
 // Driver prints the information passed to a driver
 //
 // It does not ever run the image.
@@ -16,7 +20,7 @@ type Driver struct {
 
 // Run executes the operation on the Debug driver
 func (d *Driver) Run(op *driver.Operation) (driver.OperationResult, error) {
-	data, err := json.MarshalIndent(op, "", "  ")
+	data, err := json.MarshalIndent(redactOperation(op), "", "  ")
 	if err != nil {
 		return driver.OperationResult{}, err
 	}
@@ -45,3 +49,62 @@ func (d *Driver) SetConfig(settings map[string]string) error {
 	d.config = settings
 	return nil
 }
+
+// redactOperation returns a copy of the operation with the values of
+// sensitive parameters redacted, including where they are injected into the
+// environment and files of the invocation image.
+func redactOperation(op *driver.Operation) *driver.Operation {
+	if op.Bundle == nil {
+		return op
+	}
+
+	redacted := *op
+	redacted.Parameters = copyMap(op.Parameters)
+	redacted.Environment = copyStringMap(op.Environment)
+	redacted.Files = copyStringMap(op.Files)
+
+	for name, param := range op.Bundle.Parameters {
+		if sensitive, _ := op.Bundle.IsParameterSensitive(name); !sensitive {
+			continue
+		}
+
+		if _, ok := redacted.Parameters[name]; ok {
+			redacted.Parameters[name] = redactedValue
+		}
+
+		envVar := "CNAB_P_" + strings.ToUpper(name)
+		if param.Destination != nil {
+			envVar = param.Destination.EnvironmentVariable
+			if _, ok := redacted.Files[param.Destination.Path]; ok {
+				redacted.Files[param.Destination.Path] = redactedValue
+			}
+		}
+		if _, ok := redacted.Environment[envVar]; ok {
+			redacted.Environment[envVar] = redactedValue
+		}
+	}
+
+	return &redacted
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
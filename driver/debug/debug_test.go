@@ -1,12 +1,15 @@
 package debug
 
 import (
+	"bytes"
 	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
 	"github.com/cnabio/cnab-go/driver"
 )
 
@@ -39,3 +42,37 @@ func TestDebugDriver_Run(t *testing.T) {
 	_, err := d.Run(op)
 	is.NoError(err)
 }
+
+func TestDebugDriver_Run_RedactsSensitiveParameters(t *testing.T) {
+	writeOnly := true
+	b := &bundle.Bundle{
+		Definitions: definition.Definitions{
+			"string":   {Type: "string"},
+			"password": {Type: "string", WriteOnly: &writeOnly},
+		},
+		Parameters: map[string]bundle.Parameter{
+			"host":     {Definition: "string"},
+			"password": {Definition: "password"},
+			"token":    {Definition: "password", Destination: &bundle.Location{Path: "/cnab/app/token", EnvironmentVariable: "TOKEN"}},
+		},
+	}
+
+	var out bytes.Buffer
+	op := &driver.Operation{
+		Installation: "test",
+		Bundle:       b,
+		Parameters:   map[string]interface{}{"host": "localhost", "password": "topsecret", "token": "secrettoken"},
+		Environment:  map[string]string{"CNAB_P_HOST": "localhost", "CNAB_P_PASSWORD": "topsecret", "TOKEN": "secrettoken"},
+		Files:        map[string]string{"/cnab/app/token": "secrettoken"},
+		Out:          &out,
+	}
+
+	d := &Driver{}
+	_, err := d.Run(op)
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), "localhost")
+	assert.NotContains(t, out.String(), "topsecret")
+	assert.NotContains(t, out.String(), "secrettoken")
+	assert.Equal(t, "topsecret", op.Parameters["password"], "the operation should not be modified")
+}
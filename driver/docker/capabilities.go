@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+
+	"github.com/cnabio/cnab-go/bundle/extensions"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// SettingAllowedCapabilities is the environment variable for the driver that
+// specifies the docker capabilities that bundles may request with the
+// io.cnab.docker extension, separated by whitespace or commas, for example
+// "privileged host-network". Bundles requesting other capabilities are not
+// run. Defaults to no capabilities.
+const SettingAllowedCapabilities = "DOCKER_ALLOWED_CAPABILITIES"
+
+// knownCapabilities are the docker capabilities that bundles can request.
+var knownCapabilities = map[string]bool{
+	extensions.DockerCapabilityPrivileged:  true,
+	extensions.DockerCapabilityHostNetwork: true,
+}
+
+// parseCapabilitySettings reads the allowed capabilities from the driver
+// settings, falling back to the capabilities already set on the driver.
+func (d *Driver) parseCapabilitySettings(settings map[string]string) error {
+	value, ok := settings[SettingAllowedCapabilities]
+	if !ok || value == "" {
+		return nil
+	}
+
+	capabilities := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	for _, capability := range capabilities {
+		if !knownCapabilities[capability] {
+			return fmt.Errorf("environment variable %s has unexpected value %q: unknown capability %s, it must be %s or %s",
+				SettingAllowedCapabilities, value, capability, extensions.DockerCapabilityPrivileged, extensions.DockerCapabilityHostNetwork)
+		}
+	}
+	d.AllowedCapabilities = capabilities
+	return nil
+}
+
+// applyCapabilities grants the capabilities requested by the bundle with the
+// io.cnab.docker extension, failing when they are not allowed.
+func (d *Driver) applyCapabilities(op *driver.Operation) error {
+	if op.Bundle == nil || !extensions.HasDockerExtension(*op.Bundle) {
+		return nil
+	}
+
+	requested, err := extensions.ReadDockerExtension(*op.Bundle)
+	if err != nil {
+		return err
+	}
+	if err := requested.CheckAllowed(d.AllowedCapabilities); err != nil {
+		return err
+	}
+
+	if requested.Privileged {
+		d.containerHostCfg.Privileged = true
+	}
+
+	if requested.HostNetwork {
+		if len(d.Networks) > 0 && !container.NetworkMode(d.Networks[0].Name).IsHost() {
+			return fmt.Errorf("the bundle requests the host network, which cannot be combined with the networks configured with %s", SettingNetwork)
+		}
+		d.containerHostCfg.NetworkMode = container.NetworkMode(network.NetworkHost)
+	}
+
+	return nil
+}
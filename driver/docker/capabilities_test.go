@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/extensions"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_Capabilities(t *testing.T) {
+	newOperation := func(requested map[string]interface{}) *driver.Operation {
+		b := &bundle.Bundle{}
+		if requested != nil {
+			b.Custom = map[string]interface{}{extensions.DockerExtensionKey: requested}
+		}
+		return &driver.Operation{
+			Image:  bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "example.com/myimage"}},
+			Bundle: b,
+		}
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{SettingAllowedCapabilities: "privileged, host-network"}))
+		assert.Equal(t, []string{extensions.DockerCapabilityPrivileged, extensions.DockerCapabilityHostNetwork}, d.AllowedCapabilities)
+
+		require.NoError(t, d.setConfigurationOptions(newOperation(map[string]interface{}{"privileged": true, "hostNetwork": true})))
+		assert.True(t, d.containerHostCfg.Privileged)
+		assert.True(t, d.containerHostCfg.NetworkMode.IsHost())
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		d := &Driver{AllowedCapabilities: []string{extensions.DockerCapabilityPrivileged}}
+		require.NoError(t, d.setConfigurationOptions(newOperation(nil)))
+		assert.False(t, d.containerHostCfg.Privileged, "allowed capabilities should only be granted when requested")
+		assert.Empty(t, d.containerHostCfg.NetworkMode)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		d := &Driver{AllowedCapabilities: []string{extensions.DockerCapabilityHostNetwork}}
+		err := d.setConfigurationOptions(newOperation(map[string]interface{}{"privileged": true}))
+		assert.EqualError(t, err, "the bundle requests the docker capabilities privileged, which are not allowed by the runtime")
+		assert.IsType(t, extensions.DeniedDockerCapabilitiesError{}, err)
+	})
+
+	t.Run("host network with networks", func(t *testing.T) {
+		d := &Driver{
+			AllowedCapabilities: []string{extensions.DockerCapabilityHostNetwork},
+			Networks:            []NetworkAttachment{{Name: "backend"}},
+		}
+		err := d.setConfigurationOptions(newOperation(map[string]interface{}{"hostNetwork": true}))
+		assert.EqualError(t, err, "the bundle requests the host network, which cannot be combined with the networks configured with DOCKER_NETWORK")
+	})
+
+	t.Run("unknown capability", func(t *testing.T) {
+		d := &Driver{}
+		err := d.SetConfig(map[string]string{SettingAllowedCapabilities: "privileged root"})
+		assert.EqualError(t, err, `environment variable DOCKER_ALLOWED_CAPABILITIES has unexpected value "privileged root": unknown capability root, it must be privileged or host-network`)
+	})
+}
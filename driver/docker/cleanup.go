@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+var _ driver.Cleaner = &Driver{}
+
+// cleanupClient is the subset of the docker client used to find and remove
+// orphaned resources.
+type cleanupClient interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerRemove(ctx context.Context, container string, options container.RemoveOptions) error
+	VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+}
+
+// Cleanup finds the containers created by the docker driver that are no
+// longer running, for example when CLEANUP_CONTAINERS is never or the process
+// running the driver crashed, and unused volumes labeled with
+// cnab.io/driver=docker. The resources are removed when opts.Delete is set.
+func (d *Driver) Cleanup(ctx context.Context, opts driver.CleanupOptions) ([]driver.OrphanedResource, error) {
+	cli, err := d.initializeDockerCli()
+	if err != nil {
+		return nil, err
+	}
+
+	return cleanup(ctx, cli.Client(), opts)
+}
+
+func cleanup(ctx context.Context, cli cleanupClient, opts driver.CleanupOptions) ([]driver.OrphanedResource, error) {
+	labelFilter := filters.Arg("label", driver.LabelDriver+"=docker")
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(labelFilter),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list containers")
+	}
+
+	volumes, err := cli.VolumeList(ctx, volume.ListOptions{
+		Filters: filters.NewArgs(labelFilter, filters.Arg("dangling", "true")),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list volumes")
+	}
+
+	var orphans []driver.OrphanedResource
+	var deleteErrs *multierror.Error
+	for _, c := range containers {
+		if c.State == "running" || c.State == "restarting" || c.State == "paused" {
+			continue
+		}
+
+		orphan := driver.ParseOperationLabels(c.Labels).OrphanedResource("container", containerDisplayName(c))
+		orphan.Created = time.Unix(c.Created, 0)
+		if !opts.Applies(orphan) {
+			continue
+		}
+
+		if opts.Delete {
+			if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{RemoveVolumes: true}); err != nil {
+				deleteErrs = multierror.Append(deleteErrs, errors.Wrapf(err, "could not remove container %s", orphan.Name))
+			} else {
+				orphan.Deleted = true
+			}
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	for _, v := range volumes.Volumes {
+		orphan := driver.ParseOperationLabels(v.Labels).OrphanedResource("volume", v.Name)
+		if created, err := time.Parse(time.RFC3339, v.CreatedAt); err == nil {
+			orphan.Created = created
+		}
+		if !opts.Applies(orphan) {
+			continue
+		}
+
+		if opts.Delete {
+			if err := cli.VolumeRemove(ctx, v.Name, false); err != nil {
+				deleteErrs = multierror.Append(deleteErrs, errors.Wrapf(err, "could not remove volume %s", orphan.Name))
+			} else {
+				orphan.Deleted = true
+			}
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	return orphans, deleteErrs.ErrorOrNil()
+}
+
+// containerDisplayName returns the name of the container, falling back to its ID.
+func containerDisplayName(c types.Container) string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	return c.ID
+}
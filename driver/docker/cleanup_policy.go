@@ -0,0 +1,145 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// SettingCleanupContainers is the environment variable for the driver
+	// that specifies when the invocation image container is removed after it
+	// ran, either CleanupAlways, CleanupOnSuccess or CleanupNever. The values
+	// true and false are accepted for compatibility, and are equivalent to
+	// CleanupAlways and CleanupNever. Defaults to CleanupAlways.
+	SettingCleanupContainers = "CLEANUP_CONTAINERS"
+
+	// SettingCleanupRetainFailed is the environment variable for the driver
+	// that specifies how many of the most recent failed containers of an
+	// installation are kept with the CleanupOnSuccess policy. Older failed
+	// containers of the installation are removed.
+	// Defaults to 0, which keeps every failed container.
+	SettingCleanupRetainFailed = "CLEANUP_RETAIN_FAILED"
+
+	// CleanupAlways removes the container once it ran.
+	CleanupAlways = "always"
+
+	// CleanupOnSuccess removes the container once it ran successfully, and
+	// keeps failed containers for debugging.
+	CleanupOnSuccess = "on-success"
+
+	// CleanupNever keeps the container once it ran.
+	CleanupNever = "never"
+)
+
+// pruneClient is the subset of the docker client used to remove containers
+// that are no longer retained.
+type pruneClient interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	ContainerRemove(ctx context.Context, container string, options container.RemoveOptions) error
+}
+
+// parseCleanupSettings reads the cleanup policy of the containers from the
+// driver settings, falling back to the values already set on the driver.
+func (d *Driver) parseCleanupSettings(settings map[string]string) error {
+	if value, ok := settings[SettingCleanupContainers]; ok {
+		switch value {
+		case "true", CleanupAlways:
+			d.CleanupPolicy = CleanupAlways
+		case "false", CleanupNever:
+			d.CleanupPolicy = CleanupNever
+		case CleanupOnSuccess:
+			d.CleanupPolicy = CleanupOnSuccess
+		default:
+			return fmt.Errorf("environment variable %s has unexpected value %q. Supported values are '%s', '%s', '%s', 'true', 'false', or unset",
+				SettingCleanupContainers, value, CleanupAlways, CleanupOnSuccess, CleanupNever)
+		}
+	} else if d.CleanupPolicy == "" {
+		d.CleanupPolicy = CleanupAlways
+	}
+
+	if value, ok := settings[SettingCleanupRetainFailed]; ok && value != "" {
+		retain, err := strconv.Atoi(value)
+		if err != nil || retain < 0 {
+			return fmt.Errorf("environment variable %s has unexpected value %q, it must be zero or a positive number", SettingCleanupRetainFailed, value)
+		}
+		d.RetainFailedContainers = retain
+	}
+
+	return nil
+}
+
+// cleanupContainer removes the container of the operation once it ran,
+// according to the cleanup policy of the driver. A driver without a cleanup
+// policy, which was not configured with SetConfig, keeps the container.
+// Failures are reported to errOut but do not fail the operation.
+func (d *Driver) cleanupContainer(ctx context.Context, cli pruneClient, errOut io.Writer, id string, op *driver.Operation, succeeded bool) {
+	switch d.CleanupPolicy {
+	case "", CleanupNever:
+		return
+	case CleanupOnSuccess:
+		if !succeeded {
+			if d.RetainFailedContainers > 0 {
+				if err := pruneFailedContainers(ctx, cli, op.Installation, d.RetainFailedContainers); err != nil {
+					fmt.Fprintf(errOut, "unable to remove the failed containers that are no longer retained: %v\n", err)
+				}
+			}
+			return
+		}
+	}
+
+	if err := cli.ContainerRemove(ctx, id, container.RemoveOptions{}); err != nil {
+		fmt.Fprintf(errOut, "unable to remove container %s: %v\n", id, err)
+	}
+}
+
+// pruneFailedContainers removes the containers created by the docker driver
+// for the installation that exited with an error, except the most recent
+// ones. The containers of other installations are left alone, since they may
+// be retained by another process with a different policy.
+func pruneFailedContainers(ctx context.Context, cli pruneClient, installation string, retain int) error {
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", driver.LabelDriver+"=docker"),
+			filters.Arg("label", driver.LabelInstallation+"="+installation),
+			filters.Arg("status", "exited"),
+		),
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []types.Container
+	for _, c := range containers {
+		info, err := cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			continue
+		}
+		if info.ContainerJSONBase != nil && info.State != nil && info.State.ExitCode != 0 {
+			failed = append(failed, c)
+		}
+	}
+	if len(failed) <= retain {
+		return nil
+	}
+
+	sort.SliceStable(failed, func(i, j int) bool {
+		return failed[i].Created > failed[j].Created
+	})
+	for _, c := range failed[retain:] {
+		if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{RemoveVolumes: true}); err != nil {
+			return fmt.Errorf("could not remove container %s: %w", containerDisplayName(c), err)
+		}
+	}
+	return nil
+}
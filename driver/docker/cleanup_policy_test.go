@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+type testPruneClient struct {
+	containers []types.Container
+	exitCodes  map[string]int
+	removed    []string
+}
+
+func (c *testPruneClient) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	var containers []types.Container
+	for _, ctr := range c.containers {
+		matches := true
+		for _, label := range options.Filters.Get("label") {
+			key, value, _ := strings.Cut(label, "=")
+			if ctr.Labels[key] != value {
+				matches = false
+			}
+		}
+		if matches {
+			containers = append(containers, ctr)
+		}
+	}
+	return containers, nil
+}
+
+func (c *testPruneClient) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    id,
+			State: &types.ContainerState{ExitCode: c.exitCodes[id]},
+		},
+	}, nil
+}
+
+func (c *testPruneClient) ContainerRemove(ctx context.Context, id string, options container.RemoveOptions) error {
+	c.removed = append(c.removed, id)
+	return nil
+}
+
+func newTestPruneClient() *testPruneClient {
+	mysql := map[string]string{driver.LabelDriver: "docker", driver.LabelInstallation: "mysql"}
+	wordpress := map[string]string{driver.LabelDriver: "docker", driver.LabelInstallation: "wordpress"}
+	return &testPruneClient{
+		containers: []types.Container{
+			{ID: "failed-old", Created: 100, Labels: mysql},
+			{ID: "succeeded", Created: 200, Labels: mysql},
+			{ID: "failed-new", Created: 300, Labels: mysql},
+			{ID: "failed-newest", Created: 400, Labels: mysql},
+			{ID: "failed-other-installation", Created: 50, Labels: wordpress},
+		},
+		exitCodes: map[string]int{
+			"failed-old":                1,
+			"failed-new":                2,
+			"failed-newest":             137,
+			"failed-other-installation": 1,
+		},
+	}
+}
+
+func TestDriver_CleanupContainer(t *testing.T) {
+	testcases := []struct {
+		name        string
+		policy      string
+		retain      int
+		succeeded   bool
+		wantRemoved []string
+	}{
+		{name: "not configured", succeeded: false, wantRemoved: nil},
+		{name: "always", policy: CleanupAlways, succeeded: false, wantRemoved: []string{"current"}},
+		{name: "never", policy: CleanupNever, succeeded: true, wantRemoved: nil},
+		{name: "on-success: succeeded", policy: CleanupOnSuccess, succeeded: true, wantRemoved: []string{"current"}},
+		{name: "on-success: failed", policy: CleanupOnSuccess, succeeded: false, wantRemoved: nil},
+		{name: "on-success: retain failed", policy: CleanupOnSuccess, retain: 2, succeeded: false, wantRemoved: []string{"failed-old"}},
+		{name: "on-success: retain more than failed", policy: CleanupOnSuccess, retain: 5, succeeded: false, wantRemoved: nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cli := newTestPruneClient()
+			d := &Driver{CleanupPolicy: tc.policy, RetainFailedContainers: tc.retain}
+
+			var errOut bytes.Buffer
+			d.cleanupContainer(context.Background(), cli, &errOut, "current", &driver.Operation{Installation: "mysql"}, tc.succeeded)
+			assert.Equal(t, tc.wantRemoved, cli.removed)
+			assert.Empty(t, errOut.String())
+		})
+	}
+}
+
+func TestDriver_ParseCleanupSettings(t *testing.T) {
+	testcases := []struct {
+		name       string
+		settings   map[string]string
+		wantPolicy string
+		wantRetain int
+		wantError  string
+	}{
+		{name: "unset", settings: map[string]string{}, wantPolicy: CleanupAlways},
+		{name: "true", settings: map[string]string{SettingCleanupContainers: "true"}, wantPolicy: CleanupAlways},
+		{name: "false", settings: map[string]string{SettingCleanupContainers: "false"}, wantPolicy: CleanupNever},
+		{name: "on-success", settings: map[string]string{
+			SettingCleanupContainers:   "on-success",
+			SettingCleanupRetainFailed: "3",
+		}, wantPolicy: CleanupOnSuccess, wantRetain: 3},
+		{name: "invalid policy", settings: map[string]string{SettingCleanupContainers: "sometimes"},
+			wantError: `environment variable CLEANUP_CONTAINERS has unexpected value "sometimes". Supported values are 'always', 'on-success', 'never', 'true', 'false', or unset`},
+		{name: "invalid retention", settings: map[string]string{SettingCleanupRetainFailed: "-1"},
+			wantError: `environment variable CLEANUP_RETAIN_FAILED has unexpected value "-1", it must be zero or a positive number`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &Driver{}
+			err := d.parseCleanupSettings(tc.settings)
+			if tc.wantError != "" {
+				require.EqualError(t, err, tc.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPolicy, d.CleanupPolicy)
+			assert.Equal(t, tc.wantRetain, d.RetainFailedContainers)
+		})
+	}
+}
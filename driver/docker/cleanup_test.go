@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+type testCleanupClient struct {
+	containers        []types.Container
+	volumes           []*volume.Volume
+	removedContainers []string
+	removedVolumes    []string
+}
+
+func (c *testCleanupClient) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	return c.containers, nil
+}
+
+func (c *testCleanupClient) ContainerRemove(ctx context.Context, id string, options container.RemoveOptions) error {
+	c.removedContainers = append(c.removedContainers, id)
+	return nil
+}
+
+func (c *testCleanupClient) VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error) {
+	return volume.ListResponse{Volumes: c.volumes}, nil
+}
+
+func (c *testCleanupClient) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	c.removedVolumes = append(c.removedVolumes, volumeID)
+	return nil
+}
+
+func newTestCleanupClient() *testCleanupClient {
+	old := time.Now().Add(-2 * time.Hour)
+	labels := func(installation string) map[string]string {
+		return map[string]string{
+			driver.LabelDriver:       "docker",
+			driver.LabelInstallation: installation,
+			driver.LabelAction:       "install",
+			driver.LabelRevision:     "01FZVC5AVP8Z7A78CSCP1EJ604",
+		}
+	}
+
+	return &testCleanupClient{
+		containers: []types.Container{
+			{ID: "abc", Names: []string{"/cnab-mysql-install"}, State: "exited", Created: old.Unix(), Labels: labels("mysql")},
+			{ID: "def", Names: []string{"/cnab-wordpress-install"}, State: "running", Created: old.Unix(), Labels: labels("wordpress")},
+			{ID: "ghi", State: "created", Created: time.Now().Unix(), Labels: labels("wordpress")},
+		},
+		volumes: []*volume.Volume{
+			{Name: "mysql-data", CreatedAt: old.Format(time.RFC3339), Labels: labels("mysql")},
+		},
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("list", func(t *testing.T) {
+		cli := newTestCleanupClient()
+		orphans, err := cleanup(ctx, cli, driver.CleanupOptions{})
+		require.NoError(t, err)
+
+		require.Len(t, orphans, 3, "running containers should not be orphans")
+		assert.Equal(t, "container", orphans[0].Kind)
+		assert.Equal(t, "cnab-mysql-install", orphans[0].Name)
+		assert.Equal(t, "mysql", orphans[0].Installation)
+		assert.Equal(t, "install", orphans[0].Action)
+		assert.False(t, orphans[0].Deleted)
+		assert.Equal(t, "ghi", orphans[1].Name)
+		assert.Equal(t, "volume", orphans[2].Kind)
+		assert.Equal(t, "mysql-data", orphans[2].Name)
+
+		assert.Empty(t, cli.removedContainers, "nothing should be removed unless Delete is set")
+		assert.Empty(t, cli.removedVolumes, "nothing should be removed unless Delete is set")
+	})
+
+	t.Run("delete older than", func(t *testing.T) {
+		cli := newTestCleanupClient()
+		orphans, err := cleanup(ctx, cli, driver.CleanupOptions{Delete: true, OlderThan: time.Hour})
+		require.NoError(t, err)
+
+		require.Len(t, orphans, 2)
+		assert.True(t, orphans[0].Deleted)
+		assert.True(t, orphans[1].Deleted)
+		assert.Equal(t, []string{"abc"}, cli.removedContainers)
+		assert.Equal(t, []string{"mysql-data"}, cli.removedVolumes)
+	})
+
+	t.Run("installation", func(t *testing.T) {
+		cli := newTestCleanupClient()
+		orphans, err := cleanup(ctx, cli, driver.CleanupOptions{Installation: "wordpress"})
+		require.NoError(t, err)
+
+		require.Len(t, orphans, 1)
+		assert.Equal(t, "ghi", orphans[0].Name)
+	})
+}
@@ -25,11 +25,15 @@ const (
 // GetDockerClient creates a Docker CLI client that uses the user's Docker configuration
 // such as environment variables and the Docker home directory to initialize the client.
 func GetDockerClient() (*command.DockerCli, error) {
+	return newDockerClient(BuildDockerClientOptions())
+}
+
+// newDockerClient creates a Docker CLI client with the specified options.
+func newDockerClient(opts *cliflags.ClientOptions) (*command.DockerCli, error) {
 	cli, err := command.NewDockerCli()
 	if err != nil {
 		return nil, fmt.Errorf("could not create new docker client: %w", err)
 	}
-	opts := BuildDockerClientOptions()
 	if err = cli.Initialize(opts); err != nil {
 		return nil, fmt.Errorf("error initializing docker client: %w", err)
 	}
@@ -0,0 +1,274 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/cli/opts"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// SettingCommitContainers is the environment variable for the driver that
+	// specifies when the invocation image container is committed to an image
+	// after it ran, for example to analyze the files left by a failed
+	// install, either CommitNever, CommitOnFailure or CommitAlways. Defaults
+	// to CommitNever. The injected files, the files of the sensitive outputs
+	// and the environment variables of the operation are emptied in the
+	// committed image, so that it does not hold the credentials.
+	SettingCommitContainers = "DOCKER_COMMIT_CONTAINERS"
+
+	// SettingCommitRepository is the environment variable for the driver that
+	// specifies the repository of the committed images. Images are tagged
+	// with the installation and revision of the operation. Defaults to
+	// DefaultCommitRepository.
+	SettingCommitRepository = "DOCKER_COMMIT_REPOSITORY"
+
+	// SettingCommitMaxSize is the environment variable for the driver that
+	// specifies the maximum size of the files changed by the container for it
+	// to be committed, for example 512m or 2g. Larger containers are not
+	// committed. Defaults to DefaultCommitMaxSize.
+	SettingCommitMaxSize = "DOCKER_COMMIT_MAX_SIZE"
+
+	// CommitNever does not commit the container.
+	CommitNever = "never"
+
+	// CommitOnFailure commits the container when the operation failed.
+	CommitOnFailure = "on-failure"
+
+	// CommitAlways commits the container once it ran.
+	CommitAlways = "always"
+
+	// DefaultCommitRepository is the default repository of the committed
+	// images.
+	DefaultCommitRepository = "cnab-commits"
+
+	// DefaultCommitMaxSize is the default maximum size, in bytes, of the
+	// files changed by a container for it to be committed.
+	DefaultCommitMaxSize = 1 << 30
+)
+
+// Keys of the driver.OperationResult metadata describing the image that the
+// container was committed to.
+const (
+	// MetadataCommittedImage is the reference of the image that the
+	// container was committed to.
+	MetadataCommittedImage = "docker.committedImage"
+
+	// MetadataCommitSkipped is the reason that the container was not
+	// committed, when it should have been, for example because it was too
+	// large.
+	MetadataCommitSkipped = "docker.commitSkipped"
+)
+
+// invalidTagChars matches the characters that are not allowed in an image tag.
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// commitClient is the subset of the docker client used to commit a container.
+type commitClient interface {
+	ContainerInspectWithRaw(ctx context.Context, container string, getSize bool) (types.ContainerJSON, []byte, error)
+	ContainerCommit(ctx context.Context, container string, options container.CommitOptions) (types.IDResponse, error)
+	ContainerStatPath(ctx context.Context, container string, path string) (container.PathStat, error)
+	CopyToContainer(ctx context.Context, container string, path string, content io.Reader, options container.CopyToContainerOptions) error
+}
+
+// parseCommitSettings reads when and where the containers are committed from
+// the driver settings, falling back to the values already set on the driver.
+func (d *Driver) parseCommitSettings(settings map[string]string) error {
+	if value, ok := settings[SettingCommitContainers]; ok && value != "" {
+		switch value {
+		case CommitNever, CommitOnFailure, CommitAlways:
+			d.CommitPolicy = value
+		default:
+			return fmt.Errorf("environment variable %s has unexpected value %q. Supported values are '%s', '%s', '%s', or unset",
+				SettingCommitContainers, value, CommitNever, CommitOnFailure, CommitAlways)
+		}
+	}
+
+	if value, ok := settings[SettingCommitRepository]; ok && value != "" {
+		if _, err := reference.ParseNormalizedNamed(value); err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingCommitRepository, value, err)
+		}
+		d.CommitRepository = value
+	}
+
+	if value, ok := settings[SettingCommitMaxSize]; ok && value != "" {
+		var size opts.MemBytes
+		if err := size.Set(value); err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingCommitMaxSize, value, err)
+		}
+		d.CommitMaxSize = size.Value()
+	}
+
+	return nil
+}
+
+// shouldCommit determines if the container is committed according to the
+// commit policy of the driver.
+func (d *Driver) shouldCommit(succeeded bool) bool {
+	switch d.CommitPolicy {
+	case CommitAlways:
+		return true
+	case CommitOnFailure:
+		return !succeeded
+	default:
+		return false
+	}
+}
+
+// commitReference returns the reference of the image that the container of
+// the operation is committed to, REPOSITORY:INSTALLATION-REVISION.
+func (d *Driver) commitReference(op *driver.Operation) (string, error) {
+	repository := d.CommitRepository
+	if repository == "" {
+		repository = DefaultCommitRepository
+	}
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid commit repository %q", repository)
+	}
+
+	tag := op.Installation
+	if op.Revision != "" {
+		tag += "-" + op.Revision
+	}
+	// Tags cannot start with a dot or a dash, and are at most 128 characters
+	tag = strings.TrimLeft(invalidTagChars.ReplaceAllString(tag, "-"), ".-")
+	if tag == "" {
+		return "", errors.Errorf("cannot tag the committed image of installation %q", op.Installation)
+	}
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+
+	tagged, err := reference.WithTag(named, tag)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid commit tag %q", tag)
+	}
+	return reference.FamiliarString(tagged), nil
+}
+
+// commitContainer commits the container once it ran, according to the commit
+// policy of the driver, and records the reference of the image in the
+// metadata of the result. Containers whose changed files are larger than the
+// maximum size are not committed. Failures are reported to errOut but do not
+// fail the operation.
+//
+// The committed image must not leak the secrets of the operation, so the
+// injected files, which hold the credentials and parameters, and the files of
+// the sensitive outputs are emptied before the container is committed, and
+// the environment variables of the operation are cleared in the config of the
+// image. The container is not committed when they cannot be removed.
+func (d *Driver) commitContainer(ctx context.Context, cli commitClient, errOut io.Writer, id string, op *driver.Operation, files map[string]string, succeeded bool, opResult *driver.OperationResult) {
+	if !d.shouldCommit(succeeded) {
+		return
+	}
+	if opResult.Metadata == nil {
+		opResult.Metadata = map[string]string{}
+	}
+
+	maxSize := d.CommitMaxSize
+	if maxSize == 0 {
+		maxSize = DefaultCommitMaxSize
+	}
+	c, _, err := cli.ContainerInspectWithRaw(ctx, id, true)
+	if err != nil {
+		fmt.Fprintf(errOut, "unable to commit container %s: %v\n", id, err)
+		return
+	}
+	if c.SizeRw != nil && *c.SizeRw > maxSize {
+		opResult.Metadata[MetadataCommitSkipped] = fmt.Sprintf("the container changed %d bytes, which is more than the maximum of %d bytes", *c.SizeRw, maxSize)
+		return
+	}
+
+	ref, err := d.commitReference(op)
+	if err != nil {
+		fmt.Fprintf(errOut, "unable to commit container %s: %v\n", id, err)
+		return
+	}
+
+	if err := scrubContainer(ctx, cli, id, op, files); err != nil {
+		opResult.Metadata[MetadataCommitSkipped] = "the credentials and sensitive values could not be removed from the container"
+		fmt.Fprintf(errOut, "unable to commit container %s: %v\n", id, err)
+		return
+	}
+
+	_, err = cli.ContainerCommit(ctx, id, container.CommitOptions{
+		Reference: ref,
+		Comment:   fmt.Sprintf("%s of installation %s, revision %s", op.Action, op.Installation, op.Revision),
+		Config:    &container.Config{Env: scrubbedEnv(op)},
+	})
+	if err != nil {
+		fmt.Fprintf(errOut, "unable to commit container %s to %s: %v\n", id, ref, err)
+		return
+	}
+	opResult.Metadata[MetadataCommittedImage] = ref
+}
+
+// scrubContainer empties the files injected in the container, and the files
+// of the sensitive outputs written by the container.
+func scrubContainer(ctx context.Context, cli commitClient, id string, op *driver.Operation, files map[string]string) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	for path, output := range op.Outputs {
+		if op.Bundle == nil {
+			break
+		}
+		if sensitive, err := op.Bundle.IsOutputSensitive(output); err != nil || !sensitive {
+			continue
+		}
+		if _, ok := files[path]; ok {
+			continue
+		}
+		// Do not create the files of the outputs that were not written
+		if _, err := cli.ContainerStatPath(ctx, id, path); err != nil {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, path := range paths {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: path, Mode: 0600}); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := cli.CopyToContainer(ctx, id, "/", &buf, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("cannot empty the injected files: %w", err)
+	}
+	return nil
+}
+
+// scrubbedEnv returns the environment variables of the operation with empty
+// values. The docker daemon merges the environment variables of the container
+// into the config of the committed image, except those that the config
+// overrides.
+func scrubbedEnv(op *driver.Operation) []string {
+	env := make([]string, 0, len(op.Environment))
+	for key := range op.Environment {
+		env = append(env, key+"=")
+	}
+	sort.Strings(env)
+	return env
+}
@@ -0,0 +1,213 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+type testCommitClient struct {
+	sizeRw    int64
+	commitErr error
+	copyErr   error
+	committed []container.CommitOptions
+
+	// files of the container, by path
+	files map[string]string
+}
+
+func (c *testCommitClient) ContainerInspectWithRaw(ctx context.Context, id string, getSize bool) (types.ContainerJSON, []byte, error) {
+	if !getSize {
+		return types.ContainerJSON{}, nil, errors.New("the size of the container should be requested")
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: id, SizeRw: &c.sizeRw},
+	}, nil, nil
+}
+
+func (c *testCommitClient) ContainerCommit(ctx context.Context, id string, options container.CommitOptions) (types.IDResponse, error) {
+	if c.commitErr != nil {
+		return types.IDResponse{}, c.commitErr
+	}
+	c.committed = append(c.committed, options)
+	return types.IDResponse{ID: "sha256:1234"}, nil
+}
+
+func (c *testCommitClient) ContainerStatPath(ctx context.Context, id string, path string) (container.PathStat, error) {
+	if _, ok := c.files[path]; !ok {
+		return container.PathStat{}, errors.New("no such file")
+	}
+	return container.PathStat{Name: path}, nil
+}
+
+func (c *testCommitClient) CopyToContainer(ctx context.Context, id string, path string, content io.Reader, options container.CopyToContainerOptions) error {
+	if c.copyErr != nil {
+		return c.copyErr
+	}
+	if c.files == nil {
+		c.files = map[string]string{}
+	}
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		c.files[header.Name] = string(data)
+	}
+}
+
+func TestDriver_CommitContainer(t *testing.T) {
+	op := &driver.Operation{Installation: "mysql", Revision: "01FZVC5AVP8Z7A78CSCP1EJ604", Action: "install"}
+
+	testcases := []struct {
+		name      string
+		driver    Driver
+		succeeded bool
+		sizeRw    int64
+		commitErr error
+		metadata  map[string]string
+		errOut    string
+	}{
+		{name: "never", succeeded: false},
+		{name: "on failure, succeeded", driver: Driver{CommitPolicy: CommitOnFailure}, succeeded: true},
+		{name: "on failure, failed", driver: Driver{CommitPolicy: CommitOnFailure}, sizeRw: 1024,
+			metadata: map[string]string{MetadataCommittedImage: "cnab-commits:mysql-01FZVC5AVP8Z7A78CSCP1EJ604"}},
+		{name: "always", driver: Driver{CommitPolicy: CommitAlways, CommitRepository: "localhost:5000/forensics"}, succeeded: true,
+			metadata: map[string]string{MetadataCommittedImage: "localhost:5000/forensics:mysql-01FZVC5AVP8Z7A78CSCP1EJ604"}},
+		{name: "too large", driver: Driver{CommitPolicy: CommitAlways, CommitMaxSize: 100}, sizeRw: 1024,
+			metadata: map[string]string{MetadataCommitSkipped: "the container changed 1024 bytes, which is more than the maximum of 100 bytes"}},
+		{name: "commit failed", driver: Driver{CommitPolicy: CommitAlways}, commitErr: errors.New("no space left on device"),
+			metadata: map[string]string{}, errOut: "unable to commit container abc to cnab-commits:mysql-01FZVC5AVP8Z7A78CSCP1EJ604: no space left on device\n"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cli := &testCommitClient{sizeRw: tc.sizeRw, commitErr: tc.commitErr}
+			var errOut bytes.Buffer
+			var opResult driver.OperationResult
+			tc.driver.commitContainer(context.Background(), cli, &errOut, "abc", op, nil, tc.succeeded, &opResult)
+
+			assert.Equal(t, tc.metadata, opResult.Metadata)
+			assert.Equal(t, tc.errOut, errOut.String())
+			if ref, ok := tc.metadata[MetadataCommittedImage]; ok {
+				require.Len(t, cli.committed, 1)
+				assert.Equal(t, ref, cli.committed[0].Reference)
+				assert.Equal(t, "install of installation mysql, revision 01FZVC5AVP8Z7A78CSCP1EJ604", cli.committed[0].Comment)
+			} else {
+				assert.Empty(t, cli.committed)
+			}
+		})
+	}
+}
+
+func TestDriver_CommitContainer_Secrets(t *testing.T) {
+	op := &driver.Operation{
+		Installation: "mysql",
+		Revision:     "01FZVC5AVP8Z7A78CSCP1EJ604",
+		Action:       "install",
+		Environment:  map[string]string{"CNAB_P_PASSWORD": "topsecret", "CNAB_ACTION": "install"},
+		Outputs: map[string]string{
+			"/cnab/app/outputs/token":    "token",
+			"/cnab/app/outputs/endpoint": "endpoint",
+			"/cnab/app/outputs/missing":  "missing",
+		},
+		Bundle: &bundle.Bundle{
+			Definitions: definition.Definitions{
+				"secret": &definition.Schema{Type: "string", WriteOnly: &[]bool{true}[0]},
+				"string": &definition.Schema{Type: "string"},
+			},
+			Outputs: map[string]bundle.Output{
+				"token":    {Definition: "secret"},
+				"endpoint": {Definition: "string"},
+				"missing":  {Definition: "secret"},
+			},
+		},
+	}
+	files := map[string]string{
+		"/root/.kube/config":        "kubeconfig with a token",
+		"/cnab/app/parameters.json": `{"password":"topsecret"}`,
+	}
+	cli := &testCommitClient{files: map[string]string{
+		"/root/.kube/config":         "kubeconfig with a token",
+		"/cnab/app/parameters.json":  `{"password":"topsecret"}`,
+		"/cnab/app/outputs/token":    "topsecret-token",
+		"/cnab/app/outputs/endpoint": "https://example.com",
+	}}
+
+	var errOut bytes.Buffer
+	var opResult driver.OperationResult
+	d := Driver{CommitPolicy: CommitAlways}
+	d.commitContainer(context.Background(), cli, &errOut, "abc", op, files, true, &opResult)
+	require.Empty(t, errOut.String())
+	require.Len(t, cli.committed, 1)
+
+	assert.Equal(t, map[string]string{
+		"/root/.kube/config":         "",
+		"/cnab/app/parameters.json":  "",
+		"/cnab/app/outputs/token":    "",
+		"/cnab/app/outputs/endpoint": "https://example.com",
+	}, cli.files, "the injected files and sensitive outputs should be emptied before the container is committed")
+	require.NotNil(t, cli.committed[0].Config)
+	assert.Equal(t, []string{"CNAB_ACTION=", "CNAB_P_PASSWORD="}, cli.committed[0].Config.Env,
+		"the environment variables of the operation should be cleared in the committed image")
+
+	cli = &testCommitClient{copyErr: errors.New("container is gone")}
+	errOut.Reset()
+	opResult = driver.OperationResult{}
+	d.commitContainer(context.Background(), cli, &errOut, "abc", op, files, true, &opResult)
+	assert.Empty(t, cli.committed, "the container should not be committed with its secrets")
+	assert.Equal(t, "the credentials and sensitive values could not be removed from the container", opResult.Metadata[MetadataCommitSkipped])
+	assert.Equal(t, "unable to commit container abc: cannot empty the injected files: container is gone\n", errOut.String())
+}
+
+func TestDriver_CommitReference(t *testing.T) {
+	d := &Driver{}
+	ref, err := d.commitReference(&driver.Operation{Installation: "-my app/prod", Revision: "01FZVC5AVP8Z7A78CSCP1EJ604"})
+	require.NoError(t, err)
+	assert.Equal(t, "cnab-commits:my-app-prod-01FZVC5AVP8Z7A78CSCP1EJ604", ref)
+
+	_, err = d.commitReference(&driver.Operation{Installation: "..."})
+	assert.EqualError(t, err, `cannot tag the committed image of installation "..."`)
+}
+
+func TestDriver_SetConfig_Commit(t *testing.T) {
+	d := &Driver{}
+	require.NoError(t, d.SetConfig(map[string]string{
+		SettingCommitContainers: CommitOnFailure,
+		SettingCommitRepository: "localhost:5000/forensics",
+		SettingCommitMaxSize:    "512m",
+	}))
+	assert.Equal(t, CommitOnFailure, d.CommitPolicy)
+	assert.Equal(t, "localhost:5000/forensics", d.CommitRepository)
+	assert.Equal(t, int64(512*1024*1024), d.CommitMaxSize)
+
+	for setting, value := range map[string]string{
+		SettingCommitContainers: "sometimes",
+		SettingCommitRepository: "Not A Repository",
+		SettingCommitMaxSize:    "huge",
+	} {
+		err := (&Driver{}).SetConfig(map[string]string{setting: value})
+		require.Error(t, err, setting)
+		assert.Contains(t, err.Error(), "environment variable "+setting+" has unexpected value", setting)
+	}
+}
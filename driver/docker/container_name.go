@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// SettingContainerNameTemplate is the environment variable for the driver
+	// that specifies a Go template used to name the invocation image container.
+	// The template is executed with ContainerNameData. Set it to an empty
+	// value to let docker generate a random name.
+	SettingContainerNameTemplate = "DOCKER_CONTAINER_NAME_TEMPLATE"
+
+	// DefaultContainerNameTemplate is the template used to name containers
+	// when SettingContainerNameTemplate is not set.
+	DefaultContainerNameTemplate = "cnab-{{.Installation}}-{{.Action}}-{{.ShortRevision}}"
+
+	// maxContainerNameAttempts is the number of suffixed names tried when the
+	// generated container name is already in use.
+	maxContainerNameAttempts = 10
+
+	// shortRevisionLength is the number of characters of the revision used in ShortRevision.
+	shortRevisionLength = 8
+)
+
+// invalidContainerNameChars matches characters that are not allowed in a docker container name.
+var invalidContainerNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// ContainerNameData is the data available to the container name template.
+type ContainerNameData struct {
+	// Installation name.
+	Installation string
+
+	// Action being executed.
+	Action string
+
+	// Revision of the installation.
+	Revision string
+
+	// ShortRevision is the first 8 characters of the revision.
+	ShortRevision string
+
+	// Bundle name.
+	Bundle string
+}
+
+// parseContainerNameTemplate parses the container name template from the
+// driver settings. A nil template indicates that docker should generate the name.
+func parseContainerNameTemplate(settings map[string]string) (*template.Template, error) {
+	tmplText, ok := settings[SettingContainerNameTemplate]
+	if !ok {
+		tmplText = DefaultContainerNameTemplate
+	}
+
+	if tmplText == "" {
+		return nil, nil
+	}
+
+	return template.New("container-name").Option("missingkey=error").Parse(tmplText)
+}
+
+// generateContainerName names the invocation image container for the operation
+// using the configured template. An empty name indicates that docker should
+// generate the name.
+func (d *Driver) generateContainerName(op *driver.Operation) (string, error) {
+	tmpl, err := parseContainerNameTemplate(d.config)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", SettingContainerNameTemplate, err)
+	}
+	if tmpl == nil {
+		return "", nil
+	}
+
+	data := ContainerNameData{
+		Installation:  op.Installation,
+		Action:        op.Action,
+		Revision:      op.Revision,
+		ShortRevision: op.Revision,
+	}
+	if len(data.ShortRevision) > shortRevisionLength {
+		data.ShortRevision = data.ShortRevision[:shortRevisionLength]
+	}
+	if op.Bundle != nil {
+		data.Bundle = op.Bundle.Name
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not generate the container name from %s: %w", SettingContainerNameTemplate, err)
+	}
+
+	return sanitizeContainerName(buf.String()), nil
+}
+
+// sanitizeContainerName replaces characters that docker does not allow in a
+// container name, and trims separators left over from empty template values.
+func sanitizeContainerName(name string) string {
+	name = invalidContainerNameChars.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-_.")
+}
+
+// createContainer creates the container with the requested name. When the
+// name is already in use, for example by a container kept from a previous run
+// of the same revision, a numeric suffix is added to the name.
+func createContainer(name string, create func(name string) (container.CreateResponse, error)) (container.CreateResponse, error) {
+	if name == "" {
+		return create("")
+	}
+
+	candidate := name
+	for attempt := 1; ; attempt++ {
+		resp, err := create(candidate)
+		if err == nil || !errdefs.IsConflict(err) || attempt >= maxContainerNameAttempts {
+			return resp, err
+		}
+		candidate = fmt.Sprintf("%s-%d", name, attempt+1)
+	}
+}
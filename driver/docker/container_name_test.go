@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_GenerateContainerName(t *testing.T) {
+	op := &driver.Operation{
+		Installation: "my app",
+		Action:       "install",
+		Revision:     "01FZVC5AVP8Z7A78CSCP1EJ604",
+		Bundle:       &bundle.Bundle{Name: "mybuns"},
+	}
+
+	testcases := []struct {
+		name     string
+		settings map[string]string
+		want     string
+	}{
+		{
+			name:     "default template",
+			settings: map[string]string{},
+			want:     "cnab-my-app-install-01FZVC5A",
+		},
+		{
+			name:     "custom template",
+			settings: map[string]string{SettingContainerNameTemplate: "{{.Bundle}}_{{.Installation}}.{{.Revision}}"},
+			want:     "mybuns_my-app.01FZVC5AVP8Z7A78CSCP1EJ604",
+		},
+		{
+			name:     "random name",
+			settings: map[string]string{SettingContainerNameTemplate: ""},
+			want:     "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &Driver{}
+			require.NoError(t, d.SetConfig(tc.settings))
+
+			name, err := d.generateContainerName(op)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, name)
+		})
+	}
+}
+
+func TestDriver_SetConfig_InvalidContainerNameTemplate(t *testing.T) {
+	d := &Driver{}
+	err := d.SetConfig(map[string]string{SettingContainerNameTemplate: "{{.Installation"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "environment variable DOCKER_CONTAINER_NAME_TEMPLATE is invalid")
+}
+
+func TestSanitizeContainerName(t *testing.T) {
+	assert.Equal(t, "cnab-my-app-install", sanitizeContainerName("cnab-my app/install-"))
+	assert.Equal(t, "app.v1_test", sanitizeContainerName("--app.v1_test"))
+}
+
+func TestCreateContainer(t *testing.T) {
+	t.Run("name available", func(t *testing.T) {
+		var attempts []string
+		_, err := createContainer("cnab-app", func(name string) (container.CreateResponse, error) {
+			attempts = append(attempts, name)
+			return container.CreateResponse{ID: "abc"}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"cnab-app"}, attempts)
+	})
+
+	t.Run("name conflict", func(t *testing.T) {
+		var attempts []string
+		resp, err := createContainer("cnab-app", func(name string) (container.CreateResponse, error) {
+			attempts = append(attempts, name)
+			if len(attempts) < 3 {
+				return container.CreateResponse{}, errdefs.Conflict(errors.New("name in use"))
+			}
+			return container.CreateResponse{ID: "abc"}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "abc", resp.ID)
+		assert.Equal(t, []string{"cnab-app", "cnab-app-2", "cnab-app-3"}, attempts)
+	})
+
+	t.Run("too many conflicts", func(t *testing.T) {
+		attempts := 0
+		_, err := createContainer("cnab-app", func(name string) (container.CreateResponse, error) {
+			attempts++
+			return container.CreateResponse{}, errdefs.Conflict(errors.New("name in use"))
+		})
+		require.Error(t, err)
+		assert.Equal(t, maxContainerNameAttempts, attempts)
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		attempts := 0
+		_, err := createContainer("cnab-app", func(name string) (container.CreateResponse, error) {
+			attempts++
+			return container.CreateResponse{}, errors.New("boom")
+		})
+		require.EqualError(t, err, "boom")
+		assert.Equal(t, 1, attempts)
+	})
+}
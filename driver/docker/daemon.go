@@ -0,0 +1,259 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/cli/cli/command"
+	cliconfig "github.com/docker/cli/cli/config"
+	cliflags "github.com/docker/cli/cli/flags"
+	"github.com/docker/cli/opts"
+	"github.com/docker/go-connections/tlsconfig"
+)
+
+const (
+	// SettingHost is the environment variable for the driver that specifies
+	// the address of the docker daemon, for example unix:///var/run/docker.sock,
+	// tcp://docker.example.com:2376 or ssh://me@docker.example.com.
+	SettingHost = "DOCKER_HOST"
+
+	// SettingContext is the environment variable for the driver that
+	// specifies the docker context used to connect to the docker daemon, for
+	// example the rootless context created by dockerd-rootless-setuptool.sh.
+	SettingContext = "DOCKER_CONTEXT"
+
+	// SettingTLSVerify is the environment variable for the driver that
+	// connects to the docker daemon with TLS. When true, the certificate of
+	// the docker daemon is verified, and when false it is not.
+	SettingTLSVerify = DockerTLSVerifyEnvVar
+
+	// SettingCertPath is the environment variable for the driver that
+	// specifies the directory of the TLS certificates used to connect to the
+	// docker daemon: ca.pem, cert.pem and key.pem. Defaults to the docker
+	// config directory.
+	SettingCertPath = DockerCertPathEnvVar
+
+	// SettingRootless is the environment variable for the driver that
+	// connects to the rootless docker daemon of the current user, through
+	// the socket in $XDG_RUNTIME_DIR.
+	SettingRootless = "DOCKER_ROOTLESS"
+
+	// daemonPingTimeout is how long SetConfig waits for the docker daemon to
+	// respond when the connection to the daemon is configured.
+	daemonPingTimeout = 10 * time.Second
+)
+
+// DaemonConfig is how the driver connects to the docker daemon. When it is
+// empty, the driver connects like the docker CLI does, from the DOCKER_HOST,
+// DOCKER_CONTEXT, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH variables of the
+// environment and the current docker context.
+type DaemonConfig struct {
+	// Host is the address of the docker daemon, with the unix, tcp, npipe or
+	// ssh scheme.
+	Host string
+
+	// Context is the name of the docker context used to connect to the docker
+	// daemon. It cannot be set with Host.
+	Context string
+
+	// TLS connects to the docker daemon with TLS. It is not supported with an
+	// ssh:// Host, which is secured by ssh.
+	TLS bool
+
+	// TLSVerify verifies the certificate of the docker daemon against the
+	// ca.pem of CertPath. It requires TLS.
+	TLSVerify bool
+
+	// CertPath is the directory of the TLS certificates. Defaults to the
+	// docker config directory.
+	CertPath string
+
+	// Rootless connects to the rootless docker daemon of the current user. It
+	// cannot be set with Host or Context.
+	Rootless bool
+}
+
+// IsZero returns true when the connection to the docker daemon is not
+// configured, and the driver connects from the environment.
+func (c DaemonConfig) IsZero() bool {
+	return c == DaemonConfig{}
+}
+
+// Validate checks that the settings are consistent, and that the TLS
+// certificates exist.
+func (c DaemonConfig) Validate() error {
+	if c.Host != "" && c.Context != "" {
+		return fmt.Errorf("%s and %s cannot both be set, the docker context specifies the docker host", SettingHost, SettingContext)
+	}
+	if c.Rootless && (c.Host != "" || c.Context != "") {
+		return fmt.Errorf("%s cannot be set with %s or %s", SettingRootless, SettingHost, SettingContext)
+	}
+
+	if c.Host != "" {
+		if _, err := opts.ParseHost(c.TLS, c.Host); err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingHost, c.Host, err)
+		}
+		if c.TLS && strings.HasPrefix(c.Host, "ssh://") {
+			return fmt.Errorf("%s cannot be set with an ssh:// %s, the connection is secured by ssh", SettingTLSVerify, SettingHost)
+		}
+	}
+
+	if c.TLSVerify && !c.TLS {
+		return fmt.Errorf("verifying the certificate of the docker daemon requires TLS")
+	}
+	if c.CertPath != "" && !c.TLS {
+		return fmt.Errorf("%s requires %s to be set", SettingCertPath, SettingTLSVerify)
+	}
+	if c.TLS {
+		return c.validateCertificates()
+	}
+	return nil
+}
+
+// validateCertificates checks that the CA certificate exists when the
+// certificate of the daemon is verified, and that the client certificate
+// and key are either both present or both absent.
+func (c DaemonConfig) validateCertificates() error {
+	tlsOptions := c.tlsOptions()
+	if c.CertPath != "" {
+		if fi, err := os.Stat(c.CertPath); err != nil || !fi.IsDir() {
+			return fmt.Errorf("environment variable %s has unexpected value %q: it must be a directory", SettingCertPath, c.CertPath)
+		}
+	}
+
+	if c.TLSVerify && !fileExists(tlsOptions.CAFile) {
+		return fmt.Errorf("the CA certificate %s is required to verify the certificate of the docker daemon", tlsOptions.CAFile)
+	}
+	if fileExists(tlsOptions.CertFile) != fileExists(tlsOptions.KeyFile) {
+		return fmt.Errorf("the client certificate %s and key %s must be specified together", tlsOptions.CertFile, tlsOptions.KeyFile)
+	}
+	return nil
+}
+
+// tlsOptions returns the paths of the TLS certificates. Certificates that do
+// not exist are not used by the client.
+func (c DaemonConfig) tlsOptions() *tlsconfig.Options {
+	certPath := c.CertPath
+	if certPath == "" {
+		certPath = cliconfig.Dir()
+	}
+	return &tlsconfig.Options{
+		CAFile:   filepath.Join(certPath, cliflags.DefaultCaFile),
+		CertFile: filepath.Join(certPath, cliflags.DefaultCertFile),
+		KeyFile:  filepath.Join(certPath, cliflags.DefaultKeyFile),
+	}
+}
+
+// ClientOptions returns the options of the docker CLI client that connects to
+// the docker daemon.
+func (c DaemonConfig) ClientOptions() *cliflags.ClientOptions {
+	if c.IsZero() {
+		return BuildDockerClientOptions()
+	}
+
+	cliOpts := cliflags.NewClientOptions()
+	cliOpts.ConfigDir = cliconfig.Dir()
+	cliOpts.Context = c.Context
+	if c.Host != "" {
+		cliOpts.Hosts = []string{c.Host}
+	} else if c.Rootless {
+		cliOpts.Hosts = []string{"unix://" + rootlessSocket()}
+	}
+
+	if c.TLS {
+		cliOpts.TLS = true
+		cliOpts.TLSVerify = c.TLSVerify
+		tlsOptions := c.tlsOptions()
+		// Unlike the docker CLI flags, the client fails when a certificate
+		// does not exist, so only the certificates that exist are used
+		if !fileExists(tlsOptions.CAFile) {
+			tlsOptions.CAFile = ""
+		}
+		if !fileExists(tlsOptions.CertFile) || !fileExists(tlsOptions.KeyFile) {
+			tlsOptions.CertFile = ""
+			tlsOptions.KeyFile = ""
+		}
+		cliOpts.TLSOptions = tlsOptions
+	}
+	return cliOpts
+}
+
+// rootlessSocket returns the path of the socket of the rootless docker daemon
+// of the current user.
+func rootlessSocket() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join("/run/user", strconv.Itoa(os.Getuid()))
+	}
+	return filepath.Join(runtimeDir, "docker.sock")
+}
+
+func fileExists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
+
+// parseDaemonSettings reads how to connect to the docker daemon from the
+// driver settings, falling back to the configuration already set on the
+// driver.
+func (d *Driver) parseDaemonSettings(settings map[string]string) error {
+	if value, ok := settings[SettingHost]; ok && value != "" {
+		d.Daemon.Host = value
+	}
+	if value, ok := settings[SettingContext]; ok && value != "" {
+		d.Daemon.Context = value
+	}
+	if value, ok := settings[SettingTLSVerify]; ok && value != "" {
+		verify, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q, it must be true or false", SettingTLSVerify, value)
+		}
+		// As with the docker CLI, TLS is used whenever the variable is set,
+		// and false only skips verifying the certificate of the daemon
+		d.Daemon.TLS = true
+		d.Daemon.TLSVerify = verify
+	}
+	if value, ok := settings[SettingCertPath]; ok && value != "" {
+		d.Daemon.CertPath = value
+	}
+	if value, ok := settings[SettingRootless]; ok && value != "" {
+		rootless, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q, it must be true or false", SettingRootless, value)
+		}
+		d.Daemon.Rootless = rootless
+	}
+
+	return d.Daemon.Validate()
+}
+
+// pingDaemon checks that the docker daemon configured on the driver responds,
+// so that a misconfigured connection fails when the driver is configured
+// instead of when the invocation image is run.
+func (d *Driver) pingDaemon() error {
+	cli, err := d.initializeDockerCli()
+	if err != nil {
+		return err
+	}
+
+	if dockerCli, ok := cli.(*command.DockerCli); ok {
+		// The client exits the process when its context cannot be resolved,
+		// so resolve it first
+		name := dockerCli.CurrentContext()
+		if _, err := dockerCli.ContextStore().GetMetadata(name); err != nil {
+			return fmt.Errorf("could not load docker context %s: %w", name, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonPingTimeout)
+	defer cancel()
+	if _, err := cli.Client().Ping(ctx); err != nil {
+		return fmt.Errorf("could not connect to the docker daemon %s: %w", cli.Client().DaemonHost(), err)
+	}
+	return nil
+}
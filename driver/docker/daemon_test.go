@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemonConfig_Validate(t *testing.T) {
+	certs := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(certs, "ca.pem"), []byte("ca"), 0600))
+	clientCerts := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(clientCerts, "cert.pem"), []byte("cert"), 0600))
+
+	testcases := []struct {
+		name      string
+		config    DaemonConfig
+		wantError string
+	}{
+		{name: "environment", config: DaemonConfig{}},
+		{name: "unix socket", config: DaemonConfig{Host: "unix:///var/run/docker.sock"}},
+		{name: "ssh", config: DaemonConfig{Host: "ssh://me@docker.example.com"}},
+		{name: "context", config: DaemonConfig{Context: "rootless"}},
+		{name: "rootless", config: DaemonConfig{Rootless: true}},
+		{name: "tls", config: DaemonConfig{Host: "tcp://docker.example.com:2376", TLS: true, TLSVerify: true, CertPath: certs}},
+		{name: "missing cert path", config: DaemonConfig{Host: "tcp://docker.example.com:2376", TLS: true, CertPath: clientCerts + "-missing"}, wantError: "must be a directory"},
+		{name: "invalid host", config: DaemonConfig{Host: "http://docker.example.com"}, wantError: "environment variable DOCKER_HOST has unexpected value"},
+		{name: "host and context", config: DaemonConfig{Host: "unix:///var/run/docker.sock", Context: "rootless"}, wantError: "DOCKER_HOST and DOCKER_CONTEXT cannot both be set"},
+		{name: "rootless with host", config: DaemonConfig{Host: "unix:///var/run/docker.sock", Rootless: true}, wantError: "DOCKER_ROOTLESS cannot be set with DOCKER_HOST or DOCKER_CONTEXT"},
+		{name: "tls over ssh", config: DaemonConfig{Host: "ssh://me@docker.example.com", TLS: true, CertPath: certs}, wantError: "the connection is secured by ssh"},
+		{name: "verify without tls", config: DaemonConfig{TLSVerify: true}, wantError: "requires TLS"},
+		{name: "cert path without tls", config: DaemonConfig{CertPath: certs}, wantError: "DOCKER_CERT_PATH requires DOCKER_TLS_VERIFY to be set"},
+		{name: "missing CA", config: DaemonConfig{TLS: true, TLSVerify: true, CertPath: clientCerts}, wantError: "the CA certificate " + filepath.Join(clientCerts, "ca.pem") + " is required"},
+		{name: "certificate without key", config: DaemonConfig{TLS: true, CertPath: clientCerts}, wantError: "must be specified together"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.wantError == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantError)
+			}
+		})
+	}
+}
+
+func TestDaemonConfig_ClientOptions(t *testing.T) {
+	t.Run("rootless", func(t *testing.T) {
+		t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+		opts := DaemonConfig{Rootless: true}.ClientOptions()
+		assert.Equal(t, []string{"unix:///run/user/1000/docker.sock"}, opts.Hosts)
+		assert.False(t, opts.TLS)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		certs := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(certs, "ca.pem"), []byte("ca"), 0600))
+		opts := DaemonConfig{Host: "tcp://docker.example.com:2376", TLS: true, TLSVerify: true, CertPath: certs}.ClientOptions()
+		assert.Equal(t, []string{"tcp://docker.example.com:2376"}, opts.Hosts)
+		assert.True(t, opts.TLS)
+		assert.True(t, opts.TLSVerify)
+		require.NotNil(t, opts.TLSOptions)
+		assert.Equal(t, filepath.Join(certs, "ca.pem"), opts.TLSOptions.CAFile)
+		assert.Empty(t, opts.TLSOptions.CertFile, "a client certificate that does not exist should not be used")
+	})
+
+	t.Run("context", func(t *testing.T) {
+		opts := DaemonConfig{Context: "rootless"}.ClientOptions()
+		assert.Equal(t, "rootless", opts.Context)
+		assert.Empty(t, opts.Hosts)
+	})
+}
+
+func TestDriver_SetConfig_Daemon(t *testing.T) {
+	t.Run("reachable daemon", func(t *testing.T) {
+		var pinged bool
+		daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/_ping") {
+				pinged = true
+				w.Header().Set("API-Version", "1.45")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer daemon.Close()
+
+		host := "tcp://" + strings.TrimPrefix(daemon.URL, "http://")
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{SettingHost: host}))
+		assert.True(t, pinged, "the docker daemon should be pinged")
+		assert.Equal(t, host, d.Daemon.Host)
+		require.NotNil(t, d.dockerCli)
+		assert.Equal(t, host, d.dockerCli.Client().DaemonHost())
+	})
+
+	t.Run("unreachable daemon", func(t *testing.T) {
+		d := &Driver{}
+		err := d.SetConfig(map[string]string{SettingHost: "unix://" + filepath.Join(t.TempDir(), "docker.sock")})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not connect to the docker daemon unix://")
+		assert.Nil(t, d.dockerCli, "the cli of an unreachable daemon should not be kept")
+	})
+
+	t.Run("unknown context", func(t *testing.T) {
+		d := &Driver{}
+		err := d.SetConfig(map[string]string{SettingContext: "missing-context"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not load docker context missing-context")
+	})
+
+	t.Run("invalid setting", func(t *testing.T) {
+		d := &Driver{}
+		err := d.SetConfig(map[string]string{SettingTLSVerify: "yes"})
+		assert.EqualError(t, err, `environment variable DOCKER_TLS_VERIFY has unexpected value "yes", it must be true or false`)
+	})
+
+	t.Run("simulate", func(t *testing.T) {
+		d := &Driver{Simulate: true}
+		require.NoError(t, d.SetConfig(map[string]string{SettingHost: "unix://" + filepath.Join(t.TempDir(), "docker.sock")}))
+	})
+}
@@ -13,9 +13,11 @@ import (
 
 	"github.com/distribution/reference"
 	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
 	registrytypes "github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/client"
@@ -31,7 +33,9 @@ import (
 
 const (
 	// SettingNetwork is the environment variable for the driver that specifies
-	// the docker network to which the invocation image should be attached.
+	// the docker networks to which the invocation image should be attached,
+	// separated by whitespace, each in the format NAME[=ALIAS[,ALIAS...]], for
+	// example "backend=db,mysql frontend".
 	SettingNetwork = "DOCKER_NETWORK"
 )
 
@@ -46,6 +50,92 @@ type Driver struct {
 	containerErr               io.Writer
 	containerHostCfg           container.HostConfig
 	containerCfg               container.Config
+	containerNetworkingCfg     network.NetworkingConfig
+	mounts                     []VolumeMount
+	serverVersion              *types.Version
+
+	// LimitCPU is the number of CPUs available to the invocation image, in
+	// units of 1e-9 CPUs. Zero does not limit the CPU.
+	LimitCPU int64
+
+	// LimitMemory is the memory limit for the invocation image, in bytes.
+	// Zero does not limit the memory.
+	LimitMemory int64
+
+	// LimitPids is the maximum number of processes in the invocation image.
+	// Zero uses the docker daemon default, and -1 is unlimited.
+	LimitPids int64
+
+	// User that runs the invocation image, in the format UID[:GID]. Defaults
+	// to the user configured by the image.
+	User string
+
+	// Entrypoint overrides the entrypoint of the invocation image, for
+	// example to run /cnab/app/run with an init process. Defaults to
+	// /cnab/app/run.
+	Entrypoint []string
+
+	// Cmd is the arguments passed to the entrypoint of the invocation image.
+	Cmd []string
+
+	// WorkingDir is the absolute path of the working directory of the
+	// invocation image. Defaults to the working directory configured by the
+	// image.
+	WorkingDir string
+
+	// Networks that the invocation image is attached to. Defaults to the
+	// default network of the docker daemon.
+	Networks []NetworkAttachment
+
+	// ExtraHosts are additional entries for the /etc/hosts file of the
+	// invocation image, in the format HOST:IP.
+	ExtraHosts []string
+
+	// CleanupPolicy specifies when the invocation image container is removed
+	// after it ran, either CleanupAlways, CleanupOnSuccess or CleanupNever.
+	// SetConfig defaults it to CleanupAlways, a driver that is not configured
+	// keeps its containers.
+	CleanupPolicy string
+
+	// Daemon is how the driver connects to the docker daemon. Defaults to
+	// the docker configuration of the environment.
+	Daemon DaemonConfig
+
+	// FilesInclude are .dockerignore patterns selecting the files of the
+	// operation that are injected into the invocation image. Defaults to
+	// every file.
+	FilesInclude []string
+
+	// FilesExclude are .dockerignore patterns excluding files of the
+	// operation from being injected into the invocation image. The files
+	// required by the CNAB runtime, such as /cnab/bundle.json, are always
+	// injected.
+	FilesExclude []string
+
+	// RetainFailedContainers is how many of the most recent failed containers
+	// of an installation are kept with the CleanupOnSuccess policy, older
+	// failed containers of the installation are removed. Zero keeps every
+	// failed container.
+	RetainFailedContainers int
+
+	// AllowedCapabilities are the docker capabilities that bundles may
+	// request with the io.cnab.docker extension, for example
+	// extensions.DockerCapabilityPrivileged. Bundles requesting other
+	// capabilities are not run.
+	AllowedCapabilities []string
+
+	// CommitPolicy specifies when the invocation image container is committed
+	// to an image after it ran, for forensic analysis, either CommitNever,
+	// CommitOnFailure or CommitAlways. Defaults to CommitNever.
+	CommitPolicy string
+
+	// CommitRepository is the repository of the committed images. Defaults to
+	// DefaultCommitRepository.
+	CommitRepository string
+
+	// CommitMaxSize is the maximum size, in bytes, of the files changed by
+	// the container for it to be committed. Defaults to DefaultCommitMaxSize.
+	CommitMaxSize int64
 }
 
 // Run executes the Docker driver
@@ -98,28 +188,95 @@ func (d *Driver) GetContainerHostConfig() (container.HostConfig, error) {
 // Config returns the Docker driver configuration options
 func (d *Driver) Config() map[string]string {
 	return map[string]string{
-		"PULL_ALWAYS":         "Always pull image, even if locally available (0|1)",
-		"DOCKER_DRIVER_QUIET": "Make the Docker driver quiet (only print container stdout/stderr)",
-		"CLEANUP_CONTAINERS":  "If true, the docker container will be destroyed when it finishes running. If false, it will not be destroyed. The supported values are true and false. Defaults to true.",
-		SettingNetwork:        "Attach the invocation image to the specified docker network",
+		"PULL_ALWAYS":                "Always pull image, even if locally available (0|1)",
+		"DOCKER_DRIVER_QUIET":        "Make the Docker driver quiet (only print container stdout/stderr)",
+		SettingCleanupContainers:     "When the docker container is destroyed after it ran: always, on-success to keep failed containers for debugging, or never. The values true and false are equivalent to always and never. Defaults to always.",
+		SettingCleanupRetainFailed:   "Number of the most recent failed containers of an installation kept when " + SettingCleanupContainers + " is on-success, older failed containers of the installation are destroyed. Defaults to 0, which keeps every failed container.",
+		SettingNetwork:               "Attach the invocation image to the specified docker networks, separated by whitespace, in the format NAME[=ALIAS[,ALIAS...]]",
+		SettingExtraHosts:            "Additional entries for /etc/hosts in the invocation image, separated by whitespace, in the format HOST:IP",
+		SettingContainerNameTemplate: "Go template used to name the invocation image container, for example " + DefaultContainerNameTemplate + ". Set to an empty value to use a random name. Defaults to " + DefaultContainerNameTemplate,
+		SettingCPULimit:              "Number of CPUs available to the invocation image, for example 1.5",
+		SettingMemoryLimit:           "Memory limit for the invocation image, for example 512m or 2g",
+		SettingPidsLimit:             "Maximum number of processes in the invocation image, -1 for unlimited",
+		SettingUser:                  "User that runs the invocation image, in the format UID[:GID], for example 1000:1000",
+		SettingEntrypoint:            "Entrypoint of the invocation image, as a JSON array or separated by whitespace. Defaults to /cnab/app/run",
+		SettingCmd:                   "Arguments passed to the entrypoint of the invocation image, as a JSON array or separated by whitespace",
+		SettingWorkingDir:            "Absolute path of the working directory of the invocation image",
+		SettingMounts:                "Host paths or docker volumes to mount into the invocation image, separated by whitespace, in the format SOURCE:TARGET[:ro|rw]",
+		SettingHost:                  "Address of the docker daemon, for example unix:///var/run/docker.sock, tcp://docker.example.com:2376 or ssh://me@docker.example.com. Defaults to the docker configuration of the environment",
+		SettingContext:               "Docker context used to connect to the docker daemon, for example rootless",
+		SettingTLSVerify:             "Connect to the docker daemon with TLS. When true the certificate of the docker daemon is verified, when false it is not (true|false)",
+		SettingCertPath:              "Directory of the TLS certificates used to connect to the docker daemon: ca.pem, cert.pem and key.pem. Defaults to the docker config directory",
+		SettingRootless:              "Connect to the rootless docker daemon of the current user, through $XDG_RUNTIME_DIR/docker.sock (true|false)",
+		SettingFilesInclude:          "Files of the operation injected into the invocation image, as .dockerignore patterns separated by whitespace, for example cnab/app/**. Defaults to every file",
+		SettingFilesExclude:          "Files of the operation not injected into the invocation image, as .dockerignore patterns separated by whitespace. Patterns starting with ! are exceptions",
+		SettingAllowedCapabilities:   "Docker capabilities that bundles may request with the io.cnab.docker extension, separated by whitespace or commas: privileged, host-network. Defaults to none",
+		SettingCommitContainers:      "When the docker container is committed to an image after it ran, for forensic analysis: never, on-failure or always. Defaults to never",
+		SettingCommitRepository:      "Repository of the images that containers are committed to, tagged with the installation and revision. Defaults to " + DefaultCommitRepository,
+		SettingCommitMaxSize:         "Maximum size of the files changed by a container for it to be committed, for example 512m or 2g. Defaults to 1g",
 	}
 }
 
 // SetConfig sets Docker driver configuration
 func (d *Driver) SetConfig(settings map[string]string) error {
-	// Set default and provide feedback on acceptable input values.
-	value, ok := settings["CLEANUP_CONTAINERS"]
-	if !ok {
-		settings["CLEANUP_CONTAINERS"] = "true"
-	} else if value != "true" && value != "false" {
-		return fmt.Errorf("environment variable CLEANUP_CONTAINERS has unexpected value %q. Supported values are 'true', 'false', or unset", value)
+	if err := d.parseCleanupSettings(settings); err != nil {
+		return err
+	}
+
+	if _, err := parseContainerNameTemplate(settings); err != nil {
+		return fmt.Errorf("environment variable %s is invalid: %w", SettingContainerNameTemplate, err)
+	}
+
+	if _, err := parseVolumeMounts(settings[SettingMounts]); err != nil {
+		return fmt.Errorf("environment variable %s is invalid: %w", SettingMounts, err)
+	}
+
+	if err := d.parseResourceLimits(settings); err != nil {
+		return err
+	}
+
+	if err := d.parseProcessSettings(settings); err != nil {
+		return err
+	}
+
+	if err := d.parseNetworkSettings(settings); err != nil {
+		return err
+	}
+
+	if err := d.parseDaemonSettings(settings); err != nil {
+		return err
+	}
+
+	if err := d.parseFileFilterSettings(settings); err != nil {
+		return err
+	}
+
+	if err := d.parseCapabilitySettings(settings); err != nil {
+		return err
+	}
+
+	if err := d.parseCommitSettings(settings); err != nil {
+		return err
 	}
 
 	d.config = settings
+
+	// Fail fast when the connection to the docker daemon is configured but
+	// the daemon cannot be reached
+	if !d.Daemon.IsZero() && !d.Simulate {
+		// Connect with the configured settings instead of a cli that was
+		// initialized before
+		d.dockerCli = nil
+		if err := d.pingDaemon(); err != nil {
+			d.dockerCli = nil
+			return err
+		}
+	}
 	return nil
 }
 
-// SetDockerCli makes the driver use an already initialized cli
+// SetDockerCli makes the driver use an already initialized cli. The cli is
+// replaced when SetConfig configures the connection to the docker daemon.
 func (d *Driver) SetDockerCli(dockerCli command.Cli) {
 	d.dockerCli = dockerCli
 }
@@ -134,7 +291,7 @@ func (d *Driver) SetContainerErr(w io.Writer) {
 	d.containerErr = w
 }
 
-func pullImage(ctx context.Context, cli command.Cli, imageName string) error {
+func pullImage(ctx context.Context, cli command.Cli, imageName string, auth *driver.RegistryAuth) error {
 	ref, err := reference.ParseNormalizedNamed(imageName)
 	if err != nil {
 		return err
@@ -145,7 +302,10 @@ func pullImage(ctx context.Context, cli command.Cli, imageName string) error {
 	if err != nil {
 		return err
 	}
-	authConfig := command.ResolveAuthConfig(cli.ConfigFile(), repoInfo.Index)
+	authConfig, err := resolveAuthConfig(cli, repoInfo.Index, auth)
+	if err != nil {
+		return err
+	}
 	encodedAuth, err := registrytypes.EncodeAuthConfig(authConfig)
 	if err != nil {
 		return err
@@ -163,12 +323,45 @@ func pullImage(ctx context.Context, cli command.Cli, imageName string) error {
 	return jsonmessage.DisplayJSONMessagesStream(responseBody, cli.Out(), cli.Out().FD(), false, nil)
 }
 
+// resolveAuthConfig returns the credentials for the registry, from the
+// operation's registry auth when it is set, and otherwise from the docker
+// config file of the cli.
+func resolveAuthConfig(cli command.Cli, index *registrytypes.IndexInfo, auth *driver.RegistryAuth) (registrytypes.AuthConfig, error) {
+	if auth == nil {
+		return command.ResolveAuthConfig(cli.ConfigFile(), index), nil
+	}
+
+	if err := auth.Validate(); err != nil {
+		return registrytypes.AuthConfig{}, err
+	}
+
+	if auth.DockerConfig != "" {
+		cfg := configfile.New("")
+		if err := cfg.LoadFromReader(strings.NewReader(auth.DockerConfig)); err != nil {
+			return registrytypes.AuthConfig{}, errors.Wrap(err, "could not load the docker config of the registry auth")
+		}
+		return command.ResolveAuthConfig(cfg, index), nil
+	}
+
+	serverAddress := index.Name
+	if index.Official {
+		serverAddress = registry.IndexServer
+	}
+	username, password, token := auth.Credentials()
+	return registrytypes.AuthConfig{
+		Username:      username,
+		Password:      password,
+		RegistryToken: token,
+		ServerAddress: serverAddress,
+	}, nil
+}
+
 func (d *Driver) initializeDockerCli() (command.Cli, error) {
 	if d.dockerCli != nil {
 		return d.dockerCli, nil
 	}
 
-	cli, err := GetDockerClient()
+	cli, err := newDockerClient(d.Daemon.ClientOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -193,36 +386,57 @@ func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
 		return driver.OperationResult{}, nil
 	}
 	if d.config["PULL_ALWAYS"] == "1" {
-		if err := pullImage(ctx, cli, op.Image.Image); err != nil {
-			return driver.OperationResult{}, err
+		if err := pullImage(ctx, cli, op.Image.Image, op.RegistryAuth); err != nil {
+			return driver.OperationResult{}, driver.NewImageError(op.Image.Image, err)
 		}
+		op.Emit(driver.Event{Type: driver.EventImagePulled, Image: op.Image.Image})
 	}
 
-	ii, err := d.inspectImage(ctx, op.Image)
+	ii, err := d.inspectImage(ctx, op)
 	if err != nil {
-		return driver.OperationResult{}, err
+		return driver.OperationResult{}, driver.NewImageError(op.Image.Image, err)
 	}
 
 	err = d.validateImageDigest(op.Image, ii.RepoDigests)
 	if err != nil {
-		return driver.OperationResult{}, errors.Wrap(err, "image digest validation failed")
+		return driver.OperationResult{}, driver.NewImageError(op.Image.Image, errors.Wrap(err, "image digest validation failed"))
 	}
 
 	if err := d.setConfigurationOptions(op); err != nil {
 		return driver.OperationResult{}, err
 	}
 
-	resp, err := cli.Client().ContainerCreate(ctx, &d.containerCfg, &d.containerHostCfg, nil, nil, "")
+	containerName, err := d.generateContainerName(op)
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+
+	resp, err := createContainer(containerName, func(name string) (container.CreateResponse, error) {
+		return cli.Client().ContainerCreate(ctx, &d.containerCfg, &d.containerHostCfg, d.networkingConfig(), nil, name)
+	})
 	if err != nil {
 		return driver.OperationResult{}, fmt.Errorf("cannot create container: %v", err)
 	}
 
-	if d.config["CLEANUP_CONTAINERS"] == "true" {
-		defer cli.Client().ContainerRemove(ctx, resp.ID, container.RemoveOptions{})
+	succeeded := false
+	defer func() {
+		d.cleanupContainer(ctx, cli.Client(), cli.Err(), resp.ID, op, succeeded)
+	}()
+
+	if err := d.connectNetworks(ctx, cli.Client(), resp.ID); err != nil {
+		return driver.OperationResult{}, err
 	}
 
-	containerUID := getContainerUserID(ii.Config.User)
-	tarContent, err := generateTar(op.Files, containerUID)
+	containerUser := ii.Config.User
+	if d.containerCfg.User != "" {
+		containerUser = d.containerCfg.User
+	}
+	containerUID := getContainerUserID(containerUser)
+	files, err := d.filterFiles(op.Files)
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+	tarContent, err := generateTar(files, containerUID)
 	if err != nil {
 		return driver.OperationResult{}, fmt.Errorf("error staging files: %s", err)
 	}
@@ -238,6 +452,7 @@ func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
 
 	attach, err := cli.Client().ContainerAttach(ctx, resp.ID, container.AttachOptions{
 		Stream: true,
+		Stdin:  op.In != nil,
 		Stdout: true,
 		Stderr: true,
 		Logs:   true,
@@ -245,20 +460,7 @@ func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
 	if err != nil {
 		return driver.OperationResult{}, fmt.Errorf("unable to retrieve logs: %v", err)
 	}
-	var (
-		stdout io.Writer = os.Stdout
-		stderr io.Writer = os.Stderr
-	)
-	if d.containerOut != nil {
-		stdout = d.containerOut
-	} else if op.Out != nil {
-		stdout = op.Out
-	}
-	if d.containerErr != nil {
-		stderr = d.containerErr
-	} else if op.Err != nil {
-		stderr = op.Err
-	}
+	stdout, stderr := d.outputStreams(op)
 	go func() {
 		defer attach.Close()
 		for {
@@ -272,31 +474,117 @@ func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
 	if err = cli.Client().ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
 		return driver.OperationResult{}, fmt.Errorf("cannot start container: %v", err)
 	}
-	statusc, errc := cli.Client().ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	if op.In != nil {
+		go streamStdin(attach, op.In)
+	}
+
+	opResult, err := d.waitForContainer(ctx, cli, resp.ID, op)
+	opResult.Metadata[MetadataInjectedFiles] = injectedFilesMetadata(files)
+	succeeded = err == nil
+	d.commitContainer(ctx, cli.Client(), cli.Err(), resp.ID, op, files, succeeded, &opResult)
+	return opResult, err
+}
+
+var _ driver.StdinStreamer = &Driver{}
+
+// SupportsStdin returns true: the input of an operation is always streamed to
+// the standard input of the container.
+func (d *Driver) SupportsStdin() bool {
+	return true
+}
+
+// streamStdin copies the input of the operation to the standard input of the
+// container, and closes it once the input is consumed so that the container
+// reads the end of its input.
+func streamStdin(attach types.HijackedResponse, in io.Reader) {
+	if _, err := io.Copy(attach.Conn, in); err != nil {
+		return
+	}
+	attach.CloseWrite()
+}
+
+// outputStreams returns the writers that the logs of the container are copied to.
+func (d *Driver) outputStreams(op *driver.Operation) (stdout io.Writer, stderr io.Writer) {
+	stdout, stderr = os.Stdout, os.Stderr
+	if d.containerOut != nil {
+		stdout = d.containerOut
+	} else if op.Out != nil {
+		stdout = op.Out
+	}
+	if d.containerErr != nil {
+		stderr = d.containerErr
+	} else if op.Err != nil {
+		stderr = op.Err
+	}
+	return stdout, stderr
+}
+
+// waitForContainer waits for the container to stop running and fetches the
+// outputs of the operation from it, along with how the container was executed.
+func (d *Driver) waitForContainer(ctx context.Context, cli command.Cli, id string, op *driver.Operation) (driver.OperationResult, error) {
+	opResult, err := d.waitForExit(ctx, cli, id, op)
+
+	info, inspectErr := inspectExecution(ctx, cli.Client(), id)
+	if inspectErr != nil {
+		// The execution metadata is informational, so failing to inspect the
+		// container does not fail the operation.
+		fmt.Fprintf(cli.Err(), "unable to inspect the execution of container %s: %v\n", id, inspectErr)
+		if info.ContainerID == "" {
+			info.ContainerID = id
+		}
+	}
+	opResult.Metadata = info.Metadata()
+
+	return opResult, err
+}
+
+// waitForExit waits for the container to stop running and fetches the outputs
+// of the operation from it.
+func (d *Driver) waitForExit(ctx context.Context, cli command.Cli, id string, op *driver.Operation) (driver.OperationResult, error) {
+	var err error
+	waitCtx, cancel := op.WithDeadline(ctx)
+	defer cancel()
+	statusc, errc := cli.Client().ContainerWait(waitCtx, id, container.WaitConditionNotRunning)
 	select {
 	case err := <-errc:
+		if err != nil && errors.Is(waitCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return d.stopContainer(ctx, cli, id, op)
+		}
 		if err != nil {
-			opResult, fetchErr := d.fetchOutputs(ctx, resp.ID, op)
+			opResult, fetchErr := d.fetchOutputs(ctx, id, op)
 			return opResult, containerError("error in container", err, fetchErr)
 		}
 	case s := <-statusc:
 		if s.StatusCode == 0 {
-			return d.fetchOutputs(ctx, resp.ID, op)
+			return d.fetchOutputs(ctx, id, op)
 		}
 		if s.Error != nil {
-			opResult, fetchErr := d.fetchOutputs(ctx, resp.ID, op)
+			opResult, fetchErr := d.fetchOutputs(ctx, id, op)
 			return opResult, containerError(fmt.Sprintf("container exit code: %d, message", s.StatusCode), err, fetchErr)
 		}
-		opResult, fetchErr := d.fetchOutputs(ctx, resp.ID, op)
+		opResult, fetchErr := d.fetchOutputs(ctx, id, op)
 		return opResult, containerError(fmt.Sprintf("container exit code: %d, message", s.StatusCode), err, fetchErr)
 	}
-	opResult, fetchErr := d.fetchOutputs(ctx, resp.ID, op)
+	opResult, fetchErr := d.fetchOutputs(ctx, id, op)
 	if fetchErr != nil {
 		return opResult, fmt.Errorf("fetching outputs failed: %s", fetchErr)
 	}
 	return opResult, err
 }
 
+// stopContainer stops a container that is still running when the deadline of
+// the operation is reached, and fetches whatever outputs it had written.
+func (d *Driver) stopContainer(ctx context.Context, cli command.Cli, id string, op *driver.Operation) (driver.OperationResult, error) {
+	if err := cli.Client().ContainerStop(ctx, id, container.StopOptions{}); err != nil {
+		fmt.Fprintf(cli.Err(), "unable to stop container %s after the deadline of the operation: %v\n", id, err)
+	}
+	opResult, fetchErr := d.fetchOutputs(ctx, id, op)
+	if fetchErr != nil {
+		return opResult, fmt.Errorf("%w, fetching outputs failed: %s", op.DeadlineError(), fetchErr)
+	}
+	return opResult, op.DeadlineError()
+}
+
 // getContainerUserID determines the user id that the container will execute as
 // based on the image's configured user. Defaults to 0 (root) if a user id is not set.
 func getContainerUserID(user string) int {
@@ -333,12 +621,26 @@ func (d *Driver) setConfigurationOptions(op *driver.Operation) error {
 		Entrypoint:   strslice.StrSlice{"/cnab/app/run"},
 		AttachStderr: true,
 		AttachStdout: true,
+		Labels:       driver.NewOperationLabels("docker", op).Map(),
+	}
+	if op.In != nil {
+		d.containerCfg.AttachStdin = true
+		d.containerCfg.OpenStdin = true
+		d.containerCfg.StdinOnce = true
 	}
 
 	d.containerHostCfg = container.HostConfig{}
 
-	if network, ok := d.config[SettingNetwork]; ok {
-		d.containerHostCfg.NetworkMode = container.NetworkMode(network)
+	d.applyNetworkSettings()
+	d.applyResourceLimits()
+	d.applyProcessSettings()
+
+	if err := d.applyCapabilities(op); err != nil {
+		return err
+	}
+
+	if err := d.applyVolumeMounts(); err != nil {
+		return err
 	}
 
 	if err := d.ApplyConfigurationOptions(); err != nil {
@@ -452,14 +754,16 @@ type ConfigurationOption func(*container.Config, *container.HostConfig) error
 
 // inspectImage inspects the operation image and returns an object of types.ImageInspect,
 // pulling the image if not found locally
-func (d *Driver) inspectImage(ctx context.Context, image bundle.InvocationImage) (types.ImageInspect, error) {
+func (d *Driver) inspectImage(ctx context.Context, op *driver.Operation) (types.ImageInspect, error) {
+	image := op.Image
 	ii, _, err := d.dockerCli.Client().ImageInspectWithRaw(ctx, image.Image)
 	switch {
 	case client.IsErrNotFound(err):
 		fmt.Fprintf(d.dockerCli.Err(), "Unable to find image '%s' locally\n", image.Image)
-		if err := pullImage(ctx, d.dockerCli, image.Image); err != nil {
+		if err := pullImage(ctx, d.dockerCli, image.Image, op.RegistryAuth); err != nil {
 			return ii, err
 		}
+		op.Emit(driver.Event{Type: driver.EventImagePulled, Image: image.Image})
 		if ii, _, err = d.dockerCli.Client().ImageInspectWithRaw(ctx, image.Image); err != nil {
 			return ii, errors.Wrapf(err, "cannot inspect image %s", image.Image)
 		}
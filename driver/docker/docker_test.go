@@ -1,8 +1,12 @@
 package docker
 
 import (
+	"io"
+	"net"
+	"strings"
 	"testing"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/stretchr/testify/assert"
@@ -107,6 +111,12 @@ func TestDriver_setConfigurationOptions(t *testing.T) {
 			AttachStdout: true,
 			AttachStderr: true,
 			Entrypoint:   []string{"/cnab/app/run"},
+			Labels: map[string]string{
+				driver.LabelDriver:       "docker",
+				driver.LabelInstallation: "",
+				driver.LabelAction:       "",
+				driver.LabelRevision:     "",
+			},
 		}
 		assert.Equal(t, wantCfg, cfg)
 
@@ -161,6 +171,21 @@ func TestDriver_SetConfig(t *testing.T) {
 			},
 			wantError: "environment variable CLEANUP_CONTAINERS has unexpected value",
 		},
+		{
+			name: "mounts",
+			settings: map[string]string{
+				"CLEANUP_CONTAINERS": "true",
+				SettingMounts:        "/var/run/docker.sock:/var/run/docker.sock mydata:/data:ro",
+			},
+			wantError: "",
+		},
+		{
+			name: "mounts - invalid",
+			settings: map[string]string{
+				SettingMounts: "/var/run/docker.sock",
+			},
+			wantError: "environment variable DOCKER_MOUNTS is invalid",
+		},
 	}
 
 	for _, tc := range testcases {
@@ -252,3 +277,25 @@ func TestGetContainerUserId(t *testing.T) {
 		})
 	}
 }
+
+func TestDriver_Stdin(t *testing.T) {
+	d := &Driver{}
+	assert.True(t, driver.SupportsStdin(d))
+
+	op := &driver.Operation{Image: bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "example.com/myimage"}}}
+	require.NoError(t, d.setConfigurationOptions(op))
+	assert.False(t, d.containerCfg.OpenStdin, "stdin should only be opened for operations with an input")
+
+	op.In = strings.NewReader("yes\n")
+	require.NoError(t, d.setConfigurationOptions(op))
+	assert.True(t, d.containerCfg.AttachStdin)
+	assert.True(t, d.containerCfg.OpenStdin)
+	assert.True(t, d.containerCfg.StdinOnce)
+
+	client, server := net.Pipe()
+	go streamStdin(types.HijackedResponse{Conn: client}, op.In)
+	buf := make([]byte, 4)
+	_, err := io.ReadFull(server, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "yes\n", string(buf))
+}
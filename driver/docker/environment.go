@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+var _ driver.EnvironmentDescriber = &Driver{}
+
+// DescribeEnvironment describes the docker daemon that the driver runs
+// operations on. The daemon is only queried once the driver connected to it
+// to run an operation, and its version is cached.
+func (d *Driver) DescribeEnvironment() driver.ExecutionEnvironment {
+	if d.serverVersion == nil && d.dockerCli != nil && !d.Simulate {
+		if v, err := d.dockerCli.Client().ServerVersion(context.Background()); err == nil {
+			d.serverVersion = &v
+		}
+	}
+	return describeDaemon(d.serverVersion)
+}
+
+// describeDaemon returns the environment of the docker daemon with the
+// version, when known.
+func describeDaemon(v *types.Version) driver.ExecutionEnvironment {
+	env := driver.ExecutionEnvironment{Driver: "docker"}
+	if v != nil {
+		env.ServerVersion = v.Version
+		env.ServerOS = v.Os
+		env.ServerArch = v.Arch
+	}
+	return env
+}
@@ -0,0 +1,27 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_DescribeEnvironment(t *testing.T) {
+	t.Run("not connected", func(t *testing.T) {
+		d := &Driver{}
+		assert.Equal(t, driver.ExecutionEnvironment{Driver: "docker"}, d.DescribeEnvironment())
+	})
+
+	t.Run("cached daemon version", func(t *testing.T) {
+		d := &Driver{serverVersion: &types.Version{Version: "20.10.7", Os: "linux", Arch: "amd64"}}
+		assert.Equal(t, driver.ExecutionEnvironment{
+			Driver:        "docker",
+			ServerVersion: "20.10.7",
+			ServerOS:      "linux",
+			ServerArch:    "amd64",
+		}, d.DescribeEnvironment())
+	})
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+// Keys of the driver.OperationResult metadata describing how the invocation
+// image container was executed.
+const (
+	// MetadataContainerID is the ID of the container.
+	MetadataContainerID = "docker.containerID"
+
+	// MetadataExitCode is the exit code of the container.
+	MetadataExitCode = "docker.exitCode"
+
+	// MetadataOOMKilled is "true" when the container was killed because it ran
+	// out of memory.
+	MetadataOOMKilled = "docker.oomKilled"
+
+	// MetadataStartedAt is when the container started, in RFC3339 format.
+	MetadataStartedAt = "docker.startedAt"
+
+	// MetadataFinishedAt is when the container exited, in RFC3339 format.
+	MetadataFinishedAt = "docker.finishedAt"
+
+	// MetadataImageID is the ID of the image that the container ran.
+	MetadataImageID = "docker.imageID"
+
+	// MetadataImageDigest is the repository digest of the image that the
+	// container ran, when the image was pulled from a registry.
+	MetadataImageDigest = "docker.imageDigest"
+)
+
+// inspectClient is the subset of the docker client used to inspect an
+// executed container.
+type inspectClient interface {
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+}
+
+// ExecutionInfo describes how the invocation image container was executed.
+type ExecutionInfo struct {
+	ContainerID string
+	ExitCode    int
+	OOMKilled   bool
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	ImageID     string
+	ImageDigest string
+}
+
+// Metadata describes the execution as driver.OperationResult metadata.
+func (e ExecutionInfo) Metadata() map[string]string {
+	metadata := map[string]string{
+		MetadataContainerID: e.ContainerID,
+		MetadataExitCode:    strconv.Itoa(e.ExitCode),
+		MetadataOOMKilled:   strconv.FormatBool(e.OOMKilled),
+	}
+	if !e.StartedAt.IsZero() {
+		metadata[MetadataStartedAt] = e.StartedAt.Format(time.RFC3339Nano)
+	}
+	if !e.FinishedAt.IsZero() {
+		metadata[MetadataFinishedAt] = e.FinishedAt.Format(time.RFC3339Nano)
+	}
+	if e.ImageID != "" {
+		metadata[MetadataImageID] = e.ImageID
+	}
+	if e.ImageDigest != "" {
+		metadata[MetadataImageDigest] = e.ImageDigest
+	}
+	return metadata
+}
+
+// inspectExecution returns how the container, which is no longer running,
+// was executed.
+func inspectExecution(ctx context.Context, cli inspectClient, id string) (ExecutionInfo, error) {
+	c, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return ExecutionInfo{}, errors.Wrapf(err, "cannot inspect container %s", id)
+	}
+
+	info := ExecutionInfo{
+		ContainerID: c.ID,
+		ImageID:     c.Image,
+	}
+	if c.ContainerJSONBase != nil && c.State != nil {
+		info.ExitCode = c.State.ExitCode
+		info.OOMKilled = c.State.OOMKilled
+		// Docker reports the timestamps of a container that has not run as the zero time.
+		info.StartedAt, _ = time.Parse(time.RFC3339Nano, c.State.StartedAt)
+		info.FinishedAt, _ = time.Parse(time.RFC3339Nano, c.State.FinishedAt)
+	}
+
+	if c.Image == "" {
+		return info, nil
+	}
+	ii, _, err := cli.ImageInspectWithRaw(ctx, c.Image)
+	if err != nil {
+		return info, errors.Wrapf(err, "cannot inspect image %s", c.Image)
+	}
+	var repository string
+	if c.Config != nil {
+		if named, err := reference.ParseNormalizedNamed(c.Config.Image); err == nil {
+			repository = named.Name()
+		}
+	}
+	info.ImageDigest = findRepoDigest(ii.RepoDigests, repository)
+
+	return info, nil
+}
+
+// findRepoDigest returns the digest of the repository from the repo digests of
+// an image, falling back to the first digest when the image was not pulled
+// from the repository.
+func findRepoDigest(repoDigests []string, repository string) string {
+	var first string
+	for _, repoDigest := range repoDigests {
+		ref, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		digestRef, ok := ref.(reference.Digested)
+		if !ok {
+			continue
+		}
+		if ref.Name() == repository {
+			return digestRef.Digest().String()
+		}
+		if first == "" {
+			first = digestRef.Digest().String()
+		}
+	}
+	return first
+}
@@ -0,0 +1,104 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testInspectClient struct {
+	container types.ContainerJSON
+	image     types.ImageInspect
+	imageErr  error
+}
+
+func (c *testInspectClient) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	if containerID != c.container.ID {
+		return types.ContainerJSON{}, errors.New("no such container")
+	}
+	return c.container, nil
+}
+
+func (c *testInspectClient) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	return c.image, nil, c.imageErr
+}
+
+func newTestInspectClient() *testInspectClient {
+	return &testInspectClient{
+		container: types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:    "abc",
+				Image: "sha256:1234",
+				State: &types.ContainerState{
+					ExitCode:   137,
+					OOMKilled:  true,
+					StartedAt:  "2021-04-01T10:00:00.5Z",
+					FinishedAt: "2021-04-01T10:01:00Z",
+				},
+			},
+			Config: &container.Config{Image: "localhost:5000/cnab/helloworld:0.1.1"},
+		},
+		image: types.ImageInspect{
+			RepoDigests: []string{
+				"cnab/helloworld@sha256:aaaa000000000000000000000000000000000000000000000000000000000000",
+				"localhost:5000/cnab/helloworld@sha256:bbbb000000000000000000000000000000000000000000000000000000000000",
+			},
+		},
+	}
+}
+
+func TestInspectExecution(t *testing.T) {
+	t.Run("exited container", func(t *testing.T) {
+		info, err := inspectExecution(context.Background(), newTestInspectClient(), "abc")
+		require.NoError(t, err)
+		assert.Equal(t, ExecutionInfo{
+			ContainerID: "abc",
+			ExitCode:    137,
+			OOMKilled:   true,
+			StartedAt:   time.Date(2021, 4, 1, 10, 0, 0, 500000000, time.UTC),
+			FinishedAt:  time.Date(2021, 4, 1, 10, 1, 0, 0, time.UTC),
+			ImageID:     "sha256:1234",
+			ImageDigest: "sha256:bbbb000000000000000000000000000000000000000000000000000000000000",
+		}, info)
+
+		assert.Equal(t, map[string]string{
+			MetadataContainerID: "abc",
+			MetadataExitCode:    "137",
+			MetadataOOMKilled:   "true",
+			MetadataStartedAt:   "2021-04-01T10:00:00.5Z",
+			MetadataFinishedAt:  "2021-04-01T10:01:00Z",
+			MetadataImageID:     "sha256:1234",
+			MetadataImageDigest: "sha256:bbbb000000000000000000000000000000000000000000000000000000000000",
+		}, info.Metadata())
+	})
+
+	t.Run("image not pulled from the repository", func(t *testing.T) {
+		cli := newTestInspectClient()
+		cli.container.Config.Image = "cnab/other:latest"
+
+		info, err := inspectExecution(context.Background(), cli, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, "sha256:aaaa000000000000000000000000000000000000000000000000000000000000", info.ImageDigest)
+	})
+
+	t.Run("image inspect failure", func(t *testing.T) {
+		cli := newTestInspectClient()
+		cli.imageErr = errors.New("no such image")
+
+		info, err := inspectExecution(context.Background(), cli, "abc")
+		require.EqualError(t, err, "cannot inspect image sha256:1234: no such image")
+		assert.Equal(t, 137, info.ExitCode)
+		assert.Empty(t, info.ImageDigest)
+	})
+
+	t.Run("missing container", func(t *testing.T) {
+		_, err := inspectExecution(context.Background(), newTestInspectClient(), "def")
+		require.EqualError(t, err, "cannot inspect container def: no such container")
+	})
+}
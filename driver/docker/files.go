@@ -0,0 +1,145 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/moby/patternmatcher"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const (
+	// SettingFilesInclude is the environment variable for the driver that
+	// specifies which files of the operation are injected into the
+	// invocation image, as patterns separated by whitespace, in the format of
+	// a .dockerignore file, for example "cnab/app/**". Defaults to every file.
+	SettingFilesInclude = "DOCKER_FILES_INCLUDE"
+
+	// SettingFilesExclude is the environment variable for the driver that
+	// specifies which files of the operation are not injected into the
+	// invocation image, as patterns separated by whitespace, in the format
+	// of a .dockerignore file. Patterns starting with ! are exceptions, for
+	// example "cnab/app/params/* !cnab/app/params/port".
+	SettingFilesExclude = "DOCKER_FILES_EXCLUDE"
+
+	// MetadataInjectedFiles is the key of the driver.OperationResult
+	// metadata listing the paths of the files injected into the invocation
+	// image, as a sorted JSON array, so that the filters can be verified.
+	MetadataInjectedFiles = "docker.injectedFiles"
+)
+
+// requiredFiles are injected regardless of the filters, because the
+// invocation image cannot run the bundle without them.
+var requiredFiles = map[string]struct{}{
+	"/cnab/bundle.json":          {},
+	bundle.ImageMapPath:          {},
+	bundle.RelocationMappingPath: {},
+}
+
+// parseFileFilterSettings reads the patterns that filter the files injected
+// into the invocation image from the driver settings, falling back to the
+// values already set on the driver.
+func (d *Driver) parseFileFilterSettings(settings map[string]string) error {
+	for _, setting := range []struct {
+		name     string
+		patterns *[]string
+	}{
+		{SettingFilesInclude, &d.FilesInclude},
+		{SettingFilesExclude, &d.FilesExclude},
+	} {
+		value, ok := settings[setting.name]
+		if !ok || value == "" {
+			continue
+		}
+		patterns := strings.Fields(value)
+		if _, err := newFileMatcher(patterns); err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", setting.name, value, err)
+		}
+		*setting.patterns = patterns
+	}
+	return nil
+}
+
+// newFileMatcher compiles .dockerignore patterns. The patterns, and the
+// paths they are matched against, are relative to the root of the
+// container.
+func newFileMatcher(patterns []string) (*patternmatcher.PatternMatcher, error) {
+	relative := make([]string, len(patterns))
+	for i, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			relative[i] = "!" + strings.TrimLeft(p[1:], "/")
+		} else {
+			relative[i] = strings.TrimLeft(p, "/")
+		}
+	}
+	return patternmatcher.New(relative)
+}
+
+// filterFiles returns the files of the operation that are injected into the
+// invocation image: the files matching the include patterns, when there are
+// any, that do not match the exclude patterns. Files required by the CNAB
+// runtime are always injected.
+func (d *Driver) filterFiles(files map[string]string) (map[string]string, error) {
+	if len(d.FilesInclude) == 0 && len(d.FilesExclude) == 0 {
+		return files, nil
+	}
+
+	var include, exclude *patternmatcher.PatternMatcher
+	var err error
+	if len(d.FilesInclude) > 0 {
+		if include, err = newFileMatcher(d.FilesInclude); err != nil {
+			return nil, fmt.Errorf("invalid file include patterns: %w", err)
+		}
+	}
+	if len(d.FilesExclude) > 0 {
+		if exclude, err = newFileMatcher(d.FilesExclude); err != nil {
+			return nil, fmt.Errorf("invalid file exclude patterns: %w", err)
+		}
+	}
+
+	filtered := make(map[string]string, len(files))
+	for path, content := range files {
+		if _, ok := requiredFiles[path]; ok {
+			filtered[path] = content
+			continue
+		}
+
+		relative := strings.TrimLeft(path, "/")
+		if include != nil {
+			matched, err := include.MatchesOrParentMatches(relative)
+			if err != nil {
+				return nil, fmt.Errorf("error matching file %s: %w", path, err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		if exclude != nil {
+			matched, err := exclude.MatchesOrParentMatches(relative)
+			if err != nil {
+				return nil, fmt.Errorf("error matching file %s: %w", path, err)
+			}
+			if matched {
+				continue
+			}
+		}
+		filtered[path] = content
+	}
+	return filtered, nil
+}
+
+// injectedFilesMetadata lists the paths of the injected files as a sorted
+// JSON array.
+func injectedFilesMetadata(files map[string]string) string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	data, _ := json.Marshal(paths)
+	return string(data)
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestDriver_FilterFiles(t *testing.T) {
+	files := map[string]string{
+		"/cnab/bundle.json":           "{}",
+		bundle.ImageMapPath:           "{}",
+		"/cnab/app/params/port":       "3306",
+		"/cnab/app/params/dump.sql":   "CREATE TABLE",
+		"/cnab/app/params/seed.sql":   "INSERT INTO",
+		"/root/.kube/config":          "apiVersion: v1",
+		"/cnab/app/credentials/token": "secret",
+	}
+
+	testcases := []struct {
+		name     string
+		settings map[string]string
+		want     []string
+	}{
+		{
+			name: "no filters",
+			want: []string{"/cnab/app/credentials/token", bundle.ImageMapPath, "/cnab/app/params/dump.sql", "/cnab/app/params/port", "/cnab/app/params/seed.sql", "/cnab/bundle.json", "/root/.kube/config"},
+		},
+		{
+			name:     "include",
+			settings: map[string]string{SettingFilesInclude: "cnab/app/params"},
+			want:     []string{bundle.ImageMapPath, "/cnab/app/params/dump.sql", "/cnab/app/params/port", "/cnab/app/params/seed.sql", "/cnab/bundle.json"},
+		},
+		{
+			name:     "exclude with an exception",
+			settings: map[string]string{SettingFilesExclude: "**/*.sql !/cnab/app/params/seed.sql /root"},
+			want:     []string{"/cnab/app/credentials/token", bundle.ImageMapPath, "/cnab/app/params/port", "/cnab/app/params/seed.sql", "/cnab/bundle.json"},
+		},
+		{
+			name:     "include and exclude",
+			settings: map[string]string{SettingFilesInclude: "/cnab/app/*/*", SettingFilesExclude: "cnab/app/credentials"},
+			want:     []string{bundle.ImageMapPath, "/cnab/app/params/dump.sql", "/cnab/app/params/port", "/cnab/app/params/seed.sql", "/cnab/bundle.json"},
+		},
+		{
+			name:     "required files are always injected",
+			settings: map[string]string{SettingFilesExclude: "cnab"},
+			want:     []string{bundle.ImageMapPath, "/cnab/bundle.json", "/root/.kube/config"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &Driver{}
+			require.NoError(t, d.SetConfig(tc.settings))
+
+			filtered, err := d.filterFiles(files)
+			require.NoError(t, err)
+			var got []string
+			for path, content := range filtered {
+				assert.Equal(t, files[path], content)
+				got = append(got, path)
+			}
+			assert.ElementsMatch(t, tc.want, got)
+		})
+	}
+}
+
+func TestDriver_SetConfig_FileFilters(t *testing.T) {
+	d := &Driver{}
+	require.NoError(t, d.SetConfig(map[string]string{
+		SettingFilesInclude: "cnab/app/**",
+		SettingFilesExclude: "*.sql\n!seed.sql",
+	}))
+	assert.Equal(t, []string{"cnab/app/**"}, d.FilesInclude)
+	assert.Equal(t, []string{"*.sql", "!seed.sql"}, d.FilesExclude)
+
+	err := d.SetConfig(map[string]string{SettingFilesExclude: "!"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `environment variable DOCKER_FILES_EXCLUDE has unexpected value "!"`)
+}
+
+func TestInjectedFilesMetadata(t *testing.T) {
+	metadata := injectedFilesMetadata(map[string]string{
+		"/cnab/bundle.json":     "{}",
+		"/cnab/app/params/port": "3306",
+	})
+	assert.Equal(t, `["/cnab/app/params/port","/cnab/bundle.json"]`, metadata)
+}
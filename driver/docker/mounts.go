@@ -0,0 +1,161 @@
+package docker
+
+import (
+	"fmt"
+	unix_path "path"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+)
+
+// SettingMounts is the environment variable for the driver that specifies
+// host paths and named volumes to mount into the invocation image container.
+//
+// Mounts are separated by whitespace and use the same format as docker run
+// --volume: SOURCE:TARGET[:ro|rw]. When SOURCE is an absolute path it is
+// bind mounted from the host, otherwise it is the name of a docker volume.
+// For example "/var/run/docker.sock:/var/run/docker.sock mydata:/data:ro".
+const SettingMounts = "DOCKER_MOUNTS"
+
+// VolumeMount is a host path or named volume that is mounted into the
+// invocation image container.
+type VolumeMount struct {
+	// Source is either an absolute path on the host, which is bind mounted,
+	// or the name of a docker volume.
+	Source string
+
+	// Target is the absolute path in the container where the source is mounted.
+	Target string
+
+	// ReadOnly mounts the source as read-only.
+	ReadOnly bool
+}
+
+// Validate the VolumeMount.
+func (m VolumeMount) Validate() error {
+	if m.Source == "" {
+		return fmt.Errorf("invalid mount %s: the source must be set", m)
+	}
+
+	if !unix_path.IsAbs(m.Target) {
+		return fmt.Errorf("invalid mount %s: the target must be an absolute unix path", m)
+	}
+
+	return nil
+}
+
+// String returns the mount in the format used by the DOCKER_MOUNTS setting.
+func (m VolumeMount) String() string {
+	spec := m.Source + ":" + m.Target
+	if m.ReadOnly {
+		spec += ":ro"
+	}
+	return spec
+}
+
+// isBind determines if the source is a host path, instead of a named volume.
+func (m VolumeMount) isBind() bool {
+	return unix_path.IsAbs(m.Source) || filepath.IsAbs(m.Source) || hasDriveLetter(m.Source)
+}
+
+// hasDriveLetter determines if the path starts with a Windows drive letter,
+// such as C:\data or C:/data.
+func hasDriveLetter(path string) bool {
+	if len(path) < 3 || path[1] != ':' || (path[2] != '\\' && path[2] != '/') {
+		return false
+	}
+	c := path[0]
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// toMount converts the VolumeMount to its docker representation.
+func (m VolumeMount) toMount() mount.Mount {
+	mountType := mount.TypeVolume
+	if m.isBind() {
+		mountType = mount.TypeBind
+	}
+
+	return mount.Mount{
+		Type:     mountType,
+		Source:   m.Source,
+		Target:   m.Target,
+		ReadOnly: m.ReadOnly,
+	}
+}
+
+// ParseVolumeMount parses a mount in the format SOURCE:TARGET[:ro|rw]. The
+// SOURCE may be a Windows path starting with a drive letter, such as
+// C:\data:/data.
+func ParseVolumeMount(spec string) (VolumeMount, error) {
+	// Keep the colon after a drive letter with the source
+	drive := ""
+	if hasDriveLetter(spec) {
+		drive, spec = spec[:2], spec[2:]
+	}
+
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return VolumeMount{}, fmt.Errorf("invalid mount %q: expected the format SOURCE:TARGET[:ro|rw]", drive+spec)
+	}
+	parts[0] = drive + parts[0]
+
+	m := VolumeMount{
+		Source: parts[0],
+		Target: parts[1],
+	}
+
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			m.ReadOnly = true
+		case "rw":
+		default:
+			return VolumeMount{}, fmt.Errorf("invalid mount %q: unsupported mode %q, expected ro or rw", drive+spec, parts[2])
+		}
+	}
+
+	return m, m.Validate()
+}
+
+// parseVolumeMounts parses the whitespace separated mounts from the DOCKER_MOUNTS setting.
+func parseVolumeMounts(value string) ([]VolumeMount, error) {
+	var mounts []VolumeMount
+	for _, spec := range strings.Fields(value) {
+		m, err := ParseVolumeMount(spec)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
+
+// AddVolumeMounts adds host paths or named volumes to mount into the
+// invocation image container, in addition to those specified with the
+// DOCKER_MOUNTS setting.
+func (d *Driver) AddVolumeMounts(mounts ...VolumeMount) error {
+	for _, m := range mounts {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+
+	d.mounts = append(d.mounts, mounts...)
+	return nil
+}
+
+// applyVolumeMounts adds the mounts from the driver's settings and those
+// added with AddVolumeMounts to the container's host configuration.
+func (d *Driver) applyVolumeMounts() error {
+	configMounts, err := parseVolumeMounts(d.config[SettingMounts])
+	if err != nil {
+		return err
+	}
+
+	for _, m := range append(configMounts, d.mounts...) {
+		d.containerHostCfg.Mounts = append(d.containerHostCfg.Mounts, m.toMount())
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestParseVolumeMount(t *testing.T) {
+	testcases := []struct {
+		spec      string
+		want      VolumeMount
+		wantError string
+	}{
+		{spec: "/var/run/docker.sock:/var/run/docker.sock", want: VolumeMount{Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"}},
+		{spec: "mydata:/data:ro", want: VolumeMount{Source: "mydata", Target: "/data", ReadOnly: true}},
+		{spec: "mydata:/data:rw", want: VolumeMount{Source: "mydata", Target: "/data"}},
+		{spec: "/data", wantError: "expected the format SOURCE:TARGET[:ro|rw]"},
+		{spec: "mydata:/data:z", wantError: `unsupported mode "z"`},
+		{spec: "mydata:data", wantError: "the target must be an absolute unix path"},
+		{spec: ":/data", wantError: "the source must be set"},
+		{spec: `C:\data:/data`, want: VolumeMount{Source: `C:\data`, Target: "/data"}},
+		{spec: "c:/Users/me/data:/data:ro", want: VolumeMount{Source: "c:/Users/me/data", Target: "/data", ReadOnly: true}},
+		{spec: `C:\data`, wantError: "expected the format SOURCE:TARGET[:ro|rw]"},
+		{spec: `C:\data:/data:z`, wantError: `invalid mount "C:\\data:/data:z": unsupported mode "z"`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.spec, func(t *testing.T) {
+			m, err := ParseVolumeMount(tc.spec)
+			if tc.wantError == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, m)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantError)
+			}
+		})
+	}
+}
+
+func TestDriver_VolumeMounts(t *testing.T) {
+	op := &driver.Operation{
+		Image: bundle.InvocationImage{
+			BaseImage: bundle.BaseImage{Image: "example.com/myimage"},
+		},
+	}
+
+	d := &Driver{}
+	require.NoError(t, d.SetConfig(map[string]string{
+		SettingMounts: "/var/run/docker.sock:/var/run/docker.sock\nmydata:/data:ro",
+	}))
+	require.NoError(t, d.AddVolumeMounts(VolumeMount{Source: "/home/me/.kube", Target: "/root/.kube", ReadOnly: true}))
+	require.NoError(t, d.AddVolumeMounts(VolumeMount{Source: `C:\Users\me\data`, Target: "/data/windows"}))
+
+	err := d.setConfigurationOptions(op)
+	require.NoError(t, err)
+
+	wantMounts := []mount.Mount{
+		{Type: mount.TypeBind, Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"},
+		{Type: mount.TypeVolume, Source: "mydata", Target: "/data", ReadOnly: true},
+		{Type: mount.TypeBind, Source: "/home/me/.kube", Target: "/root/.kube", ReadOnly: true},
+		{Type: mount.TypeBind, Source: `C:\Users\me\data`, Target: "/data/windows"},
+	}
+	assert.Equal(t, wantMounts, d.containerHostCfg.Mounts)
+
+	// Running another operation should not duplicate the mounts
+	err = d.setConfigurationOptions(op)
+	require.NoError(t, err)
+	assert.Len(t, d.containerHostCfg.Mounts, 4)
+}
+
+func TestDriver_AddVolumeMounts_Invalid(t *testing.T) {
+	d := &Driver{}
+	err := d.AddVolumeMounts(VolumeMount{Source: "mydata", Target: "data"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid mount mydata:data")
+	assert.Empty(t, d.mounts)
+}
@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/cli/opts"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// SettingExtraHosts is the environment variable for the driver that
+	// specifies additional entries for the /etc/hosts file of the invocation
+	// image, separated by whitespace, in the format HOST:IP, for example
+	// db.example.com:10.0.0.5 or mysql:host-gateway.
+	SettingExtraHosts = "DOCKER_EXTRA_HOSTS"
+)
+
+// NetworkAttachment is a docker network that the invocation image is
+// attached to.
+type NetworkAttachment struct {
+	// Name of the network.
+	Name string
+
+	// Aliases of the invocation image container on the network, so that
+	// other containers on the network can reach it by name.
+	Aliases []string
+}
+
+// parseNetworkSettings reads the networks and extra hosts of the invocation
+// image from the driver settings, falling back to the values already set on
+// the driver.
+func (d *Driver) parseNetworkSettings(settings map[string]string) error {
+	if value, ok := settings[SettingNetwork]; ok && value != "" {
+		networks, err := parseNetworks(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingNetwork, value, err)
+		}
+		d.Networks = networks
+	}
+
+	if value, ok := settings[SettingExtraHosts]; ok && value != "" {
+		var hosts []string
+		for _, host := range strings.Fields(value) {
+			host, err := opts.ValidateExtraHost(host)
+			if err != nil {
+				return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingExtraHosts, value, err)
+			}
+			hosts = append(hosts, host)
+		}
+		d.ExtraHosts = hosts
+	}
+
+	return nil
+}
+
+// parseNetworks parses networks separated by whitespace, in the format
+// NAME[=ALIAS[,ALIAS...]], for example "backend=db,mysql frontend". A network
+// mode, such as host or container:NAME, can only be used on its own.
+func parseNetworks(value string) ([]NetworkAttachment, error) {
+	fields := strings.Fields(value)
+	var networks []NetworkAttachment
+	for _, field := range fields {
+		name, aliases, hasAliases := strings.Cut(field, "=")
+		if name == "" {
+			return nil, fmt.Errorf("invalid network %q, the network name is required", field)
+		}
+
+		mode := container.NetworkMode(name)
+		if len(fields) > 1 && (mode.IsHost() || mode.IsNone() || mode.IsContainer()) {
+			return nil, fmt.Errorf("the network mode %s cannot be combined with other networks", name)
+		}
+
+		n := NetworkAttachment{Name: name}
+		if hasAliases {
+			for _, alias := range strings.Split(aliases, ",") {
+				if alias == "" {
+					return nil, fmt.Errorf("invalid network %q, aliases must not be empty", field)
+				}
+				n.Aliases = append(n.Aliases, alias)
+			}
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// applyNetworkSettings attaches the container to the first network, and sets
+// the extra hosts on the host configuration. The container is connected to
+// any other networks by connectNetworks once it has been created, because
+// older docker daemons only support one network when creating a container.
+func (d *Driver) applyNetworkSettings() {
+	d.containerNetworkingCfg = network.NetworkingConfig{}
+	if len(d.Networks) > 0 {
+		first := d.Networks[0]
+		d.containerHostCfg.NetworkMode = container.NetworkMode(first.Name)
+		if len(first.Aliases) > 0 {
+			d.containerNetworkingCfg.EndpointsConfig = map[string]*network.EndpointSettings{
+				first.Name: {Aliases: first.Aliases},
+			}
+		}
+	}
+
+	if len(d.ExtraHosts) > 0 {
+		d.containerHostCfg.ExtraHosts = append(d.containerHostCfg.ExtraHosts, d.ExtraHosts...)
+	}
+}
+
+// networkingConfig returns the networking configuration used to create the
+// container, or nil when the defaults are used.
+func (d *Driver) networkingConfig() *network.NetworkingConfig {
+	if len(d.containerNetworkingCfg.EndpointsConfig) == 0 {
+		return nil
+	}
+	return &d.containerNetworkingCfg
+}
+
+// connectNetworks connects the created container to the networks after the
+// first, before it is started.
+func (d *Driver) connectNetworks(ctx context.Context, cli client.NetworkAPIClient, containerID string) error {
+	for i := 1; i < len(d.Networks); i++ {
+		n := d.Networks[i]
+		if err := cli.NetworkConnect(ctx, n.Name, containerID, &network.EndpointSettings{Aliases: n.Aliases}); err != nil {
+			return fmt.Errorf("cannot connect container to network %s: %w", n.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_NetworkSettings(t *testing.T) {
+	op := &driver.Operation{
+		Image: bundle.InvocationImage{
+			BaseImage: bundle.BaseImage{Image: "example.com/myimage"},
+		},
+	}
+
+	t.Run("networks and extra hosts", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{
+			SettingNetwork:    "backend=db,mysql frontend",
+			SettingExtraHosts: "registry.example.com:10.0.0.5 gateway=host-gateway",
+		}))
+		assert.Equal(t, []NetworkAttachment{
+			{Name: "backend", Aliases: []string{"db", "mysql"}},
+			{Name: "frontend"},
+		}, d.Networks)
+
+		require.NoError(t, d.setConfigurationOptions(op))
+		assert.Equal(t, container.NetworkMode("backend"), d.containerHostCfg.NetworkMode)
+		assert.Equal(t, []string{"registry.example.com:10.0.0.5", "gateway:host-gateway"}, d.containerHostCfg.ExtraHosts)
+		require.NotNil(t, d.networkingConfig())
+		assert.Equal(t, []string{"db", "mysql"}, d.networkingConfig().EndpointsConfig["backend"].Aliases)
+	})
+
+	t.Run("network mode", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{SettingNetwork: "container:proxy"}))
+
+		require.NoError(t, d.setConfigurationOptions(op))
+		assert.Equal(t, container.NetworkMode("container:proxy"), d.containerHostCfg.NetworkMode)
+		assert.Nil(t, d.networkingConfig())
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{}))
+
+		require.NoError(t, d.setConfigurationOptions(op))
+		assert.Empty(t, d.containerHostCfg.NetworkMode)
+		assert.Empty(t, d.containerHostCfg.ExtraHosts)
+		assert.Nil(t, d.networkingConfig())
+	})
+}
+
+func TestDriver_SetConfig_InvalidNetworkSettings(t *testing.T) {
+	testcases := []struct {
+		setting string
+		value   string
+	}{
+		{SettingNetwork, "=db"},
+		{SettingNetwork, "backend=db,"},
+		{SettingNetwork, "host backend"},
+		{SettingExtraHosts, "registry.example.com"},
+		{SettingExtraHosts, "registry.example.com:not-an-ip"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.setting+"="+tc.value, func(t *testing.T) {
+			d := &Driver{}
+			err := d.SetConfig(map[string]string{tc.setting: tc.value})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.setting)
+		})
+	}
+}
+
+// mockNetworkClient records the networks that containers are connected to.
+type mockNetworkClient struct {
+	client.NetworkAPIClient
+	connected map[string]*network.EndpointSettings
+	err       error
+}
+
+func (c *mockNetworkClient) NetworkConnect(ctx context.Context, networkID, containerID string, config *network.EndpointSettings) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.connected[networkID] = config
+	return nil
+}
+
+func TestDriver_ConnectNetworks(t *testing.T) {
+	d := &Driver{Networks: []NetworkAttachment{
+		{Name: "backend"},
+		{Name: "frontend", Aliases: []string{"web"}},
+	}}
+
+	cli := &mockNetworkClient{connected: map[string]*network.EndpointSettings{}}
+	require.NoError(t, d.connectNetworks(context.Background(), cli, "abc123"))
+	assert.Equal(t, map[string]*network.EndpointSettings{
+		"frontend": {Aliases: []string{"web"}},
+	}, cli.connected, "only the networks after the first should be connected")
+
+	cli.err = errors.New("network not found")
+	err := d.connectNetworks(context.Background(), cli, "abc123")
+	assert.EqualError(t, err, "cannot connect container to network frontend: network not found")
+}
@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	unix_path "path"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types/strslice"
+)
+
+const (
+	// SettingUser is the environment variable for the driver that specifies
+	// the user that runs the invocation image, in the format UID[:GID], for
+	// example 1000:1000. Defaults to the user configured by the image.
+	SettingUser = "DOCKER_USER"
+
+	// SettingEntrypoint is the environment variable for the driver that
+	// overrides the entrypoint of the invocation image, either as a JSON
+	// array, e.g. ["/sbin/tini", "--"], or separated by whitespace. Defaults
+	// to /cnab/app/run.
+	SettingEntrypoint = "DOCKER_ENTRYPOINT"
+
+	// SettingCmd is the environment variable for the driver that specifies
+	// the arguments passed to the entrypoint of the invocation image, in the
+	// same format as SettingEntrypoint.
+	SettingCmd = "DOCKER_CMD"
+
+	// SettingWorkingDir is the environment variable for the driver that
+	// specifies the absolute path of the working directory of the invocation
+	// image. Defaults to the working directory configured by the image.
+	SettingWorkingDir = "DOCKER_WORKING_DIR"
+)
+
+// userReg matches a numeric user id, optionally followed by a group id.
+var userReg = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+// parseProcessSettings reads the user, entrypoint, command and working
+// directory of the invocation image from the driver settings, falling back
+// to the values already set on the driver.
+func (d *Driver) parseProcessSettings(settings map[string]string) error {
+	if value, ok := settings[SettingUser]; ok && value != "" {
+		if !userReg.MatchString(value) {
+			return fmt.Errorf("environment variable %s has unexpected value %q, it must be in the format UID[:GID]", SettingUser, value)
+		}
+		d.User = value
+	}
+
+	if value, ok := settings[SettingEntrypoint]; ok && value != "" {
+		entrypoint, err := parseCommand(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingEntrypoint, value, err)
+		}
+		d.Entrypoint = entrypoint
+	}
+
+	if value, ok := settings[SettingCmd]; ok && value != "" {
+		cmd, err := parseCommand(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingCmd, value, err)
+		}
+		d.Cmd = cmd
+	}
+
+	if value, ok := settings[SettingWorkingDir]; ok && value != "" {
+		if !unix_path.IsAbs(value) {
+			return fmt.Errorf("environment variable %s has unexpected value %q, it must be an absolute path", SettingWorkingDir, value)
+		}
+		d.WorkingDir = value
+	}
+
+	return nil
+}
+
+// parseCommand splits a command that is either a JSON array of strings or
+// separated by whitespace.
+func parseCommand(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") {
+		return strings.Fields(value), nil
+	}
+
+	var cmd []string
+	if err := json.Unmarshal([]byte(value), &cmd); err != nil {
+		return nil, fmt.Errorf("invalid JSON array: %w", err)
+	}
+	return cmd, nil
+}
+
+// applyProcessSettings sets the user, entrypoint, command and working
+// directory on the container configuration.
+func (d *Driver) applyProcessSettings() {
+	if d.User != "" {
+		d.containerCfg.User = d.User
+	}
+
+	if len(d.Entrypoint) > 0 {
+		d.containerCfg.Entrypoint = strslice.StrSlice(d.Entrypoint)
+	}
+
+	if len(d.Cmd) > 0 {
+		d.containerCfg.Cmd = strslice.StrSlice(d.Cmd)
+	}
+
+	if d.WorkingDir != "" {
+		d.containerCfg.WorkingDir = d.WorkingDir
+	}
+}
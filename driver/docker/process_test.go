@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_ProcessSettings(t *testing.T) {
+	op := &driver.Operation{
+		Image: bundle.InvocationImage{
+			BaseImage: bundle.BaseImage{Image: "example.com/myimage"},
+		},
+	}
+
+	t.Run("from settings", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{
+			SettingUser:       "1000:1000",
+			SettingEntrypoint: `["/sbin/tini", "--"]`,
+			SettingCmd:        "/cnab/app/run --verbose",
+			SettingWorkingDir: "/cnab/app",
+		}))
+
+		require.NoError(t, d.setConfigurationOptions(op))
+		assert.Equal(t, "1000:1000", d.containerCfg.User)
+		assert.Equal(t, strslice.StrSlice{"/sbin/tini", "--"}, d.containerCfg.Entrypoint)
+		assert.Equal(t, strslice.StrSlice{"/cnab/app/run", "--verbose"}, d.containerCfg.Cmd)
+		assert.Equal(t, "/cnab/app", d.containerCfg.WorkingDir)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{}))
+
+		require.NoError(t, d.setConfigurationOptions(op))
+		assert.Empty(t, d.containerCfg.User)
+		assert.Equal(t, strslice.StrSlice{"/cnab/app/run"}, d.containerCfg.Entrypoint)
+		assert.Empty(t, d.containerCfg.Cmd)
+		assert.Empty(t, d.containerCfg.WorkingDir)
+	})
+}
+
+func TestDriver_SetConfig_InvalidProcessSettings(t *testing.T) {
+	testcases := []struct {
+		setting string
+		value   string
+	}{
+		{SettingUser, "root"},
+		{SettingUser, "1000:"},
+		{SettingEntrypoint, `["/sbin/tini", `},
+		{SettingCmd, `[1, 2]`},
+		{SettingWorkingDir, "cnab/app"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.setting+"="+tc.value, func(t *testing.T) {
+			d := &Driver{}
+			err := d.SetConfig(map[string]string{tc.setting: tc.value})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.setting)
+		})
+	}
+}
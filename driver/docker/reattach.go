@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+var _ driver.Reattacher = &Driver{}
+
+// containerLister is the subset of the docker client used to find the
+// container of an existing run.
+type containerLister interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+}
+
+// Reattach resumes tracking an operation whose container was started by
+// another process, for example before the process running the driver was
+// restarted. The container is found using the labels set on it when it was
+// created, so the operation must have the same installation and revision.
+// The logs of the container are streamed again from the beginning.
+func (d *Driver) Reattach(op *driver.Operation) (driver.OperationResult, error) {
+	ctx := context.Background()
+
+	cli, err := d.initializeDockerCli()
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+
+	if d.Simulate {
+		return driver.OperationResult{}, nil
+	}
+
+	c, err := findRunContainer(ctx, cli.Client(), op)
+	if err != nil {
+		return driver.OperationResult{}, err
+	}
+	if c.State == "created" {
+		return driver.OperationResult{}, fmt.Errorf("container %s was created but never started, run the operation again", containerDisplayName(c))
+	}
+
+	succeeded := false
+	defer func() {
+		d.cleanupContainer(ctx, cli.Client(), cli.Err(), c.ID, op, succeeded)
+	}()
+
+	logs, err := cli.Client().ContainerLogs(ctx, c.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return driver.OperationResult{}, fmt.Errorf("unable to retrieve logs: %v", err)
+	}
+	stdout, stderr := d.outputStreams(op)
+	go func() {
+		defer logs.Close()
+		stdcopy.StdCopy(stdout, stderr, logs)
+	}()
+
+	opResult, err := d.waitForContainer(ctx, cli, c.ID, op)
+	succeeded = err == nil
+	return opResult, err
+}
+
+// findRunContainer returns the container created by the docker driver for
+// the installation and revision of the operation. When more than one
+// container matches, the most recently created one is returned.
+func findRunContainer(ctx context.Context, cli containerLister, op *driver.Operation) (types.Container, error) {
+	if op.Installation == "" || op.Revision == "" {
+		return types.Container{}, errors.New("the installation and revision of the operation are required to reattach to it")
+	}
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", driver.LabelDriver+"=docker"),
+			filters.Arg("label", driver.LabelInstallation+"="+op.Installation),
+			filters.Arg("label", driver.LabelRevision+"="+op.Revision),
+		),
+	})
+	if err != nil {
+		return types.Container{}, errors.Wrap(err, "could not list containers")
+	}
+	if len(containers) == 0 {
+		return types.Container{}, errors.Wrapf(driver.ErrRunNotFound, "no container found for installation %s revision %s", op.Installation, op.Revision)
+	}
+
+	sort.SliceStable(containers, func(i, j int) bool {
+		return containers[i].Created > containers[j].Created
+	})
+	return containers[0], nil
+}
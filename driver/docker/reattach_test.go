@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+type testContainerLister struct {
+	containers []types.Container
+	options    container.ListOptions
+}
+
+func (c *testContainerLister) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	c.options = options
+	return c.containers, nil
+}
+
+func TestFindRunContainer(t *testing.T) {
+	ctx := context.Background()
+	op := &driver.Operation{Installation: "mysql", Revision: "01FZVC5AVP8Z7A78CSCP1EJ604"}
+
+	t.Run("filters by operation labels", func(t *testing.T) {
+		cli := &testContainerLister{containers: []types.Container{
+			{ID: "abc", State: "exited", Created: 100},
+			{ID: "def", State: "running", Created: 200},
+		}}
+		c, err := findRunContainer(ctx, cli, op)
+		require.NoError(t, err)
+
+		assert.Equal(t, "def", c.ID, "the most recently created container should be used")
+		assert.True(t, cli.options.All, "stopped containers should be found")
+		labels := cli.options.Filters.Get("label")
+		assert.ElementsMatch(t, []string{
+			driver.LabelDriver + "=docker",
+			driver.LabelInstallation + "=mysql",
+			driver.LabelRevision + "=01FZVC5AVP8Z7A78CSCP1EJ604",
+		}, labels)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := findRunContainer(ctx, &testContainerLister{}, op)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, driver.ErrRunNotFound))
+		assert.Contains(t, err.Error(), "installation mysql revision 01FZVC5AVP8Z7A78CSCP1EJ604")
+	})
+
+	t.Run("revision required", func(t *testing.T) {
+		_, err := findRunContainer(ctx, &testContainerLister{}, &driver.Operation{Installation: "mysql"})
+		require.EqualError(t, err, "the installation and revision of the operation are required to reattach to it")
+	})
+}
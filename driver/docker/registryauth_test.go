@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/cli/cli/command"
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestResolveAuthConfig(t *testing.T) {
+	cli, err := command.NewDockerCli()
+	require.NoError(t, err)
+
+	hub := &registrytypes.IndexInfo{Name: "docker.io", Official: true}
+	private := &registrytypes.IndexInfo{Name: "example.com"}
+
+	t.Run("password", func(t *testing.T) {
+		auth := &driver.RegistryAuth{Username: "me", Password: "secret"}
+		cfg, err := resolveAuthConfig(cli, hub, auth)
+		require.NoError(t, err)
+		assert.Equal(t, registrytypes.AuthConfig{Username: "me", Password: "secret", ServerAddress: "https://index.docker.io/v1/"}, cfg)
+	})
+
+	t.Run("token", func(t *testing.T) {
+		auth := &driver.RegistryAuth{Token: "abc123"}
+		cfg, err := resolveAuthConfig(cli, private, auth)
+		require.NoError(t, err)
+		assert.Equal(t, registrytypes.AuthConfig{RegistryToken: "abc123", ServerAddress: "example.com"}, cfg)
+	})
+
+	t.Run("docker config", func(t *testing.T) {
+		auth := &driver.RegistryAuth{DockerConfig: `{"auths": {"example.com": {"auth": "bWU6c2VjcmV0"}}}`}
+		cfg, err := resolveAuthConfig(cli, private, auth)
+		require.NoError(t, err)
+		assert.Equal(t, "me", cfg.Username)
+		assert.Equal(t, "secret", cfg.Password)
+		assert.Equal(t, "example.com", cfg.ServerAddress)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := resolveAuthConfig(cli, private, &driver.RegistryAuth{Password: "secret"})
+		require.EqualError(t, err, "invalid registry auth: a password requires a username")
+	})
+}
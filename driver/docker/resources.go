@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/docker/cli/opts"
+)
+
+const (
+	// SettingCPULimit is the environment variable for the driver that limits
+	// the number of CPUs available to the invocation image, for example 1.5.
+	SettingCPULimit = "DOCKER_CPU_LIMIT"
+
+	// SettingMemoryLimit is the environment variable for the driver that limits
+	// the memory available to the invocation image, for example 512m or 2g.
+	SettingMemoryLimit = "DOCKER_MEMORY_LIMIT"
+
+	// SettingPidsLimit is the environment variable for the driver that limits
+	// the number of processes that can run in the invocation image.
+	SettingPidsLimit = "DOCKER_PIDS_LIMIT"
+)
+
+// parseResourceLimits reads the resource limits from the driver settings,
+// falling back to the limits already set on the driver.
+func (d *Driver) parseResourceLimits(settings map[string]string) error {
+	if value, ok := settings[SettingCPULimit]; ok && value != "" {
+		cpus, err := opts.ParseCPUs(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingCPULimit, value, err)
+		}
+		d.LimitCPU = cpus
+	}
+
+	if value, ok := settings[SettingMemoryLimit]; ok && value != "" {
+		var memory opts.MemBytes
+		if err := memory.Set(value); err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingMemoryLimit, value, err)
+		}
+		d.LimitMemory = memory.Value()
+	}
+
+	if value, ok := settings[SettingPidsLimit]; ok && value != "" {
+		pids, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingPidsLimit, value, err)
+		}
+		d.LimitPids = pids
+	}
+
+	return nil
+}
+
+// applyResourceLimits sets the resource limits on the container host configuration.
+func (d *Driver) applyResourceLimits() {
+	if d.LimitCPU > 0 {
+		d.containerHostCfg.Resources.NanoCPUs = d.LimitCPU
+	}
+
+	if d.LimitMemory > 0 {
+		d.containerHostCfg.Resources.Memory = d.LimitMemory
+	}
+
+	if d.LimitPids != 0 {
+		pids := d.LimitPids
+		d.containerHostCfg.Resources.PidsLimit = &pids
+	}
+}
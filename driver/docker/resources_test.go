@@ -0,0 +1,67 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_ResourceLimits(t *testing.T) {
+	op := &driver.Operation{
+		Image: bundle.InvocationImage{
+			BaseImage: bundle.BaseImage{Image: "example.com/myimage"},
+		},
+	}
+
+	t.Run("from settings", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{
+			SettingCPULimit:    "1.5",
+			SettingMemoryLimit: "512m",
+			SettingPidsLimit:   "100",
+		}))
+		assert.Equal(t, int64(1500000000), d.LimitCPU)
+		assert.Equal(t, int64(512*1024*1024), d.LimitMemory)
+		assert.Equal(t, int64(100), d.LimitPids)
+
+		require.NoError(t, d.setConfigurationOptions(op))
+		assert.Equal(t, int64(1500000000), d.containerHostCfg.Resources.NanoCPUs)
+		assert.Equal(t, int64(512*1024*1024), d.containerHostCfg.Resources.Memory)
+		require.NotNil(t, d.containerHostCfg.Resources.PidsLimit)
+		assert.Equal(t, int64(100), *d.containerHostCfg.Resources.PidsLimit)
+	})
+
+	t.Run("from fields", func(t *testing.T) {
+		d := &Driver{LimitMemory: 1024}
+		require.NoError(t, d.SetConfig(map[string]string{}))
+
+		require.NoError(t, d.setConfigurationOptions(op))
+		assert.Equal(t, int64(0), d.containerHostCfg.Resources.NanoCPUs)
+		assert.Equal(t, int64(1024), d.containerHostCfg.Resources.Memory)
+		assert.Nil(t, d.containerHostCfg.Resources.PidsLimit)
+	})
+}
+
+func TestDriver_SetConfig_InvalidResourceLimits(t *testing.T) {
+	testcases := []struct {
+		setting string
+		value   string
+	}{
+		{SettingCPULimit, "lots"},
+		{SettingMemoryLimit, "12parsecs"},
+		{SettingPidsLimit, "1.5"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.setting, func(t *testing.T) {
+			d := &Driver{}
+			err := d.SetConfig(map[string]string{tc.setting: tc.value})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "environment variable "+tc.setting+" has unexpected value")
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package driver
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
+	"time"
 
 	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
 )
 
 // ImageType constants provide some of the image types supported
@@ -13,6 +17,8 @@ const (
 	ImageTypeDocker = "docker"
 	ImageTypeOCI    = "oci"
 	ImageTypeQCOW   = "qcow"
+	ImageTypeExec   = "exec"
+	ImageTypeBinary = "binary"
 )
 
 // Operation describes the data passed into the driver to run an operation
@@ -34,12 +40,41 @@ type Operation struct {
 	// Outputs map of output paths (e.g. /cnab/app/outputs/NAME) to the name of the output.
 	// Indicates which outputs the driver should return the contents of in the OperationResult.
 	Outputs map[string]string `json:"outputs"`
+	// RelocationMapping maps the original references of the bundle's images to
+	// their relocated references. Use ApplyRelocationMapping to set it.
+	RelocationMapping bundle.RelocationMapping `json:"relocationMapping,omitempty"`
 	// Output stream for log messages from the driver
 	Out io.Writer `json:"-"`
 	// Output stream for error messages from the driver
 	Err io.Writer `json:"-"`
+	// In is streamed to the standard input of the invocation image, for
+	// interactive actions such as prompts or debug shells, when the driver
+	// supports it. See SupportsStdin.
+	In io.Reader `json:"-"`
 	// Bundle represents the bundle information for use by the operation
 	Bundle *bundle.Bundle
+	// CorrelationID identifies the operation in the logs and telemetry of the
+	// calling tool, for example a request ID, and is applied by drivers as the
+	// LabelCorrelationID label.
+	CorrelationID string `json:"correlationId,omitempty"`
+	// Labels that drivers should apply to any resources created for the operation,
+	// in addition to their own, for example a CI run ID or tenant ID.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations that drivers should apply to any resources created for the operation,
+	// in addition to their own.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// RegistryAuth holds the credentials used to pull the invocation image,
+	// instead of the credentials configured for the driver, and is nil when
+	// the driver's own credentials are used. It is not serialized, so that
+	// the credentials are not persisted with the operation.
+	RegistryAuth *RegistryAuth `json:"-"`
+	// Events receives the progress of the operation, such as when the invocation
+	// image is pulled. Use Emit to report an event.
+	Events EventHandler `json:"-"`
+	// Deadline is the time by which the operation must complete, and is zero
+	// when the operation has no deadline. Drivers stop the operation when it
+	// is reached and return an error that wraps ErrDeadlineExceeded.
+	Deadline time.Time `json:"-"`
 }
 
 // ResolvedCred is a credential that has been resolved and is ready for injection into the runtime.
@@ -56,35 +91,78 @@ type OperationResult struct {
 
 	// Error is any errors from executing the operation.
 	Error error
+
+	// InvocationImage is the invocation image that was used to run the operation.
+	InvocationImage bundle.InvocationImage
+
+	// Metadata reported by the driver about how the operation was executed,
+	// such as how the invocation image exited. The keys are defined by each
+	// driver.
+	Metadata map[string]string
 }
 
 // SetDefaultOutputValues for an output when it does not exist and it has a
-// non-empty default value.
+// non-empty default value. The defaults of the properties of object outputs
+// are materialized recursively, both in the default of a missing output and
+// in the JSON document of an output that exists but is missing properties.
 func (r *OperationResult) SetDefaultOutputValues(op Operation) error {
 	if r.Outputs == nil {
 		r.Outputs = make(map[string]string)
 	}
 
 	for name, output := range op.Bundle.Outputs {
-		_, hasOutput := r.Outputs[name]
-		if hasOutput || !output.AppliesTo(op.Action) {
+		if !output.AppliesTo(op.Action) {
+			continue
+		}
+
+		outputDefinition, exists := op.Bundle.Definitions[output.Definition]
+		if !exists {
 			continue
 		}
 
-		if outputDefinition, exists := op.Bundle.Definitions[output.Definition]; exists {
-			outputDefault := outputDefinition.Default
-			if outputDefault != nil {
-				contents := fmt.Sprintf("%v", outputDefault)
-				r.Outputs[name] = contents
-			} else {
-				return fmt.Errorf("required output %s is missing and has no default", name)
+		if contents, hasOutput := r.Outputs[name]; hasOutput {
+			defaulted, err := applyObjectDefaults(outputDefinition, contents)
+			if err != nil {
+				return fmt.Errorf("could not apply the default values of output %s: %w", name, err)
 			}
+			r.Outputs[name] = defaulted
+			continue
+		}
+
+		outputDefault := outputDefinition.DefaultValue()
+		if outputDefault == nil {
+			return fmt.Errorf("required output %s is missing and has no default", name)
 		}
+		contents, err := definition.FormatValue(outputDefault)
+		if err != nil {
+			return fmt.Errorf("could not format the default value of output %s: %w", name, err)
+		}
+		r.Outputs[name] = contents
 	}
 
 	return nil
 }
 
+// applyObjectDefaults sets the defaults of the properties missing from the
+// JSON object in the contents of an output. The contents are returned
+// unchanged when they are not a JSON object or no default applies.
+func applyObjectDefaults(def *definition.Schema, contents string) (string, error) {
+	if len(def.Properties) == 0 {
+		return contents, nil
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(contents), &obj); err != nil || obj == nil {
+		return contents, nil
+	}
+
+	defaulted := def.ApplyDefaults(obj)
+	if reflect.DeepEqual(obj, defaulted) {
+		return contents, nil
+	}
+	return definition.FormatValue(defaulted)
+}
+
 // Driver is capable of running a invocation image
 type Driver interface {
 	// Run executes the operation inside of the invocation image
@@ -2,13 +2,17 @@ package driver
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
 )
 
 func TestOperation_Unmarshall(t *testing.T) {
@@ -84,3 +88,67 @@ func TestOperation_Marshall(t *testing.T) {
 	expectedJSON := string(bytes)
 	is.Equal(expectedJSON, actualJSON)
 }
+
+func TestIsImageError(t *testing.T) {
+	imgErr := NewImageError("cnab/helloworld:latest", errors.New("pull failed"))
+	assert.True(t, IsImageError(imgErr))
+	assert.True(t, IsImageError(fmt.Errorf("wrapped: %w", imgErr)))
+	assert.EqualError(t, imgErr, "pull failed")
+	assert.False(t, IsImageError(errors.New("container exit code: 1")))
+	assert.False(t, IsImageError(nil))
+}
+
+func TestOperationResult_SetDefaultOutputValues(t *testing.T) {
+	op := Operation{
+		Action: "install",
+		Bundle: &bundle.Bundle{
+			Definitions: definition.Definitions{
+				"connection": {
+					Type: "object",
+					Properties: map[string]*definition.Schema{
+						"host": {Type: "string", Default: "localhost"},
+						"port": {Type: "integer", Default: 5432},
+					},
+				},
+				"string":   {Type: "string", Default: "hello"},
+				"required": {Type: "string"},
+			},
+			Outputs: map[string]bundle.Output{
+				"connection": {Definition: "connection"},
+				"greeting":   {Definition: "string"},
+				"partial":    {Definition: "connection"},
+				"raw":        {Definition: "connection"},
+			},
+		},
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		r := OperationResult{Outputs: map[string]string{
+			"partial": `{"host":"db"}`,
+			"raw":     "not json",
+		}}
+		require.NoError(t, r.SetDefaultOutputValues(op))
+		assert.Equal(t, map[string]string{
+			"connection": `{"host":"localhost","port":5432}`,
+			"greeting":   "hello",
+			"partial":    `{"host":"db","port":5432}`,
+			"raw":        "not json",
+		}, r.Outputs)
+	})
+
+	t.Run("complete output is unchanged", func(t *testing.T) {
+		r := OperationResult{Outputs: map[string]string{
+			"partial": `{ "host": "db", "port": 1 }`,
+		}}
+		require.NoError(t, r.SetDefaultOutputValues(op))
+		assert.Equal(t, `{ "host": "db", "port": 1 }`, r.Outputs["partial"])
+	})
+
+	t.Run("missing required output", func(t *testing.T) {
+		b := *op.Bundle
+		b.Outputs = map[string]bundle.Output{"required": {Definition: "required"}}
+		r := OperationResult{}
+		err := r.SetDefaultOutputValues(Operation{Action: "install", Bundle: &b})
+		assert.EqualError(t, err, "required output required is missing and has no default")
+	})
+}
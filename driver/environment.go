@@ -0,0 +1,87 @@
+package driver
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// cnabGoModule is the path of the module that the drivers are built from.
+const cnabGoModule = "github.com/cnabio/cnab-go"
+
+// ExecutionEnvironment describes the environment that ran an operation, so
+// that failures can be correlated with differences between environments.
+type ExecutionEnvironment struct {
+	// Driver is the name of the driver.
+	Driver string `json:"driver"`
+
+	// DriverVersion is the version of the driver, when known. For the drivers
+	// of this module, it is the version of the module.
+	DriverVersion string `json:"driverVersion,omitempty"`
+
+	// HostOS is the operating system of the host running the driver.
+	HostOS string `json:"hostOS"`
+
+	// HostArch is the architecture of the host running the driver.
+	HostArch string `json:"hostArch"`
+
+	// ServerVersion is the version of the server that ran the invocation
+	// image, such as the docker daemon or the kubernetes cluster, when known.
+	ServerVersion string `json:"serverVersion,omitempty"`
+
+	// ServerOS is the operating system of the server, when known.
+	ServerOS string `json:"serverOS,omitempty"`
+
+	// ServerArch is the architecture of the server, when known.
+	ServerArch string `json:"serverArch,omitempty"`
+}
+
+// EnvironmentDescriber is implemented by drivers that describe the
+// environment that they run operations in.
+type EnvironmentDescriber interface {
+	// DescribeEnvironment returns the name of the driver and the details of
+	// the server that it runs operations on, when they are cheaply available.
+	// It is called after an operation ran, so that the driver can reuse the
+	// clients that it initialized, and must not fail the operation.
+	DescribeEnvironment() ExecutionEnvironment
+}
+
+// DescribeEnvironment returns the environment that the driver runs
+// operations in. The host and the version of the drivers of this module are
+// always set, the other details are set when the driver implements
+// EnvironmentDescriber.
+func DescribeEnvironment(d Driver) ExecutionEnvironment {
+	var env ExecutionEnvironment
+	if describer, ok := d.(EnvironmentDescriber); ok {
+		env = describer.DescribeEnvironment()
+	}
+	if env.Driver == "" {
+		env.Driver = fmt.Sprintf("%T", d)
+	}
+	if env.DriverVersion == "" {
+		env.DriverVersion = moduleVersion()
+	}
+	env.HostOS = runtime.GOOS
+	env.HostArch = runtime.GOARCH
+	return env
+}
+
+// moduleVersion returns the version of this module in the running binary.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if info.Main.Path == cnabGoModule {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == cnabGoModule {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package driver
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type describedDriver struct {
+	env ExecutionEnvironment
+}
+
+func (d *describedDriver) Run(op *Operation) (OperationResult, error) {
+	return OperationResult{}, nil
+}
+
+func (d *describedDriver) Handles(imageType string) bool {
+	return true
+}
+
+func (d *describedDriver) DescribeEnvironment() ExecutionEnvironment {
+	return d.env
+}
+
+func TestDescribeEnvironment(t *testing.T) {
+	t.Run("describer", func(t *testing.T) {
+		d := &describedDriver{env: ExecutionEnvironment{
+			Driver:        "test",
+			DriverVersion: "v1.0.0",
+			ServerVersion: "20.10.7",
+			ServerOS:      "linux",
+			ServerArch:    "arm64",
+		}}
+
+		assert.Equal(t, ExecutionEnvironment{
+			Driver:        "test",
+			DriverVersion: "v1.0.0",
+			HostOS:        runtime.GOOS,
+			HostArch:      runtime.GOARCH,
+			ServerVersion: "20.10.7",
+			ServerOS:      "linux",
+			ServerArch:    "arm64",
+		}, DescribeEnvironment(d))
+	})
+
+	t.Run("driver without describer", func(t *testing.T) {
+		env := DescribeEnvironment(undescribedRunner{})
+		assert.Equal(t, "driver.undescribedRunner", env.Driver)
+		assert.Equal(t, runtime.GOOS, env.HostOS)
+		assert.Equal(t, runtime.GOARCH, env.HostArch)
+		assert.Empty(t, env.ServerVersion)
+	})
+}
+
+type undescribedRunner struct{}
+
+func (undescribedRunner) Run(op *Operation) (OperationResult, error) {
+	return OperationResult{}, nil
+}
+
+func (undescribedRunner) Handles(imageType string) bool {
+	return true
+}
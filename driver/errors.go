@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The errors returned by drivers wrap the following sentinel errors, so that
+// callers can handle them with errors.Is, regardless of the driver that ran
+// the operation. Drivers may also return their own errors, such as the
+// kubernetes.JobFailedError, that can be inspected with errors.As.
+var (
+	// ErrExecutionInterrupted is wrapped by the error returned by a driver when
+	// the operation was stopped before it completed, for example because the
+	// resources running it were deleted by another tool, rather than because
+	// the bundle failed. The result of the operation is recorded as canceled.
+	ErrExecutionInterrupted = errors.New("the execution of the operation was interrupted")
+
+	// ErrDeadlineExceeded is wrapped by the errors returned by drivers when an
+	// operation was stopped because it did not complete before its Deadline.
+	ErrDeadlineExceeded = errors.New("the operation did not complete before its deadline")
+
+	// ErrRunNotFound is returned by Reattacher.Reattach when the driver cannot
+	// find the resources of an existing run of the operation.
+	ErrRunNotFound = errors.New("no existing run found for the operation")
+
+	// ErrStdinNotSupported is returned when an operation with an input stream
+	// is run with a driver that cannot stream it to the invocation image.
+	ErrStdinNotSupported = errors.New("the driver does not support streaming the standard input of the operation")
+)
+
+// DeadlineExceededError is returned by a driver when it stopped an operation
+// that did not complete before its deadline.
+type DeadlineExceededError struct {
+	// Deadline of the operation.
+	Deadline time.Time
+}
+
+func (e DeadlineExceededError) Error() string {
+	return fmt.Sprintf("the operation was stopped because it did not complete before its deadline of %s",
+		e.Deadline.Format(time.RFC3339))
+}
+
+func (e DeadlineExceededError) Unwrap() error {
+	return ErrDeadlineExceeded
+}
+
+// ImageError indicates that the driver could not run the operation because of
+// a problem with the invocation image, for example the image could not be pulled
+// or is not supported on the platform, rather than a failure of the bundle itself.
+type ImageError struct {
+	// Image is the name of the invocation image.
+	Image string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// NewImageError wraps an error encountered while preparing the specified
+// invocation image.
+func NewImageError(image string, err error) *ImageError {
+	return &ImageError{Image: image, Err: err}
+}
+
+func (e *ImageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ImageError) Unwrap() error {
+	return e.Err
+}
+
+// IsImageError determines if the error, or any error that it wraps, is an ImageError.
+func IsImageError(err error) bool {
+	var imgErr *ImageError
+	return errors.As(err, &imgErr)
+}
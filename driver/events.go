@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"io"
+	"time"
+)
+
+// EventType identifies the kind of progress reported by an Event.
+type EventType string
+
+const (
+	// EventOperationStarted is emitted before the driver runs the operation.
+	EventOperationStarted EventType = "OperationStarted"
+
+	// EventImagePulled is emitted when the driver pulled the invocation image.
+	EventImagePulled EventType = "ImagePulled"
+
+	// EventLogsChunk is emitted for each chunk of logs written by the
+	// invocation image, with the chunk in Data.
+	EventLogsChunk EventType = "LogsChunk"
+
+	// EventOutputCaptured is emitted for each output collected from the
+	// invocation image, with the name of the output in Output.
+	EventOutputCaptured EventType = "OutputCaptured"
+
+	// EventOperationCompleted is emitted after the driver has run the
+	// operation, with any error from the driver in Error. When the action
+	// falls back to another invocation image, it is emitted for each image
+	// that was tried.
+	EventOperationCompleted EventType = "OperationCompleted"
+)
+
+const (
+	// StreamStdout identifies logs written to the operation's Out stream.
+	StreamStdout = "stdout"
+
+	// StreamStderr identifies logs written to the operation's Err stream.
+	StreamStderr = "stderr"
+)
+
+// Event reports the progress of an operation, so that a UI can render it
+// while the operation runs instead of only getting the final OperationResult.
+type Event struct {
+	// Type of the event.
+	Type EventType
+
+	// Time that the event occurred.
+	Time time.Time
+
+	// Installation is the name of the installation of the operation.
+	Installation string
+
+	// Action is the action performed by the operation.
+	Action string
+
+	// Image is the invocation image, set on EventImagePulled,
+	// EventOperationStarted and EventOperationCompleted.
+	Image string
+
+	// Stream is the stream that the logs were written to, either StreamStdout
+	// or StreamStderr, set on EventLogsChunk.
+	Stream string
+
+	// Data is the chunk of logs, set on EventLogsChunk.
+	Data []byte
+
+	// Output is the name of the output, set on EventOutputCaptured.
+	Output string
+
+	// Error is the error from running the operation, set on
+	// EventOperationCompleted when the operation failed.
+	Error error
+}
+
+// EventHandler receives the events of an operation. Events may be emitted
+// from multiple goroutines, for example when logs are written to both the
+// Out and Err streams, so handlers must be safe for concurrent use and should
+// return quickly to avoid slowing down the operation.
+type EventHandler func(Event)
+
+// Emit sends the event to the operation's event handler, when set, filling in
+// the operation's installation and action and the time of the event.
+func (o *Operation) Emit(e Event) {
+	if o.Events == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Installation == "" {
+		e.Installation = o.Installation
+	}
+	if e.Action == "" {
+		e.Action = o.Action
+	}
+	o.Events(e)
+}
+
+// EventWriter returns a writer that emits an EventLogsChunk for each write,
+// before writing it to w, so that the logs of the operation are reported to
+// its event handler.
+func (o *Operation) EventWriter(stream string, w io.Writer) io.Writer {
+	return &eventWriter{op: o, stream: stream, out: w}
+}
+
+type eventWriter struct {
+	op     *Operation
+	stream string
+	out    io.Writer
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		data := make([]byte, len(p))
+		copy(data, p)
+		w.op.Emit(Event{Type: EventLogsChunk, Stream: w.stream, Data: data})
+	}
+
+	if w.out == nil {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
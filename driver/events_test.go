@@ -0,0 +1,64 @@
+package driver
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperation_Emit(t *testing.T) {
+	t.Run("no handler", func(t *testing.T) {
+		op := &Operation{}
+		assert.NotPanics(t, func() {
+			op.Emit(Event{Type: EventOperationStarted})
+		})
+	})
+
+	t.Run("fills in the operation", func(t *testing.T) {
+		var events []Event
+		op := &Operation{
+			Installation: "mysql",
+			Action:       "install",
+			Events:       func(e Event) { events = append(events, e) },
+		}
+
+		op.Emit(Event{Type: EventImagePulled, Image: "example.com/mysql:v1"})
+
+		require.Len(t, events, 1)
+		e := events[0]
+		assert.Equal(t, EventImagePulled, e.Type)
+		assert.Equal(t, "mysql", e.Installation)
+		assert.Equal(t, "install", e.Action)
+		assert.Equal(t, "example.com/mysql:v1", e.Image)
+		assert.False(t, e.Time.IsZero(), "the time of the event should be set")
+	})
+}
+
+func TestOperation_EventWriter(t *testing.T) {
+	var events []Event
+	op := &Operation{
+		Events: func(e Event) { events = append(events, e) },
+	}
+
+	var out bytes.Buffer
+	w := op.EventWriter(StreamStderr, &out)
+	buf := []byte("installing mysql\n")
+	fmt.Fprint(w, string(buf))
+	fmt.Fprint(w, "")
+	copy(buf, "XXXX")
+
+	assert.Equal(t, "installing mysql\n", out.String())
+	require.Len(t, events, 1)
+	assert.Equal(t, EventLogsChunk, events[0].Type)
+	assert.Equal(t, StreamStderr, events[0].Stream)
+	assert.Equal(t, "installing mysql\n", string(events[0].Data))
+
+	t.Run("nil writer", func(t *testing.T) {
+		n, err := op.EventWriter(StreamStdout, nil).Write([]byte("hello\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 6, n)
+	})
+}
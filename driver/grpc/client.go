@@ -0,0 +1,321 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// handshakeTimeout is how long Launch waits for a plugin to print its handshake.
+var handshakeTimeout = 30 * time.Second
+
+var (
+	_ driver.Driver       = &Driver{}
+	_ driver.Configurable = &Driver{}
+)
+
+// Driver runs operations using a driver plugin over gRPC.
+type Driver struct {
+	conn *gogrpc.ClientConn
+
+	// plugin is the plugin process, when the driver was created with Launch.
+	plugin *exec.Cmd
+
+	// socketDir is the private directory of the plugin's socket, when the
+	// driver was created with Launch.
+	socketDir string
+}
+
+// New creates a driver that uses an existing connection to a driver plugin.
+func New(conn *gogrpc.ClientConn) *Driver {
+	return &Driver{conn: conn}
+}
+
+// Dial creates a driver that connects to a driver plugin listening on the
+// target address. The transport credentials of the connection must be set
+// in the options, for example with gogrpc.WithTransportCredentials, and
+// insecure.NewCredentials() should only be used explicitly, for plugins
+// reached over a trusted channel.
+func Dial(target string, opts ...gogrpc.DialOption) (*Driver, error) {
+	conn, err := gogrpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the driver plugin at %s: %w", target, err)
+	}
+
+	return New(conn), nil
+}
+
+// LaunchOptions customize how Launch starts a driver plugin.
+type LaunchOptions struct {
+	// Args are the arguments passed to the plugin.
+	Args []string
+
+	// AllowInsecureTCP accepts plugins that serve the protocol on a tcp port
+	// instead of the unix socket requested by Launch. The connection is not
+	// authenticated nor encrypted, so any local process could connect to the
+	// plugin and run operations with its credentials.
+	AllowInsecureTCP bool
+}
+
+// Launch starts the driver plugin executable, waits for its handshake and
+// connects to it. Call Close to stop the plugin.
+//
+// The plugin is asked to serve the protocol on a unix socket, in a
+// directory that is only accessible by the current user, and plugins that
+// serve it on a tcp port are rejected. See LaunchWithOptions to allow them.
+func Launch(path string, args ...string) (*Driver, error) {
+	return LaunchWithOptions(path, LaunchOptions{Args: args})
+}
+
+// LaunchWithOptions starts the driver plugin executable like Launch, with
+// the specified options.
+func LaunchWithOptions(path string, opts LaunchOptions) (*Driver, error) {
+	socketDir, err := ioutil.TempDir("", "cnab-driver-plugin")
+	if err != nil {
+		return nil, fmt.Errorf("could not create the socket directory of the driver plugin: %w", err)
+	}
+	if err := os.Chmod(socketDir, 0700); err != nil {
+		os.RemoveAll(socketDir)
+		return nil, fmt.Errorf("could not create the socket directory of the driver plugin: %w", err)
+	}
+	socket := filepath.Join(socketDir, "plugin.sock")
+
+	cmd := exec.Command(path, opts.Args...)
+	cmd.Env = append(os.Environ(),
+		PluginMagicCookieKey+"="+PluginMagicCookie,
+		PluginSocketKey+"="+socket,
+	)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		os.RemoveAll(socketDir)
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(socketDir)
+		return nil, fmt.Errorf("could not start the driver plugin %s: %w", path, err)
+	}
+
+	stop := func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+		os.RemoveAll(socketDir)
+	}
+
+	network, address, err := readHandshake(stdout, handshakeTimeout)
+	if err != nil {
+		stop()
+		return nil, fmt.Errorf("invalid handshake from the driver plugin %s: %w", path, err)
+	}
+
+	// Keep draining the plugin's stdout so that it doesn't block on writes
+	go io.Copy(ioutil.Discard, stdout)
+
+	var target string
+	var creds gogrpc.DialOption
+	switch {
+	case network == "unix":
+		target = "unix://" + address
+		// Local credentials verify that the connection is made over the
+		// unix socket, whose access is restricted by its permissions
+		creds = gogrpc.WithTransportCredentials(local.NewCredentials())
+	case opts.AllowInsecureTCP:
+		target = address
+		creds = gogrpc.WithTransportCredentials(insecure.NewCredentials())
+	default:
+		stop()
+		return nil, fmt.Errorf("the driver plugin %s serves the protocol on the insecure tcp address %s instead of a unix socket, set LaunchOptions.AllowInsecureTCP to allow it", path, address)
+	}
+
+	d, err := Dial(target, creds)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	d.plugin = cmd
+	d.socketDir = socketDir
+	return d, nil
+}
+
+// readHandshake reads the first line printed by the plugin, in the format
+// PROTOCOL_VERSION|NETWORK|ADDRESS.
+func readHandshake(r io.Reader, timeout time.Duration) (string, string, error) {
+	lines := make(chan string, 1)
+	errs := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil {
+			errs <- err
+			return
+		}
+		lines <- line
+	}()
+
+	var line string
+	select {
+	case line = <-lines:
+	case err := <-errs:
+		return "", "", err
+	case <-time.After(timeout):
+		return "", "", fmt.Errorf("timed out after %s", timeout)
+	}
+
+	return parseHandshake(line)
+}
+
+func parseHandshake(line string) (string, string, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("expected PROTOCOL_VERSION|NETWORK|ADDRESS but got %q", line)
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || version != ProtocolVersion {
+		return "", "", fmt.Errorf("unsupported protocol version %q, expected %d", parts[0], ProtocolVersion)
+	}
+
+	network, address := parts[1], parts[2]
+	if network != "tcp" && network != "unix" {
+		return "", "", fmt.Errorf("unsupported network %q, expected tcp or unix", network)
+	}
+
+	return network, address, nil
+}
+
+// Close the connection to the plugin, and stop the plugin if it was started
+// with Launch.
+func (d *Driver) Close() error {
+	err := d.conn.Close()
+	if d.plugin != nil {
+		d.plugin.Process.Kill()
+		d.plugin.Wait()
+	}
+	if d.socketDir != "" {
+		os.RemoveAll(d.socketDir)
+	}
+	return err
+}
+
+// Handles asks the plugin if it supports the image type. When the plugin
+// cannot be reached, false is returned.
+func (d *Driver) Handles(imageType string) bool {
+	resp := &HandlesResponse{}
+	err := d.invoke("Handles", &HandlesRequest{ImageType: imageType}, resp)
+	return err == nil && resp.Handles
+}
+
+// Config returns the configuration supported by the plugin.
+func (d *Driver) Config() map[string]string {
+	resp := &ConfigResponse{}
+	if err := d.invoke("Config", &ConfigRequest{}, resp); err != nil {
+		return map[string]string{}
+	}
+	return resp.Config
+}
+
+// SetConfig configures the plugin. Plugins that are not configurable ignore
+// the settings.
+func (d *Driver) SetConfig(settings map[string]string) error {
+	err := d.invoke("SetConfig", &SetConfigRequest{Settings: settings}, &SetConfigResponse{})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return errors.New(status.Convert(err).Message())
+	}
+	return nil
+}
+
+// Run executes the operation using the plugin, copying the output of the
+// invocation image to the operation's streams as it is received.
+func (d *Driver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	// The deadline of the operation is propagated to the plugin with the call
+	ctx, cancel := op.WithDeadline(context.Background())
+	defer cancel()
+
+	stream, err := d.conn.NewStream(ctx, &serviceDesc.Streams[0], methodName("Run"), gogrpc.ForceCodec(jsonCodec{}))
+	if err != nil {
+		return driver.OperationResult{}, fmt.Errorf("could not call the driver plugin: %w", err)
+	}
+	if err := stream.SendMsg(&RunRequest{Operation: *op}); err != nil {
+		return driver.OperationResult{}, fmt.Errorf("could not send the operation to the driver plugin: %w", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return driver.OperationResult{}, fmt.Errorf("could not send the operation to the driver plugin: %w", err)
+	}
+
+	out, errOut := op.Out, op.Err
+	if out == nil {
+		out = ioutil.Discard
+	}
+	if errOut == nil {
+		errOut = ioutil.Discard
+	}
+
+	for {
+		resp := &RunResponse{}
+		err := stream.RecvMsg(resp)
+		if err == io.EOF {
+			return driver.OperationResult{}, errors.New("the driver plugin did not return the result of the operation")
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return driver.OperationResult{}, op.DeadlineError()
+		}
+		if err != nil {
+			return driver.OperationResult{}, fmt.Errorf("error receiving from the driver plugin: %w", err)
+		}
+
+		if len(resp.Stdout) > 0 {
+			out.Write(resp.Stdout)
+		}
+		if len(resp.Stderr) > 0 {
+			errOut.Write(resp.Stderr)
+		}
+		if resp.Result != nil {
+			return resp.Result.toOperationResult(op)
+		}
+	}
+}
+
+func (r *RunResult) toOperationResult(op *driver.Operation) (driver.OperationResult, error) {
+	opResult := driver.OperationResult{Outputs: r.Outputs}
+	if opResult.Outputs == nil {
+		opResult.Outputs = map[string]string{}
+	}
+	if r.OperationError != "" {
+		opResult.Error = errors.New(r.OperationError)
+	}
+
+	if r.RunError == "" {
+		return opResult, nil
+	}
+
+	err := errors.New(r.RunError)
+	if r.ImageError {
+		err = driver.NewImageError(op.Image.Image, err)
+	}
+	return opResult, err
+}
+
+func (d *Driver) invoke(method string, req interface{}, resp interface{}) error {
+	return d.conn.Invoke(context.Background(), methodName(method), req, resp, gogrpc.ForceCodec(jsonCodec{}))
+}
@@ -0,0 +1,42 @@
+/*
+Package grpc implements a driver that runs operations using an out-of-process
+driver plugin over gRPC, so that CNAB tools can support additional drivers
+without compiling them into their binary.
+
+# Protocol
+
+Plugins implement the gRPC service cnab.driver.v1.Driver. Messages are
+encoded as JSON, using the content-subtype "json", so plugins may be written
+in any language without generated code. The service has the following methods:
+
+	rpc Handles(HandlesRequest) returns (HandlesResponse)
+	rpc Config(ConfigRequest) returns (ConfigResponse)
+	rpc SetConfig(SetConfigRequest) returns (SetConfigResponse)
+	rpc Run(RunRequest) returns (stream RunResponse)
+
+Run streams the output of the invocation image as RunResponse messages with
+Stdout or Stderr set, followed by a final message with the Result set.
+Config and SetConfig are optional and may return codes.Unimplemented when the
+plugin is not configurable.
+
+# Plugins
+
+Similar to hashicorp/go-plugin, a plugin is an executable that is started by
+Launch. The plugin must verify that the environment variable
+CNAB_DRIVER_PLUGIN contains the PluginMagicCookie, start serving the
+protocol on the unix socket whose path is in the environment variable
+CNAB_DRIVER_PLUGIN_SOCKET, with permissions 0600, and then print a handshake
+line to stdout in the format
+
+	PROTOCOL_VERSION|NETWORK|ADDRESS
+
+for example "1|unix|/tmp/cnab-driver-plugin123/plugin.sock". Plugins written
+in Go can call Serve, which does all of this.
+
+The socket is created in a directory only accessible by the user running
+Launch, so that other users cannot connect to the plugin. Plugins that
+serve the protocol on a tcp port, for example "1|tcp|127.0.0.1:41234", are
+not authenticated, and are only accepted with
+LaunchOptions.AllowInsecureTCP.
+*/
+package grpc
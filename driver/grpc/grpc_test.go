@@ -0,0 +1,245 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// testPluginTCPKey is set when launching the test binary as a plugin that
+// serves the protocol on a tcp port.
+const testPluginTCPKey = "CNAB_TEST_PLUGIN_TCP"
+
+func TestMain(m *testing.M) {
+	// When the test binary is launched as a plugin by TestLaunch, serve the test driver
+	if os.Getenv(PluginMagicCookieKey) == PluginMagicCookie {
+		// Serve on a tcp port like plugins that do not support unix sockets
+		if os.Getenv(testPluginTCPKey) != "" {
+			os.Unsetenv(PluginSocketKey)
+		}
+		if err := Serve(&testDriver{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testDriver echoes the operation's environment as outputs.
+type testDriver struct {
+	settings map[string]string
+}
+
+func (d *testDriver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	switch op.Action {
+	case "image-error":
+		return driver.OperationResult{}, driver.NewImageError(op.Image.Image, errors.New("pull access denied"))
+	case "fail":
+		fmt.Fprintln(op.Err, "something went wrong")
+		return driver.OperationResult{Error: errors.New("exit code 1")}, nil
+	}
+
+	fmt.Fprintf(op.Out, "running %s for %s\n", op.Action, op.Installation)
+	return driver.OperationResult{Outputs: op.Environment}, nil
+}
+
+func (d *testDriver) Handles(imageType string) bool {
+	return imageType == driver.ImageTypeDocker
+}
+
+func (d *testDriver) Config() map[string]string {
+	return map[string]string{"VERBOSE": "Increase verbosity"}
+}
+
+func (d *testDriver) SetConfig(settings map[string]string) error {
+	if _, ok := settings["INVALID"]; ok {
+		return errors.New("INVALID is not a supported setting")
+	}
+	d.settings = settings
+	return nil
+}
+
+// notConfigurableDriver hides the Configurable methods of the test driver.
+type notConfigurableDriver struct {
+	driver.Driver
+}
+
+// startPlugin serves the driver on an in-memory connection and returns a
+// client connected to it.
+func startPlugin(t *testing.T, d driver.Driver) *Driver {
+	listener := bufconn.Listen(1024 * 1024)
+	srv := NewGRPCServer()
+	NewServer(d).Register(srv)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	client, err := Dial("passthrough:///bufconn",
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()),
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func testOperation(action string) *driver.Operation {
+	return &driver.Operation{
+		Installation: "mysql",
+		Action:       action,
+		Image:        bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "example.com/mysql:1.0.0"}},
+		Environment:  map[string]string{"HOST": "localhost"},
+		Bundle:       &bundle.Bundle{Name: "mysql"},
+	}
+}
+
+func TestDriver_Run(t *testing.T) {
+	client := startPlugin(t, &testDriver{})
+
+	t.Run("success", func(t *testing.T) {
+		op := testOperation("install")
+		var out bytes.Buffer
+		op.Out = &out
+
+		opResult, err := client.Run(op)
+		require.NoError(t, err)
+		assert.NoError(t, opResult.Error)
+		assert.Equal(t, map[string]string{"HOST": "localhost"}, opResult.Outputs)
+		assert.Equal(t, "running install for mysql\n", out.String(), "the plugin's output should be streamed to the operation")
+	})
+
+	t.Run("operation failed", func(t *testing.T) {
+		op := testOperation("fail")
+		var errOut bytes.Buffer
+		op.Err = &errOut
+
+		opResult, err := client.Run(op)
+		require.NoError(t, err)
+		assert.EqualError(t, opResult.Error, "exit code 1")
+		assert.Equal(t, "something went wrong\n", errOut.String())
+	})
+
+	t.Run("image error", func(t *testing.T) {
+		_, err := client.Run(testOperation("image-error"))
+		require.Error(t, err)
+		assert.True(t, driver.IsImageError(err), "image errors should be preserved by the protocol")
+		assert.EqualError(t, err, "pull access denied")
+	})
+}
+
+func TestDriver_Configurable(t *testing.T) {
+	t.Run("configurable", func(t *testing.T) {
+		plugin := &testDriver{}
+		client := startPlugin(t, plugin)
+
+		assert.True(t, client.Handles(driver.ImageTypeDocker))
+		assert.False(t, client.Handles(driver.ImageTypeQCOW))
+		assert.Equal(t, map[string]string{"VERBOSE": "Increase verbosity"}, client.Config())
+
+		require.NoError(t, client.SetConfig(map[string]string{"VERBOSE": "true"}))
+		assert.Equal(t, map[string]string{"VERBOSE": "true"}, plugin.settings)
+
+		err := client.SetConfig(map[string]string{"INVALID": "true"})
+		assert.EqualError(t, err, "INVALID is not a supported setting")
+	})
+
+	t.Run("not configurable", func(t *testing.T) {
+		client := startPlugin(t, notConfigurableDriver{&testDriver{}})
+
+		assert.Empty(t, client.Config())
+		assert.NoError(t, client.SetConfig(map[string]string{"VERBOSE": "true"}))
+	})
+}
+
+func TestLaunch(t *testing.T) {
+	// Launch the test binary, which serves the test driver from TestMain
+	client, err := Launch(os.Args[0])
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.True(t, client.Handles(driver.ImageTypeDocker))
+
+	opResult, err := client.Run(testOperation("install"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"HOST": "localhost"}, opResult.Outputs)
+
+	socket := filepath.Join(client.socketDir, "plugin.sock")
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSocket|0600, info.Mode(), "the plugin should serve on a unix socket only accessible by the current user")
+	info, err = os.Stat(client.socketDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	require.NoError(t, client.Close())
+	assert.NoDirExists(t, client.socketDir, "the socket directory should be removed when the plugin is stopped")
+}
+
+func TestLaunch_InsecureTCP(t *testing.T) {
+	t.Setenv(testPluginTCPKey, "true")
+
+	_, err := Launch(os.Args[0])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "serves the protocol on the insecure tcp address")
+
+	client, err := LaunchWithOptions(os.Args[0], LaunchOptions{AllowInsecureTCP: true})
+	require.NoError(t, err)
+	defer client.Close()
+	assert.True(t, client.Handles(driver.ImageTypeDocker))
+}
+
+func TestDial_RequiresCredentials(t *testing.T) {
+	_, err := Dial("127.0.0.1:1234")
+	require.Error(t, err, "the connection should not be insecure unless explicitly requested")
+}
+
+func TestServe_NotLaunched(t *testing.T) {
+	err := Serve(&testDriver{})
+	assert.EqualError(t, err, "this binary is a CNAB driver plugin and is not meant to be executed directly")
+}
+
+func TestParseHandshake(t *testing.T) {
+	testcases := []struct {
+		line        string
+		wantNetwork string
+		wantAddress string
+		wantErr     string
+	}{
+		{line: "1|tcp|127.0.0.1:1234\n", wantNetwork: "tcp", wantAddress: "127.0.0.1:1234"},
+		{line: "1|unix|/tmp/plugin.sock", wantNetwork: "unix", wantAddress: "/tmp/plugin.sock"},
+		{line: "2|tcp|127.0.0.1:1234", wantErr: `unsupported protocol version "2"`},
+		{line: "1|udp|127.0.0.1:1234", wantErr: `unsupported network "udp"`},
+		{line: "hello world", wantErr: "expected PROTOCOL_VERSION|NETWORK|ADDRESS"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(strings.TrimSpace(tc.line), func(t *testing.T) {
+			network, address, err := parseHandshake(tc.line)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantNetwork, network)
+			assert.Equal(t, tc.wantAddress, address)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+
+	gogrpc "google.golang.org/grpc"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// ProtocolVersion is the version of the driver plugin protocol.
+	ProtocolVersion = 1
+
+	// ServiceName is the name of the gRPC service implemented by driver plugins.
+	ServiceName = "cnab.driver.v1.Driver"
+)
+
+// HandlesRequest asks if the plugin supports an image type.
+type HandlesRequest struct {
+	ImageType string `json:"imageType"`
+}
+
+// HandlesResponse indicates if the plugin supports the requested image type.
+type HandlesResponse struct {
+	Handles bool `json:"handles"`
+}
+
+// ConfigRequest asks for the configuration supported by the plugin.
+type ConfigRequest struct{}
+
+// ConfigResponse maps the names of the plugin's settings to their description.
+type ConfigResponse struct {
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// SetConfigRequest configures the plugin.
+type SetConfigRequest struct {
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// SetConfigResponse is returned after the plugin is configured.
+type SetConfigResponse struct{}
+
+// RunRequest asks the plugin to execute an operation.
+type RunRequest struct {
+	Operation driver.Operation `json:"operation"`
+}
+
+// RunResponse is streamed by the plugin while an operation executes. Each
+// message contains either output from the invocation image or, in the last
+// message, the result of the operation.
+type RunResponse struct {
+	// Stdout is output written by the operation to its output stream.
+	Stdout []byte `json:"stdout,omitempty"`
+
+	// Stderr is output written by the operation to its error stream.
+	Stderr []byte `json:"stderr,omitempty"`
+
+	// Result of the operation, set on the final message.
+	Result *RunResult `json:"result,omitempty"`
+}
+
+// RunResult is the outcome of executing an operation.
+type RunResult struct {
+	// Outputs maps from the name of the output to its content.
+	Outputs map[string]string `json:"outputs,omitempty"`
+
+	// OperationError is any error from executing the operation, see
+	// driver.OperationResult.Error.
+	OperationError string `json:"operationError,omitempty"`
+
+	// RunError is the error returned by the driver when the operation could
+	// not be run.
+	RunError string `json:"runError,omitempty"`
+
+	// ImageError indicates that RunError was caused by the invocation image,
+	// see driver.ImageError.
+	ImageError bool `json:"imageError,omitempty"`
+}
+
+// jsonCodec encodes the protocol messages as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// driverService is the server side of the protocol.
+type driverService interface {
+	Handles(context.Context, *HandlesRequest) (*HandlesResponse, error)
+	Config(context.Context, *ConfigRequest) (*ConfigResponse, error)
+	SetConfig(context.Context, *SetConfigRequest) (*SetConfigResponse, error)
+	Run(*RunRequest, gogrpc.ServerStream) error
+}
+
+var serviceDesc = gogrpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*driverService)(nil),
+	Methods: []gogrpc.MethodDesc{
+		{
+			MethodName: "Handles",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &HandlesRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return unaryCall(ctx, interceptor, "Handles", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(driverService).Handles(ctx, req.(*HandlesRequest))
+				})
+			},
+		},
+		{
+			MethodName: "Config",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &ConfigRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return unaryCall(ctx, interceptor, "Config", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(driverService).Config(ctx, req.(*ConfigRequest))
+				})
+			},
+		},
+		{
+			MethodName: "SetConfig",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &SetConfigRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return unaryCall(ctx, interceptor, "SetConfig", req, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(driverService).SetConfig(ctx, req.(*SetConfigRequest))
+				})
+			},
+		},
+	},
+	Streams: []gogrpc.StreamDesc{
+		{
+			StreamName:    "Run",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream gogrpc.ServerStream) error {
+				req := &RunRequest{}
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(driverService).Run(req, stream)
+			},
+		},
+	},
+}
+
+// unaryCall invokes the handler for a unary method, using the server's
+// interceptor when one is configured.
+func unaryCall(ctx context.Context, interceptor gogrpc.UnaryServerInterceptor, method string, req interface{}, handler gogrpc.UnaryHandler) (interface{}, error) {
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+
+	info := &gogrpc.UnaryServerInfo{
+		FullMethod: methodName(method),
+	}
+	return interceptor(ctx, req, info, handler)
+}
+
+// methodName returns the full name of a method on the driver service.
+func methodName(method string) string {
+	return "/" + ServiceName + "/" + method
+}
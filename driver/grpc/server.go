@@ -0,0 +1,170 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// PluginMagicCookieKey is the environment variable set by Launch to
+	// identify that a plugin is being executed by a CNAB tool.
+	PluginMagicCookieKey = "CNAB_DRIVER_PLUGIN"
+
+	// PluginMagicCookie is the value of PluginMagicCookieKey. It is not a
+	// security measure, it only prevents plugins from being run directly.
+	PluginMagicCookie = "d1f9a8d3-6d0c-4a43-9b8e-8f4c7a1e2b65"
+
+	// PluginSocketKey is the environment variable set by Launch to the path
+	// of the unix socket on which the plugin must serve the protocol.
+	PluginSocketKey = "CNAB_DRIVER_PLUGIN_SOCKET"
+)
+
+var _ driverService = &Server{}
+
+// Server exposes a driver over the driver plugin protocol.
+type Server struct {
+	driver driver.Driver
+}
+
+// NewServer creates a Server for the driver.
+func NewServer(d driver.Driver) *Server {
+	return &Server{driver: d}
+}
+
+// NewGRPCServer creates a gRPC server that uses the protocol's message
+// encoding, ready to have a Server registered on it.
+func NewGRPCServer(opts ...gogrpc.ServerOption) *gogrpc.Server {
+	opts = append([]gogrpc.ServerOption{gogrpc.ForceServerCodec(jsonCodec{})}, opts...)
+	return gogrpc.NewServer(opts...)
+}
+
+// Register the driver service on a gRPC server created with NewGRPCServer.
+func (s *Server) Register(srv *gogrpc.Server) {
+	srv.RegisterService(&serviceDesc, s)
+}
+
+// Serve runs the driver as a plugin, and should be called from the plugin's
+// main function. It serves the protocol on the unix socket requested by
+// Launch, only accessible by the current user, prints the handshake for
+// Launch and blocks until the plugin is stopped. When no socket is
+// requested, the protocol is served on a local tcp port, which Launch only
+// accepts with LaunchOptions.AllowInsecureTCP.
+func Serve(d driver.Driver) error {
+	if os.Getenv(PluginMagicCookieKey) != PluginMagicCookie {
+		return errors.New("this binary is a CNAB driver plugin and is not meant to be executed directly")
+	}
+
+	var listener net.Listener
+	var opts []gogrpc.ServerOption
+	if socket := os.Getenv(PluginSocketKey); socket != "" {
+		l, err := listenUnix(socket)
+		if err != nil {
+			return err
+		}
+		listener = l
+		opts = append(opts, gogrpc.Creds(local.NewCredentials()))
+	} else {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return fmt.Errorf("could not listen for driver plugin connections: %w", err)
+		}
+		listener = l
+	}
+
+	srv := NewGRPCServer(opts...)
+	NewServer(d).Register(srv)
+
+	fmt.Printf("%d|%s|%s\n", ProtocolVersion, listener.Addr().Network(), listener.Addr().String())
+	return srv.Serve(listener)
+}
+
+// listenUnix listens on the unix socket, and restricts its access to the
+// current user.
+func listenUnix(socket string) (net.Listener, error) {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for driver plugin connections: %w", err)
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("could not restrict the access to the driver plugin socket: %w", err)
+	}
+	return listener, nil
+}
+
+func (s *Server) Handles(_ context.Context, req *HandlesRequest) (*HandlesResponse, error) {
+	return &HandlesResponse{Handles: s.driver.Handles(req.ImageType)}, nil
+}
+
+func (s *Server) Config(_ context.Context, _ *ConfigRequest) (*ConfigResponse, error) {
+	configurable, ok := s.driver.(driver.Configurable)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the driver is not configurable")
+	}
+
+	return &ConfigResponse{Config: configurable.Config()}, nil
+}
+
+func (s *Server) SetConfig(_ context.Context, req *SetConfigRequest) (*SetConfigResponse, error) {
+	configurable, ok := s.driver.(driver.Configurable)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the driver is not configurable")
+	}
+
+	if err := configurable.SetConfig(req.Settings); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &SetConfigResponse{}, nil
+}
+
+func (s *Server) Run(req *RunRequest, stream gogrpc.ServerStream) error {
+	// The operation's streams may be written to concurrently, but messages
+	// must be sent on the stream one at a time.
+	var sendMu sync.Mutex
+	send := func(resp *RunResponse) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.SendMsg(resp)
+	}
+
+	op := req.Operation
+	op.Out = streamWriter(func(p []byte) error { return send(&RunResponse{Stdout: p}) })
+	op.Err = streamWriter(func(p []byte) error { return send(&RunResponse{Stderr: p}) })
+
+	opResult, err := s.driver.Run(&op)
+
+	result := &RunResult{Outputs: opResult.Outputs}
+	if opResult.Error != nil {
+		result.OperationError = opResult.Error.Error()
+	}
+	if err != nil {
+		result.RunError = err.Error()
+		result.ImageError = driver.IsImageError(err)
+	}
+
+	return send(&RunResponse{Result: result})
+}
+
+// streamWriter sends each write to the client.
+type streamWriter func(p []byte) error
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	// Copy the buffer since the caller may reuse it after Write returns
+	data := make([]byte, len(p))
+	copy(data, p)
+	if err := w(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,265 @@
+// Package host provides a driver that runs the invocation image as a process
+// on the host, without a container runtime.
+package host
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	unix_path "path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// SettingInheritEnvironment is the environment variable for the driver
+	// that specifies whether the process inherits the environment of the
+	// driver (true|false).
+	SettingInheritEnvironment = "HOST_INHERIT_ENV"
+
+	// SettingDir is the environment variable for the driver that specifies
+	// the directory that relative paths to the executable are resolved from.
+	SettingDir = "HOST_DIR"
+
+	// EnvRoot is the environment variable set for the process with the
+	// temporary root directory where the files of the operation are written,
+	// for example $CNAB_ROOT/cnab/app/params/port.
+	EnvRoot = "CNAB_ROOT"
+
+	// EnvOutputDir is the environment variable set for the process with the
+	// directory where it writes the outputs of the operation, which is
+	// $CNAB_ROOT/cnab/app/outputs.
+	EnvOutputDir = "CNAB_OUTPUT_DIR"
+
+	// outputWaitDelay is how long to wait for the output of the process to
+	// be closed after it exits or is killed, for example by a child process
+	// that is still running.
+	outputWaitDelay = 10 * time.Second
+
+	// outputsDir is the directory where outputs are written, relative to
+	// the root of the operation.
+	outputsDir = "/cnab/app/outputs"
+)
+
+// Driver runs the executable referenced by the invocation image, for example
+// a script, as a process on the host, for bundles with the "exec" or
+// "binary" image type. It is only intended for trusted bundles, because the
+// process is not isolated from the host.
+//
+// The files of the operation are written to a temporary root directory,
+// available to the process in the CNAB_ROOT environment variable, instead of
+// their absolute path, so the process reads /cnab/app/params/port from
+// $CNAB_ROOT/cnab/app/params/port. Outputs are written to CNAB_OUTPUT_DIR.
+// The process runs in the root directory, which is removed afterwards.
+//
+// When the invocation image has a digest, the executable must have the same
+// sha256 digest.
+type Driver struct {
+	// Dir is the directory that relative paths to the executable are
+	// resolved from. Defaults to the current directory.
+	Dir string
+
+	// InheritEnvironment passes the environment of the driver to the process,
+	// in addition to the environment of the operation. Otherwise only PATH is
+	// passed.
+	InheritEnvironment bool
+}
+
+// Run executes the operation as a process on the host.
+func (d *Driver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	return d.exec(op)
+}
+
+// Handles indicates that the host driver supports "exec" and "binary"
+func (d *Driver) Handles(dt string) bool {
+	return dt == driver.ImageTypeExec || dt == driver.ImageTypeBinary
+}
+
+// DescribeEnvironment describes the driver by its name.
+func (d *Driver) DescribeEnvironment() driver.ExecutionEnvironment {
+	return driver.ExecutionEnvironment{Driver: "host"}
+}
+
+// Config returns the host driver configuration options
+func (d *Driver) Config() map[string]string {
+	return map[string]string{
+		SettingInheritEnvironment: "Pass the environment of the driver to the process, otherwise only PATH is passed (true|false). Defaults to false",
+		SettingDir:                "Directory that relative paths to the executable are resolved from. Defaults to the current directory",
+	}
+}
+
+// SetConfig sets host driver configuration
+func (d *Driver) SetConfig(settings map[string]string) error {
+	if value, ok := settings[SettingInheritEnvironment]; ok && value != "" {
+		inherit, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("environment variable %s has unexpected value %q: %w", SettingInheritEnvironment, value, err)
+		}
+		d.InheritEnvironment = inherit
+	}
+
+	if dir, ok := settings[SettingDir]; ok {
+		d.Dir = dir
+	}
+	return nil
+}
+
+func (d *Driver) exec(op *driver.Operation) (driver.OperationResult, error) {
+	executable, err := d.executable(op)
+	if err != nil {
+		return driver.OperationResult{}, driver.NewImageError(op.Image.Image, err)
+	}
+
+	root, err := os.MkdirTemp("", "cnab-host-")
+	if err != nil {
+		return driver.OperationResult{}, fmt.Errorf("error creating the root directory of the operation: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := writeFiles(root, op.Files); err != nil {
+		return driver.OperationResult{}, err
+	}
+	hostOutputsDir := filepath.Join(root, filepath.FromSlash(outputsDir))
+	if err := os.MkdirAll(hostOutputsDir, 0700); err != nil {
+		return driver.OperationResult{}, fmt.Errorf("error creating the outputs directory: %w", err)
+	}
+
+	ctx, cancel := op.WithDeadline(context.Background())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, executable)
+	cmd.Dir = root
+	cmd.Env = d.environment(op, root, hostOutputsDir)
+	cmd.Stdout = writerOrDiscard(op.Out)
+	cmd.Stderr = writerOrDiscard(op.Err)
+	cmd.WaitDelay = outputWaitDelay
+	runErr := cmd.Run()
+
+	outputs, err := readOutputs(hostOutputsDir, op.Outputs)
+	opResult := driver.OperationResult{Outputs: outputs}
+
+	if runErr != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return opResult, op.DeadlineError()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
+			runErr = fmt.Errorf("process exit code: %d", exitErr.ExitCode())
+		}
+		if err != nil {
+			return opResult, fmt.Errorf("%w, fetching outputs failed: %s", runErr, err)
+		}
+		return opResult, runErr
+	}
+	return opResult, err
+}
+
+// executable returns the path to the executable of the invocation image, and
+// verifies its digest when the image has one.
+func (d *Driver) executable(op *driver.Operation) (string, error) {
+	path := op.Image.Image
+	if path == "" {
+		return "", errors.New("the invocation image does not reference an executable")
+	}
+	if !filepath.IsAbs(path) && d.Dir != "" {
+		path = filepath.Join(d.Dir, path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("error resolving the path to executable %s: %w", op.Image.Image, err)
+	}
+
+	if op.Image.Digest != "" {
+		digest, err := fileDigest(path)
+		if err != nil {
+			return "", err
+		}
+		if digest != op.Image.Digest {
+			return "", fmt.Errorf("executable %s has digest %s but the invocation image requires %s", path, digest, op.Image.Digest)
+		}
+	}
+	return path, nil
+}
+
+// environment returns the environment of the process.
+func (d *Driver) environment(op *driver.Operation, root string, hostOutputsDir string) []string {
+	var env []string
+	if d.InheritEnvironment {
+		env = os.Environ()
+	} else if path, ok := os.LookupEnv("PATH"); ok {
+		env = append(env, "PATH="+path)
+	}
+	for k, v := range op.Environment {
+		env = append(env, k+"="+v)
+	}
+	return append(env, EnvRoot+"="+root, EnvOutputDir+"="+hostOutputsDir)
+}
+
+// writeFiles writes the files of the operation below the root directory.
+func writeFiles(root string, files map[string]string) error {
+	for path, content := range files {
+		if !unix_path.IsAbs(path) {
+			return fmt.Errorf("destination path %s should be an absolute unix path", path)
+		}
+		hostPath := filepath.Join(root, filepath.FromSlash(unix_path.Clean(path)))
+		if err := os.MkdirAll(filepath.Dir(hostPath), 0700); err != nil {
+			return fmt.Errorf("error creating the directory of file %s: %w", path, err)
+		}
+		if err := os.WriteFile(hostPath, []byte(content), 0600); err != nil {
+			return fmt.Errorf("error writing file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// readOutputs reads the outputs of the operation written by the process.
+// Outputs that were not written are skipped.
+func readOutputs(hostOutputsDir string, outputs map[string]string) (map[string]string, error) {
+	values := map[string]string{}
+	for path, name := range outputs {
+		rel := strings.TrimPrefix(path, outputsDir+"/")
+		if rel == path {
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(hostOutputsDir, filepath.FromSlash(rel)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return values, fmt.Errorf("error reading output %s: %w", name, err)
+		}
+		values[name] = string(contents)
+	}
+	return values, nil
+}
+
+// fileDigest returns the sha256 digest of the file.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening executable %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error computing the digest of executable %s: %w", path, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writerOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
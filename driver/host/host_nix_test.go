@@ -0,0 +1,111 @@
+//go:build !windows
+// +build !windows
+
+package host
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func writeScript(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "run")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700))
+	return path
+}
+
+func testOperation(executable string) *driver.Operation {
+	return &driver.Operation{
+		Installation: "mysql",
+		Action:       "install",
+		Image: bundle.InvocationImage{
+			BaseImage: bundle.BaseImage{Image: executable, ImageType: driver.ImageTypeExec},
+		},
+		Environment: map[string]string{"CNAB_ACTION": "install"},
+		Files:       map[string]string{"/cnab/app/params/port": "3306"},
+		Outputs:     map[string]string{"/cnab/app/outputs/url": "url", "/cnab/app/outputs/missing": "missing"},
+		Out:         &bytes.Buffer{},
+		Err:         &bytes.Buffer{},
+	}
+}
+
+func TestDriver_Run(t *testing.T) {
+	script := writeScript(t, `
+echo "$CNAB_ACTION on port $(cat $CNAB_ROOT/cnab/app/params/port)"
+echo "HOME=$HOME" >&2
+echo "mysql://localhost:3306" > $CNAB_OUTPUT_DIR/url
+`)
+	t.Setenv("HOME", "/home/cnab")
+
+	d := &Driver{}
+	op := testOperation(script)
+	opResult, err := d.Run(op)
+	require.NoError(t, err)
+	assert.Equal(t, "install on port 3306\n", op.Out.(*bytes.Buffer).String())
+	assert.Equal(t, "HOME=\n", op.Err.(*bytes.Buffer).String(), "the environment of the driver should not be inherited")
+	assert.Equal(t, map[string]string{"url": "mysql://localhost:3306\n"}, opResult.Outputs)
+
+	t.Run("inherit environment", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{SettingInheritEnvironment: "true"}))
+		op := testOperation(script)
+		_, err := d.Run(op)
+		require.NoError(t, err)
+		assert.Equal(t, "HOME=/home/cnab\n", op.Err.(*bytes.Buffer).String())
+	})
+
+	t.Run("relative path", func(t *testing.T) {
+		d := &Driver{}
+		require.NoError(t, d.SetConfig(map[string]string{SettingDir: filepath.Dir(script)}))
+		_, err := d.Run(testOperation("run"))
+		require.NoError(t, err)
+	})
+}
+
+func TestDriver_Run_Failed(t *testing.T) {
+	script := writeScript(t, `
+echo "partial" > $CNAB_OUTPUT_DIR/url
+exit 3
+`)
+
+	opResult, err := (&Driver{}).Run(testOperation(script))
+	require.EqualError(t, err, "process exit code: 3")
+	assert.Equal(t, map[string]string{"url": "partial\n"}, opResult.Outputs, "the outputs should be collected")
+}
+
+func TestDriver_Run_Deadline(t *testing.T) {
+	script := writeScript(t, "exec sleep 10\n")
+	op := testOperation(script)
+	op.Deadline = time.Now().Add(100 * time.Millisecond)
+
+	_, err := (&Driver{}).Run(op)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, driver.ErrDeadlineExceeded)
+}
+
+func TestDriver_Run_Digest(t *testing.T) {
+	script := writeScript(t, "exit 0\n")
+	digest, err := fileDigest(script)
+	require.NoError(t, err)
+
+	op := testOperation(script)
+	op.Image.Digest = digest
+	_, err = (&Driver{}).Run(op)
+	require.NoError(t, err)
+
+	op.Image.Digest = "sha256:d2f1cbd2a2ab2a8a0f0d2b8b1b3c6c4e4c2f8e3f6d1a7b7e6c5d4e3f2a1b0c9d"
+	_, err = (&Driver{}).Run(op)
+	require.Error(t, err)
+	var imageErr *driver.ImageError
+	require.ErrorAs(t, err, &imageErr, "a modified executable should not be run")
+	assert.Contains(t, err.Error(), "but the invocation image requires sha256:d2f1")
+}
@@ -0,0 +1,41 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+var _ driver.Driver = &Driver{}
+var _ driver.Configurable = &Driver{}
+
+func TestDriver_Handles(t *testing.T) {
+	d := &Driver{}
+	assert.True(t, d.Handles(driver.ImageTypeExec))
+	assert.True(t, d.Handles(driver.ImageTypeBinary))
+	assert.False(t, d.Handles(driver.ImageTypeDocker))
+}
+
+func TestDriver_SetConfig(t *testing.T) {
+	d := &Driver{}
+	require.NoError(t, d.SetConfig(map[string]string{
+		SettingInheritEnvironment: "true",
+		SettingDir:                "/opt/bundles",
+	}))
+	assert.True(t, d.InheritEnvironment)
+	assert.Equal(t, "/opt/bundles", d.Dir)
+
+	err := d.SetConfig(map[string]string{SettingInheritEnvironment: "yes"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `environment variable HOST_INHERIT_ENV has unexpected value "yes"`)
+}
+
+func TestDriver_Run_MissingExecutable(t *testing.T) {
+	_, err := (&Driver{}).Run(&driver.Operation{})
+	require.Error(t, err)
+	var imageErr *driver.ImageError
+	assert.ErrorAs(t, err, &imageErr)
+}
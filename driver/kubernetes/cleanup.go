@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+var _ driver.Cleaner = &Driver{}
+
+// secretGracePeriod is how long a secret without any job for its revision is
+// assumed to belong to an operation that is starting. Run creates the secrets
+// of an operation before its job, so they must not be reported as orphaned
+// in the meantime.
+const secretGracePeriod = 10 * time.Minute
+
+// Cleanup finds the jobs created by the kubernetes driver that have finished,
+// and the environment secrets that no longer belong to a running job, except
+// for recent secrets whose job may not have been created yet. These are left
+// behind when SkipCleanup is set or the process running the driver crashed.
+// The resources are removed when opts.Delete is set.
+func (k *Driver) Cleanup(ctx context.Context, opts driver.CleanupOptions) ([]driver.OrphanedResource, error) {
+	if err := k.initClient(); err != nil {
+		return nil, err
+	}
+
+	selector := metav1.ListOptions{
+		LabelSelector: newSingleFieldSelector(driver.LabelDriver, "kubernetes"),
+	}
+
+	jobs, err := k.jobs.List(ctx, selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list jobs")
+	}
+
+	secrets, err := k.secrets.List(ctx, selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list secrets")
+	}
+
+	var orphans []driver.OrphanedResource
+	var deleteErrs *multierror.Error
+
+	// Secrets are still in use while a job for the same revision is running
+	activeRevisions := map[string]bool{}
+	jobRevisions := map[string]bool{}
+	for _, job := range jobs.Items {
+		jobRevisions[job.Annotations[driver.LabelRevision]] = true
+		if !isJobFinished(job) {
+			activeRevisions[job.Annotations[driver.LabelRevision]] = true
+			continue
+		}
+
+		orphan := newOrphanedResource("job", job.ObjectMeta)
+		if !opts.Applies(orphan) {
+			continue
+		}
+
+		if opts.Delete {
+			if err := k.deleteJob(ctx, job.Name); err != nil {
+				deleteErrs = multierror.Append(deleteErrs, errors.Wrapf(err, "could not delete job %s", job.Name))
+			} else {
+				orphan.Deleted = true
+			}
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	for _, secret := range secrets.Items {
+		revision := secret.Annotations[driver.LabelRevision]
+		if activeRevisions[revision] {
+			continue
+		}
+		if !jobRevisions[revision] && time.Since(secret.CreationTimestamp.Time) < secretGracePeriod {
+			// The job for the secret may not have been created yet
+			continue
+		}
+
+		orphan := newOrphanedResource("secret", secret.ObjectMeta)
+		if !opts.Applies(orphan) {
+			continue
+		}
+
+		if opts.Delete {
+			if err := k.deleteSecret(ctx, secret.Name); err != nil {
+				deleteErrs = multierror.Append(deleteErrs, errors.Wrapf(err, "could not delete secret %s", secret.Name))
+			} else {
+				orphan.Deleted = true
+			}
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	return orphans, deleteErrs.ErrorOrNil()
+}
+
+// isJobFinished returns true when the job has completed or failed.
+func isJobFinished(job batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if (cond.Type == batchv1.JobComplete || cond.Type == batchv1.JobFailed) && cond.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// newOrphanedResource describes a resource using the annotations applied by the driver.
+func newOrphanedResource(kind string, meta metav1.ObjectMeta) driver.OrphanedResource {
+	orphan := driver.ParseOperationLabels(meta.Annotations).OrphanedResource(kind, meta.Name)
+	orphan.Created = meta.CreationTimestamp.Time
+	return orphan
+}
@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_Cleanup(t *testing.T) {
+	ctx := context.Background()
+	namespace := "default"
+	old := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+
+	meta := func(name string, installation string, revision string, created metav1.Time) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			CreationTimestamp: created,
+			Labels:            map[string]string{driver.LabelDriver: "kubernetes"},
+			Annotations: map[string]string{
+				driver.LabelInstallation: installation,
+				driver.LabelAction:       "install",
+				driver.LabelRevision:     revision,
+			},
+		}
+	}
+	finished := batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+		{Type: batchv1.JobComplete, Status: v1.ConditionTrue},
+	}}
+
+	newDriver := func() Driver {
+		objects := []runtime.Object{
+			&batchv1.Job{ObjectMeta: meta("install-mysql-abc", "mysql", "rev1", old), Status: finished},
+			&v1.Secret{ObjectMeta: meta("install-mysql-env-abc", "mysql", "rev1", old)},
+			&batchv1.Job{ObjectMeta: meta("install-wordpress-def", "wordpress", "rev2", old)},
+			&v1.Secret{ObjectMeta: meta("install-wordpress-env-def", "wordpress", "rev2", old)},
+			&batchv1.Job{ObjectMeta: meta("install-redis-ghi", "redis", "rev3", metav1.Now()), Status: finished},
+			&v1.Secret{ObjectMeta: meta("install-redis-env-ghi", "redis", "rev3", metav1.Now())},
+			&v1.Secret{ObjectMeta: meta("install-nginx-env-jkl", "nginx", "rev4", metav1.Now())},
+			&v1.Secret{ObjectMeta: meta("install-nginx-env-mno", "nginx", "rev5", old)},
+			&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: namespace}, Status: finished},
+		}
+		client := fake.NewSimpleClientset(objects...)
+		return Driver{
+			Namespace:      namespace,
+			jobs:           client.BatchV1().Jobs(namespace),
+			secrets:        client.CoreV1().Secrets(namespace),
+			pods:           client.CoreV1().Pods(namespace),
+			deletionPolicy: metav1.DeletePropagationBackground,
+		}
+	}
+
+	names := func(orphans []driver.OrphanedResource) []string {
+		var result []string
+		for _, o := range orphans {
+			result = append(result, o.Kind+"/"+o.Name)
+		}
+		return result
+	}
+
+	t.Run("list", func(t *testing.T) {
+		k := newDriver()
+		orphans, err := k.Cleanup(ctx, driver.CleanupOptions{})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"job/install-mysql-abc", "job/install-redis-ghi", "secret/install-mysql-env-abc", "secret/install-redis-env-ghi", "secret/install-nginx-env-mno"}, names(orphans),
+			"a recent secret without a job should not be reported, since its job may not have been created yet")
+
+		jobs, err := k.jobs.List(ctx, metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Len(t, jobs.Items, 4, "nothing should be deleted unless Delete is set")
+	})
+
+	t.Run("delete older than", func(t *testing.T) {
+		k := newDriver()
+		orphans, err := k.Cleanup(ctx, driver.CleanupOptions{Delete: true, OlderThan: time.Hour})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"job/install-mysql-abc", "secret/install-mysql-env-abc", "secret/install-nginx-env-mno"}, names(orphans))
+		for _, o := range orphans {
+			assert.True(t, o.Deleted, "%s/%s should be deleted", o.Kind, o.Name)
+		}
+
+		jobs, err := k.jobs.List(ctx, metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Len(t, jobs.Items, 3)
+
+		secrets, err := k.secrets.List(ctx, metav1.ListOptions{})
+		require.NoError(t, err)
+		var remaining []string
+		for _, secret := range secrets.Items {
+			remaining = append(remaining, secret.Name)
+		}
+		assert.ElementsMatch(t, []string{"install-wordpress-env-def", "install-redis-env-ghi", "install-nginx-env-jkl"}, remaining,
+			"secrets for running jobs, and recent secrets without a job, should not be deleted")
+	})
+
+	t.Run("delete everything", func(t *testing.T) {
+		k := newDriver()
+		orphans, err := k.Cleanup(ctx, driver.CleanupOptions{Delete: true})
+		require.NoError(t, err)
+		assert.NotContains(t, names(orphans), "secret/install-nginx-env-jkl", "a secret for an operation that is starting should not be deleted")
+	})
+
+	t.Run("installation", func(t *testing.T) {
+		k := newDriver()
+		orphans, err := k.Cleanup(ctx, driver.CleanupOptions{Installation: "redis"})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"job/install-redis-ghi", "secret/install-redis-env-ghi"}, names(orphans))
+	})
+}
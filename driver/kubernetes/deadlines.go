@@ -0,0 +1,205 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cnabio/cnab-go/driver"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// jobReasonDeadlineExceeded is the reason set on a failed job's condition
+// when it exceeded its ActiveDeadlineSeconds.
+const jobReasonDeadlineExceeded = "DeadlineExceeded"
+
+// podStartPollInterval is how often the bundle's pod is checked while waiting
+// for it to start.
+var podStartPollInterval = time.Second
+
+// imagePullErrorPolls is how many consecutive checks a pod must be waiting
+// because of an image pull error that Kubernetes may recover from, such as
+// ErrImagePull, before waitForPodStart fails.
+var imagePullErrorPolls = 3
+
+// terminalImagePullReasons are the image pull errors on which waitForPodStart
+// fails immediately, because Kubernetes is already backing off from pulling
+// the image or the image cannot be pulled at all.
+var terminalImagePullReasons = []string{"ImagePullBackOff", "InvalidImageName"}
+
+// ProgressDeadlineExceededError is returned when the bundle's pod did not start
+// running within the ProgressDeadlineSeconds, for example because it could
+// not be scheduled or its image could not be pulled.
+type ProgressDeadlineExceededError struct {
+	// Job is the name of the bundle's job.
+	Job string
+
+	// Deadline is the time allowed for the pod to start.
+	Deadline time.Duration
+
+	// Reason the pod was pending when the deadline was exceeded, if known.
+	Reason string
+}
+
+func (e ProgressDeadlineExceededError) Error() string {
+	msg := fmt.Sprintf("the pod for job %s did not start within the progress deadline of %s", e.Job, e.Deadline)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Unwrap classifies the failure as ErrImagePull when the pod was pending
+// because its image could not be pulled, and driver.ErrDeadlineExceeded
+// otherwise.
+func (e ProgressDeadlineExceededError) Unwrap() error {
+	if isImagePullReason(e.Reason) {
+		return ErrImagePull
+	}
+	return driver.ErrDeadlineExceeded
+}
+
+// ActiveDeadlineExceededError is returned when the bundle's job was stopped by
+// Kubernetes because it ran longer than the ActiveDeadlineSeconds.
+type ActiveDeadlineExceededError struct {
+	// Job is the name of the bundle's job.
+	Job string
+
+	// Deadline is the time allowed for the job to run.
+	Deadline time.Duration
+
+	// Message reported by Kubernetes on the failed job.
+	Message string
+}
+
+func (e ActiveDeadlineExceededError) Error() string {
+	return fmt.Sprintf("job %s was stopped after exceeding the active deadline of %s: %s", e.Job, e.Deadline, e.Message)
+}
+
+// Unwrap classifies the failure as a driver.ErrDeadlineExceeded, so that it is
+// reported as a timeout of the operation.
+func (e ActiveDeadlineExceededError) Unwrap() error {
+	return driver.ErrDeadlineExceeded
+}
+
+// activeDeadlineSeconds returns the ActiveDeadlineSeconds of the job for the
+// operation, which is the earlier of the ActiveDeadlineSeconds configured on
+// the driver and the time remaining until the deadline of the operation.
+// Zero means that the job has no deadline.
+func (k *Driver) activeDeadlineSeconds(op *driver.Operation) int64 {
+	seconds := k.ActiveDeadlineSeconds
+	if !op.HasDeadline() {
+		return seconds
+	}
+
+	// Round up, and always leave the job at least a second, since zero would
+	// disable the deadline altogether.
+	remaining := int64(math.Ceil(time.Until(op.Deadline).Seconds()))
+	if remaining < 1 {
+		remaining = 1
+	}
+	if seconds <= 0 || remaining < seconds {
+		seconds = remaining
+	}
+	return seconds
+}
+
+// jobActiveDeadline returns the active deadline set on the job.
+func jobActiveDeadline(job *batchv1.Job) time.Duration {
+	if job.Spec.ActiveDeadlineSeconds == nil {
+		return 0
+	}
+	return time.Duration(*job.Spec.ActiveDeadlineSeconds) * time.Second
+}
+
+// waitForPodStart blocks until a pod for the job has started. It returns an
+// ImagePullError as soon as a pod is waiting with ImagePullBackOff or
+// InvalidImageName, or when it keeps waiting with another image pull error
+// for imagePullErrorPolls checks, and a ProgressDeadlineExceededError when
+// ProgressDeadlineSeconds is set and elapses first.
+func (k *Driver) waitForPodStart(ctx context.Context, jobName string, podSelector metav1.ListOptions) error {
+	deadline := time.Duration(k.ProgressDeadlineSeconds) * time.Second
+	var timeout <-chan time.Time
+	if deadline > 0 {
+		timer := time.NewTimer(deadline)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	ticker := time.NewTicker(podStartPollInterval)
+	defer ticker.Stop()
+
+	reason := ""
+	// Consecutive checks that each pod was waiting on an image pull error
+	pullErrors := map[string]int{}
+	for {
+		pods, err := k.pods.List(ctx, podSelector)
+		if err == nil {
+			for _, pod := range pods.Items {
+				if pod.Status.Phase != v1.PodPending && pod.Status.Phase != "" {
+					return nil
+				}
+				r := pendingReason(pod)
+				if !isImagePullReason(r) {
+					delete(pullErrors, pod.Name)
+				} else {
+					pullErrors[pod.Name]++
+					if isTerminalImagePullReason(r) || pullErrors[pod.Name] >= imagePullErrorPolls {
+						return ImagePullError{Job: jobName, Pod: pod.Name, Reason: r}
+					}
+				}
+				if r != "" {
+					reason = r
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return ProgressDeadlineExceededError{Job: jobName, Deadline: deadline, Reason: reason}
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingReason explains why a pod has not started yet, preferring container
+// errors such as ImagePullBackOff over scheduling problems. The init
+// containers, such as the outputs sidecar, are checked first since they
+// start before the invocation image.
+func pendingReason(pod v1.Pod) string {
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" {
+			return formatReason(waiting.Reason, waiting.Message)
+		}
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse {
+			return formatReason(cond.Reason, cond.Message)
+		}
+	}
+
+	return ""
+}
+
+func formatReason(reason string, message string) string {
+	if message == "" {
+		return reason
+	}
+	return fmt.Sprintf("%s: %s", reason, message)
+}
+
+func isTerminalImagePullReason(reason string) bool {
+	for _, r := range terminalImagePullReasons {
+		if reason == r || strings.HasPrefix(reason, r+":") {
+			return true
+		}
+	}
+	return false
+}
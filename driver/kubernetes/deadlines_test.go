@@ -0,0 +1,208 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDriver_WaitForPodStart(t *testing.T) {
+	defer func(interval time.Duration) { podStartPollInterval = interval }(podStartPollInterval)
+	podStartPollInterval = 10 * time.Millisecond
+
+	ctx := context.Background()
+	podSelector := metav1.ListOptions{LabelSelector: newSingleFieldSelector("job-name", "install-mysql-abc")}
+	newPod := func(status v1.PodStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "install-mysql-abc-123",
+				Labels: map[string]string{"job-name": "install-mysql-abc"},
+			},
+			Status: status,
+		}
+	}
+
+	testcases := []struct {
+		name       string
+		status     v1.PodStatus
+		wantReason string
+	}{
+		{
+			name: "unschedulable",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				Conditions: []v1.PodCondition{
+					{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: "Unschedulable", Message: "exceeded quota"},
+				},
+			},
+			wantReason: "Unschedulable: exceeded quota",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(newPod(tc.status))
+			k := Driver{
+				pods:                    client.CoreV1().Pods(""),
+				ProgressDeadlineSeconds: 1,
+			}
+
+			err := k.waitForPodStart(ctx, "install-mysql-abc", podSelector)
+			require.Error(t, err)
+			deadlineErr, ok := err.(ProgressDeadlineExceededError)
+			require.True(t, ok, "expected a ProgressDeadlineExceededError, got %T", err)
+			assert.Equal(t, "install-mysql-abc", deadlineErr.Job)
+			assert.Equal(t, time.Second, deadlineErr.Deadline)
+			assert.Equal(t, tc.wantReason, deadlineErr.Reason)
+		})
+	}
+
+	pullTestcases := []struct {
+		name     string
+		status   v1.PodStatus
+		wantErr  error
+		wantPoll int
+	}{
+		{
+			name: "image pull back off",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{
+					{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}},
+				},
+			},
+			wantErr:  ImagePullError{Job: "install-mysql-abc", Pod: "install-mysql-abc-123", Reason: "ImagePullBackOff: Back-off pulling image"},
+			wantPoll: 1,
+		},
+		{
+			name: "invalid sidecar image",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				InitContainerStatuses: []v1.ContainerStatus{
+					{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "InvalidImageName"}}},
+				},
+			},
+			wantErr:  ImagePullError{Job: "install-mysql-abc", Pod: "install-mysql-abc-123", Reason: "InvalidImageName"},
+			wantPoll: 1,
+		},
+		{
+			name: "repeated pull errors",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{
+					{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "connection reset by peer"}}},
+				},
+			},
+			wantErr:  ImagePullError{Job: "install-mysql-abc", Pod: "install-mysql-abc-123", Reason: "ErrImagePull: connection reset by peer"},
+			wantPoll: imagePullErrorPolls,
+		},
+	}
+
+	for _, tc := range pullTestcases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(newPod(tc.status))
+			polls := 0
+			client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				polls++
+				return false, nil, nil
+			})
+			// The image pull failure is detected without a progress deadline
+			k := Driver{pods: client.CoreV1().Pods("")}
+
+			err := k.waitForPodStart(ctx, "install-mysql-abc", podSelector)
+			require.Error(t, err)
+			assert.Equal(t, tc.wantErr, err)
+			assert.ErrorIs(t, err, ErrImagePull)
+			assert.Equal(t, tc.wantPoll, polls, "unexpected number of checks before failing")
+		})
+	}
+
+	t.Run("transient pull error", func(t *testing.T) {
+		client := fake.NewSimpleClientset(newPod(v1.PodStatus{
+			Phase: v1.PodPending,
+			ContainerStatuses: []v1.ContainerStatus{
+				{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+			},
+		}))
+		// The pod starts once the image is pulled on the next attempt
+		polls := 0
+		client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			polls++
+			if polls < imagePullErrorPolls {
+				return false, nil, nil
+			}
+			return true, &v1.PodList{Items: []v1.Pod{*newPod(v1.PodStatus{Phase: v1.PodRunning})}}, nil
+		})
+		k := Driver{pods: client.CoreV1().Pods("")}
+
+		err := k.waitForPodStart(ctx, "install-mysql-abc", podSelector)
+		require.NoError(t, err, "a single ErrImagePull should not fail the operation")
+	})
+
+	t.Run("pod started", func(t *testing.T) {
+		client := fake.NewSimpleClientset(newPod(v1.PodStatus{Phase: v1.PodRunning}))
+		k := Driver{
+			pods:                    client.CoreV1().Pods(""),
+			ProgressDeadlineSeconds: 1,
+		}
+
+		err := k.waitForPodStart(ctx, "install-mysql-abc", podSelector)
+		require.NoError(t, err)
+	})
+}
+
+func TestDeadlineErrors(t *testing.T) {
+	progressErr := ProgressDeadlineExceededError{Job: "install-mysql-abc", Deadline: 30 * time.Second, Reason: "ImagePullBackOff"}
+	assert.EqualError(t, progressErr, "the pod for job install-mysql-abc did not start within the progress deadline of 30s: ImagePullBackOff")
+	assert.NotErrorIs(t, progressErr, driver.ErrDeadlineExceeded, "an image pull failure should not be reported as a timeout")
+
+	activeErr := ActiveDeadlineExceededError{Job: "install-mysql-abc", Deadline: 5 * time.Minute, Message: "Job was active longer than specified deadline"}
+	assert.EqualError(t, activeErr, "job install-mysql-abc was stopped after exceeding the active deadline of 5m0s: Job was active longer than specified deadline")
+	assert.ErrorIs(t, activeErr, driver.ErrDeadlineExceeded, "the active deadline should be reported as a timeout of the operation")
+}
+
+func TestDriver_ActiveDeadlineSeconds(t *testing.T) {
+	t.Run("no deadline", func(t *testing.T) {
+		k := Driver{}
+		assert.Equal(t, int64(0), k.activeDeadlineSeconds(&driver.Operation{}))
+	})
+
+	t.Run("driver deadline", func(t *testing.T) {
+		k := Driver{ActiveDeadlineSeconds: 60}
+		assert.Equal(t, int64(60), k.activeDeadlineSeconds(&driver.Operation{}))
+	})
+
+	t.Run("operation deadline", func(t *testing.T) {
+		k := Driver{}
+		op := &driver.Operation{Deadline: time.Now().Add(90 * time.Second)}
+		got := k.activeDeadlineSeconds(op)
+		assert.True(t, got > 85 && got <= 90, "expected the time remaining until the deadline, got %d", got)
+	})
+
+	t.Run("earlier operation deadline", func(t *testing.T) {
+		k := Driver{ActiveDeadlineSeconds: 600}
+		op := &driver.Operation{Deadline: time.Now().Add(90 * time.Second)}
+		assert.LessOrEqual(t, k.activeDeadlineSeconds(op), int64(90))
+	})
+
+	t.Run("earlier driver deadline", func(t *testing.T) {
+		k := Driver{ActiveDeadlineSeconds: 60}
+		op := &driver.Operation{Deadline: time.Now().Add(time.Hour)}
+		assert.Equal(t, int64(60), k.activeDeadlineSeconds(op))
+	})
+
+	t.Run("deadline passed", func(t *testing.T) {
+		k := Driver{ActiveDeadlineSeconds: 60}
+		op := &driver.Operation{Deadline: time.Now().Add(-time.Minute)}
+		assert.Equal(t, int64(1), k.activeDeadlineSeconds(op))
+	})
+}
@@ -0,0 +1,26 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+var _ driver.EnvironmentDescriber = &Driver{}
+
+// DescribeEnvironment describes the kubernetes cluster that the driver runs
+// operations on. The cluster is only queried once the driver connected to it
+// to run an operation, and its version is cached.
+func (k *Driver) DescribeEnvironment() driver.ExecutionEnvironment {
+	env := driver.ExecutionEnvironment{Driver: "kubernetes"}
+	if k.clusterVersion == nil && k.discovery != nil {
+		if v, err := k.discovery.ServerVersion(); err == nil {
+			k.clusterVersion = v
+		}
+	}
+	if k.clusterVersion != nil {
+		env.ServerVersion = k.clusterVersion.GitVersion
+		env.ServerOS, env.ServerArch, _ = strings.Cut(k.clusterVersion.Platform, "/")
+	}
+	return env
+}
@@ -0,0 +1,37 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_DescribeEnvironment(t *testing.T) {
+	t.Run("not connected", func(t *testing.T) {
+		k := &Driver{}
+		assert.Equal(t, driver.ExecutionEnvironment{Driver: "kubernetes"}, k.DescribeEnvironment())
+	})
+
+	t.Run("cluster version", func(t *testing.T) {
+		fake := &k8stesting.Fake{}
+		k := &Driver{discovery: &fakediscovery.FakeDiscovery{
+			Fake:               fake,
+			FakedServerVersion: &version.Info{GitVersion: "v1.31.1", Platform: "linux/amd64"},
+		}}
+
+		want := driver.ExecutionEnvironment{
+			Driver:        "kubernetes",
+			ServerVersion: "v1.31.1",
+			ServerOS:      "linux",
+			ServerArch:    "amd64",
+		}
+		assert.Equal(t, want, k.DescribeEnvironment())
+		assert.Equal(t, want, k.DescribeEnvironment())
+		assert.Len(t, fake.Actions(), 1, "the cluster version should be cached")
+	})
+}
@@ -0,0 +1,256 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// Keys of the driver.OperationResult metadata describing how the invocation
+// image container exited in the last pod of the bundle's job.
+const (
+	// MetadataPod is the name of the pod.
+	MetadataPod = "kubernetes.pod"
+
+	// MetadataExitCode is the exit code of the container.
+	MetadataExitCode = "kubernetes.exitCode"
+
+	// MetadataTerminationReason is the reason reported by Kubernetes for the
+	// exit, e.g. Error or OOMKilled.
+	MetadataTerminationReason = "kubernetes.terminationReason"
+
+	// MetadataTerminationMessage is the termination message of the container,
+	// or the end of its logs when it failed without writing one.
+	MetadataTerminationMessage = "kubernetes.terminationMessage"
+)
+
+// Classification of the failures of the bundle's job, wrapped by the errors
+// returned by the driver so that callers can tell why the final attempt
+// failed with errors.Is, regardless of how many times the job was retried.
+// A job that exceeded its deadlines wraps driver.ErrDeadlineExceeded.
+var (
+	// ErrImagePull is wrapped when the invocation image could not be pulled,
+	// for example ImagePullBackOff or InvalidImageName.
+	ErrImagePull = errors.New("the invocation image could not be pulled")
+
+	// ErrOOM is wrapped when the invocation image container was killed
+	// because it ran out of memory.
+	ErrOOM = errors.New("the invocation image ran out of memory")
+
+	// ErrAppFailure is wrapped when the bundle itself failed, exiting with a
+	// non-zero exit code.
+	ErrAppFailure = errors.New("the bundle failed")
+)
+
+// imagePullReasons are the reasons reported by Kubernetes for a container
+// waiting because its image could not be pulled.
+var imagePullReasons = []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull", "RegistryUnavailable"}
+
+// terminationReasonOOMKilled is the reason reported by Kubernetes for a
+// container killed because it ran out of memory.
+const terminationReasonOOMKilled = "OOMKilled"
+
+// ClassifyFailure returns the classification of an error returned by the
+// driver, one of ErrImagePull, ErrOOM, driver.ErrDeadlineExceeded or
+// ErrAppFailure, or nil when the error does not describe a failure of the bundle's job.
+func ClassifyFailure(err error) error {
+	for _, class := range []error{ErrImagePull, ErrOOM, driver.ErrDeadlineExceeded, ErrAppFailure} {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+	return nil
+}
+
+// jobError wraps an error returned while waiting for the bundle's job. A
+// failure to pull the invocation image is returned as a driver.ImageError,
+// so that the caller can fall back to another compatible invocation image.
+func jobError(op *driver.Operation, jobName string, err error) error {
+	err = fmt.Errorf("job %s failed: %w", jobName, err)
+	if errors.Is(err, ErrImagePull) {
+		return driver.NewImageError(op.Image.Image, err)
+	}
+	return err
+}
+
+// isImagePullReason determines if a formatted reason, such as returned by
+// pendingReason, is a failure to pull the image.
+func isImagePullReason(reason string) bool {
+	for _, r := range imagePullReasons {
+		if reason == r || strings.HasPrefix(reason, r+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainerTermination describes how the invocation image container exited
+// in one of the pods of the bundle's job.
+type ContainerTermination struct {
+	// Pod is the name of the pod.
+	Pod string
+
+	// ExitCode of the container.
+	ExitCode int32
+
+	// Reason reported by Kubernetes for the exit, e.g. Error or OOMKilled.
+	Reason string
+
+	// Message reported by Kubernetes for the exit, such as the container's
+	// termination message.
+	Message string
+}
+
+func (t ContainerTermination) String() string {
+	msg := fmt.Sprintf("pod %s exited with code %d", t.Pod, t.ExitCode)
+	if t.Reason != "" {
+		msg += " (" + formatReason(t.Reason, t.Message) + ")"
+	} else if t.Message != "" {
+		msg += ": " + t.Message
+	}
+	return msg
+}
+
+// JobFailedError is returned when the bundle's job failed, for example
+// because the bundle exited with a non-zero exit code on each retry.
+type JobFailedError struct {
+	// Job is the name of the bundle's job.
+	Job string
+
+	// Message reported by Kubernetes on the failed job.
+	Message string
+
+	// Terminations describes how the container exited in each of the job's
+	// pods, in the order that the pods were created.
+	Terminations []ContainerTermination
+}
+
+func (e JobFailedError) Error() string {
+	if len(e.Terminations) == 0 {
+		return e.Message
+	}
+
+	exits := make([]string, len(e.Terminations))
+	for i, t := range e.Terminations {
+		exits[i] = t.String()
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(exits, "; "))
+}
+
+// Unwrap classifies the failure by how the container exited in the last
+// pod: ErrOOM when it was killed because it ran out of memory, and
+// ErrAppFailure otherwise.
+func (e JobFailedError) Unwrap() error {
+	if n := len(e.Terminations); n > 0 && e.Terminations[n-1].Reason == terminationReasonOOMKilled {
+		return ErrOOM
+	}
+	return ErrAppFailure
+}
+
+// ImagePullError is returned when a pod of the bundle's job is waiting
+// because an image could not be pulled, instead of waiting for the job's
+// deadlines: immediately on ImagePullBackOff or InvalidImageName, and after
+// repeated checks on errors that may be transient such as ErrImagePull. It
+// wraps ErrImagePull.
+type ImagePullError struct {
+	// Job is the name of the bundle's job.
+	Job string
+
+	// Pod is the name of the pod that could not pull the image.
+	Pod string
+
+	// Reason reported by Kubernetes for the container waiting.
+	Reason string
+}
+
+func (e ImagePullError) Error() string {
+	return fmt.Sprintf("the invocation image of pod %s for job %s could not be pulled: %s", e.Pod, e.Job, e.Reason)
+}
+
+func (e ImagePullError) Unwrap() error {
+	return ErrImagePull
+}
+
+// JobDeletedError is returned when the bundle's job was deleted before it
+// completed, for example with kubectl delete or because its namespace was
+// removed. It wraps driver.ErrExecutionInterrupted.
+type JobDeletedError struct {
+	// Job is the name of the bundle's job.
+	Job string
+}
+
+func (e JobDeletedError) Error() string {
+	return fmt.Sprintf("job %s was deleted before it completed", e.Job)
+}
+
+func (e JobDeletedError) Unwrap() error {
+	return driver.ErrExecutionInterrupted
+}
+
+// Metadata describes the termination as driver.OperationResult metadata.
+func (t ContainerTermination) Metadata() map[string]string {
+	metadata := map[string]string{
+		MetadataPod:      t.Pod,
+		MetadataExitCode: strconv.Itoa(int(t.ExitCode)),
+	}
+	if t.Reason != "" {
+		metadata[MetadataTerminationReason] = t.Reason
+	}
+	if t.Message != "" {
+		metadata[MetadataTerminationMessage] = t.Message
+	}
+	return metadata
+}
+
+// listContainerTerminations returns how the invocation image container exited
+// in each of the pods matching the selector, ordered by when the pods were
+// created. Pods whose container has not exited are skipped.
+func (k *Driver) listContainerTerminations(ctx context.Context, podSelector metav1.ListOptions) ([]ContainerTermination, error) {
+	pods, err := k.pods.List(ctx, podSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	items := pods.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].CreationTimestamp.Equal(&items[j].CreationTimestamp) {
+			return items[i].CreationTimestamp.Before(&items[j].CreationTimestamp)
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	var terminations []ContainerTermination
+	for _, pod := range items {
+		if t := containerTermination(pod); t != nil {
+			terminations = append(terminations, *t)
+		}
+	}
+	return terminations, nil
+}
+
+// containerTermination returns how the invocation image container of the pod
+// exited, or nil when it has not exited.
+func containerTermination(pod v1.Pod) *ContainerTermination {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != k8sContainerName && len(pod.Status.ContainerStatuses) > 1 {
+			continue
+		}
+		if terminated := status.State.Terminated; terminated != nil {
+			return &ContainerTermination{
+				Pod:      pod.Name,
+				ExitCode: terminated.ExitCode,
+				Reason:   terminated.Reason,
+				Message:  strings.TrimSpace(terminated.Message),
+			}
+		}
+	}
+	return nil
+}
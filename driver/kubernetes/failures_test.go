@@ -0,0 +1,165 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_ListContainerTerminations(t *testing.T) {
+	created := time.Date(2020, 4, 18, 1, 2, 3, 0, time.UTC)
+	newPod := func(name string, age time.Duration, state v1.ContainerState) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				Labels:            map[string]string{"job-name": "install-mysql-abc"},
+				CreationTimestamp: metav1.NewTime(created.Add(-age)),
+			},
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{{Name: k8sContainerName, State: state}},
+			},
+		}
+	}
+
+	client := fake.NewSimpleClientset(
+		newPod("install-mysql-abc-retry", time.Minute, v1.ContainerState{
+			Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+		}),
+		newPod("install-mysql-abc-first", 2*time.Minute, v1.ContainerState{
+			Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Reason: "Error", Message: "database unreachable\n"},
+		}),
+		newPod("install-mysql-abc-running", 0, v1.ContainerState{
+			Running: &v1.ContainerStateRunning{},
+		}),
+	)
+	k := Driver{pods: client.CoreV1().Pods("default")}
+
+	podSelector := metav1.ListOptions{LabelSelector: newSingleFieldSelector("job-name", "install-mysql-abc")}
+	terminations, err := k.listContainerTerminations(context.Background(), podSelector)
+	require.NoError(t, err)
+	assert.Equal(t, []ContainerTermination{
+		{Pod: "install-mysql-abc-first", ExitCode: 1, Reason: "Error", Message: "database unreachable"},
+		{Pod: "install-mysql-abc-retry", ExitCode: 137, Reason: "OOMKilled"},
+	}, terminations)
+
+	err = JobFailedError{Job: "install-mysql-abc", Message: "Job has reached the specified backoff limit", Terminations: terminations}
+	assert.EqualError(t, err, "Job has reached the specified backoff limit: pod install-mysql-abc-first exited with code 1 (Error: database unreachable); pod install-mysql-abc-retry exited with code 137 (OOMKilled)")
+}
+
+func TestJobFailedError_NoTerminations(t *testing.T) {
+	err := JobFailedError{Job: "install-mysql-abc", Message: "Job has reached the specified backoff limit"}
+	assert.EqualError(t, err, "Job has reached the specified backoff limit")
+}
+
+func TestContainerTermination_Metadata(t *testing.T) {
+	term := ContainerTermination{Pod: "install-mysql-abc-first", ExitCode: 1, Reason: "Error", Message: "database unreachable"}
+	assert.Equal(t, map[string]string{
+		MetadataPod:                "install-mysql-abc-first",
+		MetadataExitCode:           "1",
+		MetadataTerminationReason:  "Error",
+		MetadataTerminationMessage: "database unreachable",
+	}, term.Metadata())
+
+	term = ContainerTermination{Pod: "install-mysql-abc-first"}
+	assert.Equal(t, map[string]string{
+		MetadataPod:      "install-mysql-abc-first",
+		MetadataExitCode: "0",
+	}, term.Metadata())
+}
+
+func TestJobDeletedError(t *testing.T) {
+	err := JobDeletedError{Job: "install-mysql-abc"}
+	assert.EqualError(t, err, "job install-mysql-abc was deleted before it completed")
+	assert.ErrorIs(t, err, driver.ErrExecutionInterrupted)
+}
+
+func TestClassifyFailure(t *testing.T) {
+	testcases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "image pull",
+			err:  ProgressDeadlineExceededError{Job: "myjob", Reason: "ImagePullBackOff: Back-off pulling image"},
+			want: ErrImagePull,
+		},
+		{
+			name: "invalid image",
+			err:  ProgressDeadlineExceededError{Job: "myjob", Reason: "InvalidImageName"},
+			want: ErrImagePull,
+		},
+		{
+			name: "unschedulable",
+			err:  ProgressDeadlineExceededError{Job: "myjob", Reason: "Unschedulable: 0/3 nodes are available"},
+			want: driver.ErrDeadlineExceeded,
+		},
+		{
+			name: "active deadline",
+			err:  ActiveDeadlineExceededError{Job: "myjob"},
+			want: driver.ErrDeadlineExceeded,
+		},
+		{
+			name: "out of memory on the last attempt",
+			err: JobFailedError{Job: "myjob", Terminations: []ContainerTermination{
+				{Pod: "myjob-1", ExitCode: 1, Reason: "Error"},
+				{Pod: "myjob-2", ExitCode: 137, Reason: "OOMKilled"},
+			}},
+			want: ErrOOM,
+		},
+		{
+			name: "bundle failed on the last attempt",
+			err: JobFailedError{Job: "myjob", Terminations: []ContainerTermination{
+				{Pod: "myjob-1", ExitCode: 137, Reason: "OOMKilled"},
+				{Pod: "myjob-2", ExitCode: 1, Reason: "Error"},
+			}},
+			want: ErrAppFailure,
+		},
+		{
+			name: "wrapped",
+			err:  multierror.Append(nil, errors.Wrap(JobFailedError{Job: "myjob"}, "job myjob failed")),
+			want: ErrAppFailure,
+		},
+		{
+			name: "deleted",
+			err:  JobDeletedError{Job: "myjob"},
+			want: nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ClassifyFailure(tc.err))
+		})
+	}
+}
+
+func TestJobError(t *testing.T) {
+	op := &driver.Operation{Image: bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "example.com/mysql:v1"}}}
+
+	t.Run("image pull failure", func(t *testing.T) {
+		err := jobError(op, "install-mysql-abc", ProgressDeadlineExceededError{Job: "install-mysql-abc", Reason: "ImagePullBackOff"})
+		assert.True(t, driver.IsImageError(err), "an image pull failure should be reported as an image error")
+		assert.ErrorIs(t, err, ErrImagePull)
+		assert.Contains(t, err.Error(), "job install-mysql-abc failed")
+	})
+
+	t.Run("bundle failure", func(t *testing.T) {
+		err := jobError(op, "install-mysql-abc", JobFailedError{Job: "install-mysql-abc", Message: "BackoffLimitExceeded"})
+		assert.False(t, driver.IsImageError(err))
+		assert.ErrorIs(t, err, ErrAppFailure)
+		assert.EqualError(t, err, "job install-mysql-abc failed: BackoffLimitExceeded")
+	})
+}
@@ -0,0 +1,233 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// SettingFileInjection selects how the operation's files are injected
+	// into the bundle's pod, either FileInjectionVolume or FileInjectionSecret.
+	SettingFileInjection = "FILE_INJECTION"
+
+	// SettingOutputsImage is the image of the sidecar container that
+	// collects the bundle's outputs when the files are injected with a secret.
+	SettingOutputsImage = "OUTPUTS_IMAGE"
+
+	// FileInjectionVolume writes the operation's files to the shared job
+	// volume, JobVolumeName, which is mounted into the bundle's pod. The
+	// outputs are read from the shared job volume.
+	FileInjectionVolume = "volume"
+
+	// FileInjectionSecret writes the operation's files to a secret that is
+	// projected into the bundle's pod, and collects the outputs with a
+	// sidecar container, so that a persistent volume claim shared with the
+	// driver is not required. The files must fit in a secret, which is
+	// limited to 1MiB, and the cluster must support sidecar containers, which
+	// are enabled by default since Kubernetes 1.29.
+	FileInjectionSecret = "secret"
+
+	// DefaultOutputsImage is the default image of the sidecar container that
+	// collects the bundle's outputs. It must provide sh, sleep, tar and base64.
+	DefaultOutputsImage = "busybox:1.36"
+
+	// outputsContainerName is the name of the sidecar container that collects
+	// the bundle's outputs.
+	outputsContainerName = "outputs"
+
+	// inputsVolumeName is the name of the volume projecting the files secret.
+	inputsVolumeName = "cnab-inputs"
+
+	// outputsVolumeName is the name of the volume that the bundle writes its
+	// outputs to when they are collected by the sidecar container.
+	outputsVolumeName = "cnab-outputs"
+
+	// Markers surrounding the outputs archive in the logs of the sidecar container.
+	outputsBeginMarker = "--- cnab outputs begin ---"
+	outputsEndMarker   = "--- cnab outputs end ---"
+)
+
+// outputsScript waits for the sidecar container to be stopped, which happens
+// once the invocation image has exited, and then writes an archive of the
+// outputs directory to its logs.
+var outputsScript = fmt.Sprintf(`trap 'echo "%s"; tar -C /cnab/app/outputs -czf - . | base64; echo "%s"; exit 0' TERM
+while true; do sleep 1; done`, outputsBeginMarker, outputsEndMarker)
+
+// usesSecretFiles determines if the operation's files are injected with a secret.
+func (k *Driver) usesSecretFiles() bool {
+	return k.FileInjection == FileInjectionSecret
+}
+
+// renderFilesSecret returns the secret holding the operation's files, or nil
+// when it has no files, and the volume mounts of the files in the
+// invocation image. Each file is stored with a generated key, because a
+// path is not a valid key.
+func renderFilesSecret(meta metav1.ObjectMeta, files map[string]string) (*v1.Secret, []v1.VolumeMount) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	secret := &v1.Secret{
+		ObjectMeta: meta,
+		StringData: make(map[string]string, len(files)),
+	}
+	secret.ObjectMeta.GenerateName += "files-"
+
+	mounts := make([]v1.VolumeMount, 0, len(paths))
+	for i, p := range paths {
+		key := fmt.Sprintf("file-%d", i)
+		secret.StringData[key] = files[p]
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      inputsVolumeName,
+			MountPath: p,
+			SubPath:   key,
+			ReadOnly:  true,
+		})
+	}
+	return secret, mounts
+}
+
+// outputsSidecar returns the sidecar container that collects the outputs.
+func (k *Driver) outputsSidecar() v1.Container {
+	image := k.OutputsImage
+	if image == "" {
+		image = DefaultOutputsImage
+	}
+
+	always := v1.ContainerRestartPolicyAlways
+	return v1.Container{
+		Name:            outputsContainerName,
+		Image:           image,
+		Command:         []string{"sh", "-c", outputsScript},
+		ImagePullPolicy: k.imagePullPolicy(),
+		RestartPolicy:   &always,
+		VolumeMounts: []v1.VolumeMount{
+			{
+				Name:      outputsVolumeName,
+				MountPath: "/cnab/app/outputs",
+				ReadOnly:  true,
+			},
+		},
+	}
+}
+
+// fetchSidecarOutputs collects the outputs from the logs of the sidecar
+// container of the most recently created pod of the job.
+func (k *Driver) fetchSidecarOutputs(ctx context.Context, op *driver.Operation, podSelector metav1.ListOptions) (driver.OperationResult, error) {
+	opResult := driver.OperationResult{
+		Outputs: map[string]string{},
+	}
+
+	if len(op.Bundle.Outputs) == 0 {
+		return opResult, nil
+	}
+
+	pods, err := k.pods.List(ctx, podSelector)
+	if err != nil {
+		return opResult, errors.Wrap(err, "error listing the pods of the job to collect outputs")
+	}
+	if len(pods.Items) == 0 {
+		return opResult, errors.New("error collecting outputs: the job does not have any pods")
+	}
+
+	latest := pods.Items[0]
+	for _, pod := range pods.Items[1:] {
+		if latest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			latest = pod
+		}
+	}
+
+	logs, err := k.pods.GetLogs(latest.Name, &v1.PodLogOptions{Container: outputsContainerName}).DoRaw(ctx)
+	if err != nil {
+		return opResult, errors.Wrapf(err, "error reading the outputs from pod %s", latest.Name)
+	}
+
+	opResult.Outputs, err = parseOutputsArchive(logs, op)
+	return opResult, err
+}
+
+// parseOutputsArchive extracts the outputs from the archive written to the
+// logs of the sidecar container.
+func parseOutputsArchive(logs []byte, op *driver.Operation) (map[string]string, error) {
+	outputs := map[string]string{}
+
+	text := string(logs)
+	begin := strings.Index(text, outputsBeginMarker)
+	end := strings.LastIndex(text, outputsEndMarker)
+	if begin < 0 || end < begin {
+		return outputs, errors.New("error collecting outputs: the outputs sidecar did not write the outputs archive")
+	}
+
+	encoded := strings.Join(strings.Fields(text[begin+len(outputsBeginMarker):end]), "")
+	archive, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return outputs, errors.Wrap(err, "error decoding the outputs archive")
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return outputs, errors.Wrap(err, "error reading the outputs archive")
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return outputs, errors.Wrap(err, "error reading the outputs archive")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		relPath, err := outputsArchivePath(header.Name)
+		if err != nil {
+			return outputs, err
+		}
+		pathInContainer := path.Join("/cnab/app/outputs", relPath)
+		outputName, shouldCapture := op.Outputs[pathInContainer]
+		if !shouldCapture {
+			continue
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return outputs, errors.Wrapf(err, "error while reading %q from outputs", pathInContainer)
+		}
+		outputs[outputName] = string(contents)
+	}
+	return outputs, nil
+}
+
+// outputsArchivePath returns the path of an entry of the outputs archive
+// relative to the outputs directory, preserving the directories of nested
+// outputs. Entries outside of the outputs directory are rejected.
+func outputsArchivePath(name string) (string, error) {
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", errors.Errorf("error reading the outputs archive: invalid path %q outside of the outputs directory", name)
+		}
+	}
+	return strings.TrimPrefix(path.Clean("/"+name), "/"), nil
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestParseOutputsArchive(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./db/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, contents := range map[string]string{"./port": "3306", "./unknown": "ignored", "./db/host": "mysql.local", "./host": "wrong"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents))}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	// base64 wraps the encoded archive across lines
+	encoded := base64.StdEncoding.EncodeToString(archive.Bytes())
+	wrapped := encoded[:10] + "\n" + encoded[10:]
+	logs := "some startup noise\n" + outputsBeginMarker + "\n" + wrapped + "\n" + outputsEndMarker + "\n"
+
+	op := &driver.Operation{Outputs: map[string]string{"/cnab/app/outputs/port": "port", "/cnab/app/outputs/db/host": "host"}}
+	outputs, err := parseOutputsArchive([]byte(logs), op)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"port": "3306", "host": "mysql.local"}, outputs, "nested outputs should keep their directories")
+
+	_, err = parseOutputsArchive([]byte("terminated before writing the outputs"), op)
+	require.EqualError(t, err, "error collecting outputs: the outputs sidecar did not write the outputs archive")
+}
+
+func TestOutputsArchivePath(t *testing.T) {
+	testcases := map[string]string{
+		"./port":     "port",
+		"port":       "port",
+		"./db/host":  "db/host",
+		"db//host":   "db/host",
+		"/db/./host": "db/host",
+	}
+	for name, want := range testcases {
+		got, err := outputsArchivePath(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	for _, name := range []string{"../port", "./db/../../port", "db/.."} {
+		_, err := outputsArchivePath(name)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "outside of the outputs directory")
+	}
+}
+
+func TestDriver_Run_SecretFiles(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	// The fake client does not generate names
+	generated := 0
+	client.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.CreateAction).GetObject().(metav1.Object)
+		generated++
+		obj.SetName(fmt.Sprintf("%s%d", obj.GetGenerateName(), generated))
+		return false, nil, nil
+	})
+	namespace := "default"
+	k := Driver{
+		Namespace:          namespace,
+		FileInjection:      FileInjectionSecret,
+		jobs:               client.BatchV1().Jobs(namespace),
+		secrets:            client.CoreV1().Secrets(namespace),
+		pods:               client.CoreV1().Pods(namespace),
+		SkipCleanup:        true,
+		skipJobStatusCheck: true,
+	}
+
+	op := newManifestsTestOperation()
+	_, err := k.Run(op)
+	require.NoError(t, err, "the outputs should not be collected when the bundle does not define any")
+
+	secretList, err := k.secrets.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, secretList.Items, 2, "expected secrets for the environment variables and the files")
+
+	jobList, err := k.jobs.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, jobList.Items, 1)
+	volumes := jobList.Items[0].Spec.Template.Spec.Volumes
+	require.Len(t, volumes, 2)
+	assert.Equal(t, "install-mysql-files-2", volumes[1].Secret.SecretName, "the job should reference the files secret by its generated name")
+
+	t.Run("worker pod", func(t *testing.T) {
+		k.WorkerPod = "cnab-worker"
+		defer func() { k.WorkerPod = "" }()
+
+		_, err := k.Run(op)
+		require.EqualError(t, err, "FILE_INJECTION secret is not supported when running operations in the worker pod cnab-worker")
+	})
+
+	t.Run("missing pod", func(t *testing.T) {
+		op := newManifestsTestOperation()
+		op.Bundle = &bundle.Bundle{Outputs: map[string]bundle.Output{"port": {Path: "/cnab/app/outputs/port"}}}
+		op.Outputs = map[string]string{"/cnab/app/outputs/port": "port"}
+
+		_, err := k.Run(op)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error collecting outputs: the job does not have any pods")
+	})
+}
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+const (
+	// SettingImagePullPolicy is the setting for the image pull policy of the
+	// containers of the bundle's job: Always, IfNotPresent or Never.
+	SettingImagePullPolicy = "IMAGE_PULL_POLICY"
+
+	// SettingPreloadedImages is the setting that references the invocation
+	// image by the name in the bundle, without its digest, so that images
+	// preloaded on the nodes are found when IMAGE_PULL_POLICY is Never.
+	SettingPreloadedImages = "PRELOADED_IMAGES"
+)
+
+// parseImagePullPolicy parses an image pull policy, ignoring its case.
+func parseImagePullPolicy(value string) (v1.PullPolicy, error) {
+	for _, policy := range []v1.PullPolicy{v1.PullAlways, v1.PullIfNotPresent, v1.PullNever} {
+		if strings.EqualFold(value, string(policy)) {
+			return policy, nil
+		}
+	}
+	return "", errors.Errorf("invalid value %q for %s, must be %s, %s or %s", value, SettingImagePullPolicy, v1.PullAlways, v1.PullIfNotPresent, v1.PullNever)
+}
+
+// parseImageSettings reads the image pull policy from the driver settings.
+func (k *Driver) parseImageSettings(settings map[string]string) error {
+	k.ImagePullPolicy = ""
+	if value := settings[SettingImagePullPolicy]; value != "" {
+		policy, err := parseImagePullPolicy(value)
+		if err != nil {
+			return err
+		}
+		k.ImagePullPolicy = policy
+	}
+
+	k.PreloadedImages = false
+	if value := settings[SettingPreloadedImages]; value != "" {
+		preloaded, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value %q for %s", value, SettingPreloadedImages)
+		}
+		k.PreloadedImages = preloaded
+	}
+
+	return k.validateImageSettings()
+}
+
+// validateImageSettings checks that preloaded images are only used when the
+// images are never pulled.
+func (k *Driver) validateImageSettings() error {
+	if k.PreloadedImages && k.imagePullPolicy() != v1.PullNever {
+		return errors.Errorf("%s requires %s to be %s, otherwise the invocation image would be pulled without verifying its digest", SettingPreloadedImages, SettingImagePullPolicy, v1.PullNever)
+	}
+	return nil
+}
+
+// imagePullPolicy returns the pull policy of the containers of the bundle's
+// job.
+func (k *Driver) imagePullPolicy() v1.PullPolicy {
+	if k.ImagePullPolicy == "" {
+		return v1.PullIfNotPresent
+	}
+	return k.ImagePullPolicy
+}
+
+// invocationImage returns the reference of the invocation image run by the
+// bundle's job, which is pinned to the digest of the image unless the image
+// is preloaded on the nodes.
+func (k *Driver) invocationImage(img bundle.InvocationImage) (string, error) {
+	if err := k.validateImageSettings(); err != nil {
+		return "", err
+	}
+	if k.PreloadedImages {
+		// Images loaded onto the nodes, e.g. with kind load or ctr images
+		// import, usually have no registry digest, so the container runtime
+		// would not find them by digest
+		return img.Image, nil
+	}
+	return imageWithDigest(img)
+}
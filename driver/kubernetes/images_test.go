@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestDriver_ImagePullPolicy(t *testing.T) {
+	const digest = "sha256:7cc0618539fe11e801ce68911a0c9441a0e1ba0a1e7cb9e8e1e1e4d5d3c5e1a2"
+	op := newManifestsTestOperation()
+	op.Image = bundle.InvocationImage{
+		BaseImage: bundle.BaseImage{Image: "example.com/foo/bar:v1", Digest: digest},
+	}
+
+	testcases := []struct {
+		name      string
+		driver    Driver
+		wantImage string
+		want      v1.PullPolicy
+	}{
+		{name: "default", want: v1.PullIfNotPresent, wantImage: "example.com/foo/bar:v1@" + digest},
+		{name: "always", driver: Driver{ImagePullPolicy: v1.PullAlways}, want: v1.PullAlways, wantImage: "example.com/foo/bar:v1@" + digest},
+		{name: "never", driver: Driver{ImagePullPolicy: v1.PullNever}, want: v1.PullNever, wantImage: "example.com/foo/bar:v1@" + digest},
+		{name: "preloaded images", driver: Driver{ImagePullPolicy: v1.PullNever, PreloadedImages: true}, want: v1.PullNever, wantImage: "example.com/foo/bar:v1"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			k := tc.driver
+			k.Namespace = "default"
+			k.FileInjection = FileInjectionSecret
+
+			m, err := k.RenderManifests(op)
+			require.NoError(t, err)
+
+			spec := m.Job.Spec.Template.Spec
+			require.Len(t, spec.Containers, 1)
+			assert.Equal(t, tc.wantImage, spec.Containers[0].Image)
+			assert.Equal(t, tc.want, spec.Containers[0].ImagePullPolicy)
+			require.Len(t, spec.InitContainers, 1, "expected the outputs sidecar")
+			assert.Equal(t, tc.want, spec.InitContainers[0].ImagePullPolicy, "the sidecar should use the same pull policy")
+		})
+	}
+
+	t.Run("preloaded images pulled", func(t *testing.T) {
+		k := Driver{Namespace: "default", FileInjection: FileInjectionSecret, PreloadedImages: true}
+		_, err := k.RenderManifests(op)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "PRELOADED_IMAGES requires IMAGE_PULL_POLICY to be Never")
+	})
+}
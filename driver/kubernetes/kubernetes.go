@@ -25,6 +25,9 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
 	batchclientv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
 	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
@@ -51,6 +54,8 @@ const (
 	SettingKubeconfig             = "KUBECONFIG"
 	SettingMasterURL              = "MASTER_URL"
 	SettingPodAffinityMatchLabels = "AFFINITY_MATCH_LABELS"
+	SettingProgressDeadline       = "PROGRESS_DEADLINE_SECONDS"
+	SettingPodTemplate            = "POD_TEMPLATE"
 )
 
 var (
@@ -91,6 +96,13 @@ type Driver struct {
 	// data between the driver and the bundle.
 	JobVolumePath string
 
+	// MinVolumeFreeSpace is the minimum free space that the shared job
+	// volume, mounted at JobVolumePath, must have before an operation is
+	// started. When the volume has less free space, the operation fails with
+	// a VolumeFullError instead of failing part way through. Set to zero to
+	// skip the check. Defaults to zero.
+	MinVolumeFreeSpace resource.Quantity
+
 	// JobVolumeName is the name of the persistent volume claim that should be mounted
 	// to the bundle's pod to share data between the driver and the bundle.
 	//
@@ -99,9 +111,59 @@ type Driver struct {
 	// outputs generated.
 	JobVolumeName string
 
+	// FileInjection selects how the operation's files are injected into the
+	// bundle's pod and its outputs collected, either FileInjectionVolume,
+	// which requires JobVolumePath and JobVolumeName, or FileInjectionSecret,
+	// which does not require a persistent volume claim shared with the driver.
+	// Defaults to FileInjectionVolume.
+	FileInjection string
+
+	// OutputsImage is the image of the sidecar container that collects the
+	// bundle's outputs when FileInjection is FileInjectionSecret. Defaults to
+	// DefaultOutputsImage.
+	OutputsImage string
+
+	// ImagePullPolicy is the pull policy of the containers of the bundle's
+	// job: v1.PullAlways to refresh the invocation image on every operation,
+	// v1.PullIfNotPresent, or v1.PullNever for air-gapped nodes where the
+	// images are preloaded. Defaults to v1.PullIfNotPresent.
+	ImagePullPolicy v1.PullPolicy
+
+	// PreloadedImages references the invocation image by the name in the
+	// bundle instead of pinning its digest, so that images preloaded on the
+	// nodes without a registry digest, for example with kind load, are found.
+	// It requires ImagePullPolicy to be v1.PullNever.
+	PreloadedImages bool
+
 	// Tolerations is an optional list of tolerations to apply to the bundle's job.
 	Tolerations []v1.Toleration
 
+	// PodTemplate is an optional overlay that is merged into the pod template
+	// generated for the bundle's job, using the same strategic merge semantics
+	// as kubectl patch. Use it to set fields that the driver does not expose
+	// directly, such as nodeSelector, securityContext, initContainers,
+	// imagePullSecrets and priorityClassName. The invocation image container
+	// is named "invocation" and may be customized by including a container
+	// with that name.
+	PodTemplate *v1.PodTemplateSpec
+
+	// WorkerPod is the name of a long-lived pod, in Namespace, that runs
+	// operations by executing the bundle's entrypoint with PodExecutor,
+	// instead of creating a job for each operation. This avoids the latency
+	// of scheduling a pod for very frequent, small operations. The worker pod
+	// must run the invocation image, which is checked before each operation,
+	// and mount JobVolumeName at WorkerPodVolumePath. Operations run in the
+	// worker pod one at a time. Job specific settings, such as PodTemplate
+	// and BackoffLimit, do not apply to the worker pod.
+	WorkerPod string
+
+	// WorkerPodVolumePath is the path where JobVolumeName is mounted in the
+	// WorkerPod. Defaults to /cnab/worker.
+	WorkerPodVolumePath string
+
+	// PodExecutor runs commands in the WorkerPod. Required when WorkerPod is set.
+	PodExecutor PodExecutor
+
 	// ActiveDeadlineSeconds is the time limit for running the driver's
 	// execution, including retries. Set to 0 to not use a deadline. Default is
 	// 5 minutes.
@@ -111,10 +173,30 @@ type Driver struct {
 	// before the bundle's execution run can be recorded in claim storage.
 	ActiveDeadlineSeconds int64
 
+	// ProgressDeadlineSeconds is the time limit for the bundle's pod to start
+	// running, for example while it waits to be scheduled or for its image to
+	// be pulled. When exceeded, the driver fails fast with a
+	// ProgressDeadlineExceededError instead of waiting indefinitely. Set to 0
+	// to not use a deadline. Defaults to 0. A failure to pull the invocation
+	// image is reported with an ImagePullError once Kubernetes backs off from
+	// pulling it, with or without a deadline.
+	//
+	// Unlike ActiveDeadlineSeconds, this does not limit how long a bundle that
+	// has started may run.
+	ProgressDeadlineSeconds int64
+
 	// BackoffLimit is the number of times to retry the driver's
 	// execution. Defaults to 0, so failed executions will not be retried.
 	BackoffLimit int32
 
+	// LogPodNames prefixes each line of the bundle's logs with the name of the
+	// pod that wrote it, to tell apart the logs of retried executions.
+	LogPodNames bool
+
+	// LogTimestamps prefixes each line of the bundle's logs with the time
+	// that it was written, as reported by Kubernetes.
+	LogTimestamps bool
+
 	// SkipCleanup specifies if the driver should remove any Kubernetes
 	// resources that it created when the driver execution completes.
 	SkipCleanup bool
@@ -133,6 +215,8 @@ type Driver struct {
 	jobs               batchclientv1.JobInterface
 	secrets            coreclientv1.SecretInterface
 	pods               coreclientv1.PodInterface
+	discovery          discovery.ServerVersionInterface
+	clusterVersion     *version.Info
 	deletionPolicy     metav1.DeletionPropagation
 }
 
@@ -165,6 +249,17 @@ func (k *Driver) Config() map[string]string {
 		SettingKubeconfig:             "Absolute path to the kubeconfig file",
 		SettingMasterURL:              "Kubernetes master endpoint",
 		SettingPodAffinityMatchLabels: "Pod Affinity Match Labels to apply to job created by the driver, expressed as name value pairs separated by whitespace. (e.g 'A=B X=Y'), the topology key is set to kubernetes.io/hostname",
+		SettingPodTemplate:            "Pod template overlay, in YAML or JSON, merged into the pod template of the job created by the driver, e.g. to set nodeSelector, securityContext, initContainers, imagePullSecrets or priorityClassName",
+		SettingWorkerPod:              "Name of a long-lived pod running the invocation image in which to run operations, instead of creating a job for each operation",
+		SettingWorkerPodVolumePath:    "Path where the persistent volume is mounted in the worker pod. Defaults to " + DefaultWorkerPodVolumePath,
+		SettingLogPodNames:            "If true, prefix each line of the bundle's logs with the name of the pod that wrote it. Defaults to false.",
+		SettingLogTimestamps:          "If true, prefix each line of the bundle's logs with the time it was written. Defaults to false.",
+		SettingFileInjection:          "How files are injected into the job and outputs collected, either volume, using the persistent volume claim JOB_VOLUME_NAME, or secret, using a secret and a sidecar container that does not require a persistent volume claim. Defaults to volume.",
+		SettingOutputsImage:           "Image of the sidecar container that collects the outputs when FILE_INJECTION is secret. Defaults to " + DefaultOutputsImage,
+		SettingMinVolumeFreeSpace:     "Minimum free space, e.g. 500Mi, that the persistent volume JOB_VOLUME_NAME must have before an operation is started. Defaults to 0, which skips the check.",
+		SettingImagePullPolicy:        "Pull policy of the invocation image: Always to pull it for every operation, IfNotPresent, or Never for nodes where the images are preloaded. Defaults to IfNotPresent.",
+		SettingPreloadedImages:        "If true, run the invocation image by the name in the bundle without pinning its digest, so that images preloaded on the nodes without a registry digest are found. Requires IMAGE_PULL_POLICY Never. Defaults to false.",
+		SettingProgressDeadline:       "Number of seconds to wait for the job's pod to start running, e.g. while it is scheduled and its image pulled, before failing. Defaults to 0, which waits indefinitely.",
 	}
 }
 
@@ -214,13 +309,34 @@ func (k *Driver) SetConfig(settings map[string]string) error {
 	k.ServiceAccountName = settings[SettingServiceAccount]
 	k.Labels = strings.Split(settings[SettingLabels], " ")
 
-	k.JobVolumePath = settings[SettingJobVolumePath]
-	if k.JobVolumePath == "" {
-		return errors.Errorf("setting %s is required", SettingJobVolumePath)
+	k.FileInjection = settings[SettingFileInjection]
+	switch k.FileInjection {
+	case "", FileInjectionVolume, FileInjectionSecret:
+	default:
+		return errors.Errorf("invalid value %q for %s, must be %s or %s", k.FileInjection, SettingFileInjection, FileInjectionVolume, FileInjectionSecret)
 	}
+	k.OutputsImage = settings[SettingOutputsImage]
+
+	if err := k.parseImageSettings(settings); err != nil {
+		return err
+	}
+
+	k.JobVolumePath = settings[SettingJobVolumePath]
 	k.JobVolumeName = settings[SettingJobVolumeName]
-	if k.JobVolumeName == "" {
-		return errors.Errorf("setting %s is required", SettingJobVolumeName)
+	if !k.usesSecretFiles() {
+		if k.JobVolumePath == "" {
+			return errors.Errorf("setting %s is required", SettingJobVolumePath)
+		}
+		if k.JobVolumeName == "" {
+			return errors.Errorf("setting %s is required", SettingJobVolumeName)
+		}
+	}
+	if freeSpace := settings[SettingMinVolumeFreeSpace]; freeSpace != "" {
+		q, err := parseMinVolumeFreeSpace(freeSpace)
+		if err != nil {
+			return err
+		}
+		k.MinVolumeFreeSpace = q
 	}
 
 	cleanup, err := strconv.ParseBool(settings[SettingCleanupJobs])
@@ -228,6 +344,41 @@ func (k *Driver) SetConfig(settings map[string]string) error {
 		k.SkipCleanup = !cleanup
 	}
 
+	for setting, value := range map[string]*bool{
+		SettingLogPodNames:   &k.LogPodNames,
+		SettingLogTimestamps: &k.LogTimestamps,
+	} {
+		if val, ok := settings[setting]; ok && val != "" {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value %q for %s", val, setting)
+			}
+			*value = b
+		}
+	}
+
+	if deadlineVal, ok := settings[SettingProgressDeadline]; ok && deadlineVal != "" {
+		deadline, err := strconv.ParseInt(deadlineVal, 10, 64)
+		if err != nil || deadline < 0 {
+			return errors.Errorf("invalid value %q for %s, must be a non-negative number of seconds", deadlineVal, SettingProgressDeadline)
+		}
+		k.ProgressDeadlineSeconds = deadline
+	}
+
+	if podTemplate := settings[SettingPodTemplate]; podTemplate != "" {
+		tmpl, err := parsePodTemplate(podTemplate)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for %s", SettingPodTemplate)
+		}
+		k.PodTemplate = tmpl
+	}
+
+	k.WorkerPod = settings[SettingWorkerPod]
+	k.WorkerPodVolumePath = settings[SettingWorkerPodVolumePath]
+	if k.WorkerPodVolumePath != "" && !path.IsAbs(k.WorkerPodVolumePath) {
+		return errors.Errorf("invalid value %q for %s, must be an absolute path", k.WorkerPodVolumePath, SettingWorkerPodVolumePath)
+	}
+
 	if inClusterVal, ok := settings[SettingInCluster]; ok {
 		inCluster, err := strconv.ParseBool(inClusterVal)
 		if err != nil {
@@ -255,6 +406,9 @@ func (k *Driver) setDefaults() {
 	k.SkipCleanup = false
 	k.BackoffLimit = 0
 	k.ActiveDeadlineSeconds = 0 // Default to not cutting off a bundle mid-run
+	k.ProgressDeadlineSeconds = 0
+	k.LogPodNames = false
+	k.LogTimestamps = false
 	k.deletionPolicy = metav1.DeletePropagationBackground
 }
 
@@ -290,9 +444,14 @@ func (k *Driver) setClient(conf *rest.Config) error {
 	if err != nil {
 		return errors.Wrap(err, "error creating BatchClient for Kubernetes Driver")
 	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(conf)
+	if err != nil {
+		return errors.Wrap(err, "error creating DiscoveryClient for Kubernetes Driver")
+	}
 	k.jobs = batchClient.Jobs(k.Namespace)
 	k.secrets = coreClient.Secrets(k.Namespace)
 	k.pods = coreClient.Pods(k.Namespace)
+	k.discovery = discoveryClient
 
 	return nil
 }
@@ -305,140 +464,72 @@ func (k *Driver) Run(op *driver.Operation) (driver.OperationResult, error) {
 	}
 
 	ctx := context.Background()
-	const sharedVolumeName = "cnab-driver-share"
-	err = k.initJobVolumes()
-	if err != nil {
-		return driver.OperationResult{}, err
+	if k.WorkerPod != "" {
+		if k.usesSecretFiles() {
+			return driver.OperationResult{}, errors.Errorf("%s %s is not supported when running operations in the worker pod %s", SettingFileInjection, FileInjectionSecret, k.WorkerPod)
+		}
 	}
 
-	meta := metav1.ObjectMeta{
-		Namespace:    k.Namespace,
-		GenerateName: generateNameTemplate(op),
-		Labels: map[string]string{
-			"cnab.io/driver": "kubernetes",
-		},
-		Annotations: generateMergedAnnotations(op, k.Annotations),
+	// Check the shared job volume before anything is created in the cluster,
+	// so that a full volume does not leave secrets behind
+	if !k.usesSecretFiles() {
+		if err := k.CheckVolumeFreeSpace(); err != nil {
+			return driver.OperationResult{}, err
+		}
 	}
 
-	// Apply custom labels
-	for _, l := range k.Labels {
-		parts := strings.SplitN(l, "=", 2)
-		if len(parts) > 1 {
-			meta.Labels[parts[0]] = parts[1]
+	if k.WorkerPod != "" {
+		if err := k.initJobVolumes(); err != nil {
+			return driver.OperationResult{}, err
 		}
+		return k.runInWorkerPod(ctx, op)
 	}
 
-	// Mount SA token if a non-zero value for ServiceAccountName has been specified
-	mountServiceAccountToken := k.ServiceAccountName != ""
-
-	job := &batchv1.Job{
-		ObjectMeta: meta,
-		Spec: batchv1.JobSpec{
-			ActiveDeadlineSeconds: defaultInt64Ptr(k.ActiveDeadlineSeconds),
-			Completions:           defaultInt32Ptr(1),
-			BackoffLimit:          &k.BackoffLimit,
-			Template: v1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels:      meta.Labels,
-					Annotations: meta.Annotations,
-				},
-				Spec: v1.PodSpec{
-					Affinity:                     k.Affinity,
-					ServiceAccountName:           k.ServiceAccountName,
-					AutomountServiceAccountToken: &mountServiceAccountToken,
-					RestartPolicy:                v1.RestartPolicyNever,
-					Tolerations:                  k.Tolerations,
-					Volumes: []v1.Volume{
-						// This is a shared volume between the driver and the job so that files be shared
-						{
-							Name: sharedVolumeName,
-							VolumeSource: v1.VolumeSource{
-								PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
-									ClaimName: k.JobVolumeName,
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-	img, err := imageWithDigest(op.Image)
+	m, err := k.renderManifests(op)
 	if err != nil {
 		return driver.OperationResult{}, err
 	}
 
-	container := v1.Container{
-		Name:            k8sContainerName,
-		Image:           img,
-		Command:         []string{"/cnab/app/run"},
-		ImagePullPolicy: v1.PullIfNotPresent,
-		VolumeMounts: []v1.VolumeMount{
-			{
-				Name:      sharedVolumeName,
-				MountPath: "/cnab/app/outputs",
-				SubPath:   "outputs",
-			},
-		},
-	}
-
-	if !k.LimitCPU.IsZero() {
-		container.Resources.Limits[v1.ResourceCPU] = k.LimitCPU
-	}
-
-	if !k.LimitMemory.IsZero() {
-		container.Resources.Limits[v1.ResourceMemory] = k.LimitMemory
+	if m.Secret != nil {
+		secret, err := k.secrets.Create(ctx, m.Secret, metav1.CreateOptions{})
+		if err != nil {
+			return driver.OperationResult{}, err
+		}
+		if !k.SkipCleanup {
+			defer k.deleteSecret(ctx, secret.ObjectMeta.Name)
+		}
+		m.setSecretName(secret.ObjectMeta.Name)
 	}
 
-	if len(op.Environment) > 0 {
-		secret := &v1.Secret{
-			ObjectMeta: meta,
-			StringData: op.Environment,
-		}
-		secret.ObjectMeta.GenerateName += "env-"
-		secret, err := k.secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if m.FilesSecret != nil {
+		secret, err := k.secrets.Create(ctx, m.FilesSecret, metav1.CreateOptions{})
 		if err != nil {
 			return driver.OperationResult{}, err
 		}
 		if !k.SkipCleanup {
 			defer k.deleteSecret(ctx, secret.ObjectMeta.Name)
 		}
+		m.setFilesSecretName(secret.ObjectMeta.Name)
+	}
 
-		container.EnvFrom = []v1.EnvFromSource{
-			{
-				SecretRef: &v1.SecretEnvSource{
-					LocalObjectReference: v1.LocalObjectReference{
-						Name: secret.ObjectMeta.Name,
-					},
-				},
-			},
+	if m.PullSecret != nil {
+		secret, err := k.secrets.Create(ctx, m.PullSecret, metav1.CreateOptions{})
+		if err != nil {
+			return driver.OperationResult{}, err
 		}
+		if !k.SkipCleanup {
+			defer k.deleteSecret(ctx, secret.ObjectMeta.Name)
+		}
+		m.setPullSecretName(secret.ObjectMeta.Name)
 	}
 
-	if len(op.Files) > 0 {
-		// Write the files to the inputs directory on the shared volume and mount them individually to the desired location in the invocation image
-		for inputRelPath, contents := range op.Files {
-			inputPath := filepath.Join(k.JobVolumePath, "inputs", inputRelPath)
-			err = os.MkdirAll(filepath.Dir(inputPath), 0700)
-			if err != nil {
-				return driver.OperationResult{}, errors.Wrapf(err, "error creating directory for file %s on the shared job volume %s", inputPath, k.JobVolumeName)
-			}
-			err = ioutil.WriteFile(inputPath, []byte(contents), 0600)
-			if err != nil {
-				return driver.OperationResult{}, errors.Wrapf(err, "error writing file %s to the shared job volume %s", inputPath, k.JobVolumeName)
-			}
-
-			container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
-				Name:      sharedVolumeName,
-				MountPath: inputRelPath,
-				SubPath:   path.Join("inputs", inputRelPath),
-			})
+	if !k.usesSecretFiles() {
+		if err := k.writeInputFiles(op); err != nil {
+			return driver.OperationResult{}, err
 		}
 	}
 
-	job.Spec.Template.Spec.Containers = []v1.Container{container}
-
-	job, err = k.jobs.Create(ctx, job, metav1.CreateOptions{})
+	job, err := k.jobs.Create(ctx, m.Job, metav1.CreateOptions{})
 	if err != nil {
 		return driver.OperationResult{}, err
 	}
@@ -448,7 +539,15 @@ func (k *Driver) Run(op *driver.Operation) (driver.OperationResult, error) {
 
 	// Skip waiting for the job in unit tests (the fake k8s client implementation just
 	// hangs during watch because no events are ever created on the Job)
-	var opErr *multierror.Error
+	// Prevent detecting pods from prior jobs by adding the job name to the labels
+	podSelector := metav1.ListOptions{
+		LabelSelector: newSingleFieldSelector("job-name", job.ObjectMeta.Name),
+	}
+
+	var (
+		opErr       *multierror.Error
+		termination *ContainerTermination
+	)
 	if !k.skipJobStatusCheck {
 		// Create a selector to detect the job just created
 		jobSelector := metav1.ListOptions{
@@ -456,37 +555,73 @@ func (k *Driver) Run(op *driver.Operation) (driver.OperationResult, error) {
 			FieldSelector: newSingleFieldSelector("metadata.name", job.ObjectMeta.Name),
 		}
 
-		// Prevent detecting pods from prior jobs by adding the job name to the labels
-		podSelector := metav1.ListOptions{
-			LabelSelector: newSingleFieldSelector("job-name", job.ObjectMeta.Name),
+		err = k.watchJobStatusAndLogs(ctx, job.Name, jobActiveDeadline(job), podSelector, jobSelector, op.Out)
+		if errors.Is(err, driver.ErrExecutionInterrupted) {
+			// The outputs of the job are incomplete, or were deleted along with it
+			return driver.OperationResult{}, err
 		}
-
-		err = k.watchJobStatusAndLogs(ctx, podSelector, jobSelector, op.Out)
 		if err != nil {
-			opErr = multierror.Append(opErr, errors.Wrapf(err, "job %s failed", job.Name))
+			opErr = multierror.Append(opErr, jobError(op, job.Name, err))
+			if !k.usesSecretFiles() {
+				if volumeErr := k.checkVolumeAfterFailure(filepath.Join(k.JobVolumePath, "outputs")); volumeErr != nil {
+					opErr = multierror.Append(opErr, volumeErr)
+				}
+			}
+		}
+
+		if terminations, err := k.listContainerTerminations(ctx, podSelector); err == nil && len(terminations) > 0 {
+			termination = &terminations[len(terminations)-1]
 		}
 	}
 
-	opResult, err := k.fetchOutputs(op)
+	var opResult driver.OperationResult
+	if k.usesSecretFiles() {
+		opResult, err = k.fetchSidecarOutputs(ctx, op, podSelector)
+	} else {
+		opResult, err = k.fetchOutputs(op)
+	}
 	if err != nil {
 		opErr = multierror.Append(opErr, err)
 	}
+	if termination != nil {
+		opResult.Metadata = termination.Metadata()
+	}
 
 	return opResult, opErr.ErrorOrNil()
 }
 
+// writeInputFiles writes the files to the inputs directory on the shared volume, where they are mounted into the invocation image.
+func (k *Driver) writeInputFiles(op *driver.Operation) error {
+	if err := k.initJobVolumes(); err != nil {
+		return err
+	}
+
+	for inputRelPath, contents := range op.Files {
+		inputPath := filepath.Join(k.JobVolumePath, "inputs", inputRelPath)
+		err := os.MkdirAll(filepath.Dir(inputPath), 0700)
+		if err != nil {
+			return k.volumeError(inputPath, errors.Wrapf(err, "error creating directory for file %s on the shared job volume %s", inputPath, k.JobVolumeName))
+		}
+		err = ioutil.WriteFile(inputPath, []byte(contents), 0600)
+		if err != nil {
+			return k.volumeError(inputPath, errors.Wrapf(err, "error writing file %s to the shared job volume %s", inputPath, k.JobVolumeName))
+		}
+	}
+	return nil
+}
+
 // Store all job input files in ./inputs and outputs in ./outputs on the shared volume
 func (k *Driver) initJobVolumes() error {
 	inputsDir := filepath.Join(k.JobVolumePath, "inputs")
 	err := os.Mkdir(inputsDir, 0700)
 	if err != nil && !os.IsExist(err) {
-		return errors.Wrapf(err, "error creating inputs directory %s on shared job volume %s", inputsDir, k.JobVolumeName)
+		return k.volumeError(inputsDir, errors.Wrapf(err, "error creating inputs directory %s on shared job volume %s", inputsDir, k.JobVolumeName))
 	}
 
 	outputsDir := filepath.Join(k.JobVolumePath, "outputs")
 	err = os.Mkdir(outputsDir, 0700)
 	if err != nil && !os.IsExist(err) {
-		return errors.Wrapf(err, "error creating outputs directory %s on shared job volume %s", outputsDir, k.JobVolumeName)
+		return k.volumeError(outputsDir, errors.Wrapf(err, "error creating outputs directory %s on shared job volume %s", outputsDir, k.JobVolumeName))
 	}
 
 	return nil
@@ -518,6 +653,11 @@ func defaultInt32Ptr(value int32) *int32 {
 // The goal is to collect all the files in the directory (recursively) and put them in a flat map of path to contents.
 // This map will be inside the OperationResult. When fetchOutputs returns an error, it may also return partial results.
 func (k *Driver) fetchOutputs(op *driver.Operation) (driver.OperationResult, error) {
+	return k.fetchOutputsFrom(op, filepath.Join(k.JobVolumePath, "outputs"))
+}
+
+// fetchOutputsFrom collects the outputs persisted to the specified directory on the shared volume.
+func (k *Driver) fetchOutputsFrom(op *driver.Operation, outputsDir string) (driver.OperationResult, error) {
 	opResult := driver.OperationResult{
 		Outputs: map[string]string{},
 	}
@@ -526,7 +666,6 @@ func (k *Driver) fetchOutputs(op *driver.Operation) (driver.OperationResult, err
 		return opResult, nil
 	}
 
-	outputsDir := filepath.Join(k.JobVolumePath, "outputs")
 	err := filepath.Walk(outputsDir, func(currentPath string, info os.FileInfo, err error) error {
 		// skip directories because we're gathering file contents
 		if info.IsDir() {
@@ -550,107 +689,86 @@ func (k *Driver) fetchOutputs(op *driver.Operation) (driver.OperationResult, err
 	return opResult, err
 }
 
-func (k *Driver) watchJobStatusAndLogs(ctx context.Context, podSelector metav1.ListOptions, jobSelector metav1.ListOptions, out io.Writer) error {
-	// Stream Pod logs in the background
-	logsStreamingComplete := make(chan bool)
-	err := k.streamPodLogs(ctx, podSelector, out, logsStreamingComplete)
+func (k *Driver) watchJobStatusAndLogs(ctx context.Context, jobName string, activeDeadline time.Duration, podSelector metav1.ListOptions, jobSelector metav1.ListOptions, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stream the logs of each of the job's pods in the background
+	logs, err := k.streamPodLogs(ctx, podSelector, out)
 	if err != nil {
 		return err
 	}
+
+	// Fail fast if the image can't be pulled or the pod doesn't start in time
+	podStarted := make(chan error, 1)
+	go func() {
+		podStarted <- k.waitForPodStart(ctx, jobName, podSelector)
+	}()
+
 	// Watch job events and exit on failure/success
-	watch, err := k.jobs.Watch(ctx, jobSelector)
+	watcher, err := k.jobs.Watch(ctx, jobSelector)
 	if err != nil {
 		return err
 	}
-	for event := range watch.ResultChan() {
-		job, ok := event.Object.(*batchv1.Job)
-		if !ok {
-			return fmt.Errorf("unexpected type")
-		}
-		complete := false
-		for _, cond := range job.Status.Conditions {
-			if cond.Type == batchv1.JobFailed {
-				err = fmt.Errorf("%s", cond.Message)
+	defer watcher.Stop()
+
+	events := watcher.ResultChan()
+	complete := false
+	for !complete {
+		select {
+		case startErr := <-podStarted:
+			if startErr != nil {
+				// The pod never started, so there are no logs to wait for
+				return startErr
+			}
+			// Stop waiting on the pod now that it has started
+			podStarted = nil
+		case event, ok := <-events:
+			if !ok {
 				complete = true
 				break
 			}
-			if cond.Type == batchv1.JobComplete {
+			if event.Type == watch.Deleted {
+				// The job was deleted by someone else, e.g. kubectl delete
+				// or the removal of the namespace, before it completed
+				err = JobDeletedError{Job: jobName}
 				complete = true
 				break
 			}
-		}
-		if complete {
-			break
-		}
-	}
-
-	// Wait for pod logs to finish printing
-	<-logsStreamingComplete
-
-	return err
-}
-
-func (k *Driver) streamPodLogs(ctx context.Context, options metav1.ListOptions, out io.Writer, done chan bool) error {
-	watcher, err := k.pods.Watch(ctx, options)
-	if err != nil {
-		return err
-	}
-
-	go func() {
-		// Track pods whose logs have been streamed by pod name. We need to know when we've already
-		// processed logs for a given pod, since multiple lifecycle events are received per pod.
-		streamedLogs := map[string]bool{}
-		for event := range watcher.ResultChan() {
-			pod, ok := event.Object.(*v1.Pod)
+			job, ok := event.Object.(*batchv1.Job)
 			if !ok {
-				continue
+				return fmt.Errorf("unexpected type")
 			}
-			podName := pod.GetName()
-			if streamedLogs[podName] {
-				// The event was for a pod whose logs have already been streamed, so do nothing.
-				continue
-			}
-
-			var i int
-			for i = 0; i < numBackoffLoops; i++ {
-				time.Sleep(time.Duration(i*i/2) * time.Second)
-				req := k.pods.GetLogs(podName, &v1.PodLogOptions{
-					Container: k8sContainerName,
-					Follow:    true,
-				})
-				reader, err := req.Stream(ctx)
-				if err != nil {
-					// There was an error connecting to the pod, so continue the loop and attempt streaming
-					// the logs again.
-					continue
+			for _, cond := range job.Status.Conditions {
+				if cond.Type == batchv1.JobFailed {
+					terminations, _ := k.listContainerTerminations(ctx, podSelector)
+					err = JobFailedError{
+						Job:          jobName,
+						Message:      cond.Message,
+						Terminations: terminations,
+					}
+					if cond.Reason == jobReasonDeadlineExceeded {
+						err = ActiveDeadlineExceededError{
+							Job:      jobName,
+							Deadline: activeDeadline,
+							Message:  cond.Message,
+						}
+					}
+					complete = true
+					break
 				}
-
-				// Block the loop until all logs from the pod have been processed.
-				bytesRead, err := io.Copy(out, reader)
-				reader.Close()
-				if err != nil {
-					continue
-				}
-				if bytesRead == 0 {
-					// There is a chance where we have connected to the pod, but it has yet to write something.
-					// In that case, we continue to to keep streaming until it does.
-					continue
+				if cond.Type == batchv1.JobComplete {
+					complete = true
+					break
 				}
-				// Set the pod to have successfully streamed data.
-				streamedLogs[podName] = true
-				break
-			}
-
-			// check if we hit our maximum number of retries
-			if i >= numBackoffLoops {
-				fmt.Fprintln(out, errors.Wrapf(err, "Could not copy logs for pod %s", podName))
 			}
-
-			done <- true
 		}
-	}()
+	}
 
-	return nil
+	// Wait for the logs of every pod to finish printing
+	logs.Wait()
+
+	return err
 }
 
 func (k *Driver) deleteSecret(ctx context.Context, name string) error {
@@ -688,12 +806,12 @@ func generateNameTemplate(op *driver.Operation) string {
 	return result
 }
 
+// generateMergedAnnotations returns the operation labels as annotations,
+// because their values, such as the bundle version, are not always valid label
+// values, merged with the custom annotations. The driver is applied as a label
+// instead, so that the resources created by the driver can be selected.
 func generateMergedAnnotations(op *driver.Operation, mergeWith map[string]string) map[string]string {
-	anno := map[string]string{
-		"cnab.io/installation": op.Installation,
-		"cnab.io/action":       op.Action,
-		"cnab.io/revision":     op.Revision,
-	}
+	anno := driver.NewOperationLabels("", op).Map()
 
 	for k, v := range mergeWith {
 		if strings.HasPrefix(k, cnabPrefix) {
@@ -9,8 +9,13 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/driver"
@@ -388,6 +393,28 @@ func TestDriver_SetConfig(t *testing.T) {
 		assert.Contains(t, err.Error(), "setting JOB_VOLUME_PATH is required")
 	})
 
+	t.Run("secret file injection", func(t *testing.T) {
+		d := Driver{}
+		settings := validSettings()
+		settings[SettingFileInjection] = FileInjectionSecret
+		settings[SettingOutputsImage] = "example.com/busybox"
+		settings[SettingJobVolumeName] = ""
+		settings[SettingJobVolumePath] = ""
+		err := d.SetConfig(settings)
+		require.NoError(t, err, "the job volume should not be required")
+
+		assert.Equal(t, FileInjectionSecret, d.FileInjection)
+		assert.Equal(t, "example.com/busybox", d.OutputsImage)
+	})
+
+	t.Run("invalid file injection", func(t *testing.T) {
+		d := Driver{}
+		settings := validSettings()
+		settings[SettingFileInjection] = "configmap"
+		err := d.SetConfig(settings)
+		require.EqualError(t, err, `invalid value "configmap" for FILE_INJECTION, must be volume or secret`)
+	})
+
 	t.Run("invalid PodAffinity match labels ", func(t *testing.T) {
 		d := Driver{}
 		settings := validSettings()
@@ -405,4 +432,84 @@ func TestDriver_SetConfig(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character")
 	})
+	t.Run("progress deadline", func(t *testing.T) {
+		d := Driver{}
+		settings := validSettings()
+		settings[SettingProgressDeadline] = "120"
+		err := d.SetConfig(settings)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(120), d.ProgressDeadlineSeconds, "incorrect ProgressDeadlineSeconds value")
+		assert.Equal(t, int64(0), d.ActiveDeadlineSeconds, "the progress deadline should not change ActiveDeadlineSeconds")
+	})
+
+	t.Run("image pull policy", func(t *testing.T) {
+		d := Driver{}
+		settings := validSettings()
+		settings[SettingImagePullPolicy] = "never"
+		settings[SettingPreloadedImages] = "true"
+		err := d.SetConfig(settings)
+		require.NoError(t, err)
+
+		assert.Equal(t, v1.PullNever, d.ImagePullPolicy, "incorrect ImagePullPolicy value")
+		assert.True(t, d.PreloadedImages, "incorrect PreloadedImages value")
+	})
+
+	t.Run("invalid image pull policy", func(t *testing.T) {
+		d := Driver{}
+		settings := validSettings()
+		settings[SettingImagePullPolicy] = "Sometimes"
+		err := d.SetConfig(settings)
+		require.EqualError(t, err, `invalid value "Sometimes" for IMAGE_PULL_POLICY, must be Always, IfNotPresent or Never`)
+	})
+
+	t.Run("preloaded images pulled", func(t *testing.T) {
+		d := Driver{}
+		settings := validSettings()
+		settings[SettingPreloadedImages] = "true"
+		err := d.SetConfig(settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "PRELOADED_IMAGES requires IMAGE_PULL_POLICY to be Never")
+	})
+
+	t.Run("invalid progress deadline", func(t *testing.T) {
+		d := Driver{}
+		settings := validSettings()
+		settings[SettingProgressDeadline] = "-1"
+		err := d.SetConfig(settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value "-1" for PROGRESS_DEADLINE_SECONDS`)
+	})
+}
+
+func TestDriver_Run_JobDeleted(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	jobWatcher := watch.NewFake()
+	client.PrependWatchReactor("jobs", k8stesting.DefaultWatchReactor(jobWatcher, nil))
+	client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		job.Name = job.GenerateName + "abc"
+		go jobWatcher.Delete(job)
+		return false, nil, nil
+	})
+
+	namespace := "default"
+	k := Driver{
+		Namespace:     namespace,
+		jobs:          client.BatchV1().Jobs(namespace),
+		secrets:       client.CoreV1().Secrets(namespace),
+		pods:          client.CoreV1().Pods(namespace),
+		FileInjection: FileInjectionSecret,
+	}
+	op := driver.Operation{
+		Action:       "install",
+		Installation: "mysql",
+		Bundle:       &bundle.Bundle{},
+		Image:        bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "foo/bar"}},
+		Out:          ioutil.Discard,
+	}
+
+	_, err := k.Run(&op)
+	require.EqualError(t, err, "job install-mysql-abc was deleted before it completed")
+	assert.ErrorIs(t, err, driver.ErrExecutionInterrupted)
 }
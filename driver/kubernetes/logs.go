@@ -0,0 +1,234 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// SettingLogPodNames prefixes each line of the bundle's logs with the
+	// name of the pod that wrote it.
+	SettingLogPodNames = "LOG_POD_NAMES"
+
+	// SettingLogTimestamps prefixes each line of the bundle's logs with the
+	// time that Kubernetes received it.
+	SettingLogTimestamps = "LOG_TIMESTAMPS"
+)
+
+// podLogStreamer follows the logs of every pod created for a job, including
+// the pods created when a failed job is retried, and copies them line by line
+// to a shared writer so that the logs of different pods are not interleaved
+// mid-line.
+type podLogStreamer struct {
+	driver   *Driver
+	ctx      context.Context
+	selector metav1.ListOptions
+	watcher  watch.Interface
+
+	// watchDone is closed once the watcher stops delivering events.
+	watchDone chan struct{}
+
+	// mu guards streamed and serializes writes to out.
+	mu       sync.Mutex
+	streamed map[string]bool
+	out      io.Writer
+
+	wg sync.WaitGroup
+}
+
+// streamPodLogs starts following the logs of the pods matching the selector
+// in the background. Call Wait on the returned streamer once the job has
+// finished to wait for all of the logs to be written to out.
+func (k *Driver) streamPodLogs(ctx context.Context, options metav1.ListOptions, out io.Writer) (*podLogStreamer, error) {
+	watcher, err := k.pods.Watch(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if out == nil {
+		out = ioutil.Discard
+	}
+
+	s := &podLogStreamer{
+		driver:    k,
+		ctx:       ctx,
+		selector:  options,
+		watcher:   watcher,
+		watchDone: make(chan struct{}),
+		streamed:  map[string]bool{},
+		out:       out,
+	}
+	go s.watch()
+
+	return s, nil
+}
+
+// watch follows the logs of each pod as it is created.
+func (s *podLogStreamer) watch() {
+	defer close(s.watchDone)
+	for event := range s.watcher.ResultChan() {
+		pod, ok := event.Object.(*v1.Pod)
+		if !ok {
+			continue
+		}
+		s.follow(pod.GetName())
+	}
+}
+
+// follow starts streaming the logs of the pod, unless they are already being
+// streamed. Multiple lifecycle events are received per pod.
+func (s *podLogStreamer) follow(podName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streamed[podName] {
+		return
+	}
+	s.streamed[podName] = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.stream(podName)
+	}()
+}
+
+// Wait stops watching for new pods and blocks until the logs of every pod
+// have been written. Pods that were not seen by the watcher, for example
+// because the job finished before their events were delivered, are streamed
+// before returning.
+func (s *podLogStreamer) Wait() {
+	s.watcher.Stop()
+	<-s.watchDone
+
+	pods, err := s.driver.pods.List(s.ctx, s.selector)
+	if err == nil {
+		for _, pod := range pods.Items {
+			s.follow(pod.GetName())
+		}
+	}
+
+	s.wg.Wait()
+}
+
+// stream copies the logs of the pod to the shared writer, retrying while the
+// pod is not ready to serve its logs or has yet to write anything.
+func (s *podLogStreamer) stream(podName string) {
+	w := &lineWriter{mu: &s.mu, out: s.out}
+	if s.driver.LogPodNames {
+		w.prefix = fmt.Sprintf("[%s] ", podName)
+	}
+	defer w.Flush()
+
+	var err error
+	for i := 0; i < numBackoffLoops; i++ {
+		time.Sleep(time.Duration(i*i/2) * time.Second)
+		req := s.driver.pods.GetLogs(podName, &v1.PodLogOptions{
+			Container:  k8sContainerName,
+			Follow:     true,
+			Timestamps: s.driver.LogTimestamps,
+		})
+
+		var reader io.ReadCloser
+		reader, err = req.Stream(s.ctx)
+		if err != nil {
+			// There was an error connecting to the pod, so attempt streaming the logs again.
+			continue
+		}
+
+		// Block until all logs from the pod have been processed.
+		var bytesRead int64
+		bytesRead, err = io.Copy(w, reader)
+		reader.Close()
+		if err != nil {
+			continue
+		}
+		if bytesRead == 0 && !s.driver.isContainerTerminated(s.ctx, podName) {
+			// We may have connected to the pod before it has written anything,
+			// so keep streaming until it does or its container exits.
+			continue
+		}
+		return
+	}
+
+	if err == nil {
+		err = errors.New("no logs were written")
+	}
+	w.Flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintln(s.out, errors.Wrapf(err, "Could not copy logs for pod %s", podName))
+}
+
+// isContainerTerminated checks if the invocation image container of the pod
+// has exited.
+func (k *Driver) isContainerTerminated(ctx context.Context, podName string) bool {
+	pod, err := k.pods.Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return containerTermination(*pod) != nil
+}
+
+// lineWriter writes complete lines to a writer shared with other pods,
+// prefixing each line.
+type lineWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	lines := w.buf[:i+1]
+	if err := w.writeLines(lines); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+
+	return len(p), nil
+}
+
+// Flush writes any partial line that is left, terminating it with a newline.
+func (w *lineWriter) Flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+	w.writeLines(append(w.buf, '\n'))
+	w.buf = w.buf[:0]
+}
+
+func (w *lineWriter) writeLines(lines []byte) error {
+	if w.prefix != "" {
+		var prefixed bytes.Buffer
+		for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			prefixed.WriteString(w.prefix)
+			prefixed.Write(line)
+		}
+		lines = prefixed.Bytes()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.out.Write(lines)
+	return err
+}
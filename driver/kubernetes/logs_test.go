@@ -0,0 +1,73 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDriver_StreamPodLogs(t *testing.T) {
+	newPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{"job-name": "install-mysql-abc"},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name        string
+		logPodNames bool
+		wantLines   []string
+	}{
+		{name: "plain", wantLines: []string{"fake logs\n"}},
+		{name: "pod names", logPodNames: true, wantLines: []string{"[install-mysql-abc-1] fake logs\n", "[install-mysql-abc-2] fake logs\n"}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The first pod failed and the second pod is its retry
+			client := fake.NewSimpleClientset(newPod("install-mysql-abc-1"), newPod("install-mysql-abc-2"))
+			k := Driver{
+				pods:        client.CoreV1().Pods("default"),
+				LogPodNames: tc.logPodNames,
+			}
+
+			var out bytes.Buffer
+			podSelector := metav1.ListOptions{LabelSelector: newSingleFieldSelector("job-name", "install-mysql-abc")}
+			logs, err := k.streamPodLogs(context.Background(), podSelector, &out)
+			require.NoError(t, err)
+			logs.Wait()
+
+			for _, line := range tc.wantLines {
+				assert.Contains(t, out.String(), line)
+			}
+			assert.Equal(t, 2, bytes.Count(out.Bytes(), []byte("fake logs\n")), "the logs of each pod should be streamed once")
+		})
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	w1 := &lineWriter{mu: &mu, out: &out, prefix: "[pod-1] "}
+	w2 := &lineWriter{mu: &mu, out: &out, prefix: "[pod-2] "}
+
+	w1.Write([]byte("hello "))
+	w2.Write([]byte("first\nsec"))
+	w1.Write([]byte("world\n"))
+	w2.Write([]byte("ond"))
+	w1.Flush()
+	w2.Flush()
+
+	assert.Equal(t, "[pod-2] first\n[pod-1] hello world\n[pod-2] second\n", out.String())
+}
@@ -0,0 +1,316 @@
+package kubernetes
+
+import (
+	"context"
+	"path"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// sharedVolumeName is the name of the volume in the bundle's pod for the
+// persistent volume claim shared between the driver and the bundle.
+const sharedVolumeName = "cnab-driver-share"
+
+// Manifests are the Kubernetes resources that the driver creates to run an
+// operation in a job.
+type Manifests struct {
+	// Secret holds the environment variables of the operation, and is nil
+	// when the operation does not have any.
+	Secret *v1.Secret
+
+	// FilesSecret holds the files of the operation when the driver injects
+	// them with FileInjectionSecret, and is nil otherwise or when the
+	// operation does not have any.
+	FilesSecret *v1.Secret
+
+	// PullSecret holds the credentials to pull the invocation image when the
+	// operation has a RegistryAuth, and is nil otherwise.
+	PullSecret *v1.Secret
+
+	// Job runs the invocation image. When the manifests are rendered without
+	// being submitted, the job references the secrets by their GenerateName,
+	// because the names are generated by the cluster.
+	Job *batchv1.Job
+}
+
+// RenderManifests returns the resources that Run creates for the operation,
+// without connecting to the cluster or writing the operation's files to the
+// shared job volume, so that they can be reviewed before the driver is used.
+// The manifests are not rendered when the driver runs operations in a
+// WorkerPod, because no resources are created.
+func (k *Driver) RenderManifests(op *driver.Operation) (Manifests, error) {
+	if k.WorkerPod != "" {
+		return Manifests{}, errors.Errorf("the driver runs operations in the worker pod %s and does not create any resources", k.WorkerPod)
+	}
+	return k.renderManifests(op)
+}
+
+// DryRun submits the resources that Run creates for the operation to the
+// cluster using a server-side dry run, which checks them against RBAC and
+// admission controllers without persisting them. The resources returned
+// include any defaults and mutations applied by the cluster.
+func (k *Driver) DryRun(op *driver.Operation) (Manifests, error) {
+	m, err := k.RenderManifests(op)
+	if err != nil {
+		return Manifests{}, err
+	}
+
+	err = k.initClient()
+	if err != nil {
+		return Manifests{}, err
+	}
+
+	ctx := context.Background()
+	opts := metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}
+	if m.Secret != nil {
+		secret, err := k.secrets.Create(ctx, m.Secret, opts)
+		if err != nil {
+			return m, errors.Wrap(err, "dry run of the secret failed")
+		}
+		m.Secret = secret
+		if secret.ObjectMeta.Name != "" {
+			m.setSecretName(secret.ObjectMeta.Name)
+		}
+	}
+	if m.FilesSecret != nil {
+		secret, err := k.secrets.Create(ctx, m.FilesSecret, opts)
+		if err != nil {
+			return m, errors.Wrap(err, "dry run of the files secret failed")
+		}
+		m.FilesSecret = secret
+		if secret.ObjectMeta.Name != "" {
+			m.setFilesSecretName(secret.ObjectMeta.Name)
+		}
+	}
+	if m.PullSecret != nil {
+		secret, err := k.secrets.Create(ctx, m.PullSecret, opts)
+		if err != nil {
+			return m, errors.Wrap(err, "dry run of the pull secret failed")
+		}
+		m.PullSecret = secret
+		if secret.ObjectMeta.Name != "" {
+			m.setPullSecretName(secret.ObjectMeta.Name)
+		}
+	}
+
+	job, err := k.jobs.Create(ctx, m.Job, opts)
+	if err != nil {
+		return m, errors.Wrap(err, "dry run of the job failed")
+	}
+	m.Job = job
+
+	return m, nil
+}
+
+// renderManifests generates the resources for the operation.
+func (k *Driver) renderManifests(op *driver.Operation) (Manifests, error) {
+	meta, err := k.generateObjectMeta(op)
+	if err != nil {
+		return Manifests{}, err
+	}
+
+	// Mount SA token if a non-zero value for ServiceAccountName has been specified
+	mountServiceAccountToken := k.ServiceAccountName != ""
+
+	job := &batchv1.Job{
+		ObjectMeta: meta,
+		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: defaultInt64Ptr(k.activeDeadlineSeconds(op)),
+			Completions:           defaultInt32Ptr(1),
+			BackoffLimit:          &k.BackoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      meta.Labels,
+					Annotations: meta.Annotations,
+				},
+				Spec: v1.PodSpec{
+					Affinity:                     k.Affinity,
+					ServiceAccountName:           k.ServiceAccountName,
+					AutomountServiceAccountToken: &mountServiceAccountToken,
+					RestartPolicy:                v1.RestartPolicyNever,
+					Tolerations:                  k.Tolerations,
+				},
+			},
+		},
+	}
+	img, err := k.invocationImage(op.Image)
+	if err != nil {
+		return Manifests{}, err
+	}
+
+	container := v1.Container{
+		Name:            k8sContainerName,
+		Image:           img,
+		Command:         []string{"/cnab/app/run"},
+		ImagePullPolicy: k.imagePullPolicy(),
+		// Use the end of the logs as the termination message when the bundle
+		// fails without writing one, so that the error is reported even
+		// when streaming the logs was interrupted
+		TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+	}
+
+	if !k.LimitCPU.IsZero() {
+		container.Resources.Limits[v1.ResourceCPU] = k.LimitCPU
+	}
+
+	if !k.LimitMemory.IsZero() {
+		container.Resources.Limits[v1.ResourceMemory] = k.LimitMemory
+	}
+
+	var secret *v1.Secret
+	if len(op.Environment) > 0 {
+		secret = &v1.Secret{
+			ObjectMeta: meta,
+			StringData: op.Environment,
+		}
+		secret.ObjectMeta.GenerateName += "env-"
+
+		container.EnvFrom = []v1.EnvFromSource{
+			{
+				SecretRef: &v1.SecretEnvSource{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: secret.ObjectMeta.GenerateName,
+					},
+				},
+			},
+		}
+	}
+
+	var filesSecret *v1.Secret
+	podSpec := &job.Spec.Template.Spec
+	if k.usesSecretFiles() {
+		// Project the files from a secret, and collect the outputs written to
+		// an emptyDir volume with a sidecar container
+		var fileMounts []v1.VolumeMount
+		filesSecret, fileMounts = renderFilesSecret(meta, op.Files)
+		podSpec.Volumes = []v1.Volume{
+			{
+				Name:         outputsVolumeName,
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+			},
+		}
+		if filesSecret != nil {
+			podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+				Name: inputsVolumeName,
+				VolumeSource: v1.VolumeSource{
+					Secret: &v1.SecretVolumeSource{SecretName: filesSecret.ObjectMeta.GenerateName},
+				},
+			})
+		}
+		container.VolumeMounts = append([]v1.VolumeMount{
+			{
+				Name:      outputsVolumeName,
+				MountPath: "/cnab/app/outputs",
+			},
+		}, fileMounts...)
+		podSpec.InitContainers = []v1.Container{k.outputsSidecar()}
+	} else {
+		// This is a shared volume between the driver and the job so that files be shared
+		podSpec.Volumes = []v1.Volume{
+			{
+				Name: sharedVolumeName,
+				VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+						ClaimName: k.JobVolumeName,
+					},
+				},
+			},
+		}
+		container.VolumeMounts = []v1.VolumeMount{
+			{
+				Name:      sharedVolumeName,
+				MountPath: "/cnab/app/outputs",
+				SubPath:   "outputs",
+			},
+		}
+
+		// Mount the files individually from the inputs directory on the shared volume to the desired location in the invocation image
+		for inputRelPath := range op.Files {
+			container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+				Name:      sharedVolumeName,
+				MountPath: inputRelPath,
+				SubPath:   path.Join("inputs", inputRelPath),
+			})
+		}
+	}
+
+	podSpec.Containers = []v1.Container{container}
+
+	var pullSecret *v1.Secret
+	if op.RegistryAuth != nil {
+		dockerConfig, err := op.RegistryAuth.DockerConfigJSON(op.Image.Image)
+		if err != nil {
+			return Manifests{}, err
+		}
+		pullSecret = &v1.Secret{
+			ObjectMeta: meta,
+			Type:       v1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				v1.DockerConfigJsonKey: dockerConfig,
+			},
+		}
+		pullSecret.ObjectMeta.GenerateName += "pull-"
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, v1.LocalObjectReference{Name: pullSecret.ObjectMeta.GenerateName})
+	}
+
+	err = applyPodTemplate(&job.Spec.Template, k.PodTemplate)
+	if err != nil {
+		return Manifests{}, err
+	}
+
+	return Manifests{Secret: secret, FilesSecret: filesSecret, PullSecret: pullSecret, Job: job}, nil
+}
+
+// setSecretName updates the references to the Secret from the Job with the
+// name generated for the Secret by the cluster.
+func (m *Manifests) setSecretName(name string) {
+	if m.Secret == nil {
+		return
+	}
+
+	placeholder := m.Secret.ObjectMeta.GenerateName
+	containers := m.Job.Spec.Template.Spec.Containers
+	for i := range containers {
+		for _, envFrom := range containers[i].EnvFrom {
+			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == placeholder {
+				envFrom.SecretRef.Name = name
+			}
+		}
+	}
+}
+
+// setFilesSecretName updates the reference to the FilesSecret from the Job
+// with the name generated for the secret by the cluster.
+func (m *Manifests) setFilesSecretName(name string) {
+	if m.FilesSecret == nil {
+		return
+	}
+
+	placeholder := m.FilesSecret.ObjectMeta.GenerateName
+	for _, volume := range m.Job.Spec.Template.Spec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == placeholder {
+			volume.Secret.SecretName = name
+		}
+	}
+}
+
+// setPullSecretName updates the reference to the PullSecret from the Job
+// with the name generated for the secret by the cluster.
+func (m *Manifests) setPullSecretName(name string) {
+	if m.PullSecret == nil {
+		return
+	}
+
+	placeholder := m.PullSecret.ObjectMeta.GenerateName
+	pullSecrets := m.Job.Spec.Template.Spec.ImagePullSecrets
+	for i := range pullSecrets {
+		if pullSecrets[i].Name == placeholder {
+			pullSecrets[i].Name = name
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func newManifestsTestOperation() *driver.Operation {
+	return &driver.Operation{
+		Action:       "install",
+		Installation: "mysql",
+		Bundle:       &bundle.Bundle{},
+		Image:        bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "foo/bar"}},
+		Environment: map[string]string{
+			"foo": "bar",
+		},
+		Files: map[string]string{
+			"/cnab/app/someinput": "input value",
+		},
+	}
+}
+
+func TestDriver_RenderManifests(t *testing.T) {
+	sharedDir, err := ioutil.TempDir("", "cnab-go")
+	require.NoError(t, err, "could not create test directory")
+	defer os.RemoveAll(sharedDir)
+
+	k := Driver{
+		Namespace:     "default",
+		JobVolumePath: sharedDir,
+		JobVolumeName: "cnab-driver-shared",
+	}
+
+	m, err := k.RenderManifests(newManifestsTestOperation())
+	require.NoError(t, err)
+
+	require.NotNil(t, m.Secret, "expected a secret for the environment variables")
+	assert.Equal(t, "install-mysql-env-", m.Secret.GenerateName)
+	assert.Equal(t, map[string]string{"foo": "bar"}, m.Secret.StringData)
+
+	require.NotNil(t, m.Job)
+	assert.Equal(t, "install-mysql-", m.Job.GenerateName)
+	assert.Equal(t, "default", m.Job.Namespace)
+	containers := m.Job.Spec.Template.Spec.Containers
+	require.Len(t, containers, 1)
+	assert.Equal(t, "foo/bar", containers[0].Image)
+	assert.Equal(t, v1.TerminationMessageFallbackToLogsOnError, containers[0].TerminationMessagePolicy)
+	require.Len(t, containers[0].EnvFrom, 1)
+	assert.Equal(t, "install-mysql-env-", containers[0].EnvFrom[0].SecretRef.Name, "expected the job to reference the secret by its generate name")
+	require.Len(t, containers[0].VolumeMounts, 2)
+	assert.Equal(t, "/cnab/app/someinput", containers[0].VolumeMounts[1].MountPath)
+	assert.Equal(t, "inputs/cnab/app/someinput", containers[0].VolumeMounts[1].SubPath)
+
+	_, err = os.Stat(filepath.Join(sharedDir, "inputs/cnab/app/someinput"))
+	assert.True(t, os.IsNotExist(err), "expected the files to not be written to the shared volume")
+
+	t.Run("worker pod", func(t *testing.T) {
+		k := Driver{Namespace: "default", WorkerPod: "cnab-worker"}
+		_, err := k.RenderManifests(newManifestsTestOperation())
+		require.EqualError(t, err, "the driver runs operations in the worker pod cnab-worker and does not create any resources")
+	})
+}
+
+func TestDriver_DryRun(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := action.(k8stesting.CreateAction).GetObject().(*v1.Secret).DeepCopy()
+		secret.Name = secret.GenerateName + "abcde"
+		return true, secret, nil
+	})
+
+	namespace := "default"
+	k := Driver{
+		Namespace:     namespace,
+		jobs:          client.BatchV1().Jobs(namespace),
+		secrets:       client.CoreV1().Secrets(namespace),
+		pods:          client.CoreV1().Pods(namespace),
+		JobVolumeName: "cnab-driver-shared",
+	}
+
+	m, err := k.DryRun(newManifestsTestOperation())
+	require.NoError(t, err)
+
+	assert.Equal(t, "install-mysql-env-abcde", m.Secret.Name)
+	assert.Equal(t, "install-mysql-env-abcde", m.Job.Spec.Template.Spec.Containers[0].EnvFrom[0].SecretRef.Name,
+		"expected the job to reference the secret by its generated name")
+
+	var creates int
+	for _, action := range client.Actions() {
+		create, ok := action.(k8stesting.CreateActionImpl)
+		if !ok {
+			continue
+		}
+		creates++
+		assert.Equal(t, []string{metav1.DryRunAll}, create.GetCreateOptions().DryRun,
+			"expected the %s to be created with a dry run", action.GetResource().Resource)
+	}
+	assert.Equal(t, 2, creates, "expected the secret and job to be submitted")
+}
+
+func TestDriver_RenderManifests_SecretFiles(t *testing.T) {
+	k := Driver{
+		Namespace:     "default",
+		FileInjection: FileInjectionSecret,
+	}
+
+	m, err := k.RenderManifests(newManifestsTestOperation())
+	require.NoError(t, err)
+
+	require.NotNil(t, m.FilesSecret, "expected a secret for the files")
+	assert.Equal(t, "install-mysql-files-", m.FilesSecret.GenerateName)
+	assert.Equal(t, map[string]string{"file-0": "input value"}, m.FilesSecret.StringData)
+
+	podSpec := m.Job.Spec.Template.Spec
+	for _, volume := range podSpec.Volumes {
+		assert.Nil(t, volume.PersistentVolumeClaim, "the shared job volume should not be used")
+	}
+	require.Len(t, podSpec.Volumes, 2)
+	assert.Equal(t, "install-mysql-files-", podSpec.Volumes[1].Secret.SecretName)
+
+	containers := podSpec.Containers
+	require.Len(t, containers, 1)
+	require.Len(t, containers[0].VolumeMounts, 2)
+	assert.Equal(t, v1.VolumeMount{Name: outputsVolumeName, MountPath: "/cnab/app/outputs"}, containers[0].VolumeMounts[0])
+	assert.Equal(t, v1.VolumeMount{Name: inputsVolumeName, MountPath: "/cnab/app/someinput", SubPath: "file-0", ReadOnly: true}, containers[0].VolumeMounts[1])
+
+	require.Len(t, podSpec.InitContainers, 1, "expected a sidecar to collect the outputs")
+	sidecar := podSpec.InitContainers[0]
+	assert.Equal(t, outputsContainerName, sidecar.Name)
+	assert.Equal(t, DefaultOutputsImage, sidecar.Image)
+	require.NotNil(t, sidecar.RestartPolicy)
+	assert.Equal(t, v1.ContainerRestartPolicyAlways, *sidecar.RestartPolicy)
+
+	m.setFilesSecretName("install-mysql-files-abc")
+	assert.Equal(t, "install-mysql-files-abc", m.Job.Spec.Template.Spec.Volumes[1].Secret.SecretName)
+}
+
+func TestDriver_RenderManifests_RegistryAuth(t *testing.T) {
+	k := Driver{
+		Namespace:     "default",
+		JobVolumePath: "/tmp",
+		JobVolumeName: "cnab-driver-shared",
+	}
+
+	op := newManifestsTestOperation()
+	op.Image.Image = "example.com/foo/bar:v1"
+	op.RegistryAuth = &driver.RegistryAuth{Username: "me", Password: "secret"}
+
+	m, err := k.RenderManifests(op)
+	require.NoError(t, err)
+
+	require.NotNil(t, m.PullSecret, "expected a secret to pull the invocation image")
+	assert.Equal(t, "install-mysql-pull-", m.PullSecret.GenerateName)
+	assert.Equal(t, v1.SecretTypeDockerConfigJson, m.PullSecret.Type)
+	assert.JSONEq(t, `{"auths": {"example.com": {"username": "me", "password": "secret", "auth": "bWU6c2VjcmV0"}}}`,
+		string(m.PullSecret.Data[v1.DockerConfigJsonKey]))
+
+	pullSecrets := m.Job.Spec.Template.Spec.ImagePullSecrets
+	assert.Equal(t, []v1.LocalObjectReference{{Name: "install-mysql-pull-"}}, pullSecrets)
+
+	m.setPullSecretName("install-mysql-pull-abc")
+	assert.Equal(t, "install-mysql-pull-abc", m.Job.Spec.Template.Spec.ImagePullSecrets[0].Name)
+
+	t.Run("invalid", func(t *testing.T) {
+		op.RegistryAuth = &driver.RegistryAuth{Token: "abc123"}
+		_, err := k.RenderManifests(op)
+		require.EqualError(t, err, "invalid registry auth: a token requires a username to be stored in a docker config")
+	})
+}
@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/cnabio/cnab-go/driver"
+)
+
+// generateObjectMeta returns the metadata for the resources created for the
+// operation. The labels and annotations from the operation are merged with
+// those set on the driver, and are validated before any resources are
+// created, so that an invalid value does not leave behind a partially
+// created operation.
+func (k *Driver) generateObjectMeta(op *driver.Operation) (metav1.ObjectMeta, error) {
+	meta := metav1.ObjectMeta{
+		Namespace:    k.Namespace,
+		GenerateName: generateNameTemplate(op),
+		Labels: map[string]string{
+			driver.LabelDriver: "kubernetes",
+		},
+		Annotations: generateMergedAnnotations(op, k.Annotations),
+	}
+
+	// Apply custom labels
+	for _, l := range k.Labels {
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) > 1 {
+			meta.Labels[parts[0]] = parts[1]
+		}
+	}
+
+	// Apply the labels and annotations from the operation, which may not
+	// override the labels and annotations reserved for the driver
+	for key, value := range op.Labels {
+		if strings.HasPrefix(key, cnabPrefix) {
+			return metav1.ObjectMeta{}, errors.Errorf("invalid operation label %s: labels with the prefix %s are reserved", key, cnabPrefix)
+		}
+		meta.Labels[key] = value
+	}
+	for key, value := range op.Annotations {
+		if strings.HasPrefix(key, cnabPrefix) {
+			return metav1.ObjectMeta{}, errors.Errorf("invalid operation annotation %s: annotations with the prefix %s are reserved", key, cnabPrefix)
+		}
+		meta.Annotations[key] = value
+	}
+
+	errs := validation.ValidateLabels(meta.Labels, field.NewPath("metadata", "labels"))
+	errs = append(errs, apivalidation.ValidateAnnotations(meta.Annotations, field.NewPath("metadata", "annotations"))...)
+	if len(errs) > 0 {
+		return metav1.ObjectMeta{}, errors.Wrap(errs.ToAggregate(), "invalid labels or annotations for the operation")
+	}
+
+	return meta, nil
+}
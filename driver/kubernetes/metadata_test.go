@@ -0,0 +1,172 @@
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+func TestDriver_GenerateObjectMeta(t *testing.T) {
+	k := Driver{
+		Namespace:   "default",
+		Labels:      []string{"team=data"},
+		Annotations: map[string]string{"owner": "data-team"},
+	}
+
+	testcases := []struct {
+		name            string
+		labels          map[string]string
+		annotations     map[string]string
+		wantLabels      map[string]string
+		wantAnnotations map[string]string
+		wantErr         string
+	}{
+		{
+			name:        "merged",
+			labels:      map[string]string{"ci.example.com/run-id": "1234", "team": "platform"},
+			annotations: map[string]string{"cost-center": "cc-42"},
+			wantLabels: map[string]string{
+				driver.LabelDriver:      "kubernetes",
+				"team":                  "platform",
+				"ci.example.com/run-id": "1234",
+			},
+			wantAnnotations: map[string]string{
+				driver.LabelInstallation: "mysql",
+				driver.LabelAction:       "install",
+				driver.LabelRevision:     "01E2ZZ2FEPK5HP8SQ0BEE6FWHB",
+				"owner":                  "data-team",
+				"cost-center":            "cc-42",
+			},
+		},
+		{
+			name:    "invalid label value",
+			labels:  map[string]string{"tenant": "not a valid value"},
+			wantErr: "invalid labels or annotations for the operation",
+		},
+		{
+			name:    "invalid label key",
+			labels:  map[string]string{"-tenant": "a"},
+			wantErr: "invalid labels or annotations for the operation",
+		},
+		{
+			name:        "invalid annotation key",
+			annotations: map[string]string{"cost center": "cc-42"},
+			wantErr:     "invalid labels or annotations for the operation",
+		},
+		{
+			name:    "reserved label",
+			labels:  map[string]string{driver.LabelDriver: "docker"},
+			wantErr: "labels with the prefix cnab.io/ are reserved",
+		},
+		{
+			name:        "reserved annotation",
+			annotations: map[string]string{driver.LabelRevision: "abc"},
+			wantErr:     "annotations with the prefix cnab.io/ are reserved",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			op := &driver.Operation{
+				Installation: "mysql",
+				Action:       "install",
+				Revision:     "01E2ZZ2FEPK5HP8SQ0BEE6FWHB",
+				Labels:       tc.labels,
+				Annotations:  tc.annotations,
+			}
+
+			meta, err := k.generateObjectMeta(op)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantLabels, meta.Labels)
+			assert.Equal(t, tc.wantAnnotations, meta.Annotations)
+		})
+	}
+}
+
+func TestDriver_GenerateObjectMeta_WellKnownLabels(t *testing.T) {
+	k := Driver{Namespace: "default"}
+	op := &driver.Operation{
+		Installation:  "mysql",
+		Action:        "upgrade",
+		Revision:      "01E2ZZ2FEPK5HP8SQ0BEE6FWHB",
+		CorrelationID: "req-1234",
+		Bundle:        &bundle.Bundle{Name: "mysql", Version: "1.0.0+build.1"},
+	}
+
+	meta, err := k.generateObjectMeta(op)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{driver.LabelDriver: "kubernetes"}, meta.Labels)
+	assert.Equal(t, map[string]string{
+		driver.LabelInstallation:  "mysql",
+		driver.LabelAction:        "upgrade",
+		driver.LabelRevision:      "01E2ZZ2FEPK5HP8SQ0BEE6FWHB",
+		driver.LabelBundleName:    "mysql",
+		driver.LabelBundleVersion: "1.0.0+build.1",
+		driver.LabelCorrelationID: "req-1234",
+	}, meta.Annotations, "the operation labels should be applied as annotations")
+	assert.Equal(t, driver.NewOperationLabels("", op), driver.ParseOperationLabels(meta.Annotations))
+}
+
+func TestDriver_Run_OperationLabels(t *testing.T) {
+	ctx := context.Background()
+	sharedDir, err := ioutil.TempDir("", "cnab-go")
+	require.NoError(t, err, "could not create test directory")
+	defer os.RemoveAll(sharedDir)
+
+	client := fake.NewSimpleClientset()
+	namespace := "default"
+	k := Driver{
+		Namespace:          namespace,
+		jobs:               client.BatchV1().Jobs(namespace),
+		secrets:            client.CoreV1().Secrets(namespace),
+		pods:               client.CoreV1().Pods(namespace),
+		JobVolumePath:      sharedDir,
+		JobVolumeName:      "cnab-driver-shared",
+		SkipCleanup:        true,
+		skipJobStatusCheck: true,
+	}
+	op := driver.Operation{
+		Action:      "install",
+		Bundle:      &bundle.Bundle{},
+		Image:       bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "foo/bar"}},
+		Out:         ioutil.Discard,
+		Environment: map[string]string{"foo": "bar"},
+		Labels:      map[string]string{"tenant": "acme"},
+		Annotations: map[string]string{"cost-center": "cc-42"},
+	}
+
+	_, err = k.Run(&op)
+	require.NoError(t, err)
+
+	jobList, err := k.jobs.List(ctx, metav1.ListOptions{LabelSelector: "tenant=acme"})
+	require.NoError(t, err)
+	require.Len(t, jobList.Items, 1, "expected the job to have the operation's labels")
+	job := jobList.Items[0]
+	assert.Equal(t, "cc-42", job.Annotations["cost-center"])
+	assert.Equal(t, "acme", job.Spec.Template.Labels["tenant"], "expected the pod to have the operation's labels")
+
+	secretList, err := k.secrets.List(ctx, metav1.ListOptions{LabelSelector: "tenant=acme"})
+	require.NoError(t, err)
+	assert.Len(t, secretList.Items, 1, "expected the secret to have the operation's labels")
+
+	op.Labels = map[string]string{"tenant": "not valid"}
+	_, err = k.Run(&op)
+	require.Error(t, err)
+	jobList, err = k.jobs.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, jobList.Items, 1, "no job should be created when the labels are invalid")
+}
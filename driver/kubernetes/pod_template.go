@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// parsePodTemplate parses a pod template overlay from YAML or JSON.
+func parsePodTemplate(data string) (*v1.PodTemplateSpec, error) {
+	templateJSON, err := yaml.ToJSON([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &v1.PodTemplateSpec{}
+	err = json.Unmarshal(templateJSON, tmpl)
+	return tmpl, err
+}
+
+// applyPodTemplate merges the overlay into the pod template of the bundle's
+// job using a strategic merge patch, the same semantics as kubectl patch.
+// For example, containers are merged by name so the invocation container can
+// be customized, while initContainers and imagePullSecrets are added.
+func applyPodTemplate(tmpl *v1.PodTemplateSpec, overlay *v1.PodTemplateSpec) error {
+	if overlay == nil {
+		return nil
+	}
+
+	original, err := json.Marshal(tmpl)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the job's pod template")
+	}
+
+	patch, err := podTemplatePatch(overlay)
+	if err != nil {
+		return err
+	}
+
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, v1.PodTemplateSpec{})
+	if err != nil {
+		return errors.Wrap(err, "error merging the pod template overlay into the job's pod template")
+	}
+
+	result := v1.PodTemplateSpec{}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return errors.Wrap(err, "error unmarshaling the merged pod template")
+	}
+
+	*tmpl = result
+	return nil
+}
+
+// podTemplatePatch converts the overlay into a patch document. Fields that
+// are unset on the overlay are marshaled as null, which a strategic merge
+// patch would treat as a deletion, so they are removed from the patch.
+func podTemplatePatch(overlay *v1.PodTemplateSpec) ([]byte, error) {
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling the pod template overlay")
+	}
+
+	var patch map[string]interface{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling the pod template overlay")
+	}
+
+	return json.Marshal(removeNulls(patch))
+}
+
+// removeNulls recursively removes null values from a JSON document.
+func removeNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = removeNulls(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = removeNulls(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const testPodTemplate = `
+metadata:
+  labels:
+    team: platform
+spec:
+  nodeSelector:
+    disktype: ssd
+  priorityClassName: high
+  imagePullSecrets:
+  - name: regcred
+  initContainers:
+  - name: setup
+    image: busybox
+  securityContext:
+    runAsNonRoot: true
+  containers:
+  - name: invocation
+    securityContext:
+      readOnlyRootFilesystem: true
+`
+
+func TestDriver_RunWithPodTemplate(t *testing.T) {
+	ctx := context.Background()
+	sharedDir, err := ioutil.TempDir("", "cnab-go")
+	require.NoError(t, err, "could not create test directory")
+	defer os.RemoveAll(sharedDir)
+
+	tmpl, err := parsePodTemplate(testPodTemplate)
+	require.NoError(t, err, "could not parse the pod template")
+
+	client := fake.NewSimpleClientset()
+	namespace := "default"
+	k := Driver{
+		Namespace:          namespace,
+		jobs:               client.BatchV1().Jobs(namespace),
+		secrets:            client.CoreV1().Secrets(namespace),
+		pods:               client.CoreV1().Pods(namespace),
+		JobVolumePath:      sharedDir,
+		JobVolumeName:      "cnab-driver-shared",
+		Tolerations:        []v1.Toleration{{Key: "dedicated", Value: "cnab"}},
+		PodTemplate:        tmpl,
+		SkipCleanup:        true,
+		skipJobStatusCheck: true,
+	}
+	op := driver.Operation{
+		Action: "install",
+		Bundle: &bundle.Bundle{},
+		Image:  bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "foo/bar"}},
+		Out:    os.Stdout,
+	}
+
+	_, err = k.Run(&op)
+	require.NoError(t, err)
+
+	jobList, err := k.jobs.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, jobList.Items, 1, "expected one job to be created")
+
+	podTmpl := jobList.Items[0].Spec.Template
+	assert.Equal(t, "platform", podTmpl.Labels["team"], "labels from the overlay should be added")
+	assert.Equal(t, "kubernetes", podTmpl.Labels["cnab.io/driver"], "labels generated by the driver should be kept")
+
+	spec := podTmpl.Spec
+	assert.Equal(t, map[string]string{"disktype": "ssd"}, spec.NodeSelector)
+	assert.Equal(t, "high", spec.PriorityClassName)
+	assert.Equal(t, []v1.LocalObjectReference{{Name: "regcred"}}, spec.ImagePullSecrets)
+	require.Len(t, spec.InitContainers, 1)
+	assert.Equal(t, "busybox", spec.InitContainers[0].Image)
+	require.NotNil(t, spec.SecurityContext)
+	assert.True(t, *spec.SecurityContext.RunAsNonRoot)
+	assert.Equal(t, v1.RestartPolicyNever, spec.RestartPolicy, "fields generated by the driver should be kept")
+	assert.Equal(t, k.Tolerations, spec.Tolerations)
+	require.Len(t, spec.Volumes, 1, "the shared volume should be kept")
+
+	require.Len(t, spec.Containers, 1, "the invocation container should be merged, not duplicated")
+	invocation := spec.Containers[0]
+	assert.Equal(t, "foo/bar", invocation.Image)
+	assert.Equal(t, []string{"/cnab/app/run"}, invocation.Command)
+	require.NotNil(t, invocation.SecurityContext)
+	assert.True(t, *invocation.SecurityContext.ReadOnlyRootFilesystem)
+}
+
+func TestDriver_SetConfig_PodTemplate(t *testing.T) {
+	settings := map[string]string{
+		SettingKubeNamespace: "default",
+		SettingJobVolumeName: "cnab-driver-shared",
+		SettingJobVolumePath: "/tmp",
+		SettingPodTemplate:   testPodTemplate,
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		d := Driver{}
+		err := d.SetConfig(settings)
+		require.NoError(t, err)
+
+		require.NotNil(t, d.PodTemplate)
+		assert.Equal(t, "high", d.PodTemplate.Spec.PriorityClassName)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		d := Driver{}
+		settings[SettingPodTemplate] = "spec: [oops"
+		err := d.SetConfig(settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid value for POD_TEMPLATE")
+	})
+}
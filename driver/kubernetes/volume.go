@@ -0,0 +1,161 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// SettingMinVolumeFreeSpace is the minimum free space, as a Kubernetes
+// quantity such as 500Mi, that the shared job volume must have before the
+// driver starts an operation.
+const SettingMinVolumeFreeSpace = "MIN_VOLUME_FREE_SPACE"
+
+// minVolumeFreeSpaceAfterFailure is the free space below which the shared job
+// volume is considered full after the bundle failed, when MinVolumeFreeSpace
+// is not set. Writes fail well before the volume has no free space left at
+// all, e.g. when the space left is smaller than a filesystem block.
+const minVolumeFreeSpaceAfterFailure = 1024 * 1024
+
+// ErrVolumeFull is matched by a VolumeFullError with errors.Is.
+var ErrVolumeFull = errors.New("the shared job volume is full")
+
+// VolumeUsage is the disk usage of the shared job volume, in bytes.
+type VolumeUsage struct {
+	// Total size of the volume.
+	Total int64
+
+	// Free space available to the driver.
+	Free int64
+}
+
+// Used returns the space used on the volume.
+func (u VolumeUsage) Used() int64 {
+	return u.Total - u.Free
+}
+
+func (u VolumeUsage) String() string {
+	return fmt.Sprintf("%s free of %s", formatBytes(u.Free), formatBytes(u.Total))
+}
+
+// VolumeFullError is returned when the shared job volume does not have
+// enough free space to write the operation's files, or for the bundle to
+// write its outputs, instead of the underlying I/O error.
+type VolumeFullError struct {
+	// Volume is the name of the persistent volume claim, JobVolumeName.
+	Volume string
+
+	// Path that was being written, when the volume filled up.
+	Path string
+
+	// Usage of the volume when the error was detected. It is zero when the
+	// usage could not be determined.
+	Usage VolumeUsage
+
+	// Required is the minimum free space that was requested with
+	// MinVolumeFreeSpace, when the volume failed the preflight check.
+	Required int64
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e VolumeFullError) Error() string {
+	msg := fmt.Sprintf("the shared job volume %s is full", e.Volume)
+	if e.Required > 0 {
+		msg = fmt.Sprintf("the shared job volume %s does not have the required free space of %s", e.Volume, formatBytes(e.Required))
+	}
+	if e.Path != "" {
+		msg += fmt.Sprintf(" while writing %s", e.Path)
+	}
+	if e.Usage.Total > 0 {
+		msg += fmt.Sprintf(" (%s)", e.Usage)
+	}
+	if e.Err != nil {
+		msg += fmt.Sprintf(": %v", e.Err)
+	}
+	return msg
+}
+
+// Is matches ErrVolumeFull.
+func (e VolumeFullError) Is(target error) bool {
+	return target == ErrVolumeFull
+}
+
+func (e VolumeFullError) Unwrap() error {
+	return e.Err
+}
+
+// VolumeUsage returns the disk usage of the shared job volume, which is
+// mounted locally at JobVolumePath.
+func (k *Driver) VolumeUsage() (VolumeUsage, error) {
+	return statVolume(k.JobVolumePath)
+}
+
+// CheckVolumeFreeSpace is a preflight check that returns a VolumeFullError
+// when the shared job volume has less free space than MinVolumeFreeSpace.
+// It is run before the job of each operation is started, and does nothing
+// when MinVolumeFreeSpace is zero.
+func (k *Driver) CheckVolumeFreeSpace() error {
+	required := k.MinVolumeFreeSpace.Value()
+	if required <= 0 {
+		return nil
+	}
+
+	usage, err := k.VolumeUsage()
+	if err != nil {
+		return errors.Wrapf(err, "error checking the free space of the shared job volume %s", k.JobVolumeName)
+	}
+	if usage.Free < required {
+		return VolumeFullError{Volume: k.JobVolumeName, Usage: usage, Required: required}
+	}
+	return nil
+}
+
+// volumeError returns a VolumeFullError, with the usage of the volume, when
+// writing the path failed because the shared job volume is full, and
+// otherwise returns the error unchanged.
+func (k *Driver) volumeError(path string, err error) error {
+	if err == nil || !isNoSpaceError(err) {
+		return err
+	}
+
+	usage, _ := k.VolumeUsage()
+	return VolumeFullError{Volume: k.JobVolumeName, Path: path, Usage: usage, Err: err}
+}
+
+// checkVolumeAfterFailure returns a VolumeFullError when the bundle failed
+// and left the shared job volume with less free space than
+// MinVolumeFreeSpace, or minVolumeFreeSpaceAfterFailure when it is not set,
+// which is the likely cause of the failure, because the bundle could not
+// write its outputs.
+func (k *Driver) checkVolumeAfterFailure(outputsDir string) error {
+	usage, err := k.VolumeUsage()
+	if err != nil || usage.Total == 0 {
+		return nil
+	}
+
+	required := k.MinVolumeFreeSpace.Value()
+	if required <= 0 {
+		required = minVolumeFreeSpaceAfterFailure
+	}
+	if usage.Free >= required {
+		return nil
+	}
+	return VolumeFullError{Volume: k.JobVolumeName, Path: outputsDir, Usage: usage}
+}
+
+// parseMinVolumeFreeSpace parses the value of SettingMinVolumeFreeSpace.
+func parseMinVolumeFreeSpace(value string) (resource.Quantity, error) {
+	q, err := resource.ParseQuantity(value)
+	if err != nil || q.Sign() < 0 {
+		return resource.Quantity{}, errors.Errorf("invalid value %q for %s, must be a non-negative quantity such as 500Mi", value, SettingMinVolumeFreeSpace)
+	}
+	return q, nil
+}
+
+// formatBytes formats a number of bytes as a binary Kubernetes quantity.
+func formatBytes(n int64) string {
+	return resource.NewQuantity(n, resource.BinarySI).String()
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package kubernetes
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// statVolume returns the disk usage of the filesystem mounted at the path.
+func statVolume(path string) (VolumeUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return VolumeUsage{}, err
+	}
+
+	blockSize := int64(stat.Bsize)
+	return VolumeUsage{
+		Total: int64(stat.Blocks) * blockSize,
+		Free:  int64(stat.Bavail) * blockSize,
+	}, nil
+}
+
+// isNoSpaceError determines if the error was caused by a full filesystem or
+// an exceeded disk quota.
+func isNoSpaceError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT)
+}
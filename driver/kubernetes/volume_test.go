@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestVolumeFullError(t *testing.T) {
+	usage := VolumeUsage{Total: 1024 * 1024 * 1024, Free: 0}
+	err := VolumeFullError{
+		Volume: "cnab-driver-shared",
+		Path:   "/mnt/cnab/inputs/cnab/app/image-map.json",
+		Usage:  usage,
+		Err:    syscall.ENOSPC,
+	}
+	assert.EqualError(t, err, "the shared job volume cnab-driver-shared is full while writing /mnt/cnab/inputs/cnab/app/image-map.json (0 free of 1Gi): "+syscall.ENOSPC.Error())
+	assert.ErrorIs(t, err, ErrVolumeFull)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	assert.Equal(t, int64(1024*1024*1024), usage.Used())
+
+	preflightErr := VolumeFullError{Volume: "cnab-driver-shared", Usage: VolumeUsage{Total: 1024 * 1024 * 1024, Free: 1024 * 1024}, Required: 100 * 1024 * 1024}
+	assert.EqualError(t, preflightErr, "the shared job volume cnab-driver-shared does not have the required free space of 100Mi (1Mi free of 1Gi)")
+}
+
+func TestDriver_CheckVolumeFreeSpace(t *testing.T) {
+	k := Driver{JobVolumePath: t.TempDir(), JobVolumeName: "cnab-driver-shared"}
+
+	usage, err := k.VolumeUsage()
+	require.NoError(t, err)
+	assert.Greater(t, usage.Total, int64(0))
+
+	t.Run("not set", func(t *testing.T) {
+		assert.NoError(t, k.CheckVolumeFreeSpace())
+	})
+
+	t.Run("enough free space", func(t *testing.T) {
+		k.MinVolumeFreeSpace = resource.MustParse("1")
+		assert.NoError(t, k.CheckVolumeFreeSpace())
+	})
+
+	t.Run("not enough free space", func(t *testing.T) {
+		k.MinVolumeFreeSpace = resource.MustParse("1Ei")
+		err := k.CheckVolumeFreeSpace()
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrVolumeFull)
+
+		var volumeErr VolumeFullError
+		require.ErrorAs(t, err, &volumeErr)
+		assert.Equal(t, "cnab-driver-shared", volumeErr.Volume)
+		assert.Equal(t, k.MinVolumeFreeSpace.Value(), volumeErr.Required)
+		assert.Greater(t, volumeErr.Usage.Total, int64(0), "the usage of the volume should be reported")
+	})
+
+	t.Run("missing volume", func(t *testing.T) {
+		missing := Driver{JobVolumePath: "/missing/cnab", MinVolumeFreeSpace: resource.MustParse("1Mi")}
+		err := missing.CheckVolumeFreeSpace()
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrVolumeFull)
+	})
+}
+
+func TestDriver_VolumeError(t *testing.T) {
+	k := Driver{JobVolumePath: t.TempDir(), JobVolumeName: "cnab-driver-shared"}
+
+	writeErr := errors.Wrap(&os.PathError{Op: "write", Path: "/mnt/cnab/inputs/file", Err: syscall.ENOSPC}, "error writing file")
+	err := k.volumeError("/mnt/cnab/inputs/file", writeErr)
+	assert.ErrorIs(t, err, ErrVolumeFull)
+	var volumeErr VolumeFullError
+	require.ErrorAs(t, err, &volumeErr)
+	assert.Equal(t, "/mnt/cnab/inputs/file", volumeErr.Path)
+
+	quotaErr := &os.PathError{Op: "write", Path: "/mnt/cnab/inputs/file", Err: syscall.EDQUOT}
+	assert.ErrorIs(t, k.volumeError("/mnt/cnab/inputs/file", quotaErr), ErrVolumeFull)
+
+	otherErr := &os.PathError{Op: "write", Path: "/mnt/cnab/inputs/file", Err: syscall.EACCES}
+	assert.Equal(t, otherErr, k.volumeError("/mnt/cnab/inputs/file", otherErr))
+	assert.NoError(t, k.volumeError("/mnt/cnab/inputs/file", nil))
+}
+
+func TestDriver_CheckVolumeAfterFailure(t *testing.T) {
+	k := Driver{JobVolumePath: t.TempDir(), JobVolumeName: "cnab-driver-shared"}
+
+	assert.NoError(t, k.checkVolumeAfterFailure("/mnt/cnab/outputs"), "a volume with free space should not be reported as full")
+
+	k.MinVolumeFreeSpace = resource.MustParse("1Ei")
+	err := k.checkVolumeAfterFailure("/mnt/cnab/outputs")
+	assert.ErrorIs(t, err, ErrVolumeFull, "a volume with less free space than MinVolumeFreeSpace should be reported as full")
+	var volumeErr VolumeFullError
+	require.ErrorAs(t, err, &volumeErr)
+	assert.Equal(t, "/mnt/cnab/outputs", volumeErr.Path)
+}
@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package kubernetes
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Windows error codes reported when a disk is full.
+const (
+	errorHandleDiskFull syscall.Errno = 39
+	errorDiskFull       syscall.Errno = 112
+)
+
+// statVolume is not supported on Windows.
+func statVolume(path string) (VolumeUsage, error) {
+	return VolumeUsage{}, errors.New("checking the disk usage of the shared job volume is not supported on Windows")
+}
+
+// isNoSpaceError determines if the error was caused by a full disk.
+func isNoSpaceError(err error) bool {
+	return errors.Is(err, errorDiskFull) || errors.Is(err, errorHandleDiskFull) || errors.Is(err, syscall.ENOSPC)
+}
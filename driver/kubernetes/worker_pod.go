@@ -0,0 +1,353 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+const (
+	// SettingWorkerPod is the name of a long-lived pod in which to run
+	// operations via exec, instead of creating a job for each operation.
+	SettingWorkerPod = "WORKER_POD"
+
+	// SettingWorkerPodVolumePath is the path where the shared job volume is
+	// mounted in the worker pod.
+	SettingWorkerPodVolumePath = "WORKER_POD_VOLUME_PATH"
+
+	// DefaultWorkerPodVolumePath is the default path where the shared job
+	// volume is mounted in the worker pod.
+	DefaultWorkerPodVolumePath = "/cnab/worker"
+
+	// workerRunsDir is the directory on the shared volume that holds the
+	// working directory of each run in the worker pod.
+	workerRunsDir = "runs"
+
+	// workerPodLock is the directory in the worker pod that is created by
+	// the run holding the worker pod's lock.
+	workerPodLock = "/tmp/cnab-worker.lock"
+
+	// workerRunStopGracePeriod is the number of seconds that the processes of
+	// a run that is stopped have to exit before they are killed.
+	workerRunStopGracePeriod = 10
+
+	// workerRunStopTimeout is how long stopWorkerRun waits for the run to
+	// stop.
+	workerRunStopTimeout = 30 * time.Second
+)
+
+// envVarNameReg matches environment variable names that can be exported by the
+// worker pod's shell.
+var envVarNameReg = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// PodExecutor runs a command in a container of an existing pod, for example
+// using the SPDY executor from k8s.io/client-go/tools/remotecommand:
+//
+//	req := coreClient.RESTClient().Post().Resource("pods").Namespace(namespace).
+//		Name(pod).SubResource("exec").VersionedParams(&v1.PodExecOptions{
+//			Container: container, Command: command, Stdout: true, Stderr: true,
+//		}, scheme.ParameterCodec)
+//	exec, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+//	...
+//	return exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: stdout, Stderr: stderr})
+type PodExecutor interface {
+	// Exec runs the command in the container and returns an error when it
+	// could not be run or exited with a non-zero exit code.
+	Exec(ctx context.Context, pod string, container string, command []string, stdout io.Writer, stderr io.Writer) error
+}
+
+// runInWorkerPod executes the operation inside of the long-lived WorkerPod.
+//
+// The worker pod's container must run the operation's invocation image. Each
+// run has its own working directory on the shared job volume, holding the
+// environment, input files and the outputs collected after the bundle's
+// entrypoint exits. Runs in the same worker pod share its filesystem, so each
+// run holds a lock in the pod while it runs, and concurrent operations wait
+// for their turn.
+func (k *Driver) runInWorkerPod(ctx context.Context, op *driver.Operation) (driver.OperationResult, error) {
+	if k.PodExecutor == nil {
+		return driver.OperationResult{}, errors.Errorf("a PodExecutor is required to run operations in the worker pod %s", k.WorkerPod)
+	}
+
+	pod, err := k.pods.Get(ctx, k.WorkerPod, metav1.GetOptions{})
+	if err != nil {
+		return driver.OperationResult{}, errors.Wrapf(err, "could not retrieve the worker pod %s", k.WorkerPod)
+	}
+	if pod.Status.Phase != v1.PodRunning {
+		return driver.OperationResult{}, errors.Errorf("the worker pod %s is not running, its phase is %s", k.WorkerPod, pod.Status.Phase)
+	}
+	container := workerPodContainer(pod)
+	if err := checkWorkerPodImage(pod, container, op.Image); err != nil {
+		return driver.OperationResult{}, err
+	}
+
+	runName := generateNameTemplate(op) + strconv.FormatInt(time.Now().UnixNano(), 36)
+	runDir := filepath.Join(k.JobVolumePath, workerRunsDir, runName)
+	if err := prepareWorkerRunDir(runDir, op); err != nil {
+		return driver.OperationResult{}, k.volumeError(runDir, errors.Wrapf(err, "error preparing the working directory for the run on the shared job volume %s", k.JobVolumeName))
+	}
+	if !k.SkipCleanup {
+		defer os.RemoveAll(runDir)
+	}
+
+	if k.ActiveDeadlineSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(k.ActiveDeadlineSeconds)*time.Second)
+		defer cancel()
+	}
+	ctx, cancel := op.WithDeadline(ctx)
+	defer cancel()
+
+	volumePath := k.WorkerPodVolumePath
+	if volumePath == "" {
+		volumePath = DefaultWorkerPodVolumePath
+	}
+	podRunDir := path.Join(volumePath, workerRunsDir, runName)
+	script := generateWorkerScript(podRunDir, op)
+
+	out := op.Out
+	if out == nil {
+		out = ioutil.Discard
+	}
+	errOut := op.Err
+	if errOut == nil {
+		errOut = out
+	}
+
+	var opErr *multierror.Error
+	err = k.PodExecutor.Exec(ctx, k.WorkerPod, container, []string{"/bin/sh", "-c", script}, out, errOut)
+	if err != nil {
+		opErr = multierror.Append(opErr, errors.Wrapf(err, "run %s in worker pod %s failed", runName, k.WorkerPod))
+		if ctx.Err() != nil {
+			if stopErr := k.stopWorkerRun(container, podRunDir); stopErr != nil {
+				opErr = multierror.Append(opErr, stopErr)
+			}
+		}
+		if volumeErr := k.checkVolumeAfterFailure(filepath.Join(runDir, "outputs")); volumeErr != nil {
+			opErr = multierror.Append(opErr, volumeErr)
+		}
+		if op.HasDeadline() && !time.Now().Before(op.Deadline) {
+			opErr = multierror.Append(opErr, op.DeadlineError())
+		}
+	}
+
+	opResult, err := k.fetchOutputsFrom(op, filepath.Join(runDir, "outputs"))
+	if err != nil {
+		opErr = multierror.Append(opErr, err)
+	}
+
+	return opResult, opErr.ErrorOrNil()
+}
+
+// workerPodContainer returns the name of the container in the worker pod
+// that runs the invocation image, preferring one named "invocation".
+func workerPodContainer(pod *v1.Pod) string {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == k8sContainerName {
+			return c.Name
+		}
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Name
+	}
+	return k8sContainerName
+}
+
+// checkWorkerPodImage returns an error when the container of the worker pod
+// does not run the invocation image, so that a bundle never runs the code of
+// another bundle. When the invocation image has a digest, the digest of the
+// image that the container is running must match it, otherwise the
+// container's image must be the same reference.
+func checkWorkerPodImage(pod *v1.Pod, container string, img bundle.InvocationImage) error {
+	var containerImage, containerImageID string
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			containerImage = c.Image
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			containerImageID = status.ImageID
+		}
+	}
+
+	mismatch := driver.NewImageError(img.Image, errors.Errorf("the container %s of the worker pod %s runs the image %s instead of the invocation image", container, pod.Name, containerImage))
+
+	want, err := imageWithDigest(img)
+	if err != nil {
+		return driver.NewImageError(img.Image, err)
+	}
+	wantRef, err := reference.ParseNormalizedNamed(want)
+	if err != nil {
+		return driver.NewImageError(img.Image, errors.Wrapf(err, "could not parse %s as an OCI reference", want))
+	}
+	gotRef, err := reference.ParseNormalizedNamed(containerImage)
+	if err != nil {
+		return mismatch
+	}
+
+	if wantDigested, ok := wantRef.(reference.Digested); ok {
+		d := wantDigested.Digest().String()
+		if gotDigested, ok := gotRef.(reference.Digested); ok && gotDigested.Digest().String() == d {
+			return nil
+		}
+		// The image ID reported for the container ends with the digest of
+		// the image that was pulled, e.g. docker.io/foo/bar@sha256:...
+		if strings.HasSuffix(containerImageID, "@"+d) {
+			return nil
+		}
+		return mismatch
+	}
+
+	if reference.TagNameOnly(gotRef).String() != reference.TagNameOnly(wantRef).String() {
+		return mismatch
+	}
+	return nil
+}
+
+// prepareWorkerRunDir writes the operation's environment and files to the
+// run's working directory on the shared volume.
+func prepareWorkerRunDir(runDir string, op *driver.Operation) error {
+	if err := os.MkdirAll(filepath.Join(runDir, "outputs"), 0700); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(op.Environment))
+	for name := range op.Environment {
+		if !envVarNameReg.MatchString(name) {
+			return errors.Errorf("invalid environment variable name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var env strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&env, "export %s=%s\n", name, shellQuote(op.Environment[name]))
+	}
+	if err := ioutil.WriteFile(filepath.Join(runDir, "env"), []byte(env.String()), 0600); err != nil {
+		return err
+	}
+
+	for inputRelPath, contents := range op.Files {
+		inputPath := filepath.Join(runDir, "inputs", inputRelPath)
+		if err := os.MkdirAll(filepath.Dir(inputPath), 0700); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(inputPath, []byte(contents), 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateWorkerScript returns the shell script that runs the operation in
+// the worker pod, where runDir is the path of the run's working directory in
+// the pod. The script first takes the worker pod's lock, waiting while
+// another run holds it, since the input files and /cnab/app/outputs are
+// shared by every run. The bundle's entrypoint runs in its own process group,
+// so that stopping the run with stopWorkerRun also stops the processes that
+// it started. The outputs are copied to the run's working directory even when
+// the bundle fails, and the script exits with the bundle's exit code. When
+// the script exits, the input files, which hold the credentials and
+// parameters, and /cnab/app/outputs are removed, so that the next run cannot
+// read them.
+func generateWorkerScript(runDir string, op *driver.Operation) string {
+	files := make([]string, 0, len(op.Files))
+	for inputPath := range op.Files {
+		files = append(files, inputPath)
+	}
+	sort.Strings(files)
+
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	fmt.Fprintf(&script, "echo $$ > %s\n", shellQuote(path.Join(runDir, "pid")))
+	// Run the EXIT trap when the run is stopped
+	script.WriteString("trap 'exit 143' TERM INT HUP\n")
+	fmt.Fprintf(&script, "lock=%s\n", shellQuote(workerPodLock))
+	// mkdir is atomic, so only one run acquires the lock. A lock left behind
+	// by a run that was killed is released once its process no longer exists.
+	script.WriteString("while ! mkdir \"$lock\" 2>/dev/null; do\n")
+	script.WriteString("  owner=$(cat \"$lock/pid\" 2>/dev/null || true)\n")
+	script.WriteString("  if [ -n \"$owner\" ] && ! kill -0 \"$owner\" 2>/dev/null && [ \"$(cat \"$lock/pid\" 2>/dev/null || true)\" = \"$owner\" ]; then rm -rf \"$lock\"; fi\n")
+	script.WriteString("  sleep 1\n")
+	script.WriteString("done\n")
+	script.WriteString("pid=\n")
+	script.WriteString("cleanup() {\n")
+	script.WriteString("  set +e\n")
+	script.WriteString("  if [ -n \"$pid\" ]; then\n")
+	script.WriteString("    kill -TERM -\"$pid\" 2>/dev/null || kill -TERM \"$pid\" 2>/dev/null\n")
+	fmt.Fprintf(&script, "    (sleep %d; kill -KILL -\"$pid\" 2>/dev/null || kill -KILL \"$pid\" 2>/dev/null) >/dev/null 2>&1 &\n", workerRunStopGracePeriod)
+	script.WriteString("    watchdog=$!\n")
+	script.WriteString("    wait \"$pid\"\n")
+	script.WriteString("    kill \"$watchdog\" 2>/dev/null\n")
+	script.WriteString("    kill -KILL -\"$pid\" 2>/dev/null\n")
+	script.WriteString("  fi\n")
+	for _, inputPath := range files {
+		fmt.Fprintf(&script, "  rm -f %s\n", shellQuote(inputPath))
+	}
+	script.WriteString("  rm -rf /cnab/app/outputs\n")
+	script.WriteString("  rm -rf \"$lock\"\n")
+	script.WriteString("}\n")
+	script.WriteString("trap cleanup EXIT\n")
+	script.WriteString("echo $$ > \"$lock/pid\"\n")
+	fmt.Fprintf(&script, ". %s\n", shellQuote(path.Join(runDir, "env")))
+	for _, inputPath := range files {
+		fmt.Fprintf(&script, "mkdir -p %s\n", shellQuote(path.Dir(inputPath)))
+		fmt.Fprintf(&script, "cp %s %s\n", shellQuote(path.Join(runDir, "inputs", inputPath)), shellQuote(inputPath))
+	}
+	script.WriteString("rm -rf /cnab/app/outputs\n")
+	script.WriteString("mkdir -p /cnab/app/outputs\n")
+	script.WriteString("set +e\n")
+	// Without job control, setsid runs the entrypoint in a new process
+	// group whose id is its pid
+	script.WriteString("if command -v setsid >/dev/null 2>&1; then setsid /cnab/app/run & else /cnab/app/run & fi\n")
+	script.WriteString("pid=$!\n")
+	script.WriteString("wait \"$pid\"\n")
+	script.WriteString("status=$?\n")
+	script.WriteString("pid=\n")
+	fmt.Fprintf(&script, "cp -R /cnab/app/outputs/. %s\n", shellQuote(path.Join(runDir, "outputs")))
+	script.WriteString("exit $status\n")
+	return script.String()
+}
+
+// stopWorkerRun stops the run in the worker pod, whose working directory in
+// the pod is runDir, once its exec stream was interrupted, for example by the
+// deadline of the operation: stopping the stream does not stop the script in
+// the pod, which would keep the worker pod's lock. The script stops the
+// bundle's processes and removes its files before it exits.
+func (k *Driver) stopWorkerRun(container string, runDir string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), workerRunStopTimeout)
+	defer cancel()
+
+	var script strings.Builder
+	fmt.Fprintf(&script, "pid=$(cat %s 2>/dev/null) || exit 0\n", shellQuote(path.Join(runDir, "pid")))
+	script.WriteString("kill -TERM \"$pid\" 2>/dev/null || exit 0\n")
+	script.WriteString("while kill -0 \"$pid\" 2>/dev/null; do sleep 1; done\n")
+	err := k.PodExecutor.Exec(ctx, k.WorkerPod, container, []string{"/bin/sh", "-c", script.String()}, ioutil.Discard, ioutil.Discard)
+	return errors.Wrapf(err, "could not stop the run in worker pod %s", k.WorkerPod)
+}
+
+// shellQuote quotes a value so that it is interpreted literally by the shell.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,354 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/driver"
+)
+
+type testPodExecutor struct {
+	runsDir   string
+	pod       string
+	container string
+	command   []string
+	commands  [][]string
+	err       error
+
+	// waitForCancel runs the operation until its context is canceled.
+	waitForCancel bool
+}
+
+// Exec simulates the bundle writing its output to the run's working directory.
+func (e *testPodExecutor) Exec(ctx context.Context, pod string, container string, command []string, stdout io.Writer, stderr io.Writer) error {
+	e.commands = append(e.commands, command)
+	if len(e.commands) > 1 {
+		// Stopping the run
+		return nil
+	}
+	e.pod = pod
+	e.container = container
+	e.command = command
+	if e.waitForCancel {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	runs, err := ioutil.ReadDir(e.runsDir)
+	if err != nil {
+		return err
+	}
+	if len(runs) != 1 {
+		return errors.New("expected a single run directory")
+	}
+	runDir := filepath.Join(e.runsDir, runs[0].Name())
+	if err := ioutil.WriteFile(filepath.Join(runDir, "outputs", "foo"), []byte("foobar"), 0600); err != nil {
+		return err
+	}
+
+	return e.err
+}
+
+func TestDriver_RunInWorkerPod(t *testing.T) {
+	ctx := context.Background()
+	sharedDir, err := ioutil.TempDir("", "cnab-go")
+	require.NoError(t, err, "could not create test directory")
+	defer os.RemoveAll(sharedDir)
+
+	namespace := "default"
+	client := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: namespace},
+		Spec: v1.PodSpec{Containers: []v1.Container{
+			{Name: "sidecar", Image: "busybox"},
+			{Name: k8sContainerName, Image: "foo/bar"},
+		}},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	})
+
+	executor := &testPodExecutor{runsDir: filepath.Join(sharedDir, workerRunsDir)}
+	k := Driver{
+		Namespace:     namespace,
+		jobs:          client.BatchV1().Jobs(namespace),
+		secrets:       client.CoreV1().Secrets(namespace),
+		pods:          client.CoreV1().Pods(namespace),
+		JobVolumePath: sharedDir,
+		JobVolumeName: "cnab-driver-shared",
+		WorkerPod:     "runner",
+		PodExecutor:   executor,
+	}
+	op := driver.Operation{
+		Action:       "install",
+		Installation: "test",
+		Bundle: &bundle.Bundle{
+			Outputs: map[string]bundle.Output{
+				"foo": {Definition: "foo"},
+			},
+		},
+		Image:       bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "foo/bar"}},
+		Out:         os.Stdout,
+		Environment: map[string]string{"CNAB_ACTION": "install"},
+		Files:       map[string]string{"/cnab/app/config.json": "{}"},
+		Outputs:     map[string]string{"/cnab/app/outputs/foo": "foo"},
+	}
+
+	opResult, err := k.Run(&op)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "foobar"}, opResult.Outputs)
+
+	assert.Equal(t, "runner", executor.pod)
+	assert.Equal(t, k8sContainerName, executor.container)
+	require.Len(t, executor.command, 3)
+	assert.Equal(t, []string{"/bin/sh", "-c"}, executor.command[:2])
+	assert.Contains(t, executor.command[2], "setsid /cnab/app/run &")
+
+	jobList, err := k.jobs.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, jobList.Items, "no job should be created when using a worker pod")
+
+	runs, err := ioutil.ReadDir(executor.runsDir)
+	require.NoError(t, err)
+	assert.Empty(t, runs, "the run's working directory should be cleaned up")
+}
+
+func TestDriver_RunInWorkerPod_Failures(t *testing.T) {
+	sharedDir, err := ioutil.TempDir("", "cnab-go")
+	require.NoError(t, err, "could not create test directory")
+	defer os.RemoveAll(sharedDir)
+
+	namespace := "default"
+	op := driver.Operation{
+		Action: "install",
+		Bundle: &bundle.Bundle{},
+		Image:  bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: "foo/bar"}},
+	}
+
+	newDriver := func(pod *v1.Pod, executor PodExecutor) Driver {
+		client := fake.NewSimpleClientset()
+		if pod != nil {
+			client = fake.NewSimpleClientset(pod)
+		}
+		return Driver{
+			Namespace:     namespace,
+			jobs:          client.BatchV1().Jobs(namespace),
+			secrets:       client.CoreV1().Secrets(namespace),
+			pods:          client.CoreV1().Pods(namespace),
+			JobVolumePath: sharedDir,
+			JobVolumeName: "cnab-driver-shared",
+			WorkerPod:     "runner",
+			PodExecutor:   executor,
+		}
+	}
+	runningPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: namespace},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: k8sContainerName, Image: "foo/bar"}}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+
+	t.Run("missing executor", func(t *testing.T) {
+		k := newDriver(runningPod, nil)
+		_, err := k.Run(&op)
+		require.EqualError(t, err, "a PodExecutor is required to run operations in the worker pod runner")
+	})
+
+	t.Run("missing pod", func(t *testing.T) {
+		k := newDriver(nil, &testPodExecutor{})
+		_, err := k.Run(&op)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not retrieve the worker pod runner")
+	})
+
+	t.Run("pod not running", func(t *testing.T) {
+		pod := runningPod.DeepCopy()
+		pod.Status.Phase = v1.PodPending
+		k := newDriver(pod, &testPodExecutor{})
+		_, err := k.Run(&op)
+		require.EqualError(t, err, "the worker pod runner is not running, its phase is Pending")
+	})
+
+	t.Run("another image", func(t *testing.T) {
+		pod := runningPod.DeepCopy()
+		pod.Spec.Containers[0].Image = "foo/other"
+		k := newDriver(pod, &testPodExecutor{})
+		_, err := k.Run(&op)
+		require.Error(t, err)
+		assert.True(t, driver.IsImageError(err), "expected an image error, got %v", err)
+		assert.Contains(t, err.Error(), "the container invocation of the worker pod runner runs the image foo/other instead of the invocation image")
+	})
+
+	t.Run("bundle failed", func(t *testing.T) {
+		executor := &testPodExecutor{runsDir: filepath.Join(sharedDir, workerRunsDir), err: errors.New("command terminated with exit code 1")}
+		k := newDriver(runningPod, executor)
+		_, err := k.Run(&op)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "command terminated with exit code 1")
+		assert.Len(t, executor.commands, 1, "a run that exited should not be stopped")
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		executor := &testPodExecutor{runsDir: filepath.Join(sharedDir, workerRunsDir), waitForCancel: true}
+		k := newDriver(runningPod, executor)
+		op := op
+		op.Deadline = time.Now().Add(100 * time.Millisecond)
+		_, err := k.Run(&op)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, driver.ErrDeadlineExceeded)
+
+		require.Len(t, executor.commands, 2, "the run should be stopped in the worker pod")
+		stop := executor.commands[1]
+		require.Len(t, stop, 3)
+		pidFile := regexp.MustCompile(`'/cnab/worker/runs/install-[^/']+/pid'`).FindString(executor.command[2])
+		require.NotEmpty(t, pidFile, "the run should record its pid")
+		assert.Contains(t, stop[2], "pid=$(cat "+pidFile+" 2>/dev/null)")
+		assert.Contains(t, stop[2], `kill -TERM "$pid"`)
+	})
+}
+
+func TestCheckWorkerPodImage(t *testing.T) {
+	const digest = "sha256:4d8a7cb8c9b1c0a3b0c5e8e5d9e1f1e4d7c2b9a3c6e5f4d3c2b1a0f9e8d7c6b5"
+	newPod := func(image string, imageID string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "runner"},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: k8sContainerName, Image: image}}},
+			Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{
+				{Name: k8sContainerName, Image: image, ImageID: imageID},
+			}},
+		}
+	}
+	newImage := func(image string, digest string) bundle.InvocationImage {
+		return bundle.InvocationImage{BaseImage: bundle.BaseImage{Image: image, Digest: digest}}
+	}
+
+	testcases := []struct {
+		name    string
+		pod     *v1.Pod
+		image   bundle.InvocationImage
+		wantErr bool
+	}{
+		{name: "same image", pod: newPod("docker.io/foo/bar:latest", ""), image: newImage("foo/bar", "")},
+		{name: "same tag", pod: newPod("foo/bar:v1", ""), image: newImage("docker.io/foo/bar:v1", "")},
+		{name: "another tag", pod: newPod("foo/bar:v2", ""), image: newImage("foo/bar:v1", ""), wantErr: true},
+		{name: "another image", pod: newPod("foo/other:v1", ""), image: newImage("foo/bar:v1", ""), wantErr: true},
+		{name: "digest of the pulled image", pod: newPod("foo/bar:v1", "docker.io/foo/bar@"+digest), image: newImage("foo/bar:v1", digest)},
+		{name: "digest in the pod spec", pod: newPod("foo/bar@"+digest, ""), image: newImage("foo/bar@"+digest, "")},
+		{name: "another digest", pod: newPod("foo/bar:v1", "docker.io/foo/bar@sha256:0000000000000000000000000000000000000000000000000000000000000000"), image: newImage("foo/bar:v1", digest), wantErr: true},
+		{name: "unverified digest", pod: newPod("foo/bar:v1", ""), image: newImage("foo/bar:v1", digest), wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkWorkerPodImage(tc.pod, k8sContainerName, tc.image)
+			if tc.wantErr {
+				require.Error(t, err)
+				assert.True(t, driver.IsImageError(err))
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateWorkerScript(t *testing.T) {
+	op := &driver.Operation{
+		Files: map[string]string{"/cnab/app/it's.json": "{}"},
+	}
+
+	script := generateWorkerScript("/cnab/worker/runs/install-test-abc", op)
+	want := `set -e
+echo $$ > '/cnab/worker/runs/install-test-abc/pid'
+trap 'exit 143' TERM INT HUP
+lock='/tmp/cnab-worker.lock'
+while ! mkdir "$lock" 2>/dev/null; do
+  owner=$(cat "$lock/pid" 2>/dev/null || true)
+  if [ -n "$owner" ] && ! kill -0 "$owner" 2>/dev/null && [ "$(cat "$lock/pid" 2>/dev/null || true)" = "$owner" ]; then rm -rf "$lock"; fi
+  sleep 1
+done
+pid=
+cleanup() {
+  set +e
+  if [ -n "$pid" ]; then
+    kill -TERM -"$pid" 2>/dev/null || kill -TERM "$pid" 2>/dev/null
+    (sleep 10; kill -KILL -"$pid" 2>/dev/null || kill -KILL "$pid" 2>/dev/null) >/dev/null 2>&1 &
+    watchdog=$!
+    wait "$pid"
+    kill "$watchdog" 2>/dev/null
+    kill -KILL -"$pid" 2>/dev/null
+  fi
+  rm -f '/cnab/app/it'\''s.json'
+  rm -rf /cnab/app/outputs
+  rm -rf "$lock"
+}
+trap cleanup EXIT
+echo $$ > "$lock/pid"
+. '/cnab/worker/runs/install-test-abc/env'
+mkdir -p '/cnab/app'
+cp '/cnab/worker/runs/install-test-abc/inputs/cnab/app/it'\''s.json' '/cnab/app/it'\''s.json'
+rm -rf /cnab/app/outputs
+mkdir -p /cnab/app/outputs
+set +e
+if command -v setsid >/dev/null 2>&1; then setsid /cnab/app/run & else /cnab/app/run & fi
+pid=$!
+wait "$pid"
+status=$?
+pid=
+cp -R /cnab/app/outputs/. '/cnab/worker/runs/install-test-abc/outputs'
+exit $status
+`
+	assert.Equal(t, want, script)
+}
+
+func TestPrepareWorkerRunDir(t *testing.T) {
+	runDir, err := ioutil.TempDir("", "cnab-go")
+	require.NoError(t, err, "could not create test directory")
+	defer os.RemoveAll(runDir)
+
+	op := &driver.Operation{
+		Environment: map[string]string{"B": "it's", "A": "1"},
+		Files:       map[string]string{"/cnab/app/config.json": "{}"},
+	}
+	require.NoError(t, prepareWorkerRunDir(runDir, op))
+
+	env, err := ioutil.ReadFile(filepath.Join(runDir, "env"))
+	require.NoError(t, err)
+	assert.Equal(t, "export A='1'\nexport B='it'\\''s'\n", string(env))
+
+	config, err := ioutil.ReadFile(filepath.Join(runDir, "inputs", "cnab", "app", "config.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(config))
+
+	op.Environment = map[string]string{"NOT-VALID": "1"}
+	require.EqualError(t, prepareWorkerRunDir(runDir, op), `invalid environment variable name "NOT-VALID"`)
+}
+
+func TestDriver_SetConfig_WorkerPod(t *testing.T) {
+	settings := map[string]string{
+		SettingInCluster:           "true",
+		SettingKubeNamespace:       "default",
+		SettingJobVolumeName:       "cnab-driver-shared",
+		SettingJobVolumePath:       "/tmp",
+		SettingWorkerPod:           "runner",
+		SettingWorkerPodVolumePath: "/mnt/shared",
+	}
+
+	d := Driver{}
+	require.NoError(t, d.SetConfig(settings))
+	assert.Equal(t, "runner", d.WorkerPod, "incorrect WorkerPod value")
+	assert.Equal(t, "/mnt/shared", d.WorkerPodVolumePath, "incorrect WorkerPodVolumePath value")
+
+	settings[SettingWorkerPodVolumePath] = "shared"
+	err := d.SetConfig(settings)
+	require.EqualError(t, err, `invalid value "shared" for WORKER_POD_VOLUME_PATH, must be an absolute path`)
+}
@@ -0,0 +1,118 @@
+package driver
+
+// Well-known labels applied by drivers to the resources that they create, so
+// that they can be traced back to the operation and found again during
+// cleanup.
+const (
+	// LabelDriver is the name of the driver that created the resource.
+	LabelDriver = "cnab.io/driver"
+
+	// LabelInstallation is the name of the installation.
+	LabelInstallation = "cnab.io/installation"
+
+	// LabelAction is the action being executed.
+	LabelAction = "cnab.io/action"
+
+	// LabelRevision is the revision of the installation.
+	LabelRevision = "cnab.io/revision"
+
+	// LabelBundleName is the name of the bundle.
+	LabelBundleName = "cnab.io/bundle-name"
+
+	// LabelBundleVersion is the version of the bundle.
+	LabelBundleVersion = "cnab.io/bundle-version"
+
+	// LabelCorrelationID is the correlation ID of the operation.
+	LabelCorrelationID = "cnab.io/correlation-id"
+)
+
+// OperationLabels identify the operation that created a resource. Each driver
+// maps them to its native labeling, for example container labels, so that
+// resources are labeled consistently regardless of the driver.
+type OperationLabels struct {
+	// Driver is the name of the driver that created the resource.
+	Driver string
+
+	// Installation is the name of the installation.
+	Installation string
+
+	// Action is the action being executed.
+	Action string
+
+	// Revision of the installation.
+	Revision string
+
+	// BundleName is the name of the bundle, when known.
+	BundleName string
+
+	// BundleVersion is the version of the bundle, when known.
+	BundleVersion string
+
+	// CorrelationID of the operation, when set.
+	CorrelationID string
+}
+
+// NewOperationLabels returns the labels for the resources that the driver
+// creates to run the operation.
+func NewOperationLabels(driverName string, op *Operation) OperationLabels {
+	l := OperationLabels{
+		Driver:        driverName,
+		Installation:  op.Installation,
+		Action:        op.Action,
+		Revision:      op.Revision,
+		CorrelationID: op.CorrelationID,
+	}
+	if op.Bundle != nil {
+		l.BundleName = op.Bundle.Name
+		l.BundleVersion = op.Bundle.Version
+	}
+	return l
+}
+
+// ParseOperationLabels reads the labels from the labels of a resource, such
+// as one found during cleanup. Labels that are not set are left empty.
+func ParseOperationLabels(labels map[string]string) OperationLabels {
+	return OperationLabels{
+		Driver:        labels[LabelDriver],
+		Installation:  labels[LabelInstallation],
+		Action:        labels[LabelAction],
+		Revision:      labels[LabelRevision],
+		BundleName:    labels[LabelBundleName],
+		BundleVersion: labels[LabelBundleVersion],
+		CorrelationID: labels[LabelCorrelationID],
+	}
+}
+
+// Map returns the labels keyed by their well-known names. The driver is
+// omitted when empty, and the installation, action and revision are always
+// included so that the resource can be matched to an operation. The bundle
+// and correlation ID are only included when set.
+func (l OperationLabels) Map() map[string]string {
+	labels := map[string]string{
+		LabelInstallation: l.Installation,
+		LabelAction:       l.Action,
+		LabelRevision:     l.Revision,
+	}
+	setIfNotEmpty := func(key string, value string) {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	setIfNotEmpty(LabelDriver, l.Driver)
+	setIfNotEmpty(LabelBundleName, l.BundleName)
+	setIfNotEmpty(LabelBundleVersion, l.BundleVersion)
+	setIfNotEmpty(LabelCorrelationID, l.CorrelationID)
+	return labels
+}
+
+// OrphanedResource describes the resource using the labels, to report it
+// during cleanup.
+func (l OperationLabels) OrphanedResource(kind string, name string) OrphanedResource {
+	return OrphanedResource{
+		Kind:         kind,
+		Name:         name,
+		Installation: l.Installation,
+		Action:       l.Action,
+		Revision:     l.Revision,
+	}
+}
@@ -0,0 +1,52 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestOperationLabels(t *testing.T) {
+	op := &Operation{
+		Installation:  "mysql",
+		Action:        "upgrade",
+		Revision:      "01FZVC5AVP8Z7A78CSCP1EJ604",
+		CorrelationID: "req-1234",
+		Bundle:        &bundle.Bundle{Name: "mysql", Version: "1.0.0+build.1"},
+	}
+
+	labels := NewOperationLabels("docker", op)
+	want := map[string]string{
+		LabelDriver:        "docker",
+		LabelInstallation:  "mysql",
+		LabelAction:        "upgrade",
+		LabelRevision:      "01FZVC5AVP8Z7A78CSCP1EJ604",
+		LabelBundleName:    "mysql",
+		LabelBundleVersion: "1.0.0+build.1",
+		LabelCorrelationID: "req-1234",
+	}
+	assert.Equal(t, want, labels.Map())
+	assert.Equal(t, labels, ParseOperationLabels(labels.Map()), "the labels should round trip")
+
+	t.Run("optional labels omitted", func(t *testing.T) {
+		labels := NewOperationLabels("", &Operation{Installation: "mysql"})
+		assert.Equal(t, map[string]string{
+			LabelInstallation: "mysql",
+			LabelAction:       "",
+			LabelRevision:     "",
+		}, labels.Map())
+	})
+
+	t.Run("orphaned resource", func(t *testing.T) {
+		orphan := labels.OrphanedResource("container", "cnab-mysql")
+		assert.Equal(t, OrphanedResource{
+			Kind:         "container",
+			Name:         "cnab-mysql",
+			Installation: "mysql",
+			Action:       "upgrade",
+			Revision:     "01FZVC5AVP8Z7A78CSCP1EJ604",
+		}, orphan)
+	})
+}
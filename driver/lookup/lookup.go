@@ -7,7 +7,9 @@ import (
 
 	"github.com/cnabio/cnab-go/driver"
 	"github.com/cnabio/cnab-go/driver/command"
+	"github.com/cnabio/cnab-go/driver/compose"
 	"github.com/cnabio/cnab-go/driver/docker"
+	"github.com/cnabio/cnab-go/driver/host"
 	"github.com/cnabio/cnab-go/driver/kubernetes"
 )
 
@@ -18,6 +20,10 @@ func Lookup(name string) (driver.Driver, error) {
 		return &docker.Driver{}, nil
 	case "kubernetes", "k8s":
 		return &kubernetes.Driver{}, nil
+	case "compose", "docker-compose":
+		return &compose.Driver{}, nil
+	case "host":
+		return &host.Driver{}, nil
 	case "debug":
 		return &debug.Driver{}, nil
 	default:
@@ -0,0 +1,12 @@
+package driver
+
+// Reattacher is implemented by drivers that can resume tracking an operation
+// that was started by another process, for example when the process running
+// the driver was restarted while the operation was executing.
+type Reattacher interface {
+	// Reattach finds the run of the operation, identified by the installation
+	// and revision of the operation, resumes streaming its logs, waits for it
+	// to complete and returns its outputs. When the run is not found, the
+	// error wraps ErrRunNotFound.
+	Reattach(op *Operation) (OperationResult, error)
+}
@@ -0,0 +1,136 @@
+package driver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/distribution/reference"
+)
+
+// dockerHubAuthKey is the key of the credentials for Docker Hub in a docker config file.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// RegistryAuth holds the credentials that a driver uses to pull the
+// invocation image, instead of the credentials configured on the host. Set
+// either Username and Password, Token, or DockerConfig.
+type RegistryAuth struct {
+	// Username for the registry of the invocation image.
+	Username string `json:"username,omitempty"`
+
+	// Password for the registry of the invocation image.
+	Password string `json:"password,omitempty"`
+
+	// Token is an access token for the registry of the invocation image. It is
+	// used as the password when Username is set, as required by registries
+	// such as GCR and ACR, and otherwise it is sent as a bearer token, which
+	// not every driver supports.
+	Token string `json:"token,omitempty"`
+
+	// DockerConfig is the contents of a docker config.json file, whose auths
+	// section holds the credentials of one or more registries. Credential
+	// helpers are not supported.
+	DockerConfig string `json:"dockerConfig,omitempty"`
+}
+
+// Validate that the credentials are set in exactly one of the supported forms.
+func (a RegistryAuth) Validate() error {
+	set := 0
+	if a.Password != "" {
+		set++
+	}
+	if a.Token != "" {
+		set++
+	}
+	if a.DockerConfig != "" {
+		set++
+		if a.Username != "" {
+			return errors.New("invalid registry auth: a username cannot be used with a docker config")
+		}
+		var cfg dockerConfig
+		if err := json.Unmarshal([]byte(a.DockerConfig), &cfg); err != nil {
+			return fmt.Errorf("invalid registry auth: the docker config is not valid: %v", err)
+		}
+	}
+
+	if set != 1 {
+		return errors.New("invalid registry auth: set either a password, a token or a docker config")
+	}
+	if a.Password != "" && a.Username == "" {
+		return errors.New("invalid registry auth: a password requires a username")
+	}
+	return nil
+}
+
+// Credentials returns the username and password to authenticate with, or
+// the bearer token when the token is not used as a password.
+func (a RegistryAuth) Credentials() (username, password, token string) {
+	if a.Token != "" && a.Username == "" {
+		return "", "", a.Token
+	}
+	password = a.Password
+	if a.Token != "" {
+		password = a.Token
+	}
+	return a.Username, password, ""
+}
+
+// DockerConfigJSON returns a docker config.json file holding the credentials
+// for the registry of the image, suitable for a kubernetes.io/dockerconfigjson
+// secret. When DockerConfig is set, it is returned unchanged.
+func (a RegistryAuth) DockerConfigJSON(image string) ([]byte, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	if a.DockerConfig != "" {
+		return []byte(a.DockerConfig), nil
+	}
+
+	username, password, token := a.Credentials()
+	if token != "" {
+		return nil, errors.New("invalid registry auth: a token requires a username to be stored in a docker config")
+	}
+
+	registry, err := RegistryHost(image)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := dockerConfig{
+		Auths: map[string]dockerConfigEntry{
+			registry: {
+				Username: username,
+				Password: password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	}
+	return json.Marshal(cfg)
+}
+
+// RegistryHost returns the registry of the image, as it is keyed in a docker
+// config file.
+func RegistryHost(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the image %q: %v", image, err)
+	}
+
+	registry := reference.Domain(named)
+	if registry == "docker.io" {
+		return dockerHubAuthKey, nil
+	}
+	return registry, nil
+}
+
+// dockerConfig is the subset of a docker config.json file holding credentials.
+type dockerConfig struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
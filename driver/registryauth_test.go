@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryAuth_Validate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		auth    RegistryAuth
+		wantErr string
+	}{
+		{name: "password", auth: RegistryAuth{Username: "me", Password: "secret"}},
+		{name: "token", auth: RegistryAuth{Token: "abc123"}},
+		{name: "token with username", auth: RegistryAuth{Username: "oauth2accesstoken", Token: "abc123"}},
+		{name: "docker config", auth: RegistryAuth{DockerConfig: `{"auths": {}}`}},
+		{name: "empty", wantErr: "invalid registry auth: set either a password, a token or a docker config"},
+		{name: "password and token", auth: RegistryAuth{Username: "me", Password: "secret", Token: "abc123"},
+			wantErr: "invalid registry auth: set either a password, a token or a docker config"},
+		{name: "password without username", auth: RegistryAuth{Password: "secret"},
+			wantErr: "invalid registry auth: a password requires a username"},
+		{name: "docker config with username", auth: RegistryAuth{Username: "me", DockerConfig: `{"auths": {}}`},
+			wantErr: "invalid registry auth: a username cannot be used with a docker config"},
+		{name: "invalid docker config", auth: RegistryAuth{DockerConfig: `{"auths":`},
+			wantErr: "invalid registry auth: the docker config is not valid: unexpected end of JSON input"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.auth.Validate()
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRegistryAuth_Credentials(t *testing.T) {
+	username, password, token := RegistryAuth{Username: "me", Password: "secret"}.Credentials()
+	assert.Equal(t, []string{"me", "secret", ""}, []string{username, password, token})
+
+	username, password, token = RegistryAuth{Username: "oauth2accesstoken", Token: "abc123"}.Credentials()
+	assert.Equal(t, []string{"oauth2accesstoken", "abc123", ""}, []string{username, password, token}, "the token should be used as the password")
+
+	username, password, token = RegistryAuth{Token: "abc123"}.Credentials()
+	assert.Equal(t, []string{"", "", "abc123"}, []string{username, password, token})
+}
+
+func TestRegistryAuth_DockerConfigJSON(t *testing.T) {
+	t.Run("docker hub", func(t *testing.T) {
+		data, err := RegistryAuth{Username: "me", Password: "secret"}.DockerConfigJSON("mysql:5.7")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"auths": {"https://index.docker.io/v1/": {"username": "me", "password": "secret", "auth": "bWU6c2VjcmV0"}}}`, string(data))
+	})
+
+	t.Run("private registry", func(t *testing.T) {
+		data, err := RegistryAuth{Username: "oauth2accesstoken", Token: "abc123"}.DockerConfigJSON("gcr.io/myproject/mybun:v1")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"auths": {"gcr.io": {"username": "oauth2accesstoken", "password": "abc123", "auth": "b2F1dGgyYWNjZXNzdG9rZW46YWJjMTIz"}}}`, string(data))
+	})
+
+	t.Run("docker config", func(t *testing.T) {
+		cfg := `{"auths": {"example.com": {"auth": "bWU6c2VjcmV0"}}}`
+		data, err := RegistryAuth{DockerConfig: cfg}.DockerConfigJSON("example.com/mybun:v1")
+		require.NoError(t, err)
+		assert.Equal(t, cfg, string(data))
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		_, err := RegistryAuth{Username: "me", Password: "secret"}.DockerConfigJSON("Invalid Image")
+		require.EqualError(t, err, `could not parse the image "Invalid Image": invalid reference format: repository name (library/Invalid Image) must be lowercase`)
+	})
+}
@@ -0,0 +1,57 @@
+package driver
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+// ApplyRelocationMapping prepares the operation to run with the images of the
+// bundle relocated to another registry, following the CNAB image relocation
+// specification. The invocation image and the references in the image map,
+// bundle.ImageMapPath, are rewritten to the relocated references, and the
+// mapping is injected into the invocation image at
+// bundle.RelocationMappingPath for the bundle to consume.
+func (o *Operation) ApplyRelocationMapping(m bundle.RelocationMapping) error {
+	var mapping []byte
+	var err error
+	if o.Bundle != nil {
+		mapping, err = bundle.BuildRelocationMapping(*o.Bundle, m)
+	} else {
+		mapping, err = json.Marshal(m)
+	}
+	if err != nil {
+		return err
+	}
+
+	if relocated, ok := m[o.Image.Image]; ok {
+		o.Image.Image = relocated
+	}
+
+	if o.Files == nil {
+		o.Files = map[string]string{}
+	}
+	if data, ok := o.Files[bundle.ImageMapPath]; ok {
+		var imgMap bundle.ImageMap
+		if err := json.Unmarshal([]byte(data), &imgMap); err != nil {
+			return errors.Wrap(err, "could not unmarshal the image map")
+		}
+		for name, img := range imgMap {
+			if relocated, ok := m[img.Image]; ok {
+				img.Image = relocated
+				imgMap[name] = img
+			}
+		}
+		relocatedMap, err := json.Marshal(imgMap)
+		if err != nil {
+			return errors.Wrap(err, "could not marshal the image map")
+		}
+		o.Files[bundle.ImageMapPath] = string(relocatedMap)
+	}
+
+	o.Files[bundle.RelocationMappingPath] = string(mapping)
+	o.RelocationMapping = m
+	return nil
+}
@@ -0,0 +1,72 @@
+package driver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+)
+
+func TestOperation_ApplyRelocationMapping(t *testing.T) {
+	b := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "docker.io/example/installer:v1"}},
+		},
+		Images: map[string]bundle.Image{
+			"web": {BaseImage: bundle.BaseImage{Image: "docker.io/example/web:v1"}},
+			"db":  {BaseImage: bundle.BaseImage{Image: "docker.io/library/mysql:8"}},
+		},
+	}
+	newOp := func() *Operation {
+		imgMap, err := bundle.BuildImageMap(*b)
+		require.NoError(t, err)
+		return &Operation{
+			Image:  b.InvocationImages[0],
+			Bundle: b,
+			Files:  map[string]string{bundle.ImageMapPath: string(imgMap)},
+		}
+	}
+
+	t.Run("relocated", func(t *testing.T) {
+		m := bundle.RelocationMapping{
+			"docker.io/example/installer:v1": "registry.example.com/example/installer:v1",
+			"docker.io/example/web:v1":       "registry.example.com/example/web:v1",
+		}
+		op := newOp()
+		require.NoError(t, op.ApplyRelocationMapping(m))
+
+		assert.Equal(t, "registry.example.com/example/installer:v1", op.Image.Image)
+		assert.Equal(t, m, op.RelocationMapping)
+
+		var imgMap bundle.ImageMap
+		require.NoError(t, json.Unmarshal([]byte(op.Files[bundle.ImageMapPath]), &imgMap))
+		assert.Equal(t, "registry.example.com/example/web:v1", imgMap["web"].Image)
+		assert.Equal(t, "docker.io/library/mysql:8", imgMap["db"].Image, "images that were not relocated should not change")
+
+		var mapping bundle.RelocationMapping
+		require.NoError(t, json.Unmarshal([]byte(op.Files[bundle.RelocationMappingPath]), &mapping))
+		assert.Equal(t, m, mapping)
+
+		assert.Equal(t, "docker.io/example/installer:v1", b.InvocationImages[0].Image, "the bundle should not be modified")
+	})
+
+	t.Run("invalid mapping", func(t *testing.T) {
+		op := newOp()
+		err := op.ApplyRelocationMapping(bundle.RelocationMapping{"docker.io/example/other:v1": "registry.example.com/other:v1"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid relocation mapping")
+		assert.Equal(t, "docker.io/example/installer:v1", op.Image.Image)
+	})
+
+	t.Run("no bundle", func(t *testing.T) {
+		op := &Operation{Image: b.InvocationImages[0]}
+		require.NoError(t, op.ApplyRelocationMapping(bundle.RelocationMapping{
+			"docker.io/example/installer:v1": "registry.example.com/example/installer:v1",
+		}))
+		assert.Equal(t, "registry.example.com/example/installer:v1", op.Image.Image)
+		assert.Contains(t, op.Files, bundle.RelocationMappingPath)
+	})
+}
@@ -0,0 +1,18 @@
+package driver
+
+// StdinStreamer is implemented by drivers that can stream the input of an
+// operation, Operation.In, to the standard input of the invocation image, so
+// that interactive actions can read from it.
+type StdinStreamer interface {
+	// SupportsStdin determines if the driver streams Operation.In with its
+	// current configuration.
+	SupportsStdin() bool
+}
+
+// SupportsStdin determines if the driver streams the input of operations to
+// the invocation image. Drivers that do not implement StdinStreamer ignore
+// Operation.In.
+func SupportsStdin(d Driver) bool {
+	streamer, ok := d.(StdinStreamer)
+	return ok && streamer.SupportsStdin()
+}
@@ -0,0 +1,384 @@
+// Package e2e provides end-to-end scenarios that install, upgrade and
+// uninstall the cnab/helloworld bundle, so that integrators can smoke-test
+// their combination of driver and claim provider with a single call:
+//
+//	d, _ := lookup.Lookup("docker")
+//	claims := claim.NewClaimStore(crud.NewFileSystemStore(home, claim.NewClaimStoreFileExtensions()), nil, nil)
+//	if err := e2e.SmokeTest(d, claims); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Each step of a scenario runs an operation with the action package, persists
+// its claim, result and outputs with the provider, and checks that the
+// operation succeeded, that its logs are as expected, and that the provider
+// reports the new status of the installation.
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/action"
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/cnabio/cnab-go/valuesource"
+)
+
+// HelloWorldImage is the invocation image of the cnab/helloworld bundle.
+const HelloWorldImage = "cnab/helloworld:latest"
+
+// HelloWorldDigest is the content digest of HelloWorldImage.
+const HelloWorldDigest = "sha256:55f83710272990efab4e076f9281453e136980becfd879640b06552ead751284"
+
+// DefaultInstallationPrefix prefixes the name of the installation of each
+// scenario.
+const DefaultInstallationPrefix = "cnab-go-e2e-"
+
+// HelloWorldBundle returns the definition of the cnab/helloworld bundle,
+// which prints the action that it runs and the value of its port parameter.
+func HelloWorldBundle() bundle.Bundle {
+	return bundle.Bundle{
+		SchemaVersion: bundle.GetDefaultSchemaVersion(),
+		Name:          "helloworld",
+		Version:       "0.1.1",
+		Description:   "A short, super simple hello world example",
+		InvocationImages: []bundle.InvocationImage{
+			{
+				BaseImage: bundle.BaseImage{
+					ImageType: driver.ImageTypeDocker,
+					Image:     HelloWorldImage,
+					Digest:    HelloWorldDigest,
+				},
+			},
+		},
+		Definitions: definition.Definitions{
+			"http_port": &definition.Schema{
+				Type:    "integer",
+				Default: 8080,
+				Minimum: float64Ptr(1024),
+				Maximum: float64Ptr(65535),
+			},
+		},
+		Parameters: map[string]bundle.Parameter{
+			"port": {
+				Definition:  "http_port",
+				Destination: &bundle.Location{EnvironmentVariable: "PORT"},
+			},
+		},
+	}
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+// Step is an operation run by a scenario.
+type Step struct {
+	// Action to run, for example claim.ActionInstall.
+	Action string
+
+	// Parameters of the operation. Defaults to the parameters of the previous
+	// step of the scenario.
+	Parameters map[string]interface{}
+
+	// WantLogs are the lines that the logs of the operation must contain.
+	WantLogs []string
+}
+
+// Scenario is a sequence of operations on an installation.
+type Scenario struct {
+	// Name of the scenario, which is appended to the installation prefix to
+	// name its installation.
+	Name string
+
+	// Steps run in order, until one fails.
+	Steps []Step
+}
+
+// Install installs the bundle.
+func Install() Scenario {
+	return Scenario{
+		Name: "install",
+		Steps: []Step{
+			{Action: claim.ActionInstall, Parameters: map[string]interface{}{"port": 3000}, WantLogs: []string{"Port parameter was set to 3000", "Install action"}},
+		},
+	}
+}
+
+// Upgrade installs the bundle, then upgrades it with another parameter value.
+func Upgrade() Scenario {
+	return Scenario{
+		Name: "upgrade",
+		Steps: []Step{
+			{Action: claim.ActionInstall, Parameters: map[string]interface{}{"port": 3000}, WantLogs: []string{"Install action"}},
+			{Action: claim.ActionUpgrade, Parameters: map[string]interface{}{"port": 3001}, WantLogs: []string{"Port parameter was set to 3001", "Upgrade action"}},
+		},
+	}
+}
+
+// Uninstall installs the bundle, then uninstalls it.
+func Uninstall() Scenario {
+	return Scenario{
+		Name: "uninstall",
+		Steps: []Step{
+			{Action: claim.ActionInstall, Parameters: map[string]interface{}{"port": 3000}, WantLogs: []string{"Install action"}},
+			{Action: claim.ActionUninstall, WantLogs: []string{"Uninstall action"}},
+		},
+	}
+}
+
+// Lifecycle installs, upgrades and then uninstalls the bundle.
+func Lifecycle() Scenario {
+	return Scenario{
+		Name: "lifecycle",
+		Steps: []Step{
+			{Action: claim.ActionInstall, Parameters: map[string]interface{}{"port": 3000}, WantLogs: []string{"Install action"}},
+			{Action: claim.ActionUpgrade, Parameters: map[string]interface{}{"port": 3001}, WantLogs: []string{"Upgrade action"}},
+			{Action: claim.ActionUninstall, WantLogs: []string{"Uninstall action"}},
+		},
+	}
+}
+
+// Scenarios returns every scenario of the package.
+func Scenarios() []Scenario {
+	return []Scenario{Install(), Upgrade(), Uninstall(), Lifecycle()}
+}
+
+// StepResult is the outcome of a step of a scenario.
+type StepResult struct {
+	// Step that was run.
+	Step Step
+
+	// Claim of the operation.
+	Claim claim.Claim
+
+	// Result of the operation.
+	Result claim.Result
+
+	// Logs written by the invocation image.
+	Logs string
+
+	// Err is set when the step failed.
+	Err error
+}
+
+// ScenarioResult is the outcome of a scenario.
+type ScenarioResult struct {
+	// Scenario that was run.
+	Scenario string
+
+	// Installation that the scenario operated on.
+	Installation string
+
+	// Steps that were run, which stop at the first failed step.
+	Steps []StepResult
+}
+
+// Err returns the error of the failed step, if any.
+func (r ScenarioResult) Err() error {
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			return errors.Wrapf(step.Err, "scenario %s failed to %s installation %s", r.Scenario, step.Step.Action, r.Installation)
+		}
+	}
+	return nil
+}
+
+// Runner runs scenarios with a driver, persisting their claims with a
+// provider.
+type Runner struct {
+	// Driver that runs the operations. Required.
+	Driver driver.Driver
+
+	// Claims persists the claims, results and outputs of the operations.
+	// Required.
+	Claims claim.Provider
+
+	// Bundle that is run. Defaults to HelloWorldBundle. The logs expected
+	// by the scenarios of the package are those of the cnab/helloworld
+	// bundle.
+	Bundle *bundle.Bundle
+
+	// Credentials passed to every operation.
+	Credentials valuesource.Set
+
+	// InstallationPrefix prefixes the name of the scenario to name its
+	// installation. Defaults to DefaultInstallationPrefix.
+	InstallationPrefix string
+
+	// Out also receives the logs of the operations, when it is set.
+	Out io.Writer
+
+	// OperationConfigs are applied to every operation, for example to relocate
+	// the invocation image with action.WithRelocationMapping.
+	OperationConfigs []action.OperationConfigFunc
+}
+
+// SmokeTest runs every scenario with the driver and the provider, and returns
+// the errors of the scenarios that failed.
+func SmokeTest(d driver.Driver, claims claim.Provider) error {
+	r := Runner{Driver: d, Claims: claims}
+	results, err := r.RunAll()
+	if err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	for _, sr := range results {
+		if err := sr.Err(); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+// RunAll runs every scenario of the package.
+func (r Runner) RunAll() ([]ScenarioResult, error) {
+	var results []ScenarioResult
+	for _, s := range Scenarios() {
+		sr, err := r.Run(s)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, sr)
+	}
+	return results, nil
+}
+
+// Run runs the steps of the scenario in order, until one fails. The failure
+// of a step is reported in the ScenarioResult, and an error is only returned
+// when the runner is misconfigured.
+func (r Runner) Run(s Scenario) (ScenarioResult, error) {
+	if r.Driver == nil || r.Claims == nil {
+		return ScenarioResult{}, errors.New("the driver and the claim provider of the runner are required")
+	}
+
+	b := HelloWorldBundle()
+	if r.Bundle != nil {
+		b = *r.Bundle
+	}
+	prefix := r.InstallationPrefix
+	if prefix == "" {
+		prefix = DefaultInstallationPrefix
+	}
+
+	sr := ScenarioResult{Scenario: s.Name, Installation: prefix + s.Name}
+	var previous *claim.Claim
+	for _, step := range s.Steps {
+		result := r.runStep(sr.Installation, b, previous, step)
+		sr.Steps = append(sr.Steps, result)
+		if result.Err != nil {
+			break
+		}
+		previous = &result.Claim
+	}
+	return sr, nil
+}
+
+// runStep runs the operation of a step, after the claim of the previous step
+// when there is one, and checks its outcome.
+func (r Runner) runStep(installation string, b bundle.Bundle, previous *claim.Claim, step Step) StepResult {
+	result := StepResult{Step: step}
+
+	params := step.Parameters
+	var err error
+	if previous == nil {
+		result.Claim, err = claim.New(installation, step.Action, b, params)
+	} else {
+		if params == nil {
+			params = previous.Parameters
+		}
+		result.Claim, err = previous.NewClaim(step.Action, b, params)
+	}
+	if err != nil {
+		result.Err = errors.Wrap(err, "could not create the claim")
+		return result
+	}
+
+	a := action.New(r.Driver)
+	a.Claims = r.Claims
+	if _, err := a.SaveInitialClaim(result.Claim, claim.StatusRunning); err != nil {
+		result.Err = err
+		return result
+	}
+
+	var logs bytes.Buffer
+	opCfgs := append([]action.OperationConfigFunc{r.captureLogs(&logs)}, r.OperationConfigs...)
+	opResult, claimResult, err := a.Run(result.Claim, r.Credentials, opCfgs...)
+	result.Logs = logs.String()
+	if err != nil {
+		result.Err = errors.Wrap(err, "could not run the operation")
+		return result
+	}
+	result.Result = claimResult
+
+	if err := r.saveResult(result.Claim, claimResult, opResult); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if claimResult.Status != claim.StatusSucceeded {
+		result.Err = errors.Errorf("the operation %s: %v", claimResult.Status, opResult.Error)
+		return result
+	}
+	for _, want := range step.WantLogs {
+		if !strings.Contains(result.Logs, want) {
+			result.Err = errors.Errorf("the logs of the operation do not contain %q", want)
+			return result
+		}
+	}
+	result.Err = r.checkInstallation(result.Claim)
+	return result
+}
+
+// captureLogs copies the logs of the operation to the buffer, and to the Out
+// of the runner when it is set.
+func (r Runner) captureLogs(logs *bytes.Buffer) action.OperationConfigFunc {
+	return func(op *driver.Operation) error {
+		if r.Out != nil {
+			op.Out = io.MultiWriter(logs, r.Out)
+		} else {
+			op.Out = logs
+		}
+		return nil
+	}
+}
+
+func (r Runner) saveResult(c claim.Claim, result claim.Result, opResult driver.OperationResult) error {
+	if err := r.Claims.SaveResult(result); err != nil {
+		return errors.Wrapf(err, "could not save the result of claim %s", c.ID)
+	}
+	for name, value := range opResult.Outputs {
+		if err := r.Claims.SaveOutput(claim.NewOutput(c, result, name, []byte(value))); err != nil {
+			return errors.Wrapf(err, "could not save output %s of claim %s", name, c.ID)
+		}
+	}
+	return nil
+}
+
+// checkInstallation checks that the provider reports the claim as the last
+// successful operation on the installation.
+func (r Runner) checkInstallation(c claim.Claim) error {
+	i, err := r.Claims.ReadInstallationStatus(c.Installation)
+	if err != nil {
+		return errors.Wrap(err, "could not read the status of the installation")
+	}
+	last, err := i.GetLastClaim()
+	if err != nil {
+		return errors.Wrap(err, "could not read the last claim of the installation")
+	}
+	if last.ID != c.ID {
+		return fmt.Errorf("the last claim of the installation is %s instead of %s", last.ID, c.ID)
+	}
+	if status := i.GetLastStatus(); status != claim.StatusSucceeded {
+		return fmt.Errorf("the status of the installation is %s instead of %s", status, claim.StatusSucceeded)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/driver"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+// helloWorldDriver prints the logs of the cnab/helloworld bundle instead of
+// running it.
+type helloWorldDriver struct {
+	fail string
+}
+
+func (d helloWorldDriver) Handles(imageType string) bool {
+	return imageType == driver.ImageTypeDocker
+}
+
+func (d helloWorldDriver) Run(op *driver.Operation) (driver.OperationResult, error) {
+	if op.Action == d.fail {
+		return driver.OperationResult{}, errors.New("container exit code: 1")
+	}
+	fmt.Fprintf(op.Out, "Port parameter was set to %s\n", op.Environment["PORT"])
+	fmt.Fprintf(op.Out, "%s%s action\n", strings.ToUpper(op.Action[:1]), op.Action[1:])
+	fmt.Fprintf(op.Out, "Action %s complete for %s\n", op.Action, op.Installation)
+	return driver.OperationResult{}, nil
+}
+
+func TestSmokeTest(t *testing.T) {
+	claims := claim.NewClaimStore(crud.NewMockStore(), nil, nil)
+	require.NoError(t, SmokeTest(helloWorldDriver{}, claims))
+
+	installations, err := claims.ListInstallations()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cnab-go-e2e-install", "cnab-go-e2e-lifecycle", "cnab-go-e2e-uninstall", "cnab-go-e2e-upgrade"}, installations)
+
+	i, err := claims.ReadInstallation("cnab-go-e2e-lifecycle")
+	require.NoError(t, err)
+	require.Len(t, i.Claims, 3)
+	assert.Equal(t, claim.ActionUninstall, i.Claims[2].Action)
+	assert.Equal(t, float64(3001), i.Claims[2].Parameters["port"], "uninstall should reuse the parameters of the upgrade")
+}
+
+func TestRunner_Run(t *testing.T) {
+	t.Run("logs", func(t *testing.T) {
+		var out strings.Builder
+		r := Runner{Driver: helloWorldDriver{}, Claims: claim.NewClaimStore(crud.NewMockStore(), nil, nil), InstallationPrefix: "test-", Out: &out}
+		sr, err := r.Run(Upgrade())
+		require.NoError(t, err)
+		require.NoError(t, sr.Err())
+
+		assert.Equal(t, "test-upgrade", sr.Installation)
+		require.Len(t, sr.Steps, 2)
+		assert.Contains(t, sr.Steps[1].Logs, "Port parameter was set to 3001")
+		assert.Equal(t, claim.StatusSucceeded, sr.Steps[1].Result.Status)
+		assert.Contains(t, out.String(), "Action upgrade complete for test-upgrade")
+	})
+
+	t.Run("failed step", func(t *testing.T) {
+		r := Runner{Driver: helloWorldDriver{fail: claim.ActionUpgrade}, Claims: claim.NewClaimStore(crud.NewMockStore(), nil, nil)}
+		sr, err := r.Run(Lifecycle())
+		require.NoError(t, err)
+		require.Len(t, sr.Steps, 2, "the scenario should stop at the failed step")
+		assert.Equal(t, claim.StatusFailed, sr.Steps[1].Result.Status)
+		require.Error(t, sr.Err())
+		assert.Contains(t, sr.Err().Error(), "scenario lifecycle failed to upgrade installation cnab-go-e2e-lifecycle: the operation failed")
+		assert.Contains(t, sr.Err().Error(), "container exit code: 1")
+	})
+
+	t.Run("unexpected logs", func(t *testing.T) {
+		r := Runner{Driver: helloWorldDriver{}, Claims: claim.NewClaimStore(crud.NewMockStore(), nil, nil)}
+		sr, err := r.Run(Scenario{Name: "logs", Steps: []Step{{Action: claim.ActionInstall, WantLogs: []string{"Goodbye"}}}})
+		require.NoError(t, err)
+		assert.EqualError(t, sr.Err(), `scenario logs failed to install installation cnab-go-e2e-logs: the logs of the operation do not contain "Goodbye"`)
+	})
+
+	t.Run("misconfigured", func(t *testing.T) {
+		_, err := Runner{Driver: helloWorldDriver{}}.Run(Install())
+		assert.EqualError(t, err, "the driver and the claim provider of the runner are required")
+	})
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/schema"
 )
 
 // CNABSchemaURLPrefix is the URL prefix to fetch schemas from
@@ -17,41 +23,102 @@ const CNABSchemaURLPrefix = "https://cdn.cnab.io/schema"
 // CNABSchemaDestPrefix is the filepath prefix to write schemas to
 const CNABSchemaDestPrefix = "./schema/schema"
 
+// CNABSchemaVersionsFile is the file recording the version of each embedded schema
+const CNABSchemaVersionsFile = CNABSchemaDestPrefix + "/versions.json"
+
 func main() {
+	source := flag.String("source", CNABSchemaURLPrefix,
+		"URL prefix or local directory to refresh the schemas from, for example a checkout of the cnab-spec schema directory when offline")
+	flag.Parse()
+
 	schemas := map[string]string{
-		"bundle": bundle.CNABSpecVersion,
-		"claim":  claim.CNABSpecVersion,
+		schema.TypeBundle: bundle.CNABSpecVersion,
+		schema.TypeClaim:  claim.CNABSpecVersion,
 	}
 
-	for schema, version := range schemas {
-		bytes, err := fetchSchema(schema, version)
+	versions, err := readVersions()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for schemaType, version := range schemas {
+		bytes, err := fetchSchema(*source, schemaType, version)
 		if err != nil {
-			fmt.Printf("unable to fetch %s schema with version %s: %s\n", schema, version, err.Error())
+			fmt.Printf("unable to fetch %s schema with version %s: %s\n", schemaType, version, err.Error())
 			// if cdn.cnab.io is not reachable, we stick with default schema
 			continue
 		}
 
-		err = writeSchema(schema, bytes)
+		err = writeSchema(schemaType, bytes)
 		if err != nil {
-			fmt.Printf("unable to write %s schema: %s\n", schema, err.Error())
+			fmt.Printf("unable to write %s schema: %s\n", schemaType, err.Error())
+			continue
 		}
+
+		semver, err := schema.GetSemver(version)
+		if err != nil {
+			fmt.Printf("unable to determine the version of the %s schema: %s\n", schemaType, err.Error())
+			continue
+		}
+		versions[schemaType] = semver
+	}
+
+	if err := writeVersions(versions); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 }
 
-func fetchSchema(schemaType, schemaVersion string) ([]byte, error) {
-	schemaURL := fmt.Sprintf("%s/%s/%s.schema.json", CNABSchemaURLPrefix, schemaVersion, schemaType)
+func fetchSchema(source, schemaType, schemaVersion string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		schemaPath := filepath.Join(source, schemaVersion, schemaType+".schema.json")
+		fmt.Println("Reading schema", schemaPath)
+		data, err := ioutil.ReadFile(schemaPath)
+		return data, errors.Wrapf(err, "unable to read schema from %q", schemaPath)
+	}
+
+	schemaURL := fmt.Sprintf("%s/%s/%s.schema.json", source, schemaVersion, schemaType)
 	fmt.Println("Retrieving schema", schemaURL)
 	resp, err := http.Get(schemaURL)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to fetch schema from %q", schemaURL)
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to fetch schema from %q: %s", schemaURL, resp.Status)
+	}
 
 	data, err := ioutil.ReadAll(resp.Body)
 	return data, errors.Wrap(err, "unable to read response body")
 }
 
 func writeSchema(schemaType string, data []byte) error {
+	if !json.Valid(data) {
+		return errors.New("the schema is not valid json")
+	}
+
 	dest := fmt.Sprintf("%s/%s.schema.json", CNABSchemaDestPrefix, schemaType)
 	err := ioutil.WriteFile(dest, data, 0644)
 	return errors.Wrapf(err, "unable to write file to %q", dest)
 }
+
+func readVersions() (map[string]schema.Version, error) {
+	versions := map[string]schema.Version{}
+	data, err := ioutil.ReadFile(CNABSchemaVersionsFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %q", CNABSchemaVersionsFile)
+	}
+	err = json.Unmarshal(data, &versions)
+	return versions, errors.Wrapf(err, "unable to parse %q", CNABSchemaVersionsFile)
+}
+
+func writeVersions(versions map[string]schema.Version) error {
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the schema versions")
+	}
+	err = ioutil.WriteFile(CNABSchemaVersionsFile, append(data, '\n'), 0644)
+	return errors.Wrapf(err, "unable to write file to %q", CNABSchemaVersionsFile)
+}
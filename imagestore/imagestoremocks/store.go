@@ -5,6 +5,7 @@ import "github.com/cnabio/image-relocation/pkg/image"
 type MockStore struct {
 	AddStub  func(im string) (string, error)
 	PushStub func(image.Digest, image.Name, image.Name) error
+	FindStub func(im string) (string, error)
 }
 
 func (i *MockStore) Add(im string) (string, error) {
@@ -14,3 +15,10 @@ func (i *MockStore) Add(im string) (string, error) {
 func (i *MockStore) Push(dig image.Digest, src image.Name, dst image.Name) error {
 	return i.PushStub(dig, src, dst)
 }
+
+func (i *MockStore) Find(im string) (string, error) {
+	if i.FindStub == nil {
+		return "", nil
+	}
+	return i.FindStub(im)
+}
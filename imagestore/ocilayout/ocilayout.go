@@ -19,6 +19,8 @@ type ociLayout struct {
 	logs   io.Writer
 }
 
+var _ imagestore.Finder = &ociLayout{}
+
 func Create(options ...imagestore.Option) (imagestore.Store, error) {
 	parms := imagestore.Create(options...)
 
@@ -68,6 +70,20 @@ func (o *ociLayout) Add(im string) (string, error) {
 	return dig.String(), nil
 }
 
+func (o *ociLayout) Find(im string) (string, error) {
+	n, err := image.NewName(im)
+	if err != nil {
+		return "", err
+	}
+
+	dig, err := o.layout.Find(n)
+	if err != nil {
+		return "", err
+	}
+
+	return dig.String(), nil
+}
+
 func (o *ociLayout) Push(dig image.Digest, src image.Name, dst image.Name) error {
 	if dig == image.EmptyDigest {
 		var err error
@@ -0,0 +1,200 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cnabio/image-relocation/pkg/image"
+	"github.com/cnabio/image-relocation/pkg/pathmapping"
+	"github.com/cnabio/image-relocation/pkg/registry"
+	"github.com/cnabio/image-relocation/pkg/registry/ggcr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/imagestore"
+)
+
+// DefaultParallelism is the number of images copied concurrently by a
+// Relocator when the Parallelism parameter is not set.
+const DefaultParallelism = 4
+
+// Relocator copies the images of a bundle from their registry to another
+// registry, for example to install the bundle in an air-gapped environment.
+type Relocator struct {
+	registryClient registry.Client
+	logs           io.Writer
+	parallelism    int
+	retries        int
+
+	// retryDelay is the delay before the first retry, doubled for every
+	// following retry.
+	retryDelay time.Duration
+}
+
+// NewRelocator creates a Relocator. The Transport, Logs, Parallelism and
+// Retries parameters are used, the ArchiveDir parameter is ignored.
+func NewRelocator(options ...imagestore.Option) *Relocator {
+	parms := imagestore.Create(options...)
+
+	parallelism := parms.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	retries := parms.Retries
+	if retries < 0 {
+		retries = 0
+	}
+
+	return &Relocator{
+		registryClient: ggcr.NewRegistryClient(parms.BuildRegistryOptions()...),
+		logs:           parms.Logs,
+		parallelism:    parallelism,
+		retries:        retries,
+		retryDelay:     time.Second,
+	}
+}
+
+// Relocate copies the invocation images and images of the bundle to
+// repositories under the repository prefix, such as
+// registry.example.com/team, and returns the relocation mapping of the
+// bundle. The relocated references are pinned to the digest of the copied
+// images, so that the bundle runs the exact images that were copied.
+//
+// Images found in the mapping, because they were relocated previously, are
+// copied from their relocated reference rather than from their original
+// reference. The copy fails when the digest of an image does not match the
+// content digest declared by the bundle.
+func (r *Relocator) Relocate(b bundle.Bundle, repoPrefix string, mapping bundle.RelocationMapping) (bundle.RelocationMapping, error) {
+	if repoPrefix == "" {
+		return nil, errors.New("the repository prefix to relocate the images to is required")
+	}
+
+	images := bundleImages(b)
+	relocated := make(bundle.RelocationMapping, len(images))
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		result *multierror.Error
+	)
+	sem := make(chan struct{}, r.parallelism)
+	for _, img := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(img bundle.BaseImage) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ref, err := r.relocateImage(img, repoPrefix, mapping[img.Image])
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result = multierror.Append(result, errors.Wrapf(err, "could not relocate image %s", img.Image))
+				return
+			}
+			relocated[img.Image] = ref
+		}(img)
+	}
+	wg.Wait()
+
+	if err := result.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return relocated, nil
+}
+
+// relocateImage copies the image from its current reference to the
+// repository prefix, and returns the relocated reference pinned to the
+// digest of the copy.
+func (r *Relocator) relocateImage(img bundle.BaseImage, repoPrefix string, current string) (string, error) {
+	original, err := image.NewName(img.Image)
+	if err != nil {
+		return "", err
+	}
+
+	source := original
+	if current != "" {
+		if source, err = image.NewName(current); err != nil {
+			return "", errors.Wrapf(err, "invalid relocated reference %s", current)
+		}
+	}
+
+	target, err := pathmapping.FlattenRepoPath(repoPrefix, original)
+	if err != nil {
+		return "", err
+	}
+	if tag := original.Tag(); tag != "" {
+		if target, err = target.WithTag(tag); err != nil {
+			return "", err
+		}
+	}
+
+	dig, err := r.copy(source, target)
+	if err != nil {
+		return "", err
+	}
+
+	for _, expected := range []string{img.Digest, original.Digest().String()} {
+		if expected != "" && expected != dig.String() {
+			return "", fmt.Errorf("digest of image %s not preserved: expected %s, copied %s", source, expected, dig)
+		}
+	}
+
+	pinned, err := target.WithoutTagOrDigest().WithDigest(dig)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(r.logs, "Relocated %s to %s\n", source, pinned)
+	return pinned.String(), nil
+}
+
+// copy copies the image, retrying with an exponential backoff when the
+// copy fails.
+func (r *Relocator) copy(source image.Name, target image.Name) (image.Digest, error) {
+	delay := r.retryDelay
+	for attempt := 0; ; attempt++ {
+		dig, _, err := r.registryClient.Copy(source, target)
+		if err == nil {
+			return dig, nil
+		}
+		if attempt >= r.retries {
+			return image.EmptyDigest, err
+		}
+
+		fmt.Fprintf(r.logs, "Retrying the copy of %s after error: %s\n", source, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// bundleImages returns the invocation images and images of the bundle,
+// sorted by reference and without duplicates.
+func bundleImages(b bundle.Bundle) []bundle.BaseImage {
+	seen := map[string]struct{}{}
+	var images []bundle.BaseImage
+	add := func(img bundle.BaseImage) {
+		if _, ok := seen[img.Image]; ok {
+			return
+		}
+		seen[img.Image] = struct{}{}
+		images = append(images, img)
+	}
+
+	for _, ii := range b.InvocationImages {
+		add(ii.BaseImage)
+	}
+	for _, img := range b.Images {
+		add(img.BaseImage)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Image < images[j].Image
+	})
+	return images
+}
@@ -0,0 +1,149 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cnabio/image-relocation/pkg/image"
+	"github.com/cnabio/image-relocation/pkg/registry"
+	ggcrname "github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	ggcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/imagestore"
+)
+
+// startRegistry runs an in-memory registry and returns its host.
+func startRegistry(t *testing.T) string {
+	srv := httptest.NewServer(ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	return u.Host
+}
+
+// pushRandomImage pushes a random image to the reference and returns its
+// digest.
+func pushRandomImage(t *testing.T, ref string) string {
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	tag, err := ggcrname.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, ggcrremote.Write(tag, img))
+	dig, err := img.Digest()
+	require.NoError(t, err)
+	return dig.String()
+}
+
+func TestRelocator_Relocate(t *testing.T) {
+	source := startRegistry(t)
+	target := startRegistry(t)
+
+	invocationImage := source + "/cnab/helloworld:v1"
+	invocationDigest := pushRandomImage(t, invocationImage)
+	appImage := source + "/cnab/app:latest"
+	appDigest := pushRandomImage(t, appImage)
+
+	b := bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: invocationImage, ImageType: "docker", Digest: invocationDigest}},
+		},
+		Images: map[string]bundle.Image{
+			"app":   {BaseImage: bundle.BaseImage{Image: appImage, ImageType: "docker"}},
+			"again": {BaseImage: bundle.BaseImage{Image: appImage, ImageType: "docker"}},
+		},
+	}
+
+	r := NewRelocator(imagestore.WithParallelism(2))
+	mapping, err := r.Relocate(b, target+"/relocated", nil)
+	require.NoError(t, err)
+	require.Len(t, mapping, 2)
+	require.NoError(t, mapping.Validate(b))
+
+	for original, dig := range map[string]string{invocationImage: invocationDigest, appImage: appDigest} {
+		relocated, err := image.NewName(mapping[original])
+		require.NoError(t, err)
+		assert.Equal(t, target, relocated.Host())
+		assert.Equal(t, dig, relocated.Digest().String(), "the relocated reference should be pinned to the digest of the image")
+
+		ref, err := ggcrname.NewDigest(mapping[original])
+		require.NoError(t, err)
+		_, err = ggcrremote.Head(ref)
+		require.NoError(t, err, "the image should be copied to the target registry")
+	}
+
+	t.Run("from the relocated images", func(t *testing.T) {
+		again := startRegistry(t)
+		remapped, err := r.Relocate(b, again+"/relocated", mapping)
+		require.NoError(t, err)
+		relocated, err := image.NewName(remapped[appImage])
+		require.NoError(t, err)
+		assert.Equal(t, again, relocated.Host())
+		assert.Equal(t, appDigest, relocated.Digest().String())
+	})
+
+	t.Run("digest not preserved", func(t *testing.T) {
+		modified := b
+		modified.InvocationImages = []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: invocationImage, ImageType: "docker", Digest: appDigest}},
+		}
+		_, err := r.Relocate(modified, target+"/relocated", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("digest of image %s not preserved: expected %s", invocationImage, appDigest))
+	})
+
+	t.Run("missing repository prefix", func(t *testing.T) {
+		_, err := r.Relocate(b, "", nil)
+		assert.EqualError(t, err, "the repository prefix to relocate the images to is required")
+	})
+}
+
+// flakyClient fails to copy images a number of times before succeeding.
+type flakyClient struct {
+	registry.Client
+	failures int
+	copies   int
+}
+
+func (c *flakyClient) Copy(source image.Name, target image.Name) (image.Digest, int64, error) {
+	c.copies++
+	if c.copies <= c.failures {
+		return image.EmptyDigest, 0, errors.New("service unavailable")
+	}
+	dig, err := image.NewDigest("sha256:55f83710272990efab4e076f9281453e136980becfd879640b06552ead751284")
+	return dig, 0, err
+}
+
+func TestRelocator_Retries(t *testing.T) {
+	b := bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "cnab/helloworld:latest", ImageType: "docker"}},
+		},
+	}
+
+	client := &flakyClient{failures: 2}
+	r := NewRelocator(imagestore.WithRetries(2))
+	r.registryClient = client
+	r.retryDelay = 0
+
+	mapping, err := r.Relocate(b, "example.com/relocated", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, client.copies)
+	assert.Contains(t, mapping["cnab/helloworld:latest"], "@sha256:55f83710272990efab4e076f9281453e136980becfd879640b06552ead751284")
+
+	client = &flakyClient{failures: 3}
+	r.registryClient = client
+	_, err = r.Relocate(b, "example.com/relocated", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "service unavailable")
+	assert.Equal(t, 3, client.copies, "the copy should not be retried more than the configured retries")
+}
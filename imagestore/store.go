@@ -19,6 +19,13 @@ type Store interface {
 	Push(dig image.Digest, src image.Name, dst image.Name) error
 }
 
+// Finder is implemented by image stores that hold copies of images, such as
+// the OCI image layout of a thick bundle, and can look up their digests.
+type Finder interface {
+	// Find returns the digest of the image with the given name in the image store.
+	Find(img string) (contentDigest string, err error)
+}
+
 // Constructor is a function which creates an images store based on parameters represented as options
 type Constructor func(...Option) (Store, error)
 
@@ -29,6 +36,14 @@ type Parameters struct {
 
 	// Transport is http.Transport to use when communicating with an OCI registry.
 	Transport *http.Transport
+
+	// Parallelism is the number of images copied concurrently when images
+	// are relocated. Zero selects the default of the image store.
+	Parallelism int
+
+	// Retries is the number of times that copying an image is retried after
+	// it failed, for example because of a transient registry error.
+	Retries int
 }
 
 // BuildRegistryOptions returns a list of applicable ggcr.Option values
@@ -57,32 +72,39 @@ func Create(options ...Option) Parameters {
 // WithArchiveDir return an option to set the archive directory parameter.
 func WithArchiveDir(archiveDir string) Option {
 	return func(b Parameters) Parameters {
-		return Parameters{
-			ArchiveDir: archiveDir,
-			Logs:       b.Logs,
-			Transport:  b.Transport,
-		}
+		b.ArchiveDir = archiveDir
+		return b
 	}
 }
 
 // WithLogs return an option to set the logs parameter.
 func WithLogs(logs io.Writer) Option {
 	return func(b Parameters) Parameters {
-		return Parameters{
-			ArchiveDir: b.ArchiveDir,
-			Logs:       logs,
-			Transport:  b.Transport,
-		}
+		b.Logs = logs
+		return b
 	}
 }
 
 // WithTransport returns an option with the Transport parameter set.
 func WithTransport(transport *http.Transport) Option {
 	return func(b Parameters) Parameters {
-		return Parameters{
-			ArchiveDir: b.ArchiveDir,
-			Logs:       b.Logs,
-			Transport:  transport,
-		}
+		b.Transport = transport
+		return b
+	}
+}
+
+// WithParallelism returns an option with the Parallelism parameter set.
+func WithParallelism(parallelism int) Option {
+	return func(b Parameters) Parameters {
+		b.Parallelism = parallelism
+		return b
+	}
+}
+
+// WithRetries returns an option with the Retries parameter set.
+func WithRetries(retries int) Option {
+	return func(b Parameters) Parameters {
+		b.Retries = retries
+		return b
 	}
 }
@@ -10,6 +10,8 @@ import (
 
 	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/bundle/loader"
+	"github.com/cnabio/cnab-go/imagestore"
+	"github.com/cnabio/cnab-go/imagestore/construction"
 )
 
 // Importer is responsible for importing a file
@@ -17,6 +19,26 @@ type Importer struct {
 	Source      string
 	Destination string
 	Loader      loader.BundleLoader
+
+	// ImageStoreConstructor locates the images packaged with an imported
+	// bundle. Defaults to construction.NewLocatingConstructor, which uses the
+	// OCI image layout of a thick bundle and the remote registries of a thin
+	// bundle.
+	ImageStoreConstructor imagestore.Constructor
+}
+
+// ImportedBundle is a bundle unpacked from an archive.
+type ImportedBundle struct {
+	// Path is the directory where the archive was unpacked.
+	Path string
+
+	// Bundle is the bundle definition from the archive.
+	Bundle *bundle.Bundle
+
+	// ImageStore holds the bundle's images, for example to push them to a
+	// registry in an air-gapped environment. For a thin bundle, the images
+	// are copied from their original registries instead.
+	ImageStore imagestore.Store
 }
 
 // NewImporter creates a new secure *Importer
@@ -34,11 +56,63 @@ func NewImporter(source, destination string, load loader.BundleLoader) *Importer
 
 // Import decompresses a bundle from Source (location of the compressed bundle) and properly places artifacts in the correct location(s)
 func (im *Importer) Import() error {
-	_, _, err := im.Unzip()
+	_, err := im.ImportBundle()
+	return err
+}
 
-	// TODO: https://github.com/cnabio/cnab-go/issues/136
+// ImportBundle decompresses a bundle from Source, verifies that the digests
+// of the images packaged with a thick bundle match the bundle definition, and
+// returns the bundle along with an image store holding its images.
+func (im *Importer) ImportBundle() (ImportedBundle, error) {
+	dest, bun, err := im.Unzip()
+	if err != nil {
+		return ImportedBundle{}, err
+	}
 
-	return err
+	newImageStore := im.ImageStoreConstructor
+	if newImageStore == nil {
+		newImageStore = construction.NewLocatingConstructor()
+	}
+	store, err := newImageStore(imagestore.WithArchiveDir(dest))
+	if err != nil {
+		return ImportedBundle{}, fmt.Errorf("failed to locate the images for bundle %s: %s", bun.Name, err)
+	}
+
+	if finder, ok := store.(imagestore.Finder); ok {
+		if err := verifyImages(bun, finder); err != nil {
+			return ImportedBundle{}, err
+		}
+	}
+
+	return ImportedBundle{
+		Path:       dest,
+		Bundle:     bun,
+		ImageStore: store,
+	}, nil
+}
+
+// verifyImages checks that every image referenced by the bundle is in the
+// image store with the digest from the bundle definition.
+func verifyImages(bun *bundle.Bundle, finder imagestore.Finder) error {
+	images := make([]bundle.BaseImage, 0, len(bun.Images)+len(bun.InvocationImages))
+	for _, img := range bun.Images {
+		images = append(images, img.BaseImage)
+	}
+	for _, img := range bun.InvocationImages {
+		images = append(images, img.BaseImage)
+	}
+
+	for _, img := range images {
+		dig, err := finder.Find(img.Image)
+		if err != nil {
+			return fmt.Errorf("image %s is missing from the bundle archive: %s", img.Image, err)
+		}
+		if err := checkDigest(img, dig); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Unzip decompresses a bundle from Source (location of the compressed bundle) and returns the path of the bundle and the bundle itself.
@@ -1,14 +1,19 @@
 package packager
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/cnabio/cnab-go/bundle"
 	"github.com/cnabio/cnab-go/bundle/loader"
+	"github.com/cnabio/cnab-go/imagestore"
+	"github.com/cnabio/cnab-go/imagestore/imagestoremocks"
 )
 
 func TestImport(t *testing.T) {
@@ -51,3 +56,75 @@ func TestMalformedImport(t *testing.T) {
 		t.Error("expected malformed bundle error")
 	}
 }
+
+func TestImportBundle(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "duffle-import-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var archiveDir string
+	store := &imagestoremocks.MockStore{}
+	im := Importer{
+		Source:      "testdata/examplebun-0.1.0.tgz",
+		Destination: tempDir,
+		Loader:      loader.NewLoader(),
+		ImageStoreConstructor: func(options ...imagestore.Option) (imagestore.Store, error) {
+			archiveDir = imagestore.Create(options...).ArchiveDir
+			return store, nil
+		},
+	}
+
+	imported, err := im.ImportBundle()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tempDir, "examplebun-0.1.0"), imported.Path)
+	assert.Equal(t, imported.Path, archiveDir, "the image store should be located in the unpacked archive")
+	assert.Equal(t, "examplebun", imported.Bundle.Name)
+	assert.Same(t, store, imported.ImageStore)
+}
+
+func TestVerifyImages(t *testing.T) {
+	const (
+		digestA = "sha256:a2f0a0f4f8e8aef5f8e97e45c4b07e4e48d6b89a5e6f1cbbca4a1d96b53a5f3c"
+		digestB = "sha256:b4a2f5b6d8fd7d3d9f3e8e7c6b5a4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e"
+	)
+	bun := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "example.com/installer:v1", Digest: digestA}},
+		},
+		Images: map[string]bundle.Image{
+			"app": {BaseImage: bundle.BaseImage{Image: "example.com/app:v1"}},
+		},
+	}
+
+	testcases := []struct {
+		name    string
+		layout  map[string]string
+		wantErr string
+	}{
+		{name: "valid", layout: map[string]string{"example.com/installer:v1": digestA, "example.com/app:v1": digestB}},
+		{name: "digest mismatch", layout: map[string]string{"example.com/installer:v1": digestB, "example.com/app:v1": digestB}, wantErr: "content digest mismatch"},
+		{name: "missing image", layout: map[string]string{"example.com/installer:v1": digestA}, wantErr: "image example.com/app:v1 is missing from the bundle archive"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &imagestoremocks.MockStore{
+				FindStub: func(im string) (string, error) {
+					dig, ok := tc.layout[im]
+					if !ok {
+						return "", errors.New("not found")
+					}
+					return dig, nil
+				},
+			}
+
+			err := verifyImages(bun, store)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package parameters
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/schema"
+	"github.com/cnabio/cnab-go/secrets"
+	"github.com/cnabio/cnab-go/valuesource"
+)
+
+const (
+	// DefaultSchemaVersion is the default SchemaVersion value
+	// set on new ParameterSet instances, and is the semver portion
+	// of CNABSpecVersion.
+	DefaultSchemaVersion = schema.Version("1.0.0-DRAFT+b6c701f")
+
+	// CNABSpecVersion represents the CNAB Spec version of the Parameters
+	// that this library implements
+	// This value is prefixed with e.g. `cnab-parametersets-` so isn't itself valid semver.
+	CNABSpecVersion string = "cnab-parametersets-" + string(DefaultSchemaVersion)
+)
+
+// ParameterSet represents a collection of parameters
+type ParameterSet struct {
+	// SchemaVersion is the version of the parameter-set schema.
+	SchemaVersion schema.Version `json:"schemaVersion" yaml:"schemaVersion"`
+	// Name is the name of the parameterset.
+	Name string `json:"name" yaml:"name"`
+	// Created timestamp of the parameterset.
+	Created time.Time `json:"created" yaml:"created"`
+	// Modified timestamp of the parameterset.
+	Modified time.Time `json:"modified" yaml:"modified"`
+	// Parameters is a list of parameter specs.
+	Parameters []valuesource.Strategy `json:"parameters" yaml:"parameters"`
+}
+
+// NewParameterSet creates a new ParameterSet with the required fields initialized.
+func NewParameterSet(name string, params ...valuesource.Strategy) ParameterSet {
+	now := time.Now()
+	ps := ParameterSet{
+		SchemaVersion: DefaultSchemaVersion,
+		Name:          name,
+		Created:       now,
+		Modified:      now,
+		Parameters:    params,
+	}
+
+	return ps
+}
+
+// Load a ParameterSet from a file at a given path.
+//
+// It does not load the individual parameters.
+func Load(path string) (*ParameterSet, error) {
+	pset := &ParameterSet{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return pset, err
+	}
+	return pset, yaml.Unmarshal(data, pset)
+}
+
+// ResolveParameters looks up the parameters as described in Source, then copies
+// the resulting value into the Value field of each parameter strategy.
+//
+// The typical workflow for working with a parameter set is:
+//
+//   - Load the set
+//   - Resolve the parameters
+//   - Validate the parameters against the bundle
+//   - Convert them into the parameters of a claim
+func (p *ParameterSet) ResolveParameters(s secrets.Store) (valuesource.Set, error) {
+	l := len(p.Parameters)
+	res := make(map[string]string, l)
+	for i := 0; i < l; i++ {
+		param := p.Parameters[i]
+		val, err := s.Resolve(param.Source.Key, param.Source.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %v", p.Parameters[i].Name, err)
+		}
+		param.Value = val
+		res[p.Parameters[i].Name] = param.Value
+	}
+	return res, nil
+}
+
+// Validate compares the given parameters with the bundle.
+//
+// This will result in an error when any of the following conditions are true:
+// - a parameter in the given set is not defined by the bundle
+// - a parameter value cannot be converted to the type of its definition, or
+// is not valid according to its definition
+// - a parameter that applies to the specified action is required, does not
+// have a default, and is not present in the given set
+func Validate(given valuesource.Set, b bundle.Bundle, action string) error {
+	_, err := Convert(given, b, action)
+	return err
+}
+
+// Convert the given parameters into the values of the claim parameters, as
+// consumed by claim.New, using the types from the bundle's definitions.
+// Parameters that do not apply to the action are skipped. The given
+// parameters are validated as described by Validate, and every error is
+// returned.
+func Convert(given valuesource.Set, b bundle.Bundle, action string) (map[string]interface{}, error) {
+	return ConvertValues(given.Values(), b, action)
+}
+
+// ConvertValues converts the given parameters like Convert, with values that
+// keep their type. Strings are converted to the type of their definition,
+// while values of other kinds, such as integers or objects, are used as is
+// and only validated against their definition.
+func ConvertValues(given valuesource.Values, b bundle.Bundle, action string) (map[string]interface{}, error) {
+	var result *multierror.Error
+	values := make(map[string]interface{}, len(given))
+
+	for _, name := range sortedNames(given) {
+		param, ok := b.Parameters[name]
+		if !ok {
+			result = multierror.Append(result, errors.Errorf("parameter %s is not defined in the bundle", name))
+			continue
+		}
+		if !param.AppliesTo(action) {
+			continue
+		}
+
+		def, ok := b.Definitions[param.Definition]
+		if !ok {
+			result = multierror.Append(result, errors.Errorf("parameter %s: definition %s not found", name, param.Definition))
+			continue
+		}
+
+		value, err := convertTypedValue(def, given[name])
+		if err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "parameter %s", name))
+			continue
+		}
+		values[name] = value
+	}
+
+	required := make([]string, 0, len(b.Parameters))
+	for name := range b.Parameters {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	for _, name := range required {
+		param := b.Parameters[name]
+		if !param.Required || !param.AppliesTo(action) {
+			continue
+		}
+		if _, ok := given[name]; ok {
+			continue
+		}
+		if def, ok := b.Definitions[param.Definition]; ok && def.Default != nil {
+			continue
+		}
+		result = multierror.Append(result, errors.Errorf("bundle requires parameter %s", name))
+	}
+
+	if err := result.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// convertTypedValue converts a string to the type of the definition, and
+// validates values of other kinds against the definition.
+func convertTypedValue(def *definition.Schema, value valuesource.Value) (interface{}, error) {
+	if value.Kind == valuesource.KindString {
+		return convertValue(def, value.String())
+	}
+
+	converted, err := value.Interface()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateValue(def, converted, value.String()); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// convertValue converts the value to the type of the definition and
+// validates it. When the definition allows multiple types, each type is
+// tried in order.
+func convertValue(def *definition.Schema, value string) (interface{}, error) {
+	types, ok, _ := def.GetTypes()
+	if !ok {
+		return convertSingleValue(def, value)
+	}
+
+	var lastErr error
+	for _, t := range types {
+		typed := *def
+		typed.Type = t
+		converted, err := convertSingleValue(&typed, value)
+		if err == nil {
+			return converted, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// convertSingleValue converts the value to the single type of the definition
+// and validates it.
+func convertSingleValue(def *definition.Schema, value string) (interface{}, error) {
+	converted, err := def.ConvertValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateValue(def, converted, value); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// validateValue validates the converted value against the definition,
+// reporting the value as it was given.
+func validateValue(def *definition.Schema, converted interface{}, given string) error {
+	valErrs, err := def.Validate(converted)
+	if err != nil {
+		return err
+	}
+	if len(valErrs) > 0 {
+		msgs := make([]string, len(valErrs))
+		for i, valErr := range valErrs {
+			msgs[i] = valErr.Error
+		}
+		return errors.Errorf("invalid value %q: %s", given, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+func sortedNames(set valuesource.Values) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
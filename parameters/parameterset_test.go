@@ -0,0 +1,168 @@
+package parameters
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/schema"
+	"github.com/cnabio/cnab-go/secrets/host"
+	"github.com/cnabio/cnab-go/valuesource"
+)
+
+func testBundle() bundle.Bundle {
+	maxPort := float64(65535)
+	return bundle.Bundle{
+		Definitions: map[string]*definition.Schema{
+			"port":    {Type: "integer", Maximum: &maxPort},
+			"boolean": {Type: "boolean", Default: false},
+			"tags":    {Type: "array"},
+			"mode":    {Type: []interface{}{"boolean", "string"}},
+			"string":  {Type: "string"},
+		},
+		Parameters: map[string]bundle.Parameter{
+			"port":     {Definition: "port", Required: true},
+			"tls":      {Definition: "boolean", Required: true},
+			"tags":     {Definition: "tags"},
+			"mode":     {Definition: "mode"},
+			"password": {Definition: "string", Required: true, ApplyTo: []string{"install"}},
+		},
+	}
+}
+
+func TestParameterSet_ResolveParameters(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_PARAM_TLS", "true"))
+	defer os.Unsetenv("TEST_PARAM_TLS")
+
+	pset, err := Load("testdata/staging.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", pset.Name)
+
+	params, err := pset.ResolveParameters(&host.SecretStore{})
+	require.NoError(t, err)
+	assert.Equal(t, valuesource.Set{"port": "8080", "tls": "true", "tags": `["web", "db"]`}, params)
+
+	values, err := Convert(params, testBundle(), "upgrade")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"port": 8080,
+		"tls":  true,
+		"tags": []interface{}{"web", "db"},
+	}, values)
+}
+
+func TestConvert(t *testing.T) {
+	b := testBundle()
+
+	testcases := []struct {
+		name    string
+		given   valuesource.Set
+		action  string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "multiple types",
+			given:  valuesource.Set{"port": "80", "mode": "fast"},
+			action: "upgrade",
+			want:   map[string]interface{}{"port": 80, "mode": "fast"},
+		},
+		{
+			name:   "first matching type",
+			given:  valuesource.Set{"port": "80", "mode": "true"},
+			action: "upgrade",
+			want:   map[string]interface{}{"port": 80, "mode": true},
+		},
+		{
+			name:   "does not apply to the action",
+			given:  valuesource.Set{"port": "80", "password": "hunter2"},
+			action: "upgrade",
+			want:   map[string]interface{}{"port": 80},
+		},
+		{
+			name:    "required",
+			given:   valuesource.Set{},
+			action:  "install",
+			wantErr: "bundle requires parameter password",
+		},
+		{
+			name:    "undefined",
+			given:   valuesource.Set{"port": "80", "color": "blue"},
+			action:  "upgrade",
+			wantErr: "parameter color is not defined in the bundle",
+		},
+		{
+			name:    "invalid type",
+			given:   valuesource.Set{"port": "eighty"},
+			action:  "upgrade",
+			wantErr: `parameter port: strconv.Atoi: parsing "eighty": invalid syntax`,
+		},
+		{
+			name:    "invalid value",
+			given:   valuesource.Set{"port": "70000"},
+			action:  "upgrade",
+			wantErr: `parameter port: invalid value "70000"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := Convert(tc.given, b, tc.action)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				assert.EqualError(t, Validate(tc.given, b, tc.action), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, values)
+			assert.NoError(t, Validate(tc.given, b, tc.action))
+		})
+	}
+}
+
+func TestConvertValues(t *testing.T) {
+	b := testBundle()
+
+	port, err := valuesource.NewValue(80)
+	require.NoError(t, err)
+	mode, err := valuesource.NewValue(true)
+	require.NoError(t, err)
+
+	values, err := ConvertValues(valuesource.Values{"port": port, "mode": mode}, b, "upgrade")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"port": 80, "mode": true}, values)
+
+	values, err = ConvertValues(valuesource.Values{"port": valuesource.StringValue("80")}, b, "upgrade")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"port": 80}, values, "strings should be converted to the type of their definition")
+
+	_, err = ConvertValues(valuesource.Values{"port": mode}, b, "upgrade")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `parameter port: invalid value "true"`, "values of other kinds should not be converted")
+
+	tooLarge, err := valuesource.NewValue(70000)
+	require.NoError(t, err)
+	_, err = ConvertValues(valuesource.Values{"port": tooLarge}, b, "upgrade")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `parameter port: invalid value "70000"`)
+}
+
+func TestNewParameterSet(t *testing.T) {
+	ps := NewParameterSet("myparams", valuesource.Strategy{Name: "port", Source: valuesource.Source{Key: "value", Value: "80"}})
+
+	assert.Equal(t, "myparams", ps.Name)
+	assert.Equal(t, DefaultSchemaVersion, ps.SchemaVersion)
+	assert.Equal(t, ps.Created, ps.Modified)
+	assert.Len(t, ps.Parameters, 1)
+}
+
+func TestCNABSpecVersion(t *testing.T) {
+	version, err := schema.GetSemver(CNABSpecVersion)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSchemaVersion, version)
+}
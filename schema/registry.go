@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Schema types embedded in this package.
+const (
+	TypeBundle = "bundle"
+	TypeClaim  = "claim"
+)
+
+// versionsFile records the version of each embedded schema, and is updated
+// when the schemas are refreshed with `make fetch-schemas`.
+const versionsFile = "schema/versions.json"
+
+var (
+	registryMu sync.RWMutex
+
+	// registry holds the schemas registered with RegisterSchema, by type
+	// and then by version.
+	registry = map[string]map[Version][]byte{}
+)
+
+// RegisterSchema makes an additional version of a schema available to
+// GetSchema and ValidateVersion, for example a newer draft of the CNAB
+// specification that is not embedded in this package. A registered schema
+// replaces any schema previously registered with the same type and version.
+func RegisterSchema(schemaType string, version Version, data []byte) error {
+	if err := version.Validate(); err != nil {
+		return err
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("the %s schema version %s is not valid json", schemaType, version)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if registry[schemaType] == nil {
+		registry[schemaType] = map[Version][]byte{}
+	}
+	registry[schemaType][version] = append([]byte(nil), data...)
+	return nil
+}
+
+// GetSchema returns the schema of the specified type and version. When the
+// version is empty, the embedded schema is returned.
+func GetSchema(schemaType string, version Version) ([]byte, error) {
+	registryMu.RLock()
+	data, ok := registry[schemaType][version]
+	registryMu.RUnlock()
+	if ok {
+		return append([]byte(nil), data...), nil
+	}
+
+	embedded, err := GetEmbeddedVersion(schemaType)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" && version != embedded {
+		return nil, fmt.Errorf("the %s schema version %s is not available, the embedded version is %s", schemaType, version, embedded)
+	}
+
+	data, err = schemas.ReadFile(fmt.Sprintf("schema/%s.schema.json", schemaType))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the schema data for type %q", schemaType)
+	}
+	return data, nil
+}
+
+// GetBundleSchema returns the bundle schema with the specified version. When
+// the version is empty, the embedded schema is returned.
+func GetBundleSchema(version Version) ([]byte, error) {
+	return GetSchema(TypeBundle, version)
+}
+
+// GetClaimSchema returns the claim schema with the specified version. When
+// the version is empty, the embedded schema is returned.
+func GetClaimSchema(version Version) ([]byte, error) {
+	return GetSchema(TypeClaim, version)
+}
+
+// GetEmbeddedVersion returns the version of the schema of the specified type
+// that is embedded in this package.
+func GetEmbeddedVersion(schemaType string) (Version, error) {
+	versions, err := embeddedVersions()
+	if err != nil {
+		return "", err
+	}
+
+	version, ok := versions[schemaType]
+	if !ok {
+		return "", fmt.Errorf("unknown schema type %q", schemaType)
+	}
+	return version, nil
+}
+
+// ListVersions returns the available versions of the schema of the specified
+// type, including the embedded version, sorted by semver.
+func ListVersions(schemaType string) ([]Version, error) {
+	var versions []Version
+	if embedded, err := GetEmbeddedVersion(schemaType); err == nil {
+		versions = append(versions, embedded)
+	}
+
+	registryMu.RLock()
+	for version := range registry[schemaType] {
+		if len(versions) == 0 || version != versions[0] {
+			versions = append(versions, version)
+		}
+	}
+	registryMu.RUnlock()
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("unknown schema type %q", schemaType)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].lessThan(versions[j])
+	})
+	return versions, nil
+}
+
+func embeddedVersions() (map[string]Version, error) {
+	data, err := schemas.ReadFile(versionsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the embedded schema versions")
+	}
+
+	versions := map[string]Version{}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the embedded schema versions")
+	}
+	return versions, nil
+}
@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSchema(t *testing.T) {
+	t.Run("embedded", func(t *testing.T) {
+		version, err := GetEmbeddedVersion(TypeBundle)
+		require.NoError(t, err)
+
+		data, err := GetBundleSchema(version)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "https://cnab.io/v1/bundle.schema.json")
+
+		latest, err := GetBundleSchema("")
+		require.NoError(t, err)
+		assert.Equal(t, data, latest, "an empty version should return the embedded schema")
+
+		data, err = GetClaimSchema("")
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "https://cnab.io/v1/claim.schema.json")
+	})
+
+	t.Run("unavailable version", func(t *testing.T) {
+		_, err := GetClaimSchema("0.1.0")
+		require.EqualError(t, err, "the claim schema version 0.1.0 is not available, the embedded version is 1.0.0-DRAFT+b5ed2f3")
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := GetSchema("widget", "")
+		require.EqualError(t, err, `unknown schema type "widget"`)
+	})
+}
+
+func TestRegisterSchema(t *testing.T) {
+	defer func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, TypeBundle)
+		delete(registry, "widget")
+	}()
+
+	bundleSchema := []byte(`{"type": "object", "required": ["name", "color"]}`)
+	require.NoError(t, RegisterSchema(TypeBundle, "2.0.0", bundleSchema))
+	require.NoError(t, RegisterSchema("widget", "1.0.0", []byte(`{"type": "object"}`)))
+
+	data, err := GetBundleSchema("2.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, bundleSchema, data)
+
+	valErrs, err := ValidateVersion(TypeBundle, "2.0.0", []byte(`{"name": "mybun"}`))
+	require.NoError(t, err)
+	require.Len(t, valErrs, 1)
+	assert.EqualError(t, valErrs[0], "(root): color is required")
+
+	versions, err := ListVersions(TypeBundle)
+	require.NoError(t, err)
+	assert.Equal(t, []Version{"1.2.0", "2.0.0"}, versions)
+
+	versions, err = ListVersions("widget")
+	require.NoError(t, err)
+	assert.Equal(t, []Version{"1.0.0"}, versions, "types that are not embedded can be registered")
+
+	t.Run("invalid version", func(t *testing.T) {
+		err := RegisterSchema(TypeBundle, "latest", bundleSchema)
+		require.EqualError(t, err, `invalid schema version "latest": invalid semantic version`)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		err := RegisterSchema(TypeBundle, "2.1.0", []byte(`{"type":`))
+		require.EqualError(t, err, "the bundle schema version 2.1.0 is not valid json")
+	})
+}
@@ -2,7 +2,6 @@ package schema
 
 import (
 	"embed"
-	"fmt"
 
 	"github.com/pkg/errors"
 
@@ -32,17 +31,37 @@ func ValidateClaim(bytes []byte) ([]ValidationError, error) {
 
 // Validate validates the provided bytes against the provided CNAB-Spec schemaType
 func Validate(schemaType string, bytes []byte) ([]ValidationError, error) {
+	return ValidateVersion(schemaType, "", bytes)
+}
+
+// ValidateVersion validates the provided bytes against the specified version
+// of the provided CNAB-Spec schemaType, which is either embedded in this
+// package or registered with RegisterSchema. When the version is empty, the
+// embedded schema is used.
+func ValidateVersion(schemaType string, version Version, bytes []byte) ([]ValidationError, error) {
 	valErrs := []ValidationError{}
 
 	// Retrieve main schema bytes
-	schemaData, err := schemas.ReadFile(fmt.Sprintf("schema/%s.schema.json", schemaType))
+	schemaData, err := GetSchema(schemaType, version)
 	if err != nil {
-		return valErrs, errors.Wrapf(err, "failed to read the schema data for type %q", schemaType)
+		return valErrs, err
 	}
 
 	// Build schema validator
 	sl := gojsonschema.NewSchemaLoader()
 
+	// The claim schema references the bundle schema, which is resolved with
+	// the embedded bundle schema instead of being fetched from cnab.io
+	if schemaType == TypeClaim {
+		bundleData, err := GetBundleSchema("")
+		if err != nil {
+			return valErrs, err
+		}
+		if err := sl.AddSchemas(gojsonschema.NewBytesLoader(bundleData)); err != nil {
+			return valErrs, errors.Wrap(err, "unable to load the bundle schema referenced by the claim schema")
+		}
+	}
+
 	// Now add main schema and compile
 	schemaLoader := gojsonschema.NewBytesLoader(schemaData)
 	schema, err := sl.Compile(schemaLoader)
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"regexp"
 
-	"github.com/Masterminds/semver"
+	"github.com/Masterminds/semver/v3"
 )
 
 // Version represents the schema version of an object
@@ -40,3 +40,14 @@ func GetSemver(schemaVersion string) (Version, error) {
 
 	return version, nil
 }
+
+// lessThan compares the versions by semver, falling back to a string
+// comparison when either version is not valid semver.
+func (v Version) lessThan(other Version) bool {
+	a, errA := semver.NewVersion(string(v))
+	b, errB := semver.NewVersion(string(other))
+	if errA != nil || errB != nil {
+		return v < other
+	}
+	return a.LessThan(b)
+}
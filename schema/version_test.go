@@ -14,11 +14,11 @@ func TestValidate(t *testing.T) {
 	}{{
 		name:    "empty",
 		version: Version(""),
-		err:     `invalid schema version "": Invalid Semantic Version`,
+		err:     `invalid schema version "": invalid semantic version`,
 	}, {
 		name:    "invalid",
 		version: Version("not-semver"),
-		err:     `invalid schema version "not-semver": Invalid Semantic Version`,
+		err:     `invalid schema version "not-semver": invalid semantic version`,
 	}, {
 		name:    "valid",
 		version: Version("v1.0.0"),
@@ -56,7 +56,7 @@ func TestGetSemver(t *testing.T) {
 		name:     "match but invalid",
 		version:  "cnab-core-1.0.0.0",
 		expected: Version(""),
-		err:      `invalid schema version "1.0.0.0": Invalid Semantic Version`,
+		err:      `invalid schema version "1.0.0.0": invalid semantic version`,
 	}, {
 		name:     "match and valid",
 		version:  "cnab-core-1.0.0",
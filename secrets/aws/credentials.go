@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultIMDSEndpoint is the endpoint of the EC2 Instance Metadata Service.
+const DefaultIMDSEndpoint = "http://169.254.169.254"
+
+// Credentials are the AWS credentials used to sign requests.
+type Credentials struct {
+	// AccessKeyID of the credentials.
+	AccessKeyID string
+
+	// SecretAccessKey of the credentials.
+	SecretAccessKey string
+
+	// SessionToken of temporary credentials. Optional.
+	SessionToken string
+
+	// Expires is when temporary credentials expire. The zero value means
+	// that the credentials do not expire.
+	Expires time.Time
+}
+
+// CredentialsProvider retrieves the AWS credentials used to sign requests.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context, client *http.Client) (Credentials, error)
+}
+
+// StaticCredentials are credentials that do not change, such as those of an
+// IAM user.
+type StaticCredentials Credentials
+
+// Retrieve returns the credentials.
+func (c StaticCredentials) Retrieve(ctx context.Context, client *http.Client) (Credentials, error) {
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return Credentials{}, errors.New("the access key ID and secret access key are required")
+	}
+	return Credentials(c), nil
+}
+
+// InstanceRoleCredentials retrieves the temporary credentials of the IAM role
+// of the EC2 instance from the Instance Metadata Service, using IMDSv2.
+type InstanceRoleCredentials struct {
+	// Endpoint of the Instance Metadata Service. Defaults to DefaultIMDSEndpoint.
+	Endpoint string
+}
+
+// Retrieve the credentials of the instance's role.
+func (c InstanceRoleCredentials) Retrieve(ctx context.Context, client *http.Client) (Credentials, error) {
+	endpoint := strings.TrimSuffix(c.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = DefaultIMDSEndpoint
+	}
+
+	token, err := imdsRequest(ctx, client, http.MethodPut, endpoint+"/latest/api/token", "")
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, "could not retrieve an instance metadata token")
+	}
+
+	credentialsURL := endpoint + "/latest/meta-data/iam/security-credentials/"
+	role, err := imdsRequest(ctx, client, http.MethodGet, credentialsURL, string(token))
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, "could not retrieve the instance role")
+	}
+	roleName := strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0])
+	if roleName == "" {
+		return Credentials{}, errors.New("the instance does not have a role")
+	}
+
+	data, err := imdsRequest(ctx, client, http.MethodGet, credentialsURL+roleName, string(token))
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, "could not retrieve the credentials of the instance role %s", roleName)
+	}
+
+	var resp struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return Credentials{}, errors.Wrapf(err, "could not decode the credentials of the instance role %s", roleName)
+	}
+	return Credentials{
+		AccessKeyID:     resp.AccessKeyID,
+		SecretAccessKey: resp.SecretAccessKey,
+		SessionToken:    resp.Token,
+		Expires:         resp.Expiration,
+	}, nil
+}
+
+// imdsRequest sends a request to the Instance Metadata Service, returning the
+// response body.
+func imdsRequest(ctx context.Context, client *http.Client, method string, url string, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	} else {
+		req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("instance metadata service returned %s", resp.Status)
+	}
+	return body, nil
+}
@@ -0,0 +1,299 @@
+// Package aws resolves secrets from AWS Secrets Manager, so that credential
+// and parameter sets can reference values stored in Secrets Manager instead
+// of embedding them.
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/internal/sigv4"
+	"github.com/cnabio/cnab-go/secrets"
+)
+
+const (
+	// SourceSecretsManager is the key of a value source that is resolved from
+	// AWS Secrets Manager. The value is the name or ARN of the secret,
+	// optionally followed by the key of a value in a secret that holds JSON
+	// key/value pairs separated by a #, for example "myapp/db#password" or
+	// "arn:aws:secretsmanager:us-east-1:123456789012:secret:myapp/db-AbCdEf#password".
+	SourceSecretsManager = "aws-secretsmanager"
+
+	// EnvRegion is the environment variable for the AWS region.
+	EnvRegion = "AWS_REGION"
+
+	// EnvDefaultRegion is the environment variable for the AWS region, used
+	// when AWS_REGION is not set.
+	EnvDefaultRegion = "AWS_DEFAULT_REGION"
+
+	// EnvAccessKeyID is the environment variable for the access key ID.
+	EnvAccessKeyID = "AWS_ACCESS_KEY_ID"
+
+	// EnvSecretAccessKey is the environment variable for the secret access key.
+	EnvSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+
+	// EnvSessionToken is the environment variable for the session token.
+	EnvSessionToken = "AWS_SESSION_TOKEN"
+
+	// signingService is the name of the Secrets Manager service used to sign requests.
+	signingService = "secretsmanager"
+
+	// credentialsRefreshWindow is how long before temporary credentials
+	// expire that they are refreshed.
+	credentialsRefreshWindow = 5 * time.Minute
+)
+
+var _ secrets.Store = &SecretStore{}
+
+// Config of the connection to AWS Secrets Manager.
+type Config struct {
+	// Region of the secrets that are referenced by name. Secrets referenced
+	// by ARN are read from the region in the ARN.
+	Region string
+
+	// Credentials used to sign requests. Required.
+	Credentials CredentialsProvider
+
+	// Endpoint of Secrets Manager, for example a VPC endpoint. Defaults to
+	// https://secretsmanager.REGION.amazonaws.com.
+	Endpoint string
+
+	// HTTPClient used to connect to AWS. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewConfigFromEnv creates a Config using the standard AWS environment
+// variables, using the credentials from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY when set, and the credentials of the EC2 instance's
+// role otherwise.
+func NewConfigFromEnv() Config {
+	region := os.Getenv(EnvRegion)
+	if region == "" {
+		region = os.Getenv(EnvDefaultRegion)
+	}
+
+	var creds CredentialsProvider = InstanceRoleCredentials{}
+	if keyID := os.Getenv(EnvAccessKeyID); keyID != "" {
+		creds = StaticCredentials{
+			AccessKeyID:     keyID,
+			SecretAccessKey: os.Getenv(EnvSecretAccessKey),
+			SessionToken:    os.Getenv(EnvSessionToken),
+		}
+	}
+
+	return Config{
+		Region:      region,
+		Credentials: creds,
+	}
+}
+
+// SecretStore resolves values from AWS Secrets Manager.
+type SecretStore struct {
+	config Config
+	now    func() time.Time
+
+	credsMutex sync.Mutex
+	creds      *Credentials
+}
+
+// NewSecretStore creates a SecretStore with the specified configuration.
+func NewSecretStore(config Config) (*SecretStore, error) {
+	if config.Credentials == nil {
+		return nil, errors.New("AWS credentials are required")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+
+	return &SecretStore{
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// Resolve the value of a secret stored in AWS Secrets Manager.
+// - keyName must be "aws-secretsmanager".
+// - keyValue is the name or ARN of the secret and the key, for example "myapp/db#password".
+func (s *SecretStore) Resolve(keyName string, keyValue string) (string, error) {
+	if strings.ToLower(keyName) != SourceSecretsManager {
+		return "", fmt.Errorf("invalid value source: %s", keyName)
+	}
+
+	secretID, key := keyValue, ""
+	if i := strings.LastIndex(keyValue, "#"); i >= 0 {
+		secretID, key = keyValue[:i], keyValue[i+1:]
+	}
+	if secretID == "" {
+		return "", fmt.Errorf("invalid secrets manager secret %q: the name or ARN is required", keyValue)
+	}
+
+	region := s.config.Region
+	if strings.HasPrefix(secretID, "arn:") {
+		parts := strings.SplitN(secretID, ":", 7)
+		if len(parts) != 7 || parts[2] != signingService || parts[3] == "" {
+			return "", fmt.Errorf("invalid secrets manager secret %q: the ARN is not a secrets manager ARN", keyValue)
+		}
+		region = parts[3]
+	}
+	if region == "" {
+		return "", fmt.Errorf("the AWS region is required to read the secrets manager secret %s", secretID)
+	}
+
+	value, err := s.getSecretValue(context.Background(), region, secretID)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read secrets manager secret %s", secretID)
+	}
+
+	if key == "" {
+		return value, nil
+	}
+	return selectKey(secretID, key, value)
+}
+
+// awsError is an error returned by an AWS JSON API.
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// expired determines if the error was caused by expired credentials.
+func (e awsError) expired() bool {
+	return strings.HasSuffix(e.Type, "ExpiredTokenException")
+}
+
+// getSecretValue reads the value of the secret, refreshing the credentials
+// and retrying once when they have expired.
+func (s *SecretStore) getSecretValue(ctx context.Context, region string, secretID string) (string, error) {
+	value, awsErr, err := s.tryGetSecretValue(ctx, region, secretID)
+	if awsErr != nil && awsErr.expired() {
+		s.clearCredentials()
+		value, _, err = s.tryGetSecretValue(ctx, region, secretID)
+	}
+	return value, err
+}
+
+func (s *SecretStore) tryGetSecretValue(ctx context.Context, region string, secretID string) (string, *awsError, error) {
+	creds, err := s.getCredentials(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", nil, err
+	}
+
+	endpoint := s.config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, sigv4.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, region, signingService, sigv4.HashPayload(body), s.now())
+
+	resp, err := s.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsErr awsError
+		if json.Unmarshal(respBody, &awsErr) == nil && awsErr.Type != "" {
+			errType := awsErr.Type[strings.LastIndex(awsErr.Type, "#")+1:]
+			return "", &awsErr, fmt.Errorf("secrets manager returned %s: %s: %s", resp.Status, errType, awsErr.Message)
+		}
+		return "", nil, fmt.Errorf("secrets manager returned %s", resp.Status)
+	}
+
+	var secret struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary []byte  `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(respBody, &secret); err != nil {
+		return "", nil, errors.Wrap(err, "could not decode the secret")
+	}
+	if secret.SecretString != nil {
+		return *secret.SecretString, nil, nil
+	}
+	return string(secret.SecretBinary), nil, nil
+}
+
+// getCredentials returns the credentials used to sign requests, retrieving
+// them on first use and when temporary credentials are about to expire.
+func (s *SecretStore) getCredentials(ctx context.Context) (Credentials, error) {
+	s.credsMutex.Lock()
+	defer s.credsMutex.Unlock()
+
+	if s.creds != nil && (s.creds.Expires.IsZero() || s.now().Add(credentialsRefreshWindow).Before(s.creds.Expires)) {
+		return *s.creds, nil
+	}
+
+	creds, err := s.config.Credentials.Retrieve(ctx, s.config.HTTPClient)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, "could not retrieve AWS credentials")
+	}
+	s.creds = &creds
+	return creds, nil
+}
+
+func (s *SecretStore) clearCredentials() {
+	s.credsMutex.Lock()
+	defer s.credsMutex.Unlock()
+	s.creds = nil
+}
+
+// selectKey returns the value of the key from a secret that holds JSON
+// key/value pairs.
+func selectKey(secretID string, key string, secretValue string) (string, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(secretValue), &data); err != nil {
+		return "", fmt.Errorf("secrets manager secret %s does not hold JSON key/value pairs, remove #%s to use the whole secret", secretID, key)
+	}
+
+	value, ok := data[key]
+	if !ok {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return "", fmt.Errorf("secrets manager secret %s does not have the key %s, it has the keys %s", secretID, key, strings.Join(keys, ", "))
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrapf(err, "could not convert the key %s of secrets manager secret %s", key, secretID)
+		}
+		return string(encoded), nil
+	}
+}
@@ -0,0 +1,209 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAWS simulates Secrets Manager and the EC2 Instance Metadata Service.
+type testAWS struct {
+	*httptest.Server
+
+	// expires is when the instance role credentials expire.
+	expires time.Time
+
+	// expiredKey is an access key ID that Secrets Manager rejects as expired.
+	expiredKey string
+
+	credentials int32
+	reads       int32
+}
+
+func newTestAWS(t *testing.T) *testAWS {
+	a := &testAWS{expires: time.Now().Add(time.Hour)}
+
+	secretValues := map[string]map[string]interface{}{
+		"myapp/db":  {"SecretString": `{"username": "admin", "password": "topsecret", "port": 5432}`},
+		"myapp/api": {"SecretString": "abc123"},
+		"myapp/key": {"SecretBinary": []byte("binary")},
+		"arn:aws:secretsmanager:eu-west-1:123456789012:secret:myapp/db-AbCdEf": {"SecretString": "from-arn"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		w.Write([]byte("imds-token"))
+	})
+	mux.HandleFunc("/latest/meta-data/iam/security-credentials/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		role := strings.TrimPrefix(r.URL.Path, "/latest/meta-data/iam/security-credentials/")
+		if role == "" {
+			w.Write([]byte("cnab-role\n"))
+			return
+		}
+		n := atomic.AddInt32(&a.credentials, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"AccessKeyId":     fmt.Sprintf("ASIA%d", n),
+			"SecretAccessKey": "secret",
+			"Token":           "session",
+			"Expiration":      a.expires,
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "MissingAuthenticationTokenException", "message": "Missing Authentication Token"}`))
+			return
+		}
+		if a.expiredKey != "" && strings.Contains(auth, "Credential="+a.expiredKey+"/") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "com.amazon.coral.service#ExpiredTokenException", "message": "The security token included in the request is expired"}`))
+			return
+		}
+
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		atomic.AddInt32(&a.reads, 1)
+		secret, ok := secretValues[body["SecretId"]]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		json.NewEncoder(w).Encode(secret)
+	})
+
+	a.Server = httptest.NewServer(mux)
+	return a
+}
+
+func (a *testAWS) newStore(t *testing.T, creds CredentialsProvider) *SecretStore {
+	s, err := NewSecretStore(Config{Region: "us-east-1", Endpoint: a.URL, Credentials: creds})
+	require.NoError(t, err)
+	return s
+}
+
+func TestSecretStore_Resolve(t *testing.T) {
+	a := newTestAWS(t)
+	defer a.Close()
+
+	s := a.newStore(t, StaticCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"})
+
+	testcases := []struct {
+		name     string
+		keyValue string
+		want     string
+		wantErr  string
+	}{
+		{name: "whole secret", keyValue: "myapp/api", want: "abc123"},
+		{name: "key", keyValue: "myapp/db#password", want: "topsecret"},
+		{name: "json key", keyValue: "myapp/db#port", want: "5432"},
+		{name: "binary", keyValue: "myapp/key", want: "binary"},
+		{name: "arn", keyValue: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:myapp/db-AbCdEf", want: "from-arn"},
+		{name: "missing key", keyValue: "myapp/db#host", wantErr: "secrets manager secret myapp/db does not have the key host, it has the keys password, port, username"},
+		{name: "not json", keyValue: "myapp/api#token", wantErr: "secrets manager secret myapp/api does not hold JSON key/value pairs, remove #token to use the whole secret"},
+		{name: "missing secret", keyValue: "myapp/missing", wantErr: "could not read secrets manager secret myapp/missing: secrets manager returned 400 Bad Request: ResourceNotFoundException: Secrets Manager can't find the specified secret."},
+		{name: "invalid arn", keyValue: "arn:aws:ssm:us-east-1:123456789012:parameter/db", wantErr: `invalid secrets manager secret "arn:aws:ssm:us-east-1:123456789012:parameter/db": the ARN is not a secrets manager ARN`},
+		{name: "missing name", keyValue: "#password", wantErr: `invalid secrets manager secret "#password": the name or ARN is required`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Resolve(SourceSecretsManager, tc.keyValue)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := s.Resolve("env", "AWS_SECRET_ACCESS_KEY")
+	require.EqualError(t, err, "invalid value source: env")
+
+	t.Run("missing region", func(t *testing.T) {
+		s, err := NewSecretStore(Config{Endpoint: a.URL, Credentials: StaticCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}})
+		require.NoError(t, err)
+		_, err = s.Resolve(SourceSecretsManager, "myapp/api")
+		require.EqualError(t, err, "the AWS region is required to read the secrets manager secret myapp/api")
+	})
+}
+
+func TestSecretStore_Credentials(t *testing.T) {
+	a := newTestAWS(t)
+	defer a.Close()
+
+	creds := InstanceRoleCredentials{Endpoint: a.URL}
+
+	t.Run("reused", func(t *testing.T) {
+		s := a.newStore(t, creds)
+		for _, key := range []string{"username", "password"} {
+			_, err := s.Resolve(SourceSecretsManager, "myapp/db#"+key)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&a.reads), "expected the secret to be read each time")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&a.credentials), "expected the credentials to be retrieved once")
+	})
+
+	t.Run("credentials refresh", func(t *testing.T) {
+		atomic.StoreInt32(&a.credentials, 0)
+		s := a.newStore(t, creds)
+
+		_, err := s.Resolve(SourceSecretsManager, "myapp/api")
+		require.NoError(t, err)
+
+		// Move the clock to within the refresh window of the credentials
+		s.now = func() time.Time { return a.expires.Add(-time.Minute) }
+		_, err = s.Resolve(SourceSecretsManager, "myapp/api")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&a.credentials), "expected the credentials to be refreshed before they expire")
+	})
+
+	t.Run("expired credentials", func(t *testing.T) {
+		atomic.StoreInt32(&a.credentials, 0)
+		a.expiredKey = "ASIA1"
+		defer func() { a.expiredKey = "" }()
+
+		s := a.newStore(t, creds)
+		got, err := s.Resolve(SourceSecretsManager, "myapp/api")
+		require.NoError(t, err)
+		assert.Equal(t, "abc123", got)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&a.credentials), "expected new credentials to be retrieved after they were rejected")
+	})
+}
+
+func TestNewSecretStore_Invalid(t *testing.T) {
+	_, err := NewSecretStore(Config{Region: "us-east-1"})
+	require.EqualError(t, err, "AWS credentials are required")
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvRegion, "")
+	t.Setenv(EnvDefaultRegion, "us-west-2")
+	t.Setenv(EnvAccessKeyID, "AKID")
+	t.Setenv(EnvSecretAccessKey, "secret")
+	t.Setenv(EnvSessionToken, "session")
+
+	cfg := NewConfigFromEnv()
+	assert.Equal(t, "us-west-2", cfg.Region)
+	assert.Equal(t, StaticCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}, cfg.Credentials)
+
+	t.Setenv(EnvAccessKeyID, "")
+	cfg = NewConfigFromEnv()
+	assert.Equal(t, InstanceRoleCredentials{}, cfg.Credentials)
+}
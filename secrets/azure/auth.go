@@ -0,0 +1,173 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultAuthorityHost is the Azure Active Directory endpoint of the
+	// Azure public cloud.
+	DefaultAuthorityHost = "https://login.microsoftonline.com"
+
+	// DefaultIMDSEndpoint is the Azure Instance Metadata Service endpoint
+	// that issues tokens for managed identities.
+	DefaultIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+	// keyVaultResource is the resource that tokens for Key Vault are issued for.
+	keyVaultResource = "https://vault.azure.net"
+)
+
+// AccessToken is an Azure Active Directory token used to call Key Vault.
+type AccessToken struct {
+	// Token is the bearer token.
+	Token string
+
+	// ExpiresOn is when the token expires.
+	ExpiresOn time.Time
+}
+
+// Credential retrieves tokens for Key Vault from Azure Active Directory.
+type Credential interface {
+	GetToken(ctx context.Context, client *http.Client) (AccessToken, error)
+}
+
+// tokenResponse is the response from Azure Active Directory or the Instance
+// Metadata Service when requesting a token. The Instance Metadata Service
+// returns expires_in as a string.
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+func (r tokenResponse) accessToken() (AccessToken, error) {
+	if r.AccessToken == "" {
+		return AccessToken{}, errors.New("no access token was returned")
+	}
+	expiresIn, err := strconv.ParseInt(r.ExpiresIn.String(), 10, 64)
+	if err != nil {
+		return AccessToken{}, errors.Wrapf(err, "invalid token expiry %q", r.ExpiresIn)
+	}
+	return AccessToken{
+		Token:     r.AccessToken,
+		ExpiresOn: time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}, nil
+}
+
+// ClientSecretCredential authenticates a service principal with a client secret.
+type ClientSecretCredential struct {
+	// TenantID of the service principal.
+	TenantID string
+
+	// ClientID of the service principal.
+	ClientID string
+
+	// ClientSecret of the service principal.
+	ClientSecret string
+
+	// AuthorityHost is the Azure Active Directory endpoint. Defaults to
+	// DefaultAuthorityHost.
+	AuthorityHost string
+}
+
+// GetToken requests a token with the client credentials grant.
+func (c ClientSecretCredential) GetToken(ctx context.Context, client *http.Client) (AccessToken, error) {
+	if c.TenantID == "" || c.ClientID == "" || c.ClientSecret == "" {
+		return AccessToken{}, errors.New("the tenant ID, client ID and client secret are required")
+	}
+	authority := c.AuthorityHost
+	if authority == "" {
+		authority = DefaultAuthorityHost
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"scope":         {keyVaultResource + "/.default"},
+	}
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), url.PathEscape(c.TenantID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return AccessToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	token, err := requestToken(client, req)
+	return token, errors.Wrap(err, "client secret authentication failed")
+}
+
+// ManagedIdentityCredential authenticates with the managed identity of the
+// Azure resource, such as a virtual machine or an AKS pod, that is running.
+type ManagedIdentityCredential struct {
+	// ClientID of a user-assigned managed identity. Leave blank to use the
+	// system-assigned managed identity.
+	ClientID string
+
+	// Endpoint of the Instance Metadata Service. Defaults to
+	// DefaultIMDSEndpoint.
+	Endpoint string
+}
+
+// GetToken requests a token from the Instance Metadata Service.
+func (c ManagedIdentityCredential) GetToken(ctx context.Context, client *http.Client) (AccessToken, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultIMDSEndpoint
+	}
+
+	query := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {keyVaultResource},
+	}
+	if c.ClientID != "" {
+		query.Set("client_id", c.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	token, err := requestToken(client, req)
+	return token, errors.Wrap(err, "managed identity authentication failed")
+}
+
+// requestToken sends a token request and decodes the token from the response.
+func requestToken(client *http.Client, req *http.Request) (AccessToken, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return AccessToken{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var authErr struct {
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &authErr) == nil && authErr.Description != "" {
+			return AccessToken{}, fmt.Errorf("azure active directory returned %s: %s", resp.Status, authErr.Description)
+		}
+		return AccessToken{}, fmt.Errorf("azure active directory returned %s", resp.Status)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return AccessToken{}, errors.Wrap(err, "could not decode the token response")
+	}
+	return tr.accessToken()
+}
@@ -0,0 +1,224 @@
+// Package azure resolves secrets from Azure Key Vault, so that credential and
+// parameter sets can reference values stored in a key vault instead of
+// embedding them.
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/secrets"
+)
+
+const (
+	// SourceKeyVault is the key of a value source that is resolved from Azure
+	// Key Vault. The value is the identifier of the secret, for example
+	// "https://myvault.vault.azure.net/secrets/db-password", optionally
+	// followed by the version of the secret. The latest version is used when
+	// the version is omitted.
+	SourceKeyVault = "azure-keyvault"
+
+	// EnvTenantID is the environment variable for the tenant of the service principal.
+	EnvTenantID = "AZURE_TENANT_ID"
+
+	// EnvClientID is the environment variable for the client ID of the
+	// service principal or user-assigned managed identity.
+	EnvClientID = "AZURE_CLIENT_ID"
+
+	// EnvClientSecret is the environment variable for the client secret of the service principal.
+	EnvClientSecret = "AZURE_CLIENT_SECRET"
+
+	// apiVersion of the Key Vault REST API.
+	apiVersion = "7.4"
+
+	// tokenRefreshWindow is how long before a token expires that it is refreshed.
+	tokenRefreshWindow = 5 * time.Minute
+)
+
+var _ secrets.Store = &SecretStore{}
+
+// Config of the connection to Azure Key Vault.
+type Config struct {
+	// Credential used to retrieve tokens for Key Vault. Required.
+	Credential Credential
+
+	// HTTPClient used to connect to Azure. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewConfigFromEnv creates a Config using the standard Azure environment
+// variables, authenticating as a service principal when AZURE_CLIENT_SECRET
+// is set, and with a managed identity otherwise.
+func NewConfigFromEnv() Config {
+	if secret := os.Getenv(EnvClientSecret); secret != "" {
+		return Config{
+			Credential: ClientSecretCredential{
+				TenantID:     os.Getenv(EnvTenantID),
+				ClientID:     os.Getenv(EnvClientID),
+				ClientSecret: secret,
+			},
+		}
+	}
+	return Config{
+		Credential: ManagedIdentityCredential{ClientID: os.Getenv(EnvClientID)},
+	}
+}
+
+// SecretStore resolves values from Azure Key Vault.
+type SecretStore struct {
+	config Config
+
+	tokenMutex sync.Mutex
+	token      AccessToken
+}
+
+// NewSecretStore creates a SecretStore with the specified configuration.
+func NewSecretStore(config Config) (*SecretStore, error) {
+	if config.Credential == nil {
+		return nil, errors.New("an Azure credential is required")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	return &SecretStore{config: config}, nil
+}
+
+// Resolve the value of a secret stored in Azure Key Vault.
+// - keyName must be "azure-keyvault".
+// - keyValue is the identifier of the secret, for example "https://myvault.vault.azure.net/secrets/db-password".
+func (s *SecretStore) Resolve(keyName string, keyValue string) (string, error) {
+	if strings.ToLower(keyName) != SourceKeyVault {
+		return "", fmt.Errorf("invalid value source: %s", keyName)
+	}
+
+	secretURL, err := parseSecretID(keyValue)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := s.getSecret(context.Background(), secretURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read key vault secret %s", keyValue)
+	}
+	return value, nil
+}
+
+// parseSecretID validates the identifier of a secret, returning the URL of
+// the secret without a query or fragment.
+func parseSecretID(secretID string) (string, error) {
+	u, err := url.Parse(secretID)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid key vault secret %q", secretID)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid key vault secret %q: expected an https URL, for example https://myvault.vault.azure.net/secrets/NAME", secretID)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "secrets" || parts[1] == "" {
+		return "", fmt.Errorf("invalid key vault secret %q: expected the path /secrets/NAME[/VERSION]", secretID)
+	}
+
+	u.Path = "/" + strings.Join(parts, "/")
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// getSecret reads the value of the secret, refreshing the token and retrying
+// once when Key Vault rejects the token.
+func (s *SecretStore) getSecret(ctx context.Context, secretURL string) (string, error) {
+	value, err := s.tryGetSecret(ctx, secretURL)
+	if err == errUnauthorized {
+		s.clearToken()
+		value, err = s.tryGetSecret(ctx, secretURL)
+	}
+	return value, err
+}
+
+var errUnauthorized = errors.New("key vault returned 401 Unauthorized")
+
+func (s *SecretStore) tryGetSecret(ctx context.Context, secretURL string) (string, error) {
+	token, err := s.getToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL+"?api-version="+apiVersion, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", errUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		var kvErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &kvErr) == nil && kvErr.Error.Message != "" {
+			return "", fmt.Errorf("key vault returned %s: %s", resp.Status, kvErr.Error.Message)
+		}
+		return "", fmt.Errorf("key vault returned %s", resp.Status)
+	}
+
+	var secret struct {
+		Value *string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", errors.Wrap(err, "could not decode the secret")
+	}
+	if secret.Value == nil {
+		return "", errors.New("the secret has no value")
+	}
+	return *secret.Value, nil
+}
+
+// getToken returns a token for Key Vault, retrieving a new token on first
+// use and when the current token is about to expire.
+func (s *SecretStore) getToken(ctx context.Context) (string, error) {
+	s.tokenMutex.Lock()
+	defer s.tokenMutex.Unlock()
+
+	if s.token.Token != "" && time.Now().Add(tokenRefreshWindow).Before(s.token.ExpiresOn) {
+		return s.token.Token, nil
+	}
+
+	token, err := s.config.Credential.GetToken(ctx, s.config.HTTPClient)
+	if err != nil {
+		return "", errors.Wrap(err, "could not authenticate to azure")
+	}
+	s.token = token
+	return token.Token, nil
+}
+
+func (s *SecretStore) clearToken() {
+	s.tokenMutex.Lock()
+	defer s.tokenMutex.Unlock()
+	s.token = AccessToken{}
+}
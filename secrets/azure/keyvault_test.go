@@ -0,0 +1,183 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAzure simulates Azure Active Directory, the Instance Metadata Service
+// and a key vault.
+type testAzure struct {
+	*httptest.Server
+
+	// expiresIn is the lifetime of the tokens issued, in seconds.
+	expiresIn int
+
+	// rejected is a token that the key vault rejects.
+	rejected string
+
+	tokens  int32
+	secrets int32
+}
+
+func newTestAzure(t *testing.T) *testAzure {
+	az := &testAzure{expiresIn: 3600}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mytenant/oauth2/v2.0/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.Form.Get("client_id") != "myclient" || r.Form.Get("client_secret") != "mysecret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid_client", "error_description": "invalid client secret"}`))
+			return
+		}
+		assert.Equal(t, "https://vault.azure.net/.default", r.Form.Get("scope"))
+		n := atomic.AddInt32(&az.tokens, 1)
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": %d}`, n, az.expiresIn)
+	})
+	mux.HandleFunc("/metadata/identity/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("Metadata"))
+		assert.Equal(t, "https://vault.azure.net", r.URL.Query().Get("resource"))
+		n := atomic.AddInt32(&az.tokens, 1)
+		// The Instance Metadata Service returns the expiry as a string
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": "%d"}`, n, az.expiresIn)
+	})
+	mux.HandleFunc("/secrets/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiVersion, r.URL.Query().Get("api-version"))
+		if auth := r.Header.Get("Authorization"); auth == "" || auth == "Bearer "+az.rejected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&az.secrets, 1)
+		switch r.URL.Path {
+		case "/secrets/db-password":
+			w.Write([]byte(`{"value": "topsecret", "id": "latest"}`))
+		case "/secrets/db-password/v1":
+			w.Write([]byte(`{"value": "oldsecret", "id": "v1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": "SecretNotFound", "message": "A secret with (name/id) missing was not found in this key vault."}}`))
+		}
+	})
+
+	az.Server = httptest.NewTLSServer(mux)
+	return az
+}
+
+func (az *testAzure) newStore(t *testing.T, cred Credential) *SecretStore {
+	s, err := NewSecretStore(Config{Credential: cred, HTTPClient: az.Client()})
+	require.NoError(t, err)
+	return s
+}
+
+func TestSecretStore_Resolve(t *testing.T) {
+	az := newTestAzure(t)
+	defer az.Close()
+
+	s := az.newStore(t, ClientSecretCredential{TenantID: "mytenant", ClientID: "myclient", ClientSecret: "mysecret", AuthorityHost: az.URL})
+
+	testcases := []struct {
+		name     string
+		keyValue string
+		want     string
+		wantErr  string
+	}{
+		{name: "latest", keyValue: az.URL + "/secrets/db-password", want: "topsecret"},
+		{name: "version", keyValue: az.URL + "/secrets/db-password/v1", want: "oldsecret"},
+		{name: "missing secret", keyValue: az.URL + "/secrets/missing",
+			wantErr: "could not read key vault secret " + az.URL + "/secrets/missing: key vault returned 404 Not Found: A secret with (name/id) missing was not found in this key vault."},
+		{name: "not https", keyValue: "http://myvault.vault.azure.net/secrets/db-password",
+			wantErr: `invalid key vault secret "http://myvault.vault.azure.net/secrets/db-password": expected an https URL, for example https://myvault.vault.azure.net/secrets/NAME`},
+		{name: "not a secret", keyValue: "https://myvault.vault.azure.net/keys/db-password",
+			wantErr: `invalid key vault secret "https://myvault.vault.azure.net/keys/db-password": expected the path /secrets/NAME[/VERSION]`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Resolve(SourceKeyVault, tc.keyValue)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := s.Resolve("env", "AZURE_CLIENT_SECRET")
+	require.EqualError(t, err, "invalid value source: env")
+}
+
+func TestSecretStore_Tokens(t *testing.T) {
+	az := newTestAzure(t)
+	defer az.Close()
+
+	cred := ManagedIdentityCredential{Endpoint: az.URL + "/metadata/identity/oauth2/token"}
+
+	t.Run("reused", func(t *testing.T) {
+		s := az.newStore(t, cred)
+		for i := 0; i < 3; i++ {
+			got, err := s.Resolve(SourceKeyVault, az.URL+"/secrets/db-password")
+			require.NoError(t, err)
+			assert.Equal(t, "topsecret", got)
+		}
+		assert.Equal(t, int32(3), atomic.LoadInt32(&az.secrets), "expected the secret to be read each time")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&az.tokens), "expected one token to be retrieved")
+	})
+
+	t.Run("token refresh", func(t *testing.T) {
+		atomic.StoreInt32(&az.tokens, 0)
+		az.expiresIn = 60 // within the refresh window
+		defer func() { az.expiresIn = 3600 }()
+
+		s := az.newStore(t, cred)
+		for i := 0; i < 2; i++ {
+			_, err := s.Resolve(SourceKeyVault, az.URL+"/secrets/db-password")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&az.tokens), "expected the token to be refreshed before it expires")
+	})
+
+	t.Run("rejected token", func(t *testing.T) {
+		atomic.StoreInt32(&az.tokens, 0)
+		az.rejected = "revoked"
+		defer func() { az.rejected = "" }()
+
+		s := az.newStore(t, cred)
+		s.token = AccessToken{Token: "revoked", ExpiresOn: time.Now().Add(time.Hour)}
+		_, err := s.Resolve(SourceKeyVault, az.URL+"/secrets/db-password")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&az.tokens), "expected a new token to be retrieved after the token was rejected")
+	})
+}
+
+func TestSecretStore_InvalidCredential(t *testing.T) {
+	az := newTestAzure(t)
+	defer az.Close()
+
+	s := az.newStore(t, ClientSecretCredential{TenantID: "mytenant", ClientID: "myclient", ClientSecret: "wrong", AuthorityHost: az.URL})
+	_, err := s.Resolve(SourceKeyVault, az.URL+"/secrets/db-password")
+	require.EqualError(t, err, "could not read key vault secret "+az.URL+"/secrets/db-password: could not authenticate to azure: client secret authentication failed: azure active directory returned 401 Unauthorized: invalid client secret")
+
+	_, err = NewSecretStore(Config{})
+	require.EqualError(t, err, "an Azure credential is required")
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvTenantID, "mytenant")
+	t.Setenv(EnvClientID, "myclient")
+	t.Setenv(EnvClientSecret, "mysecret")
+	cfg := NewConfigFromEnv()
+	assert.Equal(t, ClientSecretCredential{TenantID: "mytenant", ClientID: "myclient", ClientSecret: "mysecret"}, cfg.Credential)
+
+	t.Setenv(EnvClientSecret, "")
+	cfg = NewConfigFromEnv()
+	assert.Equal(t, ManagedIdentityCredential{ClientID: "myclient"}, cfg.Credential)
+}
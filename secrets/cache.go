@@ -0,0 +1,110 @@
+package secrets
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long a CachingStore caches a resolved value when a
+// time to live is not specified.
+const DefaultCacheTTL = 5 * time.Minute
+
+// CacheOptions configures a CachingStore.
+type CacheOptions struct {
+	// TTL is how long resolved values are cached. Defaults to
+	// DefaultCacheTTL.
+	TTL time.Duration
+
+	// KeyTTL returns how long the value of a key is cached, overriding TTL,
+	// for example to cache the values of a remote secret store longer than
+	// local values. Values are not cached when it returns a duration that is
+	// not positive. Optional.
+	KeyTTL func(keyName string, keyValue string) time.Duration
+}
+
+// CachingStore decorates a Store to cache the values that it resolves for a
+// limited time, so that resolving the same credentials or parameters for
+// repeated operations does not call a remote secret store, such as Vault,
+// each time. Errors are not cached. It is safe for concurrent use.
+type CachingStore struct {
+	store   Store
+	options CacheOptions
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+type cacheKey struct {
+	keyName  string
+	keyValue string
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+var _ Store = &CachingStore{}
+
+// NewCachingStore creates a CachingStore resolving values with the store.
+func NewCachingStore(store Store, options CacheOptions) *CachingStore {
+	if options.TTL == 0 {
+		options.TTL = DefaultCacheTTL
+	}
+	return &CachingStore{
+		store:   store,
+		options: options,
+		now:     time.Now,
+		entries: map[cacheKey]cacheEntry{},
+	}
+}
+
+// Resolve returns the cached value of the key when it has not expired, and
+// otherwise resolves it with the decorated store and caches it.
+func (s *CachingStore) Resolve(keyName string, keyValue string) (string, error) {
+	key := cacheKey{keyName: keyName, keyValue: keyValue}
+
+	s.mu.Lock()
+	entry, ok := s.entries[key]
+	if ok && s.now().Before(entry.expires) {
+		s.mu.Unlock()
+		return entry.value, nil
+	}
+	delete(s.entries, key)
+	s.mu.Unlock()
+
+	value, err := s.store.Resolve(keyName, keyValue)
+	if err != nil {
+		return "", err
+	}
+
+	if ttl := s.ttl(keyName, keyValue); ttl > 0 {
+		s.mu.Lock()
+		s.entries[key] = cacheEntry{value: value, expires: s.now().Add(ttl)}
+		s.mu.Unlock()
+	}
+	return value, nil
+}
+
+// Invalidate removes the cached value of the key, for example after the
+// secret was rotated, so that it is resolved again.
+func (s *CachingStore) Invalidate(keyName string, keyValue string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, cacheKey{keyName: keyName, keyValue: keyValue})
+}
+
+// InvalidateAll removes every cached value.
+func (s *CachingStore) InvalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = map[cacheKey]cacheEntry{}
+}
+
+func (s *CachingStore) ttl(keyName string, keyValue string) time.Duration {
+	if s.options.KeyTTL != nil {
+		return s.options.KeyTTL(keyName, keyValue)
+	}
+	return s.options.TTL
+}
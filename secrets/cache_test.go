@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore resolves the key value as the value, and counts how many
+// times each key was resolved.
+type countingStore struct {
+	mu       sync.Mutex
+	resolved map[string]int
+	err      error
+}
+
+func (s *countingStore) Resolve(keyName string, keyValue string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resolved == nil {
+		s.resolved = map[string]int{}
+	}
+	s.resolved[keyName+"="+keyValue]++
+	if s.err != nil {
+		return "", s.err
+	}
+	return keyValue + "-value", nil
+}
+
+func (s *countingStore) count(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resolved[key]
+}
+
+func TestCachingStore(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	backend := &countingStore{}
+	s := NewCachingStore(backend, CacheOptions{TTL: time.Minute})
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		value, err := s.Resolve("vault", "myapp/db")
+		require.NoError(t, err)
+		assert.Equal(t, "myapp/db-value", value)
+	}
+	assert.Equal(t, 1, backend.count("vault=myapp/db"), "the value should be resolved once")
+
+	_, err := s.Resolve("vault", "myapp/api")
+	require.NoError(t, err)
+	assert.Equal(t, 1, backend.count("vault=myapp/api"), "other keys should be resolved separately")
+
+	now = now.Add(time.Minute)
+	_, err = s.Resolve("vault", "myapp/db")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.count("vault=myapp/db"), "the value should be resolved again once it expired")
+
+	s.Invalidate("vault", "myapp/db")
+	_, err = s.Resolve("vault", "myapp/db")
+	require.NoError(t, err)
+	assert.Equal(t, 3, backend.count("vault=myapp/db"), "the value should be resolved again once invalidated")
+
+	s.InvalidateAll()
+	_, err = s.Resolve("vault", "myapp/api")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.count("vault=myapp/api"), "every value should be invalidated")
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		backend := &countingStore{err: errors.New("vault is sealed")}
+		s := NewCachingStore(backend, CacheOptions{})
+		for i := 0; i < 2; i++ {
+			_, err := s.Resolve("vault", "myapp/db")
+			assert.EqualError(t, err, "vault is sealed")
+		}
+		assert.Equal(t, 2, backend.count("vault=myapp/db"))
+	})
+
+	t.Run("per key ttl", func(t *testing.T) {
+		backend := &countingStore{}
+		s := NewCachingStore(backend, CacheOptions{
+			KeyTTL: func(keyName string, keyValue string) time.Duration {
+				if keyName == "command" {
+					return 0
+				}
+				return time.Hour
+			},
+		})
+		for i := 0; i < 2; i++ {
+			_, err := s.Resolve("command", "date")
+			require.NoError(t, err)
+			_, err = s.Resolve("vault", "myapp/db")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, backend.count("command=date"), "keys without a ttl should not be cached")
+		assert.Equal(t, 1, backend.count("vault=myapp/db"))
+	})
+
+	t.Run("default ttl", func(t *testing.T) {
+		assert.Equal(t, DefaultCacheTTL, NewCachingStore(backend, CacheOptions{}).options.TTL)
+	})
+}
@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultKubernetesTokenPath is the path of the service account token
+// mounted into kubernetes pods.
+const DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// AuthMethod authenticates to Vault and returns a Vault token.
+type AuthMethod interface {
+	Login(ctx context.Context, s *SecretStore) (string, error)
+}
+
+// loginResponse is the response from logging in with a Vault auth method.
+type loginResponse struct {
+	Auth struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+}
+
+// TokenAuth uses an existing Vault token.
+type TokenAuth struct {
+	Token string
+}
+
+// Login returns the token.
+func (a TokenAuth) Login(ctx context.Context, s *SecretStore) (string, error) {
+	if a.Token == "" {
+		return "", errors.New("the vault token is not set")
+	}
+	return a.Token, nil
+}
+
+// AppRoleAuth logs in with the AppRole auth method.
+type AppRoleAuth struct {
+	// RoleID of the AppRole.
+	RoleID string
+
+	// SecretID of the AppRole.
+	SecretID string
+
+	// MountPath of the auth method. Defaults to "approle".
+	MountPath string
+}
+
+// Login with the role and secret IDs.
+func (a AppRoleAuth) Login(ctx context.Context, s *SecretStore) (string, error) {
+	mountPath := a.MountPath
+	if mountPath == "" {
+		mountPath = "approle"
+	}
+
+	body := map[string]string{
+		"role_id":   a.RoleID,
+		"secret_id": a.SecretID,
+	}
+	var resp loginResponse
+	if err := s.post(ctx, "auth/"+strings.Trim(mountPath, "/")+"/login", body, &resp); err != nil {
+		return "", errors.Wrap(err, "approle login failed")
+	}
+	return resp.Auth.ClientToken, nil
+}
+
+// KubernetesAuth logs in with the Kubernetes auth method, using the service
+// account token of the pod.
+type KubernetesAuth struct {
+	// Role to log in with.
+	Role string
+
+	// TokenPath is the path of the service account token. Defaults to
+	// DefaultKubernetesTokenPath.
+	TokenPath string
+
+	// MountPath of the auth method. Defaults to "kubernetes".
+	MountPath string
+}
+
+// Login with the service account token.
+func (a KubernetesAuth) Login(ctx context.Context, s *SecretStore) (string, error) {
+	tokenPath := a.TokenPath
+	if tokenPath == "" {
+		tokenPath = DefaultKubernetesTokenPath
+	}
+	mountPath := a.MountPath
+	if mountPath == "" {
+		mountPath = "kubernetes"
+	}
+
+	jwt, err := ioutil.ReadFile(tokenPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read the service account token from %s", tokenPath)
+	}
+
+	body := map[string]string{
+		"role": a.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+	var resp loginResponse
+	if err := s.post(ctx, "auth/"+strings.Trim(mountPath, "/")+"/login", body, &resp); err != nil {
+		return "", errors.Wrap(err, "kubernetes login failed")
+	}
+	return resp.Auth.ClientToken, nil
+}
@@ -0,0 +1,255 @@
+// Package vault resolves secrets from the HashiCorp Vault KV version 2
+// secrets engine, so that credential and parameter sets can reference values
+// stored in Vault instead of embedding them.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/secrets"
+)
+
+const (
+	// SourceVault is the key of a value source that is resolved from Vault.
+	// The value is the path of the secret, relative to the KV mount, and the
+	// name of the field in the secret separated by a #, for example
+	// "myapp/db#password". The field may be omitted when the secret has a
+	// single field.
+	SourceVault = "vault"
+
+	// DefaultMountPath is the default mount path of the KV secrets engine.
+	DefaultMountPath = "secret"
+
+	// EnvAddress is the environment variable for the address of the Vault server.
+	EnvAddress = "VAULT_ADDR"
+
+	// EnvToken is the environment variable for the Vault token.
+	EnvToken = "VAULT_TOKEN"
+
+	// EnvNamespace is the environment variable for the Vault Enterprise namespace.
+	EnvNamespace = "VAULT_NAMESPACE"
+)
+
+var _ secrets.Store = &SecretStore{}
+
+// Config of the connection to Vault.
+type Config struct {
+	// Address of the Vault server, for example https://vault.example.com:8200.
+	Address string
+
+	// Namespace is the Vault Enterprise namespace. Optional.
+	Namespace string
+
+	// MountPath of the KV version 2 secrets engine. Defaults to "secret".
+	MountPath string
+
+	// Auth method used to retrieve a Vault token. Required.
+	Auth AuthMethod
+
+	// HTTPClient used to connect to Vault. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewConfigFromEnv creates a Config using the standard Vault environment
+// variables, authenticating with the token from VAULT_TOKEN.
+func NewConfigFromEnv() Config {
+	return Config{
+		Address:   os.Getenv(EnvAddress),
+		Namespace: os.Getenv(EnvNamespace),
+		Auth:      TokenAuth{Token: os.Getenv(EnvToken)},
+	}
+}
+
+// SecretStore resolves values from the Vault KV version 2 secrets engine.
+type SecretStore struct {
+	config Config
+
+	tokenMutex sync.Mutex
+	token      string
+}
+
+// NewSecretStore creates a SecretStore with the specified configuration.
+func NewSecretStore(config Config) (*SecretStore, error) {
+	if config.Address == "" {
+		return nil, errors.New("the Vault address is required")
+	}
+	if config.Auth == nil {
+		return nil, errors.New("a Vault auth method is required")
+	}
+	if config.MountPath == "" {
+		config.MountPath = DefaultMountPath
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+
+	return &SecretStore{config: config}, nil
+}
+
+// Resolve the value of a secret stored in Vault.
+// - keyName must be "vault".
+// - keyValue is the path of the secret and the field, for example "myapp/db#password".
+func (s *SecretStore) Resolve(keyName string, keyValue string) (string, error) {
+	if strings.ToLower(keyName) != SourceVault {
+		return "", fmt.Errorf("invalid value source: %s", keyName)
+	}
+
+	secretPath, field := keyValue, ""
+	if i := strings.LastIndex(keyValue, "#"); i >= 0 {
+		secretPath, field = keyValue[:i], keyValue[i+1:]
+	}
+	secretPath = strings.Trim(secretPath, "/")
+	if secretPath == "" {
+		return "", fmt.Errorf("invalid vault secret %q: the path is required", keyValue)
+	}
+
+	data, err := s.readSecret(context.Background(), secretPath)
+	if err != nil {
+		return "", err
+	}
+
+	return selectField(secretPath, field, data)
+}
+
+// kvReadResponse is the response from reading a secret from the KV version 2 secrets engine.
+type kvReadResponse struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+}
+
+// readSecret returns the data of the latest version of the secret.
+func (s *SecretStore) readSecret(ctx context.Context, secretPath string) (map[string]interface{}, error) {
+	token, err := s.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secretURL := fmt.Sprintf("%s/v1/%s/data/%s", s.config.Address, strings.Trim(s.config.MountPath, "/"), secretPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create the request for vault secret %s", secretPath)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	var resp kvReadResponse
+	if err := s.do(req, &resp); err != nil {
+		return nil, errors.Wrapf(err, "could not read vault secret %s", secretPath)
+	}
+	if resp.Data.Data == nil {
+		return nil, fmt.Errorf("vault secret %s has no data, it may have been deleted", secretPath)
+	}
+	return resp.Data.Data, nil
+}
+
+// getToken returns the Vault token, logging in on first use.
+func (s *SecretStore) getToken(ctx context.Context) (string, error) {
+	s.tokenMutex.Lock()
+	defer s.tokenMutex.Unlock()
+
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	token, err := s.config.Auth.Login(ctx, s)
+	if err != nil {
+		return "", errors.Wrap(err, "could not authenticate to vault")
+	}
+	if token == "" {
+		return "", errors.New("could not authenticate to vault: no token was returned")
+	}
+	s.token = token
+	return token, nil
+}
+
+// do sends the request to Vault and decodes the JSON response into v.
+func (s *SecretStore) do(req *http.Request, v interface{}) error {
+	if s.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+
+	resp, err := s.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(body, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(vaultErr.Errors, ", "))
+		}
+		return fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// post sends a JSON request to the Vault API, relative to /v1/.
+func (s *SecretStore) post(ctx context.Context, apiPath string, body interface{}, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.Address+"/v1/"+apiPath, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return s.do(req, v)
+}
+
+// selectField returns the value of the field from the secret data.
+func selectField(secretPath string, field string, data map[string]interface{}) (string, error) {
+	if field == "" {
+		if len(data) != 1 {
+			fields := make([]string, 0, len(data))
+			for name := range data {
+				fields = append(fields, name)
+			}
+			sort.Strings(fields)
+			return "", fmt.Errorf("vault secret %s has multiple fields (%s), specify the field as %s#FIELD", secretPath, strings.Join(fields, ", "), secretPath)
+		}
+		for name := range data {
+			field = name
+		}
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s does not have the field %s", secretPath, field)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrapf(err, "could not convert the field %s of vault secret %s", field, secretPath)
+		}
+		return string(encoded), nil
+	}
+}
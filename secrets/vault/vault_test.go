@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestVault simulates the vault API for a KV version 2 engine mounted at kv.
+func newTestVault(t *testing.T) *httptest.Server {
+	secrets := map[string]map[string]interface{}{
+		"myapp/db":  {"username": "admin", "password": "topsecret"},
+		"myapp/api": {"token": "abc123"},
+		"myapp/cfg": {"settings": map[string]interface{}{"debug": true}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["role_id"] != "myrole" || body["secret_id"] != "mysecret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors": ["invalid role or secret ID"]}`))
+			return
+		}
+		w.Write([]byte(`{"auth": {"client_token": "approle-token"}}`))
+	})
+	mux.HandleFunc("/v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["role"] != "cnab" || body["jwt"] != "sa-jwt" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		w.Write([]byte(`{"auth": {"client_token": "k8s-token"}}`))
+	})
+	mux.HandleFunc("/v1/kv/data/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Vault-Token") {
+		case "root-token", "approle-token", "k8s-token":
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+
+		data, ok := secrets[r.URL.Path[len("/v1/kv/data/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": data},
+		})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestSecretStore_Resolve(t *testing.T) {
+	server := newTestVault(t)
+	defer server.Close()
+
+	s, err := NewSecretStore(Config{
+		Address:   server.URL + "/",
+		MountPath: "kv",
+		Auth:      TokenAuth{Token: "root-token"},
+	})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		keyValue string
+		want     string
+		wantErr  string
+	}{
+		{name: "field", keyValue: "myapp/db#password", want: "topsecret"},
+		{name: "single field", keyValue: "/myapp/api", want: "abc123"},
+		{name: "json field", keyValue: "myapp/cfg#settings", want: `{"debug":true}`},
+		{name: "multiple fields", keyValue: "myapp/db", wantErr: "vault secret myapp/db has multiple fields (password, username), specify the field as myapp/db#FIELD"},
+		{name: "missing field", keyValue: "myapp/db#port", wantErr: "vault secret myapp/db does not have the field port"},
+		{name: "missing secret", keyValue: "myapp/missing#password", wantErr: "could not read vault secret myapp/missing: vault returned 404 Not Found"},
+		{name: "missing path", keyValue: "#password", wantErr: `invalid vault secret "#password": the path is required`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Resolve(SourceVault, tc.keyValue)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err = s.Resolve("env", "VAULT_TOKEN")
+	require.EqualError(t, err, "invalid value source: env")
+}
+
+func TestSecretStore_AuthMethods(t *testing.T) {
+	server := newTestVault(t)
+	defer server.Close()
+
+	tmp, err := ioutil.TempDir("", "cnab-go")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+	jwtPath := filepath.Join(tmp, "token")
+	require.NoError(t, ioutil.WriteFile(jwtPath, []byte("sa-jwt\n"), 0600))
+
+	testcases := []struct {
+		name    string
+		auth    AuthMethod
+		wantErr string
+	}{
+		{name: "approle", auth: AppRoleAuth{RoleID: "myrole", SecretID: "mysecret"}},
+		{name: "kubernetes", auth: KubernetesAuth{Role: "cnab", TokenPath: jwtPath}},
+		{name: "invalid approle", auth: AppRoleAuth{RoleID: "myrole", SecretID: "wrong"}, wantErr: "could not authenticate to vault: approle login failed: vault returned 400 Bad Request: invalid role or secret ID"},
+		{name: "invalid token", auth: TokenAuth{Token: "wrong"}, wantErr: "could not read vault secret myapp/api: vault returned 403 Forbidden: permission denied"},
+		{name: "missing token", auth: TokenAuth{}, wantErr: "could not authenticate to vault: the vault token is not set"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewSecretStore(Config{Address: server.URL, MountPath: "kv", Auth: tc.auth})
+			require.NoError(t, err)
+
+			got, err := s.Resolve(SourceVault, "myapp/api#token")
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "abc123", got)
+		})
+	}
+}
+
+func TestNewSecretStore_Invalid(t *testing.T) {
+	_, err := NewSecretStore(Config{Auth: TokenAuth{Token: "root-token"}})
+	require.EqualError(t, err, "the Vault address is required")
+
+	_, err = NewSecretStore(Config{Address: "https://vault.example.com"})
+	require.EqualError(t, err, "a Vault auth method is required")
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvAddress, "https://vault.example.com")
+	t.Setenv(EnvToken, "root-token")
+	t.Setenv(EnvNamespace, "team")
+
+	cfg := NewConfigFromEnv()
+	assert.Equal(t, "https://vault.example.com", cfg.Address)
+	assert.Equal(t, "team", cfg.Namespace)
+	assert.Equal(t, TokenAuth{Token: "root-token"}, cfg.Auth)
+}
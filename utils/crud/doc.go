@@ -0,0 +1,3 @@
+// Package crud provides a minimal abstraction over key-blob storage, used to
+// persist documents such as claims, results and outputs.
+package crud
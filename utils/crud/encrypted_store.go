@@ -0,0 +1,267 @@
+package crud
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedRecordMagic identifies a record written by EncryptedStore and the
+// version of its header format.
+var encryptedRecordMagic = []byte("CNABENC1")
+
+var (
+	_ Store            = &EncryptedStore{}
+	_ HasConnect       = &EncryptedStore{}
+	_ HasClose         = &EncryptedStore{}
+	_ ConditionalStore = &EncryptedStore{}
+)
+
+// EncryptedStore is a Store decorator that encrypts every record, regardless
+// of its item type, before it is persisted to the backing store.
+//
+// Each record is sealed with an AEAD cipher and prefixed with a header
+// containing the ID of the key used and the nonce:
+//
+//	MAGIC | KEY ID LENGTH (1 byte) | KEY ID | NONCE LENGTH (1 byte) | NONCE | CIPHERTEXT
+//
+// The item type and name of the record are used as additional authenticated
+// data, so a record cannot be moved to another key without detection.
+//
+// The secondary indexes of the backing store, when it implements Indexer,
+// are available with GetIndexer. The values of the indexes are not
+// encrypted.
+type EncryptedStore struct {
+	backingStore Store
+
+	// keyID identifies the key used to encrypt new records.
+	keyID string
+
+	// keys maps a key ID to the cipher used to decrypt records sealed with it.
+	keys map[string]cipher.AEAD
+}
+
+// NewEncryptedStore wraps a Store, encrypting records with the specified
+// AEAD, for example AES-GCM, which is identified by keyID.
+func NewEncryptedStore(store Store, keyID string, aead cipher.AEAD) (*EncryptedStore, error) {
+	if aead == nil {
+		return nil, errors.New("an AEAD cipher is required")
+	}
+
+	if err := validateKeyID(keyID); err != nil {
+		return nil, err
+	}
+
+	return &EncryptedStore{
+		backingStore: store,
+		keyID:        keyID,
+		keys:         map[string]cipher.AEAD{keyID: aead},
+	}, nil
+}
+
+// AddDecryptionKey registers an additional key that may be used to decrypt
+// existing records, for example after the encryption key has been rotated.
+func (s *EncryptedStore) AddDecryptionKey(keyID string, aead cipher.AEAD) error {
+	if aead == nil {
+		return errors.New("an AEAD cipher is required")
+	}
+
+	if err := validateKeyID(keyID); err != nil {
+		return err
+	}
+
+	s.keys[keyID] = aead
+	return nil
+}
+
+func validateKeyID(keyID string) error {
+	if keyID == "" {
+		return errors.New("the key ID must be set")
+	}
+
+	if len(keyID) > 255 {
+		return fmt.Errorf("the key ID must not be longer than 255 bytes, got %d", len(keyID))
+	}
+
+	return nil
+}
+
+// Connect to the backing store, if it requires it.
+func (s *EncryptedStore) Connect() error {
+	if connectable, ok := s.backingStore.(HasConnect); ok {
+		return connectable.Connect()
+	}
+	return nil
+}
+
+// Close the backing store, if it requires it.
+func (s *EncryptedStore) Close() error {
+	if closable, ok := s.backingStore.(HasClose); ok {
+		return closable.Close()
+	}
+	return nil
+}
+
+func (s *EncryptedStore) Count(itemType string, group string) (int, error) {
+	return s.backingStore.Count(itemType, group)
+}
+
+func (s *EncryptedStore) List(itemType string, group string) ([]string, error) {
+	return s.backingStore.List(itemType, group)
+}
+
+func (s *EncryptedStore) Save(itemType string, group string, name string, data []byte) error {
+	sealed, err := s.encrypt(itemType, name, data)
+	if err != nil {
+		return err
+	}
+
+	return s.backingStore.Save(itemType, group, name, sealed)
+}
+
+// SaveIfRevision saves the data for an item when the Revision of the stored
+// item, after it is decrypted, matches the revision. The encrypted record is
+// saved with SaveIfRevision on the backing store, conditionally on the
+// revision of the encrypted record that was compared, so that the save is
+// atomic when the backing store implements ConditionalStore.
+func (s *EncryptedStore) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	backingRevision := ""
+	if revision != "" {
+		sealed, err := s.backingStore.Read(itemType, name)
+		if errors.Is(err, ErrRecordDoesNotExist) {
+			return conflictError(itemType, name)
+		}
+		if err != nil {
+			return err
+		}
+
+		current, err := s.decrypt(itemType, name, sealed)
+		if err != nil {
+			return err
+		}
+		if Revision(current) != revision {
+			return conflictError(itemType, name)
+		}
+		backingRevision = Revision(sealed)
+	}
+
+	sealed, err := s.encrypt(itemType, name, data)
+	if err != nil {
+		return err
+	}
+
+	return SaveIfRevision(s.backingStore, itemType, group, name, sealed, backingRevision)
+}
+
+// DeleteIfRevision deletes an item when the Revision of the stored item,
+// after it is decrypted, matches the revision. The encrypted record is
+// deleted with DeleteIfRevision on the backing store, conditionally on the
+// revision of the encrypted record that was compared.
+func (s *EncryptedStore) DeleteIfRevision(itemType string, name string, revision string) error {
+	sealed, err := s.backingStore.Read(itemType, name)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.decrypt(itemType, name, sealed)
+	if err != nil {
+		return err
+	}
+	if Revision(current) != revision {
+		return fmt.Errorf("could not delete %s %s: %w", itemType, name, ErrConflict)
+	}
+
+	return DeleteIfRevision(s.backingStore, itemType, name, Revision(sealed))
+}
+
+func (s *EncryptedStore) Read(itemType string, name string) ([]byte, error) {
+	sealed, err := s.backingStore.Read(itemType, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decrypt(itemType, name, sealed)
+}
+
+func (s *EncryptedStore) Delete(itemType string, name string) error {
+	return s.backingStore.Delete(itemType, name)
+}
+
+// indexer returns the Indexer of the backing store.
+func (s *EncryptedStore) indexer() (Indexer, bool) {
+	return GetIndexer(s.backingStore)
+}
+
+func (s *EncryptedStore) encrypt(itemType string, name string, data []byte) ([]byte, error) {
+	aead := s.keys[s.keyID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(encryptedRecordMagic)
+	buf.WriteByte(byte(len(s.keyID)))
+	buf.WriteString(s.keyID)
+	buf.WriteByte(byte(len(nonce)))
+	buf.Write(nonce)
+
+	return aead.Seal(buf.Bytes(), nonce, data, additionalData(itemType, name)), nil
+}
+
+func (s *EncryptedStore) decrypt(itemType string, name string, sealed []byte) ([]byte, error) {
+	if !bytes.HasPrefix(sealed, encryptedRecordMagic) {
+		return nil, fmt.Errorf("%s %s is not an encrypted record", itemType, name)
+	}
+	remaining := sealed[len(encryptedRecordMagic):]
+
+	keyID, remaining, err := readHeaderField(remaining)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key ID in the header of %s %s: %w", itemType, name, err)
+	}
+
+	nonce, ciphertext, err := readHeaderField(remaining)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce in the header of %s %s: %w", itemType, name, err)
+	}
+
+	aead, ok := s.keys[string(keyID)]
+	if !ok {
+		return nil, fmt.Errorf("%s %s was encrypted with an unknown key %q", itemType, name, keyID)
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d in the header of %s %s", len(nonce), itemType, name)
+	}
+
+	data, err := aead.Open(nil, nonce, ciphertext, additionalData(itemType, name))
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s %s: %w", itemType, name, err)
+	}
+
+	return data, nil
+}
+
+// readHeaderField reads a length-prefixed field from the header, returning
+// the field and the remaining data.
+func readHeaderField(data []byte) ([]byte, []byte, error) {
+	if len(data) < 1 {
+		return nil, nil, errors.New("unexpected end of record")
+	}
+
+	n := int(data[0])
+	if len(data) < 1+n {
+		return nil, nil, errors.New("unexpected end of record")
+	}
+
+	return data[1 : 1+n], data[1+n:], nil
+}
+
+// additionalData binds a sealed record to its item type and name.
+func additionalData(itemType string, name string) []byte {
+	return []byte(itemType + "\x00" + name)
+}
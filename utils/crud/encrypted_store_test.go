@@ -0,0 +1,159 @@
+package crud
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAEAD(t *testing.T, key string) cipher.AEAD {
+	block, err := aes.NewCipher([]byte(key))
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	return aead
+}
+
+func TestEncryptedStore(t *testing.T) {
+	backingStore := NewMockStore()
+	s, err := NewEncryptedStore(backingStore, "key1", newTestAEAD(t, "0123456789abcdef"))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Save("claims", "mysql", "1", []byte("install")))
+
+	raw, err := backingStore.Read("claims", "1")
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "install", "the record should be encrypted in the backing store")
+	assert.Contains(t, string(raw), "key1", "the header should contain the key ID")
+
+	data, err := s.Read("claims", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "install", string(data))
+
+	names, err := s.List("claims", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, names)
+
+	require.NoError(t, s.Delete("claims", "1"))
+	_, err = s.Read("claims", "1")
+	assert.Equal(t, ErrRecordDoesNotExist, err)
+}
+
+// racingStore modifies a record before it is saved conditionally, as if
+// another process saved it after it was read.
+type racingStore struct {
+	*MockStore
+}
+
+func (s racingStore) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	if err := s.MockStore.Save(itemType, group, name, []byte("modified")); err != nil {
+		return err
+	}
+	return s.MockStore.SaveIfRevision(itemType, group, name, data, revision)
+}
+
+func TestEncryptedStore_SaveIfRevision(t *testing.T) {
+	backingStore := NewMockStore()
+	s, err := NewEncryptedStore(backingStore, "key1", newTestAEAD(t, "0123456789abcdef"))
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveIfRevision("locks", "", "mysql", []byte("one"), ""))
+	err = s.SaveIfRevision("locks", "", "mysql", []byte("two"), "")
+	assert.ErrorIs(t, err, ErrConflict, "an item that exists should not be created again")
+
+	err = s.SaveIfRevision("locks", "", "mysql", []byte("two"), Revision([]byte("other")))
+	assert.ErrorIs(t, err, ErrConflict, "an item should not be saved with the wrong revision")
+
+	require.NoError(t, s.SaveIfRevision("locks", "", "mysql", []byte("two"), Revision([]byte("one"))),
+		"the revision should be the revision of the decrypted data")
+	data, err := s.Read("locks", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(data))
+
+	err = s.SaveIfRevision("locks", "", "wordpress", []byte("one"), Revision([]byte("one")))
+	assert.ErrorIs(t, err, ErrConflict, "an item that does not exist should not be saved with a revision")
+
+	t.Run("modified concurrently", func(t *testing.T) {
+		racing := racingStore{NewMockStore()}
+		s, err := NewEncryptedStore(racing, "key1", newTestAEAD(t, "0123456789abcdef"))
+		require.NoError(t, err)
+		require.NoError(t, s.Save("locks", "", "mysql", []byte("one")))
+
+		err = s.SaveIfRevision("locks", "", "mysql", []byte("two"), Revision([]byte("one")))
+		assert.ErrorIs(t, err, ErrConflict, "the save should be conditional on the encrypted record that was compared")
+	})
+}
+
+func TestEncryptedStore_KeyRotation(t *testing.T) {
+	backingStore := NewMockStore()
+	oldKey := newTestAEAD(t, "0123456789abcdef")
+	s, err := NewEncryptedStore(backingStore, "key1", oldKey)
+	require.NoError(t, err)
+	require.NoError(t, s.Save("results", "1", "a", []byte("succeeded")))
+
+	rotated, err := NewEncryptedStore(backingStore, "key2", newTestAEAD(t, "fedcba9876543210"))
+	require.NoError(t, err)
+	_, err = rotated.Read("results", "a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown key "key1"`)
+
+	require.NoError(t, rotated.AddDecryptionKey("key1", oldKey))
+	data, err := rotated.Read("results", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "succeeded", string(data))
+}
+
+func TestEncryptedStore_TamperedRecord(t *testing.T) {
+	backingStore := NewMockStore()
+	s, err := NewEncryptedStore(backingStore, "key1", newTestAEAD(t, "0123456789abcdef"))
+	require.NoError(t, err)
+	require.NoError(t, s.Save("outputs", "1", "1-host", []byte("localhost")))
+
+	t.Run("moved record", func(t *testing.T) {
+		raw, err := backingStore.Read("outputs", "1-host")
+		require.NoError(t, err)
+		require.NoError(t, backingStore.Save("outputs", "1", "1-password", raw))
+
+		_, err = s.Read("outputs", "1-password")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error decrypting outputs 1-password")
+	})
+
+	t.Run("unencrypted record", func(t *testing.T) {
+		require.NoError(t, backingStore.Save("outputs", "1", "1-plain", []byte("localhost")))
+
+		_, err := s.Read("outputs", "1-plain")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not an encrypted record")
+	})
+
+	t.Run("truncated header", func(t *testing.T) {
+		require.NoError(t, backingStore.Save("outputs", "1", "1-short", append([]byte("CNABENC1"), 10)))
+
+		_, err := s.Read("outputs", "1-short")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected end of record")
+	})
+}
+
+func TestEncryptedStore_ManagedStore(t *testing.T) {
+	backingStore := NewMockStore()
+	s, err := NewEncryptedStore(backingStore, "key1", newTestAEAD(t, "0123456789abcdef"))
+	require.NoError(t, err)
+
+	managed := NewManagedStore(s)
+	require.NoError(t, managed.Save("claims", "mysql", "1", []byte("install")))
+	assert.Equal(t, 1, backingStore.ConnectCount, "Connect should be passed through to the backing store")
+	assert.Equal(t, 1, backingStore.CloseCount, "Close should be passed through to the backing store")
+}
+
+func TestNewEncryptedStore_Validation(t *testing.T) {
+	_, err := NewEncryptedStore(NewMockStore(), "", newTestAEAD(t, "0123456789abcdef"))
+	assert.EqualError(t, err, "the key ID must be set")
+
+	_, err = NewEncryptedStore(NewMockStore(), "key1", nil)
+	assert.EqualError(t, err, "an AEAD cipher is required")
+}
@@ -0,0 +1,381 @@
+package crud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	_ Store            = FileSystemStore{}
+	_ ConditionalStore = FileSystemStore{}
+)
+
+// fileLockTimeout is how long SaveIfRevision waits for another process to
+// release the lock file of an item.
+var fileLockTimeout = 10 * time.Second
+
+// staleFileLockAge is the age after which a lock file is considered to have
+// been left behind by a process that exited while holding it, and is removed.
+const staleFileLockAge = time.Minute
+
+// KeyingStrategy determines how group and item names are converted to file
+// names by a FileSystemStore.
+type KeyingStrategy int
+
+const (
+	// KeyByName uses the group and item names as the file names.
+	KeyByName KeyingStrategy = iota
+
+	// KeyByHash uses the SHA-256 hash of the group and item names as the file
+	// names, so that names that are too long, or that contain characters that
+	// are not allowed, on some filesystems can be stored. The original name is
+	// recorded in a sidecar file, NAME_HASH.name, next to the file or
+	// directory, which is used when listing.
+	KeyByHash
+)
+
+// nameFileExtension is the extension of the sidecar files that record the
+// original names when the names are hashed.
+const nameFileExtension = ".name"
+
+// FileSystemStoreOptions customizes how a FileSystemStore persists items.
+type FileSystemStoreOptions struct {
+	// KeyingStrategy determines how names are converted to file names.
+	// Defaults to KeyByName.
+	KeyingStrategy KeyingStrategy
+}
+
+// FileSystemStore is a Store backed by the local filesystem. Items are
+// stored at BASEDIR/ITEMTYPE/GROUP/NAME[EXTENSION], where GROUP and NAME are
+// hashed when the KeyByHash keying strategy is used.
+type FileSystemStore struct {
+	baseDirectory  string
+	fileExtensions map[string]string
+	keying         KeyingStrategy
+}
+
+// NewFileSystemStore creates a Store rooted at the specified directory.
+// fileExtensions maps an itemType to the file extension, for example ".json",
+// used when persisting items of that type.
+func NewFileSystemStore(baseDirectory string, fileExtensions map[string]string) FileSystemStore {
+	return NewFileSystemStoreWithOptions(baseDirectory, fileExtensions, FileSystemStoreOptions{})
+}
+
+// NewFileSystemStoreWithOptions creates a Store rooted at the specified
+// directory, customized by the options. Changing the keying strategy of an
+// existing store does not migrate the items that are already stored.
+func NewFileSystemStoreWithOptions(baseDirectory string, fileExtensions map[string]string, opts FileSystemStoreOptions) FileSystemStore {
+	return FileSystemStore{
+		baseDirectory:  baseDirectory,
+		fileExtensions: fileExtensions,
+		keying:         opts.KeyingStrategy,
+	}
+}
+
+func (s FileSystemStore) Count(itemType string, group string) (int, error) {
+	names, err := s.List(itemType, group)
+	return len(names), err
+}
+
+func (s FileSystemStore) List(itemType string, group string) ([]string, error) {
+	dir := s.groupDir(itemType, group)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, errors.Wrapf(err, "error listing %s", dir)
+	}
+
+	ext := s.fileExtensions[itemType]
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// When listing groups, only include directories; otherwise only include files.
+		if entry.IsDir() != (group == "") {
+			continue
+		}
+
+		key := entry.Name()
+		if group != "" {
+			if !strings.HasSuffix(key, ext) {
+				continue
+			}
+			key = strings.TrimSuffix(key, ext)
+		}
+
+		name, ok, err := s.nameOf(dir, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+func (s FileSystemStore) Save(itemType string, group string, name string, data []byte) error {
+	dir := s.groupDir(itemType, group)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrapf(err, "error creating directory %s", dir)
+	}
+	if group != "" {
+		if err := s.writeNameFile(filepath.Dir(dir), group); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(dir, s.key(name)+s.fileExtensions[itemType])
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing %s", path)
+	}
+
+	return s.writeNameFile(dir, name)
+}
+
+// SaveIfRevision saves the data for an item when the stored item has the
+// revision. The comparison and the save are made while holding a lock file
+// for the item, so that processes sharing the directory cannot interleave
+// their updates.
+func (s FileSystemStore) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	unlock, err := s.lockItem(itemType, name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := checkRevision(s, itemType, name, revision); err != nil {
+		return err
+	}
+	return s.Save(itemType, group, name, data)
+}
+
+// DeleteIfRevision deletes an item when the stored item has the revision,
+// holding the lock file of the item so that it is not saved by another
+// process between the comparison and the delete.
+func (s FileSystemStore) DeleteIfRevision(itemType string, name string, revision string) error {
+	unlock, err := s.lockItem(itemType, name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := checkDeleteRevision(s, itemType, name, revision); err != nil {
+		return err
+	}
+	return s.Delete(itemType, name)
+}
+
+// lockItem creates the lock file of an item, waiting until it is released
+// when it is held by another process, and returns a function that removes it.
+func (s FileSystemStore) lockItem(itemType string, name string) (func(), error) {
+	dir := filepath.Join(s.baseDirectory, itemType)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "error creating directory %s", dir)
+	}
+
+	path := filepath.Join(dir, "."+s.key(name)+".lock")
+	deadline := time.Now().Add(fileLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.Wrapf(err, "error creating lock file %s", path)
+		}
+
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > staleFileLockAge {
+			if err := removeStaleLock(path, fi); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("timed out waiting for the lock file %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// removeStaleLock removes the stale lock file at path, described by stale.
+// The file is first renamed, which only one of the processes finding the
+// stale lock at the same time succeeds to do, so that a lock file created by
+// another process after it removed the stale lock is not removed. A lock
+// file that is not the stale one, because it was replaced in the meantime,
+// is put back.
+func removeStaleLock(path string, stale os.FileInfo) error {
+	claimed := fmt.Sprintf("%s.%d.%d.stale", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, claimed); err != nil {
+		if os.IsNotExist(err) {
+			// Another process removed the stale lock first
+			return nil
+		}
+		return errors.Wrapf(err, "error removing the stale lock file %s", path)
+	}
+
+	// The inode of the stale lock file may be reused by the new one, which
+	// has a recent modification time
+	if fi, err := os.Stat(claimed); err == nil && (!os.SameFile(fi, stale) || !fi.ModTime().Equal(stale.ModTime())) {
+		// The lock was acquired by another process since it was found stale.
+		// Linking fails when yet another process created the lock file.
+		os.Link(claimed, path)
+	}
+	os.Remove(claimed)
+	return nil
+}
+
+func (s FileSystemStore) Read(itemType string, name string) ([]byte, error) {
+	path, err := s.findItem(itemType, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	return data, errors.Wrapf(err, "error reading %s", path)
+}
+
+func (s FileSystemStore) Delete(itemType string, name string) error {
+	path, err := s.findItem(itemType, name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		return errors.Wrapf(err, "error removing %s", path)
+	}
+
+	groupDir := filepath.Dir(path)
+	if err := s.removeNameFile(groupDir, name); err != nil {
+		return err
+	}
+
+	// Clean up the group directory once it is empty
+	itemTypeDir := filepath.Join(s.baseDirectory, itemType)
+	if groupDir == itemTypeDir {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(groupDir)
+	if err == nil && len(entries) == 0 {
+		if err := os.Remove(groupDir); err != nil {
+			return errors.Wrapf(err, "error removing %s", groupDir)
+		}
+		if s.keying == KeyByHash {
+			nameFile := filepath.Join(itemTypeDir, filepath.Base(groupDir)+nameFileExtension)
+			if err := os.Remove(nameFile); err != nil && !os.IsNotExist(err) {
+				return errors.Wrapf(err, "error removing %s", nameFile)
+			}
+		}
+	}
+
+	return nil
+}
+
+// findItem locates the file for an item, searching every group of the
+// itemType because names are unique within an itemType.
+func (s FileSystemStore) findItem(itemType string, name string) (string, error) {
+	filename := s.key(name) + s.fileExtensions[itemType]
+	itemTypeDir := filepath.Join(s.baseDirectory, itemType)
+
+	entries, err := ioutil.ReadDir(itemTypeDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "error listing %s", itemTypeDir)
+	}
+
+	// Items saved without a group are stored directly in the itemType directory
+	candidates := []string{""}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			candidates = append(candidates, entry.Name())
+		}
+	}
+	for _, group := range candidates {
+		path := filepath.Join(itemTypeDir, group, filename)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path, nil
+		}
+	}
+
+	return "", ErrRecordDoesNotExist
+}
+
+// groupDir returns the directory that holds the items of a group, or the
+// groups of the itemType when group is empty.
+func (s FileSystemStore) groupDir(itemType string, group string) string {
+	if group == "" {
+		return filepath.Join(s.baseDirectory, itemType)
+	}
+	return filepath.Join(s.baseDirectory, itemType, s.key(group))
+}
+
+// key returns the file name for a group or item name.
+func (s FileSystemStore) key(name string) string {
+	if s.keying != KeyByHash {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// nameOf returns the original name of the file or directory in dir with the
+// specified key. When the names are hashed, files that are not named with a
+// hash, such as the sidecar files, are skipped.
+func (s FileSystemStore) nameOf(dir string, key string) (string, bool, error) {
+	if s.keying != KeyByHash {
+		return key, true, nil
+	}
+	if len(key) != sha256.Size*2 {
+		return "", false, nil
+	}
+	if _, err := hex.DecodeString(key); err != nil {
+		return "", false, nil
+	}
+
+	nameFile := filepath.Join(dir, key+nameFileExtension)
+	name, err := ioutil.ReadFile(nameFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The item is still being saved, or was not saved by this store.
+			return "", false, nil
+		}
+		return "", false, errors.Wrapf(err, "error reading %s", nameFile)
+	}
+	return string(name), true, nil
+}
+
+// writeNameFile records the original name of a hashed file or directory in dir.
+func (s FileSystemStore) writeNameFile(dir string, name string) error {
+	if s.keying != KeyByHash {
+		return nil
+	}
+
+	nameFile := filepath.Join(dir, s.key(name)+nameFileExtension)
+	if err := ioutil.WriteFile(nameFile, []byte(name), 0600); err != nil {
+		return errors.Wrapf(err, "error writing %s", nameFile)
+	}
+	return nil
+}
+
+// removeNameFile removes the record of the original name of a hashed file in dir.
+func (s FileSystemStore) removeNameFile(dir string, name string) error {
+	if s.keying != KeyByHash {
+		return nil
+	}
+
+	nameFile := filepath.Join(dir, s.key(name)+nameFileExtension)
+	if err := os.Remove(nameFile); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error removing %s", nameFile)
+	}
+	return nil
+}
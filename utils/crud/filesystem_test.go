@@ -0,0 +1,187 @@
+package crud
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSystemStore(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "cnab-crud-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	s := NewFileSystemStore(tempDir, map[string]string{"claims": ".json"})
+
+	require.NoError(t, s.Save("claims", "mysql", "1", []byte("install")))
+	require.NoError(t, s.Save("claims", "mysql", "2", []byte("upgrade")))
+	require.NoError(t, s.Save("claims", "wordpress", "3", []byte("install")))
+	assert.FileExists(t, filepath.Join(tempDir, "claims", "mysql", "1.json"))
+
+	groups, err := s.List("claims", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql", "wordpress"}, groups)
+
+	names, err := s.List("claims", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, names)
+
+	count, err := s.Count("claims", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	data, err := s.Read("claims", "3")
+	require.NoError(t, err)
+	assert.Equal(t, "install", string(data))
+
+	_, err = s.Read("claims", "missing")
+	assert.Equal(t, ErrRecordDoesNotExist, err)
+
+	require.NoError(t, s.Delete("claims", "3"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "claims", "wordpress"), "empty groups should be removed")
+
+	err = s.Delete("claims", "3")
+	assert.Equal(t, ErrRecordDoesNotExist, err)
+
+	names, err = s.List("results", "1")
+	require.NoError(t, err)
+	assert.Empty(t, names, "listing an item type that was never saved should not fail")
+}
+
+func TestFileSystemStore_KeyByHash(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "cnab-crud-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, ext := range []string{".json", ""} {
+		t.Run("extension "+ext, func(t *testing.T) {
+			baseDir := filepath.Join(tempDir, "ext"+ext)
+			s := NewFileSystemStoreWithOptions(baseDir, map[string]string{"claims": ext}, FileSystemStoreOptions{KeyingStrategy: KeyByHash})
+
+			longName := strings.Repeat("my-very-long-installation-name", 20)
+			specialName := `mysql/prod: "primary"`
+			require.NoError(t, s.Save("claims", longName, "1", []byte("install")))
+			require.NoError(t, s.Save("claims", longName, "2", []byte("upgrade")))
+			require.NoError(t, s.Save("claims", specialName, "3/3", []byte("install")))
+
+			groups, err := s.List("claims", "")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{longName, specialName}, groups)
+
+			names, err := s.List("claims", longName)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"1", "2"}, names)
+
+			data, err := s.Read("claims", "3/3")
+			require.NoError(t, err)
+			assert.Equal(t, "install", string(data))
+
+			require.NoError(t, s.Delete("claims", "3/3"))
+			groups, err = s.List("claims", "")
+			require.NoError(t, err)
+			assert.Equal(t, []string{longName}, groups)
+
+			entries, err := ioutil.ReadDir(filepath.Join(baseDir, "claims"))
+			require.NoError(t, err)
+			assert.Len(t, entries, 2, "the deleted group and its name file should be removed")
+
+			_, err = s.Read("claims", "3/3")
+			assert.Equal(t, ErrRecordDoesNotExist, err)
+		})
+	}
+}
+
+func TestFileSystemStore_SaveIfRevision(t *testing.T) {
+	t.Run("concurrent creates", func(t *testing.T) {
+		s := NewFileSystemStore(t.TempDir(), map[string]string{"locks": ".json"})
+
+		const attempts = 10
+		errs := make(chan error, attempts)
+		var wg sync.WaitGroup
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- SaveIfRevision(s, "locks", "", "mysql", []byte(strconv.Itoa(i)), "")
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		saved := 0
+		for err := range errs {
+			if err == nil {
+				saved++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrConflict)
+		}
+		assert.Equal(t, 1, saved, "only one process should have created the item")
+	})
+
+	t.Run("held lock file", func(t *testing.T) {
+		defer func(timeout time.Duration) { fileLockTimeout = timeout }(fileLockTimeout)
+		fileLockTimeout = 50 * time.Millisecond
+
+		tempDir := t.TempDir()
+		s := NewFileSystemStore(tempDir, map[string]string{"locks": ".json"})
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "locks"), 0700))
+		lockFile := filepath.Join(tempDir, "locks", ".mysql.lock")
+		require.NoError(t, ioutil.WriteFile(lockFile, nil, 0600))
+
+		err := s.SaveIfRevision("locks", "", "mysql", []byte("1"), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out waiting for the lock file")
+
+		// A lock file left behind by a process that exited is removed
+		stale := time.Now().Add(-2 * staleFileLockAge)
+		require.NoError(t, os.Chtimes(lockFile, stale, stale))
+		require.NoError(t, s.SaveIfRevision("locks", "", "mysql", []byte("1"), ""))
+		assert.NoFileExists(t, lockFile, "the lock file should be removed after the save")
+	})
+}
+
+func TestRemoveStaleLock(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), ".mysql.lock")
+	require.NoError(t, ioutil.WriteFile(lockFile, nil, 0600))
+	staleTime := time.Now().Add(-2 * staleFileLockAge)
+	require.NoError(t, os.Chtimes(lockFile, staleTime, staleTime))
+	stale, err := os.Stat(lockFile)
+	require.NoError(t, err)
+
+	t.Run("lock acquired by another process", func(t *testing.T) {
+		// Another process removed the stale lock and acquired the lock since
+		// it was found stale
+		require.NoError(t, os.Remove(lockFile))
+		require.NoError(t, ioutil.WriteFile(lockFile, []byte("fresh"), 0600))
+
+		require.NoError(t, removeStaleLock(lockFile, stale))
+		data, err := ioutil.ReadFile(lockFile)
+		require.NoError(t, err, "the lock of the other process should not be removed")
+		assert.Equal(t, "fresh", string(data))
+
+		stale, err = os.Stat(lockFile)
+		require.NoError(t, err)
+	})
+
+	t.Run("stale lock", func(t *testing.T) {
+		require.NoError(t, removeStaleLock(lockFile, stale))
+		assert.NoFileExists(t, lockFile)
+
+		matches, err := filepath.Glob(lockFile + "*")
+		require.NoError(t, err)
+		assert.Empty(t, matches, "the renamed lock file should be removed")
+	})
+
+	t.Run("removed by another process", func(t *testing.T) {
+		require.NoError(t, removeStaleLock(lockFile, stale))
+	})
+}
@@ -0,0 +1,39 @@
+package crud
+
+// Indexer may be implemented by a Store that can maintain secondary indexes
+// of its items, for example using the indexes of a database, so that items
+// can be found by the value of a field without reading every item.
+//
+// Each index of an item has a single value. The values are provided by the
+// code saving the items, such as the claim store, which updates them
+// whenever an item is saved or deleted.
+type Indexer interface {
+	// SetIndexes sets the values of the secondary indexes of an item of the
+	// specified itemType. Indexes that are not specified keep their values.
+	SetIndexes(itemType string, name string, indexes map[string]string) error
+
+	// RemoveIndexes removes an item of the specified itemType from every
+	// secondary index.
+	RemoveIndexes(itemType string, name string) error
+
+	// ListByIndex returns the names of the items of the specified itemType
+	// whose index has the value.
+	ListByIndex(itemType string, index string, value string) ([]string, error)
+}
+
+// indexerProvider is implemented by the Store decorators in this package,
+// which provide the Indexer of the store that they decorate.
+type indexerProvider interface {
+	indexer() (Indexer, bool)
+}
+
+// GetIndexer returns the Indexer of a store, when the store, or the store
+// decorated by a TracingStore, EncryptedStore or ManagedStore, maintains
+// secondary indexes.
+func GetIndexer(store Store) (Indexer, bool) {
+	if provider, ok := store.(indexerProvider); ok {
+		return provider.indexer()
+	}
+	indexer, ok := store.(Indexer)
+	return indexer, ok
+}
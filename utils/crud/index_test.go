@@ -0,0 +1,97 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indexingStore maintains secondary indexes in memory.
+type indexingStore struct {
+	*MockStore
+
+	// indexes maps itemType -> name -> index -> value.
+	indexes map[string]map[string]map[string]string
+}
+
+func newIndexingStore() *indexingStore {
+	return &indexingStore{
+		MockStore: NewMockStore(),
+		indexes:   map[string]map[string]map[string]string{},
+	}
+}
+
+func (s *indexingStore) SetIndexes(itemType string, name string, indexes map[string]string) error {
+	if s.indexes[itemType] == nil {
+		s.indexes[itemType] = map[string]map[string]string{}
+	}
+	if s.indexes[itemType][name] == nil {
+		s.indexes[itemType][name] = map[string]string{}
+	}
+	for index, value := range indexes {
+		s.indexes[itemType][name][index] = value
+	}
+	return nil
+}
+
+func (s *indexingStore) RemoveIndexes(itemType string, name string) error {
+	delete(s.indexes[itemType], name)
+	return nil
+}
+
+func (s *indexingStore) ListByIndex(itemType string, index string, value string) ([]string, error) {
+	var names []string
+	for name, indexes := range s.indexes[itemType] {
+		if indexes[index] == value {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func TestGetIndexer(t *testing.T) {
+	t.Run("not an indexer", func(t *testing.T) {
+		_, ok := GetIndexer(NewMockStore())
+		assert.False(t, ok)
+
+		_, ok = GetIndexer(NewTracingStore(NewMockStore(), nil))
+		assert.False(t, ok, "a decorator should only provide an Indexer when its backing store does")
+	})
+
+	t.Run("indexer", func(t *testing.T) {
+		backingStore := newIndexingStore()
+		indexer, ok := GetIndexer(backingStore)
+		require.True(t, ok)
+		assert.Equal(t, backingStore, indexer)
+	})
+
+	t.Run("decorators", func(t *testing.T) {
+		backingStore := newIndexingStore()
+		var ops []StoreOperation
+		tracing := NewTracingStore(backingStore, func(op StoreOperation) {
+			ops = append(ops, op)
+		})
+		encrypted, err := NewEncryptedStore(tracing, "key1", newTestAEAD(t, "0123456789abcdef"))
+		require.NoError(t, err)
+		managed := NewManagedStore(encrypted)
+
+		indexer, ok := GetIndexer(managed)
+		require.True(t, ok, "the Indexer of the backing store should be available through the decorators")
+		require.NoError(t, indexer.SetIndexes("claims", "1", map[string]string{"action": "install"}))
+		names, err := indexer.ListByIndex("claims", "action", "install")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, names)
+		require.NoError(t, indexer.RemoveIndexes("claims", "1"))
+		assert.Empty(t, backingStore.indexes["claims"])
+
+		for i := range ops {
+			ops[i].Duration = 0
+		}
+		assert.Equal(t, []StoreOperation{
+			{Method: MethodSetIndexes, ItemType: "claims", Name: "1"},
+			{Method: MethodListByIndex, ItemType: "claims"},
+			{Method: MethodRemoveIndexes, ItemType: "claims", Name: "1"},
+		}, ops, "the operations on the indexes should be traced")
+	})
+}
@@ -0,0 +1,380 @@
+// Package kubernetes provides a crud.Store that persists documents, such as
+// claims, results and outputs, as Kubernetes secrets, so that a CNAB runtime
+// running in a cluster does not need an external database.
+package kubernetes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+const (
+	// LabelItemType is the label with the item type of the document stored
+	// in a secret.
+	LabelItemType = "cnab.io/item-type"
+
+	// LabelGroup is the label with a hash of the group of the document stored
+	// in a secret, because group names may not be valid label values.
+	LabelGroup = "cnab.io/group-hash"
+
+	// AnnotationName is the annotation with the name of the document stored
+	// in a secret.
+	AnnotationName = "cnab.io/name"
+
+	// AnnotationGroup is the annotation with the group of the document stored
+	// in a secret.
+	AnnotationGroup = "cnab.io/group"
+
+	// LabelIndexPrefix is the prefix of the labels holding the values of the
+	// secondary indexes of the document stored in a secret. Index names and
+	// values that are not valid in a label are replaced with their hash.
+	LabelIndexPrefix = "index.cnab.io/"
+
+	// SecretType is the type of the secrets created by the store.
+	SecretType v1.SecretType = "cnab.io/document"
+
+	// dataKey is the key in the secret's data holding the document.
+	dataKey = "data"
+)
+
+var (
+	_ crud.Store            = &SecretStore{}
+	_ crud.ConditionalStore = &SecretStore{}
+	_ crud.Indexer          = &SecretStore{}
+
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// SecretStore is a crud.Store that persists each document as a secret in a
+// namespace, labeled with its item type and group. Secrets are limited to
+// 1MiB by Kubernetes, so very large documents such as logs may not be stored.
+//
+// The store implements crud.Indexer, using the labels of the secrets to
+// maintain the secondary indexes of the documents.
+type SecretStore struct {
+	secrets coreclientv1.SecretInterface
+
+	// Labels are applied to every secret created by the store and select the
+	// secrets that belong to the store, so that several stores may share a
+	// namespace.
+	Labels map[string]string
+}
+
+// NewSecretStore creates a store that persists documents using the secrets
+// client, for example coreClient.Secrets(namespace).
+func NewSecretStore(secrets coreclientv1.SecretInterface) *SecretStore {
+	return &SecretStore{
+		secrets: secrets,
+	}
+}
+
+func (s *SecretStore) Count(itemType string, group string) (int, error) {
+	names, err := s.List(itemType, group)
+	return len(names), err
+}
+
+func (s *SecretStore) List(itemType string, group string) ([]string, error) {
+	selector := s.selector(itemType)
+	if group != "" {
+		selector[LabelGroup] = hash(group)
+	}
+
+	list, err := s.secrets.List(context.Background(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing %s", itemType)
+	}
+
+	names := make([]string, 0, len(list.Items))
+	seen := map[string]bool{}
+	for _, secret := range list.Items {
+		name := secret.Annotations[AnnotationName]
+		if group == "" {
+			// When listing groups, return each group once
+			name = secret.Annotations[AnnotationGroup]
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+		} else if secret.Annotations[AnnotationGroup] != group {
+			// Guard against hash collisions
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s *SecretStore) Save(itemType string, group string, name string, data []byte) error {
+	ctx := context.Background()
+	secret := s.newSecret(itemType, group, name, data)
+
+	_, err := s.secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		var existing *v1.Secret
+		existing, err = s.secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+		if err == nil {
+			keepIndexLabels(secret, existing)
+			secret.ResourceVersion = existing.ResourceVersion
+			_, err = s.secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		}
+	}
+
+	return errors.Wrapf(err, "error saving %s %s", itemType, name)
+}
+
+// SaveIfRevision saves the document when the stored document has the
+// revision. The secret is updated with the resource version of the secret
+// that was compared, so Kubernetes rejects the update when the secret was
+// modified in the meantime.
+func (s *SecretStore) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	ctx := context.Background()
+	secret := s.newSecret(itemType, group, name, data)
+	conflict := errors.Wrapf(crud.ErrConflict, "error saving %s %s", itemType, name)
+
+	if revision == "" {
+		_, err := s.secrets.Create(ctx, secret, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return conflict
+		}
+		return errors.Wrapf(err, "error saving %s %s", itemType, name)
+	}
+
+	existing, err := s.secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return conflict
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error saving %s %s", itemType, name)
+	}
+	if crud.Revision(existing.Data[dataKey]) != revision {
+		return conflict
+	}
+
+	keepIndexLabels(secret, existing)
+	secret.ResourceVersion = existing.ResourceVersion
+	_, err = s.secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		return conflict
+	}
+	return errors.Wrapf(err, "error saving %s %s", itemType, name)
+}
+
+// DeleteIfRevision deletes the document when the stored document has the
+// revision. The secret is deleted with a precondition on the resource version
+// of the secret that was compared, so Kubernetes rejects the delete when the
+// secret was modified in the meantime.
+func (s *SecretStore) DeleteIfRevision(itemType string, name string, revision string) error {
+	ctx := context.Background()
+	conflict := errors.Wrapf(crud.ErrConflict, "error deleting %s %s", itemType, name)
+
+	existing, err := s.secrets.Get(ctx, secretName(itemType, name), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return crud.ErrRecordDoesNotExist
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error deleting %s %s", itemType, name)
+	}
+	if crud.Revision(existing.Data[dataKey]) != revision {
+		return conflict
+	}
+
+	err = s.secrets.Delete(ctx, existing.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &existing.UID, ResourceVersion: &existing.ResourceVersion},
+	})
+	if apierrors.IsNotFound(err) {
+		return crud.ErrRecordDoesNotExist
+	}
+	if apierrors.IsConflict(err) {
+		return conflict
+	}
+	return errors.Wrapf(err, "error deleting %s %s", itemType, name)
+}
+
+// newSecret returns the secret that stores a document.
+func (s *SecretStore) newSecret(itemType string, group string, name string, data []byte) *v1.Secret {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   secretName(itemType, name),
+			Labels: s.selector(itemType),
+			Annotations: map[string]string{
+				AnnotationName:  name,
+				AnnotationGroup: group,
+			},
+		},
+		Type: SecretType,
+		Data: map[string][]byte{dataKey: data},
+	}
+	if group != "" {
+		secret.Labels[LabelGroup] = hash(group)
+	}
+	return secret
+}
+
+func (s *SecretStore) Read(itemType string, name string) ([]byte, error) {
+	secret, err := s.secrets.Get(context.Background(), secretName(itemType, name), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, crud.ErrRecordDoesNotExist
+		}
+		return nil, errors.Wrapf(err, "error reading %s %s", itemType, name)
+	}
+
+	return secret.Data[dataKey], nil
+}
+
+func (s *SecretStore) Delete(itemType string, name string) error {
+	err := s.secrets.Delete(context.Background(), secretName(itemType, name), metav1.DeleteOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return crud.ErrRecordDoesNotExist
+		}
+		return errors.Wrapf(err, "error deleting %s %s", itemType, name)
+	}
+
+	return nil
+}
+
+// selector returns the labels of the secrets for the item type.
+// SetIndexes sets the values of the secondary indexes of a document in the
+// labels of its secret.
+func (s *SecretStore) SetIndexes(itemType string, name string, indexes map[string]string) error {
+	return s.updateIndexLabels(itemType, name, func(secretLabels map[string]string) {
+		for index, value := range indexes {
+			secretLabels[indexLabel(index)] = indexLabelValue(value)
+		}
+	})
+}
+
+// RemoveIndexes removes the labels of every secondary index from the secret
+// of a document. It does nothing when the document does not exist.
+func (s *SecretStore) RemoveIndexes(itemType string, name string) error {
+	err := s.updateIndexLabels(itemType, name, func(secretLabels map[string]string) {
+		for key := range secretLabels {
+			if strings.HasPrefix(key, LabelIndexPrefix) {
+				delete(secretLabels, key)
+			}
+		}
+	})
+	if errors.Is(err, crud.ErrRecordDoesNotExist) {
+		return nil
+	}
+	return err
+}
+
+// ListByIndex returns the names of the documents whose secret has the label
+// of the index with the value.
+func (s *SecretStore) ListByIndex(itemType string, index string, value string) ([]string, error) {
+	selector := s.selector(itemType)
+	selector[indexLabel(index)] = indexLabelValue(value)
+
+	list, err := s.secrets.List(context.Background(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing %s by index %s", itemType, index)
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for _, secret := range list.Items {
+		names = append(names, secret.Annotations[AnnotationName])
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// updateIndexLabels updates the labels of the secret of a document, retrying
+// when the secret was modified concurrently.
+func (s *SecretStore) updateIndexLabels(itemType string, name string, update func(secretLabels map[string]string)) error {
+	ctx := context.Background()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := s.secrets.Get(ctx, secretName(itemType, name), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		update(secret.Labels)
+		_, err = s.secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return crud.ErrRecordDoesNotExist
+	}
+	return errors.Wrapf(err, "error indexing %s %s", itemType, name)
+}
+
+// keepIndexLabels copies the labels of the secondary indexes of the existing
+// secret to the secret replacing it, so that saving a document does not
+// remove it from the indexes.
+func keepIndexLabels(secret *v1.Secret, existing *v1.Secret) {
+	for key, value := range existing.Labels {
+		if strings.HasPrefix(key, LabelIndexPrefix) {
+			secret.Labels[key] = value
+		}
+	}
+}
+
+// indexLabel returns the label holding the value of an index.
+func indexLabel(index string) string {
+	if index == "" || len(validation.IsValidLabelValue(index)) > 0 {
+		index = hash(index)
+	}
+	return LabelIndexPrefix + index
+}
+
+// indexLabelValue returns the value of an index as a valid label value.
+func indexLabelValue(value string) string {
+	if len(validation.IsValidLabelValue(value)) > 0 {
+		return hash(value)
+	}
+	return value
+}
+
+func (s *SecretStore) selector(itemType string) map[string]string {
+	set := make(map[string]string, len(s.Labels)+2)
+	for k, v := range s.Labels {
+		set[k] = v
+	}
+	set[LabelItemType] = itemType
+	return set
+}
+
+// secretName returns a valid name for the secret holding a document, that
+// includes the item type for readability and a hash of the document's name,
+// because names are unique within an item type but may not be valid resource
+// names.
+func secretName(itemType string, name string) string {
+	prefix := invalidNameChars.ReplaceAllString(strings.ToLower(itemType), "-")
+	if len(prefix) > 20 {
+		prefix = prefix[:20]
+	}
+	prefix = strings.Trim(prefix, "-")
+	return "cnab-" + prefix + "-" + hash(itemType+"/"+name)
+}
+
+// hash returns a short hash of the value, that is a valid label value and
+// resource name.
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:20])
+}
@@ -0,0 +1,247 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/cnabio/cnab-go/bundle"
+	"github.com/cnabio/cnab-go/bundle/definition"
+	"github.com/cnabio/cnab-go/claim"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+func TestSecretStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets("cnab")
+	s := NewSecretStore(secrets)
+	s.Labels = map[string]string{"app": "porter"}
+
+	require.NoError(t, s.Save("claims", "mysql", "01E2ZZ2FEPK5HP8SQ0BEE6FWHB", []byte("install")))
+	require.NoError(t, s.Save("claims", "mysql", "01E2ZZ2FEPK5HP8SQ0BEE6FWHC", []byte("upgrade")))
+	require.NoError(t, s.Save("claims", "my wordpress", "01E2ZZ2FEPK5HP8SQ0BEE6FWHD", []byte("install")))
+	require.NoError(t, s.Save("results", "01E2ZZ2FEPK5HP8SQ0BEE6FWHB", "01E2ZZ2FEPK5HP8SQ0BEE6FWHE", []byte("succeeded")))
+
+	groups, err := s.List("claims", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"my wordpress", "mysql"}, groups)
+
+	names, err := s.List("claims", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01E2ZZ2FEPK5HP8SQ0BEE6FWHB", "01E2ZZ2FEPK5HP8SQ0BEE6FWHC"}, names)
+
+	count, err := s.Count("claims", "my wordpress")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	data, err := s.Read("claims", "01E2ZZ2FEPK5HP8SQ0BEE6FWHD")
+	require.NoError(t, err)
+	assert.Equal(t, "install", string(data))
+
+	// Saving an existing item overwrites it
+	require.NoError(t, s.Save("claims", "mysql", "01E2ZZ2FEPK5HP8SQ0BEE6FWHC", []byte("uninstall")))
+	data, err = s.Read("claims", "01E2ZZ2FEPK5HP8SQ0BEE6FWHC")
+	require.NoError(t, err)
+	assert.Equal(t, "uninstall", string(data))
+
+	_, err = s.Read("claims", "missing")
+	assert.Equal(t, crud.ErrRecordDoesNotExist, err)
+
+	require.NoError(t, s.Delete("claims", "01E2ZZ2FEPK5HP8SQ0BEE6FWHD"))
+	err = s.Delete("claims", "01E2ZZ2FEPK5HP8SQ0BEE6FWHD")
+	assert.Equal(t, crud.ErrRecordDoesNotExist, err)
+
+	groups, err = s.List("claims", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, groups)
+
+	// Secrets are labeled so that they can be found with kubectl
+	list, err := secrets.List(context.Background(), metav1.ListOptions{LabelSelector: "app=porter," + LabelItemType + "=results"})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, SecretType, list.Items[0].Type)
+}
+
+func TestSecretStore_SaveIfRevision(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s := NewSecretStore(client.CoreV1().Secrets("cnab"))
+
+	require.NoError(t, s.SaveIfRevision("results", "1", "a", []byte("running"), ""))
+	err := s.SaveIfRevision("results", "1", "a", []byte("running"), "")
+	assert.ErrorIs(t, err, crud.ErrConflict, "the document should not be created when it already exists")
+
+	revision := crud.Revision([]byte("running"))
+	err = s.SaveIfRevision("results", "1", "a", []byte("succeeded"), crud.Revision([]byte("canceled")))
+	assert.ErrorIs(t, err, crud.ErrConflict, "the document should not be updated when its revision is different")
+
+	require.NoError(t, s.SaveIfRevision("results", "1", "a", []byte("succeeded"), revision))
+	data, err := s.Read("results", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "succeeded", string(data))
+
+	err = s.SaveIfRevision("results", "1", "missing", []byte("succeeded"), revision)
+	assert.ErrorIs(t, err, crud.ErrConflict, "the document should not be created when a revision is expected")
+
+	t.Run("modified during the update", func(t *testing.T) {
+		client.PrependReactor("update", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewConflict(v1.Resource("secrets"), "a", errors.New("the object has been modified"))
+		})
+
+		err = s.SaveIfRevision("results", "1", "a", []byte("failed"), crud.Revision(data))
+		assert.ErrorIs(t, err, crud.ErrConflict)
+	})
+}
+
+func TestSecretStore_DeleteIfRevision(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s := NewSecretStore(client.CoreV1().Secrets("cnab"))
+
+	err := s.DeleteIfRevision("locks", "mysql", crud.Revision([]byte("claim1")))
+	assert.ErrorIs(t, err, crud.ErrRecordDoesNotExist)
+
+	require.NoError(t, s.Save("locks", "", "mysql", []byte("claim1")))
+	err = s.DeleteIfRevision("locks", "mysql", crud.Revision([]byte("claim2")))
+	assert.ErrorIs(t, err, crud.ErrConflict, "the document should not be deleted when its revision is different")
+
+	t.Run("modified during the delete", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		s := NewSecretStore(client.CoreV1().Secrets("cnab"))
+		require.NoError(t, s.Save("locks", "", "mysql", []byte("claim1")))
+
+		var preconditions *metav1.Preconditions
+		client.PrependReactor("delete", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			preconditions = action.(k8stesting.DeleteAction).GetDeleteOptions().Preconditions
+			return true, nil, apierrors.NewConflict(v1.Resource("secrets"), "mysql", errors.New("the object has been modified"))
+		})
+
+		err := s.DeleteIfRevision("locks", "mysql", crud.Revision([]byte("claim1")))
+		assert.ErrorIs(t, err, crud.ErrConflict)
+		require.NotNil(t, preconditions, "the secret should be deleted with preconditions")
+		assert.NotNil(t, preconditions.ResourceVersion)
+	})
+
+	require.NoError(t, s.DeleteIfRevision("locks", "mysql", crud.Revision([]byte("claim1"))))
+	_, err = s.Read("locks", "mysql")
+	assert.ErrorIs(t, err, crud.ErrRecordDoesNotExist)
+}
+
+func TestSecretStore_ClaimStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := claim.NewClaimStore(NewSecretStore(client.CoreV1().Secrets("cnab")), nil, nil)
+
+	b := bundle.Bundle{
+		Name:        "mysql",
+		Version:     "0.1.0",
+		Definitions: definition.Definitions{"string": {Type: "string"}},
+		Outputs:     map[string]bundle.Output{"connection_string": {Definition: "string"}},
+	}
+	c, err := claim.New("mysql", claim.ActionInstall, b, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(c))
+	r, err := c.NewResult(claim.StatusSucceeded)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+	require.NoError(t, store.SaveOutput(claim.NewOutput(c, r, "connection_string", []byte("root@localhost"))))
+
+	installations, err := store.ListInstallations()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, installations)
+
+	o, err := store.ReadLastOutput("mysql", "connection_string")
+	require.NoError(t, err)
+	assert.Equal(t, "root@localhost", string(o.Value))
+}
+
+func TestSecretStore_Indexer(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s := NewSecretStore(client.CoreV1().Secrets("cnab"))
+
+	require.NoError(t, s.Save("claims", "mysql", "a", []byte("install")))
+	require.NoError(t, s.Save("claims", "mysql", "b", []byte("upgrade")))
+	require.NoError(t, s.SetIndexes("claims", "a", map[string]string{"action": "install", "bundleVersion": "1.0.0+build.1"}))
+	require.NoError(t, s.SetIndexes("claims", "b", map[string]string{"action": "upgrade", "bundleVersion": "1.0.0+build.1"}))
+
+	names, err := s.ListByIndex("claims", "action", "install")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, names)
+
+	names, err = s.ListByIndex("claims", "bundleVersion", "1.0.0+build.1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names, "values that are not valid label values should be indexed")
+
+	require.NoError(t, s.Save("claims", "mysql", "a", []byte("install again")))
+	names, err = s.ListByIndex("claims", "action", "install")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, names, "saving a document should keep its indexes")
+
+	require.NoError(t, s.RemoveIndexes("claims", "a"))
+	names, err = s.ListByIndex("claims", "action", "install")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, s.RemoveIndexes("claims", "missing"), "removing the indexes of a missing document should do nothing")
+	err = s.SetIndexes("claims", "missing", map[string]string{"action": "install"})
+	assert.ErrorIs(t, err, crud.ErrRecordDoesNotExist)
+
+	secret, err := client.CoreV1().Secrets("cnab").Get(context.Background(), secretName("claims", "b"), metav1.GetOptions{})
+	require.NoError(t, err)
+	for key, value := range secret.Labels {
+		assert.Empty(t, validation.IsQualifiedName(key), "invalid label %s", key)
+		assert.Empty(t, validation.IsValidLabelValue(value), "invalid value for the label %s", key)
+	}
+}
+
+func TestSecretStore_ClaimStoreIndexes(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	var ops []crud.StoreOperation
+	backingStore := crud.NewTracingStore(NewSecretStore(client.CoreV1().Secrets("cnab")), func(op crud.StoreOperation) {
+		ops = append(ops, op)
+	})
+	store := claim.NewClaimStore(backingStore, nil, nil)
+
+	b := bundle.Bundle{Name: "mysql", Version: "0.1.0"}
+	install, err := claim.New("mysql", claim.ActionInstall, b, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(install))
+	upgrade, err := install.NewClaim(claim.ActionUpgrade, b, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveClaim(upgrade))
+	r, err := upgrade.NewResult(claim.StatusFailed)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveResult(r))
+
+	claimIDs, err := store.ListClaimsByIndex(claim.IndexAction, claim.ActionUpgrade)
+	require.NoError(t, err)
+	assert.Equal(t, []string{upgrade.ID}, claimIDs)
+
+	page, err := store.QueryClaims(claim.ClaimQuery{Statuses: []string{claim.StatusFailed}})
+	require.NoError(t, err)
+	require.Len(t, page.Claims, 1)
+	assert.Equal(t, upgrade.ID, page.Claims[0].ID)
+
+	var listedByIndex bool
+	for _, op := range ops {
+		if op.Method == crud.MethodListByIndex {
+			listedByIndex = true
+		}
+	}
+	assert.True(t, listedByIndex, "the indexes of the secret store should be used through the tracing store")
+}
+
+func TestSecretName(t *testing.T) {
+	for _, itemType := range []string{"claims", "migration-backups", "Some_Very_Long_Item_Type_Name-"} {
+		name := secretName(itemType, "01E2ZZ2FEPK5HP8SQ0BEE6FWHB-connection_string")
+		assert.Empty(t, validation.IsDNS1123Subdomain(name), "invalid secret name %s", name)
+	}
+	assert.NotEqual(t, secretName("claims", "a"), secretName("results", "a"))
+}
@@ -0,0 +1,200 @@
+package crud
+
+var (
+	_ Store            = &ManagedStore{}
+	_ ConditionalStore = &ManagedStore{}
+)
+
+// ManagedStore is a wrapper around a Store that handles connecting to and
+// closing the underlying store, when it requires it, on each operation.
+type ManagedStore struct {
+	// AutoClose specifies if the connection to the backing store should be
+	// closed after each operation. Set it to false and call Close explicitly
+	// when performing many operations in a row.
+	AutoClose bool
+
+	// opened specifies if the backing store is currently connected.
+	opened bool
+
+	// backingStore is the wrapped store.
+	backingStore Store
+}
+
+// NewManagedStore wraps the specified store. When the store is already
+// a ManagedStore, it is returned unchanged.
+func NewManagedStore(store Store) *ManagedStore {
+	if managed, ok := store.(*ManagedStore); ok {
+		return managed
+	}
+
+	return &ManagedStore{
+		AutoClose:    true,
+		backingStore: store,
+	}
+}
+
+// indexer returns the Indexer of the backing store.
+func (s *ManagedStore) indexer() (Indexer, bool) {
+	return GetIndexer(s.backingStore)
+}
+
+// GetStore returns the wrapped backing store.
+func (s *ManagedStore) GetStore() Store {
+	return s.backingStore
+}
+
+// Connect to the backing store, if it is not already connected.
+func (s *ManagedStore) Connect() error {
+	if s.opened {
+		return nil
+	}
+
+	if connectable, ok := s.backingStore.(HasConnect); ok {
+		if err := connectable.Connect(); err != nil {
+			return err
+		}
+	}
+
+	s.opened = true
+	return nil
+}
+
+// Close the connection to the backing store, if it is connected.
+func (s *ManagedStore) Close() error {
+	if !s.opened {
+		return nil
+	}
+
+	if closable, ok := s.backingStore.(HasClose); ok {
+		if err := closable.Close(); err != nil {
+			return err
+		}
+	}
+
+	s.opened = false
+	return nil
+}
+
+// HandleConnect connects to the backing store when necessary, and returns a
+// function that should be deferred to close the connection. The connection is
+// only closed when it was opened by this call and AutoClose is set.
+func (s *ManagedStore) HandleConnect() (func() error, error) {
+	if s.opened {
+		return func() error { return nil }, nil
+	}
+
+	err := s.Connect()
+	return s.autoClose, err
+}
+
+func (s *ManagedStore) autoClose() error {
+	if s.AutoClose {
+		return s.Close()
+	}
+	return nil
+}
+
+// Count the number of items of the specified itemType in a group.
+func (s *ManagedStore) Count(itemType string, group string) (int, error) {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return 0, err
+	}
+
+	return s.backingStore.Count(itemType, group)
+}
+
+// List the names of the items of the specified itemType in a group.
+func (s *ManagedStore) List(itemType string, group string) ([]string, error) {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.backingStore.List(itemType, group)
+}
+
+// Save the data for an item of the specified itemType.
+func (s *ManagedStore) Save(itemType string, group string, name string, data []byte) error {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	return s.backingStore.Save(itemType, group, name, data)
+}
+
+// SaveIfRevision saves the data for an item of the specified itemType, when
+// the stored item has the revision. See SaveIfRevision.
+func (s *ManagedStore) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	return SaveIfRevision(s.backingStore, itemType, group, name, data, revision)
+}
+
+// DeleteIfRevision deletes an item of the specified itemType, when the
+// stored item has the revision. See DeleteIfRevision.
+func (s *ManagedStore) DeleteIfRevision(itemType string, name string, revision string) error {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	return DeleteIfRevision(s.backingStore, itemType, name, revision)
+}
+
+// Read the data for an item of the specified itemType.
+func (s *ManagedStore) Read(itemType string, name string) ([]byte, error) {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.backingStore.Read(itemType, name)
+}
+
+// ReadAll retrieves the data for every item of the specified itemType in a
+// group, using a single connection to the backing store.
+func (s *ManagedStore) ReadAll(itemType string, group string) ([][]byte, error) {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := s.backingStore.List(itemType, group)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]byte, 0, len(names))
+	for _, name := range names {
+		data, err := s.backingStore.Read(itemType, name)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, data)
+	}
+
+	return results, nil
+}
+
+// Delete an item of the specified itemType.
+func (s *ManagedStore) Delete(itemType string, name string) error {
+	handleClose, err := s.HandleConnect()
+	defer handleClose()
+	if err != nil {
+		return err
+	}
+
+	return s.backingStore.Delete(itemType, name)
+}
@@ -0,0 +1,58 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagedStore_AutoClose(t *testing.T) {
+	mock := NewMockStore()
+	s := NewManagedStore(mock)
+
+	require.NoError(t, s.Save("claims", "mysql", "1", []byte("install")))
+	_, err := s.Read("claims", "1")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, mock.ConnectCount)
+	assert.Equal(t, 2, mock.CloseCount)
+}
+
+func TestManagedStore_HandleConnect(t *testing.T) {
+	mock := NewMockStore()
+	s := NewManagedStore(mock)
+
+	handleClose, err := s.HandleConnect()
+	require.NoError(t, err)
+
+	require.NoError(t, s.Save("claims", "mysql", "1", []byte("install")))
+	require.NoError(t, s.Save("claims", "mysql", "2", []byte("upgrade")))
+	data, err := s.ReadAll("claims", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("install"), []byte("upgrade")}, data)
+
+	require.NoError(t, handleClose())
+	assert.Equal(t, 1, mock.ConnectCount, "operations should reuse the open connection")
+	assert.Equal(t, 1, mock.CloseCount)
+}
+
+func TestManagedStore_DisableAutoClose(t *testing.T) {
+	mock := NewMockStore()
+	s := NewManagedStore(mock)
+	s.AutoClose = false
+
+	require.NoError(t, s.Save("claims", "mysql", "1", []byte("install")))
+	_, err := s.List("claims", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, 1, mock.ConnectCount)
+	assert.Equal(t, 0, mock.CloseCount)
+
+	require.NoError(t, s.Close())
+	assert.Equal(t, 1, mock.CloseCount)
+}
+
+func TestNewManagedStore_AlreadyManaged(t *testing.T) {
+	s := NewManagedStore(NewMockStore())
+	assert.Same(t, s, NewManagedStore(s))
+}
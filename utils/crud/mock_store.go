@@ -0,0 +1,118 @@
+package crud
+
+import (
+	"sort"
+)
+
+var (
+	_ Store            = &MockStore{}
+	_ ConditionalStore = &MockStore{}
+)
+
+// MockStore is an in-memory Store, intended for use in tests.
+type MockStore struct {
+	// ConnectCount is the number of times Connect was called.
+	ConnectCount int
+
+	// CloseCount is the number of times Close was called.
+	CloseCount int
+
+	// data maps itemType -> name -> item.
+	data map[string]map[string]mockItem
+}
+
+type mockItem struct {
+	group string
+	data  []byte
+}
+
+// NewMockStore creates an empty in-memory Store.
+func NewMockStore() *MockStore {
+	return &MockStore{
+		data: map[string]map[string]mockItem{},
+	}
+}
+
+// Connect records that the store was connected.
+func (s *MockStore) Connect() error {
+	s.ConnectCount++
+	return nil
+}
+
+// Close records that the store was closed.
+func (s *MockStore) Close() error {
+	s.CloseCount++
+	return nil
+}
+
+func (s *MockStore) Count(itemType string, group string) (int, error) {
+	names, err := s.List(itemType, group)
+	return len(names), err
+}
+
+func (s *MockStore) List(itemType string, group string) ([]string, error) {
+	items := s.data[itemType]
+
+	names := make([]string, 0, len(items))
+	if group == "" {
+		groups := map[string]struct{}{}
+		for _, item := range items {
+			if _, ok := groups[item.group]; !ok && item.group != "" {
+				groups[item.group] = struct{}{}
+				names = append(names, item.group)
+			}
+		}
+	} else {
+		for name, item := range items {
+			if item.group == group {
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MockStore) Save(itemType string, group string, name string, data []byte) error {
+	items, ok := s.data[itemType]
+	if !ok {
+		items = map[string]mockItem{}
+		s.data[itemType] = items
+	}
+
+	items[name] = mockItem{group: group, data: data}
+	return nil
+}
+
+func (s *MockStore) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	if err := checkRevision(s, itemType, name, revision); err != nil {
+		return err
+	}
+	return s.Save(itemType, group, name, data)
+}
+
+func (s *MockStore) DeleteIfRevision(itemType string, name string, revision string) error {
+	if err := checkDeleteRevision(s, itemType, name, revision); err != nil {
+		return err
+	}
+	return s.Delete(itemType, name)
+}
+
+func (s *MockStore) Read(itemType string, name string) ([]byte, error) {
+	item, ok := s.data[itemType][name]
+	if !ok {
+		return nil, ErrRecordDoesNotExist
+	}
+
+	return item.data, nil
+}
+
+func (s *MockStore) Delete(itemType string, name string) error {
+	if _, ok := s.data[itemType][name]; !ok {
+		return ErrRecordDoesNotExist
+	}
+
+	delete(s.data[itemType], name)
+	return nil
+}
@@ -0,0 +1,109 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// ErrConflict is returned when a record is saved with an expected revision
+// that does not match the revision of the stored record, because it was
+// modified, created or deleted by another process since it was read.
+var ErrConflict = errors.New("the record was modified since it was read")
+
+// Revision returns the revision of a record, which is the content digest of
+// its data as it is read from the store. The revision changes whenever the
+// record is saved with different data.
+func Revision(data []byte) string {
+	return digest.FromBytes(data).String()
+}
+
+// ConditionalStore is implemented by stores that can save or delete a record
+// only when the stored record has not been modified, atomically. Stores that
+// do not implement it are supported by SaveIfRevision and DeleteIfRevision,
+// without the guarantee that the record is not modified between the
+// comparison and the save or delete.
+type ConditionalStore interface {
+	// SaveIfRevision saves the data for an item of the specified itemType,
+	// when the Revision of the stored item matches the revision. An empty
+	// revision requires that the item does not exist yet. When the revisions
+	// do not match, an error wrapping ErrConflict is returned.
+	SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error
+
+	// DeleteIfRevision deletes an item of the specified itemType, when the
+	// Revision of the stored item matches the revision. When the revisions
+	// do not match, an error wrapping ErrConflict is returned, and
+	// ErrRecordDoesNotExist when the item does not exist.
+	DeleteIfRevision(itemType string, name string, revision string) error
+}
+
+// SaveIfRevision saves the data for an item of the specified itemType, when
+// the Revision of the stored item matches the revision, so that concurrent
+// processes updating the same item do not overwrite each other's changes. An
+// empty revision requires that the item does not exist yet. When the
+// revisions do not match, an error wrapping ErrConflict is returned.
+func SaveIfRevision(store Store, itemType string, group string, name string, data []byte, revision string) error {
+	if conditional, ok := store.(ConditionalStore); ok {
+		return conditional.SaveIfRevision(itemType, group, name, data, revision)
+	}
+
+	if err := checkRevision(store, itemType, name, revision); err != nil {
+		return err
+	}
+	return store.Save(itemType, group, name, data)
+}
+
+// DeleteIfRevision deletes an item of the specified itemType, when the
+// Revision of the stored item matches the revision, so that a record that
+// was modified by another process since it was read is not deleted. When the
+// revisions do not match, an error wrapping ErrConflict is returned, and
+// ErrRecordDoesNotExist when the item does not exist.
+func DeleteIfRevision(store Store, itemType string, name string, revision string) error {
+	if conditional, ok := store.(ConditionalStore); ok {
+		return conditional.DeleteIfRevision(itemType, name, revision)
+	}
+
+	if err := checkDeleteRevision(store, itemType, name, revision); err != nil {
+		return err
+	}
+	return store.Delete(itemType, name)
+}
+
+// checkDeleteRevision compares the Revision of the stored item, which must
+// exist, with the expected revision.
+func checkDeleteRevision(store Store, itemType string, name string, revision string) error {
+	current, err := store.Read(itemType, name)
+	if err != nil {
+		return err
+	}
+
+	if Revision(current) != revision {
+		return fmt.Errorf("could not delete %s %s: %w", itemType, name, ErrConflict)
+	}
+	return nil
+}
+
+// checkRevision compares the Revision of the stored item with the expected
+// revision.
+func checkRevision(store Store, itemType string, name string, revision string) error {
+	current, err := store.Read(itemType, name)
+	if errors.Is(err, ErrRecordDoesNotExist) {
+		if revision == "" {
+			return nil
+		}
+		return conflictError(itemType, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if Revision(current) != revision {
+		return conflictError(itemType, name)
+	}
+	return nil
+}
+
+func conflictError(itemType string, name string) error {
+	return fmt.Errorf("could not save %s %s: %w", itemType, name, ErrConflict)
+}
@@ -0,0 +1,101 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevision(t *testing.T) {
+	assert.Equal(t, Revision([]byte("install")), Revision([]byte("install")))
+	assert.NotEqual(t, Revision([]byte("install")), Revision([]byte("upgrade")))
+	assert.Contains(t, Revision([]byte("install")), "sha256:")
+}
+
+// revisionTestStores are stores saving and deleting records conditionally,
+// with and without implementing ConditionalStore.
+var revisionTestStores = []struct {
+	name  string
+	store func(t *testing.T) Store
+}{
+	{name: "conditional store", store: func(t *testing.T) Store {
+		return NewMockStore()
+	}},
+	{name: "filesystem store", store: func(t *testing.T) Store {
+		return NewFileSystemStore(t.TempDir(), map[string]string{"results": ".json"})
+	}},
+	{name: "managed store", store: func(t *testing.T) Store {
+		return NewManagedStore(NewMockStore())
+	}},
+	{name: "encrypted store", store: func(t *testing.T) Store {
+		s, err := NewEncryptedStore(NewMockStore(), "key1", newTestAEAD(t, "0123456789abcdef"))
+		require.NoError(t, err)
+		return s
+	}},
+	{name: "other store", store: func(t *testing.T) Store {
+		// Only the methods of Store are promoted
+		return struct{ Store }{NewMockStore()}
+	}},
+}
+
+func TestSaveIfRevision(t *testing.T) {
+	for _, tc := range revisionTestStores {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.store(t)
+
+			require.NoError(t, SaveIfRevision(s, "results", "1", "a", []byte("running"), ""))
+			err := SaveIfRevision(s, "results", "1", "a", []byte("running"), "")
+			assert.ErrorIs(t, err, ErrConflict, "the record should not be created when it already exists")
+
+			data, err := s.Read("results", "a")
+			require.NoError(t, err)
+			revision := Revision(data)
+
+			// Another process updates the record after it was read
+			require.NoError(t, s.Save("results", "1", "a", []byte("canceled")))
+
+			err = SaveIfRevision(s, "results", "1", "a", []byte("succeeded"), revision)
+			assert.ErrorIs(t, err, ErrConflict, "the record should not be updated when it was modified")
+			data, err = s.Read("results", "a")
+			require.NoError(t, err)
+			assert.Equal(t, "canceled", string(data), "the concurrent change should not be overwritten")
+
+			require.NoError(t, SaveIfRevision(s, "results", "1", "a", []byte("succeeded"), Revision(data)))
+			data, err = s.Read("results", "a")
+			require.NoError(t, err)
+			assert.Equal(t, "succeeded", string(data))
+
+			require.NoError(t, s.Delete("results", "a"))
+			err = SaveIfRevision(s, "results", "1", "a", []byte("running"), Revision(data))
+			assert.ErrorIs(t, err, ErrConflict, "the record should not be recreated when it was deleted")
+		})
+	}
+}
+
+func TestDeleteIfRevision(t *testing.T) {
+	for _, tc := range revisionTestStores {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.store(t)
+
+			err := DeleteIfRevision(s, "results", "a", Revision([]byte("running")))
+			assert.ErrorIs(t, err, ErrRecordDoesNotExist)
+
+			require.NoError(t, s.Save("results", "1", "a", []byte("running")))
+			revision := Revision([]byte("running"))
+
+			// Another process updates the record after it was read
+			require.NoError(t, s.Save("results", "1", "a", []byte("canceled")))
+
+			err = DeleteIfRevision(s, "results", "a", revision)
+			assert.ErrorIs(t, err, ErrConflict, "the record should not be deleted when it was modified")
+			data, err := s.Read("results", "a")
+			require.NoError(t, err)
+			assert.Equal(t, "canceled", string(data))
+
+			require.NoError(t, DeleteIfRevision(s, "results", "a", Revision(data)))
+			_, err = s.Read("results", "a")
+			assert.ErrorIs(t, err, ErrRecordDoesNotExist)
+		})
+	}
+}
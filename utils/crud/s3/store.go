@@ -0,0 +1,535 @@
+// Package s3 provides a crud.Store that persists documents, such as claims,
+// results and outputs, as objects in an S3 bucket, or any storage with an S3
+// compatible API such as MinIO, so that serverless CNAB runtimes can persist
+// claims durably without a database.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cnabio/cnab-go/internal/sigv4"
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+const (
+	// EnvAccessKeyID is the environment variable for the access key.
+	EnvAccessKeyID = "AWS_ACCESS_KEY_ID"
+
+	// EnvSecretAccessKey is the environment variable for the secret key.
+	EnvSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+
+	// EnvSessionToken is the environment variable for the session token of
+	// temporary credentials.
+	EnvSessionToken = "AWS_SESSION_TOKEN"
+
+	// EnvRegion is the environment variable for the region of the bucket.
+	EnvRegion = "AWS_REGION"
+
+	// EnvDefaultRegion is the environment variable for the region of the
+	// bucket, used when AWS_REGION is not set.
+	EnvDefaultRegion = "AWS_DEFAULT_REGION"
+
+	// EnvEndpoint is the environment variable for the endpoint of an S3
+	// compatible storage, such as MinIO.
+	EnvEndpoint = "AWS_ENDPOINT_URL_S3"
+
+	// DefaultRegion is the region used when Config.Region is not set.
+	DefaultRegion = "us-east-1"
+
+	// SSEAES256 encrypts the objects with keys managed by S3.
+	SSEAES256 = "AES256"
+
+	// SSEKMS encrypts the objects with a key managed by AWS KMS.
+	SSEKMS = "aws:kms"
+
+	// metadataGroup is the header of the object metadata holding the group
+	// of a document.
+	metadataGroup = "X-Amz-Meta-Cnab-Group"
+)
+
+var (
+	_ crud.Store            = &Store{}
+	_ crud.ConditionalStore = &Store{}
+)
+
+// Config of the connection to the bucket.
+type Config struct {
+	// Bucket where the documents are stored. Required.
+	Bucket string
+
+	// Prefix of the keys of the objects created by the store, for example
+	// "cnab/", so that several stores may share a bucket.
+	Prefix string
+
+	// Region of the bucket. Defaults to DefaultRegion.
+	Region string
+
+	// Endpoint of the S3 API, for example http://localhost:9000 for MinIO.
+	// Defaults to the AWS endpoint of the region.
+	Endpoint string
+
+	// PathStyle addresses the bucket in the path of the requests, instead of
+	// in the host name. S3 compatible storages such as MinIO usually require
+	// it.
+	PathStyle bool
+
+	// Credentials used to sign the requests. Required.
+	Credentials Credentials
+
+	// ServerSideEncryption of the objects, either SSEAES256 or SSEKMS. Leave
+	// empty to use the default encryption of the bucket.
+	ServerSideEncryption string
+
+	// SSEKMSKeyID is the KMS key used to encrypt the objects when
+	// ServerSideEncryption is SSEKMS. Defaults to the AWS managed key.
+	SSEKMSKeyID string
+
+	// HTTPClient used to connect to S3. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Credentials of an AWS account, or of an S3 compatible storage, used to sign
+// requests with AWS Signature Version 4.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken of temporary credentials, for example the credentials of
+	// an AWS Lambda function. Leave empty for long-term credentials.
+	SessionToken string
+}
+
+// NewConfigFromEnv creates a Config for the bucket using the standard AWS
+// environment variables. MinIO, and other storages set with
+// AWS_ENDPOINT_URL_S3, are addressed with path style requests.
+func NewConfigFromEnv(bucket string) Config {
+	region := os.Getenv(EnvRegion)
+	if region == "" {
+		region = os.Getenv(EnvDefaultRegion)
+	}
+	endpoint := os.Getenv(EnvEndpoint)
+
+	return Config{
+		Bucket:    bucket,
+		Region:    region,
+		Endpoint:  endpoint,
+		PathStyle: endpoint != "",
+		Credentials: Credentials{
+			AccessKeyID:     os.Getenv(EnvAccessKeyID),
+			SecretAccessKey: os.Getenv(EnvSecretAccessKey),
+			SessionToken:    os.Getenv(EnvSessionToken),
+		},
+	}
+}
+
+// Store is a crud.Store that persists each document as an object named
+// PREFIX/ITEMTYPE/items/NAME. The group of each document is recorded in the
+// metadata of its object and by an empty object named
+// PREFIX/ITEMTYPE/groups/GROUP/NAME, so that the documents of a group are
+// listed without reading them.
+type Store struct {
+	config   Config
+	endpoint *url.URL
+}
+
+// NewStore creates a store that persists documents in the configured bucket.
+func NewStore(config Config) (*Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("the bucket is required")
+	}
+	if config.Credentials.AccessKeyID == "" || config.Credentials.SecretAccessKey == "" {
+		return nil, errors.New("the access key and secret key are required")
+	}
+	switch config.ServerSideEncryption {
+	case "", SSEAES256, SSEKMS:
+	default:
+		return nil, fmt.Errorf("invalid server side encryption %q, the supported values are %s and %s", config.ServerSideEncryption, SSEAES256, SSEKMS)
+	}
+	if config.Region == "" {
+		config.Region = DefaultRegion
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", config.Endpoint)
+	}
+	if !config.PathStyle {
+		endpoint.Host = config.Bucket + "." + endpoint.Host
+	}
+
+	return &Store{
+		config:   config,
+		endpoint: endpoint,
+	}, nil
+}
+
+func (s *Store) Count(itemType string, group string) (int, error) {
+	names, err := s.List(itemType, group)
+	return len(names), err
+}
+
+func (s *Store) List(itemType string, group string) ([]string, error) {
+	ctx := context.Background()
+
+	var names []string
+	if group == "" {
+		prefix := s.groupsPrefix(itemType)
+		prefixes, err := s.listObjects(ctx, prefix, "/", func(page listBucketResult) []string {
+			var groups []string
+			for _, p := range page.CommonPrefixes {
+				groups = append(groups, strings.TrimSuffix(strings.TrimPrefix(p.Prefix, prefix), "/"))
+			}
+			return groups
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing %s", itemType)
+		}
+		names = prefixes
+	} else {
+		prefix := s.groupPrefix(itemType, group)
+		keys, err := s.listObjects(ctx, prefix, "", func(page listBucketResult) []string {
+			var keys []string
+			for _, c := range page.Contents {
+				keys = append(keys, strings.TrimPrefix(c.Key, prefix))
+			}
+			return keys
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing %s in %s", itemType, group)
+		}
+		names = keys
+	}
+
+	for i, name := range names {
+		unescaped, err := url.PathUnescape(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid key %q", name)
+		}
+		names[i] = unescaped
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s *Store) Save(itemType string, group string, name string, data []byte) error {
+	ctx := context.Background()
+
+	header := http.Header{}
+	header.Set(metadataGroup, url.PathEscape(group))
+	_, err := s.do(ctx, http.MethodPut, s.itemKey(itemType, name), nil, header, data)
+	if err != nil {
+		return errors.Wrapf(err, "error saving %s %s", itemType, name)
+	}
+
+	if group != "" {
+		_, err = s.do(ctx, http.MethodPut, s.groupPrefix(itemType, group)+url.PathEscape(name), nil, http.Header{}, nil)
+		if err != nil {
+			return errors.Wrapf(err, "error saving the group of %s %s", itemType, name)
+		}
+	}
+
+	return nil
+}
+
+// SaveIfRevision saves the document when the stored document has the
+// revision, with a conditional request that S3 rejects when the object was
+// modified since it was read: If-Match with the ETag of the object that was
+// compared, or If-None-Match when the document must not exist yet. When the
+// revisions do not match, an error wrapping crud.ErrConflict is returned.
+func (s *Store) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	ctx := context.Background()
+	key := s.itemKey(itemType, name)
+
+	header := http.Header{}
+	header.Set(metadataGroup, url.PathEscape(group))
+	if revision == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+		if err == errNotFound {
+			return conflictError(itemType, name)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "error reading %s %s", itemType, name)
+		}
+		if crud.Revision(resp.body) != revision {
+			return conflictError(itemType, name)
+		}
+		etag := resp.header.Get("ETag")
+		if etag == "" {
+			return fmt.Errorf("could not save %s %s: the storage did not return the ETag of the object", itemType, name)
+		}
+		header.Set("If-Match", etag)
+	}
+
+	_, err := s.do(ctx, http.MethodPut, key, nil, header, data)
+	if err == errPreconditionFailed {
+		return conflictError(itemType, name)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error saving %s %s", itemType, name)
+	}
+
+	if group != "" {
+		_, err = s.do(ctx, http.MethodPut, s.groupPrefix(itemType, group)+url.PathEscape(name), nil, http.Header{}, nil)
+		if err != nil {
+			return errors.Wrapf(err, "error saving the group of %s %s", itemType, name)
+		}
+	}
+
+	return nil
+}
+
+func conflictError(itemType string, name string) error {
+	return fmt.Errorf("could not save %s %s: %w", itemType, name, crud.ErrConflict)
+}
+
+func (s *Store) Read(itemType string, name string) ([]byte, error) {
+	resp, err := s.do(context.Background(), http.MethodGet, s.itemKey(itemType, name), nil, nil, nil)
+	if err != nil {
+		if err == errNotFound {
+			return nil, crud.ErrRecordDoesNotExist
+		}
+		return nil, errors.Wrapf(err, "error reading %s %s", itemType, name)
+	}
+
+	return resp.body, nil
+}
+
+func (s *Store) Delete(itemType string, name string) error {
+	ctx := context.Background()
+	key := s.itemKey(itemType, name)
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		if err == errNotFound {
+			return crud.ErrRecordDoesNotExist
+		}
+		return errors.Wrapf(err, "error deleting %s %s", itemType, name)
+	}
+
+	if group, _ := url.PathUnescape(resp.header.Get(metadataGroup)); group != "" {
+		_, err = s.do(ctx, http.MethodDelete, s.groupPrefix(itemType, group)+url.PathEscape(name), nil, nil, nil)
+		if err != nil && err != errNotFound {
+			return errors.Wrapf(err, "error deleting the group of %s %s", itemType, name)
+		}
+	}
+
+	_, err = s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil && err != errNotFound {
+		return errors.Wrapf(err, "error deleting %s %s", itemType, name)
+	}
+	return nil
+}
+
+// DeleteIfRevision deletes the document when the stored document has the
+// revision, with a conditional request that S3 rejects when the object was
+// modified since it was read: If-Match with the ETag of the object that was
+// compared.
+func (s *Store) DeleteIfRevision(itemType string, name string, revision string) error {
+	ctx := context.Background()
+	key := s.itemKey(itemType, name)
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err == errNotFound {
+		return crud.ErrRecordDoesNotExist
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error reading %s %s", itemType, name)
+	}
+	if crud.Revision(resp.body) != revision {
+		return fmt.Errorf("could not delete %s %s: %w", itemType, name, crud.ErrConflict)
+	}
+	etag := resp.header.Get("ETag")
+	if etag == "" {
+		return fmt.Errorf("could not delete %s %s: the storage did not return the ETag of the object", itemType, name)
+	}
+
+	header := http.Header{}
+	header.Set("If-Match", etag)
+	_, err = s.do(ctx, http.MethodDelete, key, nil, header, nil)
+	if err == errPreconditionFailed {
+		return fmt.Errorf("could not delete %s %s: %w", itemType, name, crud.ErrConflict)
+	}
+	if err == errNotFound {
+		return crud.ErrRecordDoesNotExist
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error deleting %s %s", itemType, name)
+	}
+
+	if group, _ := url.PathUnescape(resp.header.Get(metadataGroup)); group != "" {
+		_, err = s.do(ctx, http.MethodDelete, s.groupPrefix(itemType, group)+url.PathEscape(name), nil, nil, nil)
+		if err != nil && err != errNotFound {
+			return errors.Wrapf(err, "error deleting the group of %s %s", itemType, name)
+		}
+	}
+	return nil
+}
+
+// itemKey returns the key of the object holding a document.
+func (s *Store) itemKey(itemType string, name string) string {
+	return s.config.Prefix + url.PathEscape(itemType) + "/items/" + url.PathEscape(name)
+}
+
+// groupsPrefix returns the prefix of the keys of the objects recording the
+// groups of the documents of an item type.
+func (s *Store) groupsPrefix(itemType string) string {
+	return s.config.Prefix + url.PathEscape(itemType) + "/groups/"
+}
+
+// groupPrefix returns the prefix of the keys of the objects recording the
+// documents in a group.
+func (s *Store) groupPrefix(itemType string, group string) string {
+	return s.groupsPrefix(itemType) + url.PathEscape(group) + "/"
+}
+
+// listBucketResult is the response of ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// listObjects lists the objects with the prefix, following the continuation
+// tokens until the last page, and returns the values selected from each page.
+func (s *Store) listObjects(ctx context.Context, prefix string, delimiter string, selectValues func(listBucketResult) []string) ([]string, error) {
+	var values []string
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page listBucketResult
+		if err := xml.Unmarshal(resp.body, &page); err != nil {
+			return nil, errors.Wrap(err, "invalid ListObjectsV2 response")
+		}
+		values = append(values, selectValues(page)...)
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return values, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// errNotFound is returned by do when the object or bucket does not exist.
+var errNotFound = errors.New("not found")
+
+// errPreconditionFailed is returned when S3 rejected a conditional request,
+// because the object was modified concurrently.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// response of a request to S3.
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// s3Error is the error document returned by S3.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do sends a signed request for the object with the key, or for the bucket
+// when the key is empty.
+func (s *Store) do(ctx context.Context, method string, key string, query url.Values, header http.Header, body []byte) (response, error) {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.config.PathStyle {
+		path += "/" + s.config.Bucket
+	}
+	if key != "" || path == "" {
+		path += "/" + key
+	}
+	u.Path = path
+	u.RawPath = sigv4.URIEncode(path, false)
+	u.RawQuery = sigv4.CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return response{}, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if method == http.MethodPut {
+		switch s.config.ServerSideEncryption {
+		case SSEAES256:
+			req.Header.Set("X-Amz-Server-Side-Encryption", SSEAES256)
+		case SSEKMS:
+			req.Header.Set("X-Amz-Server-Side-Encryption", SSEKMS)
+			if s.config.SSEKMSKeyID != "" {
+				req.Header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", s.config.SSEKMSKeyID)
+			}
+		}
+	}
+
+	payloadHash := sigv4.HashPayload(body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	sigv4.Sign(req, sigv4.Credentials(s.config.Credentials), s.config.Region, "s3", payloadHash, time.Now())
+
+	resp, err := s.config.HTTPClient.Do(req)
+	if err != nil {
+		return response{}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return response{}, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return response{}, errNotFound
+	}
+	if resp.StatusCode == http.StatusPreconditionFailed || resp.StatusCode == http.StatusConflict {
+		// A conflict is returned when a conditional write raced another write
+		return response{}, errPreconditionFailed
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var s3Err s3Error
+		if xml.Unmarshal(respBody, &s3Err) == nil && s3Err.Code != "" {
+			return response{}, fmt.Errorf("s3 returned %s: %s: %s", resp.Status, s3Err.Code, s3Err.Message)
+		}
+		return response{}, fmt.Errorf("s3 returned %s", resp.Status)
+	}
+
+	return response{header: resp.Header, body: respBody}, nil
+}
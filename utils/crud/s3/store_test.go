@@ -0,0 +1,349 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cnabio/cnab-go/utils/crud"
+)
+
+type testObject struct {
+	data   []byte
+	header http.Header
+}
+
+func (o testObject) etag() string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(o.data))
+}
+
+// testS3 is an in-memory S3 server that supports path style requests to a
+// single bucket, and returns pages of two objects when listing.
+type testS3 struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]testObject
+}
+
+func newTestS3(t *testing.T) *testS3 {
+	s := &testS3{objects: map[string]testObject{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/cnab-bucket")
+		if key == r.URL.Path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		key = strings.TrimPrefix(key, "/")
+
+		switch {
+		case key == "" && r.Method == http.MethodGet:
+			s.list(w, r)
+		case r.Method == http.MethodPut:
+			data, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			o, exists := s.objects[key]
+			if (r.Header.Get("If-None-Match") == "*" && exists) ||
+				(r.Header.Get("If-Match") != "" && (!exists || r.Header.Get("If-Match") != o.etag())) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				w.Write([]byte(`<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`))
+				return
+			}
+			s.objects[key] = testObject{data: data, header: r.Header.Clone()}
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			o, ok := s.objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set(metadataGroup, o.header.Get(metadataGroup))
+			w.Header().Set("ETag", o.etag())
+			w.Write(o.data)
+		case r.Method == http.MethodDelete:
+			if o, exists := s.objects[key]; exists && r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != o.etag() {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			delete(s.objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testS3) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+
+	var keys []string
+	seen := map[string]bool{}
+	for key := range s.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				key = key[:len(prefix)+i+1]
+			}
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	start, _ := strconv.Atoi(query.Get("continuation-token"))
+	end := start + 2
+	var result listBucketResult
+	if end < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(end)
+	} else {
+		end = len(keys)
+	}
+	for _, key := range keys[start:end] {
+		if strings.HasSuffix(key, delimiter) && delimiter != "" {
+			result.CommonPrefixes = append(result.CommonPrefixes, struct {
+				Prefix string `xml:"Prefix"`
+			}{Prefix: key})
+		} else {
+			result.Contents = append(result.Contents, struct {
+				Key string `xml:"Key"`
+			}{Key: key})
+		}
+	}
+
+	data, _ := xml.Marshal(result)
+	w.Write(data)
+}
+
+func newTestStore(t *testing.T, s3 *testS3) *Store {
+	store, err := NewStore(Config{
+		Bucket:               "cnab-bucket",
+		Prefix:               "cnab/",
+		Endpoint:             s3.URL,
+		PathStyle:            true,
+		Credentials:          Credentials{AccessKeyID: "test-key", SecretAccessKey: "test-secret"},
+		ServerSideEncryption: SSEAES256,
+	})
+	require.NoError(t, err)
+	return store
+}
+
+func TestStore(t *testing.T) {
+	s3 := newTestS3(t)
+	store := newTestStore(t, s3)
+
+	require.NoError(t, store.Save("claims", "mysql", "claim-1", []byte("one")))
+	require.NoError(t, store.Save("claims", "mysql", "claim-2", []byte("two")))
+	require.NoError(t, store.Save("claims", "mysql", "claim-3", []byte("three")))
+	require.NoError(t, store.Save("claims", "word press", "claim/4", []byte("four")))
+
+	o, ok := s3.objects["cnab/claims/items/claim-1"]
+	require.True(t, ok, "the claim should be saved under the prefix")
+	assert.Equal(t, SSEAES256, o.header.Get("X-Amz-Server-Side-Encryption"))
+
+	groups, err := store.List("claims", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql", "word press"}, groups)
+
+	names, err := store.List("claims", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claim-1", "claim-2", "claim-3"}, names, "all the pages should be listed")
+
+	count, err := store.Count("claims", "word press")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	data, err := store.Read("claims", "claim/4")
+	require.NoError(t, err)
+	assert.Equal(t, "four", string(data))
+
+	require.NoError(t, store.Delete("claims", "claim/4"))
+	_, err = store.Read("claims", "claim/4")
+	assert.Equal(t, crud.ErrRecordDoesNotExist, err)
+	groups, err = store.List("claims", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, groups, "the group should be removed with its last item")
+
+	assert.Equal(t, crud.ErrRecordDoesNotExist, store.Delete("claims", "claim/4"))
+}
+
+func TestStore_SaveIfRevision(t *testing.T) {
+	s3 := newTestS3(t)
+	store := newTestStore(t, s3)
+
+	require.NoError(t, store.SaveIfRevision("locks", "", "mysql", []byte("one"), ""))
+	err := store.SaveIfRevision("locks", "", "mysql", []byte("two"), "")
+	assert.ErrorIs(t, err, crud.ErrConflict, "an item that exists should not be created again")
+
+	err = store.SaveIfRevision("locks", "", "mysql", []byte("two"), crud.Revision([]byte("other")))
+	assert.ErrorIs(t, err, crud.ErrConflict, "an item should not be saved with the wrong revision")
+
+	require.NoError(t, store.SaveIfRevision("locks", "", "mysql", []byte("two"), crud.Revision([]byte("one"))))
+	data, err := store.Read("locks", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(data))
+	assert.Equal(t, `"`+fmt.Sprintf("%x", md5.Sum([]byte("one")))+`"`, s3.objects["cnab/locks/items/mysql"].header.Get("If-Match"),
+		"the object should be saved with the ETag of the revision that was compared")
+
+	err = store.SaveIfRevision("locks", "", "wordpress", []byte("one"), crud.Revision([]byte("one")))
+	assert.ErrorIs(t, err, crud.ErrConflict, "an item that does not exist should not be saved with a revision")
+
+	t.Run("modified concurrently", func(t *testing.T) {
+		s3 := newTestS3(t)
+		store := newTestStore(t, s3)
+		require.NoError(t, store.Save("claims", "mysql", "claim-1", []byte("one")))
+
+		// Modify the object between the comparison of the revision and the save
+		s3.Config.Handler = modifyBeforePut(s3, "cnab/claims/items/claim-1", s3.Config.Handler)
+
+		err := store.SaveIfRevision("claims", "mysql", "claim-1", []byte("two"), crud.Revision([]byte("one")))
+		assert.ErrorIs(t, err, crud.ErrConflict)
+		assert.Equal(t, "modified", string(s3.objects["cnab/claims/items/claim-1"].data))
+	})
+}
+
+func TestStore_DeleteIfRevision(t *testing.T) {
+	s3 := newTestS3(t)
+	store := newTestStore(t, s3)
+
+	err := store.DeleteIfRevision("claims", "claim-1", crud.Revision([]byte("one")))
+	assert.ErrorIs(t, err, crud.ErrRecordDoesNotExist)
+
+	require.NoError(t, store.Save("claims", "mysql", "claim-1", []byte("one")))
+	err = store.DeleteIfRevision("claims", "claim-1", crud.Revision([]byte("two")))
+	assert.ErrorIs(t, err, crud.ErrConflict, "an item should not be deleted with the wrong revision")
+
+	require.NoError(t, store.DeleteIfRevision("claims", "claim-1", crud.Revision([]byte("one"))))
+	assert.Empty(t, s3.objects, "the item and its group should be deleted")
+
+	t.Run("modified concurrently", func(t *testing.T) {
+		s3 := newTestS3(t)
+		store := newTestStore(t, s3)
+		require.NoError(t, store.Save("claims", "mysql", "claim-1", []byte("one")))
+
+		s3.Config.Handler = modifyBeforeDelete(s3, "cnab/claims/items/claim-1", s3.Config.Handler)
+
+		err := store.DeleteIfRevision("claims", "claim-1", crud.Revision([]byte("one")))
+		assert.ErrorIs(t, err, crud.ErrConflict)
+		assert.Equal(t, "modified", string(s3.objects["cnab/claims/items/claim-1"].data))
+	})
+}
+
+// modifyBeforePut modifies the object with the key before it is saved.
+func modifyBeforePut(s3 *testS3, key string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			s3.mu.Lock()
+			o := s3.objects[key]
+			o.data = []byte("modified")
+			s3.objects[key] = o
+			s3.mu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestStore_ManagedStore(t *testing.T) {
+	store := crud.NewManagedStore(newTestStore(t, newTestS3(t)))
+
+	require.NoError(t, store.Save("results", "claim-1", "result-1", []byte("succeeded")))
+	data, err := store.Read("results", "result-1")
+	require.NoError(t, err)
+	assert.Equal(t, "succeeded", string(data))
+}
+
+func TestStore_Errors(t *testing.T) {
+	s3 := newTestS3(t)
+	store, err := NewStore(Config{
+		Bucket:      "cnab-bucket",
+		Endpoint:    s3.URL,
+		PathStyle:   true,
+		Credentials: Credentials{AccessKeyID: "wrong-key", SecretAccessKey: "test-secret"},
+	})
+	require.NoError(t, err)
+
+	_, err = store.Read("claims", "claim-1")
+	assert.EqualError(t, err, "error reading claims claim-1: s3 returned 403 Forbidden: AccessDenied: Access Denied")
+}
+
+func TestNewStore(t *testing.T) {
+	creds := Credentials{AccessKeyID: "key", SecretAccessKey: "secret"}
+
+	t.Run("aws", func(t *testing.T) {
+		store, err := NewStore(Config{Bucket: "cnab", Region: "eu-west-1", Credentials: creds})
+		require.NoError(t, err)
+		assert.Equal(t, "https://cnab.s3.eu-west-1.amazonaws.com", store.endpoint.String())
+	})
+
+	t.Run("missing bucket", func(t *testing.T) {
+		_, err := NewStore(Config{Credentials: creds})
+		assert.EqualError(t, err, "the bucket is required")
+	})
+
+	t.Run("missing credentials", func(t *testing.T) {
+		_, err := NewStore(Config{Bucket: "cnab"})
+		assert.EqualError(t, err, "the access key and secret key are required")
+	})
+
+	t.Run("invalid encryption", func(t *testing.T) {
+		_, err := NewStore(Config{Bucket: "cnab", Credentials: creds, ServerSideEncryption: "rot13"})
+		assert.EqualError(t, err, `invalid server side encryption "rot13", the supported values are AES256 and aws:kms`)
+	})
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvAccessKeyID, "key")
+	t.Setenv(EnvSecretAccessKey, "secret")
+	t.Setenv(EnvSessionToken, "")
+	t.Setenv(EnvRegion, "")
+	t.Setenv(EnvDefaultRegion, "eu-west-1")
+	t.Setenv(EnvEndpoint, "http://localhost:9000")
+
+	assert.Equal(t, Config{
+		Bucket:      "cnab",
+		Region:      "eu-west-1",
+		Endpoint:    "http://localhost:9000",
+		PathStyle:   true,
+		Credentials: Credentials{AccessKeyID: "key", SecretAccessKey: "secret"},
+	}, NewConfigFromEnv("cnab"))
+}
+
+// modifyBeforeDelete modifies the object with the key before it is deleted.
+func modifyBeforeDelete(s3 *testS3, key string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			s3.mu.Lock()
+			o := s3.objects[key]
+			o.data = []byte("modified")
+			s3.objects[key] = o
+			s3.mu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,42 @@
+package crud
+
+import (
+	"errors"
+)
+
+// ErrRecordDoesNotExist is returned when a requested record is not found in the store.
+// Stores may wrap it with more context, so match it with errors.Is.
+var ErrRecordDoesNotExist = errors.New("record does not exist")
+
+// Store is a simplified interface to a key-blob store supporting CRUD operations.
+type Store interface {
+	// Count the number of items of the specified itemType in a group.
+	Count(itemType string, group string) (int, error)
+
+	// List the names of the items of the specified itemType in a group.
+	// When group is empty, the names of the groups are returned instead.
+	List(itemType string, group string) ([]string, error)
+
+	// Save the data for an item of the specified itemType, overwriting any
+	// existing item with the same name.
+	Save(itemType string, group string, name string, data []byte) error
+
+	// Read the data for an item of the specified itemType. Names are unique
+	// within an itemType, so the group is not required.
+	Read(itemType string, name string) ([]byte, error)
+
+	// Delete an item of the specified itemType.
+	Delete(itemType string, name string) error
+}
+
+// HasConnect indicates that a Store must be initialized using the Connect
+// method before its other methods are called.
+type HasConnect interface {
+	Connect() error
+}
+
+// HasClose indicates that a Store must be cleaned up using the Close
+// method when it is no longer in use.
+type HasClose interface {
+	Close() error
+}
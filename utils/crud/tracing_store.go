@@ -0,0 +1,226 @@
+package crud
+
+import (
+	"log"
+	"time"
+)
+
+var (
+	_ Store            = &TracingStore{}
+	_ HasConnect       = &TracingStore{}
+	_ HasClose         = &TracingStore{}
+	_ ConditionalStore = &TracingStore{}
+)
+
+// Names of the Store methods reported in a StoreOperation.
+const (
+	MethodCount  = "Count"
+	MethodList   = "List"
+	MethodSave   = "Save"
+	MethodRead   = "Read"
+	MethodDelete = "Delete"
+
+	MethodSaveIfRevision   = "SaveIfRevision"
+	MethodDeleteIfRevision = "DeleteIfRevision"
+
+	MethodSetIndexes    = "SetIndexes"
+	MethodRemoveIndexes = "RemoveIndexes"
+	MethodListByIndex   = "ListByIndex"
+)
+
+// StoreOperation describes a call to the backing store of a TracingStore.
+type StoreOperation struct {
+	// Method of the Store that was called, for example MethodRead.
+	Method string
+
+	// ItemType of the items that were accessed.
+	ItemType string
+
+	// Group of the items, when the method accepts one.
+	Group string
+
+	// Name of the item, when the method accepts one.
+	Name string
+
+	// Duration of the call to the backing store.
+	Duration time.Duration
+
+	// Size of the data read or saved, in bytes.
+	Size int
+
+	// Slow is true when the Duration exceeded the SlowThreshold of the store.
+	Slow bool
+
+	// Err is the error returned by the backing store.
+	Err error
+}
+
+// StoreTracer is called by a TracingStore after each call to its backing store.
+type StoreTracer func(op StoreOperation)
+
+// TracingStore is a Store decorator that reports each operation on the
+// backing store, with its duration and the size of its data, to help
+// diagnose slow storage backends. Wrap the backing store before it is
+// passed to NewManagedStore or claim.NewClaimStore.
+//
+// The secondary indexes of the backing store, when it implements Indexer,
+// are available with GetIndexer, and their operations are reported too.
+type TracingStore struct {
+	backingStore Store
+	tracer       StoreTracer
+
+	// SlowThreshold flags operations that take longer as Slow. Operations are
+	// never flagged when it is zero.
+	SlowThreshold time.Duration
+}
+
+// NewTracingStore wraps a Store, reporting each operation to the tracer.
+func NewTracingStore(store Store, tracer StoreTracer) *TracingStore {
+	return &TracingStore{
+		backingStore: store,
+		tracer:       tracer,
+	}
+}
+
+// NewLoggingStore wraps a Store, logging each operation that is slower than
+// the threshold to the logger. When the threshold is zero, every operation
+// is logged.
+func NewLoggingStore(store Store, logger *log.Logger, slowThreshold time.Duration) *TracingStore {
+	s := NewTracingStore(store, func(op StoreOperation) {
+		if slowThreshold > 0 && !op.Slow {
+			return
+		}
+		LogStoreOperation(logger, op)
+	})
+	s.SlowThreshold = slowThreshold
+	return s
+}
+
+// LogStoreOperation writes a line describing the operation to the logger.
+func LogStoreOperation(logger *log.Logger, op StoreOperation) {
+	slow := ""
+	if op.Slow {
+		slow = "slow "
+	}
+
+	status := "ok"
+	if op.Err != nil {
+		status = op.Err.Error()
+	}
+
+	logger.Printf("%sstore %s itemType=%s group=%s name=%s duration=%s size=%d: %s",
+		slow, op.Method, op.ItemType, op.Group, op.Name, op.Duration, op.Size, status)
+}
+
+// Connect to the backing store, if it requires it.
+func (s *TracingStore) Connect() error {
+	if connectable, ok := s.backingStore.(HasConnect); ok {
+		return connectable.Connect()
+	}
+	return nil
+}
+
+// Close the backing store, if it requires it.
+func (s *TracingStore) Close() error {
+	if closable, ok := s.backingStore.(HasClose); ok {
+		return closable.Close()
+	}
+	return nil
+}
+
+func (s *TracingStore) Count(itemType string, group string) (int, error) {
+	start := time.Now()
+	count, err := s.backingStore.Count(itemType, group)
+	s.trace(StoreOperation{Method: MethodCount, ItemType: itemType, Group: group, Err: err}, start)
+	return count, err
+}
+
+func (s *TracingStore) List(itemType string, group string) ([]string, error) {
+	start := time.Now()
+	names, err := s.backingStore.List(itemType, group)
+	s.trace(StoreOperation{Method: MethodList, ItemType: itemType, Group: group, Err: err}, start)
+	return names, err
+}
+
+func (s *TracingStore) Save(itemType string, group string, name string, data []byte) error {
+	start := time.Now()
+	err := s.backingStore.Save(itemType, group, name, data)
+	s.trace(StoreOperation{Method: MethodSave, ItemType: itemType, Group: group, Name: name, Size: len(data), Err: err}, start)
+	return err
+}
+
+func (s *TracingStore) SaveIfRevision(itemType string, group string, name string, data []byte, revision string) error {
+	start := time.Now()
+	err := SaveIfRevision(s.backingStore, itemType, group, name, data, revision)
+	s.trace(StoreOperation{Method: MethodSaveIfRevision, ItemType: itemType, Group: group, Name: name, Size: len(data), Err: err}, start)
+	return err
+}
+
+func (s *TracingStore) Read(itemType string, name string) ([]byte, error) {
+	start := time.Now()
+	data, err := s.backingStore.Read(itemType, name)
+	s.trace(StoreOperation{Method: MethodRead, ItemType: itemType, Name: name, Size: len(data), Err: err}, start)
+	return data, err
+}
+
+func (s *TracingStore) Delete(itemType string, name string) error {
+	start := time.Now()
+	err := s.backingStore.Delete(itemType, name)
+	s.trace(StoreOperation{Method: MethodDelete, ItemType: itemType, Name: name, Err: err}, start)
+	return err
+}
+
+func (s *TracingStore) DeleteIfRevision(itemType string, name string, revision string) error {
+	start := time.Now()
+	err := DeleteIfRevision(s.backingStore, itemType, name, revision)
+	s.trace(StoreOperation{Method: MethodDeleteIfRevision, ItemType: itemType, Name: name, Err: err}, start)
+	return err
+}
+
+// indexer returns the Indexer of the backing store, reporting its operations.
+func (s *TracingStore) indexer() (Indexer, bool) {
+	backingIndexer, ok := GetIndexer(s.backingStore)
+	if !ok {
+		return nil, false
+	}
+	return tracingIndexer{store: s, backingIndexer: backingIndexer}, true
+}
+
+// tracingIndexer reports each operation on the Indexer of the backing store
+// of a TracingStore.
+type tracingIndexer struct {
+	store          *TracingStore
+	backingIndexer Indexer
+}
+
+func (i tracingIndexer) SetIndexes(itemType string, name string, indexes map[string]string) error {
+	start := time.Now()
+	err := i.backingIndexer.SetIndexes(itemType, name, indexes)
+	i.store.trace(StoreOperation{Method: MethodSetIndexes, ItemType: itemType, Name: name, Err: err}, start)
+	return err
+}
+
+func (i tracingIndexer) RemoveIndexes(itemType string, name string) error {
+	start := time.Now()
+	err := i.backingIndexer.RemoveIndexes(itemType, name)
+	i.store.trace(StoreOperation{Method: MethodRemoveIndexes, ItemType: itemType, Name: name, Err: err}, start)
+	return err
+}
+
+func (i tracingIndexer) ListByIndex(itemType string, index string, value string) ([]string, error) {
+	start := time.Now()
+	names, err := i.backingIndexer.ListByIndex(itemType, index, value)
+	i.store.trace(StoreOperation{Method: MethodListByIndex, ItemType: itemType, Err: err}, start)
+	return names, err
+}
+
+// trace reports the operation, which started at the specified time, to the tracer.
+func (s *TracingStore) trace(op StoreOperation, start time.Time) {
+	if s.tracer == nil {
+		return
+	}
+
+	op.Duration = time.Since(start)
+	op.Slow = s.SlowThreshold > 0 && op.Duration > s.SlowThreshold
+	s.tracer(op)
+}
@@ -0,0 +1,86 @@
+package crud
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowStore delays reads from the wrapped store.
+type slowStore struct {
+	Store
+	delay time.Duration
+}
+
+func (s slowStore) Read(itemType string, name string) ([]byte, error) {
+	time.Sleep(s.delay)
+	return s.Store.Read(itemType, name)
+}
+
+func TestTracingStore(t *testing.T) {
+	backingStore := NewMockStore()
+	var ops []StoreOperation
+	s := NewTracingStore(backingStore, func(op StoreOperation) {
+		ops = append(ops, op)
+	})
+
+	require.NoError(t, s.Connect())
+	require.NoError(t, s.Save("claims", "mysql", "1", []byte("install")))
+	data, err := s.Read("claims", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "install", string(data))
+	_, err = s.List("claims", "mysql")
+	require.NoError(t, err)
+	_, err = s.Count("claims", "mysql")
+	require.NoError(t, err)
+	require.NoError(t, s.Delete("claims", "1"))
+	_, err = s.Read("claims", "1")
+	require.Equal(t, ErrRecordDoesNotExist, err)
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, 1, backingStore.ConnectCount, "Connect should be passed to the backing store")
+	assert.Equal(t, 1, backingStore.CloseCount, "Close should be passed to the backing store")
+
+	require.Len(t, ops, 6)
+	for i := range ops {
+		assert.False(t, ops[i].Slow, "operations should not be flagged without a threshold")
+		ops[i].Duration = 0
+	}
+	assert.Equal(t, []StoreOperation{
+		{Method: MethodSave, ItemType: "claims", Group: "mysql", Name: "1", Size: 7},
+		{Method: MethodRead, ItemType: "claims", Name: "1", Size: 7},
+		{Method: MethodList, ItemType: "claims", Group: "mysql"},
+		{Method: MethodCount, ItemType: "claims", Group: "mysql"},
+		{Method: MethodDelete, ItemType: "claims", Name: "1"},
+		{Method: MethodRead, ItemType: "claims", Name: "1", Err: ErrRecordDoesNotExist},
+	}, ops)
+}
+
+func TestNewLoggingStore(t *testing.T) {
+	backingStore := NewMockStore()
+	require.NoError(t, backingStore.Save("claims", "mysql", "1", []byte("install")))
+
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+	s := NewLoggingStore(slowStore{Store: backingStore, delay: 20 * time.Millisecond}, logger, 10*time.Millisecond)
+
+	_, err := s.List("claims", "mysql")
+	require.NoError(t, err)
+	assert.Empty(t, buf.String(), "fast operations should not be logged")
+
+	_, err = s.Read("claims", "1")
+	require.NoError(t, err)
+	assert.Regexp(t, `^slow store Read itemType=claims group= name=1 duration=\S+ size=7: ok\n$`, buf.String())
+
+	t.Run("without a threshold", func(t *testing.T) {
+		buf.Reset()
+		s := NewLoggingStore(backingStore, logger, 0)
+		_, err := s.Read("claims", "2")
+		require.Equal(t, ErrRecordDoesNotExist, err)
+		assert.Regexp(t, `^store Read itemType=claims group= name=2 duration=\S+ size=0: record does not exist\n$`, buf.String())
+	})
+}
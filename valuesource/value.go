@@ -0,0 +1,157 @@
+package valuesource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Kind is the JSON type of a Value.
+type Kind string
+
+const (
+	KindString  Kind = "string"
+	KindInteger Kind = "integer"
+	KindNumber  Kind = "number"
+	KindBoolean Kind = "boolean"
+	KindObject  Kind = "object"
+	KindArray   Kind = "array"
+	KindNull    Kind = "null"
+)
+
+// Value is a resolved value that keeps its type, so that integers, booleans,
+// objects and arrays are not converted to strings and parsed again.
+type Value struct {
+	// Kind is the JSON type of the value.
+	Kind Kind `json:"kind" yaml:"kind"`
+	// Raw is the JSON representation of the value.
+	Raw json.RawMessage `json:"raw" yaml:"raw"`
+}
+
+// StringValue creates a Value holding the string.
+func StringValue(s string) Value {
+	raw, _ := json.Marshal(s)
+	return Value{Kind: KindString, Raw: raw}
+}
+
+// NewValue creates a Value from any value that can be serialized to JSON,
+// and determines its kind from its JSON representation.
+func NewValue(v interface{}) (Value, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return Value{}, fmt.Errorf("value cannot be serialized to JSON: %w", err)
+	}
+	return ParseValue(raw)
+}
+
+// ParseValue creates a Value from its JSON representation.
+func ParseValue(raw []byte) (Value, error) {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var decoded interface{}
+	if err := d.Decode(&decoded); err != nil {
+		return Value{}, fmt.Errorf("value is not valid JSON: %w", err)
+	}
+
+	var kind Kind
+	switch v := decoded.(type) {
+	case nil:
+		kind = KindNull
+	case bool:
+		kind = KindBoolean
+	case json.Number:
+		kind = KindNumber
+		if _, err := v.Int64(); err == nil {
+			kind = KindInteger
+		}
+	case string:
+		kind = KindString
+	case map[string]interface{}:
+		kind = KindObject
+	case []interface{}:
+		kind = KindArray
+	}
+
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, raw); err != nil {
+		return Value{}, fmt.Errorf("value is not valid JSON: %w", err)
+	}
+	return Value{Kind: kind, Raw: compacted.Bytes()}, nil
+}
+
+// String returns the value as it is injected into an environment variable or
+// a file: strings as is and other kinds as JSON.
+func (v Value) String() string {
+	if v.Kind == KindString {
+		var s string
+		if err := json.Unmarshal(v.Raw, &s); err == nil {
+			return s
+		}
+	}
+	return string(v.Raw)
+}
+
+// Interface returns the value as a Go value: a string, an int for integers,
+// a float64 for numbers, a bool, a map[string]interface{}, a
+// []interface{} or nil.
+func (v Value) Interface() (interface{}, error) {
+	switch v.Kind {
+	case KindInteger:
+		i, err := strconv.Atoi(string(v.Raw))
+		if err != nil {
+			return nil, fmt.Errorf("value %s is not an integer: %w", v.Raw, err)
+		}
+		return i, nil
+	default:
+		var decoded interface{}
+		if err := json.Unmarshal(v.Raw, &decoded); err != nil {
+			return nil, fmt.Errorf("value %s is not valid JSON: %w", v.Raw, err)
+		}
+		return decoded, nil
+	}
+}
+
+// Decode unmarshals the value into out, which must be a pointer.
+func (v Value) Decode(out interface{}) error {
+	if err := json.Unmarshal(v.Raw, out); err != nil {
+		return fmt.Errorf("value cannot be decoded into %T: %w", out, err)
+	}
+	return nil
+}
+
+// Values is a set of resolved values that keep their type.
+type Values map[string]Value
+
+// Values converts the Set into Values holding strings.
+func (s Set) Values() Values {
+	values := make(Values, len(s))
+	for k, v := range s {
+		values[k] = StringValue(v)
+	}
+	return values
+}
+
+// Set converts the Values into a Set, formatting each value as described by
+// Value.String.
+func (v Values) Set() Set {
+	set := make(Set, len(v))
+	for k, value := range v {
+		set[k] = value.String()
+	}
+	return set
+}
+
+// Merge merges a second Values into the base.
+//
+// Duplicate names are not allowed and will result in an
+// error, this is the case even if the values are identical.
+func (v Values) Merge(v2 Values) error {
+	for k, value := range v2 {
+		if _, ok := v[k]; ok {
+			return fmt.Errorf("ambiguous value resolution: %q is already present in base sets, cannot merge", k)
+		}
+		v[k] = value
+	}
+	return nil
+}
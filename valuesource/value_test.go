@@ -0,0 +1,81 @@
+package valuesource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewValue(t *testing.T) {
+	testcases := []struct {
+		name       string
+		value      interface{}
+		wantKind   Kind
+		wantString string
+		wantValue  interface{}
+	}{
+		{name: "string", value: "hunter2", wantKind: KindString, wantString: "hunter2", wantValue: "hunter2"},
+		{name: "integer", value: 8080, wantKind: KindInteger, wantString: "8080", wantValue: 8080},
+		{name: "number", value: 0.5, wantKind: KindNumber, wantString: "0.5", wantValue: 0.5},
+		{name: "boolean", value: true, wantKind: KindBoolean, wantString: "true", wantValue: true},
+		{name: "object", value: map[string]interface{}{"b": 1, "a": "x"}, wantKind: KindObject, wantString: `{"a":"x","b":1}`, wantValue: map[string]interface{}{"a": "x", "b": float64(1)}},
+		{name: "array", value: []string{"web", "db"}, wantKind: KindArray, wantString: `["web","db"]`, wantValue: []interface{}{"web", "db"}},
+		{name: "null", value: nil, wantKind: KindNull, wantString: "null", wantValue: nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewValue(tc.value)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantKind, v.Kind)
+			assert.Equal(t, tc.wantString, v.String())
+
+			got, err := v.Interface()
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantValue, got)
+		})
+	}
+
+	_, err := NewValue(make(chan int))
+	assert.Error(t, err)
+}
+
+func TestParseValue(t *testing.T) {
+	v, err := ParseValue([]byte(`{ "tags": ["web", "db"] }`))
+	require.NoError(t, err)
+	assert.Equal(t, KindObject, v.Kind)
+	assert.Equal(t, `{"tags":["web","db"]}`, v.String())
+
+	var decoded struct {
+		Tags []string `json:"tags"`
+	}
+	require.NoError(t, v.Decode(&decoded))
+	assert.Equal(t, []string{"web", "db"}, decoded.Tags)
+
+	_, err = ParseValue([]byte(`{"tags":`))
+	assert.Error(t, err)
+}
+
+func TestSet_Values(t *testing.T) {
+	set := Set{"port": "8080", "tags": `["web"]`}
+
+	values := set.Values()
+	assert.Equal(t, StringValue("8080"), values["port"], "values of a set should be strings")
+	assert.Equal(t, StringValue(`["web"]`), values["tags"])
+	assert.Equal(t, set, values.Set(), "the conversion should round trip")
+
+	port, err := NewValue(8080)
+	require.NoError(t, err)
+	assert.Equal(t, Set{"port": "8080"}, Values{"port": port}.Set())
+}
+
+func TestValues_Merge(t *testing.T) {
+	values := Values{"first": StringValue("first")}
+
+	require.NoError(t, values.Merge(Values{"second": StringValue("second")}))
+	assert.Len(t, values, 2)
+
+	err := values.Merge(Values{"first": StringValue("first")})
+	assert.EqualError(t, err, `ambiguous value resolution: "first" is already present in base sets, cannot merge`)
+}